@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"squatrr/lib/certstream"
+	"squatrr/lib/classify"
+	"squatrr/lib/config"
+	"squatrr/lib/processor"
+	"squatrr/lib/score"
+	"squatrr/lib/systemd"
+	"squatrr/lib/typo"
+	"squatrr/lib/verify"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// certAlert is one line of the certstream alerts file.
+type certAlert struct {
+	certstream.Match
+	Result *processor.Output `json:"result,omitempty"`
+}
+
+// runCertstream watches Certificate Transparency for certificates issued to
+// permutations of the given base domains.
+func runCertstream(args []string) {
+	fs := flag.NewFlagSet("certstream", flag.ExitOnError)
+	var (
+		domains  = fs.String("domain", "", "Comma-separated base domains to watch, e.g., example.com,example.org")
+		tlds     = fs.String("tlds", "", "Comma-separated TLD variants (default: each base domain's own TLD)")
+		url      = fs.String("url", certstream.DefaultURL, "certstream-compatible websocket feed")
+		alerts   = fs.String("alerts", "", "Append matches as JSON lines to this file (matches are always logged)")
+		doVerify = fs.Bool("verify", true, "Verify, score and classify matched domains before alerting")
+		doHTTP   = fs.Bool("http", true, "Attempt HTTP(S) requests when verifying matches")
+		workers  = fs.Int("workers", 8, "Matches alerted on at once; further matches wait, pausing the feed")
+		cfgFile  = fs.String("config", "", "Optional JSON config file: its scoring rubric grades matches, and its TLD policies limit the permutations watched")
+		health   = fs.String("healthz", "", "Optional address to serve /healthz on, reporting the last certificate received, e.g., localhost:8081")
+		silence  = fs.Duration("max-silence", 10*time.Minute, "Report unhealthy, and stop feeding a systemd watchdog, when no certificate arrived for this long")
+		logLevel = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	bases := parseList(*domains)
+	if len(bases) == 0 {
+		logger.Error("error: -domain is required")
+		os.Exit(2)
+	}
+	var (
+		rubric    *score.Rubric
+		tldPolicy *typo.TLDPolicy
+	)
+	if *cfgFile != "" {
+		cfg, err := config.Load(*cfgFile)
+		if err != nil {
+			logger.Error("loading config", "error", err)
+			os.Exit(2)
+		}
+		rubric, tldPolicy = cfg.Rubric, &cfg.Permutations
+	}
+	idx := certstream.NewIndex()
+	for _, base := range bases {
+		candidates, err := processor.Candidates(base, parseTLDs(base, *tlds), nil, tldPolicy, nil, logger)
+		if err != nil {
+			logger.Error("processing candidates", "domain", base, "error", err)
+			os.Exit(2)
+		}
+		idx.Add(base, candidates)
+	}
+	logger.Info("watching certstream", "url", *url, "bases", len(bases), "permutations", idx.Len())
+
+	var (
+		mu  sync.Mutex
+		out *json.Encoder
+	)
+	if *alerts != "" {
+		f, err := os.OpenFile(*alerts, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = json.NewEncoder(f)
+	}
+
+	vCfg := verify.Config{
+		DNSTimeout:    2 * time.Second,
+		TLSTimeout:    3 * time.Second,
+		HTTPTimeout:   4 * time.Second,
+		DoTLS:         true,
+		DoHTTP:        *doHTTP,
+		FetchBody:     *doHTTP,
+		UserAgent:     "saskquat-verifier/1.0",
+		DKIMSelectors: verify.DefaultDKIMSelectors,
+		BodyInspector: classify.Default,
+		Registrars:    classify.Default,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	activity := &certActivity{started: time.Now()}
+	healthy := func() error { return activity.healthy(*silence) }
+	if *health != "" {
+		ln, err := net.Listen("tcp", *health)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+			h := activity.health(*silence)
+			writeHealth(w, h.Status == "ok", h)
+		})
+		srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() { _ = srv.Serve(ln) }()
+		defer srv.Close()
+		logger.Info("serving healthz", "listen", ln.Addr())
+	}
+	go systemd.Watchdog(ctx, healthy, logger)
+	if err := systemd.Ready(); err != nil {
+		logger.Warn("processing systemd notify", "error", err)
+	}
+	defer func() { _ = systemd.Stopping() }()
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, max(*workers, 1))
+	alert := func(m certstream.Match) {
+		defer wg.Done()
+		defer func() { <-sem }()
+		a := certAlert{Match: m}
+		if *doVerify {
+			vctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			o, err := processor.Evaluate(vctx, m.Base, processor.Candidate{Domain: m.Domain, Strategy: m.Strategy, Likelihood: m.Likelihood}, vCfg, nil, rubric)
+			cancel()
+			if err == nil {
+				a.Result = &o
+			}
+		}
+		attrs := []any{"domain", m.Domain, "base", m.Base, "cert_domain", m.CertDomain, "issuer", m.Issuer, "log", m.Source}
+		if a.Result != nil {
+			attrs = append(attrs, "score", a.Result.Score, "class", a.Result.Class)
+		}
+		logger.Warn("certificate issued for permutation", attrs...)
+		if out != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			if err := out.Encode(a); err != nil {
+				logger.Error("writing alert", "error", err)
+			}
+		}
+	}
+
+	err := certstream.Watch(ctx, *url, logger, func(c certstream.Cert) {
+		activity.certs.Add(1)
+		activity.lastCert.Store(time.Now().UnixNano())
+		for _, m := range idx.Match(c) {
+			activity.matches.Add(1)
+			activity.lastMatch.Store(time.Now().UnixNano())
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			go alert(m)
+		}
+	})
+	wg.Wait()
+	if err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+}
+
+// certActivity tracks the feed for health checks.
+type certActivity struct {
+	started                             time.Time
+	certs, matches, lastCert, lastMatch atomic.Int64 // last* are unix nanoseconds
+}
+
+// certstreamHealth is the certstream mode's /healthz response.
+type certstreamHealth struct {
+	Status          string    `json:"status"` // ok, or silent when no certificate arrived within -max-silence
+	Started         time.Time `json:"started"`
+	Certificates    int64     `json:"certificates"`
+	LastCertificate time.Time `json:"last_certificate,omitzero"`
+	Matches         int64     `json:"matches"`
+	LastMatch       time.Time `json:"last_match,omitzero"`
+}
+
+// healthy fails once the feed has been silent (since startup, or since the
+// last certificate) for longer than silence; the public feed carries
+// certificates every second, so silence means a wedged connection.
+func (a *certActivity) healthy(silence time.Duration) error {
+	last := a.started
+	if n := a.lastCert.Load(); n != 0 {
+		last = time.Unix(0, n)
+	}
+	if silence > 0 && time.Since(last) > silence {
+		return fmt.Errorf("no certificate received for %s", time.Since(last).Round(time.Second))
+	}
+	return nil
+}
+
+func (a *certActivity) health(silence time.Duration) certstreamHealth {
+	h := certstreamHealth{Status: "ok", Started: a.started.UTC(), Certificates: a.certs.Load(), Matches: a.matches.Load()}
+	if n := a.lastCert.Load(); n != 0 {
+		h.LastCertificate = time.Unix(0, n).UTC()
+	}
+	if n := a.lastMatch.Load(); n != 0 {
+		h.LastMatch = time.Unix(0, n).UTC()
+	}
+	if a.healthy(silence) != nil {
+		h.Status = "silent"
+	}
+	return h
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"squatrr/lib/czds"
+	"squatrr/lib/processor"
+	"syscall"
+	"time"
+)
+
+// zoneHit is one line of the czds new-registrations output.
+type zoneHit struct {
+	Base       string    `json:"base"`
+	Domain     string    `json:"domain"`
+	Strategy   string    `json:"strategy,omitempty"`
+	Likelihood float64   `json:"likelihood,omitempty"`
+	FirstSeen  time.Time `json:"first_seen"`
+}
+
+// runCZDS downloads approved zone files and reports permutations that newly
+// appear in them since the previous run.
+func runCZDS(args []string) {
+	fs := flag.NewFlagSet("czds", flag.ExitOnError)
+	var (
+		username = fs.String("username", os.Getenv("CZDS_USERNAME"), "ICANN account username (default $CZDS_USERNAME)")
+		password = fs.String("password", os.Getenv("CZDS_PASSWORD"), "ICANN account password (default $CZDS_PASSWORD)")
+		dir      = fs.String("dir", "zones", "Directory zone files are downloaded to and matched from")
+		download = fs.Bool("download", true, "Download the account's approved zones before matching")
+		only     = fs.String("only", "", "Comma-separated TLDs to download (default: every approved zone)")
+		domains  = fs.String("domain", "", "Comma-separated base domains whose permutations are matched")
+		tlds     = fs.String("tlds", "", "Comma-separated TLD variants (default: each base domain's own TLD)")
+		seenFile = fs.String("seen", "czds-seen.json", "State file of permutations already seen in zones")
+		outfile  = fs.String("out", "", "Append newly registered permutations as JSON lines to this file")
+		logLevel = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *download {
+		if err := os.MkdirAll(*dir, 0o755); err != nil {
+			log.Fatal(err)
+		}
+		c := &czds.Client{Username: *username, Password: *password}
+		if err := c.Authenticate(ctx); err != nil {
+			logger.Error("authenticating with czds", "error", err)
+			os.Exit(1)
+		}
+		links, err := c.Links(ctx)
+		if err != nil {
+			logger.Error("listing czds zones", "error", err)
+			os.Exit(1)
+		}
+		want := parseList(*only)
+		for _, link := range links {
+			if len(want) > 0 && !slices.Contains(want, czds.TLDOf(link)) {
+				continue
+			}
+			path, err := c.Download(ctx, link, *dir)
+			if err != nil {
+				logger.Error("downloading zone", "link", link, "error", err)
+				continue
+			}
+			logger.Info("downloaded zone", "tld", czds.TLDOf(link), "path", path)
+		}
+	}
+
+	bases := parseList(*domains)
+	if len(bases) == 0 {
+		return
+	}
+	seen, err := loadSeen(*seenFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	zones := czds.Zones{Dir: *dir}
+	var fresh []zoneHit
+	now := time.Now().UTC()
+	for _, base := range bases {
+		candidates, err := processor.Candidates(base, parseTLDs(base, *tlds), nil, nil, nil, logger)
+		if err != nil {
+			logger.Error("processing candidates", "domain", base, "error", err)
+			os.Exit(2)
+		}
+		registered, uncovered, err := zones.Delegated(candidates)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(uncovered) > 0 {
+			logger.Warn("skipping TLDs without a zone file", "domain", base, "tlds", uncovered)
+		}
+		for _, c := range registered {
+			if _, ok := seen[c.Domain]; ok {
+				continue
+			}
+			hit := zoneHit{Base: base, Domain: c.Domain, Strategy: c.Strategy, Likelihood: c.Likelihood, FirstSeen: now}
+			fresh = append(fresh, hit)
+			seen[c.Domain] = now
+			logger.Warn("permutation newly registered", "domain", c.Domain, "base", base, "strategy", c.Strategy)
+		}
+		logger.Info("matched zones", "domain", base, "candidates", len(candidates), "registered", len(registered))
+	}
+
+	if *outfile != "" && len(fresh) > 0 {
+		f, err := os.OpenFile(*outfile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		enc := json.NewEncoder(f)
+		for _, h := range fresh {
+			if err := enc.Encode(h); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := saveSeen(*seenFile, seen); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadSeen reads the domain -> first seen state; a missing file is empty.
+func loadSeen(path string) (map[string]time.Time, error) {
+	seen := map[string]time.Time{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return seen, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return seen, json.Unmarshal(data, &seen)
+}
+
+// saveSeen writes the state; encoding/json sorts map keys so the file diffs cleanly.
+func saveSeen(path string, seen map[string]time.Time) error {
+	data, err := json.MarshalIndent(seen, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"squatrr/lib/archive"
+	"squatrr/lib/classify"
+	"squatrr/lib/config"
+	"squatrr/lib/encrypt"
+	"squatrr/lib/evidence"
+	"squatrr/lib/processor"
+	"squatrr/lib/score"
+	"squatrr/lib/verify"
+	"syscall"
+	"time"
+)
+
+// runEvidence collects a chain-of-custody evidence bundle for each selected
+// candidate: DNS snapshot, RDAP, certificate chain, screenshot and HAR.
+// With -base, candidate screenshots are compared with the base domain's.
+func runEvidence(args []string) {
+	fs := flag.NewFlagSet("evidence", flag.ExitOnError)
+	var (
+		domains    = fs.String("domain", "", "Comma-separated candidate domains to collect evidence for")
+		base       = fs.String("base", "", "The brand domain the candidates imitate (recorded in the manifest and used for scoring)")
+		outDir     = fs.String("out", "evidence", "Directory the bundles are written to")
+		screenshot = fs.Bool("screenshot", true, "Capture a screenshot with a headless Chromium-family browser")
+		browser    = fs.String("browser", evidence.FindBrowser(), "Headless-capable browser used for screenshots")
+		rdapBase   = fs.String("rdap-base", verify.DefaultRDAPBase, "RDAP bootstrap URL")
+		encryptTo  = fs.String("encrypt-to", "", "Comma-separated age public keys (age1...) or OpenPGP public key files the bundles are encrypted to")
+		cfgFile    = fs.String("config", "", "Optional JSON config file whose scoring rubric grades the candidates")
+		logLevel   = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	candidates := parseList(*domains)
+	if len(candidates) == 0 {
+		logger.Error("error: -domain is required")
+		os.Exit(2)
+	}
+	var rubric *score.Rubric
+	if *cfgFile != "" {
+		cfg, err := config.Load(*cfgFile)
+		if err != nil {
+			logger.Error("loading config", "error", err)
+			os.Exit(2)
+		}
+		rubric = cfg.Rubric
+	}
+	recipients, err := encrypt.Parse(parseList(*encryptTo))
+	if err != nil {
+		logger.Error("error: -encrypt-to", "error", err)
+		os.Exit(2)
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		logger.Error("creating evidence directory", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var ref *baseShot
+	if *screenshot && *base != "" {
+		if ref, err = screenshotBase(ctx, *base, *browser); err != nil {
+			logger.Warn("screenshotting base domain", "base", *base, "error", err)
+		}
+	}
+
+	failed := false
+	for _, d := range candidates {
+		if ctx.Err() != nil {
+			break
+		}
+		path, err := collectEvidence(ctx, d, *base, *outDir, *rdapBase, *screenshot, *browser, ref, recipients, rubric, logger)
+		if err != nil {
+			logger.Error("collecting evidence", "domain", d, "error", err)
+			failed = true
+			continue
+		}
+		logger.Info("evidence bundle written", "domain", d, "path", path)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// baseShot is the base domain's screenshot, with its perceptual hash.
+type baseShot struct {
+	png    []byte
+	hash   uint64
+	at     time.Time
+	source string
+}
+
+func screenshotBase(ctx context.Context, base, browser string) (*baseShot, error) {
+	target := "https://" + base + "/"
+	ctx, cancel := context.WithTimeout(ctx, 45*time.Second)
+	defer cancel()
+	at := time.Now()
+	png, err := evidence.Screenshot(ctx, browser, target)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := evidence.PHashPNG(png)
+	if err != nil {
+		return nil, err
+	}
+	return &baseShot{png: png, hash: hash, at: at, source: "headless " + filepath.Base(browser) + " render of " + target}, nil
+}
+
+// collectEvidence verifies domain and writes its bundle. With ref, the
+// candidate's screenshot is compared with the base domain's by perceptual
+// hash, catching pixel-level clones whatever HTML renders them.
+func collectEvidence(ctx context.Context, domain, base, outDir, rdapBase string, screenshot bool, browser string, ref *baseShot, recipients *encrypt.Recipients, rubric *score.Rubric, logger *slog.Logger) (string, error) {
+	har, err := os.MkdirTemp("", "sasquat-evidence-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(har)
+	archiver, err := archive.New(har, archive.FormatHAR, logger)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := verify.Config{
+		DNSTimeout:          5 * time.Second,
+		TLSTimeout:          10 * time.Second,
+		HTTPTimeout:         20 * time.Second,
+		DoTLS:               true,
+		DoHTTP:              true,
+		FetchBody:           true,
+		HTTPFollowRedirects: true,
+		DoASN:               true,
+		DoRDAP:              true,
+		RDAPBase:            rdapBase,
+		KeepRaw:             true,
+		Archive:             archiver,
+		UserAgent:           "saskquat-verifier/1.0",
+		BodyInspector:       classify.Default,
+		Registrars:          classify.Default,
+	}
+
+	started := time.Now()
+	out, err := processor.Evaluate(ctx, base, processor.Candidate{Domain: domain}, cfg, nil, rubric)
+	if err != nil {
+		return "", err
+	}
+	b := evidence.New(out.Domain, base)
+
+	dns, _ := json.MarshalIndent(out.DNS, "", "  ")
+	b.Add("dns.json", dns, started, "recursive DNS (system resolver); ASN via Team Cymru")
+
+	if out.RDAP != nil && len(out.RDAP.Raw) > 0 {
+		for i, raw := range out.RDAP.Raw {
+			name, source := "rdap.json", "RDAP "+rdapBase+"/domain/"+out.Domain
+			if i > 0 {
+				name, source = fmt.Sprintf("rdap-registrar-%d.json", i), "RDAP registrar server (linked from the registry answer)"
+			}
+			b.Add(name, raw, started, source)
+		}
+	} else {
+		b.Note("rdap: no registration data returned")
+	}
+
+	if out.TLS != nil && out.TLS.ChainPEM != "" {
+		b.Add("cert-chain.pem", []byte(out.TLS.ChainPEM), started, "TLS handshake to "+out.Domain+":443 with SNI")
+	} else {
+		b.Note("certificate: no TLS handshake on :443")
+	}
+
+	if out.HTTP != nil && out.HTTP.ArchivePath != "" {
+		if data, err := os.ReadFile(out.HTTP.ArchivePath); err == nil {
+			b.Add("http.har", data, started, "HTTP GET "+out.HTTP.URL+" following redirects")
+		}
+	} else {
+		b.Note("har: no HTTP response")
+	}
+
+	if screenshot {
+		target := "https://" + out.Domain + "/"
+		if out.HTTP != nil && out.HTTP.URL != "" {
+			target = out.HTTP.URL
+		}
+		shotCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
+		shotAt := time.Now()
+		png, err := evidence.Screenshot(shotCtx, browser, target)
+		cancel()
+		if err != nil {
+			b.Note("screenshot: %v", err)
+		} else {
+			b.Add("screenshot.png", png, shotAt, "headless "+filepath.Base(browser)+" render of "+target)
+			compareScreenshot(b, &out, png, ref, logger)
+		}
+	}
+
+	// The verification itself, minus the raw artifacts already bundled.
+	if out.RDAP != nil {
+		out.RDAP.Raw = nil
+	}
+	if out.TLS != nil {
+		out.TLS.ChainPEM = ""
+	}
+	summary, _ := json.MarshalIndent(out, "", "  ")
+	b.Add("verification.json", summary, started, "sasquat verification, score and landing-page class")
+
+	var zipped bytes.Buffer
+	manifestSum, err := b.Write(&zipped, time.Now())
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(outDir, out.Domain+"-"+time.Now().UTC().Format("20060102T150405Z")+".zip")
+	sum := sha256.Sum256(zipped.Bytes())
+	bundleSum := hex.EncodeToString(sum[:])
+	// The checksum stays that of the zip, so it verifies the bundle once
+	// decrypted, whoever it was encrypted to.
+	if err := os.WriteFile(path+".sha256", []byte(bundleSum+"  "+filepath.Base(path)+"\n"), 0o644); err != nil {
+		return "", err
+	}
+	data := zipped.Bytes()
+	if recipients != nil {
+		if data, err = recipients.Bytes(data); err != nil {
+			return "", err
+		}
+		path += recipients.Ext()
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	logger.Debug("processing evidence collectEvidence", "domain", out.Domain, "files", len(b.Manifest.Files), "manifest_sha256", manifestSum, "bundle_sha256", bundleSum)
+	return path, nil
+}
+
+// compareScreenshot sets out's visual similarity to the base domain's
+// screenshot, bundling that screenshot next to the candidate's.
+func compareScreenshot(b *evidence.Bundle, out *processor.Output, png []byte, ref *baseShot, logger *slog.Logger) {
+	if ref == nil {
+		if b.Manifest.Base != "" {
+			b.Note("visual similarity: no screenshot of %s", b.Manifest.Base)
+		}
+		return
+	}
+	hash, err := evidence.PHashPNG(png)
+	if err != nil {
+		b.Note("visual similarity: %v", err)
+		return
+	}
+	b.Add("base-screenshot.png", ref.png, ref.at, ref.source)
+	out.VisualSimilarity = evidence.Similarity(hash, ref.hash)
+	if out.VisualSimilarity >= evidence.CloneSimilarity {
+		logger.Warn("visual clone of base", "domain", out.Domain, "base", b.Manifest.Base, "visual_similarity", out.VisualSimilarity)
+	}
+}
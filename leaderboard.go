@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"squatrr/lib/history"
+	"squatrr/lib/processor"
+)
+
+// runLeaderboard writes the leaderboards of a history's currently live
+// flagged candidates, one file per base domain and one across all of them,
+// for the site's index views.
+func runLeaderboard(args []string) {
+	fs := flag.NewFlagSet("leaderboard", flag.ExitOnError)
+	var (
+		histPath  = fs.String("history", "history.db", "Run history file written by scans with -history")
+		outDir    = fs.String("out", "site/data/leaderboards", "Directory the <base>.json and all.json leaderboards are written to")
+		highScore = fs.Int("high-score", processor.DefaultHighScore, "Score from which a live candidate counts as flagged, whatever its class")
+		logLevel  = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	hist, err := history.Open(*histPath, true)
+	if err != nil {
+		logger.Error("opening history", "path", *histPath, "error", err)
+		os.Exit(2)
+	}
+	defer hist.Close()
+	bases, err := hist.Bases()
+	if err != nil {
+		logger.Error("listing bases", "error", err)
+		os.Exit(1)
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		logger.Error("creating leaderboard directory", "error", err)
+		os.Exit(1)
+	}
+
+	write := func(name, base string) {
+		board, err := leaderboardOf(hist, base, *highScore, logger)
+		if err == nil {
+			var data []byte
+			if data, err = json.MarshalIndent(board, "", "  "); err == nil {
+				err = os.WriteFile(filepath.Join(*outDir, name), append(data, '\n'), 0o644)
+			}
+		}
+		if err != nil {
+			logger.Error("writing leaderboard", "base", base, "error", err)
+			os.Exit(1)
+		}
+		logger.Info("leaderboard written", "base", base, "flagged", board.Flagged, "path", filepath.Join(*outDir, name))
+	}
+	for _, base := range bases {
+		write(base+".json", base)
+	}
+	write("all.json", "")
+}
@@ -0,0 +1,72 @@
+package archive
+
+/*
+  This library writes the raw HTTP exchanges of a candidate's probe to disk
+  as evidence of exactly what was served at scan time, one timestamped file
+  per candidate: HAR 1.2 (browser devtools, HAR viewers) or WARC 1.1
+  (web-archive tooling such as pywb and warcio). It implements
+  verify.Archiver.
+*/
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"squatrr/lib/verify"
+	"strings"
+	"time"
+)
+
+// Formats accepted by New.
+const (
+	FormatHAR  = "har"
+	FormatWARC = "warc"
+)
+
+// Dir archives into a directory.
+type Dir struct {
+	path   string
+	format string
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+// New creates dir if needed and returns an archiver writing format files.
+func New(dir, format string, logger *slog.Logger) (*Dir, error) {
+	if format != FormatHAR && format != FormatWARC {
+		return nil, fmt.Errorf("unknown archive format %q (want har or warc)", format)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Dir{path: dir, format: format, logger: logger, now: time.Now}, nil
+}
+
+// Archive implements verify.Archiver. Files are named
+// <domain>-<UTC timestamp>.har or .warc.gz so rescans never overwrite
+// earlier evidence.
+func (d *Dir) Archive(domain string, exchanges []verify.Exchange) string {
+	if len(exchanges) == 0 {
+		return ""
+	}
+	stamp := d.now().UTC().Format("20060102T150405Z")
+	name := strings.ReplaceAll(domain, "/", "_") + "-" + stamp
+	var (
+		path string
+		err  error
+	)
+	switch d.format {
+	case FormatWARC:
+		path = filepath.Join(d.path, name+".warc.gz")
+		err = writeWARC(path, exchanges, d.now())
+	default:
+		path = filepath.Join(d.path, name+".har")
+		err = writeHAR(path, exchanges)
+	}
+	if err != nil {
+		d.logger.Warn("processing archive Archive", "domain", domain, "path", path, "error", err)
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,87 @@
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"squatrr/lib/verify"
+)
+
+func testExchanges() []verify.Exchange {
+	started := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	return []verify.Exchange{
+		{Started: started, Duration: 40 * time.Millisecond, Method: "GET", URL: "https://examp1e.com/", Proto: "HTTP/1.1",
+			Header: http.Header{"User-Agent": {"sasquat-verifier/1.0"}}, Status: "302 Found", StatusCode: 302, ResponseProto: "HTTP/1.1",
+			ResponseHeader: http.Header{"Location": {"/login?next=1"}}, RemoteAddr: "192.0.2.10"},
+		{Started: started.Add(time.Second), Method: "GET", URL: "https://examp1e.com/login?next=1", Proto: "HTTP/1.1",
+			Status: "200 OK", StatusCode: 200, ResponseProto: "HTTP/1.1",
+			ResponseHeader: http.Header{"Content-Type": {"text/html"}}, Body: []byte("<title>Sign in</title>"), RemoteAddr: "192.0.2.10"},
+		{Started: started.Add(2 * time.Second), Method: "GET", URL: "https://examp1e.com/favicon.ico", Proto: "HTTP/1.1", Err: "connection reset"},
+	}
+}
+
+func newTestDir(t *testing.T, format string) *Dir {
+	d, err := New(t.TempDir(), format, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 5, 0, time.UTC) }
+	return d
+}
+
+func TestArchiveHAR(t *testing.T) {
+	d := newTestDir(t, FormatHAR)
+	path := d.Archive("examp1e.com", testExchanges())
+	if !strings.HasSuffix(path, "examp1e.com-20261016T090005Z.har") {
+		t.Fatalf("Archive() = %q", path)
+	}
+	data, _ := os.ReadFile(path)
+	var h harLog
+	if err := json.Unmarshal(data, &h); err != nil {
+		t.Fatal(err)
+	}
+	e := h.Log.Entries
+	if len(e) != 3 || e[0].Response.RedirectURL != "/login?next=1" || e[1].Response.StatusText != "OK" ||
+		e[1].Response.Content.Text != "<title>Sign in</title>" || e[1].Request.QueryString[0].Name != "next" || e[2].Comment != "connection reset" {
+		t.Errorf("HAR entries = %+v", e)
+	}
+}
+
+func TestArchiveWARC(t *testing.T) {
+	d := newTestDir(t, FormatWARC)
+	path := d.Archive("examp1e.com", testExchanges())
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f) // reads every member
+	if err != nil {
+		t.Fatal(err)
+	}
+	var types []string
+	sc := bufio.NewScanner(zr)
+	for sc.Scan() {
+		if v, ok := strings.CutPrefix(sc.Text(), "WARC-Type: "); ok {
+			types = append(types, v)
+		}
+	}
+	want := "warcinfo response request response request request"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("WARC record types = %q, want %q", got, want)
+	}
+}
+
+func TestNewUnknownFormat(t *testing.T) {
+	if _, err := New(t.TempDir(), "pcap", slog.Default()); err == nil {
+		t.Error("New(pcap) error = nil, want error")
+	}
+}
@@ -0,0 +1,169 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"squatrr/lib/verify"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harContent struct {
+	Size        int    `json:"size"`
+	Compression int    `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+	Comment     string `json:"comment,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []any          `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []any          `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harEntry struct {
+	StartedDateTime string         `json:"startedDateTime"`
+	Time            float64        `json:"time"`
+	Request         harRequest     `json:"request"`
+	Response        harResponse    `json:"response"`
+	Cache           struct{}       `json:"cache"`
+	Timings         map[string]any `json:"timings"`
+	Comment         string         `json:"comment,omitempty"`
+}
+
+type harLog struct {
+	Log struct {
+		Version string         `json:"version"`
+		Creator map[string]any `json:"creator"`
+		Entries []harEntry     `json:"entries"`
+	} `json:"log"`
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	out := []harNameValue{}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			out = append(out, harNameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
+
+func harQuery(raw string) []harNameValue {
+	out := []harNameValue{}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return out
+	}
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			out = append(out, harNameValue{Name: k, Value: v})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// harBody decodes a gzip-encoded body for content.text, as HAR expects, and
+// base64-encodes anything that isn't UTF-8.
+func harBody(ex verify.Exchange) harContent {
+	c := harContent{Size: len(ex.Body), MimeType: ex.ResponseHeader.Get("Content-Type")}
+	body := ex.Body
+	if strings.EqualFold(ex.ResponseHeader.Get("Content-Encoding"), "gzip") {
+		if zr, err := gzip.NewReader(bytes.NewReader(body)); err == nil {
+			if plain, err := io.ReadAll(io.LimitReader(zr, 16<<20)); err == nil || len(plain) > 0 {
+				c.Size, c.Compression = len(plain), len(plain)-len(body)
+				body = plain
+			}
+		}
+	}
+	if mt, _, _ := mime.ParseMediaType(c.MimeType); utf8.Valid(body) || strings.HasPrefix(mt, "text/") {
+		c.Text = string(body)
+	} else {
+		c.Text, c.Encoding = base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	if ex.BodyTruncated {
+		c.Comment = "body truncated at the scanner's size cap"
+	}
+	return c
+}
+
+func writeHAR(path string, exchanges []verify.Exchange) error {
+	var h harLog
+	h.Log.Version = "1.2"
+	h.Log.Creator = map[string]any{"name": "sasquat", "version": "1.0"}
+	h.Log.Entries = []harEntry{}
+	for _, ex := range exchanges {
+		e := harEntry{
+			StartedDateTime: ex.Started.UTC().Format("2006-01-02T15:04:05.000Z07:00"),
+			Time:            float64(ex.Duration.Microseconds()) / 1000,
+			Request: harRequest{
+				Method: ex.Method, URL: ex.URL, HTTPVersion: ex.Proto, Cookies: []any{},
+				Headers: harHeaders(ex.Header), QueryString: harQuery(ex.URL), HeadersSize: -1,
+			},
+			Response: harResponse{
+				Status: ex.StatusCode, StatusText: strings.TrimSpace(strings.TrimPrefix(ex.Status, strconv.Itoa(ex.StatusCode))),
+				HTTPVersion: ex.ResponseProto, Cookies: []any{}, Headers: harHeaders(ex.ResponseHeader),
+				RedirectURL: ex.ResponseHeader.Get("Location"), HeadersSize: -1, BodySize: len(ex.Body),
+			},
+			Timings: map[string]any{"send": 0, "wait": float64(ex.Duration.Microseconds()) / 1000, "receive": 0},
+			Comment: ex.Err,
+		}
+		if ex.ResponseHeader != nil {
+			e.Response.Content = harBody(ex)
+		} else {
+			e.Response.Headers, e.Response.BodySize = []harNameValue{}, -1
+		}
+		h.Log.Entries = append(h.Log.Entries, e)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(h); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,135 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"squatrr/lib/verify"
+	"time"
+)
+
+// writeWARC writes a warcinfo record followed by a request/response record
+// pair per exchange, each record its own gzip member as the spec recommends.
+func writeWARC(path string, exchanges []verify.Exchange, now time.Time) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	info := []byte("software: sasquat\r\nformat: WARC File Format 1.1\r\nconformsTo: http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n")
+	err = writeRecord(f, map[string]string{
+		"WARC-Type":     "warcinfo",
+		"WARC-Date":     now.UTC().Format(time.RFC3339),
+		"WARC-Filename": filepath.Base(path),
+		"Content-Type":  "application/warc-fields",
+	}, info)
+
+	for _, ex := range exchanges {
+		if err != nil {
+			break
+		}
+		date := ex.Started.UTC().Format(time.RFC3339)
+		respID := recordID()
+		if ex.ResponseHeader != nil {
+			err = writeRecord(f, map[string]string{
+				"WARC-Type":       "response",
+				"WARC-Record-ID":  respID,
+				"WARC-Date":       date,
+				"WARC-Target-URI": ex.URL,
+				"WARC-IP-Address": ex.RemoteAddr,
+				"WARC-Truncated":  truncated(ex.BodyTruncated),
+				"Content-Type":    "application/http;msgtype=response",
+			}, rawResponse(ex))
+			if err != nil {
+				break
+			}
+		}
+		err = writeRecord(f, map[string]string{
+			"WARC-Type":          "request",
+			"WARC-Date":          date,
+			"WARC-Target-URI":    ex.URL,
+			"WARC-Concurrent-To": concurrent(ex.ResponseHeader != nil, respID),
+			"Content-Type":       "application/http;msgtype=request",
+		}, rawRequest(ex))
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeRecord writes one gzip-compressed WARC record. Empty header values
+// are omitted; a Record-ID is generated when none is given.
+func writeRecord(f *os.File, fields map[string]string, block []byte) error {
+	if fields["WARC-Record-ID"] == "" {
+		fields["WARC-Record-ID"] = recordID()
+	}
+	sum := sha256.Sum256(block)
+	var b bytes.Buffer
+	b.WriteString("WARC/1.1\r\n")
+	for _, k := range []string{"WARC-Type", "WARC-Record-ID", "WARC-Date", "WARC-Filename", "WARC-Target-URI", "WARC-IP-Address", "WARC-Concurrent-To", "WARC-Truncated", "Content-Type"} {
+		if v := fields[k]; v != "" {
+			fmt.Fprintf(&b, "%s: %s\r\n", k, v)
+		}
+	}
+	fmt.Fprintf(&b, "WARC-Block-Digest: sha256:%s\r\n", base32.StdEncoding.EncodeToString(sum[:]))
+	fmt.Fprintf(&b, "Content-Length: %d\r\n\r\n", len(block))
+	b.Write(block)
+	b.WriteString("\r\n\r\n")
+
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(b.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func rawRequest(ex verify.Exchange) []byte {
+	var b bytes.Buffer
+	target, host := ex.URL, ""
+	if u, err := url.Parse(ex.URL); err == nil {
+		target, host = u.RequestURI(), u.Host
+	}
+	fmt.Fprintf(&b, "%s %s %s\r\nHost: %s\r\n", ex.Method, target, ex.Proto, host)
+	_ = ex.Header.Write(&b)
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func rawResponse(ex verify.Exchange) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s %s\r\n", ex.ResponseProto, ex.Status)
+	_ = http.Header(ex.ResponseHeader).Write(&b)
+	b.WriteString("\r\n")
+	b.Write(ex.Body)
+	return b.Bytes()
+}
+
+func recordID() string {
+	var u [16]byte
+	_, _ = rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40 // version 4
+	u[8] = u[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+}
+
+func truncated(t bool) string {
+	if t {
+		return "length"
+	}
+	return ""
+}
+
+func concurrent(ok bool, id string) string {
+	if ok {
+		return id
+	}
+	return ""
+}
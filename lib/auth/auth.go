@@ -0,0 +1,184 @@
+package auth
+
+/*
+  This library guards the serve-mode API with bearer tokens, each granted
+  one scope: read (the viewer, dashboards and exports), triage (read, plus
+  case state, assignee and note changes, and scan submission) or admin
+  (everything). Tokens are listed in a JSON file (-tokens):
+
+	[
+	  {"name": "wallboard", "token": "…", "scope": "read"},
+	  {"name": "dana", "token": "…", "scope": "triage"}
+	]
+
+  Browsers can't attach a header to a page load, so a shared dashboard link
+  may carry ?token=…; the token is moved into a cookie and the link
+  redirected without it.
+*/
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Scopes, each granting the ones before it.
+const (
+	ScopeRead   = "read"
+	ScopeTriage = "triage"
+	ScopeAdmin  = "admin"
+)
+
+// Scopes lists the scopes from least to most privileged.
+var Scopes = []string{ScopeRead, ScopeTriage, ScopeAdmin}
+
+// CookieName is the cookie a ?token= link is exchanged for.
+const CookieName = "sasquat_token"
+
+// minTokenLen rejects tokens short enough to guess.
+const minTokenLen = 16
+
+// Token is one API token.
+type Token struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+	Scope string `json:"scope"`
+}
+
+// Tokens is a set of API tokens. A nil *Tokens grants every request admin,
+// as serve mode behaves without -tokens.
+type Tokens struct {
+	tokens []Token
+}
+
+// Parse decodes and validates a token file.
+func Parse(data []byte) (*Tokens, error) {
+	var tokens []Token
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("no tokens")
+	}
+	seen := map[string]bool{}
+	for _, t := range tokens {
+		if t.Name == "" {
+			return nil, errors.New("token without a name")
+		}
+		if !slices.Contains(Scopes, t.Scope) {
+			return nil, fmt.Errorf("token %q: scope %q is not one of %s", t.Name, t.Scope, strings.Join(Scopes, ", "))
+		}
+		if len(t.Token) < minTokenLen {
+			return nil, fmt.Errorf("token %q: shorter than %d characters", t.Name, minTokenLen)
+		}
+		if seen[t.Token] {
+			return nil, fmt.Errorf("token %q: reused", t.Name)
+		}
+		seen[t.Token] = true
+	}
+	return &Tokens{tokens: tokens}, nil
+}
+
+// Load reads the token file at path; an empty path is nil (no auth).
+func Load(path string) (*Tokens, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Allows reports whether scope grants need.
+func Allows(scope, need string) bool {
+	have, want := slices.Index(Scopes, scope), slices.Index(Scopes, need)
+	return have >= 0 && want >= 0 && have >= want
+}
+
+// lookup finds the token presented as secret, comparing against every
+// token in constant time.
+func (ts *Tokens) lookup(secret string) (Token, bool) {
+	var found Token
+	ok := false
+	for _, t := range ts.tokens {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(t.Token)) == 1 {
+			found, ok = t, true
+		}
+	}
+	return found, ok
+}
+
+// Authenticate returns the token r presents: a bearer Authorization header
+// or the token cookie.
+func (ts *Tokens) Authenticate(r *http.Request) (Token, bool) {
+	if ts == nil {
+		return Token{Name: "anonymous", Scope: ScopeAdmin}, true
+	}
+	if secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return ts.lookup(secret)
+	}
+	if c, err := r.Cookie(CookieName); err == nil {
+		return ts.lookup(c.Value)
+	}
+	return Token{}, false
+}
+
+// Require serves h to requests whose token grants need; others get 401
+// without a valid token and 403 with one of too little scope.
+func (ts *Tokens) Require(need string, h http.Handler) http.Handler {
+	if ts == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t, ok := ts.Authenticate(r)
+		switch {
+		case !ok:
+			w.Header().Set("WWW-Authenticate", `Bearer realm="sasquat"`)
+			http.Error(w, "missing or unknown API token", http.StatusUnauthorized)
+		case !Allows(t.Scope, need):
+			http.Error(w, "token "+t.Name+" lacks the "+need+" scope", http.StatusForbidden)
+		default:
+			h.ServeHTTP(w, r)
+		}
+	})
+}
+
+// Links exchanges a valid ?token= on a GET for the token cookie, sent back
+// only below path, redirecting to the same URL without it, before passing
+// requests on to h.
+func (ts *Tokens) Links(path string, h http.Handler) http.Handler {
+	if ts == nil {
+		return h
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		secret := q.Get("token")
+		if r.Method != http.MethodGet || secret == "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := ts.lookup(secret); !ok {
+			http.Error(w, "unknown API token", http.StatusUnauthorized)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{
+			Name: CookieName, Value: secret, Path: path,
+			HttpOnly: true, Secure: r.TLS != nil, SameSite: http.SameSiteStrictMode,
+		})
+		q.Del("token")
+		u := *r.URL
+		u.RawQuery = q.Encode()
+		http.Redirect(w, r, u.RequestURI(), http.StatusSeeOther)
+	})
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	readSecret   = "read-0123456789abcdef"
+	triageSecret = "triage-0123456789abcdef"
+)
+
+func testTokens(t *testing.T) *Tokens {
+	t.Helper()
+	ts, err := Parse([]byte(`[{"name":"wallboard","token":"` + readSecret + `","scope":"read"},{"name":"dana","token":"` + triageSecret + `","scope":"triage"}]`))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	return ts
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr bool
+	}{
+		{`[{"name":"a","token":"0123456789abcdef","scope":"admin"}]`, false},
+		{`[]`, true},
+		{`[{"token":"0123456789abcdef","scope":"read"}]`, true},
+		{`[{"name":"a","token":"0123456789abcdef","scope":"write"}]`, true},
+		{`[{"name":"a","token":"short","scope":"read"}]`, true},
+		{`[{"name":"a","token":"0123456789abcdef","scope":"read"},{"name":"b","token":"0123456789abcdef","scope":"admin"}]`, true},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.data)); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		scope, need string
+		want        bool
+	}{
+		{ScopeRead, ScopeRead, true},
+		{ScopeRead, ScopeTriage, false},
+		{ScopeTriage, ScopeRead, true},
+		{ScopeTriage, ScopeAdmin, false},
+		{ScopeAdmin, ScopeTriage, true},
+		{"", ScopeRead, false},
+	}
+	for _, tt := range tests {
+		if got := Allows(tt.scope, tt.need); got != tt.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", tt.scope, tt.need, got, tt.want)
+		}
+	}
+}
+
+func TestRequire(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+	h := testTokens(t).Require(ScopeTriage, ok)
+	tests := []struct {
+		name   string
+		auth   string
+		cookie string
+		want   int
+	}{
+		{"no token", "", "", http.StatusUnauthorized},
+		{"unknown token", "Bearer nope-0123456789abcdef", "", http.StatusUnauthorized},
+		{"read scope", "Bearer " + readSecret, "", http.StatusForbidden},
+		{"triage scope", "Bearer " + triageSecret, "", http.StatusOK},
+		{"triage cookie", "", triageSecret, http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/api/state", nil)
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		if tt.cookie != "" {
+			r.AddCookie(&http.Cookie{Name: CookieName, Value: tt.cookie})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	var none *Tokens
+	w := httptest.NewRecorder()
+	none.Require(ScopeAdmin, ok).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("without tokens: status = %d, want open", w.Code)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	h := testTokens(t).Links("/w/acme/", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/w/acme/home.html?base=example.com&token="+readSecret, nil))
+	cookies := w.Result().Cookies()
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/w/acme/home.html?base=example.com" {
+		t.Errorf("link = %d to %q, want a redirect without the token", w.Code, w.Header().Get("Location"))
+	}
+	if len(cookies) != 1 || cookies[0].Name != CookieName || cookies[0].Value != readSecret || !cookies[0].HttpOnly || cookies[0].Path != "/w/acme/" {
+		t.Errorf("cookies = %v, want the HttpOnly token cookie of /w/acme/", cookies)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?token=nope", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unknown link token: status = %d, want 401", w.Code)
+	}
+}
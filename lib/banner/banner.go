@@ -56,7 +56,7 @@ func PrintBanner() {
 
 	title := "Sasquat.rr"
 	// A compact ASCII block that renders well in most terminals.
-	
+
 	art := []string{
 		"███████╗ █████╗ ███████╗ ██████╗ ██╗   ██╗ █████╗ ████████╗",
 		"██╔════╝██╔══██╗██╔════╝██╔═══██╗██║   ██║██╔══██╗╚══██╔══╝",
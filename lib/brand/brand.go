@@ -0,0 +1,273 @@
+package brand
+
+/*
+  This library recognizes a brand's own assets (logo images, copyright
+  lines, support phone numbers) in content fetched from candidates, for the
+  -brand-assets flag. A candidate showing them trades on the brand whatever
+  its name looks like, which catches combosquats such as
+  acme-rewards.com or secure-login-help.net that no permutation of the
+  brand's domain produces. It implements verify.AssetMatcher.
+
+  An assets file is JSON:
+
+	{"logos": [{"name": "acme", "file": "acme-logo.png"},
+	           {"name": "acme-favicon", "sha256": "9f86d081884c7d65..."}],
+	 "copyright": ["Acme Corporation"],
+	 "phones": ["+1 800 555 0199"]}
+
+  A logo's file, relative to the assets file, is hashed when it is loaded;
+  "sha256" and "phash" (16 hex digits, see evidence.PHash) give the hashes
+  directly. An image matches a logo on its SHA-256, or on its perceptual
+  hash to within evidence.CloneSimilarity, so re-encoded and resized copies
+  match too. Copyright lines match case-insensitively, whatever the
+  whitespace and HTML entities between their words. Phone numbers match on
+  their digits, whatever the punctuation, with or without the country code
+  or a trunk 0.
+*/
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"squatrr/lib/evidence"
+	"strconv"
+	"strings"
+)
+
+// maxImageSide caps the width and height of images decoded for their
+// perceptual hash: a few bytes of header can declare a picture that takes
+// gigabytes to decode.
+const maxImageSide = 4096
+
+// minPhoneDigits is the fewest digits, after any trunk 0, a number found
+// on a page must share with a configured one.
+const minPhoneDigits = 7
+
+// Logo is one of the brand's images.
+type Logo struct {
+	Name   string `json:"name"`
+	File   string `json:"file,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	PHash  string `json:"phash,omitempty"`
+
+	phash uint64
+}
+
+// Assets is a parsed assets file.
+type Assets struct {
+	Logos     []Logo   `json:"logos,omitempty"`
+	Copyright []string `json:"copyright,omitempty"`
+	Phones    []string `json:"phones,omitempty"`
+
+	copyright []string // normalized
+	phones    []string // digits
+	digest    string
+}
+
+// Parse decodes and validates an assets file, reading logo files relative
+// to dir.
+func Parse(data []byte, dir string) (*Assets, error) {
+	var a Assets
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, err
+	}
+	if len(a.Logos) == 0 && len(a.Copyright) == 0 && len(a.Phones) == 0 {
+		return nil, errors.New("no assets")
+	}
+	for i := range a.Logos {
+		l := &a.Logos[i]
+		if l.Name == "" {
+			return nil, fmt.Errorf("logo %d: missing name", i)
+		}
+		if err := l.load(dir); err != nil {
+			return nil, fmt.Errorf("logo %s: %w", l.Name, err)
+		}
+	}
+	for _, c := range a.Copyright {
+		if c = normalize(c); c == "" {
+			return nil, errors.New("empty copyright line")
+		}
+		a.copyright = append(a.copyright, c)
+	}
+	for _, p := range a.Phones {
+		d := phoneDigits(p)
+		if len(d) < minPhoneDigits {
+			return nil, fmt.Errorf("phone %q: fewer than %d digits", p, minPhoneDigits)
+		}
+		a.phones = append(a.phones, d)
+	}
+	// The logos' hashes stand for their files' content.
+	resolved, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(resolved)
+	a.digest = hex.EncodeToString(sum[:6])
+	return &a, nil
+}
+
+// Load reads and parses the assets file at path.
+func Load(path string) (*Assets, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := Parse(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, nil
+}
+
+// load hashes the logo's file, if any, and checks its hashes.
+func (l *Logo) load(dir string) error {
+	if l.File != "" {
+		path := l.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		l.SHA256 = hex.EncodeToString(sum[:])
+		img, err := decodeImage(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", l.File, err)
+		}
+		l.phash = evidence.PHash(img)
+		l.PHash = fmt.Sprintf("%016x", l.phash)
+	} else if l.PHash != "" {
+		h, err := strconv.ParseUint(l.PHash, 16, 64)
+		if err != nil || len(l.PHash) != 16 {
+			return fmt.Errorf("phash %q: want 16 hex digits", l.PHash)
+		}
+		l.phash = h
+	}
+	l.SHA256 = strings.ToLower(l.SHA256)
+	if l.SHA256 == "" && l.PHash == "" {
+		return errors.New("needs a file, sha256 or phash")
+	}
+	if _, err := hex.DecodeString(l.SHA256); err != nil || (l.SHA256 != "" && len(l.SHA256) != 64) {
+		return fmt.Errorf("sha256 %q: want 64 hex digits", l.SHA256)
+	}
+	return nil
+}
+
+// decodeImage decodes a GIF, JPEG or PNG no larger than maxImageSide
+// either way.
+func decodeImage(data []byte) (image.Image, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width > maxImageSide || cfg.Height > maxImageSide {
+		return nil, fmt.Errorf("image of %dx%d exceeds %dx%d", cfg.Width, cfg.Height, maxImageSide, maxImageSide)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// Digest implements verify.AssetMatcher: a short hash of the assets,
+// logo files included.
+func (a *Assets) Digest() string { return a.digest }
+
+// HasLogos implements verify.AssetMatcher.
+func (a *Assets) HasLogos() bool { return len(a.Logos) > 0 }
+
+// MatchImage implements verify.AssetMatcher, returning "logo:<name>" for
+// each logo data is a copy of.
+func (a *Assets) MatchImage(data []byte) []string {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	var hash uint64
+	var decoded, ok bool
+	var out []string
+	for _, l := range a.Logos {
+		match := l.SHA256 == digest
+		if !match && l.PHash != "" {
+			if !decoded {
+				decoded = true
+				if img, err := decodeImage(data); err == nil {
+					hash, ok = evidence.PHash(img), true
+				}
+			}
+			match = ok && evidence.Similarity(hash, l.phash) >= evidence.CloneSimilarity
+		}
+		if match {
+			out = append(out, "logo:"+l.Name)
+		}
+	}
+	return out
+}
+
+// phoneRe finds runs that may be phone numbers: digits with the usual
+// separators, optionally led by + or (.
+var phoneRe = regexp.MustCompile(`[+(]?\d[\d ().\-/]{5,}\d`)
+
+// MatchPage implements verify.AssetMatcher, returning "copyright:<line>"
+// and "phone:<number>", as configured, for each found in body.
+func (a *Assets) MatchPage(body []byte) []string {
+	if len(body) == 0 {
+		return nil
+	}
+	var out []string
+	text := normalize(string(body))
+	for i, c := range a.copyright {
+		if strings.Contains(text, c) {
+			out = append(out, "copyright:"+a.Copyright[i])
+		}
+	}
+	if len(a.phones) == 0 {
+		return out
+	}
+	var found []string
+	for _, m := range phoneRe.FindAllString(text, -1) {
+		found = append(found, phoneDigits(m))
+	}
+	for i, p := range a.phones {
+		if slices.ContainsFunc(found, func(f string) bool { return samePhone(f, p) }) {
+			out = append(out, "phone:"+a.Phones[i])
+		}
+	}
+	return out
+}
+
+// normalize lowercases s, decodes HTML entities and collapses whitespace
+// (including non-breaking spaces) to single spaces.
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(html.UnescapeString(s))), " ")
+}
+
+// phoneDigits keeps the digits of a phone number, without trunk 0s.
+func phoneDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimLeft(b.String(), "0")
+}
+
+// samePhone reports whether two numbers' digits are the same number, one
+// possibly written with a country code the other lacks.
+func samePhone(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return len(a) >= minPhoneDigits && strings.HasSuffix(b, a)
+}
@@ -0,0 +1,174 @@
+package brand
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// logo draws a w x h wordmark: a badge on the left and three bars of
+// "lettering" of rising height, or the mirror image. Nothing in it is
+// symmetric, which would leave half the hash bits to rounding noise.
+func logo(w, h int, mirrored bool) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill := func(x0, y0, x1, y1 float64, c color.Color) {
+		if mirrored {
+			x0, x1 = 1-x1, 1-x0
+		}
+		r := image.Rect(int(x0*float64(w)), int(y0*float64(h)), int(x1*float64(w)), int(y1*float64(h)))
+		draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	fill(0, 0, 1, 1, color.White)
+	fill(0.05, 0.05, 0.3, 0.7, color.RGBA{200, 30, 30, 255})
+	for i := range 3 {
+		x := 0.4 + 0.18*float64(i)
+		fill(x, 0.35, x+0.12, 0.55+0.1*float64(i), color.RGBA{20, 20, 20, 255})
+	}
+	return img
+}
+
+func encode(t *testing.T, img image.Image, jpg bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	if jpg {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 70})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMatchImage(t *testing.T) {
+	dir := t.TempDir()
+	original := encode(t, logo(400, 160, false), false)
+	if err := os.WriteFile(filepath.Join(dir, "acme.png"), original, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "assets.json")
+	if err := os.WriteFile(path, []byte(`{"logos": [{"name": "acme", "file": "acme.png"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"same file", original, []string{"logo:acme"}},
+		{"resized JPEG", encode(t, logo(180, 72, false), true), []string{"logo:acme"}},
+		{"different image", encode(t, logo(400, 160, true), false), nil},
+		{"not an image", []byte("<html>not found</html>"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.MatchImage(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// pngHeader is a PNG declaring a w x h picture and holding no pixels.
+func pngHeader(w, h uint32) []byte {
+	chunk := func(typ string, data []byte) []byte {
+		out := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+		out = append(append(out, typ...), data...)
+		return binary.BigEndian.AppendUint32(out, crc32.ChecksumIEEE(append([]byte(typ), data...)))
+	}
+	ihdr := binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint32(nil, w), h)
+	ihdr = append(ihdr, 8, 6, 0, 0, 0) // 8-bit RGBA
+	out := append([]byte("\x89PNG\r\n\x1a\n"), chunk("IHDR", ihdr)...)
+	return append(out, chunk("IEND", nil)...)
+}
+
+func TestMatchImageBomb(t *testing.T) {
+	a, err := Parse([]byte(`{"logos": [{"name": "acme", "phash": "0123456789abcdef"}]}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := decodeImage(pngHeader(100000, 100000)); err == nil {
+		t.Error("decodeImage() of a 100000x100000 PNG succeeded")
+	}
+	if got := a.MatchImage(pngHeader(100000, 100000)); got != nil {
+		t.Errorf("MatchImage() = %v, want nil", got)
+	}
+}
+
+func TestDigest(t *testing.T) {
+	parse := func(data string) string {
+		a, err := Parse([]byte(data), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a.Digest()
+	}
+	one, same := parse(`{"phones": ["+1 800 555 0199"]}`), parse(`{"phones": ["+1 800 555 0199"]}`)
+	other := parse(`{"phones": ["+1 800 555 0100"]}`)
+	if one == "" || one != same || one == other {
+		t.Errorf("Digest() = %q, %q, %q; want equal for equal assets only", one, same, other)
+	}
+}
+
+func TestMatchPage(t *testing.T) {
+	a, err := Parse([]byte(`{"copyright": ["Acme Corporation"], "phones": ["+1 800 555 0199", "+44 20 7946 0958"]}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"copyright across entities", `<footer>&copy; 2025 ACME&nbsp;Corporation.</footer>`, []string{"copyright:Acme Corporation"}},
+		{"national format", `Call us: (800) 555-0199`, []string{"phone:+1 800 555 0199"}},
+		{"trunk zero", `<a href="tel:020-7946-0958">020 7946 0958</a>`, []string{"phone:+44 20 7946 0958"}},
+		{"other numbers", `Order 8005550100 placed 2025-01-15`, nil},
+		{"empty", ``, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.MatchPage([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatchPage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no assets", `{}`},
+		{"logo without name", `{"logos": [{"sha256": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"}]}`},
+		{"logo without hashes", `{"logos": [{"name": "acme"}]}`},
+		{"bad sha256", `{"logos": [{"name": "acme", "sha256": "9f86"}]}`},
+		{"bad phash", `{"logos": [{"name": "acme", "phash": "xyz"}]}`},
+		{"missing file", `{"logos": [{"name": "acme", "file": "nope.png"}]}`},
+		{"short phone", `{"phones": ["555-01"]}`},
+		{"empty copyright", `{"copyright": [" "]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data), t.TempDir()); err == nil {
+				t.Errorf("Parse(%s) succeeded", tt.data)
+			}
+		})
+	}
+}
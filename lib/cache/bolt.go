@@ -0,0 +1,111 @@
+package cache
+
+/*
+  This library persists verification results across runs so scheduled
+  monitoring scans only re-probe stale or changed candidates. Entries are
+  stored per stage (DNS, TLS, HTTP) keyed by domain and expire by stage TTL.
+*/
+
+import (
+	"encoding/json"
+	"log/slog"
+	"squatrr/lib/verify"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// TTLs maps a verification stage to how long its entries stay fresh.
+// HTTP variants ("http+body", ...) use the StageHTTP TTL.
+type TTLs map[string]time.Duration
+
+func (t TTLs) forStage(stage string) time.Duration {
+	if ttl, ok := t[stage]; ok {
+		return ttl
+	}
+	base, _, _ := strings.Cut(stage, "+")
+	return t[base]
+}
+
+type entry struct {
+	Stored time.Time       `json:"t"`
+	Value  json.RawMessage `json:"v"`
+}
+
+func encodeEntry(v any, stored time.Time) ([]byte, error) {
+	val, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(entry{Stored: stored.UTC(), Value: val})
+}
+
+// decodeEntry unpacks raw into v, returning when it was stored.
+func decodeEntry(raw []byte, v any) (time.Time, bool) {
+	var e entry
+	if json.Unmarshal(raw, &e) != nil || json.Unmarshal(e.Value, v) != nil {
+		return time.Time{}, false
+	}
+	return e.Stored, true
+}
+
+// Bolt is a verify.Cache backed by a single BoltDB file.
+type Bolt struct {
+	db     *bolt.DB
+	ttls   TTLs
+	now    func() time.Time
+	logger *slog.Logger
+}
+
+var _ verify.Cache = (*Bolt)(nil)
+
+// OpenBolt opens (or creates) the cache file at path.
+func OpenBolt(path string, ttls TTLs, logger *slog.Logger) (*Bolt, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &Bolt{db: db, ttls: ttls, now: time.Now, logger: logger}, nil
+}
+
+func (b *Bolt) Get(stage, domain string, v any) (found, fresh bool) {
+	var stored time.Time
+	_ = b.db.View(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte(stage))
+		if bkt == nil {
+			return nil
+		}
+		if raw := bkt.Get([]byte(domain)); raw != nil {
+			stored, found = decodeEntry(raw, v)
+		}
+		return nil
+	})
+	if !found {
+		return false, false
+	}
+	return true, b.now().Sub(stored) < b.ttls.forStage(stage)
+}
+
+// Put stores v for stage/domain. Writes from concurrent workers are coalesced
+// into shared transactions so caching doesn't serialize the scan on fsync.
+func (b *Bolt) Put(stage, domain string, v any) {
+	raw, err := encodeEntry(v, b.now())
+	if err != nil {
+		return
+	}
+	err = b.db.Batch(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists([]byte(stage))
+		if err != nil {
+			return err
+		}
+		return bkt.Put([]byte(domain), raw)
+	})
+	if err != nil {
+		b.logger.Warn("processing cache put", "stage", stage, "domain", domain, "error", err)
+	}
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
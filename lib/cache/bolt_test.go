@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"io"
+	"log/slog"
+	"path/filepath"
+	"squatrr/lib/verify"
+	"testing"
+	"time"
+)
+
+func TestBolt(t *testing.T) {
+	c, err := OpenBolt(filepath.Join(t.TempDir(), "cache.db"), TTLs{verify.StageDNS: time.Hour, verify.StageHTTP: time.Minute},
+		slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("OpenBolt() error: %v", err)
+	}
+	defer c.Close()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	var got verify.DNSResult
+	if found, fresh := c.Get(verify.StageDNS, "exampel.com", &got); found || fresh {
+		t.Fatalf("Get() on empty cache = %v, %v, want false, false", found, fresh)
+	}
+
+	c.Put(verify.StageDNS, "exampel.com", verify.DNSResult{HasA: true, A: []string{"203.0.113.10"}})
+	c.Put("http+body", "exampel.com", verify.HTTPResult{StatusCode: 200})
+
+	now = now.Add(30 * time.Minute)
+	if found, fresh := c.Get(verify.StageDNS, "exampel.com", &got); !found || !fresh || got.A[0] != "203.0.113.10" {
+		t.Errorf("Get() within TTL = %v, %v, %+v, want fresh entry", found, fresh, got)
+	}
+
+	var hr verify.HTTPResult
+	if found, fresh := c.Get("http+body", "exampel.com", &hr); !found || fresh || hr.StatusCode != 200 {
+		t.Errorf("Get() past variant TTL = %v, %v, %+v, want stale entry", found, fresh, hr)
+	}
+}
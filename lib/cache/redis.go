@@ -0,0 +1,163 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"squatrr/lib/verify"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisTimeout  = 5 * time.Second
+	redisMaxIdle  = 32
+	redisKeyspace = "squatrr:"
+
+	// staleRetention is how long entries outlive their TTL in Redis: stale
+	// DNS answers are still compared against fresh ones to spot hosting
+	// changes.
+	staleRetention = 7 * 24 * time.Hour
+
+	// Once Redis fails, commands fail fast for redisBackoff, doubling on
+	// each failed retry up to redisMaxBackoff, instead of every lookup
+	// waiting out a dial.
+	redisBackoff    = time.Second
+	redisMaxBackoff = time.Minute
+)
+
+// errRedisDown is returned without contacting Redis while it is backed off.
+var errRedisDown = errors.New("redis unavailable, backing off")
+
+// Redis is a verify.Cache shared through a Redis server, so concurrent
+// scanner instances and repeated CI runs reuse each other's lookups.
+// Entries are stored as "squatrr:<stage>:<domain>" and expire from Redis
+// a week after going stale. While the server is unreachable the cache is
+// bypassed, one command retrying it after each backoff.
+type Redis struct {
+	client *redis.Client
+	addr   string
+	ttls   TTLs
+	now    func() time.Time
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	backoff time.Duration // pause after the last failure; 0 while healthy
+	retryAt time.Time     // when one command may try the server again
+}
+
+var _ verify.Cache = (*Redis)(nil)
+
+// OpenRedis connects to rawURL (redis://[user:password@]host[:port][/db],
+// or rediss:// for TLS) and checks the server answers.
+func OpenRedis(rawURL string, ttls TTLs, logger *slog.Logger) (*Redis, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout = redisTimeout, redisTimeout, redisTimeout
+	opts.MaxIdleConns = redisMaxIdle
+	// The backoff takes the place of the client's own retries: a lookup
+	// failing over to the network is cheaper than one waiting on Redis.
+	opts.MaxRetries = -1
+	r := &Redis{client: redis.NewClient(opts), addr: opts.Addr, ttls: ttls, now: time.Now, logger: logger}
+	if _, err := r.do("PING"); err != nil {
+		r.client.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// do runs one command, unless Redis is backed off. Error replies, such as
+// a missing key's, reached the server and don't back off.
+func (r *Redis) do(args ...any) (any, error) {
+	if !r.available() {
+		return nil, errRedisDown
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	reply, err := r.client.Do(ctx, args...).Result()
+	var isReply redis.Error
+	r.record(err == nil || errors.As(err, &isReply), err)
+	return reply, err
+}
+
+// available reports whether a command may be sent: always while healthy,
+// and once backed off to a single caller per backoff period.
+func (r *Redis) available() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.backoff == 0 {
+		return true
+	}
+	now := r.now()
+	if now.Before(r.retryAt) {
+		return false
+	}
+	r.retryAt = now.Add(r.backoff) // the others wait while this one tries
+	return true
+}
+
+// record updates the backoff after a command reached the server (ok) or
+// failed with err.
+func (r *Redis) record(ok bool, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ok {
+		if r.backoff > 0 {
+			r.logger.Info("redis reachable again, using the cache", "addr", r.addr)
+			r.backoff = 0
+		}
+		return
+	}
+	if r.backoff == 0 {
+		r.logger.Warn("redis unreachable, bypassing the cache", "addr", r.addr, "error", err)
+		r.backoff = redisBackoff
+	} else {
+		r.backoff = min(2*r.backoff, redisMaxBackoff)
+	}
+	r.retryAt = r.now().Add(r.backoff)
+}
+
+func redisKey(stage, domain string) string {
+	return redisKeyspace + stage + ":" + domain
+}
+
+func (r *Redis) Get(stage, domain string, v any) (found, fresh bool) {
+	reply, err := r.do("GET", redisKey(stage, domain))
+	if errors.Is(err, redis.Nil) {
+		return false, false
+	}
+	if err != nil {
+		r.logger.Debug("processing cache get", "stage", stage, "domain", domain, "error", err)
+		return false, false
+	}
+	raw, ok := reply.(string)
+	if !ok {
+		return false, false
+	}
+	stored, ok := decodeEntry([]byte(raw), v)
+	if !ok {
+		return false, false
+	}
+	return true, r.now().Sub(stored) < r.ttls.forStage(stage)
+}
+
+// Put stores v for stage/domain.
+func (r *Redis) Put(stage, domain string, v any) {
+	raw, err := encodeEntry(v, r.now())
+	if err != nil {
+		return
+	}
+	expire := r.ttls.forStage(stage) + staleRetention
+	if _, err := r.do("SET", redisKey(stage, domain), string(raw), "PX", strconv.FormatInt(expire.Milliseconds(), 10)); err != nil {
+		r.logger.Warn("processing cache put", "stage", stage, "domain", domain, "error", err)
+	}
+}
+
+func (r *Redis) Close() error {
+	return r.client.Close()
+}
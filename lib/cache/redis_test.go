@@ -0,0 +1,204 @@
+package cache
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"squatrr/lib/verify"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis answers AUTH, SELECT, PING, GET and SET (ignoring expiry) from
+// a map, recording the commands it saw; anything else, HELLO included, is
+// an unknown command. While down, it hangs up on every command instead.
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+	cmds [][]string
+	down bool
+}
+
+func (f *fakeRedis) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		var n int
+		if _, err := fmt.Fscanf(r, "*%d\r\n", &n); err != nil {
+			return
+		}
+		args := make([]string, n)
+		for i := range args {
+			var size int
+			if _, err := fmt.Fscanf(r, "$%d\r\n", &size); err != nil {
+				return
+			}
+			buf := make([]byte, size+2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return
+			}
+			args[i] = string(buf[:size])
+		}
+		f.mu.Lock()
+		if f.down {
+			f.mu.Unlock()
+			return
+		}
+		args[0] = strings.ToUpper(args[0])
+		f.cmds = append(f.cmds, args)
+		var reply string
+		switch args[0] {
+		case "AUTH":
+			if args[len(args)-1] == "secret" {
+				reply = "+OK\r\n"
+			} else {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case "SELECT", "SET":
+			if args[0] == "SET" {
+				f.data[args[1]] = args[2]
+			}
+			reply = "+OK\r\n"
+		case "PING":
+			reply = "+PONG\r\n"
+		case "GET":
+			if v, ok := f.data[args[1]]; ok {
+				reply = "$" + strconv.Itoa(len(v)) + "\r\n" + v + "\r\n"
+			} else {
+				reply = "$-1\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+		if _, err := io.WriteString(c, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) setDown(down bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.down = down
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	f := &fakeRedis{data: map[string]string{}}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(c)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func TestRedis(t *testing.T) {
+	f, addr := startFakeRedis(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ttls := TTLs{verify.StageDNS: time.Hour, verify.StageHTTP: time.Minute}
+
+	if _, err := OpenRedis("redis://:wrong@"+addr, ttls, logger); err == nil {
+		t.Fatal("OpenRedis() with a wrong password succeeded")
+	}
+	c, err := OpenRedis("redis://:secret@"+addr+"/2", ttls, logger)
+	if err != nil {
+		t.Fatalf("OpenRedis() error: %v", err)
+	}
+	defer c.Close()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	var got verify.DNSResult
+	if found, fresh := c.Get(verify.StageDNS, "exampel.com", &got); found || fresh {
+		t.Fatalf("Get() on empty cache = %v, %v, want false, false", found, fresh)
+	}
+
+	c.Put(verify.StageDNS, "exampel.com", verify.DNSResult{HasA: true, A: []string{"203.0.113.10"}})
+	c.Put("http+body", "exampel.com", verify.HTTPResult{StatusCode: 200})
+
+	// A second scanner on the same server sees the first one's entries.
+	other, err := OpenRedis("redis://:secret@"+addr+"/2", ttls, logger)
+	if err != nil {
+		t.Fatalf("OpenRedis() error: %v", err)
+	}
+	defer other.Close()
+	other.now = func() time.Time { return now.Add(30 * time.Minute) }
+
+	if found, fresh := other.Get(verify.StageDNS, "exampel.com", &got); !found || !fresh || got.A[0] != "203.0.113.10" {
+		t.Errorf("Get() within TTL = %v, %v, %+v, want fresh entry", found, fresh, got)
+	}
+	var hr verify.HTTPResult
+	if found, fresh := other.Get("http+body", "exampel.com", &hr); !found || fresh || hr.StatusCode != 200 {
+		t.Errorf("Get() past variant TTL = %v, %v, %+v, want stale entry", found, fresh, hr)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var set []string
+	for _, cmd := range f.cmds {
+		if cmd[0] == "SET" && cmd[1] == "squatrr:dns:exampel.com" {
+			set = cmd
+		}
+	}
+	want := strconv.FormatInt((time.Hour + staleRetention).Milliseconds(), 10)
+	if len(set) != 5 || set[3] != "PX" || set[4] != want {
+		t.Errorf("SET command = %q, want key expiring after PX %s", set, want)
+	}
+}
+
+func TestRedisBackoff(t *testing.T) {
+	f, addr := startFakeRedis(t)
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := OpenRedis("redis://"+addr, TTLs{}, logger)
+	if err != nil {
+		t.Fatalf("OpenRedis() error: %v", err)
+	}
+	defer c.Close()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	// The server goes away: pooled and new connections fail.
+	f.setDown(true)
+	if _, err := c.do("PING"); err == nil || err == errRedisDown {
+		t.Fatalf("do() on a down server = %v, want the connection error", err)
+	}
+	if _, err := c.do("PING"); err != errRedisDown {
+		t.Errorf("do() while backed off = %v, want %v", err, errRedisDown)
+	}
+
+	// One retry per period, which doubles while the server stays down.
+	now = now.Add(redisBackoff)
+	if _, err := c.do("PING"); err == errRedisDown {
+		t.Error("do() after the backoff didn't retry")
+	}
+	if c.backoff != 2*redisBackoff {
+		t.Errorf("backoff = %v, want %v", c.backoff, 2*redisBackoff)
+	}
+
+	// Back up: the next retry resets it.
+	f.setDown(false)
+	now = now.Add(2 * redisBackoff)
+	if _, err := c.do("PING"); err != nil {
+		t.Fatalf("do() once the server is back = %v", err)
+	}
+	if _, err := c.do("PING"); err != nil || c.backoff != 0 {
+		t.Errorf("do() after recovery = %v (backoff %v), want no backoff", err, c.backoff)
+	}
+}
@@ -0,0 +1,184 @@
+package certstream
+
+/*
+  This library watches Certificate Transparency in near real time. It
+  subscribes to a certstream-compatible websocket feed and matches every
+  newly logged certificate against precomputed permutation sets, so a squat
+  is flagged minutes after it gets its first certificate instead of at the
+  next scheduled scan.
+*/
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"squatrr/lib/processor"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultURL is the public certstream server's full feed.
+const DefaultURL = "wss://certstream.calidog.io/"
+
+// Cert is the part of a certstream certificate_update we match on.
+type Cert struct {
+	Domains     []string
+	Issuer      string
+	NotBefore   time.Time
+	Fingerprint string
+	Source      string // CT log name
+}
+
+// Match is a logged certificate naming a known permutation.
+type Match struct {
+	Base        string    `json:"base"`
+	Domain      string    `json:"domain"`      // the matched permutation
+	CertDomain  string    `json:"cert_domain"` // the name on the certificate
+	Strategy    string    `json:"strategy,omitempty"`
+	Likelihood  float64   `json:"likelihood,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	NotBefore   time.Time `json:"not_before"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Seen        time.Time `json:"seen"`
+}
+
+type entry struct {
+	base      string
+	candidate processor.Candidate
+}
+
+// Index maps permutation domains to the base they were generated from.
+type Index struct {
+	domains map[string]entry
+}
+
+func NewIndex() *Index {
+	return &Index{domains: map[string]entry{}}
+}
+
+// Add indexes the candidates of base. When several bases produce the same
+// permutation the most likely explanation is kept.
+func (idx *Index) Add(base string, candidates []processor.Candidate) {
+	for _, c := range candidates {
+		if prev, ok := idx.domains[c.Domain]; ok && prev.candidate.Likelihood >= c.Likelihood {
+			continue
+		}
+		idx.domains[c.Domain] = entry{base: base, candidate: c}
+	}
+}
+
+func (idx *Index) Len() int { return len(idx.domains) }
+
+// Match returns a Match for each certificate name that is, or is a subdomain
+// of, an indexed permutation. Wildcards are matched on their parent name.
+func (idx *Index) Match(c Cert) []Match {
+	var out []Match
+	seen := map[string]bool{}
+	for _, name := range c.Domains {
+		name = strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(name, "*."), "."))
+		for d := name; d != ""; {
+			if e, ok := idx.domains[d]; ok && !seen[d] {
+				seen[d] = true
+				out = append(out, Match{
+					Base:        e.base,
+					Domain:      d,
+					CertDomain:  name,
+					Strategy:    e.candidate.Strategy,
+					Likelihood:  e.candidate.Likelihood,
+					Issuer:      c.Issuer,
+					NotBefore:   c.NotBefore,
+					Fingerprint: c.Fingerprint,
+					Source:      c.Source,
+					Seen:        time.Now(),
+				})
+				break
+			}
+			_, rest, ok := strings.Cut(d, ".")
+			if !ok || !strings.Contains(rest, ".") { // stop at the TLD
+				break
+			}
+			d = rest
+		}
+	}
+	return out
+}
+
+// message is the certstream wire format; heartbeats and other message types
+// only carry message_type.
+type message struct {
+	Type string `json:"message_type"`
+	Data struct {
+		LeafCert struct {
+			AllDomains  []string `json:"all_domains"`
+			NotBefore   float64  `json:"not_before"`
+			Fingerprint string   `json:"fingerprint"`
+			Issuer      struct {
+				O  string `json:"O"`
+				CN string `json:"CN"`
+			} `json:"issuer"`
+		} `json:"leaf_cert"`
+		Source struct {
+			Name string `json:"name"`
+		} `json:"source"`
+	} `json:"data"`
+}
+
+// parseMessage decodes a certificate_update; other messages return ok=false.
+func parseMessage(data []byte) (Cert, bool) {
+	var m message
+	if err := json.Unmarshal(data, &m); err != nil || m.Type != "certificate_update" {
+		return Cert{}, false
+	}
+	leaf := m.Data.LeafCert
+	return Cert{
+		Domains:     leaf.AllDomains,
+		Issuer:      cmp.Or(leaf.Issuer.O, leaf.Issuer.CN),
+		NotBefore:   time.Unix(int64(leaf.NotBefore), 0).UTC(),
+		Fingerprint: leaf.Fingerprint,
+		Source:      m.Data.Source.Name,
+	}, true
+}
+
+// Watch streams certificates from url into fn until ctx is cancelled,
+// reconnecting with capped exponential backoff when the connection drops.
+func Watch(ctx context.Context, url string, logger *slog.Logger, fn func(Cert)) error {
+	backoff := time.Second
+	for {
+		err := watchOnce(ctx, url, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Warn("processing certstream reconnect Watch", "url", url, "error", err, "backoff", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		backoff = min(2*backoff, time.Minute)
+	}
+}
+
+func watchOnce(ctx context.Context, url string, fn func(Cert)) error {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Minute)) // the server heartbeats every ~30s
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		if c, ok := parseMessage(data); ok {
+			fn(c)
+		}
+	}
+}
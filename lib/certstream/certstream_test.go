@@ -0,0 +1,98 @@
+package certstream
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"squatrr/lib/processor"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const update = `{"message_type":"certificate_update","data":{"leaf_cert":{"all_domains":["*.login.exampel.com","exampel.com","unrelated.org"],"not_before":1717200000,"fingerprint":"AB:CD","issuer":{"O":"Let's Encrypt","CN":"R3"}},"source":{"name":"Google 'Argon2024' log"}}}`
+
+func TestParseMessage(t *testing.T) {
+	c, ok := parseMessage([]byte(update))
+	if !ok {
+		t.Fatalf("parseMessage() ok = false")
+	}
+	want := Cert{
+		Domains:     []string{"*.login.exampel.com", "exampel.com", "unrelated.org"},
+		Issuer:      "Let's Encrypt",
+		NotBefore:   time.Unix(1717200000, 0).UTC(),
+		Fingerprint: "AB:CD",
+		Source:      "Google 'Argon2024' log",
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("parseMessage() = %+v, want %+v", c, want)
+	}
+	if _, ok := parseMessage([]byte(`{"message_type":"heartbeat"}`)); ok {
+		t.Errorf("parseMessage(heartbeat) ok = true")
+	}
+}
+
+func TestIndexMatch(t *testing.T) {
+	idx := NewIndex()
+	idx.Add("example.com", []processor.Candidate{{Domain: "exampel.com", Strategy: "Transposition", Likelihood: 0.9}, {Domain: "exmaple.co", Likelihood: 0.4}})
+	idx.Add("other.com", []processor.Candidate{{Domain: "exampel.com", Likelihood: 0.1}})
+
+	tests := []struct {
+		domains []string
+		want    []string
+	}{
+		{[]string{"*.login.exampel.com", "exampel.com"}, []string{"exampel.com"}},
+		{[]string{"www.exmaple.co"}, []string{"exmaple.co"}},
+		{[]string{"example.com", "com", "exampel.co"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range idx.Match(Cert{Domains: tt.domains}) {
+			got = append(got, m.Domain)
+			if m.Domain == "exampel.com" && (m.Base != "example.com" || m.Strategy != "Transposition") {
+				t.Errorf("Match() kept %+v, want the most likely base", m)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Match(%v) = %v, want %v", tt.domains, got, tt.want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	var upgrader websocket.Upgrader
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"message_type":"heartbeat"}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(update))
+		time.Sleep(time.Second)
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := make(chan Cert, 1)
+	go Watch(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), slog.New(slog.NewTextHandler(io.Discard, nil)), func(c Cert) {
+		select {
+		case got <- c:
+		default:
+		}
+	})
+	select {
+	case c := <-got:
+		if c.Fingerprint != "AB:CD" {
+			t.Errorf("Watch() delivered %+v", c)
+		}
+	case <-ctx.Done():
+		t.Fatalf("Watch() delivered nothing")
+	}
+}
@@ -0,0 +1,206 @@
+package classify
+
+/*
+  This library labels live candidates by what their landing page appears to
+  be: a reverse proxy of the brand's site, phishing-like, parked, for sale, dormant, a redirect to the brand, or an
+  unrelated business. It combines content signals sampled during HTTP
+  verification (see verify.BodyInspector) with DNS and certificate features,
+  so a label is available even when bodies were not fetched.
+
+  Parking and for-sale signatures ship as a versioned data file
+  (signatures.json, compiled in as Default) that can be refreshed without a
+  new release; see Load and Parse.
+*/
+
+import (
+	"net/url"
+	"squatrr/lib/verify"
+	"strconv"
+	"strings"
+)
+
+// Labels, most urgent first.
+const (
+	LabelReverseProxy  = "reverse_proxy"
+	LabelPhishing      = "phishing"
+	LabelForSale       = "for_sale"
+	LabelParked        = "parked"
+	LabelBrandRedirect = "brand_redirect"
+	LabelDormant       = "dormant"
+	LabelUnrelated     = "unrelated"
+	LabelUnknown       = "unknown"
+)
+
+// Result is a candidate's label and the features that produced it.
+type Result struct {
+	Label   string
+	Reasons []string
+	Listing *Listing // set for LabelForSale when the lander was recognised
+}
+
+// Listing describes an aftermarket sale lander. Price is omitted when the
+// page doesn't show one (e.g. "make an offer" listings).
+type Listing struct {
+	Marketplace string `json:"marketplace,omitempty"`
+	Currency    string `json:"currency,omitempty"`
+	Price       int    `json:"price,omitempty"`
+}
+
+// minContentBytes is the body size under which a page without a title is
+// treated as a placeholder rather than a real site.
+const minContentBytes = 512
+
+// Record labels a verification of a candidate generated from base using the
+// built-in signature set.
+func Record(base string, v verify.Verification) Result {
+	return Default.Record(base, v)
+}
+
+// Record labels a verification of a candidate generated from base.
+func (set *Set) Record(base string, v verify.Verification) Result {
+	h := v.Site()
+	if h == nil {
+		h = &verify.HTTPResult{}
+	}
+	base = strings.ToLower(strings.TrimSuffix(base, "."))
+	brand, _, _ := strings.Cut(base, ".")
+	loc := strings.ToLower(h.Location)
+	title := strings.ToLower(h.Title)
+	via := set.redirector(h)
+
+	var r Result
+	hit := func(label string, reasons ...string) Result {
+		r.Label = label
+		r.Reasons = append(r.Reasons, reasons...)
+		if via != "" {
+			r.Reasons = append(r.Reasons, "redirector:"+via)
+		}
+		return r
+	}
+
+	// A transparent reverse proxy of the brand's own site (evilginx and
+	// the like): it relays real logins and captures the session cookies
+	// they earn, second factor included.
+	if verify.ReverseProxy(h.ProxyIndicators) {
+		reasons := make([]string, len(h.ProxyIndicators))
+		for i, ind := range h.ProxyIndicators {
+			reasons[i] = "proxy:" + ind
+		}
+		return hit(LabelReverseProxy, reasons...)
+	}
+
+	// Credential collection on a typo domain.
+	for _, s := range h.Signals {
+		if s == "password_field" || s == "login_form" {
+			return hit(LabelPhishing, "signal:"+s)
+		}
+	}
+	if base != "" && strings.Contains(loc, base) {
+		return hit(LabelBrandRedirect, "location:"+base)
+	}
+
+	if s := prefixed(h.Signals, LabelForSale+":"); s != "" {
+		r.Listing = listing(s, h.Signals)
+		return hit(LabelForSale, "signal:"+s)
+	}
+	if s := containsAny(loc, set.SaleHosts); s != "" {
+		r.Listing = listing(strings.TrimSuffix(s, ".com"), h.Signals)
+		return hit(LabelForSale, "location:"+s)
+	}
+	if strings.Contains(title, "for sale") {
+		return hit(LabelForSale, "title:for sale")
+	}
+
+	if s := prefixed(h.Signals, LabelParked+":"); s != "" {
+		return hit(LabelParked, "signal:"+s)
+	}
+	dnsHay := strings.ToLower(strings.Join(v.DNS.NS, " ") + " " + v.DNS.CNAME)
+	if s := containsAny(dnsHay, set.ParkingNameservers); s != "" {
+		return hit(LabelParked, "ns:"+s)
+	}
+	// A live page titled with the brand that isn't a sale or parking lander.
+	if brand != "" && strings.Contains(title, brand) {
+		return hit(LabelPhishing, "title_brand:"+brand)
+	}
+
+	// Bounced through a shortener or traffic redirector whose target can
+	// change per request: monetized parking rather than a page of its own.
+	if via != "" {
+		return hit(LabelParked)
+	}
+
+	switch {
+	case !v.DNS.HasA && !v.DNS.HasAAAA && (v.WWW == nil || !v.WWW.Only):
+		return hit(LabelDormant, "no_address")
+	case h.Attempted && h.StatusCode == 0:
+		return hit(LabelDormant, "http_unreachable")
+	case h.StatusCode >= 400:
+		return hit(LabelDormant, "http_error")
+	case h.BodySHA256 != "" && h.Title == "" && h.ContentLength < minContentBytes:
+		return hit(LabelDormant, "placeholder_page")
+	case h.StatusCode >= 200 && h.StatusCode < 300 && (h.Title != "" || h.ContentLength >= minContentBytes):
+		return hit(LabelUnrelated, "content")
+	case v.TLS != nil && v.TLS.Connected && !h.Attempted:
+		return hit(LabelUnknown, "tls_only")
+	}
+	return hit(LabelUnknown)
+}
+
+// listing builds a Listing for the marketplace, taking the asking price from
+// a "price:<currency>:<amount>" signal if there is one.
+func listing(marketplace string, signals []string) *Listing {
+	l := &Listing{Marketplace: marketplace}
+	if p := prefixed(signals, "price:"); p != "" {
+		cur, amount, _ := strings.Cut(p, ":")
+		if n, err := strconv.Atoi(amount); err == nil {
+			l.Currency, l.Price = cur, n
+		}
+	}
+	return l
+}
+
+// prefixed returns the first signal with the given prefix, without it.
+func prefixed(signals []string, prefix string) string {
+	for _, s := range signals {
+		if strings.HasPrefix(s, prefix) {
+			return strings.TrimPrefix(s, prefix)
+		}
+	}
+	return ""
+}
+
+// redirector returns the first redirector host the redirect chain passes
+// through or lands on, checking every hop after the candidate itself, the
+// Location and the final URL.
+func (set *Set) redirector(h *verify.HTTPResult) string {
+	urls := []string{h.Location, h.FinalURL}
+	for i, hop := range h.RedirectChain {
+		if i > 0 {
+			urls = append(urls, hop.URL)
+		}
+		urls = append(urls, hop.Location)
+	}
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		for _, r := range set.Redirectors {
+			if host == r || strings.HasSuffix(host, "."+r) {
+				return r
+			}
+		}
+	}
+	return ""
+}
+
+// containsAny returns the first needle found in hay.
+func containsAny(hay string, needles []string) string {
+	for _, n := range needles {
+		if strings.Contains(hay, n) {
+			return n
+		}
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+package classify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestSignaturesInspect(t *testing.T) {
+	body := []byte(`<html><title>exampel.com</title><p>This domain is FOR SALE</p><a href="https://www.afternic.com/x">Buy</a></html>`)
+	want := []string{"for_sale:afternic", "for_sale:for_sale"}
+	if got := Default.Inspect(body); !reflect.DeepEqual(got, want) {
+		t.Errorf("Inspect() = %v, want %v", got, want)
+	}
+}
+
+func TestExtractPrice(t *testing.T) {
+	tests := []struct {
+		body     string
+		currency string
+		amount   int
+		ok       bool
+	}{
+		{`<h1>exampel.com</h1><div class="price">Buy now: <b>$2,499</b> USD</div>`, "USD", 2499, true},
+		{`<p>Asking price</p><span>1.500 €</span>`, "EUR", 1500, true},
+		{`<p>Buy now for &pound;750.00</p>`, "GBP", 750, true},
+		{`<p>Since 1998, 30 million visitors</p><p>Make an offer</p>`, "", 0, false},
+		{`<p>Price on request</p>`, "", 0, false},
+	}
+	for _, tt := range tests {
+		cur, amount, ok := extractPrice([]byte(tt.body))
+		if cur != tt.currency || amount != tt.amount || ok != tt.ok {
+			t.Errorf("extractPrice(%q) = %v, %v, %v, want %v, %v, %v", tt.body, cur, amount, ok, tt.currency, tt.amount, tt.ok)
+		}
+	}
+}
+
+func TestRecordListing(t *testing.T) {
+	v := verify.Verification{
+		DNS:  verify.DNSResult{HasA: true},
+		HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Signals: Default.Inspect([]byte(`<a href="https://www.afternic.com/d">Buy now</a> for $3,000`))},
+	}
+	got := Record("example.com", v)
+	want := &Listing{Marketplace: "afternic", Currency: "USD", Price: 3000}
+	if got.Label != LabelForSale || !reflect.DeepEqual(got.Listing, want) {
+		t.Errorf("Record() = %v %+v, want %v %+v", got.Label, got.Listing, LabelForSale, want)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	live := verify.DNSResult{HasA: true, A: []string{"192.0.2.1"}}
+	tests := []struct {
+		name  string
+		v     verify.Verification
+		label string
+	}{
+		{"reverse proxy", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Signals: []string{"password_field"}, ProxyIndicators: []string{"headers", "title"}}}, LabelReverseProxy},
+		{"cloned title alone", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Signals: []string{"password_field"}, ProxyIndicators: []string{"title", "new_cert"}}}, LabelPhishing},
+		{"password form", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Signals: []string{"password_field"}}}, LabelPhishing},
+		{"brand title", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "Example - Sign in"}}, LabelPhishing},
+		{"brand redirect", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 301, Location: "https://www.example.com/"}}, LabelBrandRedirect},
+		{"sale signal before brand title", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "example.net", Signals: []string{"for_sale:dan"}}}, LabelForSale},
+		{"sale redirect", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 302, Location: "https://sedo.com/search/details/?domain=exampel.com"}}, LabelForSale},
+		{"parking ns", verify.Verification{DNS: verify.DNSResult{HasA: true, NS: []string{"ns1.parkingcrew.net."}}}, LabelParked},
+		{"mail only", verify.Verification{DNS: verify.DNSResult{HasMX: true, MX: []string{"mx.exampel.com."}}}, LabelDormant},
+		{"http error", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 503}}, LabelDormant},
+		{"placeholder", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, BodySHA256: "x", ContentLength: 40}}, LabelDormant},
+		{"unrelated business", verify.Verification{DNS: live, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "Joe's Bakery", ContentLength: 20000}}, LabelUnrelated},
+		{"dns only", verify.Verification{DNS: live}, LabelUnknown},
+		{"www-only login", verify.Verification{WWW: &verify.WWW{Resolvable: true, Only: true, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Signals: []string{"login_form"}}}}, LabelPhishing},
+		{"www-only business", verify.Verification{WWW: &verify.WWW{Resolvable: true, Only: true, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "Joe's Bakery", ContentLength: 20000}}}, LabelUnrelated},
+	}
+	for _, tt := range tests {
+		if got := Record("example.com", tt.v); got.Label != tt.label {
+			t.Errorf("%s: Record() = %v, want %v", tt.name, got, tt.label)
+		}
+	}
+}
+
+func TestRecordRedirector(t *testing.T) {
+	live := verify.DNSResult{HasA: true, A: []string{"192.0.2.1"}}
+	tests := []struct {
+		name    string
+		h       *verify.HTTPResult
+		label   string
+		reasons []string
+	}{
+		{
+			name:    "shortener location",
+			h:       &verify.HTTPResult{Attempted: true, StatusCode: 302, Location: "https://bit.ly/3xYz"},
+			label:   LabelParked,
+			reasons: []string{"redirector:bit.ly"},
+		},
+		{
+			name: "tracker mid-chain",
+			h: &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "Win a prize", FinalURL: "https://prize.example.net/",
+				RedirectChain: []verify.Hop{
+					{URL: "https://exampel.com/", Status: 302, Location: "https://trk.voluum.com/click?c=1"},
+					{URL: "https://trk.voluum.com/click?c=1", Status: 302, Location: "https://prize.example.net/"},
+					{URL: "https://prize.example.net/", Status: 200},
+				}},
+			label:   LabelParked,
+			reasons: []string{"redirector:voluum.com"},
+		},
+		{
+			name:    "tagged on a stronger label",
+			h:       &verify.HTTPResult{Attempted: true, StatusCode: 301, Location: "https://t.co/abc", Signals: []string{"password_field"}},
+			label:   LabelPhishing,
+			reasons: []string{"signal:password_field", "redirector:t.co"},
+		},
+		{
+			name: "brand page behind a shortener",
+			h: &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "Example - Sign in", FinalURL: "https://login.example.net/",
+				RedirectChain: []verify.Hop{
+					{URL: "https://exampel.com/", Status: 302, Location: "https://bit.ly/3xYz"},
+					{URL: "https://bit.ly/3xYz", Status: 301, Location: "https://login.example.net/"},
+					{URL: "https://login.example.net/", Status: 200},
+				}},
+			label:   LabelPhishing,
+			reasons: []string{"title_brand:example", "redirector:bit.ly"},
+		},
+		{
+			name:    "lookalike host",
+			h:       &verify.HTTPResult{Attempted: true, StatusCode: 302, Location: "https://t.com/"},
+			label:   LabelUnknown,
+			reasons: nil,
+		},
+	}
+	for _, tt := range tests {
+		got := Record("example.com", verify.Verification{DNS: live, HTTP: tt.h})
+		if got.Label != tt.label || !reflect.DeepEqual(got.Reasons, tt.reasons) {
+			t.Errorf("%s: Record() = %v %v, want %v %v", tt.name, got.Label, got.Reasons, tt.label, tt.reasons)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr bool
+	}{
+		{`{"version":"2026.10.16","signatures":[{"name":"x","label":"parked","phrase":"parked here"}]}`, false},
+		{`{"signatures":[{"name":"x","label":"parked","phrase":"parked here"}]}`, true},
+		{`{"version":"1","signatures":[]}`, true},
+		{`{"version":"1","signatures":[{"name":"x","label":"phishing","phrase":"p"}]}`, true},
+		{`{"version":"1","signatures":[{"name":"x","label":"parked"}]}`, true},
+		{`not json`, true},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.data)); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	if got, err := Load(filepath.Join(dir, "missing.json")); err != nil || got != Default {
+		t.Errorf("Load(missing) = %v, %v, want Default", got.Version, err)
+	}
+
+	newer := filepath.Join(dir, "newer.json")
+	os.WriteFile(newer, []byte(`{"version":"9999.01.01","signatures":[{"name":"x","label":"parked","phrase":"p"}]}`), 0o644)
+	if got, err := Load(newer); err != nil || got.Version != "9999.01.01" {
+		t.Errorf("Load(newer) = %v, %v, want 9999.01.01", got, err)
+	}
+
+	older := filepath.Join(dir, "older.json")
+	os.WriteFile(older, []byte(`{"version":"2000.01.01","signatures":[{"name":"x","label":"parked","phrase":"p"}]}`), 0o644)
+	if got, err := Load(older); err != nil || got != Default {
+		t.Errorf("Load(older) = %v, %v, want Default", got, err)
+	}
+}
+
+func TestRegistrarClass(t *testing.T) {
+	tests := []struct {
+		name, id string
+		want     string
+	}{
+		{"MarkMonitor Inc.", "292", RegistrarBrandProtection},
+		{"", "299", RegistrarBrandProtection},
+		{"Gname.com Pte. Ltd.", "1923", RegistrarAbuseFriendly},
+		{"NameCheap, Inc.", "1068", RegistrarBulk},
+		{"GoDaddy.com, LLC", "146", RegistrarRetail},
+		{"Some Small Registrar GmbH", "9999", RegistrarOther},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Default.RegistrarClass(tt.name, tt.id); got != tt.want {
+			t.Errorf("RegistrarClass(%q, %q) = %q, want %q", tt.name, tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package classify
+
+import "strings"
+
+// Registrar classes, from the feed's "registrars" rules.
+const (
+	RegistrarBrandProtection = "brand_protection" // corporate/defensive registrars; likely owned by a brand
+	RegistrarAbuseFriendly   = "abuse_friendly"   // over-represented in abuse reporting, slow to act
+	RegistrarBulk            = "bulk"             // low-cost, high-volume retail
+	RegistrarRetail          = "retail"
+	RegistrarOther           = "other" // known registrar without a rule
+)
+
+// RegistrarRule assigns a class to registrars matching any of its names
+// (case-insensitive substring of the RDAP registrar name) or IANA IDs.
+type RegistrarRule struct {
+	Class   string   `json:"class"`
+	Names   []string `json:"names"`
+	IANAIDs []string `json:"iana_ids,omitempty"`
+}
+
+// RegistrarClass implements verify.RegistrarClassifier. Rules are checked in
+// feed order, so more specific classes (brand protection) come first. It
+// returns "" when the registrar is unknown.
+func (set *Set) RegistrarClass(name, ianaID string) string {
+	if name == "" && ianaID == "" {
+		return ""
+	}
+	lower := strings.ToLower(name)
+	for _, rule := range set.Registrars {
+		for _, id := range rule.IANAIDs {
+			if ianaID != "" && id == ianaID {
+				return rule.Class
+			}
+		}
+		if lower != "" && containsAny(lower, rule.Names) != "" {
+			return rule.Class
+		}
+	}
+	return RegistrarOther
+}
@@ -0,0 +1,178 @@
+package classify
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Signature is a named body phrase that marks a parking or sale lander.
+type Signature struct {
+	Name   string `json:"name"`
+	Label  string `json:"label"`  // LabelParked or LabelForSale
+	Phrase string `json:"phrase"` // matched case-insensitively against the body sample
+}
+
+// Signatures is a list of body signatures. It implements verify.BodyInspector,
+// emitting "<label>:<name>" for every match and, on sale landers,
+// "price:<currency>:<amount>" when an asking price is shown.
+type Signatures []Signature
+
+// Inspect implements verify.BodyInspector.
+func (s Signatures) Inspect(body []byte) []string {
+	lower := bytes.ToLower(body)
+	var out []string
+	forSale := false
+	for _, sig := range s {
+		if bytes.Contains(lower, []byte(strings.ToLower(sig.Phrase))) {
+			out = append(out, sig.Label+":"+sig.Name)
+			forSale = forSale || sig.Label == LabelForSale
+		}
+	}
+	if forSale {
+		if cur, amount, ok := extractPrice(body); ok {
+			out = append(out, fmt.Sprintf("price:%s:%d", cur, amount))
+		}
+	}
+	return out
+}
+
+// Set is a versioned signature feed: body phrases plus the nameservers and
+// redirect hosts that mark parking and aftermarket landers on HEAD-only scans,
+// the URL shorteners and traffic redirectors that monetized typos bounce
+// visitors through, and the registrar classification rules.
+type Set struct {
+	Version            string          `json:"version"`
+	Signatures         Signatures      `json:"signatures"`
+	ParkingNameservers []string        `json:"parking_nameservers"` // matched against NS/CNAME
+	SaleHosts          []string        `json:"sale_hosts"`          // matched against the redirect Location
+	Redirectors        []string        `json:"redirectors"`         // hosts (and their subdomains) matched against every redirect hop
+	Registrars         []RegistrarRule `json:"registrars"`
+}
+
+// Inspect implements verify.BodyInspector.
+func (set *Set) Inspect(body []byte) []string {
+	return set.Signatures.Inspect(body)
+}
+
+//go:embed signatures.json
+var builtin []byte
+
+// Default is the signature set compiled into the binary.
+var Default = mustParse(builtin)
+
+// Parse decodes and validates a signature feed.
+func Parse(data []byte) (*Set, error) {
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if set.Version == "" {
+		return nil, errors.New("signature feed has no version")
+	}
+	if len(set.Signatures) == 0 {
+		return nil, errors.New("signature feed has no signatures")
+	}
+	for _, sig := range set.Signatures {
+		if sig.Label != LabelParked && sig.Label != LabelForSale {
+			return nil, fmt.Errorf("signature %q: unknown label %q", sig.Name, sig.Label)
+		}
+		if sig.Name == "" || sig.Phrase == "" {
+			return nil, fmt.Errorf("signature %q: name and phrase are required", sig.Name)
+		}
+	}
+	for _, rule := range set.Registrars {
+		switch rule.Class {
+		case RegistrarBrandProtection, RegistrarAbuseFriendly, RegistrarBulk, RegistrarRetail:
+		default:
+			return nil, fmt.Errorf("registrar rule: unknown class %q", rule.Class)
+		}
+	}
+	return &set, nil
+}
+
+func mustParse(data []byte) *Set {
+	set, err := Parse(data)
+	if err != nil {
+		panic("classify: built-in signatures: " + err.Error())
+	}
+	return set
+}
+
+// DefaultPath is where update-signatures stores the refreshed feed:
+// <user config dir>/sasquat/signatures.json.
+func DefaultPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "signatures.json"
+	}
+	return filepath.Join(dir, "sasquat", "signatures.json")
+}
+
+// Load reads the feed at path, falling back to Default when the file does
+// not exist or is older than the built-in set.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return Default, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if set.Version < Default.Version {
+		return Default, nil
+	}
+	return set, nil
+}
+
+var (
+	tagRe      = regexp.MustCompile(`(?s)<[^>]*>`)
+	priceCtx   = regexp.MustCompile(`(?i)price|buy now|for sale|asking|purchase`)
+	priceRe    = regexp.MustCompile(`(?i)(?:(usd|eur|gbp|us\$|\$|€|£)\s?(\d{1,3}(?:[,.\s]\d{3})+|\d+)(?:[.,]\d{2})?\b)|(?:\b(\d{1,3}(?:[,.\s]\d{3})+|\d+)(?:[.,]\d{2})?\s?((?:usd|eur|gbp)\b|€))`)
+	currencyOf = map[string]string{"usd": "USD", "us$": "USD", "$": "USD", "eur": "EUR", "€": "EUR", "gbp": "GBP", "£": "GBP"}
+)
+
+// priceWindow is how far after a price keyword ("buy now", "asking", ...) an
+// amount may appear and still be taken as the asking price.
+const priceWindow = 200
+
+// extractPrice finds the asking price on a sale lander, returning the ISO
+// currency and whole amount. Amounts are only taken shortly after a price
+// keyword so unrelated numbers on the page are ignored.
+func extractPrice(body []byte) (currency string, amount int, ok bool) {
+	text := html.UnescapeString(tagRe.ReplaceAllString(string(body), " "))
+	for _, kw := range priceCtx.FindAllStringIndex(text, -1) {
+		end := min(len(text), kw[1]+priceWindow)
+		m := priceRe.FindStringSubmatch(text[kw[1]:end])
+		if m == nil {
+			continue
+		}
+		sym, digits := m[1], m[2]
+		if sym == "" {
+			sym, digits = m[4], m[3]
+		}
+		n, err := strconv.Atoi(strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, digits))
+		if err != nil || n == 0 {
+			continue
+		}
+		return currencyOf[strings.ToLower(sym)], n, true
+	}
+	return "", 0, false
+}
@@ -0,0 +1,109 @@
+package clickhouse
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"strings"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	var queries []string
+	var inserted []row
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("query")
+		queries = append(queries, q)
+		if r.URL.Query().Get("database") != "research" {
+			http.Error(w, "wrong database", http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(q, "INSERT") {
+			return
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sc := bufio.NewScanner(gz)
+		sc.Buffer(nil, 1<<20)
+		for sc.Scan() {
+			var rw row
+			if err := json.Unmarshal(sc.Bytes(), &rw); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			inserted = append(inserted, rw)
+		}
+	}))
+	defer srv.Close()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	w, err := NewWriter(context.Background(), srv.URL+"/?database=research", "", "example.com", "run-1", 2, logger)
+	if err != nil {
+		t.Fatalf("NewWriter() error: %v", err)
+	}
+	for _, d := range []string{"exampel.com", "examp1e.com", "exarnple.com"} {
+		o := processor.Output{Domain: d, Resolvable: true, DNS: verify.DNSResult{A: []string{"192.0.2.1"}, ASN: []verify.ASNInfo{{ASN: "64496"}}}}
+		if err := w.Write(o); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	if len(queries) != 3 || !strings.HasPrefix(queries[0], "CREATE TABLE IF NOT EXISTS squatrr_results") || queries[1] != "INSERT INTO squatrr_results FORMAT JSONEachRow" {
+		t.Errorf("queries = %q, want create then two batched inserts", queries)
+	}
+	if len(inserted) != 3 {
+		t.Fatalf("inserted %d rows, want 3", len(inserted))
+	}
+	r := inserted[2]
+	if r.Domain != "exarnple.com" || r.Base != "example.com" || r.RunID != "run-1" || r.ASNs[0] != "64496" || r.NS == nil || !strings.Contains(r.Result, `"domain":"exarnple.com"`) {
+		t.Errorf("row = %+v", r)
+	}
+}
+
+func TestWriterDropsFailedInsert(t *testing.T) {
+	inserts := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Query().Get("query"), "INSERT") {
+			return
+		}
+		if inserts++; inserts == 1 {
+			http.Error(w, "Code: 241. DB::Exception: Memory limit exceeded", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	w, err := NewWriter(context.Background(), srv.URL, "", "example.com", "run-1", 1, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("NewWriter() error: %v", err)
+	}
+	for _, d := range []string{"exampel.com", "examp1e.com"} {
+		if err := w.Write(processor.Output{Domain: d}); err != nil {
+			t.Fatalf("Write(%s) error: %v", d, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if inserts != 2 || w.total != 1 || w.dropped != 1 {
+		t.Errorf("%d inserts, %d rows inserted, %d dropped; want 2, 1, 1", inserts, w.total, w.dropped)
+	}
+}
+
+func TestNewWriterRejectsTableName(t *testing.T) {
+	if _, err := NewWriter(context.Background(), "http://localhost:8123", "x; DROP TABLE y", "", "", 0, nil); err == nil {
+		t.Error("NewWriter() with an injected table name succeeded")
+	}
+}
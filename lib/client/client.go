@@ -0,0 +1,302 @@
+package client
+
+/*
+  This library is a hand-written Go client for the serve-mode API, the
+  contract published in openapi.json (served at /api/openapi.json). Each
+  operation of the definition is the Client method of the same name, and
+  the request and response types here are the ones serve mode encodes, so
+  a change to either side shows up as a compile or test failure rather
+  than drift.
+*/
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"squatrr/lib/history"
+	"squatrr/lib/jobs"
+	"squatrr/lib/processor"
+	"squatrr/lib/sink"
+	"strings"
+	"time"
+)
+
+// Spec is the OpenAPI definition of the API.
+//
+//go:embed openapi.json
+var Spec []byte
+
+// maxResponseBytes caps a decoded response.
+const maxResponseBytes = 256 << 20
+
+// Health is the /healthz response.
+type Health struct {
+	Status  string       `json:"status"` // ok, or stale when the last run is older than -max-run-age
+	Started time.Time    `json:"started"`
+	LastRun *history.Run `json:"last_run,omitempty"`
+	History string       `json:"history,omitempty"` // why the last run is unknown, if it is
+}
+
+// Whoami is the /api/whoami response: the caller's token.
+type Whoami struct {
+	Name  string `json:"name"`
+	Scope string `json:"scope"`
+}
+
+// GeoSummary is the /api/geo response: currently live candidates of a
+// base domain by hosting country.
+type GeoSummary struct {
+	Base      string              `json:"base"`
+	Countries []sink.CountryCount `json:"countries"`
+	Unlocated int                 `json:"unlocated"`
+}
+
+// Case is one candidate's entry in the /api/states response.
+type Case struct {
+	Domain   string         `json:"domain"`
+	State    string         `json:"state"`
+	Live     bool           `json:"live"`
+	Assignee string         `json:"assignee,omitempty"`
+	Notes    []history.Note `json:"notes,omitempty"`
+}
+
+// StateChange is the /api/state request: the state to set on Domains.
+type StateChange struct {
+	Domains []string `json:"domains"`
+	State   string   `json:"state"`
+}
+
+// Assignment is the /api/assign request: the analyst to assign Domains
+// to, or none to unassign them.
+type Assignment struct {
+	Domains  []string `json:"domains"`
+	Assignee string   `json:"assignee"`
+}
+
+// NoteRequest is the /api/note request: a note to add to Domain.
+type NoteRequest struct {
+	Domain string `json:"domain"`
+	Author string `json:"author"`
+	Text   string `json:"text"`
+}
+
+// Error is an answer outside 2xx, with the server's message.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sasquat api: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Client calls a sasquat serve instance.
+type Client struct {
+	BaseURL    string       // e.g. http://localhost:8080
+	Token      string       // bearer token, when the server has -tokens
+	HTTPClient *http.Client // http.DefaultClient when nil
+}
+
+// New returns a client of the server at baseURL.
+func New(baseURL, token string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Token: token}
+}
+
+// Health returns the server's health. A stale history is not an error:
+// check Status.
+func (c *Client) Health(ctx context.Context) (Health, error) {
+	var h Health
+	err := c.do(ctx, http.MethodGet, "/healthz", nil, nil, &h, http.StatusServiceUnavailable)
+	return h, err
+}
+
+// OpenAPI returns the definition the server publishes.
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	var raw json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/api/openapi.json", nil, nil, &raw)
+	return raw, err
+}
+
+// Whoami returns the name and scope of the client's token.
+func (c *Client) Whoami(ctx context.Context) (Whoami, error) {
+	var w Whoami
+	err := c.do(ctx, http.MethodGet, "/api/whoami", nil, nil, &w)
+	return w, err
+}
+
+// Bases lists the base domains with recorded runs.
+func (c *Client) Bases(ctx context.Context) ([]string, error) {
+	var bases []string
+	err := c.do(ctx, http.MethodGet, "/api/bases", nil, nil, &bases)
+	return bases, err
+}
+
+// Runs returns base's recorded runs, oldest first. An empty base is the
+// only recorded one.
+func (c *Client) Runs(ctx context.Context, base string) ([]history.Run, error) {
+	var runs []history.Run
+	err := c.do(ctx, http.MethodGet, "/api/runs", baseQuery(base), nil, &runs)
+	return runs, err
+}
+
+// Trends returns base's weekly trends and remediation rate.
+func (c *Client) Trends(ctx context.Context, base string) (history.Trends, error) {
+	var t history.Trends
+	err := c.do(ctx, http.MethodGet, "/api/trends", baseQuery(base), nil, &t)
+	return t, err
+}
+
+// Geo returns base's currently live candidates by hosting country.
+func (c *Client) Geo(ctx context.Context, base string) (GeoSummary, error) {
+	var g GeoSummary
+	err := c.do(ctx, http.MethodGet, "/api/geo", baseQuery(base), nil, &g)
+	return g, err
+}
+
+// Leaderboard ranks the parking providers, registrars and hosting ASNs of
+// base's currently live flagged candidates. An empty base ranks those of
+// every recorded base, unless there is only one.
+func (c *Client) Leaderboard(ctx context.Context, base string) (sink.Board, error) {
+	var b sink.Board
+	err := c.do(ctx, http.MethodGet, "/api/leaderboard", baseQuery(base), nil, &b)
+	return b, err
+}
+
+// States returns the case of every recorded candidate of base.
+func (c *Client) States(ctx context.Context, base string) ([]Case, error) {
+	var cases []Case
+	err := c.do(ctx, http.MethodGet, "/api/states", baseQuery(base), nil, &cases)
+	return cases, err
+}
+
+// SetState sets the case state of domains, all or none of them.
+func (c *Client) SetState(ctx context.Context, base string, domains []string, state string) (StateChange, error) {
+	var done StateChange
+	err := c.do(ctx, http.MethodPost, "/api/state", baseQuery(base), StateChange{Domains: domains, State: state}, &done)
+	return done, err
+}
+
+// Assign assigns domains to assignee, or unassigns them when it is empty.
+func (c *Client) Assign(ctx context.Context, base string, domains []string, assignee string) (Assignment, error) {
+	var done Assignment
+	err := c.do(ctx, http.MethodPost, "/api/assign", baseQuery(base), Assignment{Domains: domains, Assignee: assignee}, &done)
+	return done, err
+}
+
+// AddNote adds a note to domain and returns it as stored. A server
+// checking tokens signs it with the token's name instead of author.
+func (c *Client) AddNote(ctx context.Context, base, domain, author, text string) (history.Note, error) {
+	var n history.Note
+	err := c.do(ctx, http.MethodPost, "/api/note", baseQuery(base), NoteRequest{Domain: domain, Author: author, Text: text}, &n)
+	return n, err
+}
+
+// Export renders results as a CSV or JSON ("csv" or "json") download.
+func (c *Client) Export(ctx context.Context, format string, results []processor.Output) ([]byte, error) {
+	var raw rawBody
+	err := c.do(ctx, http.MethodPost, "/api/export", url.Values{"format": {format}}, results, &raw)
+	return raw, err
+}
+
+// Scans lists the submitted scans the client's token may see.
+func (c *Client) Scans(ctx context.Context) ([]jobs.Job, error) {
+	var list []jobs.Job
+	err := c.do(ctx, http.MethodGet, "/api/scans", nil, nil, &list)
+	return list, err
+}
+
+// SubmitScan queues a scan; poll Scan for its progress.
+func (c *Client) SubmitScan(ctx context.Context, req jobs.Request) (jobs.Job, error) {
+	var j jobs.Job
+	err := c.do(ctx, http.MethodPost, "/api/scans", nil, req, &j)
+	return j, err
+}
+
+// Scan returns the state, queue position and progress of scan id.
+func (c *Client) Scan(ctx context.Context, id string) (jobs.Job, error) {
+	var j jobs.Job
+	err := c.do(ctx, http.MethodGet, "/api/scans/"+url.PathEscape(id), nil, nil, &j)
+	return j, err
+}
+
+// CancelScan cancels scan id, interrupting it if it is running.
+func (c *Client) CancelScan(ctx context.Context, id string) (jobs.Job, error) {
+	var j jobs.Job
+	err := c.do(ctx, http.MethodDelete, "/api/scans/"+url.PathEscape(id), nil, nil, &j)
+	return j, err
+}
+
+// Webhook posts detections, queueing a scan per base domain they name;
+// events without a base are of base.
+func (c *Client) Webhook(ctx context.Context, base string, events []jobs.Event) ([]jobs.Job, error) {
+	var queued []jobs.Job
+	err := c.do(ctx, http.MethodPost, "/api/webhook", baseQuery(base), events, &queued)
+	return queued, err
+}
+
+// rawBody receives a response body as is.
+type rawBody []byte
+
+func baseQuery(base string) url.Values {
+	if base == "" {
+		return nil
+	}
+	return url.Values{"base": {base}}
+}
+
+// do sends a request, JSON-encoding in when set, and decodes a 2xx (or an
+// accepted status) answer into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any, accept ...int) error {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+	ok := resp.StatusCode >= 200 && resp.StatusCode <= 299
+	for _, code := range accept {
+		ok = ok || resp.StatusCode == code
+	}
+	if !ok {
+		return &Error{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+	}
+	if raw, isRaw := out.(*rawBody); isRaw {
+		*raw = data
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
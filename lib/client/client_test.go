@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"squatrr/lib/history"
+	"squatrr/lib/jobs"
+	"squatrr/lib/processor"
+	"squatrr/lib/sink"
+	"strings"
+	"testing"
+)
+
+// TestSpecCoversClient keeps the definition and the client in step: every
+// operation is the Client method of the same name, and the reverse.
+func TestSpecCoversClient(t *testing.T) {
+	var spec struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Scope       string `json:"x-scope"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(Spec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	var ops []string
+	for path, methods := range spec.Paths {
+		for method, op := range methods {
+			ops = append(ops, strings.ToUpper(op.OperationID[:1])+op.OperationID[1:])
+			if op.Scope == "" && path != "/healthz" {
+				t.Errorf("%s %s has no x-scope", method, path)
+			}
+		}
+	}
+	var methods []string
+	ct := reflect.TypeOf(&Client{})
+	for i := range ct.NumMethod() {
+		methods = append(methods, ct.Method(i).Name)
+	}
+	slices.Sort(ops)
+	slices.Sort(methods)
+	if !reflect.DeepEqual(ops, methods) {
+		t.Errorf("operations %v, client methods %v", ops, methods)
+	}
+}
+
+// TestSpecSchemasMatchTypes keeps each schema's properties the JSON fields
+// of the Go type serve mode encodes. Schemas open to additional properties
+// only document some of them.
+func TestSpecSchemasMatchTypes(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties           map[string]json.RawMessage `json:"properties"`
+				AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(Spec, &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	types := map[string]any{
+		"Health":       Health{},
+		"Whoami":       Whoami{},
+		"Run":          history.Run{},
+		"Week":         history.Week{},
+		"Trends":       history.Trends{},
+		"CountryCount": sink.CountryCount{},
+		"GeoSummary":   GeoSummary{},
+		"Leader":       sink.Leader{},
+		"Board":        sink.Board{},
+		"Note":         history.Note{},
+		"Case":         Case{},
+		"StateChange":  StateChange{},
+		"Assignment":   Assignment{},
+		"NoteRequest":  NoteRequest{},
+		"ScanRequest":  jobs.Request{},
+		"Event":        jobs.Event{},
+		"Job":          jobs.Job{},
+		"Result":       processor.Output{},
+	}
+	for name, schema := range spec.Components.Schemas {
+		if schema.Properties == nil {
+			continue // not an object, e.g. the State enum
+		}
+		v, ok := types[name]
+		if !ok {
+			t.Errorf("schema %s has no Go type to check", name)
+			continue
+		}
+		fields := jsonFields(reflect.TypeOf(v))
+		for prop := range schema.Properties {
+			if !slices.Contains(fields, prop) {
+				t.Errorf("schema %s property %q is not a field of %T", name, prop, v)
+			}
+		}
+		if schema.AdditionalProperties != nil {
+			continue
+		}
+		for _, f := range fields {
+			if _, ok := schema.Properties[f]; !ok {
+				t.Errorf("%T field %q is missing from schema %s", v, f, name)
+			}
+		}
+	}
+}
+
+// jsonFields lists the names encoding/json gives t's fields, those of
+// untagged embedded structs included.
+func jsonFields(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch {
+		case tag == "-", !f.IsExported() && !f.Anonymous:
+		case f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct:
+			names = append(names, jsonFields(f.Type)...)
+		case tag != "":
+			names = append(names, tag)
+		default:
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+func TestClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret-token" {
+			http.Error(w, "missing or unknown API token", http.StatusUnauthorized)
+			return
+		}
+		switch r.Method + " " + r.URL.Path {
+		case "GET /healthz":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"stale","started":"2026-10-16T08:00:00Z","last_run":{"id":7}}`))
+		case "GET /api/states":
+			_, _ = w.Write([]byte(`[{"domain":"exampel.com","state":"triaged","live":true,"assignee":"dana","notes":[{"author":"dana","text":"hi","at":"2026-10-16T09:00:00Z"}]}]`))
+		case "POST /api/state":
+			body, _ := io.ReadAll(r.Body)
+			if r.URL.Query().Get("base") != "example.com" || string(body) != `{"domains":["exampel.com"],"state":"bogus"}` {
+				t.Errorf("setState request = %s?%s", body, r.URL.RawQuery)
+			}
+			http.Error(w, `invalid case state "bogus"`, http.StatusBadRequest)
+		case "POST /api/export":
+			w.Header().Set("Content-Type", "text/csv")
+			_, _ = w.Write([]byte("domain,score\nexampel.com,50\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	c := New(srv.URL+"/", "s3cret-token")
+
+	if h, err := c.Health(ctx); err != nil || h.Status != "stale" || h.LastRun == nil || h.LastRun.ID != 7 {
+		t.Errorf("Health() = %+v, %v, want stale after run 7", h, err)
+	}
+	cases, err := c.States(ctx, "")
+	want := []Case{{Domain: "exampel.com", State: history.StateTriaged, Live: true, Assignee: "dana", Notes: []history.Note{{Author: "dana", Text: "hi", At: cases[0].Notes[0].At}}}}
+	if err != nil || !reflect.DeepEqual(cases, want) || cases[0].Notes[0].At.IsZero() {
+		t.Errorf("States() = %+v, %v, want %+v", cases, err, want)
+	}
+	_, err = c.SetState(ctx, "example.com", []string{"exampel.com"}, "bogus")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Message != `invalid case state "bogus"` {
+		t.Errorf("SetState(bogus) error = %v, want the 400 with its message", err)
+	}
+	if csv, err := c.Export(ctx, "csv", nil); err != nil || string(csv) != "domain,score\nexampel.com,50\n" {
+		t.Errorf("Export() = %q, %v", csv, err)
+	}
+	if _, err := New(srv.URL, "").Bases(ctx); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Bases() without token error = %v, want 401", err)
+	}
+}
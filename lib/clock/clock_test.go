@@ -0,0 +1,69 @@
+package clock
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+// fakeServer answers one SNTP request as a server of stratum (0 sends a
+// kiss code) whose clock is off by skew, first sending an unrelated packet
+// when stray is set.
+func fakeServer(t *testing.T, skew time.Duration, stray bool, stratum byte) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		req := make([]byte, 48)
+		n, addr, err := conn.ReadFrom(req)
+		if err != nil || n < 48 {
+			return
+		}
+		put := func(b []byte, at time.Time) {
+			binary.BigEndian.PutUint32(b[:4], uint32(at.Unix()+ntpEpochOffset))
+			binary.BigEndian.PutUint32(b[4:8], uint32(int64(at.Nanosecond())<<32/1e9))
+		}
+		resp := make([]byte, 48)
+		resp[0], resp[1] = 4<<3|4, stratum
+		copy(resp[12:16], "RATE")
+		if stray {
+			_, _ = conn.WriteTo(resp, addr) // origin doesn't match the request
+		}
+		copy(resp[24:32], req[40:48])
+		put(resp[32:40], time.Now().Add(skew))
+		put(resp[40:48], time.Now().Add(skew))
+		_, _ = conn.WriteTo(resp, addr)
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestOffset(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	for _, skew := range []time.Duration{time.Hour, -90 * time.Minute, 0} {
+		offset, rtt, err := Offset(ctx, fakeServer(t, skew, true, 2))
+		if err != nil {
+			t.Fatalf("Offset() with skew %v: %v", skew, err)
+		}
+		if d := offset - skew; d < -time.Second || d > time.Second || rtt < 0 {
+			t.Errorf("Offset() = %v (rtt %v), want about %v", offset, rtt, skew)
+		}
+	}
+	if _, _, err := Offset(ctx, fakeServer(t, 0, false, 0)); err == nil {
+		t.Errorf("Offset() accepted a kiss-o'-death answer")
+	}
+}
+
+func TestNTPTime(t *testing.T) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, ntpEpochOffset+1_700_000_000)
+	binary.BigEndian.PutUint32(b[4:], 1<<31)
+	if got, want := ntpTime(b), time.Unix(1_700_000_000, 5e8); !got.Equal(want) {
+		t.Errorf("ntpTime() = %v, want %v", got, want)
+	}
+}
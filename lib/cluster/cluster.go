@@ -0,0 +1,101 @@
+package cluster
+
+/*
+  This library groups verified candidates that share operator fingerprints
+  (analytics, tag manager, pixel and ad-network IDs). Two squats carrying the
+  same AdSense publisher or GTM container are almost certainly run by the same
+  actor, even when their hosting, registrar and certificates differ.
+*/
+
+import "sort"
+
+// Cluster is a set of candidate domains transitively linked by shared IDs.
+type Cluster struct {
+	ID      string   `json:"id"`
+	Domains []string `json:"domains"`
+	Shared  []string `json:"shared_ids"`
+}
+
+// Group links domains that share at least one ID (transitively) and returns
+// every cluster with two or more members, largest first. The input maps a
+// domain to the IDs observed on it.
+func Group(ids map[string][]string) []Cluster {
+	parent := map[string]string{}
+	var find func(string) string
+	find = func(x string) string {
+		if parent[x] != x {
+			parent[x] = find(parent[x])
+		}
+		return parent[x]
+	}
+	union := func(a, b string) {
+		ra, rb := find(a), find(b)
+		if ra == rb {
+			return
+		}
+		// keep the lexically smaller root so cluster IDs are deterministic
+		if rb < ra {
+			ra, rb = rb, ra
+		}
+		parent[rb] = ra
+	}
+
+	owner := map[string]string{} // tracking ID -> first domain seen with it
+	domains := make([]string, 0, len(ids))
+	for d := range ids {
+		domains = append(domains, d)
+	}
+	sort.Strings(domains)
+
+	for _, d := range domains {
+		parent[d] = d
+	}
+	for _, d := range domains {
+		for _, id := range ids[d] {
+			if o, ok := owner[id]; ok {
+				union(o, d)
+			} else {
+				owner[id] = d
+			}
+		}
+	}
+
+	members := map[string][]string{}
+	for _, d := range domains {
+		r := find(d)
+		members[r] = append(members[r], d)
+	}
+
+	var out []Cluster
+	for root, ds := range members {
+		if len(ds) < 2 {
+			continue
+		}
+		out = append(out, Cluster{ID: root, Domains: ds, Shared: sharedIDs(ids, ds)})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].Domains) != len(out[j].Domains) {
+			return len(out[i].Domains) > len(out[j].Domains)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// sharedIDs returns the IDs seen on more than one of the given domains.
+func sharedIDs(ids map[string][]string, domains []string) []string {
+	count := map[string]int{}
+	for _, d := range domains {
+		for _, id := range ids[d] {
+			count[id]++
+		}
+	}
+	var shared []string
+	for id, n := range count {
+		if n > 1 {
+			shared = append(shared, id)
+		}
+	}
+	sort.Strings(shared)
+	return shared
+}
@@ -0,0 +1,35 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	ids := map[string][]string{
+		"exampel.com":  {"gtm:GTM-AAAA", "ua:UA-1-1"},
+		"examp1e.com":  {"ua:UA-1-1"},
+		"exammple.net": {"fbpixel:123456789012", "gtm:GTM-AAAA"},
+		"eample.com":   {"gtm:GTM-BBBB"},
+		"xample.com":   {"gtm:GTM-BBBB"},
+		"lonely.com":   {"ua:UA-9-9"},
+		"empty.com":    nil,
+	}
+
+	want := []Cluster{
+		{
+			ID:      "exammple.net",
+			Domains: []string{"exammple.net", "examp1e.com", "exampel.com"},
+			Shared:  []string{"gtm:GTM-AAAA", "ua:UA-1-1"},
+		},
+		{
+			ID:      "eample.com",
+			Domains: []string{"eample.com", "xample.com"},
+			Shared:  []string{"gtm:GTM-BBBB"},
+		},
+	}
+
+	if got := Group(ids); !reflect.DeepEqual(got, want) {
+		t.Errorf("Group() = %+v, want %+v", got, want)
+	}
+}
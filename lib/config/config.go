@@ -0,0 +1,64 @@
+package config
+
+/*
+  This library loads the optional config file (-config), which holds
+  settings too structured for flags. It is JSON with one section per
+  concern:
+
+	{
+	  "scoring": {"weights": {"has_mx": 12}, "disable": ["tls_entropy"]},
+	  "permutations": {"tld_policies": [{"strategies": ["Homoglyph"], "tlds": ["idn"]}]}
+	}
+
+  Unknown keys are rejected and every section is validated at startup, so
+  a typo fails the run instead of silently scoring with the defaults.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"squatrr/lib/score"
+	"squatrr/lib/typo"
+)
+
+// Config is a parsed config file.
+type Config struct {
+	Scoring      score.Config   `json:"scoring"`
+	Permutations typo.TLDPolicy `json:"permutations"`
+
+	// Rubric is Scoring applied to the default rubric.
+	Rubric *score.Rubric `json:"-"`
+}
+
+// Parse decodes and validates a config file.
+func Parse(data []byte) (*Config, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return nil, err
+	}
+	var err error
+	if c.Rubric, err = c.Scoring.Rubric(); err != nil {
+		return nil, fmt.Errorf("scoring: %w", err)
+	}
+	if err := c.Permutations.Validate(); err != nil {
+		return nil, fmt.Errorf("permutations: %w", err)
+	}
+	return &c, nil
+}
+
+// Load reads and parses the config file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
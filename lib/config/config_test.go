@@ -0,0 +1,43 @@
+package config
+
+import (
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		wantErr bool
+	}{
+		{"empty", `{}`, false},
+		{"scoring", `{"scoring": {"weights": {"has_mx": 12}, "disable": ["tls_entropy"], "high_score": 30}}`, false},
+		{"unknown section", `{"scoring": {}, "scorring": {}}`, true},
+		{"unknown scoring key", `{"scoring": {"wieghts": {}}}`, true},
+		{"invalid weight", `{"scoring": {"weights": {"has_mx": 1000}}}`, true},
+		{"not JSON", `scoring: {}`, true},
+		{"tld policy", `{"permutations": {"tld_policies": [{"strategies": ["homoglyph"], "tlds": ["idn"]}]}}`, false},
+		{"unknown strategy", `{"permutations": {"tld_policies": [{"strategies": ["Homoglyphs"], "tlds": ["idn"]}]}}`, true},
+		{"policy without TLDs", `{"permutations": {"tld_policies": [{"strategies": ["*"]}]}}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse([]byte(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && c.Rubric == nil {
+				t.Errorf("Parse() left Rubric nil")
+			}
+		})
+	}
+
+	c, err := Parse([]byte(`{"scoring": {"weights": {"has_mx": 12}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Rubric.Record("example.com", verify.Verification{Resolvable: true, HasMail: true, TLS: &verify.TLSResult{Connected: true, Issuer: "Let's Encrypt"}}); got.Score < 12 {
+		t.Errorf("Record() = %d, want the configured has_mx weight applied", got.Score)
+	}
+}
@@ -0,0 +1,137 @@
+package czds
+
+/*
+  This library uses ICANN's Centralized Zone Data Service (CZDS). It
+  downloads the zone files an account has been approved for and matches
+  permutation sets against them offline: a name missing from its TLD's zone
+  is not registered, so it needs no DNS queries at all, and names that newly
+  appear between downloads are fresh registrations.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	DefaultAuthURL = "https://account-api.icann.org/api/authenticate"
+	DefaultBaseURL = "https://czds-api.icann.org"
+)
+
+// Client talks to the CZDS API with an ICANN account.
+type Client struct {
+	Username string
+	Password string
+	AuthURL  string // DefaultAuthURL when empty
+	BaseURL  string // DefaultBaseURL when empty
+	HTTP     *http.Client
+
+	token string
+}
+
+// Authenticate exchanges the account credentials for an access token.
+func (c *Client) Authenticate(ctx context.Context) error {
+	body, _ := json.Marshal(map[string]string{"username": c.Username, "password": c.Password})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, orDefault(c.AuthURL, DefaultAuthURL), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("czds authenticate: %s", resp.Status)
+	}
+	var out struct {
+		AccessToken string `json:"accessToken"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return err
+	}
+	c.token = out.AccessToken
+	return nil
+}
+
+// Links lists the zone file URLs the account may download.
+func (c *Client) Links(ctx context.Context) ([]string, error) {
+	resp, err := c.get(ctx, strings.TrimSuffix(orDefault(c.BaseURL, DefaultBaseURL), "/")+"/czds/downloads/links")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var links []string
+	err = json.NewDecoder(resp.Body).Decode(&links)
+	return links, err
+}
+
+// Download saves the zone at link into dir as <tld>.zone.gz and returns the path.
+func (c *Client) Download(ctx context.Context, link, dir string) (string, error) {
+	resp, err := c.get(ctx, link)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	dst := filepath.Join(dir, TLDOf(link)+".zone.gz")
+	tmp, err := os.CreateTemp(dir, ".zone-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	return dst, os.Rename(tmp.Name(), dst)
+}
+
+// TLDOf returns the TLD a CZDS download link is for (".../downloads/com.zone").
+func TLDOf(link string) string {
+	return strings.TrimSuffix(path.Base(link), ".zone")
+}
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("czds %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) client() *http.Client {
+	if c.HTTP != nil {
+		return c.HTTP
+	}
+	return http.DefaultClient
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,128 @@
+package czds
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"squatrr/lib/processor"
+	"strings"
+	"testing"
+)
+
+const zone = `com.	900	in	soa	a.gtld-servers.net. nstld.verisign-grs.com. 1 1800 900 604800 86400
+; comment
+exampel.com.	172800	in	ns	ns1.parkingcrew.net.
+exampel.com.	172800	in	ns	ns2.parkingcrew.net.
+EXMPLE.COM.	172800	in	ns	ns1.example-dns.net.
+ns1.exampel.com.	172800	in	a	192.0.2.1
+`
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+func TestMatch(t *testing.T) {
+	wanted := map[string]bool{"exampel.com": true, "exmple.com": true, "exampe.com": true}
+	want := map[string]bool{"exampel.com": true, "exmple.com": true}
+	for name, data := range map[string][]byte{"plain": []byte(zone), "gzip": gzipped(zone)} {
+		got, err := Match(bytes.NewReader(data), wanted)
+		if err != nil {
+			t.Fatalf("%s: Match() error: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Match() = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestZonesFilter(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "com.zone.gz"), gzipped(zone), 0o644)
+
+	queue := []processor.Candidate{{Domain: "exampel.com"}, {Domain: "exampe.com"}, {Domain: "exampel.net"}, {Domain: "exmple.com"}}
+	got, err := Zones{Dir: dir}.Filter(queue)
+	if err != nil {
+		t.Fatalf("Filter() error: %v", err)
+	}
+	var domains []string
+	for _, c := range got {
+		domains = append(domains, c.Domain)
+	}
+	// exampe.com is absent from the .com zone; .net has no zone so it is kept.
+	if want := []string{"exampel.com", "exampel.net", "exmple.com"}; !reflect.DeepEqual(domains, want) {
+		t.Errorf("Filter() = %v, want %v", domains, want)
+	}
+}
+
+func TestZonesDelegated(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "com.zone"), []byte(zone), 0o644)
+
+	queue := []processor.Candidate{{Domain: "exampel.com"}, {Domain: "exampe.com"}, {Domain: "exampel.net"}, {Domain: "exampel.org"}, {Domain: "exmple.net"}}
+	got, uncovered, err := Zones{Dir: dir}.Delegated(queue)
+	if err != nil {
+		t.Fatalf("Delegated() error: %v", err)
+	}
+	// Candidates of .net and .org, without a zone, aren't reported.
+	if want := []processor.Candidate{{Domain: "exampel.com"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Delegated() = %v, want %v", got, want)
+	}
+	if want := []string{"net", "org"}; !reflect.DeepEqual(uncovered, want) {
+		t.Errorf("Delegated() uncovered = %v, want %v", uncovered, want)
+	}
+}
+
+func TestClient(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/auth":
+			var creds map[string]string
+			json.NewDecoder(r.Body).Decode(&creds)
+			if creds["username"] != "u" || creds["password"] != "p" {
+				http.Error(w, "denied", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"accessToken":"tok"}`)
+		case r.Header.Get("Authorization") != "Bearer tok":
+			http.Error(w, "denied", http.StatusUnauthorized)
+		case r.URL.Path == "/czds/downloads/links":
+			fmt.Fprintf(w, `["%s/czds/downloads/com.zone"]`, srv.URL)
+		case r.URL.Path == "/czds/downloads/com.zone":
+			w.Write(gzipped(zone))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := &Client{Username: "u", Password: "p", AuthURL: srv.URL + "/auth", BaseURL: srv.URL}
+	if err := c.Authenticate(ctx); err != nil {
+		t.Fatalf("Authenticate() error: %v", err)
+	}
+	links, err := c.Links(ctx)
+	if err != nil || len(links) != 1 || TLDOf(links[0]) != "com" {
+		t.Fatalf("Links() = %v, %v", links, err)
+	}
+	path, err := c.Download(ctx, links[0], t.TempDir())
+	if err != nil || !strings.HasSuffix(path, "com.zone.gz") {
+		t.Fatalf("Download() = %v, %v", path, err)
+	}
+
+	bad := &Client{Username: "u", Password: "x", AuthURL: srv.URL + "/auth"}
+	if err := bad.Authenticate(ctx); err == nil {
+		t.Errorf("Authenticate() with bad credentials succeeded")
+	}
+}
@@ -0,0 +1,160 @@
+package czds
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"squatrr/lib/processor"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// Match streams a zone file (plain or gzip) and returns which of the wanted
+// ASCII domains are delegated in it. Zones are far too large to hold in
+// memory, so only the small wanted set is kept.
+func Match(r io.Reader, wanted map[string]bool) (map[string]bool, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		br = bufio.NewReaderSize(gz, 1<<20)
+	}
+
+	found := map[string]bool{}
+	sc := bufio.NewScanner(br)
+	sc.Buffer(make([]byte, 64<<10), 1<<20)
+	for sc.Scan() {
+		line := sc.Bytes()
+		if len(line) == 0 || line[0] == ';' || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		owner, _, _ := bytes.Cut(line, []byte{'\t'})
+		owner, _, _ = bytes.Cut(owner, []byte{' '})
+		name := strings.ToLower(strings.TrimSuffix(string(owner), "."))
+		if wanted[name] {
+			found[name] = true
+		}
+	}
+	return found, sc.Err()
+}
+
+// Zones is a directory of downloaded zone files (<tld>.zone or <tld>.zone.gz).
+// It implements processor.ZoneFilter.
+type Zones struct {
+	Dir string
+}
+
+// Path returns the zone file for tld, or "" when none was downloaded.
+func (z Zones) Path(tld string) string {
+	for _, name := range []string{tld + ".zone.gz", tld + ".zone"} {
+		p := filepath.Join(z.Dir, name)
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// Registered matches candidates against the zones for their TLDs. covered
+// reports the TLDs that had a zone file; candidates in other TLDs are never
+// in registered.
+func (z Zones) Registered(domains []string) (registered map[string]bool, covered map[string]bool, err error) {
+	byTLD := map[string]map[string]bool{}
+	for _, d := range domains {
+		ascii, err := idna.Lookup.ToASCII(d)
+		if err != nil {
+			continue
+		}
+		tld := ascii[strings.LastIndexByte(ascii, '.')+1:]
+		if byTLD[tld] == nil {
+			byTLD[tld] = map[string]bool{}
+		}
+		byTLD[tld][ascii] = true
+	}
+
+	registered, covered = map[string]bool{}, map[string]bool{}
+	for tld, wanted := range byTLD {
+		p := z.Path(tld)
+		if p == "" {
+			continue
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, nil, err
+		}
+		found, err := Match(f, wanted)
+		f.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		covered[tld] = true
+		for d := range found {
+			registered[d] = true
+		}
+	}
+	return registered, covered, nil
+}
+
+// Filter implements processor.ZoneFilter: candidates in TLDs with a zone file
+// are kept only when delegated; others are kept for DNS verification.
+func (z Zones) Filter(queue []processor.Candidate) ([]processor.Candidate, error) {
+	domains := make([]string, len(queue))
+	for i, c := range queue {
+		domains[i] = c.Domain
+	}
+	registered, covered, err := z.Registered(domains)
+	if err != nil {
+		return nil, err
+	}
+	kept := queue[:0:0]
+	for _, c := range queue {
+		ascii, err := idna.Lookup.ToASCII(c.Domain)
+		if err != nil {
+			kept = append(kept, c)
+			continue
+		}
+		if covered[ascii[strings.LastIndexByte(ascii, '.')+1:]] && !registered[ascii] {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept, nil
+}
+
+// Delegated returns the candidates delegated in the zones for their TLDs,
+// and the TLDs without a zone file, sorted. Unlike Filter, it keeps no
+// candidate of a TLD it can't tell about: only names a zone lists are
+// known to be registered.
+func (z Zones) Delegated(queue []processor.Candidate) (delegated []processor.Candidate, uncovered []string, err error) {
+	domains := make([]string, len(queue))
+	for i, c := range queue {
+		domains[i] = c.Domain
+	}
+	registered, covered, err := z.Registered(domains)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range queue {
+		ascii, err := idna.Lookup.ToASCII(c.Domain)
+		if err != nil {
+			continue
+		}
+		tld := ascii[strings.LastIndexByte(ascii, '.')+1:]
+		switch {
+		case registered[ascii]:
+			delegated = append(delegated, c)
+		case !covered[tld] && !slices.Contains(uncovered, tld):
+			uncovered = append(uncovered, tld)
+		}
+	}
+	slices.Sort(uncovered)
+	return delegated, uncovered, nil
+}
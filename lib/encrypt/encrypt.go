@@ -0,0 +1,146 @@
+package encrypt
+
+/*
+  This library encrypts result files and evidence bundles to their
+  recipients (-encrypt-to), since scan results often hold investigation
+  details that travel between teams. A recipient is either an age X25519
+  public key (age1…), giving files the age CLI decrypts, or the path of an
+  OpenPGP public key file, armored or binary, giving files gpg decrypts.
+  One output can't mix the two.
+
+  age is filippo.io/age, the reference implementation. OpenPGP is
+  github.com/ProtonMail/go-crypto, the maintained fork of the deprecated
+  golang.org/x/crypto/openpgp, which also handles the ECC keys recent gpg
+  generates by default.
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// Recipients are the keys output is encrypted to. A nil *Recipients
+// leaves output as is.
+type Recipients struct {
+	age []age.Recipient
+	pgp openpgp.EntityList
+}
+
+// Parse parses recipients: age public keys or OpenPGP public key files.
+// An empty list is nil.
+func Parse(list []string) (*Recipients, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	r := &Recipients{}
+	for _, s := range list {
+		if strings.HasPrefix(s, "age1") {
+			a, err := age.ParseX25519Recipient(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", s, err)
+			}
+			r.age = append(r.age, a)
+			continue
+		}
+		keys, err := readPGPKeys(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s, err)
+		}
+		r.pgp = append(r.pgp, keys...)
+	}
+	if len(r.age) > 0 && len(r.pgp) > 0 {
+		return nil, errors.New("age and OpenPGP recipients can't be mixed")
+	}
+	return r, nil
+}
+
+// readPGPKeys reads an armored or binary OpenPGP public key file.
+func readPGPKeys(path string) (openpgp.EntityList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	keys, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		if keys, err = openpgp.ReadKeyRing(f); err != nil {
+			return nil, err
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no public keys")
+	}
+	return keys, nil
+}
+
+// Ext is the file extension of encrypted output: .age or .gpg.
+func (r *Recipients) Ext() string {
+	if len(r.age) > 0 {
+		return ".age"
+	}
+	return ".gpg"
+}
+
+// Writer returns a writer encrypting to w. Closing it finishes the
+// ciphertext but doesn't close w.
+func (r *Recipients) Writer(w io.Writer) (io.WriteCloser, error) {
+	if len(r.age) > 0 {
+		return age.Encrypt(w, r.age...)
+	}
+	return openpgp.Encrypt(w, r.pgp, nil, &openpgp.FileHints{IsBinary: true}, nil)
+}
+
+// Bytes encrypts data.
+func (r *Recipients) Bytes(data []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := r.Writer(&b)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// File encrypts the file at path into path+Ext and removes the plaintext,
+// returning the new path.
+func (r *Recipients) File(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	out, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(out.Name()) // after a failure; renamed away otherwise
+	w, err := r.Writer(out)
+	if err == nil {
+		_, err = io.Copy(w, in)
+		err = errors.Join(err, w.Close())
+	}
+	if err := errors.Join(err, out.Close()); err != nil {
+		return "", err
+	}
+	dest := path + r.Ext()
+	if err := os.Rename(out.Name(), dest); err != nil {
+		return "", err
+	}
+	return dest, os.Remove(path)
+}
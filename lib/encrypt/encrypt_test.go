@@ -0,0 +1,126 @@
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+// newAgeKey returns an X25519 identity and its age1… recipient.
+func newAgeKey(t *testing.T) (*age.X25519Identity, string) {
+	t.Helper()
+	id, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, id.Recipient().String()
+}
+
+func TestAge(t *testing.T) {
+	id, recipient := newAgeKey(t)
+	_, other := newAgeKey(t)
+	r, err := Parse([]string{other, recipient})
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if r.Ext() != ".age" {
+		t.Errorf("Ext() = %q, want .age", r.Ext())
+	}
+	const chunk = 64 << 10 // age's STREAM chunk size
+	for _, size := range []int{0, 1, chunk, chunk + 1, 3*chunk - 7} {
+		plain := bytes.Repeat([]byte("sasquat"), size/7+1)[:size]
+		sealed, err := r.Bytes(plain)
+		if err != nil {
+			t.Fatalf("Bytes(%d) error: %v", size, err)
+		}
+		dec, err := age.Decrypt(bytes.NewReader(sealed), id)
+		if err != nil {
+			t.Fatalf("size %d: Decrypt() error: %v", size, err)
+		}
+		if got, err := io.ReadAll(dec); err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("size %d: decrypted %d bytes (%v), want the plaintext back", size, len(got), err)
+		}
+	}
+}
+
+func TestPGP(t *testing.T) {
+	for _, algo := range []packet.PublicKeyAlgorithm{packet.PubKeyAlgoRSA, packet.PubKeyAlgoEdDSA} {
+		key, err := openpgp.NewEntity("analyst", "", "analyst@example.com", &packet.Config{Algorithm: algo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testPGP(t, key)
+	}
+}
+
+// testPGP encrypts a results file to key and decrypts it back.
+func testPGP(t *testing.T, key *openpgp.Entity) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "analyst.asc")
+	var pub bytes.Buffer
+	w, _ := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	if err := key.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if err := os.WriteFile(path, pub.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := Parse([]string{path})
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	results := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(results, []byte(`[{"domain":"exampel.com"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := r.File(results)
+	if err != nil || sealed != results+".gpg" {
+		t.Fatalf("File() = %q, %v, want %s.gpg", sealed, err, results)
+	}
+	if _, err := os.Stat(results); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("plaintext left behind: %v", err)
+	}
+	f, err := os.Open(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	md, err := openpgp.ReadMessage(f, openpgp.EntityList{key}, nil, nil)
+	if err != nil {
+		t.Fatalf("ReadMessage() error: %v", err)
+	}
+	if got, _ := io.ReadAll(md.UnverifiedBody); string(got) != `[{"domain":"exampel.com"}]` {
+		t.Errorf("decrypted %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	_, recipient := newAgeKey(t)
+	tests := []struct {
+		list    []string
+		wantErr bool
+	}{
+		{[]string{recipient}, false},
+		{[]string{recipient[:len(recipient)-1] + "x"}, true}, // checksum
+		{[]string{"age1qqqq"}, true},
+		{[]string{"/nonexistent/key.asc"}, true},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.list); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%v) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+		}
+	}
+	if r, err := Parse(nil); r != nil || err != nil {
+		t.Errorf("Parse(nil) = %v, %v, want nil", r, err)
+	}
+}
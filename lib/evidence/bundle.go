@@ -0,0 +1,112 @@
+package evidence
+
+/*
+  This library packages the artifacts gathered for one candidate (DNS
+  snapshot, RDAP, certificate chain, screenshot, HAR) into a zip with a
+  manifest of SHA-256 hashes and capture times, for chain of custody in UDRP
+  complaints and law-enforcement referrals.
+*/
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// File is a manifest entry.
+type File struct {
+	Name       string    `json:"name"`
+	SHA256     string    `json:"sha256"`
+	Size       int       `json:"size"`
+	CapturedAt time.Time `json:"captured_at"`
+	Source     string    `json:"source"` // how it was obtained, e.g. "RDAP https://rdap.org/domain/x"
+}
+
+// Manifest describes a bundle. It is stored as manifest.json, alongside a
+// SHA256SUMS file that sha256sum -c can check after unzipping.
+type Manifest struct {
+	Domain    string    `json:"domain"`
+	Base      string    `json:"base,omitempty"`
+	Generated time.Time `json:"generated_at"`
+	Tool      string    `json:"tool"`
+	Host      string    `json:"collected_from,omitempty"` // hostname of the collecting machine
+	Files     []File    `json:"files"`
+	Notes     []string  `json:"notes,omitempty"` // artifacts that could not be collected, and why
+}
+
+// Bundle accumulates artifacts in memory until Write.
+type Bundle struct {
+	Manifest Manifest
+	data     map[string][]byte
+}
+
+func New(domain, base string) *Bundle {
+	host, _ := os.Hostname()
+	return &Bundle{
+		Manifest: Manifest{Domain: domain, Base: base, Tool: "sasquat", Host: host},
+		data:     map[string][]byte{},
+	}
+}
+
+// Add records an artifact captured at the given time.
+func (b *Bundle) Add(name string, data []byte, capturedAt time.Time, source string) {
+	sum := sha256.Sum256(data)
+	b.data[name] = data
+	b.Manifest.Files = append(b.Manifest.Files, File{
+		Name: name, SHA256: hex.EncodeToString(sum[:]), Size: len(data),
+		CapturedAt: capturedAt.UTC(), Source: source,
+	})
+}
+
+// Note records an artifact that was not collected.
+func (b *Bundle) Note(format string, args ...any) {
+	b.Manifest.Notes = append(b.Manifest.Notes, fmt.Sprintf(format, args...))
+}
+
+// Write zips the artifacts, manifest.json and SHA256SUMS into w and returns
+// the manifest's own SHA-256, which is worth recording outside the bundle.
+func (b *Bundle) Write(w io.Writer, now time.Time) (string, error) {
+	b.Manifest.Generated = now.UTC()
+	sort.Slice(b.Manifest.Files, func(i, j int) bool { return b.Manifest.Files[i].Name < b.Manifest.Files[j].Name })
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	var sums strings.Builder
+	for _, f := range b.Manifest.Files {
+		fmt.Fprintf(&sums, "%s  %s\n", f.SHA256, f.Name)
+	}
+
+	zw := zip.NewWriter(w)
+	put := func(name string, data []byte, modified time.Time) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	}
+	for _, f := range b.Manifest.Files {
+		if err := put(f.Name, b.data[f.Name], f.CapturedAt); err != nil {
+			return "", err
+		}
+	}
+	if err := put("manifest.json", manifest, b.Manifest.Generated); err != nil {
+		return "", err
+	}
+	if err := put("SHA256SUMS", []byte(sums.String()), b.Manifest.Generated); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(manifest)
+	return hex.EncodeToString(sum[:]), nil
+}
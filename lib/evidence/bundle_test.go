@@ -0,0 +1,62 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleWrite(t *testing.T) {
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	b := New("examp1e.com", "example.com")
+	b.Add("dns.json", []byte(`{"A":["192.0.2.1"]}`), at, "recursive DNS")
+	b.Add("cert-chain.pem", []byte("-----BEGIN CERTIFICATE-----\n"), at, "TLS examp1e.com:443")
+	b.Note("screenshot: no headless browser found")
+
+	var buf bytes.Buffer
+	manifestSum, err := b.Write(&buf, at.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{}
+	var names []string
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+		names = append(names, f.Name)
+	}
+	if got := strings.Join(names, " "); got != "cert-chain.pem dns.json manifest.json SHA256SUMS" {
+		t.Fatalf("zip entries = %s", got)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(files["manifest.json"], &m); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range m.Files {
+		sum := sha256.Sum256(files[f.Name])
+		if f.SHA256 != hex.EncodeToString(sum[:]) || f.Size != len(files[f.Name]) || !f.CapturedAt.Equal(at) {
+			t.Errorf("manifest entry %+v doesn't match the zipped file", f)
+		}
+		if !strings.Contains(string(files["SHA256SUMS"]), f.SHA256+"  "+f.Name) {
+			t.Errorf("SHA256SUMS missing %s", f.Name)
+		}
+	}
+	if len(m.Notes) != 1 || m.Domain != "examp1e.com" {
+		t.Errorf("manifest = %+v", m)
+	}
+	if sum := sha256.Sum256(files["manifest.json"]); manifestSum != hex.EncodeToString(sum[:]) {
+		t.Errorf("Write() = %s, want the manifest's SHA-256", manifestSum)
+	}
+}
@@ -0,0 +1,104 @@
+package evidence
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+	"math/bits"
+	"slices"
+)
+
+// CloneSimilarity is the Similarity from which two screenshots are taken
+// for the same page: at most 9 of 64 hash bits differ.
+const CloneSimilarity = 0.85
+
+// phashSize is the side of the grayscale thumbnail PHash transforms, of
+// which the lowest 8x8 frequencies are kept.
+const phashSize = 32
+
+// PHash is the perceptual hash of img: the image shrunk to a 32x32
+// grayscale thumbnail, its DCT taken, and each of the 8x8 lowest
+// frequencies set as a bit when above their median. Re-encoding, scaling,
+// small shifts and colour tweaks barely move it, while a different layout
+// flips about half the bits, so it matches rendered clones whatever HTML
+// produced them.
+func PHash(img image.Image) uint64 {
+	var gray [phashSize][phashSize]float64
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return 0
+	}
+	// Average every source pixel into its thumbnail cell.
+	var count [phashSize][phashSize]float64
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		cy := (y - b.Min.Y) * phashSize / h
+		for x := b.Min.X; x < b.Max.X; x++ {
+			cx := (x - b.Min.X) * phashSize / w
+			r, g, bl, _ := img.At(x, y).RGBA()
+			gray[cy][cx] += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+			count[cy][cx]++
+		}
+	}
+	for y := range phashSize {
+		for x := range phashSize {
+			if count[y][x] > 0 {
+				gray[y][x] /= count[y][x]
+			}
+		}
+	}
+
+	var coeffs [64]float64
+	for v := range 8 {
+		for u := range 8 {
+			var sum float64
+			for y := range phashSize {
+				for x := range phashSize {
+					sum += gray[y][x] * dctBasis[u][x] * dctBasis[v][y]
+				}
+			}
+			coeffs[v*8+u] = sum
+		}
+	}
+	// The DC term is the overall brightness, not structure.
+	median := medianOf(coeffs[1:])
+	var hash uint64
+	for i, c := range coeffs {
+		if i > 0 && c > median {
+			hash |= 1 << i
+		}
+	}
+	return hash
+}
+
+// dctBasis[u][x] is the DCT-II basis function of frequency u at x.
+var dctBasis = func() (basis [8][phashSize]float64) {
+	for u := range 8 {
+		for x := range phashSize {
+			basis[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * phashSize))
+		}
+	}
+	return basis
+}()
+
+func medianOf(v []float64) float64 {
+	s := slices.Clone(v)
+	slices.Sort(s)
+	return (s[(len(s)-1)/2] + s[len(s)/2]) / 2
+}
+
+// PHashPNG is PHash of a PNG, such as a Screenshot.
+func PHashPNG(data []byte) (uint64, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	return PHash(img), nil
+}
+
+// Similarity is the share of equal bits between two perceptual hashes,
+// from 0 to 1; unrelated images land around 0.5.
+func Similarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
@@ -0,0 +1,54 @@
+package evidence
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// page draws a login page of w x h: a header bar, a form box and lines of
+// "text", with the form at formX (a fraction of the width).
+func page(w, h int, formX float64, tint uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	fill := func(x0, y0, x1, y1 float64, c color.Color) {
+		r := image.Rect(int(x0*float64(w)), int(y0*float64(h)), int(x1*float64(w)), int(y1*float64(h)))
+		draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	fill(0, 0, 1, 1, color.RGBA{255, 255, 255 - tint, 255})
+	fill(0, 0, 1, 0.1, color.RGBA{0, 80, 160 + tint/4, 255})
+	fill(formX, 0.3, formX+0.3, 0.7, color.RGBA{230, 230, 230, 255})
+	for i := range 4 {
+		y := 0.4 + 0.06*float64(i)
+		fill(formX+0.03, y, formX+0.27, y+0.03, color.RGBA{40, 40, 40, 255})
+	}
+	return img
+}
+
+func TestPHash(t *testing.T) {
+	base := PHash(page(1366, 900, 0.2, 0))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, page(1280, 840, 0.21, 12)); err != nil {
+		t.Fatal(err)
+	}
+	clone, err := PHashPNG(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := PHash(page(1366, 900, 0.7, 0))
+
+	if s := Similarity(base, clone); s < CloneSimilarity {
+		t.Errorf("similarity of a rescaled, retinted clone = %.2f, want at least %.2f", s, CloneSimilarity)
+	}
+	if s := Similarity(base, other); s >= CloneSimilarity {
+		t.Errorf("similarity of a different layout = %.2f, want under %.2f", s, CloneSimilarity)
+	}
+	if s := Similarity(base, base); s != 1 {
+		t.Errorf("Similarity(h, h) = %v, want 1", s)
+	}
+	if _, err := PHashPNG([]byte("not a png")); err == nil {
+		t.Errorf("PHashPNG() accepted garbage")
+	}
+}
@@ -0,0 +1,44 @@
+package evidence
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// browsers are the headless-capable executables FindBrowser looks for.
+var browsers = []string{"chromium", "chromium-browser", "google-chrome", "google-chrome-stable", "chrome", "msedge"}
+
+// FindBrowser returns the first Chromium-family browser on PATH, or "".
+func FindBrowser() string {
+	for _, b := range browsers {
+		if path, err := exec.LookPath(b); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// Screenshot renders url with a headless Chromium-family browser and
+// returns the PNG.
+func Screenshot(ctx context.Context, browser, url string) ([]byte, error) {
+	if browser == "" {
+		return nil, errors.New("no headless browser found")
+	}
+	dir, err := os.MkdirTemp("", "sasquat-shot-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "screenshot.png")
+
+	cmd := exec.CommandContext(ctx, browser,
+		"--headless", "--disable-gpu", "--hide-scrollbars", "--incognito",
+		"--user-data-dir="+dir, "--window-size=1366,900", "--screenshot="+out, url)
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(out)
+}
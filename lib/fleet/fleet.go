@@ -0,0 +1,316 @@
+package fleet
+
+/*
+  This library spreads verification across machines. A coordinator runs the
+  usual scan (generation, ordering, sampling, budgets, sinks) but hands each
+  candidate to one of a pool of remote squatrr workers over HTTP; workers
+  verify, score and classify it with their own configuration and reply with
+  the result. Candidates are dispatched one at a time, so faster workers
+  simply take more of the queue, and a worker that fails is rested while its
+  candidates go to the others.
+*/
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"squatrr/lib/classify"
+	"squatrr/lib/jobs"
+	"squatrr/lib/processor"
+	"squatrr/lib/score"
+	"squatrr/lib/verify"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	evaluatePath = "/v1/evaluate"
+	healthPath   = "/healthz"
+
+	// DefaultRequestTimeout bounds one remote verification, which may chain
+	// DNS, TLS, HTTP, RDAP and CT lookups.
+	DefaultRequestTimeout = 2 * time.Minute
+
+	// restPeriod is how long a failing worker is skipped.
+	restPeriod = 30 * time.Second
+
+	maxResponseBytes = 16 << 20
+)
+
+// ErrNoWorkers is returned when every worker in the pool is resting.
+var ErrNoWorkers = errors.New("no reachable workers")
+
+// request is the body of a POST to /v1/evaluate.
+type request struct {
+	Base       string  `json:"base"`
+	Domain     string  `json:"domain"`
+	Strategy   string  `json:"strategy,omitempty"`
+	Likelihood float64 `json:"likelihood,omitempty"`
+}
+
+// Worker is the HTTP handler a worker serves: it verifies candidates posted
+// by a coordinator, at most limit at a time.
+type Worker struct {
+	token   string
+	sem     chan struct{}
+	logger  *slog.Logger
+	eval    func(ctx context.Context, base string, c processor.Candidate) (processor.Output, error)
+	started time.Time
+
+	evaluated atomic.Int64
+	lastEval  atomic.Int64 // unix nanoseconds
+}
+
+// Health is a worker's /healthz response.
+type Health struct {
+	Status        string    `json:"status"`
+	Started       time.Time `json:"started"`
+	Evaluated     int64     `json:"evaluated"` // candidates verified since startup
+	LastEvaluated time.Time `json:"last_evaluated,omitzero"`
+}
+
+// NewWorker verifies with cfg, signatures and rubric (nil for
+// classify.Default and score.Default). When token is set, coordinators must
+// present it as a bearer token.
+func NewWorker(cfg verify.Config, signatures *classify.Set, rubric *score.Rubric, token string, limit int, logger *slog.Logger) *Worker {
+	return &Worker{
+		token:   token,
+		sem:     make(chan struct{}, max(limit, 1)),
+		logger:  logger,
+		started: time.Now().UTC(),
+		eval: func(ctx context.Context, base string, c processor.Candidate) (processor.Output, error) {
+			return processor.Evaluate(ctx, base, c, cfg, signatures, rubric)
+		},
+	}
+}
+
+func (w *Worker) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == healthPath && r.Method == http.MethodGet:
+		h := Health{Status: "ok", Started: w.started, Evaluated: w.evaluated.Load()}
+		if last := w.lastEval.Load(); last != 0 {
+			h.LastEvaluated = time.Unix(0, last).UTC()
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(rw).Encode(h)
+		return
+	case r.URL.Path != evaluatePath:
+		http.NotFound(rw, r)
+		return
+	case r.Method != http.MethodPost:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	case !w.authorized(r):
+		http.Error(rw, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil || req.Base == "" || req.Domain == "" {
+		http.Error(rw, "expected {base, domain, strategy, likelihood}", http.StatusBadRequest)
+		return
+	}
+	// Candidates are public names of a registrable base, as in a scan
+	// request: a worker doesn't probe IP addresses or internal hosts.
+	if err := errors.Join(jobs.CheckRegistrable(req.Base), jobs.CheckPublic(req.Domain)); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	select {
+	case w.sem <- struct{}{}:
+		defer func() { <-w.sem }()
+	case <-r.Context().Done():
+		return
+	}
+	o, err := w.eval(r.Context(), req.Base, processor.Candidate{Domain: req.Domain, Strategy: req.Strategy, Likelihood: req.Likelihood})
+	if err != nil {
+		// The candidate failed, not the worker: the coordinator counts it
+		// as errored instead of retrying elsewhere.
+		w.logger.Debug("processing fleet evaluate", "domain", req.Domain, "error", err)
+		http.Error(rw, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.evaluated.Add(1)
+	w.lastEval.Store(time.Now().UnixNano())
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(o)
+}
+
+func (w *Worker) authorized(r *http.Request) bool {
+	if w.token == "" {
+		return true
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(w.token)) == 1
+}
+
+// Pool is a processor.Evaluator verifying candidates on remote workers,
+// round-robin, moving on to the next worker when one fails.
+type Pool struct {
+	workers []*remote
+	next    atomic.Uint64
+	token   string
+	client  *http.Client
+	logger  *slog.Logger
+}
+
+var _ processor.Evaluator = (*Pool)(nil)
+
+type remote struct {
+	url string
+
+	mu        sync.Mutex
+	restUntil time.Time
+}
+
+func (r *remote) resting(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return now.Before(r.restUntil)
+}
+
+func (r *remote) rest(until time.Time) {
+	r.mu.Lock()
+	r.restUntil = until
+	r.mu.Unlock()
+}
+
+// NewPool dispatches to the workers at urls (http(s)://host:port), sending
+// token as a bearer token when set.
+func NewPool(urls []string, token string, logger *slog.Logger) (*Pool, error) {
+	if len(urls) == 0 {
+		return nil, errors.New("no worker URLs")
+	}
+	p := &Pool{token: token, client: &http.Client{Timeout: DefaultRequestTimeout}, logger: logger}
+	for _, u := range urls {
+		if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return nil, fmt.Errorf("worker URL %q must start with http:// or https://", u)
+		}
+		p.workers = append(p.workers, &remote{url: strings.TrimSuffix(u, "/")})
+	}
+	return p, nil
+}
+
+// Check probes every worker's health endpoint and that it accepts the
+// token, resting the others, and fails only if none passes.
+func (p *Pool) Check(ctx context.Context) error {
+	healthy := 0
+	for _, w := range p.workers {
+		err := p.health(ctx, w)
+		if err == nil {
+			err = p.authorized(ctx, w)
+		}
+		if err != nil {
+			p.logger.Warn("processing fleet check", "worker", w.url, "error", err)
+			w.rest(time.Now().Add(restPeriod))
+			continue
+		}
+		healthy++
+	}
+	p.logger.Info("processing fleet check", "workers", len(p.workers), "healthy", healthy)
+	if healthy == 0 {
+		return ErrNoWorkers
+	}
+	return nil
+}
+
+func (p *Pool) health(ctx context.Context, w *remote) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.url+healthPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("health check: %s", resp.Status)
+	}
+	return nil
+}
+
+// authorized posts an empty candidate to w: a worker accepting the token
+// rejects the body without verifying anything.
+func (p *Pool) authorized(ctx context.Context, w *remote) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url+evaluatePath, strings.NewReader("{}"))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusBadRequest:
+		return nil
+	case http.StatusUnauthorized:
+		return errors.New("worker rejects the fleet token")
+	}
+	return fmt.Errorf("token check: %s", resp.Status)
+}
+
+// Evaluate verifies c on the next available worker, trying each worker at
+// most once.
+func (p *Pool) Evaluate(ctx context.Context, base string, c processor.Candidate) (processor.Output, error) {
+	body, err := json.Marshal(request{Base: base, Domain: c.Domain, Strategy: c.Strategy, Likelihood: c.Likelihood})
+	if err != nil {
+		return processor.Output{}, err
+	}
+	start := p.next.Add(1)
+	for i := range len(p.workers) {
+		w := p.workers[(start+uint64(i))%uint64(len(p.workers))]
+		if w.resting(time.Now()) {
+			continue
+		}
+		o, retry, err := p.send(ctx, w, body)
+		if !retry || ctx.Err() != nil {
+			return o, err
+		}
+		p.logger.Warn("processing fleet evaluate", "worker", w.url, "domain", c.Domain, "error", err)
+		w.rest(time.Now().Add(restPeriod))
+	}
+	return processor.Output{}, ErrNoWorkers
+}
+
+// send posts one candidate to w; retry reports whether the failure was the
+// worker's rather than the candidate's.
+func (p *Pool) send(ctx context.Context, w *remote, body []byte) (o processor.Output, retry bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url+evaluatePath, bytes.NewReader(body))
+	if err != nil {
+		return o, false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return o, true, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&o)
+		return o, err != nil, err
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return o, false, fmt.Errorf("worker %s: %s", w.url, strings.TrimSpace(string(msg)))
+	default:
+		return o, true, fmt.Errorf("worker %s: %s", w.url, resp.Status)
+	}
+}
@@ -0,0 +1,93 @@
+package fleet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func fakeWorker(t *testing.T, token string, eval func(base string, c processor.Candidate) (processor.Output, error)) *httptest.Server {
+	t.Helper()
+	w := NewWorker(verify.Config{}, nil, nil, token, 2, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	w.eval = func(_ context.Context, base string, c processor.Candidate) (processor.Output, error) {
+		return eval(base, c)
+	}
+	srv := httptest.NewServer(w)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPool(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	live := fakeWorker(t, "s3cret", func(base string, c processor.Candidate) (processor.Output, error) {
+		if c.Domain == "broken.com" {
+			return processor.Output{}, errors.New("dns timeout")
+		}
+		return processor.Output{Domain: c.Domain, Strategy: c.Strategy, Resolvable: true, ScoreTags: []string{base}}, nil
+	})
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	p, err := NewPool([]string{down.URL, live.URL + "/"}, "s3cret", logger)
+	if err != nil {
+		t.Fatalf("NewPool() error: %v", err)
+	}
+	if err := p.Check(context.Background()); err != nil {
+		t.Fatalf("Check() error: %v", err)
+	}
+
+	tests := []struct {
+		domain  string
+		wantErr bool
+	}{
+		{"exampel.com", false},
+		{"examp1e.com", false},
+		{"broken.com", true},
+		// The worker refuses names a scan request couldn't name, without
+		// being skipped for it: the next candidate still reaches it.
+		{"10.0.0.1", true},
+		{"exampel.corp.internal", true},
+		{"com", true},
+		{"ex.ample.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			o, err := p.Evaluate(context.Background(), "example.com", processor.Candidate{Domain: tt.domain, Strategy: "omission"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (o.Domain != tt.domain || o.Strategy != "omission" || !o.Resolvable || o.ScoreTags[0] != "example.com") {
+				t.Errorf("Evaluate() = %+v, want the worker's result for %s", o, tt.domain)
+			}
+		})
+	}
+
+	if _, err := p.Evaluate(context.Background(), "localhost", processor.Candidate{Domain: "exampel.com"}); err == nil || errors.Is(err, ErrNoWorkers) {
+		t.Errorf("Evaluate() of an unregistrable base error = %v, want the worker's refusal", err)
+	}
+
+	wrong, _ := NewPool([]string{live.URL}, "guess", logger)
+	if err := wrong.Check(context.Background()); !errors.Is(err, ErrNoWorkers) {
+		t.Errorf("Check() with a wrong token error = %v, want ErrNoWorkers", err)
+	}
+	wrong, _ = NewPool([]string{live.URL}, "guess", logger)
+	if _, err := wrong.Evaluate(context.Background(), "example.com", processor.Candidate{Domain: "exampel.com"}); !errors.Is(err, ErrNoWorkers) {
+		t.Errorf("Evaluate() with a wrong token error = %v, want ErrNoWorkers", err)
+	}
+}
+
+func TestNewPool(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if _, err := NewPool(nil, "", logger); err == nil {
+		t.Error("NewPool(no URLs) succeeded")
+	}
+	if _, err := NewPool([]string{"worker-1:9000"}, "", logger); err == nil {
+		t.Error("NewPool(URL without scheme) succeeded")
+	}
+}
@@ -0,0 +1,63 @@
+package geo
+
+/*
+  This library maps resolved IPs to hosting countries from a local MaxMind
+  (GeoLite2/GeoIP2 Country or City) or DB-IP Lite mmdb file, for the
+  -geoip flag. It implements verify.GeoLocator.
+*/
+
+import (
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// DefaultHighRisk is the starting -high-risk-countries list: the FATF
+// "high-risk jurisdictions subject to a call for action" (ISO 3166-1
+// alpha-2). Hosting there makes takedown requests unlikely to succeed.
+var DefaultHighRisk = []string{"KP", "IR", "MM"}
+
+// DB is an open mmdb country database.
+type DB struct {
+	reader *maxminddb.Reader
+}
+
+type record struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// Open memory-maps the mmdb file at path.
+func Open(path string) (*DB, error) {
+	reader, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Country returns the ISO country code for ip, falling back to the country
+// the block is registered to, or "" when unknown.
+func (db *DB) Country(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+	var r record
+	if err := db.reader.Lookup(addr, &r); err != nil {
+		return ""
+	}
+	if r.Country.ISOCode != "" {
+		return strings.ToUpper(r.Country.ISOCode)
+	}
+	return strings.ToUpper(r.RegisteredCountry.ISOCode)
+}
+
+func (db *DB) Close() error {
+	return db.reader.Close()
+}
@@ -0,0 +1,66 @@
+package graph
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// KindRegistrar nodes link candidates to their sponsoring registrar.
+const KindRegistrar = "registrar"
+
+// cypherLabels maps node kinds to Neo4j labels.
+var cypherLabels = map[string]string{
+	KindDomain:    "Domain",
+	KindIP:        "IP",
+	KindASN:       "ASN",
+	KindNS:        "NS",
+	KindCert:      "Cert",
+	KindTracking:  "TrackingID",
+	KindRegistrar: "Registrar",
+}
+
+// cypherRelationships maps the kind of an edge's target node to the
+// relationship type written from the candidate Domain node.
+var cypherRelationships = map[string]string{
+	KindIP:        "RESOLVES_TO",
+	KindASN:       "HOSTED_IN",
+	KindNS:        "USES_NS",
+	KindCert:      "PRESENTS_CERT",
+	KindTracking:  "CARRIES_ID",
+	KindRegistrar: "REGISTERED_WITH",
+}
+
+// WriteCypher renders the graph as idempotent Cypher statements suitable for
+// cypher-shell. Nodes and relationships are MERGEd so statements from many runs
+// can be loaded into the same database; first_seen/last_seen track history.
+func (g *Graph) WriteCypher(w io.Writer, observed time.Time) error {
+	ts := cypherString(observed.UTC().Format(time.RFC3339))
+	kinds, labels := map[string]string{}, map[string]string{}
+
+	var b strings.Builder
+	for _, kind := range []string{KindDomain, KindIP, KindASN, KindNS, KindCert, KindTracking, KindRegistrar} {
+		fmt.Fprintf(&b, "CREATE CONSTRAINT IF NOT EXISTS FOR (n:%s) REQUIRE n.name IS UNIQUE;\n", cypherLabels[kind])
+	}
+	for _, n := range g.nodes {
+		kinds[n.ID], labels[n.ID] = n.Kind, n.Label
+		fmt.Fprintf(&b, "MERGE (n:%s {name: %s}) ON CREATE SET n.first_seen = %s SET n.last_seen = %s;\n",
+			cypherLabels[n.Kind], cypherString(n.Label), ts, ts)
+	}
+	for _, e := range g.edges {
+		to := kinds[e.To]
+		fmt.Fprintf(&b, "MATCH (a:%s {name: %s}), (b:%s {name: %s}) MERGE (a)-[r:%s]->(b) ON CREATE SET r.first_seen = %s SET r.last_seen = %s;\n",
+			cypherLabels[kinds[e.From]], cypherString(labels[e.From]),
+			cypherLabels[to], cypherString(labels[e.To]),
+			cypherRelationships[to], ts, ts)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// cypherString quotes s as a single-quoted Cypher string literal.
+func cypherString(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`)
+	return "'" + r.Replace(s) + "'"
+}
@@ -0,0 +1,180 @@
+package graph
+
+/*
+  This library builds an infrastructure relationship graph out of verified
+  candidates so analysts can visualize attacker clusters in Gephi, yEd,
+  Graphviz or Maltego-like tools. Every candidate is a node and each shared
+  attribute (IP, ASN, nameserver, certificate, tracking ID) becomes a node
+  linked to the candidates that carry it.
+*/
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Node kinds emitted by the graph.
+const (
+	KindDomain   = "domain"
+	KindIP       = "ip"
+	KindASN      = "asn"
+	KindNS       = "ns"
+	KindCert     = "cert"
+	KindTracking = "tracking"
+)
+
+type Node struct {
+	ID    string
+	Kind  string
+	Label string
+}
+
+type Edge struct {
+	From string
+	To   string
+}
+
+// Graph is an insertion-ordered, de-duplicated set of nodes and edges.
+type Graph struct {
+	nodes []Node
+	edges []Edge
+	seen  map[string]bool
+}
+
+func New() *Graph {
+	return &Graph{seen: map[string]bool{}}
+}
+
+func nodeID(kind, label string) string {
+	return kind + ":" + strings.ToLower(label)
+}
+
+// AddDomain adds a candidate node and returns its ID.
+func (g *Graph) AddDomain(domain string) string {
+	return g.addNode(KindDomain, domain)
+}
+
+// Link connects a candidate to an attribute node of the given kind,
+// creating both nodes as needed. Empty values are ignored.
+func (g *Graph) Link(domain, kind, value string) {
+	if value == "" {
+		return
+	}
+	from := g.AddDomain(domain)
+	to := g.addNode(kind, value)
+	key := "e|" + from + "|" + to
+	if g.seen[key] {
+		return
+	}
+	g.seen[key] = true
+	g.edges = append(g.edges, Edge{From: from, To: to})
+}
+
+func (g *Graph) addNode(kind, label string) string {
+	id := nodeID(kind, label)
+	if !g.seen["n|"+id] {
+		g.seen["n|"+id] = true
+		g.nodes = append(g.nodes, Node{ID: id, Kind: kind, Label: label})
+	}
+	return id
+}
+
+func (g *Graph) Nodes() []Node { return g.nodes }
+func (g *Graph) Edges() []Edge { return g.edges }
+
+// dotShapes gives each node kind a distinct Graphviz shape.
+var dotShapes = map[string]string{
+	KindDomain:    "box",
+	KindIP:        "ellipse",
+	KindASN:       "hexagon",
+	KindNS:        "diamond",
+	KindCert:      "note",
+	KindTracking:  "octagon",
+	KindRegistrar: "house",
+}
+
+// WriteDOT renders the graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("graph squatrr {\n")
+	b.WriteString("  overlap=false;\n")
+	for _, n := range g.nodes {
+		fmt.Fprintf(&b, "  %q [label=%q, kind=%q, shape=%s];\n", n.ID, n.Label, n.Kind, dotShapes[n.Kind])
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(&b, "  %q -- %q;\n", e.From, e.To)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WriteGraphML renders the graph as GraphML, which Gephi and yEd import directly.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	doc := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "kind", For: "node", Name: "kind", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "squatrr", EdgeDefault: "undirected"},
+	}
+	for _, n := range g.nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID:   n.ID,
+			Data: []graphMLData{{Key: "label", Value: n.Label}, {Key: "kind", Value: n.Kind}},
+		})
+	}
+	for _, e := range g.edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{Source: e.From, Target: e.To})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testGraph() *Graph {
+	g := New()
+	g.Link("exampel.com", KindIP, "203.0.113.10")
+	g.Link("examp1e.com", KindIP, "203.0.113.10")
+	g.Link("exampel.com", KindIP, "203.0.113.10") // duplicate edge
+	g.Link("exampel.com", KindNS, "ns1.parking.test")
+	g.Link("exampel.com", KindTracking, "")
+	return g
+}
+
+func TestLinkDeduplicates(t *testing.T) {
+	g := testGraph()
+	if got := len(g.Nodes()); got != 4 {
+		t.Errorf("len(Nodes()) = %d, want 4", got)
+	}
+	if got := len(g.Edges()); got != 3 {
+		t.Errorf("len(Edges()) = %d, want 3", got)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteDOT(&buf); err != nil {
+		t.Fatalf("WriteDOT() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`graph squatrr {`,
+		`"domain:exampel.com" [label="exampel.com", kind="domain", shape=box];`,
+		`"domain:examp1e.com" -- "ip:203.0.113.10";`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteDOT() missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	var buf bytes.Buffer
+	if err := testGraph().WriteGraphML(&buf); err != nil {
+		t.Fatalf("WriteGraphML() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<graph id="squatrr" edgedefault="undirected">`,
+		`<node id="ns:ns1.parking.test">`,
+		`<edge source="domain:exampel.com" target="ns:ns1.parking.test"></edge>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteGraphML() missing %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteCypher(t *testing.T) {
+	g := testGraph()
+	g.Link("o'brien.com", KindRegistrar, "Example Registrar, Inc.")
+
+	var buf bytes.Buffer
+	if err := g.WriteCypher(&buf, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("WriteCypher() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`CREATE CONSTRAINT IF NOT EXISTS FOR (n:Domain) REQUIRE n.name IS UNIQUE;`,
+		`MERGE (n:IP {name: '203.0.113.10'}) ON CREATE SET n.first_seen = '2024-05-01T12:00:00Z' SET n.last_seen = '2024-05-01T12:00:00Z';`,
+		`MATCH (a:Domain {name: 'exampel.com'}), (b:NS {name: 'ns1.parking.test'}) MERGE (a)-[r:USES_NS]->(b)`,
+		`MATCH (a:Domain {name: 'o\'brien.com'}), (b:Registrar {name: 'Example Registrar, Inc.'}) MERGE (a)-[r:REGISTERED_WITH]->(b)`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("WriteCypher() missing %q in:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package history
+
+import (
+	"slices"
+	"squatrr/lib/processor"
+	"squatrr/lib/sink"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Diff compares a candidate's current state against its previous
+// observation, field by field. State transitions (a new IP, a new
+// certificate issuer, parked to live) are what analysts need alerting on;
+// an unchanged candidate yields nil.
+func Diff(prev, cur processor.Output) []processor.Change {
+	var changes []processor.Change
+	scalar := func(field, was, is string) {
+		if was != is {
+			changes = append(changes, processor.Change{Field: field, Old: was, New: is})
+		}
+	}
+	set := func(field string, was, is []string) {
+		gone, added := setDiff(was, is), setDiff(is, was)
+		if len(gone) > 0 || len(added) > 0 {
+			changes = append(changes, processor.Change{Field: field, Old: strings.Join(gone, " "), New: strings.Join(added, " ")})
+		}
+	}
+
+	scalar("resolvable", strconv.FormatBool(prev.Resolvable), strconv.FormatBool(cur.Resolvable))
+	set("a", prev.DNS.A, cur.DNS.A)
+	set("aaaa", prev.DNS.AAAA, cur.DNS.AAAA)
+	scalar("cname", prev.DNS.CNAME, cur.DNS.CNAME)
+	set("ns", prev.DNS.NS, cur.DNS.NS)
+	set("mx", prev.DNS.MX, cur.DNS.MX)
+
+	// TLS and HTTP are compared only when both scans probed them, so
+	// toggling -tls or -http between runs doesn't read as a change.
+	if prev.TLS != nil && cur.TLS != nil {
+		scalar("tls_connected", strconv.FormatBool(prev.TLS.Connected), strconv.FormatBool(cur.TLS.Connected))
+		scalar("tls_issuer", prev.TLS.Issuer, cur.TLS.Issuer)
+		scalar("tls_fingerprint", prev.TLS.FingerprintSHA256, cur.TLS.FingerprintSHA256)
+		if prev.TLS.Validity != "" && cur.TLS.Validity != "" {
+			scalar("tls_validity", prev.TLS.Validity, cur.TLS.Validity)
+		}
+	}
+	if prev.HTTP != nil && cur.HTTP != nil && prev.HTTP.Attempted && cur.HTTP.Attempted {
+		scalar("http_status", strconv.Itoa(prev.HTTP.StatusCode), strconv.Itoa(cur.HTTP.StatusCode))
+		scalar("http_location", prev.HTTP.Location, cur.HTTP.Location)
+		if at := cur.HTTP.ContentChangedAt; at.After(prev.HTTP.ContentChangedAt) {
+			changes = append(changes, processor.Change{Field: "http_content", New: at.Format(time.RFC3339)})
+		}
+	}
+	if prev.Class != "" && cur.Class != "" {
+		scalar("class", prev.Class, cur.Class)
+	}
+	return changes
+}
+
+// setDiff returns the members of a missing from b, sorted.
+func setDiff(a, b []string) []string {
+	var out []string
+	for _, v := range a {
+		if !slices.Contains(b, v) && !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// ChangeDetector is a sink marking each result Changed, with its field
+// diffs, when it differs from the candidate's previous observation of base,
+// and carrying over its case State and Assignee, then passing it on to
+// next. It only reads the store, so it must see each
+// result before a Recorder on the same store overwrites that observation:
+// wrap the sinks that include the Recorder.
+type ChangeDetector struct {
+	store   *Store
+	base    string
+	next    sink.Sink
+	changed int
+}
+
+// ChangeDetector compares results of base against the store before passing
+// them to next.
+func (s *Store) ChangeDetector(base string, next sink.Sink) *ChangeDetector {
+	return &ChangeDetector{store: s, base: base, next: next}
+}
+
+func (c *ChangeDetector) Write(o processor.Output) error {
+	if !o.Live() && len(o.Errors) > 0 { // unchecked, not changed
+		return c.next.Write(o)
+	}
+	var prev *Domain
+	err := c.store.db.View(func(tx *bolt.Tx) error {
+		b := baseBucket(tx, c.base)
+		if b == nil {
+			return nil
+		}
+		raw := b.Bucket(bucketDomains).Get([]byte(o.Domain))
+		if raw == nil {
+			return nil
+		}
+		d, err := decodeDomain(raw)
+		prev = &d
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	o.State = StateNew
+	if prev != nil {
+		o.State, o.Assignee = prev.State, prev.Assignee
+		o.Changes = Diff(prev.Latest, o)
+		if !prev.Live && o.Live() { // remediated since, and back
+			o.Changes = append([]processor.Change{{Field: "live", Old: "false", New: "true"}}, o.Changes...)
+		}
+		o.Changed = len(o.Changes) > 0
+	}
+	if o.Changed {
+		c.changed++
+	}
+	return c.next.Write(o)
+}
+
+// Changed counts the results marked changed so far.
+func (c *ChangeDetector) Changed() int {
+	return c.changed
+}
+
+func (c *ChangeDetector) Close() error {
+	return c.next.Close()
+}
@@ -0,0 +1,150 @@
+package history
+
+import (
+	"path/filepath"
+	"reflect"
+	"squatrr/lib/processor"
+	"squatrr/lib/sink"
+	"squatrr/lib/verify"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	prev := processor.Output{
+		Domain:     "exampel.com",
+		Resolvable: true,
+		DNS:        verify.DNSResult{A: []string{"192.0.2.1", "192.0.2.2"}, NS: []string{"ns1.sedoparking.com"}},
+		TLS:        &verify.TLSResult{Connected: true, Issuer: "CN=R3", Validity: verify.CertCurrent},
+		HTTP:       &verify.HTTPResult{Attempted: true, StatusCode: 302, Location: "https://sedo.com/"},
+		Class:      "parked",
+	}
+	tests := []struct {
+		name string
+		cur  func(o processor.Output) processor.Output
+		want []processor.Change
+	}{
+		{"unchanged", func(o processor.Output) processor.Output { return o }, nil},
+		{
+			name: "new IP",
+			cur: func(o processor.Output) processor.Output {
+				o.DNS.A = []string{"192.0.2.2", "198.51.100.7"}
+				return o
+			},
+			want: []processor.Change{{Field: "a", Old: "192.0.2.1", New: "198.51.100.7"}},
+		},
+		{
+			name: "parked to live",
+			cur: func(o processor.Output) processor.Output {
+				o.DNS.NS = []string{"ns1.cheaphost.test"}
+				o.TLS = &verify.TLSResult{Connected: true, Issuer: "CN=ZeroSSL"}
+				o.HTTP = &verify.HTTPResult{Attempted: true, StatusCode: 200}
+				o.Class = "phishing"
+				return o
+			},
+			want: []processor.Change{
+				{Field: "ns", Old: "ns1.sedoparking.com", New: "ns1.cheaphost.test"},
+				{Field: "tls_issuer", Old: "CN=R3", New: "CN=ZeroSSL"},
+				{Field: "http_status", Old: "302", New: "200"},
+				{Field: "http_location", Old: "https://sedo.com/"},
+				{Field: "class", Old: "parked", New: "phishing"},
+			},
+		},
+		{
+			name: "certificate expired",
+			cur: func(o processor.Output) processor.Output {
+				o.TLS = &verify.TLSResult{Connected: true, Issuer: "CN=R3", Validity: verify.CertExpired, DaysUntilExpiry: -1}
+				return o
+			},
+			want: []processor.Change{{Field: "tls_validity", Old: verify.CertCurrent, New: verify.CertExpired}},
+		},
+		{
+			name: "content revalidated as changed",
+			cur: func(o processor.Output) processor.Output {
+				o.HTTP = &verify.HTTPResult{Attempted: true, StatusCode: 302, Location: "https://sedo.com/", ContentChangedAt: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+				return o
+			},
+			want: []processor.Change{{Field: "http_content", New: "2026-10-16T09:00:00Z"}},
+		},
+		{
+			name: "not probed this time",
+			cur: func(o processor.Output) processor.Output {
+				o.TLS, o.HTTP, o.Class = nil, nil, ""
+				return o
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(prev, tt.cur(prev)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// collect is a sink keeping what it is written.
+type collect struct{ outputs []processor.Output }
+
+func (c *collect) Write(o processor.Output) error { c.outputs = append(c.outputs, o); return nil }
+func (c *collect) Close() error                   { return nil }
+
+func TestChangeDetector(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"), false)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+
+	full := processor.Stats{Population: 2, Queued: 2, Dispatched: 2}
+	scan := func(outputs ...processor.Output) *collect {
+		t.Helper()
+		got := &collect{}
+		d := s.ChangeDetector("example.com", sink.Multi{got, s.Recorder("example.com", &full)})
+		for _, o := range outputs {
+			if err := d.Write(o); err != nil {
+				t.Fatalf("Write() error: %v", err)
+			}
+		}
+		if err := d.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+		return got
+	}
+
+	a := processor.Output{Domain: "exampel.com", Resolvable: true, DNS: verify.DNSResult{A: []string{"192.0.2.1"}}}
+	b := processor.Output{Domain: "examp1e.com", Resolvable: true}
+	if got := scan(a, b); got.outputs[0].Changed || got.outputs[1].Changed {
+		t.Errorf("first scan = %+v, want nothing changed", got.outputs)
+	}
+	if err := s.SetState("example.com", []string{"exampel.com"}, StateTriaged); err != nil {
+		t.Fatalf("SetState() error: %v", err)
+	}
+
+	moved := a
+	moved.DNS = verify.DNSResult{A: []string{"192.0.2.9"}}
+	got := scan(moved) // examp1e.com is remediated
+	if !got.outputs[0].Changed || len(got.outputs[0].Changes) != 1 || got.outputs[0].State != StateTriaged {
+		t.Errorf("moved = %+v, want one change, still triaged", got.outputs[0])
+	}
+
+	got = scan(moved, b)
+	want := []processor.Change{{Field: "live", Old: "false", New: "true"}}
+	if got.outputs[0].Changed || !got.outputs[1].Changed || !reflect.DeepEqual(got.outputs[1].Changes, want) {
+		t.Errorf("third scan = %+v, want only examp1e.com changed (back live)", got.outputs)
+	}
+	if runs, _ := s.Runs("example.com"); runs[2].Changed != 1 {
+		t.Errorf("run changed = %d, want 1", runs[2].Changed)
+	}
+
+	// Remediated again, then output dead by -include-unresolvable: still
+	// gone, not back.
+	scan(moved)
+	got = scan(moved, processor.Output{Domain: "examp1e.com"})
+	for _, c := range got.outputs[1].Changes {
+		if c.Field == "live" {
+			t.Errorf("dead examp1e.com changes = %+v, want no live change", got.outputs[1].Changes)
+		}
+	}
+}
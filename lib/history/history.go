@@ -0,0 +1,497 @@
+package history
+
+/*
+  This library keeps a record of every scan so monitoring runs can be compared
+  over time. Each run and the latest state of each live candidate are stored
+  per base domain in a BoltDB file; trends (live squats, new domains per week,
+  remediation rate) are derived from those records on demand.
+*/
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"squatrr/lib/processor"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	bucketBases   = []byte("bases")
+	bucketRuns    = []byte("runs")
+	bucketDomains = []byte("domains")
+)
+
+// ErrNoHistory is returned when a base domain has never been recorded.
+var ErrNoHistory = errors.New("no history for base domain")
+
+// ErrUnknownDomain is returned for a candidate never found live.
+var ErrUnknownDomain = errors.New("candidate not in history")
+
+// Case states of a candidate, set by analysts. Scans never change a
+// candidate's state, so triage decisions survive re-scans.
+const (
+	StateNew          = "new"
+	StateTriaged      = "triaged"
+	StateReported     = "reported"
+	StateRemediated   = "remediated"
+	StateAcceptedRisk = "accepted-risk"
+	StateOwned        = "owned" // the brand's own registration, e.g. proved by the ownership mode
+)
+
+// States lists the case states in workflow order.
+var States = []string{StateNew, StateTriaged, StateReported, StateRemediated, StateAcceptedRisk, StateOwned}
+
+// ErrInvalidState is returned for a state not in States.
+var ErrInvalidState = errors.New("invalid case state")
+
+// ErrEmptyNote is returned for a note without text.
+var ErrEmptyNote = errors.New("empty note")
+
+// Run summarizes one recorded scan.
+type Run struct {
+	ID         uint64    `json:"id"`
+	Base       string    `json:"base"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Complete   bool      `json:"complete"`   // every candidate was verified, so absences count as remediation
+	Live       int       `json:"live"`       // live candidates found by this run
+	New        int       `json:"new"`        // live candidates never seen by an earlier run
+	Remediated int       `json:"remediated"` // previously live candidates this run found gone
+	Changed    int       `json:"changed"`    // candidates whose state differed from their previous observation
+}
+
+// Domain is the tracked state of one candidate across runs.
+type Domain struct {
+	Domain       string           `json:"domain"`
+	FirstSeen    time.Time        `json:"first_seen"`
+	LastSeen     time.Time        `json:"last_seen"`
+	Live         bool             `json:"live"`
+	RemediatedAt time.Time        `json:"remediated_at,omitzero"`
+	Runs         int              `json:"runs"` // runs the candidate was found live in
+	State        string           `json:"state"`
+	Assignee     string           `json:"assignee,omitempty"`
+	Notes        []Note           `json:"notes,omitempty"` // oldest first
+	Latest       processor.Output `json:"latest"`
+
+	// Takedowns are the takedown requests submitted for the candidate,
+	// oldest first.
+	Takedowns []processor.Submission `json:"takedowns,omitempty"`
+}
+
+// Note is an analyst's note on a candidate.
+type Note struct {
+	Author string    `json:"author,omitempty"`
+	Text   string    `json:"text"`
+	At     time.Time `json:"at"`
+}
+
+// Store is a BoltDB file of recorded runs.
+type Store struct {
+	db  *bolt.DB
+	now func() time.Time
+}
+
+// Open opens (or creates) the history file at path. Read-only stores share
+// the file with each other but not with a scan recording into it.
+func Open(path string, readOnly bool) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second, ReadOnly: readOnly})
+	if err != nil {
+		return nil, err
+	}
+	return &Store{db: db, now: time.Now}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Bases lists every base domain with recorded runs.
+func (s *Store) Bases() ([]string, error) {
+	bases := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketBases)
+		if b == nil {
+			return nil
+		}
+		return b.ForEachBucket(func(k []byte) error {
+			bases = append(bases, string(k))
+			return nil
+		})
+	})
+	return bases, err
+}
+
+// Runs returns the recorded runs for base, oldest first.
+func (s *Store) Runs(base string) ([]Run, error) {
+	runs := []Run{}
+	err := s.view(base, func(b *bolt.Bucket) error {
+		return b.Bucket(bucketRuns).ForEach(func(_, v []byte) error {
+			var r Run
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			runs = append(runs, r)
+			return nil
+		})
+	})
+	return runs, err
+}
+
+// Domains returns every candidate ever found live for base, by name.
+func (s *Store) Domains(base string) ([]Domain, error) {
+	domains := []Domain{}
+	err := s.view(base, func(b *bolt.Bucket) error {
+		return b.Bucket(bucketDomains).ForEach(func(_, v []byte) error {
+			d, err := decodeDomain(v)
+			if err != nil {
+				return err
+			}
+			domains = append(domains, d)
+			return nil
+		})
+	})
+	return domains, err
+}
+
+// LastRun returns the most recently finished run of any base domain, or
+// ErrNoHistory before the first.
+func (s *Store) LastRun() (Run, error) {
+	bases, err := s.Bases()
+	if err != nil {
+		return Run{}, err
+	}
+	var last Run
+	for _, base := range bases {
+		runs, err := s.Runs(base)
+		if err != nil {
+			return Run{}, err
+		}
+		for _, r := range runs {
+			if r.Finished.After(last.Finished) {
+				last = r
+			}
+		}
+	}
+	if last.ID == 0 {
+		return Run{}, ErrNoHistory
+	}
+	return last, nil
+}
+
+// RecordSubmission appends a takedown request to domain's record,
+// moving a new or triaged case to reported.
+func (s *Store) RecordSubmission(base, domain string, sub processor.Submission) error {
+	return s.updateDomains(base, []string{domain}, func(d *Domain) {
+		d.Takedowns = append(d.Takedowns, sub)
+		if d.State == StateNew || d.State == StateTriaged {
+			d.State = StateReported
+		}
+	})
+}
+
+// SetState sets the case state of domains, all or none of them.
+func (s *Store) SetState(base string, domains []string, state string) error {
+	if !slices.Contains(States, state) {
+		return fmt.Errorf("%w %q", ErrInvalidState, state)
+	}
+	return s.updateDomains(base, domains, func(d *Domain) { d.State = state })
+}
+
+// Assign assigns domains to assignee, or unassigns them when it is empty.
+func (s *Store) Assign(base string, domains []string, assignee string) error {
+	return s.updateDomains(base, domains, func(d *Domain) { d.Assignee = assignee })
+}
+
+// AddNote appends a note to domain's record, stamping it with the current
+// time.
+func (s *Store) AddNote(base, domain string, n Note) (Note, error) {
+	n.Text = strings.TrimSpace(n.Text)
+	if n.Text == "" {
+		return Note{}, ErrEmptyNote
+	}
+	n.At = s.now().UTC()
+	err := s.updateDomains(base, []string{domain}, func(d *Domain) { d.Notes = append(d.Notes, n) })
+	return n, err
+}
+
+// updateDomains applies fn to the records of domains in one transaction,
+// failing with ErrUnknownDomain if any isn't recorded.
+func (s *Store) updateDomains(base string, names []string, fn func(*Domain)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := baseBucket(tx, base)
+		if b == nil {
+			return ErrNoHistory
+		}
+		domains := b.Bucket(bucketDomains)
+		for _, name := range names {
+			raw := domains.Get([]byte(name))
+			if raw == nil {
+				return fmt.Errorf("%w: %s", ErrUnknownDomain, name)
+			}
+			d, err := decodeDomain(raw)
+			if err != nil {
+				return err
+			}
+			fn(&d)
+			if err := putJSON(domains, []byte(name), d); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// decodeDomain decodes a stored record; records from before case states
+// are new.
+func decodeDomain(raw []byte) (Domain, error) {
+	var d Domain
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return Domain{}, err
+	}
+	if d.State == "" {
+		d.State = StateNew
+	}
+	return d, nil
+}
+
+func (s *Store) view(base string, fn func(*bolt.Bucket) error) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := baseBucket(tx, base)
+		if b == nil {
+			return ErrNoHistory
+		}
+		return fn(b)
+	})
+}
+
+func baseBucket(tx *bolt.Tx, base string) *bolt.Bucket {
+	if b := tx.Bucket(bucketBases); b != nil {
+		return b.Bucket([]byte(base))
+	}
+	return nil
+}
+
+func createBaseBucket(tx *bolt.Tx, base string) (*bolt.Bucket, error) {
+	bases, err := tx.CreateBucketIfNotExists(bucketBases)
+	if err != nil {
+		return nil, err
+	}
+	b, err := bases.CreateBucketIfNotExists([]byte(base))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range [][]byte{bucketRuns, bucketDomains} {
+		if _, err := b.CreateBucketIfNotExists(name); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// Recorder is a sink recording one scan of base into the store. Every live
+// result the pipeline emits is a sighting; candidates live in an earlier run but
+// absent from this one are marked remediated only when the run verified the
+// whole candidate population, since a sampled, sharded, targeted, capped or
+// interrupted run proves nothing about what it skipped, nor a result with Errors about
+// the candidate it couldn't check.
+type Recorder struct {
+	store *Store
+	stats *processor.Stats
+	run   Run
+	seen  map[string]bool
+}
+
+// Recorder starts recording a run of base. stats must be filled in by the
+// time the recorder is closed.
+func (s *Store) Recorder(base string, stats *processor.Stats) *Recorder {
+	return &Recorder{store: s, stats: stats, run: Run{Base: base, Started: s.now().UTC()}, seen: map[string]bool{}}
+}
+
+func (r *Recorder) Write(o processor.Output) error {
+	if r.seen[o.Domain] {
+		return nil
+	}
+	if !o.Live() {
+		// Not a sighting; but one that couldn't be checked isn't evidence
+		// of remediation either.
+		if len(o.Errors) > 0 {
+			r.seen[o.Domain] = true
+		}
+		return nil
+	}
+	r.seen[o.Domain] = true
+	if o.Changed {
+		r.run.Changed++
+	}
+	now := r.store.now().UTC()
+	return r.store.db.Update(func(tx *bolt.Tx) error {
+		b, err := createBaseBucket(tx, r.run.Base)
+		if err != nil {
+			return err
+		}
+		domains := b.Bucket(bucketDomains)
+		d := Domain{Domain: o.Domain, FirstSeen: now, State: StateNew}
+		if raw := domains.Get([]byte(o.Domain)); raw != nil {
+			if d, err = decodeDomain(raw); err != nil {
+				return err
+			}
+		} else {
+			r.run.New++
+		}
+		d.LastSeen, d.Live, d.RemediatedAt, d.Latest = now, true, time.Time{}, o
+		d.Runs++
+		r.run.Live++
+		return putJSON(domains, []byte(o.Domain), d)
+	})
+}
+
+// Close marks vanished candidates remediated (complete runs only) and
+// stores the run summary.
+func (r *Recorder) Close() error {
+	now := r.store.now().UTC()
+	r.run.Finished = now
+	if r.stats != nil {
+		st := r.stats
+		r.run.Complete = st.Complete()
+		if !st.Started.IsZero() {
+			r.run.Started = st.Started.UTC()
+		}
+	}
+	return r.store.db.Update(func(tx *bolt.Tx) error {
+		b, err := createBaseBucket(tx, r.run.Base)
+		if err != nil {
+			return err
+		}
+		if r.run.Complete {
+			domains := b.Bucket(bucketDomains)
+			var gone []Domain
+			err := domains.ForEach(func(k, v []byte) error {
+				if r.seen[string(k)] {
+					return nil
+				}
+				d, err := decodeDomain(v)
+				if err != nil {
+					return err
+				}
+				if d.Live {
+					gone = append(gone, d)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			// Bolt forbids modifying a bucket while iterating it.
+			for _, d := range gone {
+				d.Live, d.RemediatedAt = false, now
+				if err := putJSON(domains, []byte(d.Domain), d); err != nil {
+					return err
+				}
+			}
+			r.run.Remediated = len(gone)
+		}
+		runs := b.Bucket(bucketRuns)
+		id, err := runs.NextSequence()
+		if err != nil {
+			return err
+		}
+		r.run.ID = id
+		return putJSON(runs, binary.BigEndian.AppendUint64(nil, id), r.run)
+	})
+}
+
+func putJSON(b *bolt.Bucket, key []byte, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, raw)
+}
+
+// Week aggregates the runs started in one week (Monday 00:00 UTC onward).
+type Week struct {
+	Start      time.Time `json:"start"`
+	Runs       int       `json:"runs"`
+	Live       int       `json:"live"` // live candidates at the week's last run, carried over quiet weeks
+	New        int       `json:"new"`
+	Remediated int       `json:"remediated"`
+}
+
+// Trends is the history of one base domain in dashboard form.
+type Trends struct {
+	Base            string  `json:"base"`
+	Runs            []Run   `json:"runs"`
+	Weeks           []Week  `json:"weeks"`
+	Live            int     `json:"live"`       // candidates live as of the latest run
+	EverSeen        int     `json:"ever_seen"`  // candidates found live by any run
+	Remediated      int     `json:"remediated"` // of those, ones since gone
+	RemediationRate float64 `json:"remediation_rate"`
+}
+
+// Trends derives the weekly live/new/remediated series for base.
+func (s *Store) Trends(base string) (Trends, error) {
+	t := Trends{Base: base, Weeks: []Week{}}
+	var err error
+	if t.Runs, err = s.Runs(base); err != nil {
+		return t, err
+	}
+	domains, err := s.Domains(base)
+	if err != nil {
+		return t, err
+	}
+	t.EverSeen = len(domains)
+	for _, d := range domains {
+		if d.Live {
+			t.Live++
+		} else {
+			t.Remediated++
+		}
+	}
+	if t.EverSeen > 0 {
+		t.RemediationRate = float64(t.Remediated) / float64(t.EverSeen)
+	}
+	t.Weeks = weekly(t.Runs)
+	return t, nil
+}
+
+// weekly buckets runs by week, filling the gaps between them so charts keep
+// a uniform time axis.
+func weekly(runs []Run) []Week {
+	weeks := []Week{}
+	if len(runs) == 0 {
+		return weeks
+	}
+	runs = slices.Clone(runs)
+	slices.SortStableFunc(runs, func(a, b Run) int { return a.Started.Compare(b.Started) })
+	for _, r := range runs {
+		start := weekStart(r.Started)
+		for len(weeks) > 0 && weeks[len(weeks)-1].Start.Before(start) {
+			last := weeks[len(weeks)-1]
+			next := last.Start.AddDate(0, 0, 7)
+			if !next.Before(start) {
+				break
+			}
+			weeks = append(weeks, Week{Start: next, Live: last.Live})
+		}
+		if len(weeks) == 0 || !weeks[len(weeks)-1].Start.Equal(start) {
+			weeks = append(weeks, Week{Start: start})
+		}
+		w := &weeks[len(weeks)-1]
+		w.Runs++
+		w.Live = r.Live
+		w.New += r.New
+		w.Remediated += r.Remediated
+	}
+	return weeks
+}
+
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
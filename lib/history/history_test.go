@@ -0,0 +1,276 @@
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"), false)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC) // a Monday
+	s.now = func() time.Time { return now }
+
+	full := processor.Stats{Population: 10, Queued: 10, Dispatched: 10}
+	sampled := processor.Stats{Population: 10, Queued: 1, Dispatched: 1, SampleRate: 0.1}
+	record := func(stats processor.Stats, domains ...string) {
+		t.Helper()
+		r := s.Recorder("example.com", &stats)
+		for _, d := range domains {
+			if err := r.Write(processor.Output{Domain: d, Resolvable: true}); err != nil {
+				t.Fatalf("Write() error: %v", err)
+			}
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+
+	record(full, "exampel.com", "examp1e.com")
+	now = now.AddDate(0, 0, 7)
+	record(sampled, "exampel.net") // exampel.com missing, but the run was sampled
+	now = now.AddDate(0, 0, 14)
+	record(full, "exampel.com", "exampel.net") // examp1e.com is gone
+
+	tr, err := s.Trends("example.com")
+	if err != nil {
+		t.Fatalf("Trends() error: %v", err)
+	}
+	if len(tr.Runs) != 3 {
+		t.Fatalf("Trends() runs = %d, want 3", len(tr.Runs))
+	}
+	if tr.Runs[1].Complete || tr.Runs[1].Remediated != 0 {
+		t.Errorf("sampled run = %+v, want incomplete with nothing remediated", tr.Runs[1])
+	}
+	if tr.Live != 2 || tr.EverSeen != 3 || tr.Remediated != 1 {
+		t.Errorf("Trends() live, ever seen, remediated = %d, %d, %d, want 2, 3, 1", tr.Live, tr.EverSeen, tr.Remediated)
+	}
+
+	wantWeeks := []Week{
+		{Runs: 1, Live: 2, New: 2},
+		{Runs: 1, Live: 1, New: 1},
+		{Runs: 0, Live: 1},
+		{Runs: 1, Live: 2, Remediated: 1},
+	}
+	if len(tr.Weeks) != len(wantWeeks) {
+		t.Fatalf("Trends() weeks = %+v, want %d", tr.Weeks, len(wantWeeks))
+	}
+	for i, w := range wantWeeks {
+		w.Start = time.Date(2024, 5, 6+7*i, 0, 0, 0, 0, time.UTC)
+		if tr.Weeks[i] != w {
+			t.Errorf("Trends() week %d = %+v, want %+v", i, tr.Weeks[i], w)
+		}
+	}
+
+	last, err := s.LastRun()
+	if err != nil || last.ID != 3 || !last.Finished.Equal(now) {
+		t.Errorf("LastRun() = %+v, %v, want run 3 finished %v", last, err, now)
+	}
+
+	if _, err := s.Trends("other.com"); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("Trends(unknown) error = %v, want ErrNoHistory", err)
+	}
+}
+
+func TestRecorderUnchecked(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"), false)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+
+	full := processor.Stats{Population: 2, Queued: 2, Dispatched: 2}
+	record := func(outputs ...processor.Output) {
+		t.Helper()
+		r := s.Recorder("example.com", &full)
+		for _, o := range outputs {
+			if err := r.Write(o); err != nil {
+				t.Fatalf("Write() error: %v", err)
+			}
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+
+	record(processor.Output{Domain: "exampel.com", Resolvable: true}, processor.Output{Domain: "examp1e.com", Resolvable: true})
+	unchecked := processor.Output{Domain: "exampel.com", Errors: []verify.StageError{{Stage: "verify", Kind: "timeout"}}}
+	record(unchecked) // examp1e.com is gone, exampel.com couldn't be checked
+
+	domains, err := s.Domains("example.com")
+	if err != nil {
+		t.Fatalf("Domains() error: %v", err)
+	}
+	live := map[string]bool{}
+	for _, d := range domains {
+		live[d.Domain] = d.Live
+	}
+	if !live["exampel.com"] || live["examp1e.com"] {
+		t.Errorf("live = %v, want exampel.com kept live and examp1e.com remediated", live)
+	}
+	if runs, _ := s.Runs("example.com"); runs[1].Live != 0 {
+		t.Errorf("run live = %d, want the unchecked candidate not counted", runs[1].Live)
+	}
+}
+
+func TestRecordSubmission(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"), false)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+
+	sub := processor.Submission{Target: "hosting", Provider: "Example Host", Channel: "api", URL: "https://abuse.example.net/v1/reports", ID: "T-1001"}
+	if err := s.RecordSubmission("example.com", "exampel.com", sub); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("RecordSubmission() before any run error = %v, want ErrNoHistory", err)
+	}
+	r := s.Recorder("example.com", nil)
+	if err := r.Write(processor.Output{Domain: "exampel.com", Resolvable: true}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if err := s.RecordSubmission("example.com", "examp1e.com", sub); !errors.Is(err, ErrUnknownDomain) {
+		t.Errorf("RecordSubmission(unseen) error = %v, want ErrUnknownDomain", err)
+	}
+	if err := s.RecordSubmission("example.com", "exampel.com", sub); err != nil {
+		t.Fatalf("RecordSubmission() error: %v", err)
+	}
+
+	// A later sighting keeps the submission.
+	r = s.Recorder("example.com", nil)
+	if err := r.Write(processor.Output{Domain: "exampel.com", Resolvable: true, Score: 60}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	domains, err := s.Domains("example.com")
+	if err != nil || len(domains) != 1 || len(domains[0].Takedowns) != 1 || domains[0].Takedowns[0].ID != "T-1001" || domains[0].State != StateReported {
+		t.Errorf("Domains() = %+v, %v, want exampel.com reported with takedown T-1001", domains, err)
+	}
+}
+
+func TestSetState(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"), false)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+
+	full := processor.Stats{Population: 2, Queued: 2, Dispatched: 2}
+	record := func(domains ...string) {
+		t.Helper()
+		r := s.Recorder("example.com", &full)
+		for _, d := range domains {
+			if err := r.Write(processor.Output{Domain: d, Resolvable: true}); err != nil {
+				t.Fatalf("Write() error: %v", err)
+			}
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+	states := func() map[string]string {
+		t.Helper()
+		domains, err := s.Domains("example.com")
+		if err != nil {
+			t.Fatalf("Domains() error: %v", err)
+		}
+		m := map[string]string{}
+		for _, d := range domains {
+			m[d.Domain] = d.State
+		}
+		return m
+	}
+
+	record("exampel.com", "examp1e.com")
+	if err := s.SetState("example.com", []string{"exampel.com", "examp1e.com"}, StateAcceptedRisk); err != nil {
+		t.Fatalf("SetState() error: %v", err)
+	}
+	if err := s.SetState("example.com", []string{"exampel.com"}, "closed"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("SetState(closed) error = %v, want ErrInvalidState", err)
+	}
+	if err := s.SetState("example.com", []string{"exampel.com", "exampel.org"}, StateTriaged); !errors.Is(err, ErrUnknownDomain) {
+		t.Errorf("SetState(unknown) error = %v, want ErrUnknownDomain", err)
+	}
+	// Re-scans, including one remediating examp1e.com, keep the decisions.
+	record("exampel.com")
+	want := map[string]string{"exampel.com": StateAcceptedRisk, "examp1e.com": StateAcceptedRisk}
+	if got := states(); !reflect.DeepEqual(got, want) {
+		t.Errorf("states = %v, want %v", got, want)
+	}
+	record("exampel.com", "exarnple.com")
+	want["exarnple.com"] = StateNew
+	if got := states(); !reflect.DeepEqual(got, want) {
+		t.Errorf("states = %v, want %v", got, want)
+	}
+}
+
+func TestAssignAndNotes(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.db"), false)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	record := func() {
+		t.Helper()
+		r := s.Recorder("example.com", nil)
+		if err := r.Write(processor.Output{Domain: "exampel.com", Resolvable: true}); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error: %v", err)
+		}
+	}
+	record()
+	if err := s.Assign("example.com", []string{"exampel.com"}, "dana"); err != nil {
+		t.Fatalf("Assign() error: %v", err)
+	}
+	if _, err := s.AddNote("example.com", "exampel.com", Note{Author: "dana", Text: "  "}); !errors.Is(err, ErrEmptyNote) {
+		t.Errorf("AddNote(blank) error = %v, want ErrEmptyNote", err)
+	}
+	if _, err := s.AddNote("example.com", "examp1e.com", Note{Text: "?"}); !errors.Is(err, ErrUnknownDomain) {
+		t.Errorf("AddNote(unknown) error = %v, want ErrUnknownDomain", err)
+	}
+	n, err := s.AddNote("example.com", "exampel.com", Note{Author: "dana", Text: "Login page copied from ours; reported to host.\n"})
+	if err != nil {
+		t.Fatalf("AddNote() error: %v", err)
+	}
+	record() // re-scans keep both
+
+	domains, err := s.Domains("example.com")
+	want := []Note{{Author: "dana", Text: "Login page copied from ours; reported to host.", At: now}}
+	if err != nil || domains[0].Assignee != "dana" || !reflect.DeepEqual(domains[0].Notes, want) || n != want[0] {
+		t.Errorf("Domains() = %+v, %v, want assigned to dana with notes %+v", domains, err, want)
+	}
+}
+
+func TestWeekStart(t *testing.T) {
+	tests := []struct {
+		in   time.Time
+		want time.Time
+	}{
+		{time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 5, 12, 23, 59, 0, 0, time.UTC), time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := weekStart(tt.in); !got.Equal(tt.want) {
+			t.Errorf("weekStart(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package jobs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Event is one detection posted to the scan webhook, e.g. by a CT monitor
+// or a SIEM: candidates of Base to verify, or, naming none, a reason to
+// re-scan Base in full. Lines of the certstream mode's -alerts file are
+// events as they are.
+type Event struct {
+	Base    string   `json:"base,omitempty"`
+	Domain  string   `json:"domain,omitempty"`
+	Domains []string `json:"domains,omitempty"`
+}
+
+// ParseEvents decodes a webhook body: one event, a JSON array of events,
+// or JSON lines of them. Fields other than Event's are ignored.
+func ParseEvents(data []byte) ([]Event, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var events []Event
+		if err := json.Unmarshal(trimmed, &events); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		return events, nil
+	}
+	var events []Event
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	for {
+		var e Event
+		err := dec.Decode(&e)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// Requests turns events into one scan request per base domain, in order of
+// first mention: targeted at the candidates the events name, or a full scan
+// when any event of the base names none. Events without a base are of base.
+// Names aren't checked here: SubmitAll refuses the scans of any that isn't
+// a registrable domain, as it does for every other submission.
+func Requests(events []Event, base string) ([]Request, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: no events", ErrInvalidRequest)
+	}
+	var reqs []Request
+	full := map[string]bool{}
+	index := map[string]int{}
+	for _, e := range events {
+		b := normalize(e.Base)
+		if b == "" {
+			b = normalize(base)
+		}
+		if b == "" {
+			return nil, fmt.Errorf("%w: event without a base domain", ErrInvalidRequest)
+		}
+		i, ok := index[b]
+		if !ok {
+			i = len(reqs)
+			index[b] = i
+			reqs = append(reqs, Request{Domain: b})
+		}
+		targets := e.Domains
+		if e.Domain != "" {
+			targets = append([]string{e.Domain}, targets...)
+		}
+		if len(targets) == 0 {
+			full[b] = true
+		}
+		for _, d := range targets {
+			if d = normalize(d); d == "" {
+				continue
+			}
+			if d != b && !slices.Contains(reqs[i].Targets, d) {
+				reqs[i].Targets = append(reqs[i].Targets, d)
+			}
+		}
+	}
+	for i := range reqs {
+		if full[reqs[i].Domain] {
+			reqs[i].Targets = nil
+		}
+	}
+	return reqs, nil
+}
+
+func normalize(domain string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+}
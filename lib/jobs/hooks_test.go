@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		body    string
+		want    []Event
+		wantErr bool
+	}{
+		{body: `{"base":"example.com"}`, want: []Event{{Base: "example.com"}}},
+		{body: `[{"base":"example.com","domains":["exampel.com"]},{"domain":"examp1e.com"}]`, want: []Event{{Base: "example.com", Domains: []string{"exampel.com"}}, {Domain: "examp1e.com"}}},
+		{
+			// certstream -alerts lines
+			body: "{\"base\":\"example.com\",\"domain\":\"exampel.com\",\"cert_domain\":\"www.exampel.com\",\"seen\":\"2026-10-16T08:00:00Z\"}\n" +
+				"{\"base\":\"example.com\",\"domain\":\"examp1e.com\",\"result\":{\"score\":40}}\n",
+			want: []Event{{Base: "example.com", Domain: "exampel.com"}, {Base: "example.com", Domain: "examp1e.com"}},
+		},
+		{body: `{"base":`, wantErr: true},
+		{body: `[{"base":1}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEvents([]byte(tt.body))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEvents(%s) error = %v, wantErr %v", tt.body, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseEvents(%s) = %+v, want %+v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []Event
+		base    string
+		want    []Request
+		wantErr error
+	}{
+		{
+			name:   "targeted",
+			events: []Event{{Base: "Example.com.", Domain: "EXAMPEL.com"}, {Base: "example.com", Domains: []string{"exampel.com", "examp1e.com", "example.com"}}},
+			want:   []Request{{Domain: "example.com", Targets: []string{"exampel.com", "examp1e.com"}}},
+		},
+		{
+			name:   "full re-scan wins",
+			events: []Event{{Base: "example.com", Domain: "exampel.com"}, {Base: "example.com"}, {Base: "example.org", Domain: "exampel.org"}},
+			want:   []Request{{Domain: "example.com"}, {Domain: "example.org", Targets: []string{"exampel.org"}}},
+		},
+		{
+			name:   "default base",
+			events: []Event{{Domain: "exampel.com"}},
+			base:   "example.com",
+			want:   []Request{{Domain: "example.com", Targets: []string{"exampel.com"}}},
+		},
+		{name: "no base", events: []Event{{Domain: "exampel.com"}}, wantErr: ErrInvalidRequest},
+		{name: "no events", base: "example.com", wantErr: ErrInvalidRequest},
+		{
+			name:   "IDN",
+			events: []Event{{Domain: "exämple.com"}},
+			base:   "example.com",
+			want:   []Request{{Domain: "example.com", Targets: []string{"exämple.com"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Requests(tt.events, tt.base)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Requests() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Requests() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRequestsSubmitted checks that the scans of a webhook naming anything
+// but registrable domains are refused when submitted, all of them.
+func TestRequestsSubmitted(t *testing.T) {
+	tests := []struct {
+		name    string
+		events  []Event
+		wantErr error
+	}{
+		{name: "IP target", events: []Event{{Domain: "exampel.com"}, {Domain: "10.0.0.1"}}, wantErr: ErrInvalidRequest},
+		{name: "localhost target", events: []Event{{Domains: []string{"localhost"}}}, wantErr: ErrInvalidRequest},
+		{name: "internal target", events: []Event{{Domain: "exampel.corp.internal"}}, wantErr: ErrInvalidRequest},
+		{name: "subdomain target", events: []Event{{Domain: "www.exampel.com"}}, wantErr: ErrInvalidRequest},
+		{name: "bad base", events: []Event{{Domain: "exampel.com"}, {Base: "bad..com"}}, wantErr: ErrInvalidRequest},
+		{name: "IP base", events: []Event{{Domain: "exampel.com"}, {Base: "192.0.2.1"}}, wantErr: ErrInvalidRequest},
+		{name: "IDN", events: []Event{{Domain: "exämple.com"}, {Base: "example.org"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqs, err := Requests(tt.events, "example.com")
+			if err != nil {
+				t.Fatalf("Requests() error: %v", err)
+			}
+			q := &Queue{Max: 1, Run: (&gate{}).run}
+			defer q.Close()
+			if _, err := q.SubmitAll("", "t", reqs); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SubmitAll(%+v) error = %v, want %v", reqs, err, tt.wantErr)
+			}
+			want := len(reqs)
+			if tt.wantErr != nil {
+				want = 0
+			}
+			if got := len(q.List("", "")); got != want {
+				t.Errorf("List() = %d jobs, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package jobs
+
+/*
+  This library queues the scans submitted to serve mode. At most Max scans
+  run at once, and at most PerTenant of them for any one tenant (the API
+  token that submitted them, within its workspace); the rest wait in
+  submission order. A tenant at its limit doesn't hold up the scans queued
+  behind its own, so one team submitting a batch of large scans can't
+  starve everyone else. Workspaces share the queue, and so the server's
+  capacity, but never see each other's jobs.
+
+  Jobs are kept in memory: a restart forgets the queue, while the results
+  of finished scans live on wherever the Runner put them.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"reflect"
+	"slices"
+	"squatrr/lib/processor"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/net/publicsuffix"
+)
+
+// Job states.
+const (
+	StateQueued   = "queued"
+	StateRunning  = "running"
+	StateDone     = "done"
+	StateFailed   = "failed"
+	StateCanceled = "canceled"
+)
+
+var (
+	// ErrInvalidRequest is returned for a scan request without a
+	// registrable domain.
+	ErrInvalidRequest = errors.New("invalid scan request")
+
+	// ErrQueueFull is returned when a tenant already has MaxQueued scans
+	// waiting.
+	ErrQueueFull = errors.New("too many queued scans")
+
+	// ErrUnknownJob is returned for a job ID not in the queue (or not the
+	// tenant's).
+	ErrUnknownJob = errors.New("unknown scan")
+
+	// ErrFinished is returned when canceling a scan that already ended.
+	ErrFinished = errors.New("scan already finished")
+)
+
+// Request describes a scan to run.
+type Request struct {
+	Domain  string   `json:"domain"`
+	TLDs    []string `json:"tlds,omitempty"`    // default: the domain's own TLD
+	Max     int      `json:"max,omitempty"`     // optional cap on candidates verified (0 = no cap)
+	Targets []string `json:"targets,omitempty"` // verify only these candidates of Domain (default: a full scan)
+}
+
+// check returns an ErrInvalidRequest unless Domain and every target are
+// registrable domains under a public suffix, and every TLD a public
+// suffix, so a submission can't point scans at IP addresses or internal
+// hosts. Targets are candidates of Domain, registered beside it rather
+// than hosts of some name: neither Domain itself nor a subdomain.
+func (r Request) check() error {
+	if r.Domain == "" {
+		return fmt.Errorf("%w: no domain", ErrInvalidRequest)
+	}
+	if r.Max < 0 {
+		return fmt.Errorf("%w: negative max", ErrInvalidRequest)
+	}
+	if err := CheckRegistrable(r.Domain); err != nil {
+		return err
+	}
+	for _, tld := range r.TLDs {
+		if err := checkSuffix(tld); err != nil {
+			return err
+		}
+	}
+	for _, d := range r.Targets {
+		if err := CheckRegistrable(d); err != nil {
+			return err
+		}
+		if normalize(d) == normalize(r.Domain) {
+			return fmt.Errorf("%w: target %q is the domain itself", ErrInvalidRequest, d)
+		}
+	}
+	return nil
+}
+
+// CheckRegistrable returns an ErrInvalidRequest unless domain, a Unicode or
+// ASCII name, is registrable under a public suffix.
+func CheckRegistrable(domain string) error {
+	ascii, err := publicName(domain)
+	if err != nil {
+		return err
+	}
+	if etld1, err := publicsuffix.EffectiveTLDPlusOne(ascii); err != nil || etld1 != ascii {
+		return fmt.Errorf("%w: %q is not a registrable domain", ErrInvalidRequest, domain)
+	}
+	return nil
+}
+
+// CheckPublic returns an ErrInvalidRequest unless name is a host under a
+// public suffix: a registrable domain or a name under one, such as the
+// candidates the subdomain strategy generates.
+func CheckPublic(name string) error {
+	_, err := publicName(name)
+	return err
+}
+
+func publicName(domain string) (string, error) {
+	ascii, err := toASCII(domain)
+	if err != nil {
+		return "", err
+	}
+	if _, err := netip.ParseAddr(ascii); err == nil {
+		return "", fmt.Errorf("%w: %q is an IP address", ErrInvalidRequest, domain)
+	}
+	// Names under a suffix the list doesn't know, such as localhost or
+	// corp.internal, get their last label as an unlisted, non-ICANN suffix.
+	suffix, icann := publicsuffix.PublicSuffix(ascii)
+	if !icann && !strings.Contains(suffix, ".") {
+		return "", fmt.Errorf("%w: %q is not under a public suffix", ErrInvalidRequest, domain)
+	}
+	if suffix == ascii {
+		return "", fmt.Errorf("%w: %q is a public suffix", ErrInvalidRequest, domain)
+	}
+	return ascii, nil
+}
+
+// checkSuffix returns an ErrInvalidRequest unless tld is a public suffix.
+func checkSuffix(tld string) error {
+	ascii, err := toASCII(strings.TrimPrefix(tld, "."))
+	if err != nil {
+		return err
+	}
+	if suffix, icann := publicsuffix.PublicSuffix(ascii); suffix != ascii || (!icann && !strings.Contains(suffix, ".")) {
+		return fmt.Errorf("%w: %q is not a public suffix", ErrInvalidRequest, tld)
+	}
+	return nil
+}
+
+func toASCII(domain string) (string, error) {
+	ascii, err := idna.Lookup.ToASCII(normalize(domain))
+	if err != nil || strings.Contains("."+ascii+".", "..") {
+		return "", fmt.Errorf("%w: invalid domain %q", ErrInvalidRequest, domain)
+	}
+	return ascii, nil
+}
+
+// Job is the status of one submitted scan.
+type Job struct {
+	ID        string    `json:"id"`
+	Workspace string    `json:"workspace,omitempty"`
+	Tenant    string    `json:"tenant"`
+	Request   Request   `json:"request"`
+	State     string    `json:"state"`
+	Position  int       `json:"position,omitempty"` // place in the queue while queued, from 1
+	Submitted time.Time `json:"submitted"`
+	Started   time.Time `json:"started,omitzero"`
+	Finished  time.Time `json:"finished,omitzero"`
+
+	// Progress of a running or finished scan: candidates verified out of
+	// those queued, and how many of them were found live.
+	Candidates int `json:"candidates"`
+	Verified   int `json:"verified"`
+	Found      int `json:"found"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Runner runs one scan for workspace, updating progress as it goes. It
+// should stop promptly once ctx is canceled.
+type Runner func(ctx context.Context, workspace string, req Request, progress *processor.Progress) error
+
+// Defaults for a Queue's limits.
+const (
+	DefaultMaxQueued = 100
+	DefaultKeep      = 500
+)
+
+// Queue runs submitted scans under concurrency limits.
+type Queue struct {
+	Max       int // scans running at once (at least 1)
+	PerTenant int // scans running at once per tenant (0 = Max)
+	MaxQueued int // scans waiting per tenant (0 = DefaultMaxQueued)
+	Keep      int // finished jobs kept for status queries (0 = DefaultKeep)
+	Run       Runner
+
+	mu      sync.Mutex
+	seq     int
+	jobs    []*job // submission order
+	running int
+	tenants map[tenantKey]int // running scans per tenant
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+// tenantKey identifies a tenant across workspaces.
+type tenantKey struct{ workspace, name string }
+
+type job struct {
+	Job
+	progress processor.Progress
+	cancel   context.CancelFunc
+}
+
+func (j *job) tenant() tenantKey { return tenantKey{j.Workspace, j.Tenant} }
+
+// status snapshots j; position is its place among queued jobs.
+func (j *job) status(position int) Job {
+	s := j.Job
+	s.Request.TLDs, s.Request.Targets = slices.Clone(s.Request.TLDs), slices.Clone(s.Request.Targets)
+	if s.State == StateQueued {
+		s.Position = position
+	} else {
+		s.Candidates, s.Verified, s.Found = j.progress.Queued(), j.progress.Done(), j.progress.Found()
+	}
+	return s
+}
+
+// New returns a queue running at most limit scans at once, perTenant of
+// them per tenant, with run.
+func New(limit, perTenant int, run Runner) *Queue {
+	return &Queue{Max: limit, PerTenant: perTenant, Run: run}
+}
+
+// Submit queues a scan for tenant of workspace and starts it if the
+// limits allow. A request the tenant already has waiting returns that job
+// instead, so a repeated trigger doesn't pile up identical scans.
+func (q *Queue) Submit(workspace, tenant string, req Request) (Job, error) {
+	jobs, err := q.SubmitAll(workspace, tenant, []Request{req})
+	if err != nil {
+		return Job{}, err
+	}
+	return jobs[0], nil
+}
+
+// SubmitAll queues reqs as Submit does, all or none of them: an invalid
+// request, or too few places left in the tenant's queue for the new ones,
+// queues nothing.
+func (q *Queue) SubmitAll(workspace, tenant string, reqs []Request) ([]Job, error) {
+	for _, req := range reqs {
+		if err := req.check(); err != nil {
+			return nil, err
+		}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, errors.New("scan queue closed")
+	}
+	maxQueued := q.MaxQueued
+	if maxQueued <= 0 {
+		maxQueued = DefaultMaxQueued
+	}
+	waiting := 0
+	existing := make([]*job, len(reqs))
+	for _, j := range q.jobs {
+		if j.Workspace == workspace && j.Tenant == tenant && j.State == StateQueued {
+			for i, req := range reqs {
+				if existing[i] == nil && reflect.DeepEqual(j.Request, req) {
+					existing[i] = j
+				}
+			}
+			waiting++
+		}
+	}
+	added := 0
+	for i, req := range reqs {
+		if existing[i] == nil && !slices.ContainsFunc(reqs[:i], func(r Request) bool { return reflect.DeepEqual(r, req) }) {
+			added++
+		}
+	}
+	if added > 0 && waiting+added > maxQueued {
+		return nil, fmt.Errorf("%w: %s has %d waiting", ErrQueueFull, tenant, waiting)
+	}
+
+	jobs := make([]Job, len(reqs))
+	queued := make([]*job, len(reqs))
+	for i, req := range reqs {
+		j := existing[i]
+		if j == nil {
+			if k := slices.IndexFunc(reqs[:i], func(r Request) bool { return reflect.DeepEqual(r, req) }); k >= 0 {
+				j = queued[k]
+			}
+		}
+		if j == nil {
+			q.seq++
+			j = &job{Job: Job{ID: strconv.Itoa(q.seq), Workspace: workspace, Tenant: tenant, Request: req, State: StateQueued, Submitted: time.Now().UTC()}}
+			q.jobs = append(q.jobs, j)
+		}
+		queued[i] = j
+	}
+	q.dispatch()
+	q.prune()
+	for i, j := range queued {
+		jobs[i] = q.statusLocked(j)
+	}
+	return jobs, nil
+}
+
+// Get returns the status of job id of workspace. A non-empty tenant only
+// finds its own jobs.
+func (q *Queue) Get(workspace, tenant, id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := q.find(workspace, tenant, id)
+	if j == nil {
+		return Job{}, ErrUnknownJob
+	}
+	return q.statusLocked(j), nil
+}
+
+// List returns the status of every job of workspace (a non-empty tenant:
+// every one of its jobs), in submission order.
+func (q *Queue) List(workspace, tenant string) []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := []Job{}
+	position := 0
+	for _, j := range q.jobs {
+		if j.State == StateQueued {
+			position++
+		}
+		if j.Workspace == workspace && (tenant == "" || j.Tenant == tenant) {
+			list = append(list, j.status(position))
+		}
+	}
+	return list
+}
+
+// Cancel stops job id: a queued scan never starts, a running one is
+// interrupted. A non-empty tenant can only cancel its own jobs.
+func (q *Queue) Cancel(workspace, tenant, id string) (Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := q.find(workspace, tenant, id)
+	switch {
+	case j == nil:
+		return Job{}, ErrUnknownJob
+	case j.State == StateQueued:
+		j.State, j.Finished = StateCanceled, time.Now().UTC()
+	case j.State == StateRunning:
+		// The job is marked canceled once the runner returns.
+		j.cancel()
+	default:
+		return q.statusLocked(j), ErrFinished
+	}
+	return q.statusLocked(j), nil
+}
+
+// Close cancels every queued and running scan and waits for the running
+// ones to return.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	for _, j := range q.jobs {
+		switch j.State {
+		case StateQueued:
+			j.State, j.Finished = StateCanceled, time.Now().UTC()
+		case StateRunning:
+			j.cancel()
+		}
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+func (q *Queue) find(workspace, tenant, id string) *job {
+	for _, j := range q.jobs {
+		if j.ID == id && j.Workspace == workspace && (tenant == "" || j.Tenant == tenant) {
+			return j
+		}
+	}
+	return nil
+}
+
+func (q *Queue) statusLocked(j *job) Job {
+	position := 0
+	for _, other := range q.jobs {
+		if other.State == StateQueued {
+			position++
+		}
+		if other == j {
+			break
+		}
+	}
+	return j.status(position)
+}
+
+// dispatch starts queued jobs, oldest first, while the limits allow.
+// q.mu must be held.
+func (q *Queue) dispatch() {
+	limit := max(q.Max, 1)
+	perTenant := q.PerTenant
+	if perTenant <= 0 {
+		perTenant = limit
+	}
+	if q.tenants == nil {
+		q.tenants = map[tenantKey]int{}
+	}
+	for _, j := range q.jobs {
+		if q.running >= limit {
+			return
+		}
+		if j.State != StateQueued || q.tenants[j.tenant()] >= perTenant {
+			continue
+		}
+		q.start(j)
+	}
+}
+
+// start runs j. q.mu must be held.
+func (q *Queue) start(j *job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.State, j.Started, j.cancel = StateRunning, time.Now().UTC(), cancel
+	q.running++
+	q.tenants[j.tenant()]++
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		err := q.Run(ctx, j.Workspace, j.Request, &j.progress)
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		switch {
+		case ctx.Err() != nil:
+			j.State = StateCanceled
+		case err != nil:
+			j.State, j.Error = StateFailed, err.Error()
+		default:
+			j.State = StateDone
+		}
+		cancel()
+		j.Finished = time.Now().UTC()
+		q.running--
+		q.tenants[j.tenant()]--
+		if !q.closed {
+			q.dispatch()
+		}
+		q.prune()
+	}()
+}
+
+// prune forgets the oldest finished jobs beyond Keep. q.mu must be held.
+func (q *Queue) prune() {
+	keep := q.Keep
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	finished := 0
+	for _, j := range q.jobs {
+		if j.State != StateQueued && j.State != StateRunning {
+			finished++
+		}
+	}
+	q.jobs = slices.DeleteFunc(q.jobs, func(j *job) bool {
+		if finished <= keep || j.State == StateQueued || j.State == StateRunning {
+			return false
+		}
+		finished--
+		return true
+	})
+}
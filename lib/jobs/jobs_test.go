@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"squatrr/lib/processor"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gate is a Runner whose scans run until released (or canceled), failing
+// for domains named "fail.com".
+type gate struct {
+	mu      sync.Mutex
+	release map[string]chan struct{}
+}
+
+func (g *gate) ch(domain string) chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.release == nil {
+		g.release = map[string]chan struct{}{}
+	}
+	if g.release[domain] == nil {
+		g.release[domain] = make(chan struct{})
+	}
+	return g.release[domain]
+}
+
+func (g *gate) run(ctx context.Context, _ string, req Request, _ *processor.Progress) error {
+	select {
+	case <-g.ch(req.Domain):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if req.Domain == "fail.com" {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+// states maps each domain to its job's state, waiting for want.
+func states(t *testing.T, q *Queue, want map[string]string) {
+	t.Helper()
+	var got map[string]string
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		got = map[string]string{}
+		for _, j := range q.List("", "") {
+			got[j.Request.Domain] = j.State
+		}
+		if reflect.DeepEqual(got, want) {
+			return
+		}
+	}
+	t.Fatalf("states = %v, want %v", got, want)
+}
+
+func TestQueueLimits(t *testing.T) {
+	g := &gate{}
+	q := New(2, 1, g.run)
+	defer q.Close()
+
+	for _, s := range []struct{ tenant, domain string }{
+		{"big", "a1.com"}, {"big", "a2.com"}, {"big", "a3.com"}, {"small", "b1.com"},
+	} {
+		if _, err := q.Submit("", s.tenant, Request{Domain: s.domain}); err != nil {
+			t.Fatalf("Submit(%s) error: %v", s.domain, err)
+		}
+	}
+	// big's later scans wait behind its first; small's runs alongside.
+	states(t, q, map[string]string{"a1.com": StateRunning, "a2.com": StateQueued, "a3.com": StateQueued, "b1.com": StateRunning})
+	if j, _ := q.Get("", "", "3"); j.Position != 2 {
+		t.Errorf("a3 position = %d, want 2", j.Position)
+	}
+
+	close(g.ch("a1.com"))
+	states(t, q, map[string]string{"a1.com": StateDone, "a2.com": StateRunning, "a3.com": StateQueued, "b1.com": StateRunning})
+	if j, _ := q.Get("", "", "3"); j.Position != 1 {
+		t.Errorf("a3 position = %d, want 1", j.Position)
+	}
+
+	if _, err := q.Cancel("", "small", "3"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Cancel() of another tenant's scan error = %v, want ErrUnknownJob", err)
+	}
+	if _, err := q.Cancel("", "big", "3"); err != nil {
+		t.Errorf("Cancel() of a queued scan error: %v", err)
+	}
+	if _, err := q.Cancel("", "big", "2"); err != nil {
+		t.Errorf("Cancel() of a running scan error: %v", err)
+	}
+	if _, err := q.Cancel("", "big", "1"); !errors.Is(err, ErrFinished) {
+		t.Errorf("Cancel() of a finished scan error = %v, want ErrFinished", err)
+	}
+	states(t, q, map[string]string{"a1.com": StateDone, "a2.com": StateCanceled, "a3.com": StateCanceled, "b1.com": StateRunning})
+
+	if _, err := q.Submit("", "small", Request{Domain: "fail.com"}); err != nil {
+		t.Fatalf("Submit() error: %v", err)
+	}
+	close(g.ch("b1.com"))
+	close(g.ch("fail.com"))
+	states(t, q, map[string]string{"a1.com": StateDone, "a2.com": StateCanceled, "a3.com": StateCanceled, "b1.com": StateDone, "fail.com": StateFailed})
+	if j, _ := q.Get("", "small", "5"); j.Error != "boom" {
+		t.Errorf("failed scan error = %q, want boom", j.Error)
+	}
+	if got := len(q.List("", "small")); got != 2 {
+		t.Errorf("List(small) = %d jobs, want 2", got)
+	}
+}
+
+func TestQueueSubmitErrors(t *testing.T) {
+	q := &Queue{Max: 1, MaxQueued: 1, Keep: 1, Run: (&gate{}).run}
+	defer q.Close()
+	tests := []struct {
+		req  Request
+		want error
+	}{
+		{Request{}, ErrInvalidRequest},
+		{Request{Domain: "a.com", Max: -1}, ErrInvalidRequest},
+		{Request{Domain: "a.com"}, nil}, // runs
+		{Request{Domain: "b.com"}, nil}, // waits
+		{Request{Domain: "b.com"}, nil}, // joins the waiting one
+		{Request{Domain: "c.com"}, ErrQueueFull},
+	}
+	for _, tt := range tests {
+		if _, err := q.Submit("", "t", tt.req); !errors.Is(err, tt.want) {
+			t.Errorf("Submit(%+v) error = %v, want %v", tt.req, err, tt.want)
+		}
+	}
+	if got := len(q.List("", "")); got != 2 {
+		t.Errorf("List() = %d jobs, want 2", got)
+	}
+}
+
+func TestQueueSubmitAll(t *testing.T) {
+	q := &Queue{Max: 1, MaxQueued: 2, Run: (&gate{}).run}
+	defer q.Close()
+	if _, err := q.Submit("", "t", Request{Domain: "a.com"}); err != nil { // runs
+		t.Fatalf("Submit() error: %v", err)
+	}
+	if _, err := q.Submit("", "t", Request{Domain: "b.com"}); err != nil { // waits
+		t.Fatalf("Submit() error: %v", err)
+	}
+	// Two new scans don't fit in the one place left: neither is queued.
+	reqs := []Request{{Domain: "b.com"}, {Domain: "c.com"}, {Domain: "d.com"}}
+	if _, err := q.SubmitAll("", "t", reqs); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("SubmitAll() error = %v, want ErrQueueFull", err)
+	}
+	// One request a webhook couldn't send either rejects the batch.
+	for _, bad := range []Request{
+		{},
+		{Domain: "10.0.0.1"},
+		{Domain: "localhost"},
+		{Domain: "www.c.com"},
+		{Domain: "bad..com"},
+		{Domain: "c.com", TLDs: []string{"internal"}},
+		{Domain: "c.com", Targets: []string{"192.0.2.1"}},
+		{Domain: "c.com", Targets: []string{"exampel.corp.internal"}},
+		{Domain: "c.com", Targets: []string{"www.c0.com"}},
+		{Domain: "c.com", Targets: []string{"C.com"}},
+	} {
+		if _, err := q.SubmitAll("", "t", []Request{{Domain: "c.com"}, bad}); !errors.Is(err, ErrInvalidRequest) {
+			t.Errorf("SubmitAll(%+v) error = %v, want ErrInvalidRequest", bad, err)
+		}
+	}
+	if got := len(q.List("", "")); got != 2 {
+		t.Fatalf("List() = %d jobs, want 2", got)
+	}
+	jobs, err := q.SubmitAll("", "t", reqs[:2])
+	if err != nil {
+		t.Fatalf("SubmitAll() error: %v", err)
+	}
+	if jobs[0].ID != "2" || jobs[1].ID != "3" {
+		t.Errorf("SubmitAll() IDs = %s, %s, want 2, 3", jobs[0].ID, jobs[1].ID)
+	}
+	idn := Request{Domain: "exämple.co.uk", TLDs: []string{"co.uk", "github.io"}, Targets: []string{"exampel.co.uk", "xn--exmple-cua.github.io"}}
+	if _, err := q.SubmitAll("", "u", []Request{idn}); err != nil {
+		t.Errorf("SubmitAll(%+v) error: %v", idn, err)
+	}
+}
+
+func TestQueuePrune(t *testing.T) {
+	q := &Queue{Max: 1, Keep: 2, Run: func(context.Context, string, Request, *processor.Progress) error { return nil }}
+	defer q.Close()
+	for _, d := range []string{"a.com", "b.com", "c.com", "d.com"} {
+		submitted, err := q.Submit("", "t", Request{Domain: d})
+		if err != nil {
+			t.Fatalf("Submit(%s) error: %v", d, err)
+		}
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if j, _ := q.Get("", "", submitted.ID); j.State == StateDone {
+				break
+			}
+		}
+	}
+	var kept []string
+	for _, j := range q.List("", "") {
+		kept = append(kept, j.Request.Domain)
+	}
+	if want := []string{"c.com", "d.com"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+}
+
+func TestQueueWorkspaces(t *testing.T) {
+	g := &gate{}
+	q := New(2, 1, g.run)
+	defer q.Close()
+	// The same token name in two workspaces is two tenants.
+	for _, ws := range []string{"acme", "globex"} {
+		if _, err := q.Submit(ws, "dana", Request{Domain: ws + ".com"}); err != nil {
+			t.Fatalf("Submit(%s) error: %v", ws, err)
+		}
+	}
+	if got := q.List("acme", ""); len(got) != 1 || got[0].Request.Domain != "acme.com" || got[0].State != StateRunning {
+		t.Errorf("List(acme) = %+v, want its running scan only", got)
+	}
+	if got := q.List("globex", ""); len(got) != 1 || got[0].State != StateRunning {
+		t.Errorf("List(globex) = %+v, want its running scan only", got)
+	}
+	if _, err := q.Get("globex", "", "1"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Get() of another workspace's scan error = %v, want ErrUnknownJob", err)
+	}
+	if _, err := q.Cancel("", "", "1"); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Cancel() of another workspace's scan error = %v, want ErrUnknownJob", err)
+	}
+}
@@ -0,0 +1,195 @@
+package maltego
+
+/*
+  This library exposes squatrr generation + verification as Maltego transforms.
+  The endpoints speak the Maltego transform XML protocol used by the Transform
+  Distribution Server (TDS/iTDS), so a brand or domain entity can be expanded
+  into its verified typosquats from inside a Maltego graph.
+*/
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"squatrr/lib/processor"
+	"strconv"
+	"strings"
+)
+
+// Scanner runs the generate -> verify pipeline for a base domain.
+type Scanner func(ctx context.Context, opts processor.Options) (<-chan processor.Output, error)
+
+type message struct {
+	XMLName  xml.Name          `xml:"MaltegoMessage"`
+	Request  *requestMessage   `xml:"MaltegoTransformRequestMessage,omitempty"`
+	Response *responseMessage  `xml:"MaltegoTransformResponseMessage,omitempty"`
+	Error    *exceptionMessage `xml:"MaltegoTransformExceptionMessage,omitempty"`
+}
+
+type requestMessage struct {
+	Entities        []Entity `xml:"Entities>Entity"`
+	Limits          limits   `xml:"Limits"`
+	TransformFields []field  `xml:"TransformFields>Field"`
+}
+
+type limits struct {
+	SoftLimit int `xml:"SoftLimit,attr"`
+	HardLimit int `xml:"HardLimit,attr"`
+}
+
+type responseMessage struct {
+	Entities   []Entity    `xml:"Entities>Entity"`
+	UIMessages []uiMessage `xml:"UIMessages>UIMessage"`
+}
+
+type exceptionMessage struct {
+	Exceptions []string `xml:"Exceptions>Exception"`
+}
+
+// Entity is a Maltego entity in a request or response.
+type Entity struct {
+	Type   string  `xml:"Type,attr"`
+	Value  string  `xml:"Value"`
+	Weight int     `xml:"Weight,omitempty"`
+	Fields []field `xml:"AdditionalFields>Field,omitempty"`
+}
+
+type field struct {
+	Name        string `xml:"Name,attr"`
+	DisplayName string `xml:"DisplayName,attr,omitempty"`
+	Value       string `xml:",chardata"`
+}
+
+type uiMessage struct {
+	Type string `xml:"MessageType,attr"`
+	Text string `xml:",chardata"`
+}
+
+// Server serves the squatrr transforms.
+type Server struct {
+	Base   processor.Options // template for every scan; Domain and TLDs are set per request
+	Scan   Scanner
+	Logger *slog.Logger
+}
+
+// Handler returns the transform routes:
+//
+//	POST /run/typosquats    expand a domain into verified typosquats
+//	GET  /healthz           liveness for TDS health checks
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /run/typosquats", s.typosquats)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "ok\n")
+	})
+	return mux
+}
+
+func (s *Server) typosquats(w http.ResponseWriter, r *http.Request) {
+	var req message
+	if err := xml.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil || req.Request == nil {
+		writeException(w, "malformed transform request")
+		return
+	}
+	if len(req.Request.Entities) == 0 {
+		writeException(w, "no input entity")
+		return
+	}
+
+	domain := strings.ToLower(strings.TrimSpace(req.Request.Entities[0].Value))
+	opts := s.Base
+	opts.Domain = domain
+	opts.TLDs = requestTLDs(domain, req.Request.TransformFields)
+
+	s.Logger.Info("processing transform maltego", "domain", domain, "tlds", strings.Join(opts.TLDs, ","))
+	out, err := s.Scan(r.Context(), opts)
+	if err != nil {
+		writeException(w, err.Error())
+		return
+	}
+
+	limit := req.Request.Limits.SoftLimit
+	resp := &responseMessage{}
+	truncated := 0
+	for o := range out {
+		if limit > 0 && len(resp.Entities) >= limit {
+			truncated++
+			continue
+		}
+		resp.Entities = append(resp.Entities, toEntity(o))
+	}
+	if truncated > 0 {
+		resp.UIMessages = append(resp.UIMessages, uiMessage{
+			Type: "PartialError",
+			Text: strconv.Itoa(truncated) + " additional typosquats omitted by the result limit",
+		})
+	}
+	resp.UIMessages = append(resp.UIMessages, uiMessage{
+		Type: "Inform",
+		Text: "squatrr verified " + strconv.Itoa(len(resp.Entities)+truncated) + " live typosquats of " + domain,
+	})
+	writeMessage(w, message{Response: resp})
+}
+
+// requestTLDs reads an optional comma-separated "tlds" transform field and
+// otherwise falls back to the input domain's own TLD.
+func requestTLDs(domain string, fields []field) []string {
+	for _, f := range fields {
+		if f.Name != "tlds" {
+			continue
+		}
+		var tlds []string
+		for _, t := range strings.Split(f.Value, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tlds = append(tlds, t)
+			}
+		}
+		if len(tlds) > 0 {
+			return tlds
+		}
+	}
+	if i := strings.LastIndex(domain, "."); i >= 0 && i < len(domain)-1 {
+		return []string{domain[i+1:]}
+	}
+	return []string{"com"}
+}
+
+// toEntity converts a verified candidate into a maltego.Domain entity,
+// weighting mail-capable candidates above web-only ones.
+func toEntity(o processor.Output) Entity {
+	weight := 50
+	if o.HasMail {
+		weight = 100
+	}
+	e := Entity{Type: "maltego.Domain", Value: o.Domain, Weight: weight}
+	add := func(name, display, value string) {
+		if value != "" {
+			e.Fields = append(e.Fields, field{Name: name, DisplayName: display, Value: value})
+		}
+	}
+	add("squatrr.resolvable", "Resolvable", strconv.FormatBool(o.Resolvable))
+	add("squatrr.has_mail", "Has MX", strconv.FormatBool(o.HasMail))
+	add("squatrr.ips", "IP addresses", strings.Join(append(append([]string{}, o.DNS.A...), o.DNS.AAAA...), ", "))
+	add("squatrr.ns", "Name servers", strings.Join(o.DNS.NS, ", "))
+	add("squatrr.mx", "Mail exchangers", strings.Join(o.DNS.MX, ", "))
+	if o.TLS != nil {
+		add("squatrr.tls_issuer", "TLS issuer", o.TLS.Issuer)
+	}
+	if o.HTTP != nil {
+		add("squatrr.http_status", "HTTP status", o.HTTP.Status)
+		add("squatrr.http_location", "HTTP redirect", o.HTTP.Location)
+	}
+	return e
+}
+
+func writeException(w http.ResponseWriter, msg string) {
+	writeMessage(w, message{Error: &exceptionMessage{Exceptions: []string{msg}}})
+}
+
+func writeMessage(w http.ResponseWriter, m message) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(m)
+}
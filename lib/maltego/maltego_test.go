@@ -0,0 +1,63 @@
+package maltego
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"strings"
+	"testing"
+)
+
+func TestTyposquatsTransform(t *testing.T) {
+	var gotOpts processor.Options
+	srv := &Server{
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Scan: func(_ context.Context, opts processor.Options) (<-chan processor.Output, error) {
+			gotOpts = opts
+			out := make(chan processor.Output, 2)
+			out <- processor.Output{Domain: "exampel.com", Resolvable: true, HasMail: true, DNS: verify.DNSResult{A: []string{"203.0.113.10"}}}
+			out <- processor.Output{Domain: "examp1e.com", Resolvable: true}
+			close(out)
+			return out, nil
+		},
+	}
+
+	body := `<MaltegoMessage><MaltegoTransformRequestMessage>
+		<Entities><Entity Type="maltego.Domain"><Value>Example.com</Value></Entity></Entities>
+		<Limits SoftLimit="1" HardLimit="10"/>
+		<TransformFields><Field Name="tlds">com, net</Field></TransformFields>
+	</MaltegoTransformRequestMessage></MaltegoMessage>`
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/run/typosquats", strings.NewReader(body)))
+
+	if gotOpts.Domain != "example.com" || strings.Join(gotOpts.TLDs, ",") != "com,net" {
+		t.Errorf("scan options = %q %v, want example.com [com net]", gotOpts.Domain, gotOpts.TLDs)
+	}
+	out := rec.Body.String()
+	for _, want := range []string{
+		`<Entity Type="maltego.Domain"><Value>exampel.com</Value><Weight>100</Weight>`,
+		`<Field Name="squatrr.ips" DisplayName="IP addresses">203.0.113.10</Field>`,
+		`<UIMessage MessageType="PartialError">1 additional typosquats omitted by the result limit</UIMessage>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("response missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "examp1e.com") {
+		t.Errorf("response should honor SoftLimit, got:\n%s", out)
+	}
+}
+
+func TestTyposquatsTransformMalformed(t *testing.T) {
+	srv := &Server{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/run/typosquats", strings.NewReader("not xml")))
+	if !strings.Contains(rec.Body.String(), "<Exception>malformed transform request</Exception>") {
+		t.Errorf("expected exception message, got:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,218 @@
+package manifest
+
+/*
+  This library writes and checks the signed manifest of a scan run
+  (-sign-key): the SHA-256 of every output file, the config file and flags
+  the run used, the tool version and the run's timestamps, signed with an
+  Ed25519 key, so results presented as evidence can be shown to be
+  unaltered since the run.
+
+  The manifest is canonical JSON (fixed field order, sorted flags and
+  files) and the signature covers its exact bytes, kept in a detached
+  <manifest>.sig file. Keys are the PEM files OpenSSL produces:
+
+	openssl genpkey -algorithm ed25519 -out sign.pem
+	openssl pkey -in sign.pem -pubout -out sign.pub.pem
+
+  so a manifest can be checked without sasquat too:
+
+	openssl pkeyutl -verify -pubin -inkey sign.pub.pem -rawin -in run.manifest.json -sigfile run.manifest.json.sig
+*/
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// File is the hash of one file, named relative to the manifest.
+type File struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Manifest describes one run.
+type Manifest struct {
+	Tool     string            `json:"tool"`
+	Version  string            `json:"version"` // see Version
+	Domain   string            `json:"domain"`
+	Started  time.Time         `json:"started"`
+	Finished time.Time         `json:"finished"`
+	Flags    map[string]string `json:"flags"`            // flags set on the command line, secrets redacted
+	Config   *File             `json:"config,omitempty"` // the -config file
+	Files    []File            `json:"files"`
+	SignedBy string            `json:"signed_by"` // Fingerprint of the signing key
+}
+
+// Version identifies the running build: its module version, or for a
+// source build the VCS revision (with -dirty for uncommitted changes).
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if v := info.Main.Version; v != "" && v != "(devel)" {
+		return v
+	}
+	var revision, dirty string
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.revision":
+			revision = s.Value
+		case s.Key == "vcs.modified" && s.Value == "true":
+			dirty = "-dirty"
+		}
+	}
+	if revision == "" {
+		return "(devel)"
+	}
+	return revision + dirty
+}
+
+// Hash hashes the file at path, naming it relative to dir.
+func Hash(path, dir string) (File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return File{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return File{}, err
+	}
+	name := path
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		name = rel
+	}
+	return File{Name: filepath.ToSlash(name), SHA256: hex.EncodeToString(h.Sum(nil)), Size: n}, nil
+}
+
+// LoadPrivateKey reads a PEM PKCS #8 Ed25519 private key.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return ed, nil
+}
+
+// LoadPublicKey reads a PEM PKIX Ed25519 public key.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	ed, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return ed, nil
+}
+
+func readPEM(path, typ string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != typ {
+		return nil, fmt.Errorf("%s: no PEM %s block", path, typ)
+	}
+	return block.Bytes, nil
+}
+
+// Fingerprint is the SHA-256 of the key's PKIX encoding, as
+// "sha256:<hex>".
+func Fingerprint(pub ed25519.PublicKey) string {
+	der, _ := x509.MarshalPKIXPublicKey(pub)
+	sum := sha256.Sum256(der)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Write signs m with key and writes it to path, and the signature to
+// path+".sig".
+func Write(path string, m Manifest, key ed25519.PrivateKey) error {
+	m.SignedBy = Fingerprint(key.Public().(ed25519.PublicKey))
+	m.Files = slices.Clone(m.Files)
+	slices.SortFunc(m.Files, func(a, b File) int { return strings.Compare(a.Name, b.Name) })
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(path+".sig", ed25519.Sign(key, data), 0o644)
+}
+
+// ErrSignature is returned for a manifest its signature doesn't match.
+var ErrSignature = errors.New("manifest signature does not match")
+
+// Verify checks the manifest at path against its signature and pub, then
+// every file it lists against its hash. It returns the manifest, and an
+// error naming each file that is missing or changed.
+func Verify(path string, pub ed25519.PublicKey) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := os.ReadFile(path + ".sig")
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(pub, data, sig) {
+		return nil, ErrSignature
+	}
+	var m Manifest
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&m); err != nil {
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	var errs []error
+	files := m.Files
+	if m.Config != nil {
+		files = append([]File{*m.Config}, files...)
+	}
+	for _, want := range files {
+		full := filepath.FromSlash(want.Name)
+		if !filepath.IsAbs(full) {
+			full = filepath.Join(dir, full)
+		}
+		got, err := Hash(full, dir)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", want.Name, err))
+		case got.SHA256 != want.SHA256 || got.Size != want.Size:
+			errs = append(errs, fmt.Errorf("%s: changed since the run", want.Name))
+		}
+	}
+	return &m, errors.Join(errs...)
+}
@@ -0,0 +1,95 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeKeys(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+	pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+	privPath, pubPath := filepath.Join(dir, "sign.pem"), filepath.Join(dir, "sign.pub.pem")
+	os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600)
+	os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644)
+	return privPath, pubPath
+}
+
+func TestWriteVerify(t *testing.T) {
+	dir := t.TempDir()
+	privPath, pubPath := writeKeys(t, dir)
+	key, err := LoadPrivateKey(privPath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey() error: %v", err)
+	}
+	pub, err := LoadPublicKey(pubPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error: %v", err)
+	}
+	if _, err := LoadPublicKey(privPath); err == nil {
+		t.Error("LoadPublicKey() accepted a private key")
+	}
+
+	results := filepath.Join(dir, "results.json")
+	meta := filepath.Join(dir, "results.meta.json")
+	os.WriteFile(results, []byte(`[{"domain":"exampel.com"}]`), 0o644)
+	os.WriteFile(meta, []byte(`{"domain":"example.com"}`), 0o644)
+	m := Manifest{Tool: "sasquat", Version: Version(), Domain: "example.com",
+		Started: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), Finished: time.Date(2026, 1, 2, 3, 5, 0, 0, time.UTC),
+		Flags: map[string]string{"domain": "example.com", "tlds": "com,net"}}
+	for _, path := range []string{results, meta} {
+		f, err := Hash(path, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Files = append(m.Files, f)
+	}
+	path := filepath.Join(dir, "results.manifest.json")
+	if err := Write(path, m, key); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	first, _ := os.ReadFile(path)
+	if err := Write(path, m, key); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(first) {
+		t.Error("Write() is not reproducible")
+	}
+
+	got, err := Verify(path, pub)
+	if err != nil {
+		t.Fatalf("Verify() error: %v", err)
+	}
+	if got.Files[0].Name != "results.json" || got.SignedBy != Fingerprint(pub) {
+		t.Errorf("Verify() = files %v signed by %s", got.Files, got.SignedBy)
+	}
+
+	// A changed result is named.
+	os.WriteFile(results, []byte(`[]`), 0o644)
+	if _, err := Verify(path, pub); err == nil || !strings.Contains(err.Error(), "results.json") {
+		t.Errorf("Verify() after changing results = %v", err)
+	}
+
+	// A changed manifest, or another key, fails the signature.
+	_, otherPub := writeKeys(t, t.TempDir())
+	other, _ := LoadPublicKey(otherPub)
+	if _, err := Verify(path, other); !errors.Is(err, ErrSignature) {
+		t.Errorf("Verify() with another key = %v, want ErrSignature", err)
+	}
+	os.WriteFile(path, []byte(strings.Replace(string(first), "example.com", "example.net", 1)), 0o644)
+	if _, err := Verify(path, pub); !errors.Is(err, ErrSignature) {
+		t.Errorf("Verify() of an edited manifest = %v, want ErrSignature", err)
+	}
+}
@@ -0,0 +1,126 @@
+package ownership
+
+/*
+  This library lets an organization prove it controls candidates, such as
+  its own defensive registrations, with a DNS TXT challenge: each domain's
+  token is derived from a secret the organization keeps, so publishing
+  it at _sasquat-challenge.<domain> shows control of the zone, and tokens
+  need no storing between being issued and checked.
+*/
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// RecordLabel is the label under a candidate holding its challenge record.
+const RecordLabel = "_sasquat-challenge"
+
+// tokenPrefix starts a challenge record's value, so it is recognizable
+// among a name's other TXT records.
+const tokenPrefix = "sasquat-verification="
+
+// minSecretLen is the shortest secret accepted, in bytes.
+const minSecretLen = 16
+
+// ErrWeakSecret is returned for a secret shorter than 16 bytes.
+var ErrWeakSecret = errors.New("ownership secret must be at least 16 bytes")
+
+// Resolver looks up TXT records; *net.Resolver and verify.Resolver
+// implement it.
+type Resolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// Challenge is what to publish to prove control of Domain: a TXT record
+// at Name with Value.
+type Challenge struct {
+	Domain string
+	Name   string
+	Value  string
+}
+
+// Issuer derives and checks challenges from a secret.
+type Issuer struct {
+	secret []byte
+}
+
+// NewIssuer returns an Issuer deriving tokens from secret.
+func NewIssuer(secret []byte) (*Issuer, error) {
+	if len(secret) < minSecretLen {
+		return nil, ErrWeakSecret
+	}
+	return &Issuer{secret: secret}, nil
+}
+
+// LoadSecret reads the secret in the file at path, surrounding whitespace
+// removed. When create is set and the file doesn't exist, it is written
+// with a new random secret, readable by its owner only.
+func LoadSecret(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		secret := []byte(hex.EncodeToString(key))
+		if err := os.WriteFile(path, append(secret, '\n'), 0o600); err != nil {
+			return nil, err
+		}
+		return secret, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.TrimSpace(string(data))), nil
+}
+
+// Challenge returns the record proving control of domain, a Unicode or
+// ASCII name.
+func (i *Issuer) Challenge(domain string) (Challenge, error) {
+	ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if err != nil || strings.Contains("."+ascii+".", "..") {
+		return Challenge{}, fmt.Errorf("invalid domain %q", domain)
+	}
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(ascii))
+	return Challenge{
+		Domain: ascii,
+		Name:   RecordLabel + "." + ascii,
+		Value:  tokenPrefix + hex.EncodeToString(mac.Sum(nil)[:16]),
+	}, nil
+}
+
+// Check looks up domain's challenge record through r and reports whether
+// it holds the expected value. A name without the record is not proved,
+// and not an error.
+func (i *Issuer) Check(ctx context.Context, r Resolver, domain string) (bool, error) {
+	c, err := i.Challenge(domain)
+	if err != nil {
+		return false, err
+	}
+	txts, err := r.LookupTXT(ctx, c.Name)
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, txt := range txts {
+		if hmac.Equal([]byte(strings.TrimSpace(txt)), []byte(c.Value)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,85 @@
+package ownership
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"squatrr/lib/verify/verifytest"
+	"strings"
+	"testing"
+)
+
+var secret = []byte("0123456789abcdef0123456789abcdef")
+
+func TestChallenge(t *testing.T) {
+	i, err := NewIssuer(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := i.Challenge("Exаmple.com.") // Cyrillic а
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Domain != "xn--exmple-4nf.com" || c.Name != "_sasquat-challenge.xn--exmple-4nf.com" || !strings.HasPrefix(c.Value, "sasquat-verification=") || len(c.Value) != len("sasquat-verification=")+32 {
+		t.Errorf("Challenge() = %+v", c)
+	}
+	same, _ := i.Challenge("xn--exmple-4nf.com")
+	other, _ := i.Challenge("examp1e.com")
+	if same != c || other.Value == c.Value {
+		t.Errorf("tokens should depend on the domain only: %+v, %+v", same, other)
+	}
+	for _, bad := range []string{"", "bad..name", "exa mple.com"} {
+		if _, err := i.Challenge(bad); err == nil {
+			t.Errorf("Challenge(%q) succeeded", bad)
+		}
+	}
+	if _, err := NewIssuer([]byte("short")); !errors.Is(err, ErrWeakSecret) {
+		t.Errorf("NewIssuer(short) error = %v", err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	i, _ := NewIssuer(secret)
+	owned, _ := i.Challenge("examp1e.com")
+	stale, _ := NewIssuer([]byte("another secret, another token"))
+	wrong, _ := stale.Challenge("exampel.com")
+	r := verifytest.NewResolver(verifytest.Zone{
+		owned.Name:                       {TXT: []string{"v=spf1 -all", owned.Value}},
+		wrong.Name:                       {TXT: []string{wrong.Value}},
+		"_sasquat-challenge.exampl3.com": {A: []string{"192.0.2.1"}},
+	})
+	tests := []struct {
+		domain string
+		want   bool
+	}{
+		{"examp1e.com", true},
+		{"exampel.com", false}, // another secret's token
+		{"exampl3.com", false}, // no TXT record
+		{"exmaple.com", false}, // no such name
+	}
+	for _, tt := range tests {
+		got, err := i.Check(context.Background(), r, tt.domain)
+		if err != nil || got != tt.want {
+			t.Errorf("Check(%s) = %v, %v; want %v", tt.domain, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoadSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ownership.key")
+	if _, err := LoadSecret(path, false); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("LoadSecret() of a missing file = %v", err)
+	}
+	created, err := LoadSecret(path, true)
+	if err != nil || len(created) != 64 {
+		t.Fatalf("LoadSecret(create) = %q, %v", created, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("secret file mode = %v", info.Mode().Perm())
+	}
+	again, err := LoadSecret(path, true)
+	if err != nil || string(again) != string(created) {
+		t.Errorf("LoadSecret() again = %q, %v; want the created secret", again, err)
+	}
+}
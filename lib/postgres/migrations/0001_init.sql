@@ -0,0 +1,62 @@
+-- One row per scan.
+CREATE TABLE runs (
+    id          BIGSERIAL PRIMARY KEY,
+    base        TEXT        NOT NULL,
+    started_at  TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ,
+    complete    BOOLEAN     NOT NULL DEFAULT FALSE,
+    queued      INTEGER     NOT NULL DEFAULT 0,
+    verified    INTEGER     NOT NULL DEFAULT 0,
+    found       INTEGER     NOT NULL DEFAULT 0
+);
+CREATE INDEX runs_base_idx ON runs (base, started_at DESC);
+
+-- The latest state of every candidate ever found, upserted by domain.
+CREATE TABLE domains (
+    base       TEXT        NOT NULL,
+    domain     TEXT        NOT NULL,
+    first_seen TIMESTAMPTZ NOT NULL,
+    last_seen  TIMESTAMPTZ NOT NULL,
+    last_run   BIGINT      NOT NULL REFERENCES runs (id),
+    score      INTEGER     NOT NULL,
+    class      TEXT        NOT NULL DEFAULT '',
+    resolvable BOOLEAN     NOT NULL,
+    has_mail   BOOLEAN     NOT NULL,
+    result     JSONB       NOT NULL,
+    PRIMARY KEY (base, domain)
+);
+CREATE INDEX domains_score_idx ON domains (base, score DESC);
+
+-- What each run found, for run-over-run comparisons.
+CREATE TABLE run_results (
+    run_id BIGINT  NOT NULL REFERENCES runs (id) ON DELETE CASCADE,
+    domain TEXT    NOT NULL,
+    score  INTEGER NOT NULL,
+    class  TEXT    NOT NULL DEFAULT '',
+    result JSONB   NOT NULL,
+    PRIMARY KEY (run_id, domain)
+);
+
+-- The newest finished run per base domain.
+CREATE VIEW latest_runs AS
+SELECT DISTINCT ON (base) *
+FROM runs
+WHERE finished_at IS NOT NULL
+ORDER BY base, started_at DESC;
+
+-- Candidates the newest finished run of their base found live.
+CREATE VIEW live_domains AS
+SELECT d.*
+FROM domains d
+JOIN latest_runs r ON r.base = d.base AND r.id = d.last_run;
+
+-- Per-run rollup for dashboards.
+CREATE VIEW run_summary AS
+SELECT r.id, r.base, r.started_at, r.finished_at, r.complete, r.queued, r.verified,
+       count(rr.domain)                                    AS found,
+       count(rr.domain) FILTER (WHERE rr.class = 'phishing') AS phishing,
+       count(rr.domain) FILTER (WHERE rr.score >= 45)        AS high_score,
+       coalesce(max(rr.score), 0)                            AS max_score
+FROM runs r
+LEFT JOIN run_results rr ON rr.run_id = r.id
+GROUP BY r.id;
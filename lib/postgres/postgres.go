@@ -0,0 +1,227 @@
+package postgres
+
+/*
+  This library stores scan results in PostgreSQL so several analysts and BI
+  dashboards can query them directly. The schema is created and upgraded by
+  embedded, numbered migrations; each scan is a row in runs with what it
+  found in run_results, and domains keeps the latest state of every
+  candidate, upserted by domain.
+*/
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"squatrr/lib/processor"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+//go:embed migrations/*.sql
+var migrationFS embed.FS
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// migrations returns the embedded migrations in version order. Files are
+// named NNNN_description.sql.
+func migrations() ([]migration, error) {
+	names, err := fs.Glob(migrationFS, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	var out []migration
+	for _, name := range names {
+		base := path.Base(name)
+		num, _, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(num)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must start with a version number", base)
+		}
+		data, err := migrationFS.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, migration{version: version, name: base, sql: string(data)})
+	}
+	slices.SortFunc(out, func(a, b migration) int { return a.version - b.version })
+	for i, m := range out {
+		if m.version != i+1 {
+			return nil, fmt.Errorf("migration %s: expected version %d", m.name, i+1)
+		}
+	}
+	return out, nil
+}
+
+// DB is a migrated results database.
+type DB struct {
+	db     *sql.DB
+	logger *slog.Logger
+}
+
+// Open connects to dsn (a postgres:// URL or key=value string) and applies
+// any pending migrations.
+func Open(ctx context.Context, dsn string, logger *slog.Logger) (*DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := &DB{db: db, logger: logger}
+	if err := d.migrate(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// migrate applies pending migrations, each in its own transaction. An
+// advisory lock keeps concurrent scans from migrating at once.
+func (d *DB) migrate(ctx context.Context) error {
+	all, err := migrations()
+	if err != nil {
+		return err
+	}
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	const lockID = 0x5a5 // arbitrary, shared by every sasquat process
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return err
+	}
+	var current int
+	if err := conn.QueryRowContext(ctx, `SELECT coalesce(max(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+	for _, m := range all[current:] {
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, m.version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		d.logger.Info("processing postgres migration", "applied", m.name)
+	}
+	return nil
+}
+
+// Run is a sink recording one scan of base: each result is upserted into
+// domains and added to run_results, and the runs row is completed from
+// stats on Close. Like the streaming sinks, a result the database rejects
+// is logged and dropped rather than failing the scan; it is still in the
+// results file.
+type Run struct {
+	db      *DB
+	id      int64
+	stats   *processor.Stats
+	found   int
+	dropped int
+}
+
+// NewRun inserts the runs row for a scan of base. stats must be filled in
+// by the time the run is closed.
+func (d *DB) NewRun(ctx context.Context, base string, stats *processor.Stats) (*Run, error) {
+	r := &Run{db: d, stats: stats}
+	err := d.db.QueryRowContext(ctx, `INSERT INTO runs (base, started_at) VALUES ($1, $2) RETURNING id`,
+		base, time.Now().UTC()).Scan(&r.id)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ID is the runs.id of this scan.
+func (r *Run) ID() int64 { return r.id }
+
+func (r *Run) Write(o processor.Output) error {
+	if err := r.write(o); err != nil {
+		r.db.logger.Error("processing postgres sink", "run", r.id, "domain", o.Domain, "error", err)
+		r.dropped++
+		return nil
+	}
+	r.found++
+	return nil
+}
+
+func (r *Run) write(o processor.Output) error {
+	raw, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	tx, err := r.db.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO domains (base, domain, first_seen, last_seen, last_run, score, class, resolvable, has_mail, result)
+		SELECT base, $2, $3, $3, id, $4, $5, $6, $7, $8 FROM runs WHERE id = $1
+		ON CONFLICT (base, domain) DO UPDATE SET
+			last_seen = EXCLUDED.last_seen, last_run = EXCLUDED.last_run, score = EXCLUDED.score, class = EXCLUDED.class,
+			resolvable = EXCLUDED.resolvable, has_mail = EXCLUDED.has_mail, result = EXCLUDED.result`,
+		r.id, o.Domain, now, o.Score, o.Class, o.Resolvable, o.HasMail, raw); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO run_results (run_id, domain, score, class, result) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (run_id, domain) DO UPDATE SET score = EXCLUDED.score, class = EXCLUDED.class, result = EXCLUDED.result`,
+		r.id, o.Domain, o.Score, o.Class, raw); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close completes the runs row; the connection pool is left to DB.Close.
+// A run that dropped results isn't complete, whatever it covered.
+func (r *Run) Close() error {
+	var st processor.Stats
+	if r.stats != nil {
+		st = *r.stats
+	}
+	complete := st.Complete() && r.dropped == 0
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := r.db.db.ExecContext(ctx, `UPDATE runs SET finished_at = $2, complete = $3, queued = $4, verified = $5, found = $6 WHERE id = $1`,
+		r.id, time.Now().UTC(), complete, st.Queued, st.Verified, r.found)
+	r.db.logger.Info("processing postgres sink", "run", r.id, "found", r.found, "dropped", r.dropped)
+	return err
+}
@@ -0,0 +1,21 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMigrations(t *testing.T) {
+	all, err := migrations()
+	if err != nil {
+		t.Fatalf("migrations() error: %v", err)
+	}
+	if len(all) == 0 || all[0].version != 1 || !strings.Contains(all[0].sql, "CREATE TABLE runs") {
+		t.Fatalf("migrations() = %+v, want 0001_init first", all)
+	}
+	for i, m := range all {
+		if m.version != i+1 {
+			t.Errorf("migrations()[%d].version = %d, want %d", i, m.version, i+1)
+		}
+	}
+}
@@ -1,7 +1,567 @@
 package processor
 
-// TODO: This is where the actual processing code from main.go should reside
-// TODO: implement and then move main.go to cli.go in a new directory
-func ProcessDomain() {
+/*
+  This library runs the generate -> verify pipeline for a base domain.
+  It is shared by the CLI scan and the long-running server modes.
+*/
 
+import (
+	"context"
+	"fmt"
+	"golang.org/x/net/idna"
+	"hash/fnv"
+	"log/slog"
+	"math/rand/v2"
+	"sort"
+	"squatrr/lib/classify"
+	"squatrr/lib/score"
+	"squatrr/lib/typo"
+	"squatrr/lib/verify"
+	"strings"
+	"sync"
+	"time"
+	"zntr.io/typogenerator"
+	"zntr.io/typogenerator/strategy"
+)
+
+// Output is the shape of what is returned to the results.json and thus site
+type Output struct {
+	Domain     string                   `json:"domain"`
+	Unicode    string                   `json:"unicode,omitempty"` // Domain's Unicode form, when it has punycode labels
+	Strategy   string                   `json:"strategy,omitempty"`
+	Likelihood float64                  `json:"likelihood,omitempty"`
+	Confusable bool                     `json:"confusable,omitempty"` // same TR39 skeleton as the base domain's label (see typo.Confusable)
+	Resolvable bool                     `json:"resolvable"`
+	HasMail    bool                     `json:"has_mail"`
+	DNS        verify.DNSResult         `json:"dns"`
+	TLS        *verify.TLSResult        `json:"tls,omitempty"`
+	HTTP       *verify.HTTPResult       `json:"http,omitempty"`
+	RDAP       *verify.RDAPResult       `json:"rdap,omitempty"`
+	CT         *verify.CTResult         `json:"ct,omitempty"`
+	PDNS       *verify.PDNSResult       `json:"pdns,omitempty"`
+	Geo        *verify.GeoResult        `json:"geo,omitempty"`
+	Reputation []verify.ReputationMatch `json:"reputation,omitempty"`
+	DNSBL      *verify.DNSBLResult      `json:"dnsbl,omitempty"`
+	WWW        *verify.WWW              `json:"www,omitempty"`
+	Services   *verify.ServicesResult   `json:"services,omitempty"`
+	SMTP       *verify.SMTPResult       `json:"smtp,omitempty"`
+	Score      int                      `json:"score"`
+	ScoreTags  []string                 `json:"score_tags,omitempty"`
+	Class      string                   `json:"class,omitempty"`
+	ClassTags  []string                 `json:"class_tags,omitempty"`
+	Listing    *classify.Listing        `json:"listing,omitempty"`
+	Timing     verify.Timing            `json:"timing"`
+
+	// VisualSimilarity is how alike the candidate's screenshot and the
+	// base domain's are by perceptual hash, from 0 to 1 (see
+	// evidence.Similarity); set by the evidence mode and, like the
+	// listing, not part of Score.
+	VisualSimilarity float64 `json:"visual_similarity,omitempty"`
+
+	// Errors lists the stages that could not complete. A candidate whose
+	// verification failed outright is output with only its domain and an
+	// error for stage "verify": it couldn't be checked, which is not the
+	// same as safe.
+	Errors []verify.StageError `json:"errors,omitempty"`
+
+	// Changed is set when a re-scan finds the candidate in a different
+	// state than its previous observation in the history; Changes lists
+	// the fields that moved.
+	Changed bool     `json:"changed,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+
+	// State is the analyst's case state for the candidate and Assignee
+	// the analyst working it, carried over from the history (see
+	// lib/history).
+	State    string `json:"state,omitempty"`
+	Assignee string `json:"assignee,omitempty"`
+
+	// Takedown is the recommended channel for reporting a flagged
+	// candidate (see lib/takedown).
+	Takedown *Takedown `json:"takedown,omitempty"`
+}
+
+// Live reports whether o resolves or receives mail, as opposed to a
+// candidate output only for its Errors.
+func (o Output) Live() bool {
+	return o.Resolvable || o.HasMail
+}
+
+// Takedown is where to report a candidate: its registrar, to suspend the
+// domain, or its hosting provider, to remove the content. Also holds the
+// other channel, when known.
+type Takedown struct {
+	Target   string     `json:"target"` // "registrar" or "hosting"
+	Provider string     `json:"provider"`
+	Email    string     `json:"email,omitempty"`
+	Form     string     `json:"form,omitempty"` // web form URL
+	API      string     `json:"api,omitempty"`  // abuse API endpoint
+	Also     []Takedown `json:"also,omitempty"`
+}
+
+// Submission is a takedown request sent to a provider by the takedown
+// command, as recorded in the history.
+type Submission struct {
+	Target      string    `json:"target"`
+	Provider    string    `json:"provider"`
+	Channel     string    `json:"channel"` // "api" or "form"
+	URL         string    `json:"url"`
+	ID          string    `json:"id,omitempty"` // the provider's ticket or reference, when it returns one
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// Change is one field of a candidate that differs from its previous
+// observation. Set-valued fields (IPs, MX, NS) report what left in Old and
+// what arrived in New, space-separated.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old,omitempty"`
+	New   string `json:"new,omitempty"`
+}
+
+// Candidate is a single domain queued for verification.
+type Candidate struct {
+	Domain     string
+	Strategy   string  // permutation strategy (or "<tool>:<fuzzer>" when imported)
+	Likelihood float64 // see typo.Likelihood; the queue is verified most likely first
+}
+
+// Options controls a single generate -> verify run.
+type Options struct {
+	Domain  string
+	TLDs    []string // TLD variants every permutation is verified against
+	Workers int
+	Max     int           // optional(testing) cap on candidates processed (0 = no cap)
+	Budget  time.Duration // stop dispatching new candidates after this long (0 = no budget)
+	Sample  float64       // verify only this random fraction of candidates, in (0, 1) (0 = all)
+	Seed    uint64        // seed for Sample so a sampled run can be reproduced
+
+	// HighScore is the score from which a found candidate counts as a
+	// high-score hit in Stats.Strategies (0 = DefaultHighScore).
+	HighScore int
+
+	// Shard and Shards split the candidate space between independent runs:
+	// this run verifies only candidates hashed into shard Shard of Shards
+	// (0-based). Shards <= 1 verifies everything.
+	Shard, Shards int
+
+	// Stealth: Shuffle randomises the verification order (after Max, so the
+	// same candidates are covered) and each worker waits a random 0.5-1.5x
+	// Delay between candidates.
+	Shuffle bool
+	Delay   time.Duration
+	Verify  verify.Config
+	Logger  *slog.Logger
+
+	// Signatures classify landing pages; nil uses classify.Default.
+	Signatures *classify.Set
+
+	// Rubric grades candidates; nil uses score.Default.
+	Rubric *score.Rubric
+
+	// Zones, when set, drops candidates that registry zone files show are
+	// unregistered before any DNS queries are made.
+	Zones ZoneFilter
+
+	// Stats, when set, is filled in before the output channel is closed.
+	Stats *Stats
+
+	// Progress, when set, is updated as candidates are verified.
+	Progress *Progress
+
+	// IncludeUnresolvable emits every verified candidate; by default only
+	// live ones (Output.Live) and those with Errors are.
+	IncludeUnresolvable bool
+
+	// Strategies generate the permutations; nil uses typo's defaults.
+	// TLDPolicy limits which strategies are verified on which TLDs; nil
+	// verifies every permutation on every TLD.
+	Strategies []strategy.Strategy
+	TLDPolicy  *typo.TLDPolicy
+
+	// Imported are externally generated candidates (dnstwist, urlcrazy, ...)
+	// verified alongside our own permutations.
+	Imported []typo.Imported
+
+	// Targets, when set, are the only candidates verified instead of the
+	// permutations of Domain, e.g. the domains a CT monitor flagged. Like a
+	// shard, such a run covers a slice of the candidate space.
+	Targets []string
+
+	// Evaluator, when set, replaces local verification of each candidate,
+	// e.g. with remote workers (see lib/fleet). Verify, Signatures and
+	// Rubric are then unused.
+	Evaluator Evaluator
+}
+
+// TargetStrategy is the strategy of Options.Targets candidates.
+const TargetStrategy = "target"
+
+// Evaluator verifies, scores and classifies one candidate of base.
+type Evaluator interface {
+	Evaluate(ctx context.Context, base string, c Candidate) (Output, error)
+}
+
+// localEvaluator runs Evaluate in-process.
+type localEvaluator struct {
+	cfg        verify.Config
+	signatures *classify.Set
+	rubric     *score.Rubric
+}
+
+func (l localEvaluator) Evaluate(ctx context.Context, base string, c Candidate) (Output, error) {
+	return Evaluate(ctx, base, c, l.cfg, l.signatures, l.rubric)
+}
+
+// DefaultHighScore roughly marks a candidate with a live site, mail and an
+// unfamiliar certificate issuer, or one with stronger signals.
+const DefaultHighScore = 25
+
+// ZoneFilter removes candidates known to be unregistered, e.g. from zone
+// files (see lib/czds).
+type ZoneFilter interface {
+	Filter(queue []Candidate) ([]Candidate, error)
+}
+
+// ProcessDomain generates typo permutations of opts.Domain and verifies each one
+// against every TLD in opts.TLDs using a pool of workers. Candidates that show
+// signs of being real are sent on the returned channel, which is closed once
+// every permutation has been processed, opts.Budget has elapsed (in-flight
+// verifications still finish), or ctx is cancelled.
+func ProcessDomain(ctx context.Context, opts Options) (<-chan Output, error) {
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	started := time.Now()
+	var queue []Candidate
+	if len(opts.Targets) > 0 {
+		targets := make([]typo.Imported, len(opts.Targets))
+		for i, d := range opts.Targets {
+			targets[i] = typo.Imported{Domain: d, Strategy: TargetStrategy}
+		}
+		queue, _, _ = candidateQueue(opts.Domain, nil, nil, nil, targets)
+		logger.Info("processing targets ProcessDomain", "count", len(queue))
+	} else {
+		var err error
+		if queue, err = Candidates(opts.Domain, opts.TLDs, opts.Strategies, opts.TLDPolicy, opts.Imported, logger); err != nil {
+			return nil, err
+		}
+		logger.Info("processing candidates ProcessDomain", "count", len(queue), "imported", len(opts.Imported))
+	}
+
+	if opts.Shards > 1 {
+		queue = shardQueue(queue, opts.Shard, opts.Shards)
+		logger.Info("processing shard ProcessDomain", "shard", opts.Shard, "shards", opts.Shards, "count", len(queue))
+	}
+
+	zoneSkipped := 0
+	if opts.Zones != nil {
+		registered, err := opts.Zones.Filter(queue)
+		if err != nil {
+			return nil, err
+		}
+		zoneSkipped = len(queue) - len(registered)
+		queue = registered
+		logger.Info("processing zones ProcessDomain", "skipped", zoneSkipped, "count", len(queue))
+	}
+
+	population := len(queue)
+	var count counters
+	count.countStrategies(queue)
+	if opts.Sample > 0 && opts.Sample < 1 {
+		queue = sampleQueue(queue, opts.Sample, opts.Seed)
+		logger.Info("processing sample ProcessDomain", "rate", opts.Sample, "seed", opts.Seed, "count", len(queue))
+	}
+
+	if opts.Max > 0 && opts.Max < len(queue) {
+		queue = queue[:opts.Max]
+	}
+	if opts.Shuffle {
+		rand.Shuffle(len(queue), func(i, j int) { queue[i], queue[j] = queue[j], queue[i] })
+	}
+
+	if opts.Progress != nil {
+		opts.Progress.queued.Store(int64(len(queue)))
+	}
+
+	in := make(chan Candidate)
+	// A small buffer keeps workers busy while the consumer writes; beyond it
+	// workers block, so a slow consumer throttles verification instead of
+	// results piling up in memory.
+	out := make(chan Output, workers)
+
+	evaluator := opts.Evaluator
+	if evaluator == nil {
+		evaluator = localEvaluator{cfg: opts.Verify, signatures: opts.Signatures, rubric: opts.Rubric}
+	}
+	highScore := opts.HighScore
+	if highScore <= 0 {
+		highScore = DefaultHighScore
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range in {
+				if !pause(ctx, opts.Delay) {
+					count.errored.Add(1)
+					opts.Progress.add(false)
+					continue
+				}
+				o, err := evaluator.Evaluate(ctx, opts.Domain, c)
+				opts.Progress.add(err == nil && o.Live())
+				if err != nil {
+					count.errored.Add(1)
+					if ctx.Err() == nil {
+						out <- unchecked(opts.Domain, c, err)
+					}
+					continue
+				}
+				count.verified.Add(1)
+				count.record(c, o, highScore)
+				// Simple triage: only emit domains that show signs of being “real”,
+				// or that couldn't be checked
+				if !o.Live() {
+					if len(o.Errors) > 0 || opts.IncludeUnresolvable {
+						out <- o
+					}
+					continue
+				}
+				count.found.Add(1)
+				out <- o
+			}
+		}()
+	}
+
+	stats := Stats{Started: started, Population: population, Queued: len(queue), ZoneSkipped: zoneSkipped, Targeted: len(opts.Targets) > 0}
+	if opts.Sample > 0 && opts.Sample < 1 {
+		stats.SampleRate, stats.SampleSeed = opts.Sample, opts.Seed
+	}
+	if opts.Shards > 1 {
+		stats.Shard = fmt.Sprintf("%d/%d", opts.Shard, opts.Shards)
+	}
+	go func() {
+		// The feeder owns the timer: ProcessDomain returns long before the
+		// budget runs out.
+		var budget <-chan time.Time
+		if opts.Budget > 0 {
+			timer := time.NewTimer(opts.Budget - time.Since(started))
+			defer timer.Stop()
+			budget = timer.C
+		}
+	feed:
+		for _, c := range queue {
+			select {
+			case in <- c: // the actual typo permutation
+				count.dispatched.Add(1)
+			case <-budget:
+				stats.BudgetExhausted = true
+				logger.Warn("processing budget exhausted ProcessDomain", "budget", opts.Budget)
+				break feed
+			case <-ctx.Done():
+				stats.Interrupted = true
+				break feed
+			}
+		}
+		close(in)
+		wg.Wait()
+
+		if opts.Stats != nil {
+			stats.Finished = time.Now()
+			count.fill(&stats)
+			*opts.Stats = stats
+		}
+		close(out)
+	}()
+
+	return out, nil
+}
+
+// Candidates generates the permutations of domain across the tlds policy
+// allows (nil = all) with strategies (nil = typo's defaults), merged with
+// imported candidates, most likely first. This is the queue ProcessDomain
+// verifies; watch modes use it as the set to match against.
+func Candidates(domain string, tlds []string, strategies []strategy.Strategy, policy *typo.TLDPolicy, imported []typo.Imported, logger *slog.Logger) ([]Candidate, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	candidates, err := typo.Generate(domain, strategies, *logger)
+	if err != nil {
+		return nil, err
+	}
+	// TODO: add a completion percentage bard on the CLI for tracking
+	for _, d := range candidates {
+		logger.Debug("processing candidates Candidates", "strategy", d.StrategyName, "count", len(d.Permutations))
+	}
+	queue, skipped, unregistrable := candidateQueue(domain, candidates, tlds, policy, imported)
+	if skipped > 0 {
+		logger.Info("processing policy Candidates", "skipped", skipped)
+	}
+	if unregistrable > 0 {
+		logger.Info("processing registrable Candidates", "dropped", unregistrable)
+	}
+	return queue, nil
+}
+
+// Evaluate verifies a single candidate of base and grades and classifies the
+// result. signatures and rubric may be nil to use classify.Default and
+// score.Default.
+func Evaluate(ctx context.Context, base string, c Candidate, cfg verify.Config, signatures *classify.Set, rubric *score.Rubric) (Output, error) {
+	v, err := verify.VerifyDomain(ctx, c.Domain, cfg)
+	if err != nil {
+		return Output{}, err
+	}
+	if signatures == nil {
+		signatures = classify.Default
+	}
+	if rubric == nil {
+		rubric = score.Default
+	}
+	graded := rubric.Record(base, v)
+	label := signatures.Record(base, v)
+	return Output{
+		Domain:     v.ASCII,
+		Unicode:    typo.Unicode(v.ASCII),
+		Strategy:   c.Strategy,
+		Likelihood: c.Likelihood,
+		Confusable: typo.Confusable(base, v.ASCII),
+		Resolvable: v.Resolvable,
+		HasMail:    v.HasMail,
+		DNS:        v.DNS,
+		TLS:        v.TLS,
+		HTTP:       v.HTTP,
+		RDAP:       v.RDAP,
+		CT:         v.CT,
+		PDNS:       v.PDNS,
+		Geo:        v.Geo,
+		Reputation: v.Reputation,
+		DNSBL:      v.DNSBL,
+		WWW:        v.WWW,
+		Services:   v.Services,
+		SMTP:       v.SMTP,
+		Score:      graded.Score,
+		ScoreTags:  graded.Tags,
+		Class:      label.Label,
+		ClassTags:  label.Reasons,
+		Listing:    label.Listing,
+		Timing:     v.Timing,
+		Errors:     v.Errors,
+	}, nil
+}
+
+// unchecked is the output for a candidate of base whose verification
+// failed.
+func unchecked(base string, c Candidate, err error) Output {
+	// Named like verified ones: ASCII, with the Unicode form alongside.
+	ascii, aerr := idna.Lookup.ToASCII(c.Domain)
+	if aerr != nil {
+		ascii = c.Domain
+	}
+	return Output{
+		Domain:     ascii,
+		Unicode:    typo.Unicode(ascii),
+		Strategy:   c.Strategy,
+		Likelihood: c.Likelihood,
+		Confusable: typo.Confusable(base, ascii),
+		Errors:     []verify.StageError{*verify.NewStageError("verify", err)},
+	}
+}
+
+// candidateQueue expands every generated permutation across the TLD variants
+// policy allows, merges in imported candidates, drops duplicates and the base
+// domain itself, and orders the result most likely first so capped,
+// time-budgeted or interrupted scans still cover the most probable squats.
+// skipped counts the permutation/TLD pairs the policy ruled out, and
+// unregistrable those no registry would accept (see
+// typo.TLDPolicy.Registrable). Imported candidates are never dropped.
+func candidateQueue(base string, candidates []typogenerator.FuzzResult, tlds []string, policy *typo.TLDPolicy, imported []typo.Imported) (queue []Candidate, skipped, unregistrable int) {
+	base = strings.ToLower(strings.TrimSuffix(base, "."))
+	index := map[string]int{base: -1}
+	add := func(d, strategy string) {
+		d = strings.ToLower(d)
+		l := typo.Likelihood(base, d, strategy)
+		i, ok := index[d]
+		switch {
+		case !ok:
+			index[d] = len(queue)
+			queue = append(queue, Candidate{Domain: d, Strategy: strategy, Likelihood: l})
+		case i >= 0 && l > queue[i].Likelihood: // keep the most plausible explanation
+			queue[i].Strategy, queue[i].Likelihood = strategy, l
+		}
+	}
+
+	for _, c := range candidates {
+		for _, tld := range tlds {
+			if !policy.Allows(c.StrategyName, tld) {
+				skipped += len(c.Permutations)
+				continue
+			}
+			for _, p := range c.Permutations {
+				// Generated from the base as given, so possibly mixed case,
+				// which IDNA registration rejects.
+				p = strings.ToLower(p)
+				if err := policy.Registrable(p, tld); err != nil {
+					unregistrable++
+					continue
+				}
+				add(p+"."+tld, c.StrategyName)
+			}
+		}
+	}
+	for _, imp := range imported {
+		add(imp.Domain, imp.Strategy)
+	}
+
+	sort.SliceStable(queue, func(i, j int) bool { return queue[i].Likelihood > queue[j].Likelihood })
+	return queue, skipped, unregistrable
+}
+
+// pause waits a random 0.5-1.5x delay, reporting false if ctx ended first.
+func pause(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	t := time.NewTimer(delay/2 + rand.N(delay))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// shardQueue keeps the candidates whose domain hashes into shard of shards.
+// The hash depends only on the domain, so every run splits the same
+// candidate space the same way whatever the generation order.
+func shardQueue(queue []Candidate, shard, shards int) []Candidate {
+	var kept []Candidate
+	for _, c := range queue {
+		h := fnv.New64a()
+		h.Write([]byte(c.Domain))
+		if h.Sum64()%uint64(shards) == uint64(shard) {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// sampleQueue keeps each candidate with probability rate using a seeded
+// generator, preserving likelihood order among the kept candidates.
+func sampleQueue(queue []Candidate, rate float64, seed uint64) []Candidate {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	var kept []Candidate
+	for _, c := range queue {
+		if rng.Float64() < rate {
+			kept = append(kept, c)
+		}
+	}
+	return kept
 }
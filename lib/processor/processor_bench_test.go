@@ -0,0 +1,26 @@
+package processor
+
+import (
+	"fmt"
+	"testing"
+	"zntr.io/typogenerator"
+)
+
+// BenchmarkCandidateQueue measures queue expansion and de-duplication at the
+// scale of a large scan (100k permutations x 5 TLDs = 500k candidates).
+func BenchmarkCandidateQueue(b *testing.B) {
+	perms := make([]string, 100_000)
+	for i := range perms {
+		perms[i] = fmt.Sprintf("examp%06dle", i)
+	}
+	generated := []typogenerator.FuzzResult{{StrategyName: "Synthetic", Permutations: perms}}
+	tlds := []string{"com", "net", "org", "co", "io"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if q, _, _ := candidateQueue("example.com", generated, tlds, nil, nil); len(q) != len(perms)*len(tlds) {
+			b.Fatalf("len(queue) = %d", len(q))
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"squatrr/lib/typo"
+	"testing"
+	"time"
+	"zntr.io/typogenerator"
+	"zntr.io/typogenerator/strategy"
+)
+
+func TestCandidateQueue(t *testing.T) {
+	generated := []typogenerator.FuzzResult{
+		{StrategyName: "Omission", Permutations: []string{"exmple", "exampe"}},
+		{StrategyName: "Repetition", Permutations: []string{"exmple", "example"}},
+	}
+	imported := []typo.Imported{
+		{Domain: "exmple.com", Strategy: "dnstwist:omission"},
+		{Domain: "examp1e.org", Strategy: "dnstwist:homoglyph"},
+	}
+
+	queue, _, _ := candidateQueue("Example.com", generated, []string{"com", "net"}, nil, imported)
+	var got []string
+	for i, c := range queue {
+		got = append(got, c.Domain+"/"+c.Strategy)
+		if i > 0 && c.Likelihood > queue[i-1].Likelihood {
+			t.Errorf("queue not ordered by likelihood at %d: %f > %f", i, c.Likelihood, queue[i-1].Likelihood)
+		}
+	}
+	want := []string{
+		"example.net/Repetition",
+		"exmple.com/Omission", "exampe.com/Omission",
+		"exmple.net/Omission", "exampe.net/Omission",
+		"examp1e.org/dnstwist:homoglyph",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("candidateQueue() = %v, want %v", got, want)
+	}
+}
+
+func TestSampleQueue(t *testing.T) {
+	queue := make([]Candidate, 10000)
+	for i := range queue {
+		queue[i] = Candidate{Domain: fmt.Sprintf("c%05d.com", i), Likelihood: float64(len(queue) - i)}
+	}
+
+	a := sampleQueue(queue, 0.05, 42)
+	b := sampleQueue(queue, 0.05, 42)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("sampleQueue() with the same seed is not reproducible")
+	}
+	if len(a) < 400 || len(a) > 600 {
+		t.Errorf("len(sampleQueue(10000, 5%%)) = %d, want about 500", len(a))
+	}
+	for i := 1; i < len(a); i++ {
+		if a[i].Likelihood > a[i-1].Likelihood {
+			t.Fatalf("sample lost likelihood ordering at %d", i)
+		}
+	}
+}
+
+func TestShardQueue(t *testing.T) {
+	queue := make([]Candidate, 10000)
+	for i := range queue {
+		queue[i] = Candidate{Domain: fmt.Sprintf("c%05d.com", i), Likelihood: float64(len(queue) - i)}
+	}
+
+	const shards = 4
+	seen := map[string]int{}
+	for shard := range shards {
+		part := shardQueue(queue, shard, shards)
+		if len(part) < 2200 || len(part) > 2800 {
+			t.Errorf("len(shardQueue(10000, %d/%d)) = %d, want about 2500", shard, shards, len(part))
+		}
+		for i, c := range part {
+			seen[c.Domain]++
+			if i > 0 && c.Likelihood > part[i-1].Likelihood {
+				t.Fatalf("shard %d lost likelihood ordering at %d", shard, i)
+			}
+		}
+	}
+	if len(seen) != len(queue) {
+		t.Errorf("shards cover %d candidates, want %d", len(seen), len(queue))
+	}
+	for d, n := range seen {
+		if n != 1 {
+			t.Fatalf("%s is in %d shards, want 1", d, n)
+		}
+	}
+
+	// Reordering the queue must not move candidates between shards.
+	reversed := slices.Clone(queue)
+	slices.Reverse(reversed)
+	a, b := shardQueue(queue, 1, shards), shardQueue(reversed, 1, shards)
+	slices.Reverse(b)
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("shardQueue() depends on queue order")
+	}
+}
+
+func TestStatsEstimate(t *testing.T) {
+	if (Stats{Verified: 10, Found: 1}).Estimate() != nil {
+		t.Errorf("Estimate() for a full run should be nil")
+	}
+
+	e := Stats{Population: 100000, SampleRate: 0.05, Verified: 5000, Found: 50}.Estimate()
+	if e == nil {
+		t.Fatalf("Estimate() = nil")
+	}
+	if e.HitRate != 0.01 || e.Found != 1000 {
+		t.Errorf("Estimate() = %+v, want hit rate 0.01 and 1000 found", e)
+	}
+	if e.Found95Lo >= e.Found || e.Found95Hi <= e.Found || e.Found95Lo < 700 || e.Found95Hi > 1400 {
+		t.Errorf("Estimate() interval = [%d, %d], want a plausible interval around 1000", e.Found95Lo, e.Found95Hi)
+	}
+}
+
+func TestStatsComplete(t *testing.T) {
+	full := Stats{Population: 10, Queued: 10, Dispatched: 10}
+	if !full.Complete() {
+		t.Error("Complete() of a full scan = false")
+	}
+	for name, st := range map[string]Stats{
+		"budget":      {Population: 10, Queued: 10, Dispatched: 6, BudgetExhausted: true},
+		"max":         {Population: 10, Queued: 5, Dispatched: 5},
+		"sampled":     {Population: 10, Queued: 5, Dispatched: 5, SampleRate: 0.5},
+		"shard":       {Population: 10, Queued: 10, Dispatched: 10, Shard: "1/4"},
+		"targeted":    {Population: 2, Queued: 2, Dispatched: 2, Targeted: true},
+		"interrupted": {Population: 10, Queued: 10, Dispatched: 10, Interrupted: true},
+	} {
+		if st.Complete() {
+			t.Errorf("Complete() of a %s scan = true", name)
+		}
+	}
+}
+
+func TestStrategyStats(t *testing.T) {
+	population := []Candidate{
+		{Domain: "exampel.com", Strategy: "transposition"},
+		{Domain: "exapmle.com", Strategy: "transposition"},
+		{Domain: "examp1e.com", Strategy: "homoglyph"},
+		{Domain: "xample.com", Strategy: "omission"},
+	}
+	var c counters
+	c.countStrategies(population)
+	c.record(population[0], Output{Resolvable: true, HasMail: true, Score: 30}, 25)
+	c.record(population[1], Output{}, 25)
+	c.record(population[2], Output{HasMail: true, Score: 10}, 25)
+
+	var s Stats
+	c.fill(&s)
+	want := []StrategyStats{
+		{Strategy: "transposition", Generated: 2, Verified: 2, Resolvable: 1, Mail: 1, HighScore: 1},
+		{Strategy: "homoglyph", Generated: 1, Verified: 1, Mail: 1},
+		{Strategy: "omission", Generated: 1},
+	}
+	if !reflect.DeepEqual(s.Strategies, want) {
+		t.Errorf("Strategies = %+v, want %+v", s.Strategies, want)
+	}
+	if got := s.Strategies[0].HitRate(); got != 0.5 {
+		t.Errorf("HitRate() = %v, want 0.5", got)
+	}
+}
+
+func TestCandidateQueuePolicy(t *testing.T) {
+	generated := []typogenerator.FuzzResult{
+		{StrategyName: "Omission", Permutations: []string{"exmple"}},
+		{StrategyName: "Homoglyph", Permutations: []string{"examp1e"}},
+		{StrategyName: "WholeScript", Permutations: []string{"ехамрӏе"}}, // not registrable in .uk
+	}
+	policy := &typo.TLDPolicy{Rules: []typo.PolicyRule{{Strategies: []string{"homoglyph"}, TLDs: []string{"com"}}}}
+	if err := policy.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	queue, skipped, unregistrable := candidateQueue("example.com", generated, []string{"com", "uk"}, policy, nil)
+	var got []string
+	for _, c := range queue {
+		got = append(got, c.Domain)
+	}
+	slices.Sort(got)
+	if want := []string{"examp1e.com", "exmple.com", "exmple.uk", "ехамрӏе.com"}; !reflect.DeepEqual(got, want) || skipped != 1 || unregistrable != 1 {
+		t.Errorf("candidateQueue() = %v, skipped %d, unregistrable %d, want %v, skipped 1, unregistrable 1", got, skipped, unregistrable, want)
+	}
+}
+
+func TestCandidateQueueMixedCase(t *testing.T) {
+	generated := []typogenerator.FuzzResult{{StrategyName: "Omission", Permutations: []string{"Exmple", "Exampe"}}}
+	queue, _, unregistrable := candidateQueue("Example.com", generated, []string{"com"}, nil, nil)
+	var got []string
+	for _, c := range queue {
+		got = append(got, c.Domain)
+	}
+	if want := []string{"exmple.com", "exampe.com"}; !reflect.DeepEqual(got, want) || unregistrable != 0 {
+		t.Errorf("candidateQueue() = %v, %d unregistrable; want %v, none", got, unregistrable, want)
+	}
+}
+
+func TestUnchecked(t *testing.T) {
+	o := unchecked("example.com", Candidate{Domain: "exämple.com", Strategy: "Homoglyph"}, errors.New("timeout"))
+	if o.Domain != "xn--exmple-cua.com" || o.Unicode != "exämple.com" || o.Live() {
+		t.Errorf("unchecked() = %q (%q), live %v; want xn--exmple-cua.com (exämple.com), not live", o.Domain, o.Unicode, o.Live())
+	}
+}
+
+// fixedStrategy generates the same labels for any domain.
+type fixedStrategy []string
+
+func (f fixedStrategy) Generate(string, string) ([]string, error) { return f, nil }
+func (fixedStrategy) GetName() string                             { return "Fixed" }
+
+// fakeEvaluator answers from a table: a missing domain is unregistered.
+type fakeEvaluator map[string]error
+
+func (f fakeEvaluator) Evaluate(_ context.Context, _ string, c Candidate) (Output, error) {
+	err, ok := f[c.Domain]
+	if err != nil {
+		return Output{}, err
+	}
+	return Output{Domain: c.Domain, Resolvable: ok}, nil
+}
+
+func TestProcessDomainTriage(t *testing.T) {
+	evaluator := fakeEvaluator{"exampel.com": nil, "examp1e.com": errors.New("remote worker lost")}
+	tests := []struct {
+		all  bool
+		want []string
+	}{
+		{false, []string{"examp1e.com (verify)", "exampel.com"}},
+		{true, []string{"examp1e.com (verify)", "exampel.com", "exanple.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("all=", tt.all), func(t *testing.T) {
+			var progress Progress
+			out, err := ProcessDomain(context.Background(), Options{
+				Domain:              "example.com",
+				TLDs:                []string{"com"},
+				Workers:             2,
+				Strategies:          []strategy.Strategy{fixedStrategy{"exampel", "examp1e", "exanple"}},
+				Evaluator:           evaluator,
+				IncludeUnresolvable: tt.all,
+				Progress:            &progress,
+				Logger:              slog.New(slog.DiscardHandler),
+			})
+			if err != nil {
+				t.Fatalf("ProcessDomain() error: %v", err)
+			}
+			var got []string
+			for o := range out {
+				if len(o.Errors) > 0 {
+					o.Domain += " (" + o.Errors[0].Stage + ")"
+				}
+				got = append(got, o.Domain)
+			}
+			slices.Sort(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("outputs = %v, want %v", got, tt.want)
+			}
+			if progress.Queued() != 3 || progress.Done() != 3 || progress.Found() != 1 {
+				t.Errorf("progress = %d/%d found %d, want 3/3 found 1", progress.Done(), progress.Queued(), progress.Found())
+			}
+		})
+	}
+}
+
+func TestProcessDomainTargets(t *testing.T) {
+	var stats Stats
+	out, err := ProcessDomain(context.Background(), Options{
+		Domain:              "example.com",
+		TLDs:                []string{"com"},
+		Targets:             []string{"exampel.net", "EXAMPEL.net", "example.com", "examp1e.org"},
+		Evaluator:           fakeEvaluator{"exampel.net": nil},
+		IncludeUnresolvable: true,
+		Stats:               &stats,
+		Logger:              slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatalf("ProcessDomain() error: %v", err)
+	}
+	var got []string
+	for o := range out {
+		got = append(got, o.Domain)
+	}
+	slices.Sort(got)
+	// Only the targets, once each and never the base itself.
+	if want := []string{"examp1e.org", "exampel.net"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outputs = %v, want %v", got, want)
+	}
+	if !stats.Targeted || stats.Queued != 2 {
+		t.Errorf("stats = %+v, want 2 targeted candidates", stats)
+	}
+}
+
+// slowEvaluator takes delay over every candidate.
+type slowEvaluator time.Duration
+
+func (d slowEvaluator) Evaluate(ctx context.Context, _ string, c Candidate) (Output, error) {
+	select {
+	case <-time.After(time.Duration(d)):
+	case <-ctx.Done():
+	}
+	return Output{Domain: c.Domain}, nil
+}
+
+func TestProcessDomainBudget(t *testing.T) {
+	labels := make(fixedStrategy, 40)
+	for i := range labels {
+		labels[i] = fmt.Sprintf("example%02d", i)
+	}
+	var stats Stats
+	out, err := ProcessDomain(context.Background(), Options{
+		Domain:     "example.com",
+		TLDs:       []string{"com"},
+		Workers:    2,
+		Strategies: []strategy.Strategy{labels},
+		Evaluator:  slowEvaluator(20 * time.Millisecond),
+		Budget:     120 * time.Millisecond,
+		Stats:      &stats,
+		Logger:     slog.New(slog.DiscardHandler),
+	})
+	if err != nil {
+		t.Fatalf("ProcessDomain() error: %v", err)
+	}
+	for range out {
+	}
+	// About 2 workers x 120ms / 20ms; far from the 40 queued.
+	if !stats.BudgetExhausted || stats.Dispatched >= 30 || stats.Queued != 40 {
+		t.Errorf("stats = %d of %d dispatched, budget exhausted %v; want dispatching stopped by the budget", stats.Dispatched, stats.Queued, stats.BudgetExhausted)
+	}
+}
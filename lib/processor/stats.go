@@ -0,0 +1,195 @@
+package processor
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Stats summarizes how much of the candidate space a run covered.
+type Stats struct {
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Population int       `json:"population"` // candidates after de-duplication, sharding and zone filtering, before sampling and -max
+	Queued     int       `json:"queued"`     // candidates after sampling and -max
+	Dispatched int       `json:"dispatched"` // handed to a worker before the run stopped
+	Verified   int       `json:"verified"`   // verification completed without a hard error
+	Errored    int       `json:"errored"`    // verification aborted (timeouts, cancellation)
+	Found      int       `json:"found"`      // emitted as showing signs of being real
+
+	BudgetExhausted bool `json:"budget_exhausted"` // -budget elapsed before the queue drained
+	Interrupted     bool `json:"interrupted"`      // the run context was cancelled
+
+	ZoneSkipped int `json:"zone_skipped,omitempty"` // candidates absent from their TLD's zone file
+
+	SampleRate float64 `json:"sample_rate,omitempty"` // fraction of the population sampled (0 = full scan)
+	SampleSeed uint64  `json:"sample_seed,omitempty"`
+
+	Shard string `json:"shard,omitempty"` // "i/n" when the run covered one shard of the candidate space
+
+	Targeted bool `json:"targeted,omitempty"` // only Options.Targets were verified, not the permutations
+
+	// Strategies breaks the run down by permutation strategy, most
+	// candidates first.
+	Strategies []StrategyStats `json:"strategies,omitempty"`
+}
+
+// StrategyStats measures how much one permutation strategy yields, so
+// low-yield strategies can be pruned and attacker preferences studied.
+type StrategyStats struct {
+	Strategy   string `json:"strategy"`
+	Generated  int    `json:"generated"` // candidates in the population attributed to the strategy
+	Verified   int    `json:"verified"`
+	Resolvable int    `json:"resolvable"`
+	Mail       int    `json:"mail"`
+	HighScore  int    `json:"high_score"` // found candidates scoring at least Options.HighScore
+}
+
+// HitRate is the fraction of verified candidates that resolved.
+func (s StrategyStats) HitRate() float64 {
+	if s.Verified == 0 {
+		return 0
+	}
+	return float64(s.Resolvable) / float64(s.Verified)
+}
+
+// Estimate extrapolates a sampled run to the full candidate population.
+type Estimate struct {
+	HitRate   float64 `json:"hit_rate"` // found / verified within the sample
+	Found     int     `json:"found"`    // expected live candidates in the population
+	Found95Lo int     `json:"found_95_lo"`
+	Found95Hi int     `json:"found_95_hi"`
+}
+
+// Estimate extrapolates the sample's hit rate to the population using a 95%
+// Wilson score interval. It returns nil for full (unsampled) runs.
+func (s Stats) Estimate() *Estimate {
+	if s.SampleRate <= 0 || s.Verified == 0 {
+		return nil
+	}
+	const z = 1.96
+	n := float64(s.Verified)
+	p := float64(s.Found) / n
+	center := (p + z*z/(2*n)) / (1 + z*z/n)
+	half := z * math.Sqrt(p*(1-p)/n+z*z/(4*n*n)) / (1 + z*z/n)
+	pop := float64(s.Population)
+	return &Estimate{
+		HitRate:   p,
+		Found:     int(math.Round(p * pop)),
+		Found95Lo: int(math.Floor(max(0, center-half) * pop)),
+		Found95Hi: int(math.Ceil(min(1, center+half) * pop)),
+	}
+}
+
+// Coverage is the fraction of queued candidates that were dispatched.
+func (s Stats) Coverage() float64 {
+	if s.Queued == 0 {
+		return 1
+	}
+	return float64(s.Dispatched) / float64(s.Queued)
+}
+
+// Complete reports whether the run verified the whole candidate space:
+// every candidate dispatched, none left out by sampling, -max, sharding or
+// targeting, and not interrupted. Only then does a candidate missing from
+// the results mean it is gone.
+func (s Stats) Complete() bool {
+	return s.Dispatched == s.Queued && s.Queued == s.Population && s.SampleRate == 0 && s.Shard == "" && !s.Targeted && !s.Interrupted
+}
+
+// Progress follows a run in flight, which Stats only describes once it
+// has finished. Its methods are safe to call while the run goes on.
+type Progress struct {
+	queued, done, found atomic.Int64
+}
+
+// Queued is the number of candidates the run will verify (0 until they
+// are generated).
+func (p *Progress) Queued() int { return int(p.queued.Load()) }
+
+// Done is the number of candidates verified or abandoned so far.
+func (p *Progress) Done() int { return int(p.done.Load()) }
+
+// Found is the number of candidates so far showing signs of being real.
+func (p *Progress) Found() int { return int(p.found.Load()) }
+
+// add counts one more candidate done, found or not. A nil Progress
+// ignores it.
+func (p *Progress) add(found bool) {
+	if p == nil {
+		return
+	}
+	p.done.Add(1)
+	if found {
+		p.found.Add(1)
+	}
+}
+
+// counters are the live, concurrently updated parts of Stats.
+type counters struct {
+	dispatched, verified, errored, found atomic.Int64
+
+	// strategies is filled in before the workers start and only read after.
+	strategies map[string]*strategyCounters
+}
+
+type strategyCounters struct {
+	generated                             int
+	verified, resolvable, mail, highScore atomic.Int64
+}
+
+// countStrategies starts the per-strategy counters from the population.
+func (c *counters) countStrategies(population []Candidate) {
+	c.strategies = map[string]*strategyCounters{}
+	for _, cand := range population {
+		sc := c.strategies[cand.Strategy]
+		if sc == nil {
+			sc = &strategyCounters{}
+			c.strategies[cand.Strategy] = sc
+		}
+		sc.generated++
+	}
+}
+
+// record counts a verified candidate against its strategy.
+func (c *counters) record(cand Candidate, o Output, highScore int) {
+	sc := c.strategies[cand.Strategy]
+	if sc == nil {
+		return
+	}
+	sc.verified.Add(1)
+	if o.Resolvable {
+		sc.resolvable.Add(1)
+	}
+	if o.HasMail {
+		sc.mail.Add(1)
+	}
+	if (o.Resolvable || o.HasMail) && o.Score >= highScore {
+		sc.highScore.Add(1)
+	}
+}
+
+func (c *counters) fill(s *Stats) {
+	s.Dispatched = int(c.dispatched.Load())
+	s.Verified = int(c.verified.Load())
+	s.Errored = int(c.errored.Load())
+	s.Found = int(c.found.Load())
+
+	s.Strategies = make([]StrategyStats, 0, len(c.strategies))
+	for name, sc := range c.strategies {
+		s.Strategies = append(s.Strategies, StrategyStats{
+			Strategy:   name,
+			Generated:  sc.generated,
+			Verified:   int(sc.verified.Load()),
+			Resolvable: int(sc.resolvable.Load()),
+			Mail:       int(sc.mail.Load()),
+			HighScore:  int(sc.highScore.Load()),
+		})
+	}
+	slices.SortFunc(s.Strategies, func(a, b StrategyStats) int {
+		return cmp.Or(cmp.Compare(b.Generated, a.Generated), strings.Compare(a.Strategy, b.Strategy))
+	})
+}
@@ -0,0 +1,145 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"squatrr/lib/processor"
+	"strings"
+	"time"
+
+	"github.com/linkedin/goavro/v2"
+)
+
+// AvroSchema is the record findings are flattened into for Avro consumers.
+// The full result rides along as JSON in the result field.
+const AvroSchema = `{
+  "type": "record",
+  "name": "Finding",
+  "namespace": "squatrr",
+  "fields": [
+    {"name": "domain", "type": "string"},
+    {"name": "base", "type": "string"},
+    {"name": "strategy", "type": "string"},
+    {"name": "observed_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "resolvable", "type": "boolean"},
+    {"name": "has_mail", "type": "boolean"},
+    {"name": "score", "type": "int"},
+    {"name": "score_tags", "type": {"type": "array", "items": "string"}},
+    {"name": "class", "type": "string"},
+    {"name": "a", "type": {"type": "array", "items": "string"}},
+    {"name": "aaaa", "type": {"type": "array", "items": "string"}},
+    {"name": "ns", "type": {"type": "array", "items": "string"}},
+    {"name": "mx", "type": {"type": "array", "items": "string"}},
+    {"name": "countries", "type": {"type": "array", "items": "string"}},
+    {"name": "tls_issuer", "type": "string"},
+    {"name": "http_status", "type": "int"},
+    {"name": "result", "type": "string", "doc": "the full finding as JSON"}
+  ]
+}`
+
+// Avro encodes findings in the Confluent wire format: a zero byte, the
+// schema's registry ID, then the Avro binary record.
+type Avro struct {
+	codec *goavro.Codec
+	id    uint32
+}
+
+// NewAvro registers AvroSchema under subject (conventionally
+// "<topic>-value") with the schema registry at registryURL. Credentials in
+// the URL's userinfo are sent as basic auth.
+func NewAvro(ctx context.Context, registryURL, subject string) (*Avro, error) {
+	codec, err := goavro.NewCodec(AvroSchema)
+	if err != nil {
+		return nil, err
+	}
+	id, err := register(ctx, http.DefaultClient, registryURL, subject, codec.CanonicalSchema())
+	if err != nil {
+		return nil, fmt.Errorf("registering avro schema: %w", err)
+	}
+	return &Avro{codec: codec, id: id}, nil
+}
+
+func (a *Avro) Encode(base string, o processor.Output, observed time.Time) ([]byte, error) {
+	raw, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	strs := func(s []string) []any {
+		out := make([]any, len(s))
+		for i, v := range s {
+			out[i] = v
+		}
+		return out
+	}
+	var countries []string
+	if o.Geo != nil {
+		countries = o.Geo.Countries
+	}
+	var issuer string
+	if o.TLS != nil {
+		issuer = o.TLS.Issuer
+	}
+	var status int
+	if o.HTTP != nil {
+		status = o.HTTP.StatusCode
+	}
+	buf := binary.BigEndian.AppendUint32([]byte{0}, a.id)
+	return a.codec.BinaryFromNative(buf, map[string]any{
+		"domain":      o.Domain,
+		"base":        base,
+		"strategy":    o.Strategy,
+		"observed_at": observed,
+		"resolvable":  o.Resolvable,
+		"has_mail":    o.HasMail,
+		"score":       int32(o.Score),
+		"score_tags":  strs(o.ScoreTags),
+		"class":       o.Class,
+		"a":           strs(o.DNS.A),
+		"aaaa":        strs(o.DNS.AAAA),
+		"ns":          strs(o.DNS.NS),
+		"mx":          strs(o.DNS.MX),
+		"countries":   strs(countries),
+		"tls_issuer":  issuer,
+		"http_status": int32(status),
+		"result":      string(raw),
+	})
+}
+
+func (a *Avro) ContentType() string { return "application/vnd.squatrr.finding+avro" }
+
+// register adds schema to subject (a no-op when already registered) and
+// returns its global ID.
+func register(ctx context.Context, client *http.Client, registryURL, subject, schema string) (uint32, error) {
+	body, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return 0, err
+	}
+	u := strings.TrimSuffix(registryURL, "/") + "/subjects/" + url.PathEscape(subject) + "/versions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("schema registry: %s: %s", resp.Status, msg)
+	}
+	var out struct {
+		ID uint32 `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return 0, err
+	}
+	return out.ID, nil
+}
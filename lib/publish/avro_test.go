@@ -0,0 +1,63 @@
+package publish
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+	"time"
+)
+
+func TestAvro(t *testing.T) {
+	var subject, user string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subject = r.URL.Path
+		user, _, _ = r.BasicAuth()
+		var body struct{ Schema string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Schema == "" {
+			http.Error(w, "bad schema", http.StatusUnprocessableEntity)
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":42}`))
+	}))
+	defer srv.Close()
+
+	u := "http://reg:secret@" + srv.Listener.Addr().String()
+	a, err := NewAvro(context.Background(), u, "squats-value")
+	if err != nil {
+		t.Fatalf("NewAvro() error: %v", err)
+	}
+	if subject != "/subjects/squats-value/versions" || user != "reg" {
+		t.Errorf("registered at %q as %q", subject, user)
+	}
+
+	observed := time.UnixMilli(1714564800000).UTC()
+	o := processor.Output{Domain: "exampel.com", Resolvable: true, Score: 42,
+		DNS: verify.DNSResult{A: []string{"192.0.2.1"}}, HTTP: &verify.HTTPResult{StatusCode: 200}}
+	b, err := a.Encode("example.com", o, observed)
+	if err != nil {
+		t.Fatalf("Encode() error: %v", err)
+	}
+	if b[0] != 0 || binary.BigEndian.Uint32(b[1:]) != 42 {
+		t.Fatalf("Encode() header = %x, want magic 0 and schema id 42", b[:5])
+	}
+	native, _, err := a.codec.NativeFromBinary(b[5:])
+	if err != nil {
+		t.Fatalf("decoding Encode() output: %v", err)
+	}
+	rec := native.(map[string]any)
+	if rec["domain"] != "exampel.com" || rec["base"] != "example.com" || rec["score"] != int32(42) || rec["http_status"] != int32(200) {
+		t.Errorf("Encode() record = %v", rec)
+	}
+	if ts, _ := rec["observed_at"].(time.Time); !ts.Equal(observed) {
+		t.Errorf("observed_at = %v, want %v", rec["observed_at"], observed)
+	}
+	var full processor.Output
+	if err := json.Unmarshal([]byte(rec["result"].(string)), &full); err != nil || full.DNS.A[0] != "192.0.2.1" {
+		t.Errorf("result = %v (%v)", rec["result"], err)
+	}
+}
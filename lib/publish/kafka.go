@@ -0,0 +1,154 @@
+package publish
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"squatrr/lib/processor"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// kafkaBatch is how many findings are buffered per produce request.
+const kafkaBatch = 100
+
+// DefaultKafkaFlush is how long a finding waits for a batch to fill before
+// it is produced anyway.
+const DefaultKafkaFlush = time.Second
+
+// KafkaOptions configures the Kafka sink.
+type KafkaOptions struct {
+	Brokers  []string // bootstrap brokers, host:port
+	Topic    string
+	TLS      *tls.Config // optional
+	SASL     string      // optional mechanism: plain, scram-sha-256 or scram-sha-512
+	Username string
+	Password string
+	Flush    time.Duration // longest a partial batch waits (0 = DefaultKafkaFlush)
+	Base     string
+}
+
+// Kafka is a sink producing each finding to a topic, keyed by domain, with
+// the base domain and content type as record headers. Records are batched
+// and produced in the background, a batch leaving once full or once its
+// first record has waited Flush, so a slow scan still streams. A broker
+// outage is logged and the affected findings dropped rather than failing
+// the scan; they are still in the results file.
+type Kafka struct {
+	w      *kafka.Writer
+	base   string
+	enc    Encoder
+	logger *slog.Logger
+
+	mu      sync.Mutex
+	sent    int
+	dropped int
+}
+
+// NewKafka checks the brokers answer (and accept the credentials) and
+// returns a sink producing to opts.Topic, created by the cluster if it
+// auto-creates topics.
+func NewKafka(ctx context.Context, opts KafkaOptions, enc Encoder, logger *slog.Logger) (*Kafka, error) {
+	if len(opts.Brokers) == 0 || opts.Topic == "" {
+		return nil, errors.New("kafka: brokers and topic are required")
+	}
+	mechanism, err := kafkaSASL(opts.SASL, opts.Username, opts.Password)
+	if err != nil {
+		return nil, err
+	}
+	transport := &kafka.Transport{ClientID: "sasquat", TLS: opts.TLS, SASL: mechanism, DialTimeout: 10 * time.Second}
+	addr := kafka.TCP(opts.Brokers...)
+	client := &kafka.Client{Addr: addr, Transport: transport, Timeout: 30 * time.Second}
+	if _, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{opts.Topic}}); err != nil {
+		return nil, err
+	}
+
+	flush := opts.Flush
+	if flush <= 0 {
+		flush = DefaultKafkaFlush
+	}
+	k := &Kafka{base: opts.Base, enc: enc, logger: logger}
+	k.w = &kafka.Writer{
+		Addr:                   addr,
+		Topic:                  opts.Topic,
+		Balancer:               kafka.Murmur2Balancer{}, // the Java client's default partitioner
+		BatchSize:              kafkaBatch,
+		BatchTimeout:           flush,
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+		Async:                  true,
+		Completion:             k.completed,
+		Transport:              transport,
+	}
+	return k, nil
+}
+
+// kafkaSASL returns the SASL mechanism named, or nil for none.
+func kafkaSASL(name, username, password string) (sasl.Mechanism, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	}
+	return nil, fmt.Errorf("kafka: unknown SASL mechanism %q (want plain, scram-sha-256 or scram-sha-512)", name)
+}
+
+func (k *Kafka) Write(o processor.Output) error {
+	msg, err := kafkaMsg(k.base, k.enc, o, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := k.w.WriteMessages(context.Background(), msg); err != nil {
+		k.completed([]kafka.Message{msg}, err)
+	}
+	return nil
+}
+
+// completed counts a batch the writer produced or gave up on.
+func (k *Kafka) completed(msgs []kafka.Message, err error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err != nil {
+		k.logger.Error("processing kafka sink", "dropped", len(msgs), "error", err)
+		k.dropped += len(msgs)
+		return
+	}
+	k.sent += len(msgs)
+}
+
+func (k *Kafka) Close() error {
+	err := k.w.Close() // produces what is pending
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.logger.Info("processing kafka sink", "produced", k.sent, "dropped", k.dropped)
+	return err
+}
+
+func kafkaMsg(base string, enc Encoder, o processor.Output, now time.Time) (kafka.Message, error) {
+	value, err := enc.Encode(base, o, now)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{
+		Key:   []byte(o.Domain),
+		Value: value,
+		Time:  now,
+		Headers: []kafka.Header{
+			{Key: "base", Value: []byte(base)},
+			{Key: "content-type", Value: []byte(enc.ContentType())},
+		},
+	}, nil
+}
@@ -0,0 +1,35 @@
+package publish
+
+import (
+	"squatrr/lib/processor"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKafkaMsg(t *testing.T) {
+	msg, err := kafkaMsg("example.com", JSON{}, processor.Output{Domain: "exampel.com"}, time.Now())
+	if err != nil {
+		t.Fatalf("kafkaMsg() error: %v", err)
+	}
+	if string(msg.Key) != "exampel.com" || len(msg.Headers) != 2 || string(msg.Headers[0].Value) != "example.com" {
+		t.Errorf("kafkaMsg() = %+v", msg)
+	}
+	if !strings.HasPrefix(string(msg.Value), `{"domain":"exampel.com",`) {
+		t.Errorf("kafkaMsg() value = %s", msg.Value)
+	}
+}
+
+func TestKafkaSASL(t *testing.T) {
+	for _, name := range []string{"", "plain", "SCRAM-SHA-256", "scram-sha-512"} {
+		if _, err := kafkaSASL(name, "sasquat", "secret"); err != nil {
+			t.Errorf("kafkaSASL(%q) error: %v", name, err)
+		}
+	}
+	if m, _ := kafkaSASL("", "", ""); m != nil {
+		t.Errorf("kafkaSASL(\"\") = %v, want none", m)
+	}
+	if _, err := kafkaSASL("gssapi", "sasquat", "secret"); err == nil {
+		t.Error("kafkaSASL(gssapi) error = nil")
+	}
+}
@@ -0,0 +1,108 @@
+package publish
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"squatrr/lib/processor"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATSOptions configures the NATS sink.
+type NATSOptions struct {
+	URL     string // comma-separated server URLs; credentials may be embedded
+	Creds   string // optional .creds file (JWT + NKey seed)
+	Subject string
+	Stream  string // optional JetStream stream; created over Subject if missing
+	Base    string
+}
+
+// NATS is a sink publishing each finding to a subject with the base domain
+// and content type as headers. With a stream configured, publishes go
+// through JetStream and wait for the server's acknowledgement; each message
+// carries a Nats-Msg-Id so redelivered publishes are de-duplicated. Like
+// Kafka, failures are logged and dropped rather than failing the scan.
+type NATS struct {
+	nc      *nats.Conn
+	js      jetstream.JetStream
+	opts    NATSOptions
+	enc     Encoder
+	run     string
+	logger  *slog.Logger
+	sent    int
+	dropped int
+}
+
+func NewNATS(ctx context.Context, opts NATSOptions, enc Encoder, logger *slog.Logger) (*NATS, error) {
+	connOpts := []nats.Option{nats.Name("sasquat"), nats.MaxReconnects(-1)}
+	if opts.Creds != "" {
+		connOpts = append(connOpts, nats.UserCredentials(opts.Creds))
+	}
+	nc, err := nats.Connect(opts.URL, connOpts...)
+	if err != nil {
+		return nil, err
+	}
+	n := &NATS{nc: nc, opts: opts, enc: enc, run: time.Now().UTC().Format(time.RFC3339), logger: logger}
+	if opts.Stream == "" {
+		return n, nil
+	}
+
+	if n.js, err = jetstream.New(nc); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	// An existing stream is left as its operator configured it.
+	if _, err = n.js.Stream(ctx, opts.Stream); errors.Is(err, jetstream.ErrStreamNotFound) {
+		_, err = n.js.CreateStream(ctx, jetstream.StreamConfig{Name: opts.Stream, Subjects: []string{opts.Subject}})
+	}
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NATS) Write(o processor.Output) error {
+	msg, err := natsMsg(n.opts, n.enc, n.run, o, time.Now())
+	if err != nil {
+		return err
+	}
+	if n.js != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		_, err = n.js.PublishMsg(ctx, msg)
+		cancel()
+	} else {
+		err = n.nc.PublishMsg(msg)
+	}
+	if err != nil {
+		n.dropped++
+		n.logger.Error("processing nats sink", "domain", o.Domain, "error", err)
+		return nil
+	}
+	n.sent++
+	return nil
+}
+
+func (n *NATS) Close() error {
+	err := n.nc.FlushTimeout(30 * time.Second)
+	n.logger.Info("processing nats sink", "published", n.sent, "dropped", n.dropped)
+	n.nc.Close()
+	return err
+}
+
+func natsMsg(opts NATSOptions, enc Encoder, run string, o processor.Output, now time.Time) (*nats.Msg, error) {
+	data, err := enc.Encode(opts.Base, o, now)
+	if err != nil {
+		return nil, err
+	}
+	msg := nats.NewMsg(opts.Subject)
+	msg.Data = data
+	msg.Header.Set("base", opts.Base)
+	msg.Header.Set("domain", o.Domain)
+	msg.Header.Set("content-type", enc.ContentType())
+	msg.Header.Set(jetstream.MsgIDHeader, o.Domain+"@"+run)
+	return msg, nil
+}
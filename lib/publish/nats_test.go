@@ -0,0 +1,25 @@
+package publish
+
+import (
+	"squatrr/lib/processor"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNATSMsg(t *testing.T) {
+	opts := NATSOptions{Subject: "squatrr.findings", Base: "example.com"}
+	msg, err := natsMsg(opts, JSON{}, "2024-05-01T12:00:00Z", processor.Output{Domain: "exampel.com"}, time.Now())
+	if err != nil {
+		t.Fatalf("natsMsg() error: %v", err)
+	}
+	if msg.Subject != "squatrr.findings" || msg.Header.Get("base") != "example.com" || msg.Header.Get("domain") != "exampel.com" {
+		t.Errorf("natsMsg() = %+v", msg)
+	}
+	if got := msg.Header.Get("Nats-Msg-Id"); got != "exampel.com@2024-05-01T12:00:00Z" {
+		t.Errorf("Nats-Msg-Id = %q", got)
+	}
+	if !strings.HasPrefix(string(msg.Data), `{"domain":"exampel.com",`) {
+		t.Errorf("natsMsg() data = %s", msg.Data)
+	}
+}
@@ -0,0 +1,29 @@
+package publish
+
+/*
+  This library streams findings to message brokers as they are verified, so
+  detection pipelines can consume scans as just another event source. Each
+  result becomes one message keyed by candidate domain, encoded as JSON or,
+  with a schema registry, as Avro.
+*/
+
+import (
+	"encoding/json"
+	"squatrr/lib/processor"
+	"time"
+)
+
+// Encoder turns a finding into a message value.
+type Encoder interface {
+	Encode(base string, o processor.Output, observed time.Time) ([]byte, error)
+	ContentType() string
+}
+
+// JSON encodes findings exactly as they appear in results.json.
+type JSON struct{}
+
+func (JSON) Encode(_ string, o processor.Output, _ time.Time) ([]byte, error) {
+	return json.Marshal(o)
+}
+
+func (JSON) ContentType() string { return "application/json" }
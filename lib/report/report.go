@@ -0,0 +1,230 @@
+package report
+
+/*
+  This library fills user-provided text/template files with per-domain
+  facts from scan results, to draft UDRP complaints and registrar abuse
+  reports in bulk, or with a whole run's results, for Markdown summaries,
+  wiki pages and other custom formats. See templates/ for starting points.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"squatrr/lib/processor"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Evidence is the newest evidence bundle found for a domain (see the
+// evidence mode).
+type Evidence struct {
+	Path   string
+	SHA256 string
+}
+
+// Facts is what a template sees as ".".
+type Facts struct {
+	Domain string
+	Base   string // the brand domain being imitated
+	Now    time.Time
+
+	Registrar       string
+	RegistrarID     string
+	RegistrarAbuse  string // abuse contact email
+	Registrant      string
+	Privacy         bool
+	PrivacyService  string
+	Created         time.Time
+	Expires         time.Time
+	Status          []string
+	IPs             []string
+	Nameservers     []string
+	MX              []string
+	HostingCountry  []string
+	CertIssuer      string
+	CertValid       bool
+	CertNotBefore   time.Time
+	Title           string
+	URL             string
+	Redirect        string
+	Score           int
+	ScoreTags       []string
+	Class           string
+	ClassTags       []string
+	EvidenceSummary []string // one human-readable line per notable finding
+	Evidence        *Evidence
+
+	Result processor.Output // everything else
+}
+
+// FactsFor gathers the template facts for one result.
+func FactsFor(o processor.Output, base string, ev *Evidence, now time.Time) Facts {
+	f := Facts{
+		Domain: o.Domain, Base: base, Now: now,
+		IPs: append(append([]string{}, o.DNS.A...), o.DNS.AAAA...), Nameservers: o.DNS.NS, MX: o.DNS.MX,
+		Score: o.Score, ScoreTags: o.ScoreTags, Class: o.Class, ClassTags: o.ClassTags,
+		Evidence: ev, Result: o,
+	}
+	if r := o.RDAP; r != nil {
+		f.Registrar, f.RegistrarID, f.RegistrarAbuse = r.Registrar, r.RegistrarID, r.AbuseEmail
+		f.Registrant, f.Privacy, f.PrivacyService = r.Registrant, r.Privacy, r.PrivacyService
+		f.Created, f.Expires, f.Status = r.Created, r.Expires, r.Status
+	}
+	if o.Geo != nil {
+		f.HostingCountry = o.Geo.Countries
+	}
+	if t := o.TLS; t != nil && t.Connected {
+		f.CertIssuer, f.CertValid, f.CertNotBefore = t.Issuer, t.CertValid, t.NotBefore
+	}
+	if h := o.HTTP; h != nil {
+		f.Title, f.URL, f.Redirect = h.Title, h.URL, h.Location
+	}
+	f.EvidenceSummary = summarize(o, base)
+	return f
+}
+
+// summarize turns the notable parts of a result into plain sentences.
+func summarize(o processor.Output, base string) []string {
+	var out []string
+	add := func(format string, args ...any) { out = append(out, fmt.Sprintf(format, args...)) }
+	if o.Resolvable {
+		add("%s resolves to %s.", o.Domain, strings.Join(append(append([]string{}, o.DNS.A...), o.DNS.AAAA...), ", "))
+	}
+	if o.HasMail {
+		add("%s publishes MX records (%s) and can send and receive email.", o.Domain, strings.Join(o.DNS.MX, ", "))
+	}
+	if r := o.RDAP; r != nil && !r.Created.IsZero() {
+		add("It was registered on %s through %s.", r.Created.Format("2 January 2006"), r.Registrar)
+	}
+	if t := o.TLS; t != nil && t.Connected {
+		add("It serves a TLS certificate issued by %s on %s.", t.Issuer, t.NotBefore.Format("2 January 2006"))
+	}
+	if h := o.HTTP; h != nil && h.Title != "" {
+		add("Its web page is titled %q.", h.Title)
+	}
+	if h := o.HTTP; h != nil && h.Location != "" {
+		add("It redirects to %s.", h.Location)
+	}
+	switch o.Class {
+	case "reverse_proxy":
+		add("It relays %s's own website through the domain, capturing visitors' logins and session cookies (%s).", base, strings.Join(o.ClassTags, ", "))
+	case "phishing":
+		add("The page imitates %s and collects credentials (%s).", base, strings.Join(o.ClassTags, ", "))
+	case "for_sale":
+		add("The domain is offered for sale.")
+	case "parked":
+		add("The domain is parked with advertising.")
+	}
+	for _, m := range o.Reputation {
+		add("Its address %s is listed on %s.", m.IP, m.Feed)
+	}
+	return out
+}
+
+var funcs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"date": func(layout string, t time.Time) string {
+		if t.IsZero() {
+			return "unknown"
+		}
+		return t.Format(layout)
+	},
+	"default": func(def string, v any) any {
+		if s, ok := v.(string); ok && s == "" {
+			return def
+		}
+		if v == nil {
+			return def
+		}
+		return v
+	},
+
+	// Result set helpers for run templates (see Run).
+	"sortBy":   sortBy,
+	"where":    where,
+	"minScore": minScore,
+	"first":    first,
+	"countBy":  countBy,
+	"avgScore": avgScore,
+}
+
+// ParseTemplate loads a template file with the report functions: join,
+// upper, lower, date "2006-01-02" .Created, default "n/a" .Registrant, and
+// for result sets sortBy "score", where "class" "phishing", minScore 40,
+// first 10, countBy "country" and avgScore.
+func ParseTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(funcs).Option("missingkey=error").ParseFiles(path)
+}
+
+// Render executes tmpl for f.
+func Render(tmpl *template.Template, f Facts) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, f); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ReadResults reads a results file: the scan's JSON array or JSON lines.
+func ReadResults(r io.Reader) ([]processor.Output, error) {
+	br := bufio.NewReader(r)
+	first, err := peekNonSpace(br)
+	if err != nil {
+		return nil, err
+	}
+	if first == '[' {
+		var out []processor.Output
+		err := json.NewDecoder(br).Decode(&out)
+		return out, err
+	}
+	var out []processor.Output
+	dec := json.NewDecoder(br)
+	for {
+		var o processor.Output
+		if err := dec.Decode(&o); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return nil, err
+		}
+		out = append(out, o)
+	}
+}
+
+func peekNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\n' && b != '\r' && b != '\t' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// FindEvidence returns the newest <domain>-<time>.zip bundle in dir, with
+// the hash from its .sha256 file, or nil when there is none.
+func FindEvidence(dir, domain string) *Evidence {
+	if dir == "" {
+		return nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, domain+"-*.zip"))
+	if len(matches) == 0 {
+		return nil
+	}
+	sort.Strings(matches) // UTC timestamps sort chronologically
+	ev := &Evidence{Path: matches[len(matches)-1]}
+	if data, err := os.ReadFile(ev.Path + ".sha256"); err == nil {
+		ev.SHA256, _, _ = strings.Cut(string(data), " ")
+	}
+	return ev
+}
@@ -0,0 +1,150 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+)
+
+func testOutput() processor.Output {
+	return processor.Output{
+		Domain: "examp1e.com", Resolvable: true, HasMail: true,
+		DNS:   verify.DNSResult{A: []string{"192.0.2.1"}, MX: []string{"mx.examp1e.com"}},
+		RDAP:  &verify.RDAPResult{Registrar: "Example Registrar, Inc.", RegistrarID: "9999", AbuseEmail: "abuse@registrar.test", Privacy: true, Created: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)},
+		HTTP:  &verify.HTTPResult{Title: "Sign in to Example"},
+		Score: 60, ScoreTags: []string{"has_mx"}, Class: "phishing", ClassTags: []string{"password_field"},
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	ev := &Evidence{Path: "evidence/examp1e.com-20261016T085000Z.zip", SHA256: "abc123"}
+	f := FactsFor(testOutput(), "example.com", ev, now)
+
+	tests := []struct {
+		template string
+		want     []string
+	}{
+		{"registrar-abuse.txt.tmpl", []string{"To: abuse@registrar.test", "registered through you on 1 September 2026 (IANA ID 9999)", "- Its web page is titled \"Sign in to Example\".", "(SHA-256 abc123)"}},
+		{"udrp-draft.md.tmpl", []string{"**Registrar:** Example Registrar, Inc. (IANA ID 9999)", "the registrant is concealed", "collects credentials", "mx.examp1e.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			tmpl, err := ParseTemplate(filepath.Join("..", "..", "templates", tt.template))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := Render(tmpl, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(out), w) {
+					t.Errorf("Render() missing %q in:\n%s", w, out)
+				}
+			}
+		})
+	}
+}
+
+func TestReadResults(t *testing.T) {
+	for _, in := range []string{
+		"[{\"domain\":\"a.com\"}\n,{\"domain\":\"b.com\"}\n]\n",
+		"{\"domain\":\"a.com\"}\n{\"domain\":\"b.com\"}\n",
+	} {
+		got, err := ReadResults(strings.NewReader(in))
+		if err != nil || len(got) != 2 || got[1].Domain != "b.com" {
+			t.Errorf("ReadResults(%q) = %v, %v", in, got, err)
+		}
+	}
+}
+
+func TestFindEvidence(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"examp1e.com-20261001T000000Z.zip", "examp1e.com-20261016T000000Z.zip", "other.com-20261020T000000Z.zip"} {
+		_ = os.WriteFile(filepath.Join(dir, name), nil, 0o644)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "examp1e.com-20261016T000000Z.zip.sha256"), []byte("ff00  examp1e.com-20261016T000000Z.zip\n"), 0o644)
+	ev := FindEvidence(dir, "examp1e.com")
+	if ev == nil || filepath.Base(ev.Path) != "examp1e.com-20261016T000000Z.zip" || ev.SHA256 != "ff00" {
+		t.Errorf("FindEvidence() = %+v", ev)
+	}
+	if FindEvidence(dir, "nothing.com") != nil {
+		t.Error("FindEvidence(nothing.com) != nil")
+	}
+}
+
+func TestRunWriter(t *testing.T) {
+	tmpl, err := ParseTemplate(filepath.Join("..", "..", "templates", "run-summary.md.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "summary.md")
+	stats := processor.Stats{Verified: 120}
+	w := NewRunWriter(path, tmpl, "example.com", []string{"com", "net"}, &stats)
+	for _, o := range []processor.Output{
+		{Domain: "exampel.net", Strategy: "transposition", Score: 10, Class: "parked"},
+		testOutput(),
+		{Domain: "examplle.com", Strategy: "repetition", Score: 45, Geo: &verify.GeoResult{Countries: []string{"RU"}}},
+	} {
+		if err := w.Write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	out, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Scanned com, net on",
+		"120 candidates verified, 3 live (average score 38)",
+		"| examp1e.com | 60 | phishing | Example Registrar, Inc. | Sign in to Example |\n| examplle.com | 45 | - | - | - |",
+		"## Phishing pages\n- examp1e.com",
+		"- unknown: 1",
+	} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("rendered summary missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(string(out), "| exampel.net |") {
+		t.Errorf("rendered summary lists a result below minScore:\n%s", out)
+	}
+}
+
+func TestCountBy(t *testing.T) {
+	results := []processor.Output{
+		{Domain: "a.com", Geo: &verify.GeoResult{Countries: []string{"US", "NL"}}},
+		{Domain: "b.net", Geo: &verify.GeoResult{Countries: []string{"NL"}}},
+		{Domain: "c.com"},
+	}
+	tests := []struct {
+		field string
+		want  []KeyCount
+	}{
+		{"country", []KeyCount{{"NL", 2}, {"US", 1}, {"unknown", 1}}},
+		{"tld", []KeyCount{{"com", 2}, {"net", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			got, err := countBy(tt.field, results)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("countBy(%q) = %v, want %v", tt.field, got, tt.want)
+			}
+		})
+	}
+	if _, err := countBy("colour", results); err == nil {
+		t.Error("countBy(unknown field) succeeded")
+	}
+}
@@ -0,0 +1,180 @@
+package report
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"squatrr/lib/processor"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Run is what a run template (a scan's -template) sees as ".".
+type Run struct {
+	Base    string
+	TLDs    []string
+	Now     time.Time
+	Stats   processor.Stats
+	Results []processor.Output // in arrival order (or -sort order)
+}
+
+// KeyCount is one group of a countBy aggregation.
+type KeyCount struct {
+	Key   string
+	Count int
+}
+
+// fieldValues returns the values of a result's groupable field; results
+// can carry several countries or tags.
+func fieldValues(o processor.Output, field string) ([]string, error) {
+	switch field {
+	case "class":
+		return []string{o.Class}, nil
+	case "strategy":
+		return []string{o.Strategy}, nil
+	case "tld":
+		return []string{o.Domain[strings.LastIndexByte(o.Domain, '.')+1:]}, nil
+	case "country":
+		if o.Geo != nil {
+			return o.Geo.Countries, nil
+		}
+		return nil, nil
+	case "registrar":
+		if o.RDAP != nil {
+			return []string{o.RDAP.Registrar}, nil
+		}
+		return nil, nil
+	case "issuer":
+		if o.TLS != nil && o.TLS.Connected {
+			return []string{o.TLS.Issuer}, nil
+		}
+		return nil, nil
+	case "tag":
+		return append(slices.Clone(o.ScoreTags), o.ClassTags...), nil
+	}
+	return nil, fmt.Errorf("unknown field %q; expected class, strategy, tld, country, registrar, issuer or tag", field)
+}
+
+// sortBy orders results by score or likelihood (highest first) or domain.
+func sortBy(key string, results []processor.Output) ([]processor.Output, error) {
+	var by func(a, b processor.Output) int
+	switch key {
+	case "score":
+		by = func(a, b processor.Output) int { return cmp.Compare(b.Score, a.Score) }
+	case "likelihood":
+		by = func(a, b processor.Output) int { return cmp.Compare(b.Likelihood, a.Likelihood) }
+	case "domain":
+		by = func(a, b processor.Output) int { return strings.Compare(a.Domain, b.Domain) }
+	default:
+		return nil, fmt.Errorf("unknown sort key %q; expected score, likelihood or domain", key)
+	}
+	out := slices.Clone(results)
+	slices.SortStableFunc(out, by)
+	return out, nil
+}
+
+// where keeps the results whose field has the given value.
+func where(field, value string, results []processor.Output) ([]processor.Output, error) {
+	var out []processor.Output
+	for _, o := range results {
+		values, err := fieldValues(o, field)
+		if err != nil {
+			return nil, err
+		}
+		if slices.Contains(values, value) {
+			out = append(out, o)
+		}
+	}
+	return out, nil
+}
+
+// countBy groups results by field, largest group first; empty values are
+// grouped under "unknown".
+func countBy(field string, results []processor.Output) ([]KeyCount, error) {
+	counts := map[string]int{}
+	for _, o := range results {
+		values, err := fieldValues(o, field)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) == 0 {
+			values = []string{""}
+		}
+		for _, v := range values {
+			if v == "" {
+				v = "unknown"
+			}
+			counts[v]++
+		}
+	}
+	out := make([]KeyCount, 0, len(counts))
+	for k, n := range counts {
+		out = append(out, KeyCount{Key: k, Count: n})
+	}
+	slices.SortFunc(out, func(a, b KeyCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), strings.Compare(a.Key, b.Key))
+	})
+	return out, nil
+}
+
+// minScore keeps the results scoring at least score.
+func minScore(score int, results []processor.Output) []processor.Output {
+	var out []processor.Output
+	for _, o := range results {
+		if o.Score >= score {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// first keeps at most n results.
+func first(n int, results []processor.Output) []processor.Output {
+	return results[:max(0, min(n, len(results)))]
+}
+
+func avgScore(results []processor.Output) float64 {
+	if len(results) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, o := range results {
+		sum += o.Score
+	}
+	return float64(sum) / float64(len(results))
+}
+
+// RunWriter is a sink rendering the whole result set through a run
+// template once the scan ends; results are buffered until then.
+type RunWriter struct {
+	path  string
+	tmpl  *template.Template
+	run   Run
+	stats *processor.Stats
+}
+
+// NewRunWriter renders tmpl into path when closed. stats must be filled in
+// by then.
+func NewRunWriter(path string, tmpl *template.Template, base string, tlds []string, stats *processor.Stats) *RunWriter {
+	return &RunWriter{path: path, tmpl: tmpl, run: Run{Base: base, TLDs: tlds}, stats: stats}
+}
+
+func (w *RunWriter) Write(o processor.Output) error {
+	w.run.Results = append(w.run.Results, o)
+	return nil
+}
+
+func (w *RunWriter) Close() error {
+	w.run.Now = time.Now()
+	if w.stats != nil {
+		w.run.Stats = *w.stats
+	}
+	var b bytes.Buffer
+	if err := w.tmpl.Execute(&b, w.run); err != nil {
+		return fmt.Errorf("rendering %s: %w", w.tmpl.Name(), err)
+	}
+	return os.WriteFile(w.path, b.Bytes(), 0o644)
+}
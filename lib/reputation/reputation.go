@@ -0,0 +1,190 @@
+package reputation
+
+/*
+  This library cross-references resolved IPs against published IP
+  reputation feeds (Spamhaus DROP/EDROP, abuse.ch Feodo Tracker and SSLBL,
+  or any plain IP/CIDR list). It implements verify.IPReputation.
+*/
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Feed is a named IP/CIDR list. URL may also be a local file path.
+type Feed struct {
+	Name string
+	URL  string
+}
+
+// DefaultFeeds are free feeds of hijacked netblocks and botnet C2 servers.
+var DefaultFeeds = []Feed{
+	{Name: "spamhaus_drop", URL: "https://www.spamhaus.org/drop/drop.txt"},
+	{Name: "spamhaus_edrop", URL: "https://www.spamhaus.org/drop/edrop.txt"},
+	{Name: "spamhaus_dropv6", URL: "https://www.spamhaus.org/drop/dropv6.txt"},
+	{Name: "feodo", URL: "https://feodotracker.abuse.ch/downloads/ipblocklist.txt"},
+	{Name: "sslbl", URL: "https://sslbl.abuse.ch/blacklist/sslipblacklist.txt"},
+}
+
+// DefaultMaxAge is how long a downloaded feed is reused before refetching.
+// The feed operators ask for no more than hourly polling.
+const DefaultMaxAge = 12 * time.Hour
+
+type entry struct {
+	prefix netip.Prefix
+	feed   string
+}
+
+// List is the union of loaded feeds.
+type List struct {
+	entries []entry
+}
+
+// ParseFeed reads one IP or CIDR per line, ignoring blank lines, "#"/";"
+// comments and anything after the first field (DROP's "; SBL123").
+func ParseFeed(name string, r io.Reader) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexAny(line, "#;"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(fields) == 0 {
+			continue
+		}
+		if p, err := netip.ParsePrefix(fields[0]); err == nil {
+			out = append(out, p.Masked())
+		} else if a, err := netip.ParseAddr(fields[0]); err == nil {
+			out = append(out, netip.PrefixFrom(a, a.BitLen()))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return out, nil
+}
+
+// Add merges a parsed feed into the list.
+func (l *List) Add(feed string, prefixes []netip.Prefix) {
+	for _, p := range prefixes {
+		l.entries = append(l.entries, entry{prefix: p, feed: feed})
+	}
+}
+
+func (l *List) Len() int {
+	return len(l.entries)
+}
+
+// Lookup returns the names of the feeds listing ip.
+func (l *List) Lookup(ip string) []string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return nil
+	}
+	addr = addr.Unmap()
+	var out []string
+	for _, e := range l.entries {
+		if e.prefix.Contains(addr) && (len(out) == 0 || out[len(out)-1] != e.feed) {
+			out = append(out, e.feed)
+		}
+	}
+	return out
+}
+
+// Load fetches every feed, reusing copies in dir younger than maxAge. A feed
+// that can't be fetched falls back to a stale copy, and is skipped with a
+// warning when there is none, so one dead feed doesn't stop a scan.
+func Load(ctx context.Context, feeds []Feed, dir string, maxAge time.Duration, logger *slog.Logger) (*List, error) {
+	l := &List{}
+	for _, f := range feeds {
+		data, err := fetchFeed(ctx, f, dir, maxAge)
+		if err != nil {
+			logger.Warn("processing reputation feed Load", "feed", f.Name, "error", err)
+			continue
+		}
+		prefixes, err := ParseFeed(f.Name, strings.NewReader(string(data)))
+		if err != nil {
+			return nil, err
+		}
+		l.Add(f.Name, prefixes)
+		logger.Debug("processing reputation feed Load", "feed", f.Name, "prefixes", len(prefixes))
+	}
+	return l, nil
+}
+
+func fetchFeed(ctx context.Context, f Feed, dir string, maxAge time.Duration) ([]byte, error) {
+	if !strings.Contains(f.URL, "://") {
+		return os.ReadFile(f.URL)
+	}
+	cached := filepath.Join(dir, f.Name+".txt")
+	if st, err := os.Stat(cached); err == nil && time.Since(st.ModTime()) < maxAge {
+		return os.ReadFile(cached)
+	}
+
+	data, err := download(ctx, f.URL)
+	if err != nil {
+		if stale, serr := os.ReadFile(cached); serr == nil {
+			return stale, nil
+		}
+		return nil, err
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err == nil {
+			_ = os.WriteFile(cached, data, 0o644)
+		}
+	}
+	return data, nil
+}
+
+func download(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+}
+
+// DefaultDir is where downloaded feeds are kept between runs.
+func DefaultDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "reputation"
+	}
+	return filepath.Join(dir, "sasquat", "reputation")
+}
+
+// ParseFeeds parses "name=url" pairs; a bare URL or path is named after its
+// file name.
+func ParseFeeds(specs []string) []Feed {
+	var out []Feed
+	for _, s := range specs {
+		name, url, ok := strings.Cut(s, "=")
+		if !ok {
+			url = s
+			name = strings.TrimSuffix(filepath.Base(s), filepath.Ext(s))
+		}
+		out = append(out, Feed{Name: name, URL: url})
+	}
+	return out
+}
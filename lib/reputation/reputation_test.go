@@ -0,0 +1,63 @@
+package reputation
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFeed(t *testing.T) {
+	in := `; Spamhaus DROP List
+1.10.16.0/20 ; SBL256894
+1.19.0.0/16 ; SBL434604
+
+# Feodo Tracker
+192.0.2.7
+2001:db8::/32 ; SBL1
+not-an-ip
+`
+	got, err := ParseFeed("test", strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s []string
+	for _, p := range got {
+		s = append(s, p.String())
+	}
+	want := []string{"1.10.16.0/20", "1.19.0.0/16", "192.0.2.7/32", "2001:db8::/32"}
+	if !reflect.DeepEqual(s, want) {
+		t.Errorf("ParseFeed() = %v, want %v", s, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	dir := t.TempDir()
+	drop := filepath.Join(dir, "drop.txt")
+	feodo := filepath.Join(dir, "feodo.txt")
+	_ = os.WriteFile(drop, []byte("198.51.100.0/24 ; SBL1\n"), 0o644)
+	_ = os.WriteFile(feodo, []byte("198.51.100.9\n"), 0o644)
+
+	l, err := Load(t.Context(), ParseFeeds([]string{"drop=" + drop, feodo, "missing=" + filepath.Join(dir, "nope.txt")}), dir, DefaultMaxAge, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		ip   string
+		want []string
+	}{
+		{"198.51.100.9", []string{"drop", "feodo"}},
+		{"198.51.100.10", []string{"drop"}},
+		{"::ffff:198.51.100.10", []string{"drop"}},
+		{"203.0.113.1", nil},
+		{"bogus", nil},
+	}
+	for _, tt := range tests {
+		if got := l.Lookup(tt.ip); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package rules
+
+/*
+  This library loads user-defined content rules and evaluates them against
+  probed HTTP responses, so brand-specific detections ("links our logo
+  file", "shows our support number") need no code changes. It implements
+  verify.ResponseRules.
+
+  A rules file is JSON:
+
+	{"rules": [
+	  {"name": "acme-logo", "severity": "high",
+	   "all": [{"keyword": "acme-logo.svg"}]},
+	  {"name": "acme-support-phone", "severity": "medium",
+	   "any": [{"regex": "1[-. ]?800[-. ]?555[-. ]?0199"}, {"keyword": "acme support"}]},
+	  {"name": "kit-panel", "severity": "critical",
+	   "all": [{"status": 200}, {"header": "X-Powered-By", "regex": "(?i)php"}, {"keyword": "/panel/login.php"}]}
+	]}
+
+  A rule matches when every "all" predicate matches and, if "any" is given,
+  at least one "any" predicate does. Predicates test the body unless
+  "header" names a response header; keyword matches are case-insensitive.
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"squatrr/lib/verify"
+	"strings"
+)
+
+// Severities, lowest first.
+var Severities = []string{"info", "low", "medium", "high", "critical"}
+
+// Predicate is one test against a response.
+type Predicate struct {
+	Header  string `json:"header,omitempty"`  // test this header instead of the body
+	Keyword string `json:"keyword,omitempty"` // case-insensitive substring
+	Regex   string `json:"regex,omitempty"`
+	Status  int    `json:"status,omitempty"` // exact status code
+
+	re *regexp.Regexp
+}
+
+// Rule is a named detection.
+type Rule struct {
+	Name     string      `json:"name"`
+	Severity string      `json:"severity"`
+	All      []Predicate `json:"all,omitempty"`
+	Any      []Predicate `json:"any,omitempty"`
+}
+
+// Set is a parsed rules file.
+type Set struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Parse decodes and validates a rules file, compiling its regexes.
+func Parse(data []byte) (*Set, error) {
+	var set Set
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if len(set.Rules) == 0 {
+		return nil, errors.New("no rules")
+	}
+	for i := range set.Rules {
+		r := &set.Rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d: missing name", i)
+		}
+		if r.Severity == "" {
+			r.Severity = "medium"
+		}
+		if !slices.Contains(Severities, r.Severity) {
+			return nil, fmt.Errorf("rule %s: unknown severity %q", r.Name, r.Severity)
+		}
+		if len(r.All) == 0 && len(r.Any) == 0 {
+			return nil, fmt.Errorf("rule %s: no predicates", r.Name)
+		}
+		for _, preds := range [][]Predicate{r.All, r.Any} {
+			for j := range preds {
+				if err := preds[j].compile(); err != nil {
+					return nil, fmt.Errorf("rule %s: %w", r.Name, err)
+				}
+			}
+		}
+	}
+	return &set, nil
+}
+
+// Load reads and parses the rules file at path.
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return set, nil
+}
+
+func (p *Predicate) compile() error {
+	if p.Keyword == "" && p.Regex == "" && p.Status == 0 && p.Header == "" {
+		return errors.New("empty predicate")
+	}
+	p.Keyword = strings.ToLower(p.Keyword)
+	if p.Regex != "" {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return err
+		}
+		p.re = re
+	}
+	return nil
+}
+
+// Match implements verify.ResponseRules.
+func (set *Set) Match(status int, header http.Header, body []byte) []verify.RuleMatch {
+	var out []verify.RuleMatch
+	var lower []byte
+	for _, r := range set.Rules {
+		matches := func(p Predicate) bool {
+			if lower == nil && p.Header == "" && p.Keyword != "" {
+				lower = bytes.ToLower(body)
+			}
+			return p.matches(status, header, body, lower)
+		}
+		if !all(r.All, matches) || (len(r.Any) > 0 && !slices.ContainsFunc(r.Any, matches)) {
+			continue
+		}
+		out = append(out, verify.RuleMatch{Rule: r.Name, Severity: r.Severity})
+	}
+	return out
+}
+
+func all(preds []Predicate, f func(Predicate) bool) bool {
+	for _, p := range preds {
+		if !f(p) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Predicate) matches(status int, header http.Header, body, lowerBody []byte) bool {
+	if p.Status != 0 && p.Status != status {
+		return false
+	}
+	if p.Header != "" {
+		values, ok := header[http.CanonicalHeaderKey(p.Header)]
+		if !ok {
+			return false
+		}
+		v := strings.Join(values, ", ")
+		if p.Keyword != "" && !strings.Contains(strings.ToLower(v), p.Keyword) {
+			return false
+		}
+		return p.re == nil || p.re.MatchString(v)
+	}
+	if p.Keyword == "" && p.re == nil {
+		return true // status-only
+	}
+	if body == nil {
+		return false
+	}
+	if p.Keyword != "" && !bytes.Contains(lowerBody, []byte(p.Keyword)) {
+		return false
+	}
+	return p.re == nil || p.re.Match(body)
+}
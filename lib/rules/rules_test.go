@@ -0,0 +1,67 @@
+package rules
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"squatrr/lib/verify"
+)
+
+const testRules = `{"rules": [
+  {"name": "acme-logo", "severity": "high", "all": [{"keyword": "ACME-logo.svg"}]},
+  {"name": "acme-phone", "any": [{"regex": "1[-. ]?800[-. ]?555[-. ]?0199"}, {"keyword": "acme support"}]},
+  {"name": "php-kit", "severity": "critical", "all": [{"status": 200}, {"header": "x-powered-by", "regex": "(?i)php"}, {"keyword": "/panel/login.php"}]},
+  {"name": "nginx", "severity": "info", "all": [{"header": "Server", "keyword": "nginx"}]}
+]}`
+
+func TestMatch(t *testing.T) {
+	set, err := Parse([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   []byte
+		want   []verify.RuleMatch
+	}{
+		{"logo and phone", 200, http.Header{}, []byte(`<img src="/img/acme-logo.svg"> Call 1-800-555-0199`),
+			[]verify.RuleMatch{{Rule: "acme-logo", Severity: "high"}, {Rule: "acme-phone", Severity: "medium"}}},
+		{"kit needs every predicate", 200, http.Header{"X-Powered-By": {"PHP/7.4"}}, []byte(`<form action="/panel/login.php">`),
+			[]verify.RuleMatch{{Rule: "php-kit", Severity: "critical"}}},
+		{"kit wrong status", 404, http.Header{"X-Powered-By": {"PHP/7.4"}}, []byte(`/panel/login.php`), nil},
+		{"header-only on HEAD", 301, http.Header{"Server": {"nginx/1.25"}}, nil,
+			[]verify.RuleMatch{{Rule: "nginx", Severity: "info"}}},
+		{"nothing", 200, http.Header{}, []byte(`hello`), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := set.Match(tt.status, tt.header, tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"no rules", `{"rules": []}`},
+		{"no name", `{"rules": [{"all": [{"keyword": "x"}]}]}`},
+		{"bad severity", `{"rules": [{"name": "a", "severity": "urgent", "all": [{"keyword": "x"}]}]}`},
+		{"no predicates", `{"rules": [{"name": "a"}]}`},
+		{"empty predicate", `{"rules": [{"name": "a", "all": [{}]}]}`},
+		{"bad regex", `{"rules": [{"name": "a", "any": [{"regex": "("}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Errorf("Parse() error = nil, want error")
+			}
+		})
+	}
+}
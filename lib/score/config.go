@@ -0,0 +1,127 @@
+package score
+
+import (
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maxWeight bounds configured weights, catching typos like 400 for 40.
+const maxWeight = 100
+
+// Config is the "scoring" section of the config file. Everything is
+// optional and overrides the defaults:
+//
+//	"scoring": {
+//	  "weights": {"has_mx": 12, "whois_privacy": 0},
+//	  "disable": ["tls_entropy", "no_tls"],
+//	  "max_issuer_entropy": 4,
+//	  "severity_weights": {"critical": 60},
+//	  "tld_risk": {"tk": 15, "live": 0, "co.uk": -2},
+//	  "parking_indicators": ["sedo", "bodis"],
+//	  "known_issuers": ["let's encrypt", "digicert"],
+//	  "sinkholes": ["127.0.53.53", "2001:db8::53"],
+//	  "high_score": 30
+//	}
+type Config struct {
+	Weights           map[string]int `json:"weights,omitempty"`
+	Disable           []string       `json:"disable,omitempty"`
+	MaxIssuerEntropy  *int           `json:"max_issuer_entropy,omitempty"`
+	SeverityWeights   map[string]int `json:"severity_weights,omitempty"`
+	TLDRisk           map[string]int `json:"tld_risk,omitempty"`           // merged over the default table; 0 removes a TLD
+	ParkingIndicators []string       `json:"parking_indicators,omitempty"` // replaces the default list
+	KnownIssuers      []string       `json:"known_issuers,omitempty"`      // replaces the default list
+	Sinkholes         []string       `json:"sinkholes,omitempty"`          // IPs scored as sinkhole_ip
+
+	// HighScore is the threshold for high-score hits in the per-strategy
+	// statistics (see processor.Options.HighScore).
+	HighScore int `json:"high_score,omitempty"`
+}
+
+// Rubric validates c and applies it to the default rubric.
+func (c Config) Rubric() (*Rubric, error) {
+	rb := DefaultRubric()
+	for _, name := range sortedKeys(c.Weights) {
+		w := c.Weights[name]
+		switch {
+		case name == "tls_entropy" || name == "rule" || name == "tld_risk":
+			return nil, fmt.Errorf("weights: %s is weighted by max_issuer_entropy/severity_weights/tld_risk", name)
+		case !slices.Contains(Heuristics, name):
+			return nil, fmt.Errorf("weights: unknown heuristic %q", name)
+		case w < -maxWeight || w > maxWeight:
+			return nil, fmt.Errorf("weights: %s = %d is outside ±%d", name, w, maxWeight)
+		}
+		rb.Weights[name] = w
+	}
+	for _, name := range c.Disable {
+		if !slices.Contains(Heuristics, name) {
+			return nil, fmt.Errorf("disable: unknown heuristic %q", name)
+		}
+		rb.Disabled[name] = true
+	}
+	if c.MaxIssuerEntropy != nil {
+		if *c.MaxIssuerEntropy < 0 || *c.MaxIssuerEntropy > maxWeight {
+			return nil, fmt.Errorf("max_issuer_entropy: %d is outside 0-%d", *c.MaxIssuerEntropy, maxWeight)
+		}
+		rb.MaxIssuerEntropy = *c.MaxIssuerEntropy
+	}
+	for _, sev := range sortedKeys(c.SeverityWeights) {
+		w := c.SeverityWeights[sev]
+		if _, ok := SeverityWeights[sev]; !ok {
+			return nil, fmt.Errorf("severity_weights: unknown severity %q", sev)
+		}
+		if w < -maxWeight || w > maxWeight {
+			return nil, fmt.Errorf("severity_weights: %s = %d is outside ±%d", sev, w, maxWeight)
+		}
+		rb.SeverityWeights[sev] = w
+	}
+	for _, suffix := range sortedKeys(c.TLDRisk) {
+		w := c.TLDRisk[suffix]
+		key := strings.ToLower(strings.TrimPrefix(suffix, "."))
+		if key == "" || strings.HasSuffix(key, ".") {
+			return nil, fmt.Errorf("tld_risk: invalid TLD %q", suffix)
+		}
+		if w < -maxWeight || w > maxWeight {
+			return nil, fmt.Errorf("tld_risk: %s = %d is outside ±%d", suffix, w, maxWeight)
+		}
+		if w == 0 {
+			delete(rb.TLDRisk, key)
+		} else {
+			rb.TLDRisk[key] = w
+		}
+	}
+	if c.ParkingIndicators != nil {
+		rb.ParkingIndicators = lowerAll(c.ParkingIndicators)
+	}
+	if c.KnownIssuers != nil {
+		rb.KnownIssuers = lowerAll(c.KnownIssuers)
+	}
+	for _, ip := range c.Sinkholes {
+		addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+		if err != nil {
+			return nil, fmt.Errorf("sinkholes: invalid IP %q", ip)
+		}
+		rb.Sinkholes = append(rb.Sinkholes, addr.Unmap().String())
+	}
+	if c.HighScore < 0 {
+		return nil, fmt.Errorf("high_score: %d is negative", c.HighScore)
+	}
+	return rb, nil
+}
+
+func lowerAll(list []string) []string {
+	out := make([]string, len(list))
+	for i, s := range list {
+		out[i] = strings.ToLower(s)
+	}
+	return out
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := slices.Collect(maps.Keys(m))
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,391 @@
+package score
+
+/*
+  This library grades verified candidates for triage. It is the Go port of the
+  results viewer's scoring rubric (site/js/utilities.js scoreRecord) so results
+  can be ordered and filtered before they reach the site. The score is
+  additive; higher means "review sooner".
+*/
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"path"
+	"slices"
+	"squatrr/lib/classify"
+	"squatrr/lib/verify"
+	"strings"
+)
+
+// Default rubric weights, matching the site's scoring rubric card.
+const (
+	// A resolved IP is a known sinkhole (Sinkholes): the name was seized
+	// or is being monitored.
+	WeightSinkholeIP = 25
+
+	WeightParkingIndicator = 15
+	WeightRedirectToBrand  = 12
+	WeightRedirect         = 10
+	WeightHTTP200          = 8
+	WeightHTTP405          = 4
+	WeightHTTP4xx          = 1
+	WeightMX               = 8
+	WeightUnfamiliarIssuer = 8
+	MaxIssuerEntropy       = 8
+	WeightNoTLS            = 2
+
+	// Registration data (-rdap).
+	WeightAbuseRegistrar  = 6
+	WeightBulkRegistrar   = 2
+	WeightWhoisPrivacy    = 3
+	WeightBrandProtection = -15 // registered through a corporate registrar: likely defensive
+
+	// Hosted in a -high-risk-countries jurisdiction, where takedowns stall.
+	WeightHighRiskJurisdiction = 6
+
+	// A resolved IP is on a reputation feed (hijacked space, botnet C2): put
+	// it at the top of the queue.
+	WeightBadReputation = 40
+
+	// The candidate sets a session cookie named like the brand's real
+	// application (-brand-cookies): a cloned or proxied login.
+	WeightBrandCookie = 25
+
+	// The candidate shows the brand's logo, copyright line or support
+	// number (-brand-assets): its identity, whatever the domain says.
+	WeightBrandAsset = 20
+
+	// The candidate answers under its own name with the base domain's
+	// response headers (-header-mirror): a reverse proxy of the real site,
+	// as evilginx-style phishing kits run.
+	WeightHeaderMirror = 30
+
+	// The candidate publishes service SRV records (autodiscover, SIP,
+	// XMPP), or well-known documents naming the brand's apps or
+	// identities: impersonation beyond the website (-services).
+	WeightServiceEndpoints = 15
+
+	// The candidate's mail server greets as one of the brand's mail hosts
+	// (-smtp): set up to pass as the brand's mail, as BEC needs.
+	WeightSMTPImpersonation = 20
+)
+
+// SeverityWeights score each matched user content rule (-rules).
+var SeverityWeights = map[string]int{"info": 0, "low": 5, "medium": 10, "high": 20, "critical": 40}
+
+// DefaultParkingIndicators are matched against NS/MX/CNAME/HTTP Location.
+var DefaultParkingIndicators = []string{
+	"domaincontrol.com", "secureserver.net", "godaddy", "afternic", "dan.com",
+	"sedo", "parkingcrew", "bodis", "namecheap", "namesilo", "googlehosted.com",
+	"cloudflare", "registrar", "whois", "for-sale", "for sale", "buy this domain",
+	"coming soon", "under construction", "this domain is for sale", "parked",
+}
+
+// DefaultKnownIssuers lower the score when the TLS issuer matches.
+var DefaultKnownIssuers = []string{
+	"let's encrypt", "digicert", "sectigo", "comodoca", "globalsign", "entrust",
+	"godaddy", "amazon", "cloudflare", "microsoft",
+}
+
+// DefaultTLDRisk scores a candidate's public suffix: free and cheap TLDs
+// that dominate phishing and abuse reports. A candidate on the base
+// domain's own TLD is never scored for it.
+var DefaultTLDRisk = map[string]int{
+	// Formerly free (Freenom): still heavily abused where they resolve.
+	"tk": 10, "ml": 10, "ga": 10, "cf": 10, "gq": 10,
+	// Cheap gTLDs topping abuse rankings.
+	"top": 8, "xyz": 6, "icu": 8, "cyou": 8, "buzz": 6, "sbs": 8, "cfd": 8,
+	"bond": 6, "rest": 6, "quest": 6, "click": 6, "link": 4, "online": 4,
+	"site": 4, "shop": 4, "live": 4, "support": 6, "zip": 6, "mov": 6,
+}
+
+// Result is a candidate's score and the rubric tags that produced it.
+type Result struct {
+	Score int
+	Tags  []string
+}
+
+// Heuristics names every heuristic a rubric can weight or disable; the
+// names match the tags they produce (without any ":detail" suffix).
+var Heuristics = []string{
+	"sinkhole_ip", "parking_indicator", "redirect_to_brand", "redirect", "http_200", "http_405", "http_4xx",
+	"has_mx", "tls_unfamiliar_issuer", "tls_entropy", "no_tls",
+	"registrar_abuse_friendly", "registrar_bulk", "registrar_brand_protection", "whois_privacy",
+	"rule", "ip_reputation", "high_risk_jurisdiction", "tld_risk", "brand_cookie",
+	"brand_asset", "header_mirror", "service_endpoints", "smtp_impersonation",
+}
+
+// Rubric is a set of scoring weights, lists and switches. The zero value
+// scores nothing; start from DefaultRubric.
+type Rubric struct {
+	Weights           map[string]int // points per heuristic; tls_entropy, rule and tld_risk are weighted below
+	MaxIssuerEntropy  int            // cap on tls_entropy points
+	SeverityWeights   map[string]int // points per matched -rules rule, by severity
+	TLDRisk           map[string]int // points per candidate TLD (or longer suffix, e.g. "co.uk")
+	ParkingIndicators []string
+	KnownIssuers      []string
+	Sinkholes         []string        // IPs tagged sinkhole_ip when resolved; none by default, as in the viewer
+	Disabled          map[string]bool // heuristics neither scored nor tagged
+}
+
+// DefaultRubric returns the built-in rubric, matching the site's rubric card.
+func DefaultRubric() *Rubric {
+	return &Rubric{
+		Weights: map[string]int{
+			"sinkhole_ip":                WeightSinkholeIP,
+			"parking_indicator":          WeightParkingIndicator,
+			"redirect_to_brand":          WeightRedirectToBrand,
+			"redirect":                   WeightRedirect,
+			"http_200":                   WeightHTTP200,
+			"http_405":                   WeightHTTP405,
+			"http_4xx":                   WeightHTTP4xx,
+			"has_mx":                     WeightMX,
+			"tls_unfamiliar_issuer":      WeightUnfamiliarIssuer,
+			"no_tls":                     WeightNoTLS,
+			"registrar_abuse_friendly":   WeightAbuseRegistrar,
+			"registrar_bulk":             WeightBulkRegistrar,
+			"registrar_brand_protection": WeightBrandProtection,
+			"whois_privacy":              WeightWhoisPrivacy,
+			"ip_reputation":              WeightBadReputation,
+			"high_risk_jurisdiction":     WeightHighRiskJurisdiction,
+			"brand_cookie":               WeightBrandCookie,
+			"brand_asset":                WeightBrandAsset,
+			"header_mirror":              WeightHeaderMirror,
+			"service_endpoints":          WeightServiceEndpoints,
+			"smtp_impersonation":         WeightSMTPImpersonation,
+		},
+		MaxIssuerEntropy:  MaxIssuerEntropy,
+		SeverityWeights:   maps.Clone(SeverityWeights),
+		TLDRisk:           maps.Clone(DefaultTLDRisk),
+		ParkingIndicators: DefaultParkingIndicators,
+		KnownIssuers:      DefaultKnownIssuers,
+		Disabled:          map[string]bool{},
+	}
+}
+
+// Default is the rubric Record uses.
+var Default = DefaultRubric()
+
+// Record scores a verification against the base domain it was generated
+// from with the default rubric.
+func Record(base string, v verify.Verification) Result {
+	return Default.Record(base, v)
+}
+
+// Record scores a verification against the base domain it was generated from.
+func (rb *Rubric) Record(base string, v verify.Verification) Result {
+	var r Result
+	addPoints := func(heuristic string, points int, tag string) {
+		if rb.Disabled[heuristic] {
+			return
+		}
+		r.Score += points
+		r.Tags = append(r.Tags, tag)
+	}
+	add := func(heuristic, tag string) { addPoints(heuristic, rb.Weights[heuristic], tag) }
+
+	site := v.Site() // the www host's probe for a www-only squat
+	var loc string
+	if site != nil {
+		loc = strings.ToLower(site.Location)
+	}
+
+	// sinkhole IPs
+	ips := append(append([]string{}, v.DNS.A...), v.DNS.AAAA...)
+	if slices.ContainsFunc(ips, func(ip string) bool { return slices.Contains(rb.Sinkholes, ip) }) {
+		add("sinkhole_ip", "sinkhole_ip")
+	}
+
+	// parking/registrar indicators
+	joined := strings.ToLower(strings.Join(v.DNS.NS, " ") + " " + strings.Join(v.DNS.MX, " ") + " " + v.DNS.CNAME + " " + loc)
+	for _, ind := range rb.ParkingIndicators {
+		if strings.Contains(joined, ind) {
+			add("parking_indicator", "parking_indicator:"+ind)
+			break
+		}
+	}
+
+	// redirect-to-brand
+	base = strings.ToLower(strings.TrimSuffix(base, "."))
+	if base != "" && loc != "" {
+		brand, _, _ := strings.Cut(base, ".")
+		if strings.Contains(loc, base) {
+			add("redirect_to_brand", "redirect_to_brand")
+		} else if brand != "" && strings.Contains(loc, brand) {
+			add("redirect_to_brand", "redirect_to_brand_token")
+		}
+	}
+
+	// HTTP behavior
+	if site != nil && site.Attempted {
+		switch sc := site.StatusCode; {
+		case sc == 301 || sc == 302 || sc == 303 || sc == 307 || sc == 308:
+			add("redirect", "redirect")
+		case sc == 200:
+			add("http_200", "http_200")
+		case sc == 405:
+			add("http_405", "http_405")
+		case sc >= 400 && sc < 500:
+			add("http_4xx", "http_4xx")
+		}
+	}
+
+	// email surface
+	if v.Resolvable && v.HasMail {
+		add("has_mx", "has_mx")
+	}
+
+	// TLS issuer heuristics
+	if v.TLS != nil && v.TLS.Connected {
+		issuer := strings.ToLower(v.TLS.Issuer)
+		known := false
+		for _, k := range rb.KnownIssuers {
+			if strings.Contains(issuer, k) {
+				known = true
+				break
+			}
+		}
+		if known {
+			r.Tags = append(r.Tags, "tls_known_issuer")
+		} else {
+			add("tls_unfamiliar_issuer", "tls_unfamiliar_issuer")
+		}
+		ent := entropy(v.TLS.Issuer)
+		addPoints("tls_entropy", min(rb.MaxIssuerEntropy, int(math.Round(ent))), fmt.Sprintf("tls_entropy:%.2f", ent))
+	} else if v.Resolvable {
+		// resolvable but no TLS: still might be parking/phish; minor bump
+		add("no_tls", "no_tls")
+	}
+
+	// registration data
+	if v.RDAP != nil {
+		switch v.RDAP.RegistrarClass {
+		case classify.RegistrarAbuseFriendly:
+			add("registrar_abuse_friendly", "registrar_abuse_friendly")
+		case classify.RegistrarBulk:
+			add("registrar_bulk", "registrar_bulk")
+		case classify.RegistrarBrandProtection:
+			add("registrar_brand_protection", "registrar_brand_protection")
+		}
+		if v.RDAP.Privacy {
+			add("whois_privacy", "whois_privacy")
+		}
+	}
+
+	// user content rules
+	if site != nil {
+		for _, m := range site.RuleMatches {
+			addPoints("rule", rb.SeverityWeights[m.Severity], "rule:"+m.Rule)
+		}
+	}
+
+	// brand application cookies
+	if site != nil && len(site.BrandCookies) > 0 {
+		add("brand_cookie", "brand_cookie:"+site.BrandCookies[0])
+	}
+
+	// brand assets on the page
+	if site != nil && len(site.BrandAssets) > 0 {
+		add("brand_asset", "brand_asset:"+site.BrandAssets[0])
+	}
+
+	// reverse proxy of the base domain
+	if site != nil && site.MirrorsBase {
+		add("header_mirror", "header_mirror")
+	}
+
+	// service endpoints
+	if tag := serviceEndpoints(base, v); tag != "" {
+		add("service_endpoints", "service_endpoints:"+tag)
+	}
+
+	// mail server posing as the brand's
+	if v.SMTP != nil && len(v.SMTP.Impersonates) > 0 {
+		add("smtp_impersonation", "smtp_impersonation:"+v.SMTP.Impersonates[0])
+	}
+
+	// IP reputation
+	if len(v.Reputation) > 0 {
+		add("ip_reputation", "ip_reputation:"+v.Reputation[0].Feed)
+	}
+
+	// hosting jurisdiction
+	if v.Geo != nil && len(v.Geo.HighRisk) > 0 {
+		add("high_risk_jurisdiction", "high_risk_jurisdiction:"+v.Geo.HighRisk[0])
+	}
+
+	// TLD risk
+	if suffix, points := rb.tldRisk(base, v.Domain); points != 0 {
+		addPoints("tld_risk", points, "tld_risk:"+suffix)
+	}
+
+	return r
+}
+
+// serviceEndpoints names the first service the candidate publishes: an SRV
+// service, or a well-known document referring to the brand other than
+// through the candidate's own name.
+func serviceEndpoints(base string, v verify.Verification) string {
+	if v.Services == nil {
+		return ""
+	}
+	if len(v.Services.SRV) > 0 {
+		return v.Services.SRV[0].Service
+	}
+	brand, _, _ := strings.Cut(base, ".")
+	own := strings.ToLower(strings.TrimSuffix(v.Domain, "."))
+	for _, doc := range v.Services.WellKnown {
+		for _, ref := range doc.Refs {
+			ref = strings.ToLower(ref)
+			if own != "" {
+				ref = strings.ReplaceAll(ref, own, "")
+			}
+			if brand != "" && strings.Contains(ref, brand) {
+				return path.Base(doc.Path)
+			}
+		}
+	}
+	return ""
+}
+
+// tldRisk finds the longest suffix of domain in the TLD risk table. The
+// base domain's own suffix is neutral: a candidate sharing it is no more
+// suspect for that.
+func (rb *Rubric) tldRisk(base, domain string) (string, int) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for i := strings.IndexByte(domain, '.'); i >= 0; {
+		suffix := domain[i+1:]
+		if points, ok := rb.TLDRisk[suffix]; ok {
+			if base == suffix || strings.HasSuffix(base, "."+suffix) {
+				return suffix, 0
+			}
+			return suffix, points
+		}
+		next := strings.IndexByte(suffix, '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", 0
+}
+
+// entropy is the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	freq := map[rune]int{}
+	n := 0
+	for _, ch := range s {
+		freq[ch]++
+		n++
+	}
+	var ent float64
+	for _, c := range freq {
+		p := float64(c) / float64(n)
+		ent -= p * math.Log2(p)
+	}
+	return ent
+}
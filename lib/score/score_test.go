@@ -0,0 +1,216 @@
+package score
+
+import (
+	"reflect"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name      string
+		v         verify.Verification
+		wantScore int
+		wantTags  []string
+	}{
+		{
+			name:      "Resolvable without TLS or HTTP",
+			v:         verify.Verification{Resolvable: true},
+			wantScore: WeightNoTLS,
+			wantTags:  []string{"no_tls"},
+		},
+		{
+			name:      "Privacy-protected at an abuse-friendly registrar",
+			v:         verify.Verification{RDAP: &verify.RDAPResult{Attempted: true, RegistrarClass: "abuse_friendly", Privacy: true}},
+			wantScore: WeightAbuseRegistrar + WeightWhoisPrivacy,
+			wantTags:  []string{"registrar_abuse_friendly", "whois_privacy"},
+		},
+		{
+			name:      "Hosted in a high-risk jurisdiction",
+			v:         verify.Verification{Geo: &verify.GeoResult{Countries: []string{"NL", "IR"}, HighRisk: []string{"IR"}}},
+			wantScore: WeightHighRiskJurisdiction,
+			wantTags:  []string{"high_risk_jurisdiction:IR"},
+		},
+		{
+			name:      "User content rules",
+			v:         verify.Verification{HTTP: &verify.HTTPResult{RuleMatches: []verify.RuleMatch{{Rule: "acme-logo", Severity: "high"}, {Rule: "nginx", Severity: "info"}}}},
+			wantScore: SeverityWeights["high"],
+			wantTags:  []string{"rule:acme-logo", "rule:nginx"},
+		},
+		{
+			name:      "Resolves into botnet C2 space",
+			v:         verify.Verification{Reputation: []verify.ReputationMatch{{IP: "192.0.2.7", Feed: "feodo"}, {IP: "192.0.2.7", Feed: "spamhaus_drop"}}},
+			wantScore: WeightBadReputation,
+			wantTags:  []string{"ip_reputation:feodo"},
+		},
+		{
+			name:      "Sets the brand's session cookie",
+			v:         verify.Verification{HTTP: &verify.HTTPResult{BrandCookies: []string{"ACMESESSID"}}},
+			wantScore: WeightBrandCookie,
+			wantTags:  []string{"brand_cookie:ACMESESSID"},
+		},
+		{
+			name:      "Shows the brand's logo and support number",
+			v:         verify.Verification{HTTP: &verify.HTTPResult{BrandAssets: []string{"logo:acme", "phone:+1 800 555 0199"}}},
+			wantScore: WeightBrandAsset,
+			wantTags:  []string{"brand_asset:logo:acme"},
+		},
+		{
+			name:      "Mirrors the base domain's headers",
+			v:         verify.Verification{HTTP: &verify.HTTPResult{MirrorsBase: true}},
+			wantScore: WeightHeaderMirror,
+			wantTags:  []string{"header_mirror"},
+		},
+		{
+			name:      "Mail server greets as the brand's",
+			v:         verify.Verification{SMTP: &verify.SMTPResult{Impersonates: []string{"mail.example.com"}}},
+			wantScore: WeightSMTPImpersonation,
+			wantTags:  []string{"smtp_impersonation:mail.example.com"},
+		},
+		{
+			name:      "Publishes mail autodiscovery",
+			v:         verify.Verification{Services: &verify.ServicesResult{SRV: []verify.ServiceRecord{{Service: "_autodiscover._tcp", Target: "mail.exampel.com", Port: 443}}}},
+			wantScore: WeightServiceEndpoints,
+			wantTags:  []string{"service_endpoints:_autodiscover._tcp"},
+		},
+		{
+			name: "Claims the brand's app",
+			v: verify.Verification{Domain: "examplee.com", Services: &verify.ServicesResult{WellKnown: []verify.WellKnownDoc{
+				{Path: "/.well-known/security.txt", Refs: []string{"mailto:security@examplee.com"}},
+				{Path: "/.well-known/apple-app-site-association", Refs: []string{"ABCDE12345.com.example.app"}},
+			}}},
+			wantScore: WeightServiceEndpoints,
+			wantTags:  []string{"service_endpoints:apple-app-site-association"},
+		},
+		{
+			name:      "Free TLD",
+			v:         verify.Verification{Domain: "examp1e.tk", Resolvable: true},
+			wantScore: WeightNoTLS + DefaultTLDRisk["tk"],
+			wantTags:  []string{"no_tls", "tld_risk:tk"},
+		},
+		{
+			name:      "Unlisted TLD",
+			v:         verify.Verification{Domain: "exampel.com"},
+			wantScore: 0,
+		},
+		{
+			name:      "Defensive registration",
+			v:         verify.Verification{Resolvable: true, RDAP: &verify.RDAPResult{Attempted: true, RegistrarClass: "brand_protection"}},
+			wantScore: WeightNoTLS + WeightBrandProtection,
+			wantTags:  []string{"no_tls", "registrar_brand_protection"},
+		},
+		{
+			name: "Parked domain with MX redirecting to brand",
+			v: verify.Verification{
+				Resolvable: true,
+				HasMail:    true,
+				DNS:        verify.DNSResult{NS: []string{"ns1.sedoparking.com"}, MX: []string{"mx.exampel.com"}},
+				HTTP:       &verify.HTTPResult{Attempted: true, StatusCode: 302, Location: "https://www.example.com/login"},
+			},
+			wantScore: WeightParkingIndicator + WeightRedirectToBrand + WeightRedirect + WeightMX + WeightNoTLS,
+			wantTags:  []string{"parking_indicator:sedo", "redirect_to_brand", "redirect", "has_mx", "no_tls"},
+		},
+		{
+			name: "Unfamiliar low-entropy issuer",
+			v: verify.Verification{
+				Resolvable: true,
+				TLS:        &verify.TLSResult{Connected: true, Issuer: "aaaa"},
+			},
+			wantScore: WeightUnfamiliarIssuer,
+			wantTags:  []string{"tls_unfamiliar_issuer", "tls_entropy:0.00"},
+		},
+		{
+			name: "www-only squat scored on its www page",
+			v: verify.Verification{
+				Resolvable: true,
+				WWW:        &verify.WWW{Resolvable: true, Only: true, HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200}},
+			},
+			wantScore: WeightHTTP200 + WeightNoTLS,
+			wantTags:  []string{"http_200", "no_tls"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Record("example.com", tt.v)
+			if got.Score != tt.wantScore || !reflect.DeepEqual(got.Tags, tt.wantTags) {
+				t.Errorf("Record() = %d %v, want %d %v", got.Score, got.Tags, tt.wantScore, tt.wantTags)
+			}
+		})
+	}
+}
+
+func TestConfigRubric(t *testing.T) {
+	entropy := 0
+	rb, err := Config{
+		Weights:          map[string]int{"has_mx": 20},
+		Disable:          []string{"no_tls"},
+		MaxIssuerEntropy: &entropy,
+		KnownIssuers:     []string{"Internal CA"},
+	}.Rubric()
+	if err != nil {
+		t.Fatalf("Rubric() error: %v", err)
+	}
+	got := rb.Record("example.com", verify.Verification{Resolvable: true, HasMail: true})
+	if got.Score != 20 || !reflect.DeepEqual(got.Tags, []string{"has_mx"}) {
+		t.Errorf("Record() = %d %v, want 20 [has_mx]", got.Score, got.Tags)
+	}
+	got = rb.Record("example.com", verify.Verification{Resolvable: true, TLS: &verify.TLSResult{Connected: true, Issuer: "Internal CA G2"}})
+	if got.Score != 0 || !reflect.DeepEqual(got.Tags, []string{"tls_known_issuer", "tls_entropy:3.52"}) {
+		t.Errorf("Record() = %d %v, want 0 [tls_known_issuer tls_entropy:3.52]", got.Score, got.Tags)
+	}
+	if def := Record("example.com", verify.Verification{Resolvable: true, HasMail: true}); def.Score != WeightMX+WeightNoTLS {
+		t.Errorf("configuring a rubric changed the default: %d", def.Score)
+	}
+
+	rb, err = Config{TLDRisk: map[string]int{".TK": 20, "xyz": 0, "co.uk": 5}}.Rubric()
+	if err != nil {
+		t.Fatalf("Rubric() error: %v", err)
+	}
+	tldTests := []struct {
+		base, domain string
+		want         int
+	}{
+		{"example.com", "examp1e.tk", 20},
+		{"example.com", "exampel.xyz", 0},
+		{"example.com", "example.co.uk", 5},
+		{"example.com", "example.uk", 0},
+		{"example.tk", "exampel.tk", 0}, // the brand's own TLD is neutral
+		{"example.co.uk", "exampel.co.uk", 0},
+	}
+	for _, tt := range tldTests {
+		if got := rb.Record(tt.base, verify.Verification{Domain: tt.domain}); got.Score != tt.want {
+			t.Errorf("Record(%s, %s) = %d %v, want %d", tt.base, tt.domain, got.Score, got.Tags, tt.want)
+		}
+	}
+
+	rb, err = Config{Sinkholes: []string{"127.0.53.53", "2001:DB8::53"}}.Rubric()
+	if err != nil {
+		t.Fatalf("Rubric() error: %v", err)
+	}
+	sunk := verify.Verification{DNS: verify.DNSResult{A: []string{"192.0.2.1"}, AAAA: []string{"2001:db8::53"}}}
+	if got := rb.Record("example.com", sunk); got.Score != WeightSinkholeIP || !reflect.DeepEqual(got.Tags, []string{"sinkhole_ip"}) {
+		t.Errorf("Record() = %d %v, want %d [sinkhole_ip]", got.Score, got.Tags, WeightSinkholeIP)
+	}
+	if got := Record("example.com", sunk); got.Score != 0 {
+		t.Errorf("default rubric scored a sinkhole: %d %v", got.Score, got.Tags)
+	}
+
+	invalid := []Config{
+		{Weights: map[string]int{"has_mxx": 1}},
+		{Weights: map[string]int{"has_mx": 400}},
+		{Weights: map[string]int{"rule": 5}},
+		{Weights: map[string]int{"tld_risk": 5}},
+		{TLDRisk: map[string]int{"tk": 400}},
+		{TLDRisk: map[string]int{".": 5}},
+		{Disable: []string{"everything"}},
+		{SeverityWeights: map[string]int{"severe": 5}},
+		{Sinkholes: []string{"sinkhole.example"}},
+		{HighScore: -1},
+	}
+	for _, c := range invalid {
+		if _, err := c.Rubric(); err == nil {
+			t.Errorf("Rubric(%+v) succeeded, want a validation error", c)
+		}
+	}
+}
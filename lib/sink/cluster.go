@@ -0,0 +1,51 @@
+package sink
+
+import (
+	"encoding/json"
+	"log/slog"
+	"squatrr/lib/cluster"
+	"squatrr/lib/processor"
+	"strings"
+)
+
+// Clusters collects tracking IDs per candidate and, on Close, groups candidates
+// sharing IDs. Clusters are always logged and written to path when it is set.
+type Clusters struct {
+	path   string
+	logger *slog.Logger
+	ids    map[string][]string
+}
+
+func NewClusters(path string, logger *slog.Logger) *Clusters {
+	return &Clusters{path: path, logger: logger, ids: map[string][]string{}}
+}
+
+func (c *Clusters) Write(o processor.Output) error {
+	if o.HTTP != nil && len(o.HTTP.TrackingIDs) > 0 {
+		c.ids[o.Domain] = o.HTTP.TrackingIDs
+	}
+	return nil
+}
+
+func (c *Clusters) Close() error {
+	groups := cluster.Group(c.ids)
+	for _, g := range groups {
+		c.logger.Info("processing clusters sink", "cluster", g.ID, "domains", len(g.Domains), "shared", strings.Join(g.Shared, ","))
+	}
+	if c.path == "" {
+		return nil
+	}
+
+	f, err := createBuffered(c.path)
+	if err != nil {
+		return err
+	}
+	if groups == nil {
+		groups = []cluster.Cluster{}
+	}
+	if err := json.NewEncoder(f).Encode(groups); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
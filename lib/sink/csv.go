@@ -0,0 +1,96 @@
+package sink
+
+import (
+	"encoding/csv"
+	"io"
+	"squatrr/lib/processor"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVHeader names the columns CSV writes, one row per result.
+var CSVHeader = []string{
+	"domain", "score", "class", "strategy", "confusable", "resolvable", "has_mail",
+	"a", "aaaa", "cname", "ns", "mx", "countries",
+	"tls_issuer", "tls_validity", "tls_days_left", "http_status", "http_location", "http_title",
+	"registrar", "created", "expires", "score_tags",
+}
+
+// CSV flattens results into a spreadsheet-friendly table for hand-off.
+// Multi-valued fields are joined with spaces. Text a spreadsheet would run
+// as a formula, such as a page title starting with "=", is prefixed with
+// an apostrophe, as the candidates' owners control it.
+type CSV struct {
+	w     io.WriteCloser
+	cw    *csv.Writer
+	count int
+}
+
+// NewCSVWriter streams results into w, closing it on Close.
+func NewCSVWriter(w io.WriteCloser) *CSV {
+	return &CSV{w: w, cw: csv.NewWriter(w)}
+}
+
+func (c *CSV) Write(o processor.Output) error {
+	if c.count == 0 {
+		if err := c.cw.Write(CSVHeader); err != nil {
+			return err
+		}
+	}
+	c.count++
+	return c.cw.Write(csvRecord(o))
+}
+
+func (c *CSV) Close() error {
+	if c.count == 0 {
+		_ = c.cw.Write(CSVHeader)
+	}
+	c.cw.Flush()
+	if err := c.cw.Error(); err != nil {
+		c.w.Close()
+		return err
+	}
+	return c.w.Close()
+}
+
+func csvRecord(o processor.Output) []string {
+	join := func(s []string) string { return strings.Join(s, " ") }
+	date := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.DateOnly)
+	}
+	var countries, tlsIssuer, validity, daysLeft, status, location, title, registrar, created, expires string
+	if o.Geo != nil {
+		countries = join(o.Geo.Countries)
+	}
+	if o.TLS != nil {
+		tlsIssuer, validity = o.TLS.Issuer, o.TLS.Validity
+		if validity != "" {
+			daysLeft = strconv.Itoa(o.TLS.DaysUntilExpiry)
+		}
+	}
+	if o.HTTP != nil && o.HTTP.Attempted {
+		status, location, title = strconv.Itoa(o.HTTP.StatusCode), o.HTTP.Location, o.HTTP.Title
+	}
+	if o.RDAP != nil {
+		registrar, created, expires = o.RDAP.Registrar, date(o.RDAP.Created), date(o.RDAP.Expires)
+	}
+	return []string{
+		text(o.Domain), strconv.Itoa(o.Score), text(o.Class), text(o.Strategy), strconv.FormatBool(o.Confusable), strconv.FormatBool(o.Resolvable), strconv.FormatBool(o.HasMail),
+		text(join(o.DNS.A)), text(join(o.DNS.AAAA)), text(o.DNS.CNAME), text(join(o.DNS.NS)), text(join(o.DNS.MX)), text(countries),
+		text(tlsIssuer), text(validity), daysLeft, status, text(location), text(title),
+		text(registrar), created, expires, text(join(o.ScoreTags)),
+	}
+}
+
+// text neutralizes a cell a spreadsheet would evaluate as a formula.
+// Numeric columns are left alone, so negative numbers stay numbers.
+func text(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package sink
+
+import (
+	"bytes"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs []processor.Output
+		want    string
+	}{
+		{"Empty result set", nil, "domain,score,class,strategy,confusable,resolvable,has_mail,a,aaaa,cname,ns,mx,countries,tls_issuer,tls_validity,tls_days_left,http_status,http_location,http_title,registrar,created,expires,score_tags\n"},
+		{"Flattens multi-valued fields", []processor.Output{{
+			Domain: "exampel.com", Score: 42, Class: "parked", Resolvable: true,
+			DNS:       verify.DNSResult{A: []string{"192.0.2.1", "192.0.2.2"}},
+			TLS:       &verify.TLSResult{Issuer: "R3", Validity: verify.CertExpired, DaysUntilExpiry: -3},
+			HTTP:      &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "Buy, this domain"},
+			ScoreTags: []string{"http_200", "has_mx"},
+		}}, "domain,score,class,strategy,confusable,resolvable,has_mail,a,aaaa,cname,ns,mx,countries,tls_issuer,tls_validity,tls_days_left,http_status,http_location,http_title,registrar,created,expires,score_tags\n" +
+			"exampel.com,42,parked,,false,true,false,192.0.2.1 192.0.2.2,,,,,,R3,expired,-3,200,,\"Buy, this domain\",,,,http_200 has_mx\n"},
+		{"Neutralizes formulas", []processor.Output{{
+			Domain: "exampel.com", Score: -15,
+			HTTP: &verify.HTTPResult{Attempted: true, StatusCode: 200, Title: "=HYPERLINK(\"https://evil.test\")", Location: "@SUM(1)"},
+			RDAP: &verify.RDAPResult{Registrar: "+Registrar"},
+		}}, "domain,score,class,strategy,confusable,resolvable,has_mail,a,aaaa,cname,ns,mx,countries,tls_issuer,tls_validity,tls_days_left,http_status,http_location,http_title,registrar,created,expires,score_tags\n" +
+			"exampel.com,-15,,,false,false,false,,,,,,,,,,200,'@SUM(1),\"'=HYPERLINK(\"\"https://evil.test\"\")\",'+Registrar,,,\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := nopCloser{&bytes.Buffer{}}
+			c := NewCSVWriter(buf)
+			for _, o := range tt.outputs {
+				if err := c.Write(o); err != nil {
+					t.Fatalf("Write() error: %v", err)
+				}
+			}
+			if err := c.Close(); err != nil {
+				t.Fatalf("Close() error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package sink
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"squatrr/lib/classify"
+	"squatrr/lib/processor"
+	"strings"
+	"time"
+)
+
+// DefaultExpiryWindow is how far ahead Expiring looks for registrations
+// running out.
+const DefaultExpiryWindow = 30 * 24 * time.Hour
+
+// ExpiringEntry is one row of the expiring-soon view.
+type ExpiringEntry struct {
+	Domain    string            `json:"domain"`
+	Class     string            `json:"class"`
+	Score     int               `json:"score"`
+	Registrar string            `json:"registrar,omitempty"`
+	Expires   time.Time         `json:"expires"`
+	DaysLeft  int               `json:"days_left"`
+	Dropping  bool              `json:"dropping"` // already in redemption / pending delete
+	Status    []string          `json:"status,omitempty"`
+	Listing   *classify.Listing `json:"listing,omitempty"`
+}
+
+// Expiring is the drop-catch watch: it collects hostile or parked candidates
+// whose registration (from -rdap) expires within the window, or that are
+// already being deleted, logs an alert for each, and on Close writes them to
+// path soonest first. Candidates that redirect to the brand, are unrelated
+// businesses, or sit at a brand-protection registrar are skipped.
+type Expiring struct {
+	path    string
+	window  time.Duration
+	now     time.Time
+	logger  *slog.Logger
+	entries []ExpiringEntry
+}
+
+func NewExpiring(path string, window time.Duration, logger *slog.Logger) *Expiring {
+	if window <= 0 {
+		window = DefaultExpiryWindow
+	}
+	return &Expiring{path: path, window: window, now: time.Now(), logger: logger}
+}
+
+// watchClasses are the landing-page classes worth acquiring before they
+// are re-registered by someone else.
+var watchClasses = []string{classify.LabelReverseProxy, classify.LabelPhishing, classify.LabelParked, classify.LabelForSale, classify.LabelDormant}
+
+func (e *Expiring) Write(o processor.Output) error {
+	if o.RDAP == nil || o.RDAP.Expires.IsZero() || o.RDAP.RegistrarClass == classify.RegistrarBrandProtection {
+		return nil
+	}
+	if !slices.Contains(watchClasses, o.Class) {
+		return nil
+	}
+	left := o.RDAP.Expires.Sub(e.now)
+	dropping := isDropping(o.RDAP.Status)
+	if left > e.window && !dropping {
+		return nil
+	}
+
+	entry := ExpiringEntry{
+		Domain:    o.Domain,
+		Class:     o.Class,
+		Score:     o.Score,
+		Registrar: o.RDAP.Registrar,
+		Expires:   o.RDAP.Expires,
+		DaysLeft:  int(left.Hours() / 24),
+		Dropping:  dropping,
+		Status:    o.RDAP.Status,
+		Listing:   o.Listing,
+	}
+	e.entries = append(e.entries, entry)
+	e.logger.Warn("processing expiring candidate sink", "domain", entry.Domain, "class", entry.Class,
+		"expires", entry.Expires.Format(time.DateOnly), "days_left", entry.DaysLeft, "dropping", entry.Dropping)
+	return nil
+}
+
+func (e *Expiring) Close() error {
+	if e.path == "" {
+		return nil
+	}
+	slices.SortFunc(e.entries, func(a, b ExpiringEntry) int {
+		if c := a.Expires.Compare(b.Expires); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+
+	f, err := createBuffered(e.path)
+	if err != nil {
+		return err
+	}
+	entries := e.entries
+	if entries == nil {
+		entries = []ExpiringEntry{}
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(entries); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// isDropping reports whether RDAP status shows the registration lapsing:
+// the redemption grace period or pending delete.
+func isDropping(status []string) bool {
+	for _, s := range status {
+		switch strings.ToLower(strings.ReplaceAll(s, " ", "")) {
+		case "redemptionperiod", "pendingdelete":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+	"time"
+)
+
+func TestExpiring(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expiring.json")
+	e := NewExpiring(path, 30*24*time.Hour, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	now := e.now
+
+	in := []processor.Output{
+		{Domain: "parked-soon.com", Class: "parked", RDAP: &verify.RDAPResult{Expires: now.Add(10 * 24 * time.Hour)}},
+		{Domain: "phish-sooner.com", Class: "phishing", RDAP: &verify.RDAPResult{Expires: now.Add(3 * 24 * time.Hour)}},
+		{Domain: "parked-later.com", Class: "parked", RDAP: &verify.RDAPResult{Expires: now.Add(300 * 24 * time.Hour)}},
+		{Domain: "dropping.com", Class: "dormant", RDAP: &verify.RDAPResult{Expires: now.Add(300 * 24 * time.Hour), Status: []string{"redemption period"}}},
+		{Domain: "ours.com", Class: "brand_redirect", RDAP: &verify.RDAPResult{Expires: now.Add(24 * time.Hour)}},
+		{Domain: "defensive.com", Class: "parked", RDAP: &verify.RDAPResult{Expires: now.Add(24 * time.Hour), RegistrarClass: "brand_protection"}},
+		{Domain: "no-rdap.com", Class: "parked"},
+	}
+	for _, o := range in {
+		if err := e.Write(o); err != nil {
+			t.Fatalf("Write() error: %v", err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []ExpiringEntry
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"phish-sooner.com", "parked-soon.com", "dropping.com"}
+	if len(got) != len(want) {
+		t.Fatalf("Expiring wrote %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i, d := range want {
+		if got[i].Domain != d {
+			t.Errorf("entry %d = %s, want %s", i, got[i].Domain, d)
+		}
+	}
+	if got[0].DaysLeft != 3 || got[0].Dropping || !got[2].Dropping {
+		t.Errorf("Expiring entries = %+v", got)
+	}
+}
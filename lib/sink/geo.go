@@ -0,0 +1,81 @@
+package sink
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"squatrr/lib/processor"
+)
+
+// CountryCount is how many live candidates are hosted in one country.
+type CountryCount struct {
+	Country  string `json:"country"`
+	Live     int    `json:"live"`
+	HighRisk bool   `json:"high_risk,omitempty"`
+}
+
+// Geo rolls live candidates up by hosting country (from -geoip or -asn) for
+// map views. A candidate hosted in several countries counts once in each;
+// candidates without a known country are only counted as unlocated.
+type Geo struct {
+	path      string
+	logger    *slog.Logger
+	byCountry map[string]*CountryCount
+	unlocated int
+}
+
+func NewGeo(path string, logger *slog.Logger) *Geo {
+	return &Geo{path: path, logger: logger, byCountry: map[string]*CountryCount{}}
+}
+
+func (s *Geo) Write(o processor.Output) error {
+	if o.Geo == nil || len(o.Geo.Countries) == 0 {
+		s.unlocated++
+		return nil
+	}
+	for _, cc := range o.Geo.Countries {
+		c := s.byCountry[cc]
+		if c == nil {
+			c = &CountryCount{Country: cc}
+			s.byCountry[cc] = c
+		}
+		c.Live++
+		c.HighRisk = c.HighRisk || slices.Contains(o.Geo.HighRisk, cc)
+	}
+	return nil
+}
+
+// Counts returns the hosting countries, most candidates first.
+func (s *Geo) Counts() []CountryCount {
+	out := []CountryCount{}
+	for _, c := range s.byCountry {
+		out = append(out, *c)
+	}
+	slices.SortFunc(out, func(a, b CountryCount) int {
+		return cmp.Or(cmp.Compare(b.Live, a.Live), cmp.Compare(a.Country, b.Country))
+	})
+	return out
+}
+
+// Unlocated is how many candidates had no known hosting country.
+func (s *Geo) Unlocated() int {
+	return s.unlocated
+}
+
+func (s *Geo) Close() error {
+	counts := s.Counts()
+	s.logger.Info("processing geo sink", "countries", len(counts), "unlocated", s.unlocated)
+	if s.path == "" {
+		return nil
+	}
+	file, err := createBuffered(s.path)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(counts); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,37 @@
+package sink
+
+import (
+	"io"
+	"log/slog"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestGeoCounts(t *testing.T) {
+	s := NewGeo("", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	hosted := func(d string, countries, highRisk []string) processor.Output {
+		return processor.Output{Domain: d, Geo: &verify.GeoResult{Countries: countries, HighRisk: highRisk}}
+	}
+	for _, o := range []processor.Output{
+		hosted("a.com", []string{"US"}, nil),
+		hosted("b.com", []string{"US", "IR"}, []string{"IR"}),
+		hosted("c.com", []string{"DE"}, nil),
+		{Domain: "unknown.com"},
+	} {
+		_ = s.Write(o)
+	}
+	got := s.Counts()
+	want := []CountryCount{{Country: "US", Live: 2}, {Country: "DE", Live: 1}, {Country: "IR", Live: 1, HighRisk: true}}
+	if len(got) != len(want) {
+		t.Fatalf("Counts() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Counts()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if s.Unlocated() != 1 {
+		t.Errorf("Unlocated() = %d, want 1", s.Unlocated())
+	}
+}
@@ -0,0 +1,88 @@
+package sink
+
+import (
+	"path/filepath"
+	"squatrr/lib/graph"
+	"squatrr/lib/processor"
+	"strings"
+	"time"
+)
+
+// Graph format names accepted by NewGraph.
+const (
+	FormatDOT     = "dot"
+	FormatGraphML = "graphml"
+	FormatCypher  = "cypher"
+)
+
+// Graph incrementally links each result to its infrastructure and writes the
+// graph in the chosen format on Close.
+type Graph struct {
+	path   string
+	format string
+	g      *graph.Graph
+}
+
+// NewGraph writes the infrastructure graph to path in format. An empty format
+// is inferred from the extension: .graphml is GraphML, anything else DOT.
+func NewGraph(path, format string) *Graph {
+	if format == "" {
+		format = FormatDOT
+		if strings.EqualFold(filepath.Ext(path), ".graphml") {
+			format = FormatGraphML
+		}
+	}
+	return &Graph{path: path, format: format, g: graph.New()}
+}
+
+// Write links a result to its IPs, ASNs, nameservers, certificate
+// fingerprint, tracking IDs and registrar.
+func (s *Graph) Write(o processor.Output) error {
+	g := s.g
+	g.AddDomain(o.Domain)
+	for _, ip := range o.DNS.A {
+		g.Link(o.Domain, graph.KindIP, ip)
+	}
+	for _, ip := range o.DNS.AAAA {
+		g.Link(o.Domain, graph.KindIP, ip)
+	}
+	for _, a := range o.DNS.ASN {
+		g.Link(o.Domain, graph.KindASN, a.ASN)
+	}
+	for _, ns := range o.DNS.NS {
+		g.Link(o.Domain, graph.KindNS, ns)
+	}
+	if o.TLS != nil {
+		g.Link(o.Domain, graph.KindCert, o.TLS.FingerprintSHA256)
+	}
+	if o.HTTP != nil {
+		for _, id := range o.HTTP.TrackingIDs {
+			g.Link(o.Domain, graph.KindTracking, id)
+		}
+	}
+	if o.RDAP != nil {
+		g.Link(o.Domain, graph.KindRegistrar, o.RDAP.Registrar)
+	}
+	return nil
+}
+
+func (s *Graph) Close() error {
+	f, err := createBuffered(s.path)
+	if err != nil {
+		return err
+	}
+
+	switch s.format {
+	case FormatGraphML:
+		err = s.g.WriteGraphML(f)
+	case FormatCypher:
+		err = s.g.WriteCypher(f, time.Now())
+	default:
+		err = s.g.WriteDOT(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
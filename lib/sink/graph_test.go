@@ -0,0 +1,36 @@
+package sink
+
+import (
+	"os"
+	"path/filepath"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"strings"
+	"testing"
+)
+
+func TestGraphRegistrar(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.cypher")
+	s := NewGraph(path, FormatCypher)
+	for _, o := range []processor.Output{
+		{Domain: "exampel.com", RDAP: &verify.RDAPResult{Registrar: "Example Registrar, Inc."}},
+		{Domain: "examp1e.com", RDAP: &verify.RDAPResult{Registrar: "Example Registrar, Inc."}},
+		{Domain: "exmple.com"},
+	} {
+		_ = s.Write(o)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	if n := strings.Count(out, "MERGE (n:Registrar {name: 'Example Registrar, Inc.'})"); n != 1 {
+		t.Errorf("registrar node written %d times, want once:\n%s", n, out)
+	}
+	if n := strings.Count(out, "[r:REGISTERED_WITH]"); n != 2 {
+		t.Errorf("%d REGISTERED_WITH edges, want 2:\n%s", n, out)
+	}
+}
@@ -0,0 +1,93 @@
+package sink
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"squatrr/lib/processor"
+)
+
+// DefaultMinShared is how many candidates must share a default certificate
+// before they collapse into one infrastructure finding.
+const DefaultMinShared = 3
+
+// InfraFinding is a host serving many candidates from its default vhost.
+type InfraFinding struct {
+	CertSHA256  string   `json:"cert_sha256"`
+	CertSubject string   `json:"cert_subject,omitempty"`
+	IPs         []string `json:"ips,omitempty"`
+	Domains     []string `json:"domains"`
+}
+
+// Infra collapses candidates answered by the same default (SNI-less)
+// certificate, typically parking or shared hosting, into one finding per
+// certificate instead of hundreds of rows. Findings are logged and written
+// to path when it is set.
+type Infra struct {
+	path      string
+	minShared int
+	logger    *slog.Logger
+	byCert    map[string]*InfraFinding
+}
+
+func NewInfra(path string, minShared int, logger *slog.Logger) *Infra {
+	if minShared <= 0 {
+		minShared = DefaultMinShared
+	}
+	return &Infra{path: path, minShared: minShared, logger: logger, byCert: map[string]*InfraFinding{}}
+}
+
+func (s *Infra) Write(o processor.Output) error {
+	if o.TLS == nil || !o.TLS.DefaultVhost || o.TLS.DefaultCertSHA256 == "" {
+		return nil
+	}
+	f := s.byCert[o.TLS.DefaultCertSHA256]
+	if f == nil {
+		f = &InfraFinding{CertSHA256: o.TLS.DefaultCertSHA256, CertSubject: o.TLS.DefaultCertSubject}
+		s.byCert[o.TLS.DefaultCertSHA256] = f
+	}
+	f.Domains = append(f.Domains, o.Domain)
+	for _, ip := range o.DNS.A {
+		if !slices.Contains(f.IPs, ip) {
+			f.IPs = append(f.IPs, ip)
+		}
+	}
+	return nil
+}
+
+// Findings returns the shared default certificates, largest first.
+func (s *Infra) Findings() []InfraFinding {
+	out := []InfraFinding{}
+	for _, f := range s.byCert {
+		if len(f.Domains) < s.minShared {
+			continue
+		}
+		slices.Sort(f.Domains)
+		slices.Sort(f.IPs)
+		out = append(out, *f)
+	}
+	slices.SortFunc(out, func(a, b InfraFinding) int {
+		return cmp.Or(cmp.Compare(len(b.Domains), len(a.Domains)), cmp.Compare(a.CertSHA256, b.CertSHA256))
+	})
+	return out
+}
+
+func (s *Infra) Close() error {
+	findings := s.Findings()
+	for _, f := range findings {
+		s.logger.Info("processing shared default vhost sink", "cert", f.CertSHA256[:16], "subject", f.CertSubject, "domains", len(f.Domains))
+	}
+	if s.path == "" {
+		return nil
+	}
+	file, err := createBuffered(s.path)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(findings); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,34 @@
+package sink
+
+import (
+	"io"
+	"log/slog"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestInfraFindings(t *testing.T) {
+	s := NewInfra("", 2, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	parked := func(d, ip, cert string, def bool) processor.Output {
+		return processor.Output{Domain: d, DNS: verify.DNSResult{A: []string{ip}}, TLS: &verify.TLSResult{DefaultCertSHA256: cert, DefaultVhost: def}}
+	}
+	for _, o := range []processor.Output{
+		parked("b.com", "192.0.2.1", "aaaa", true),
+		parked("a.com", "192.0.2.2", "aaaa", true),
+		parked("c.com", "192.0.2.1", "aaaa", true),
+		parked("own-site.com", "192.0.2.1", "aaaa", false), // has its own cert on the shared host
+		parked("lonely.com", "198.51.100.1", "bbbb", true),
+		{Domain: "no-tls.com"},
+	} {
+		_ = s.Write(o)
+	}
+	got := s.Findings()
+	if len(got) != 1 {
+		t.Fatalf("Findings() = %+v, want one finding", got)
+	}
+	f := got[0]
+	if f.CertSHA256 != "aaaa" || len(f.Domains) != 3 || f.Domains[0] != "a.com" || len(f.IPs) != 2 {
+		t.Errorf("Findings()[0] = %+v", f)
+	}
+}
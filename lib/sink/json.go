@@ -0,0 +1,56 @@
+package sink
+
+import (
+	"encoding/json"
+	"io"
+	"squatrr/lib/processor"
+)
+
+// JSONArray streams results into a single JSON array, one record per line,
+// without holding the result set in memory.
+type JSONArray struct {
+	w     io.WriteCloser
+	enc   *json.Encoder
+	count int
+}
+
+// NewJSONArray creates path and streams results into it.
+func NewJSONArray(path string) (*JSONArray, error) {
+	f, err := createBuffered(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONArrayWriter(f), nil
+}
+
+// NewJSONArrayWriter streams results into w, closing it on Close.
+func NewJSONArrayWriter(w io.WriteCloser) *JSONArray {
+	return &JSONArray{w: w, enc: json.NewEncoder(w)}
+}
+
+func (j *JSONArray) Write(o processor.Output) error {
+	sep := ","
+	if j.count == 0 {
+		sep = "["
+	}
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	j.count++
+	return j.enc.Encode(o) // Encode terminates each record with a newline
+}
+
+// Count is the number of results written so far.
+func (j *JSONArray) Count() int { return j.count }
+
+func (j *JSONArray) Close() error {
+	end := "]\n"
+	if j.count == 0 {
+		end = "[]\n"
+	}
+	if _, err := io.WriteString(j.w, end); err != nil {
+		j.w.Close()
+		return err
+	}
+	return j.w.Close()
+}
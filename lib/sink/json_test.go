@@ -0,0 +1,49 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"squatrr/lib/processor"
+	"testing"
+)
+
+type nopCloser struct{ *bytes.Buffer }
+
+func (nopCloser) Close() error { return nil }
+
+func TestJSONArray(t *testing.T) {
+	tests := []struct {
+		name    string
+		domains []string
+		want    string
+	}{
+		{name: "Empty result set", domains: nil, want: "[]\n"},
+		{name: "Streams one record per line", domains: []string{"exampel.com", "examp1e.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := nopCloser{&bytes.Buffer{}}
+			j := NewJSONArrayWriter(buf)
+			for _, d := range tt.domains {
+				if err := j.Write(processor.Output{Domain: d}); err != nil {
+					t.Fatalf("Write() error: %v", err)
+				}
+			}
+			if err := j.Close(); err != nil {
+				t.Fatalf("Close() error: %v", err)
+			}
+
+			if tt.want != "" && buf.String() != tt.want {
+				t.Errorf("output = %q, want %q", buf.String(), tt.want)
+			}
+			var got []processor.Output
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("output is not a JSON array: %v\n%s", err, buf.String())
+			}
+			if len(got) != len(tt.domains) || j.Count() != len(tt.domains) {
+				t.Errorf("decoded %d records, Count() = %d, want %d", len(got), j.Count(), len(tt.domains))
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package sink
+
+import (
+	"cmp"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"squatrr/lib/classify"
+	"squatrr/lib/processor"
+	"strings"
+)
+
+// DefaultLeaderboardTop is how many entries each leaderboard keeps.
+const DefaultLeaderboardTop = 25
+
+// Board ranks the parking providers, registrars and hosting ASNs behind a
+// base domain's flagged candidates, or every base's when Base is empty.
+type Board struct {
+	Base       string   `json:"base,omitempty"`
+	Flagged    int      `json:"flagged"`
+	Parking    []Leader `json:"parking"`
+	Registrars []Leader `json:"registrars"`
+	ASNs       []Leader `json:"asns"`
+}
+
+// Leader is one provider's count of flagged candidates, and across bases
+// how many base domains they imitate.
+type Leader struct {
+	Name    string `json:"name"`
+	Flagged int    `json:"flagged"`
+	Bases   int    `json:"bases,omitempty"`
+}
+
+// Leaderboard counts flagged candidates (live ones that are reverse
+// proxies, phishing, parked or for sale, or score at least the high-score threshold) by
+// parking provider (the sale marketplace, parking signal, nameserver or
+// traffic redirector that classified them), RDAP registrar and origin
+// ASN. A candidate counts once for each of its distinct ASNs; one whose
+// provider is not known is counted as flagged only.
+type Leaderboard struct {
+	path      string
+	base      string
+	highScore int
+	top       int
+	logger    *slog.Logger
+	flagged   int
+	parking   map[string]map[string]int // provider -> base -> flagged
+	registrar map[string]map[string]int
+	asns      map[string]map[string]int
+}
+
+// NewLeaderboard ranks the candidates of base, or of every base given to
+// Record when it is empty, against highScore (0 =
+// processor.DefaultHighScore), keeping top (0 = DefaultLeaderboardTop)
+// entries per board. A path writes the board there on Close.
+func NewLeaderboard(path, base string, highScore, top int, logger *slog.Logger) *Leaderboard {
+	if highScore <= 0 {
+		highScore = processor.DefaultHighScore
+	}
+	if top <= 0 {
+		top = DefaultLeaderboardTop
+	}
+	return &Leaderboard{
+		path: path, base: base, highScore: highScore, top: top, logger: logger,
+		parking: map[string]map[string]int{}, registrar: map[string]map[string]int{}, asns: map[string]map[string]int{},
+	}
+}
+
+func (l *Leaderboard) Write(o processor.Output) error {
+	l.Record(l.base, o)
+	return nil
+}
+
+// Record counts o as a candidate of base.
+func (l *Leaderboard) Record(base string, o processor.Output) {
+	if !l.flags(o) {
+		return
+	}
+	l.flagged++
+	if p := parkingProvider(o); p != "" {
+		tally(l.parking, p, base)
+	}
+	if o.RDAP != nil && o.RDAP.Registrar != "" {
+		tally(l.registrar, o.RDAP.Registrar, base)
+	}
+	seen := map[string]bool{}
+	for _, a := range o.DNS.ASN {
+		if a.ASN != "" && !seen[a.ASN] {
+			seen[a.ASN] = true
+			tally(l.asns, a.ASN, base)
+		}
+	}
+}
+
+func (l *Leaderboard) flags(o processor.Output) bool {
+	if !o.Live() {
+		return false
+	}
+	switch o.Class {
+	case classify.LabelReverseProxy, classify.LabelPhishing, classify.LabelParked, classify.LabelForSale:
+		return true
+	}
+	return o.Score >= l.highScore
+}
+
+// parkingProvider names who monetizes a parked or for-sale candidate,
+// from its listing or the class tags that labelled it.
+func parkingProvider(o processor.Output) string {
+	if o.Class != classify.LabelParked && o.Class != classify.LabelForSale {
+		return ""
+	}
+	if o.Listing != nil && o.Listing.Marketplace != "" {
+		return o.Listing.Marketplace
+	}
+	for _, tag := range o.ClassTags {
+		for _, prefix := range []string{"signal:" + classify.LabelParked + ":", "signal:" + classify.LabelForSale + ":", "ns:", "redirector:"} {
+			if name, ok := strings.CutPrefix(tag, prefix); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func tally(counts map[string]map[string]int, name, base string) {
+	if counts[name] == nil {
+		counts[name] = map[string]int{}
+	}
+	counts[name][base]++
+}
+
+// Board returns the leaderboards so far.
+func (l *Leaderboard) Board() Board {
+	return Board{
+		Base:       l.base,
+		Flagged:    l.flagged,
+		Parking:    l.rank(l.parking),
+		Registrars: l.rank(l.registrar),
+		ASNs:       l.rank(l.asns),
+	}
+}
+
+// rank orders counts by flagged candidates, then by bases, keeping top.
+func (l *Leaderboard) rank(counts map[string]map[string]int) []Leader {
+	out := make([]Leader, 0, len(counts))
+	for name, byBase := range counts {
+		e := Leader{Name: name}
+		for _, n := range byBase {
+			e.Flagged += n
+		}
+		if l.base == "" {
+			e.Bases = len(byBase)
+		}
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b Leader) int {
+		return cmp.Or(cmp.Compare(b.Flagged, a.Flagged), cmp.Compare(b.Bases, a.Bases), cmp.Compare(a.Name, b.Name))
+	})
+	return out[:min(l.top, len(out))]
+}
+
+func (l *Leaderboard) Close() error {
+	b := l.Board()
+	l.logger.Info("processing leaderboard sink", "flagged", b.Flagged, "parking", len(b.Parking), "registrars", len(b.Registrars), "asns", len(b.ASNs))
+	if l.path == "" {
+		return nil
+	}
+	file, err := createBuffered(l.path)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(b); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
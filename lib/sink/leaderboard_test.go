@@ -0,0 +1,67 @@
+package sink
+
+import (
+	"io"
+	"log/slog"
+	"reflect"
+	"squatrr/lib/classify"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestLeaderboard(t *testing.T) {
+	live := verify.DNSResult{HasA: true}
+	parked := func(domain, tag, asn string) processor.Output {
+		dns := live
+		dns.ASN = []verify.ASNInfo{{ASN: asn}, {ASN: asn}}
+		return processor.Output{Domain: domain, Resolvable: true, DNS: dns, Class: "parked", ClassTags: []string{tag},
+			RDAP: &verify.RDAPResult{Registrar: "NameCheap, Inc."}}
+	}
+	results := map[string][]processor.Output{
+		"example.com": {
+			parked("exmple.com", "signal:parked:sedo", "AS47846"),
+			parked("exampel.com", "ns:bodis.com", "AS47846"),
+			{Domain: "examp1e.com", Resolvable: true, DNS: live, Class: "for_sale", Listing: &classify.Listing{Marketplace: "sedo"}},
+			{Domain: "exampl.com", Resolvable: true, DNS: live, Class: "unrelated", Score: 30, RDAP: &verify.RDAPResult{Registrar: "GoDaddy.com, LLC"}},
+			{Domain: "exmaple.com", Resolvable: true, DNS: live, Class: "unrelated", Score: 5, RDAP: &verify.RDAPResult{Registrar: "GoDaddy.com, LLC"}},
+			{Domain: "eample.com", Class: "parked", ClassTags: []string{"ns:sedoparking.com"}}, // not live
+		},
+		"brand.org": {
+			parked("brnad.org", "redirector:bit.ly", "AS13335"),
+			parked("bran.org", "ns:bodis.com", "AS13335"),
+		},
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	one := NewLeaderboard("", "example.com", 20, 0, logger)
+	for _, o := range results["example.com"] {
+		_ = one.Write(o)
+	}
+	want := Board{
+		Base:       "example.com",
+		Flagged:    4,
+		Parking:    []Leader{{Name: "sedo", Flagged: 2}, {Name: "bodis.com", Flagged: 1}},
+		Registrars: []Leader{{Name: "NameCheap, Inc.", Flagged: 2}, {Name: "GoDaddy.com, LLC", Flagged: 1}},
+		ASNs:       []Leader{{Name: "AS47846", Flagged: 2}},
+	}
+	if got := one.Board(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Board() = %+v, want %+v", got, want)
+	}
+
+	all := NewLeaderboard("", "", 20, 2, logger)
+	for base, outputs := range results {
+		for _, o := range outputs {
+			all.Record(base, o)
+		}
+	}
+	want = Board{
+		Flagged:    6,
+		Parking:    []Leader{{Name: "bodis.com", Flagged: 2, Bases: 2}, {Name: "sedo", Flagged: 2, Bases: 1}},
+		Registrars: []Leader{{Name: "NameCheap, Inc.", Flagged: 4, Bases: 2}, {Name: "GoDaddy.com, LLC", Flagged: 1, Bases: 1}},
+		ASNs:       []Leader{{Name: "AS13335", Flagged: 2, Bases: 1}, {Name: "AS47846", Flagged: 2, Bases: 1}},
+	}
+	if got := all.Board(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Board() across bases = %+v, want %+v", got, want)
+	}
+}
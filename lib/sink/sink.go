@@ -0,0 +1,74 @@
+package sink
+
+/*
+  This library holds the destinations verified results stream into.
+  Sinks receive results one at a time as workers produce them so a scan's
+  memory stays flat no matter how many candidates it verifies; anything that
+  needs the whole result set (clusters, graphs) keeps only the fields it uses.
+*/
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"squatrr/lib/processor"
+)
+
+// Sink consumes verified results as they stream out of the pipeline.
+// Close flushes anything buffered and releases the destination.
+type Sink interface {
+	Write(processor.Output) error
+	Close() error
+}
+
+// Multi fans every result out to each sink in order.
+type Multi []Sink
+
+func (m Multi) Write(o processor.Output) error {
+	for _, s := range m {
+		if err := s.Write(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes every sink, even if some fail, and joins their errors.
+func (m Multi) Close() error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Live passes only live results (see processor.Output.Live) on to Sink,
+// for the destinations that hold findings. Results that couldn't be
+// checked, or dead ones kept with -include-unresolvable, belong in the
+// results file and history only.
+type Live struct{ Sink }
+
+func (l Live) Write(o processor.Output) error {
+	if !o.Live() {
+		return nil
+	}
+	return l.Sink.Write(o)
+}
+
+// bufferedFile is an os.File behind a 64KiB write buffer.
+type bufferedFile struct {
+	*bufio.Writer
+	f *os.File
+}
+
+func createBuffered(path string) (*bufferedFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &bufferedFile{Writer: bufio.NewWriterSize(f, 64<<10), f: f}, nil
+}
+
+func (b *bufferedFile) Close() error {
+	return errors.Join(b.Flush(), b.f.Close())
+}
@@ -0,0 +1,32 @@
+package sink
+
+import (
+	"reflect"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+// recorded keeps the domains written to it.
+type recorded []string
+
+func (r *recorded) Write(o processor.Output) error { *r = append(*r, o.Domain); return nil }
+func (r *recorded) Close() error                   { return nil }
+
+func TestLive(t *testing.T) {
+	var all, live recorded
+	s := Multi{&all, Live{&live}}
+	for _, o := range []processor.Output{
+		{Domain: "exampel.com", Resolvable: true},
+		{Domain: "examp1e.com", HasMail: true},
+		{Domain: "exanple.com"}, // unregistered, kept by -include-unresolvable
+		{Domain: "exmaple.com", Errors: []verify.StageError{{Stage: "verify", Message: "timeout"}}},
+	} {
+		if err := s.Write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"exampel.com", "examp1e.com"}; !reflect.DeepEqual([]string(live), want) || len(all) != 4 {
+		t.Errorf("Live sink got %v (all %v), want %v", live, all, want)
+	}
+}
@@ -0,0 +1,54 @@
+package sink
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"squatrr/lib/processor"
+	"strings"
+)
+
+// Sort orders accepted by NewSorted.
+const (
+	SortDomain = "domain"
+	SortScore  = "score"
+)
+
+// Sorted buffers every result and forwards them to next in a deterministic
+// order on Close. Worker fan-in otherwise produces a different order each run.
+// Unlike the other sinks it holds the full result set in memory.
+type Sorted struct {
+	by      string
+	next    Sink
+	results []processor.Output
+}
+
+// NewSorted orders by domain ascending, or by score descending with domain as
+// the tie-breaker.
+func NewSorted(by string, next Sink) (*Sorted, error) {
+	if by != SortDomain && by != SortScore {
+		return nil, fmt.Errorf("unknown sort order %q; expected %s or %s", by, SortDomain, SortScore)
+	}
+	return &Sorted{by: by, next: next}, nil
+}
+
+func (s *Sorted) Write(o processor.Output) error {
+	s.results = append(s.results, o)
+	return nil
+}
+
+func (s *Sorted) Close() error {
+	slices.SortFunc(s.results, func(a, b processor.Output) int {
+		if s.by == SortScore && a.Score != b.Score {
+			return b.Score - a.Score
+		}
+		return strings.Compare(a.Domain, b.Domain)
+	})
+	for _, o := range s.results {
+		if err := s.next.Write(o); err != nil {
+			return errors.Join(err, s.next.Close())
+		}
+	}
+	s.results = nil
+	return s.next.Close()
+}
@@ -0,0 +1,63 @@
+package sink
+
+import (
+	"reflect"
+	"squatrr/lib/processor"
+	"testing"
+)
+
+type collect struct {
+	domains []string
+	closed  bool
+}
+
+func (c *collect) Write(o processor.Output) error {
+	c.domains = append(c.domains, o.Domain)
+	return nil
+}
+
+func (c *collect) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSorted(t *testing.T) {
+	in := []processor.Output{
+		{Domain: "exampel.com", Score: 10},
+		{Domain: "examp1e.com", Score: 30},
+		{Domain: "eample.com", Score: 10},
+	}
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{by: SortDomain, want: []string{"eample.com", "examp1e.com", "exampel.com"}},
+		{by: SortScore, want: []string{"examp1e.com", "eample.com", "exampel.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.by, func(t *testing.T) {
+			next := &collect{}
+			s, err := NewSorted(tt.by, next)
+			if err != nil {
+				t.Fatalf("NewSorted() error: %v", err)
+			}
+			for _, o := range in {
+				_ = s.Write(o)
+			}
+			if len(next.domains) != 0 {
+				t.Fatalf("Sorted forwarded results before Close")
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("Close() error: %v", err)
+			}
+			if !reflect.DeepEqual(next.domains, tt.want) || !next.closed {
+				t.Errorf("forwarded %v (closed=%v), want %v", next.domains, next.closed, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewSorted("random", &collect{}); err == nil {
+		t.Errorf("NewSorted(random) error = nil, want error")
+	}
+}
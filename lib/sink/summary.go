@@ -0,0 +1,124 @@
+package sink
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"squatrr/lib/processor"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DefaultSummaryTop is how many nameservers and ASNs a summary ranks.
+const DefaultSummaryTop = 10
+
+// Grades bucket a result's score against the run's high-score threshold.
+const (
+	GradeHigh   = "high"   // at least the threshold
+	GradeMedium = "medium" // at least half of it
+	GradeLow    = "low"
+)
+
+// RunSummary holds a run's headline numbers, recorded in its metadata so
+// they are known without loading the result set.
+type RunSummary struct {
+	Results     int            `json:"results"`
+	Strategies  map[string]int `json:"by_strategy"`
+	TLDs        map[string]int `json:"by_tld"`
+	Classes     map[string]int `json:"by_class"` // "unclassified" without a class
+	Grades      map[string]int `json:"by_grade"`
+	Nameservers []NameCount    `json:"top_nameservers"`
+	ASNs        []NameCount    `json:"top_asns"`
+}
+
+// NameCount is how many results name one nameserver or ASN.
+type NameCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Summary counts results by strategy, TLD, class and grade, and ranks the
+// nameservers and origin ASNs they share. A result counts once for each of
+// its distinct nameservers and ASNs.
+type Summary struct {
+	highScore int
+	top       int
+	sum       RunSummary
+	ns, asns  map[string]int
+}
+
+// NewSummary grades results against highScore (0 =
+// processor.DefaultHighScore) and keeps the top (0 = DefaultSummaryTop)
+// nameservers and ASNs.
+func NewSummary(highScore, top int) *Summary {
+	if highScore <= 0 {
+		highScore = processor.DefaultHighScore
+	}
+	if top <= 0 {
+		top = DefaultSummaryTop
+	}
+	return &Summary{
+		highScore: highScore,
+		top:       top,
+		sum: RunSummary{
+			Strategies: map[string]int{}, TLDs: map[string]int{},
+			Classes: map[string]int{}, Grades: map[string]int{},
+		},
+		ns:   map[string]int{},
+		asns: map[string]int{},
+	}
+}
+
+func (s *Summary) Write(o processor.Output) error {
+	s.sum.Results++
+	s.sum.Strategies[cmp.Or(o.Strategy, "unknown")]++
+	tld, _ := publicsuffix.PublicSuffix(strings.ToLower(strings.TrimSuffix(o.Domain, ".")))
+	s.sum.TLDs[tld]++
+	s.sum.Classes[cmp.Or(o.Class, "unclassified")]++
+	switch {
+	case o.Score >= s.highScore:
+		s.sum.Grades[GradeHigh]++
+	case o.Score >= s.highScore/2:
+		s.sum.Grades[GradeMedium]++
+	default:
+		s.sum.Grades[GradeLow]++
+	}
+	seen := map[string]bool{}
+	for _, ns := range o.DNS.NS {
+		if ns = strings.ToLower(strings.TrimSuffix(ns, ".")); !seen[ns] {
+			seen[ns] = true
+			s.ns[ns]++
+		}
+	}
+	for _, a := range o.DNS.ASN {
+		if a.ASN != "" && !seen[a.ASN] {
+			seen[a.ASN] = true
+			s.asns[a.ASN]++
+		}
+	}
+	return nil
+}
+
+// Summary returns the numbers so far.
+func (s *Summary) Summary() RunSummary {
+	sum := s.sum
+	sum.Strategies, sum.TLDs = maps.Clone(sum.Strategies), maps.Clone(sum.TLDs)
+	sum.Classes, sum.Grades = maps.Clone(sum.Classes), maps.Clone(sum.Grades)
+	sum.Nameservers, sum.ASNs = topCounts(s.ns, s.top), topCounts(s.asns, s.top)
+	return sum
+}
+
+func (s *Summary) Close() error { return nil }
+
+// topCounts ranks counts, most first, keeping n.
+func topCounts(counts map[string]int, n int) []NameCount {
+	out := make([]NameCount, 0, len(counts))
+	for name, c := range counts {
+		out = append(out, NameCount{Name: name, Count: c})
+	}
+	slices.SortFunc(out, func(a, b NameCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	return out[:min(n, len(out))]
+}
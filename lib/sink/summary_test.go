@@ -0,0 +1,35 @@
+package sink
+
+import (
+	"reflect"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestSummary(t *testing.T) {
+	s := NewSummary(20, 2)
+	for _, o := range []processor.Output{
+		{Domain: "exmple.com", Strategy: "Omission", Class: "parked", Score: 25,
+			DNS: verify.DNSResult{NS: []string{"ns1.parkingcrew.net.", "NS1.parkingcrew.net"}, ASN: []verify.ASNInfo{{ASN: "AS13335"}, {ASN: "AS13335"}}}},
+		{Domain: "exampel.co.uk", Strategy: "Transposition", Score: 12,
+			DNS: verify.DNSResult{NS: []string{"ns1.parkingcrew.net"}, ASN: []verify.ASNInfo{{ASN: "AS16509"}}}},
+		{Domain: "examp1e.com", Strategy: "Omission", Class: "phishing", Score: 3,
+			DNS: verify.DNSResult{NS: []string{"ns.example.org"}, ASN: []verify.ASNInfo{{ASN: "AS13335"}}}},
+	} {
+		_ = s.Write(o)
+	}
+	got := s.Summary()
+	want := RunSummary{
+		Results:     3,
+		Strategies:  map[string]int{"Omission": 2, "Transposition": 1},
+		TLDs:        map[string]int{"com": 2, "co.uk": 1},
+		Classes:     map[string]int{"parked": 1, "phishing": 1, "unclassified": 1},
+		Grades:      map[string]int{GradeHigh: 1, GradeMedium: 1, GradeLow: 1},
+		Nameservers: []NameCount{{"ns1.parkingcrew.net", 2}, {"ns.example.org", 1}},
+		ASNs:        []NameCount{{"AS13335", 2}, {"AS16509", 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Summary() = %+v, want %+v", got, want)
+	}
+}
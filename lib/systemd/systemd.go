@@ -0,0 +1,85 @@
+package systemd
+
+/*
+  This library speaks the systemd service notification protocol (sd_notify),
+  so the long-running modes can run as Type=notify units: they report
+  readiness once serving, keep a WatchdogSec= watchdog fed while healthy, and
+  announce shutdown. Outside systemd (no NOTIFY_SOCKET) every call is a no-op.
+*/
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Notify sends state (e.g. "READY=1") to the service manager.
+func Notify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' { // abstract socket namespace
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// Ready reports that startup finished and the service is serving.
+func Ready() error { return Notify("READY=1") }
+
+// Stopping reports that the service is shutting down.
+func Stopping() error { return Notify("STOPPING=1") }
+
+// Status sets the free-form status line shown by systemctl status.
+func Status(s string) error { return Notify("STATUS=" + s) }
+
+// WatchdogInterval is how often systemd expects a keep-alive, or 0 when no
+// watchdog is configured for this process.
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// Watchdog feeds the watchdog at half its interval until ctx ends. Pings are
+// withheld while healthy reports an error, so systemd restarts a service
+// that is alive but stuck. It returns at once when no watchdog is set.
+func Watchdog(ctx context.Context, healthy func() error, logger *slog.Logger) {
+	interval := WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	t := time.NewTicker(interval / 2)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if healthy != nil {
+			if err := healthy(); err != nil {
+				logger.Warn("processing watchdog", "error", err)
+				continue
+			}
+		}
+		if err := Notify("WATCHDOG=1"); err != nil {
+			logger.Warn("processing watchdog", "error", err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package takedown
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"squatrr/lib/processor"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Submission channels.
+const (
+	ChannelAPI  = "api"
+	ChannelForm = "form"
+)
+
+// maxReceiptBytes caps the response read for a submission ID.
+const maxReceiptBytes = 64 << 10
+
+// Report is what a submission says about a candidate. API channels
+// receive it as JSON; form channels as the fields their FormFields map.
+type Report struct {
+	Domain        string   `json:"domain"`
+	URL           string   `json:"url"`
+	Brand         string   `json:"brand,omitempty"`
+	Class         string   `json:"class,omitempty"`
+	Score         int      `json:"score"`
+	Evidence      []string `json:"evidence,omitempty"` // class and score tags
+	Description   string   `json:"description"`
+	ReporterName  string   `json:"reporter_name,omitempty"`
+	ReporterEmail string   `json:"reporter_email"`
+}
+
+// reportFields are the Report fields a form can map.
+var reportFields = []string{"domain", "url", "brand", "class", "score", "evidence", "description", "reporter_name", "reporter_email"}
+
+// NewReport describes o as an imitation of brand, reported by name at
+// email.
+func NewReport(o processor.Output, brand, name, email string) Report {
+	r := Report{
+		Domain: o.Domain, URL: "https://" + o.Domain + "/", Brand: brand, Class: o.Class, Score: o.Score,
+		Evidence: append(append([]string{}, o.ClassTags...), o.ScoreTags...), ReporterName: name, ReporterEmail: email,
+	}
+	if o.HTTP != nil && o.HTTP.FinalURL != "" {
+		r.URL = o.HTTP.FinalURL
+	}
+	r.Description = fmt.Sprintf("%s imitates %s", o.Domain, brand)
+	if brand == "" {
+		r.Description = o.Domain + " is a lookalike domain"
+	}
+	if o.Class != "" {
+		r.Description += " and serves a " + strings.ReplaceAll(o.Class, "_", " ") + " page"
+	}
+	r.Description += " at " + r.URL + "."
+	if len(r.Evidence) > 0 {
+		r.Description += " Findings: " + strings.Join(r.Evidence, ", ") + "."
+	}
+	return r
+}
+
+func (r Report) field(name string) string {
+	switch name {
+	case "domain":
+		return r.Domain
+	case "url":
+		return r.URL
+	case "brand":
+		return r.Brand
+	case "class":
+		return r.Class
+	case "score":
+		return strconv.Itoa(r.Score)
+	case "evidence":
+		return strings.Join(r.Evidence, ", ")
+	case "description":
+		return r.Description
+	case "reporter_name":
+		return r.ReporterName
+	case "reporter_email":
+		return r.ReporterEmail
+	}
+	return ""
+}
+
+// Submitter sends takedown requests to the providers of a routing table
+// that accept them directly: an abuse API, or a standardized form with
+// FormFields. Email-only routes are left to the analyst.
+type Submitter struct {
+	Table  *Table
+	Client *http.Client
+}
+
+// ErrNotSubmittable is returned for a candidate none of whose routes
+// accepts direct submission.
+var ErrNotSubmittable = errors.New("no route accepts direct submission")
+
+// Channel returns the route o's report would be submitted through: the
+// first of o's routes whose provider has an API or a mapped form, and
+// which of the two is used.
+func (s *Submitter) Channel(o processor.Output) (processor.Takedown, string, error) {
+	if o.Takedown == nil {
+		return processor.Takedown{}, "", ErrNotSubmittable
+	}
+	routes := append([]processor.Takedown{*o.Takedown}, o.Takedown.Also...)
+	for _, r := range routes {
+		p, ok := s.Table.provider(r.Target, r.Provider)
+		switch {
+		case !ok:
+		case p.API != "":
+			return r, ChannelAPI, nil
+		case p.Form != "" && len(p.FormFields) > 0:
+			return r, ChannelForm, nil
+		}
+	}
+	return processor.Takedown{}, "", ErrNotSubmittable
+}
+
+// Submit sends r through o's submittable route and returns the
+// submission, with the provider's ticket ID when its answer carries one.
+func (s *Submitter) Submit(ctx context.Context, o processor.Output, r Report) (processor.Submission, error) {
+	route, channel, err := s.Channel(o)
+	if err != nil {
+		return processor.Submission{}, err
+	}
+	p, _ := s.Table.provider(route.Target, route.Provider)
+	sub := processor.Submission{Target: route.Target, Provider: p.Name, Channel: channel}
+
+	var req *http.Request
+	if channel == ChannelAPI {
+		body, err := json.Marshal(r)
+		if err != nil {
+			return sub, err
+		}
+		sub.URL = p.API
+		if req, err = http.NewRequestWithContext(ctx, http.MethodPost, p.API, bytes.NewReader(body)); err != nil {
+			return sub, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if p.APITokenEnv != "" {
+			token := os.Getenv(p.APITokenEnv)
+			if token == "" {
+				return sub, fmt.Errorf("%s: %s is not set", p.Name, p.APITokenEnv)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+	} else {
+		form := url.Values{}
+		for field, name := range p.FormFields {
+			form.Set(name, r.field(field))
+		}
+		sub.URL = p.Form
+		if req, err = http.NewRequestWithContext(ctx, http.MethodPost, p.Form, strings.NewReader(form.Encode())); err != nil {
+			return sub, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return sub, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxReceiptBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return sub, fmt.Errorf("%s: %s", p.Name, resp.Status)
+	}
+	sub.ID = receiptID(body, resp.Header.Get("Location"))
+	sub.SubmittedAt = time.Now().UTC()
+	return sub, nil
+}
+
+// receiptID finds the ticket ID in a provider's answer: a JSON id,
+// ticket or reference field, else the Location of the created report.
+func receiptID(body []byte, location string) string {
+	var doc map[string]any
+	if json.Unmarshal(body, &doc) == nil {
+		for _, key := range []string{"id", "ticket", "ticket_id", "reference", "case_id", "uuid"} {
+			switch v := doc[key].(type) {
+			case string:
+				if v != "" {
+					return v
+				}
+			case float64:
+				return strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+	}
+	return location
+}
+
+// provider finds the table entry behind a route.
+func (t *Table) provider(target, name string) (Provider, bool) {
+	providers := t.Hosting
+	if target == TargetRegistrar {
+		providers = t.Registrars
+	}
+	for _, p := range providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
@@ -0,0 +1,80 @@
+package takedown
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"squatrr/lib/processor"
+	"testing"
+)
+
+func TestSubmit(t *testing.T) {
+	var got Report
+	var auth string
+	var form map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api":
+			auth = r.Header.Get("Authorization")
+			_ = json.NewDecoder(r.Body).Decode(&got)
+			_, _ = w.Write([]byte(`{"ticket_id": 4711}`))
+		case "/form":
+			_ = r.ParseForm()
+			form = map[string]string{"site": r.PostForm.Get("site"), "mail": r.PostForm.Get("mail")}
+			w.Header().Set("Location", "/form/thanks/abc")
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("EXAMPLE_ABUSE_TOKEN", "s3cret")
+
+	table := &Table{
+		Version:    "test",
+		Registrars: []Provider{{Name: "Email Registrar", Email: "abuse@registrar.test"}, {Name: "Form Registrar", Form: srv.URL + "/form", FormFields: map[string]string{"domain": "site", "reporter_email": "mail"}}},
+		Hosting:    []Provider{{Name: "API Host", API: srv.URL + "/api", APITokenEnv: "EXAMPLE_ABUSE_TOKEN"}, {Name: "Broken Host", API: srv.URL + "/nope"}},
+	}
+	s := &Submitter{Table: table, Client: srv.Client()}
+	o := processor.Output{Domain: "exampel.com", Class: "phishing", Score: 70, ClassTags: []string{"password_field"}}
+	report := NewReport(o, "example.com", "Brand Team", "brand@example.com")
+
+	// The first route is email-only, so the hosting API takes the report.
+	o.Takedown = &processor.Takedown{Target: TargetRegistrar, Provider: "Email Registrar", Also: []processor.Takedown{{Target: TargetHosting, Provider: "API Host"}}}
+	sub, err := s.Submit(context.Background(), o, report)
+	if err != nil || sub.Channel != ChannelAPI || sub.Provider != "API Host" || sub.ID != "4711" || sub.SubmittedAt.IsZero() {
+		t.Errorf("Submit(api) = %+v, %v, want ticket 4711 through API Host", sub, err)
+	}
+	if auth != "Bearer s3cret" || !reflect.DeepEqual(got, report) {
+		t.Errorf("API got %+v with %q, want %+v with the bearer token", got, auth, report)
+	}
+
+	o.Takedown = &processor.Takedown{Target: TargetRegistrar, Provider: "Form Registrar"}
+	sub, err = s.Submit(context.Background(), o, report)
+	if err != nil || sub.Channel != ChannelForm || sub.ID != "/form/thanks/abc" {
+		t.Errorf("Submit(form) = %+v, %v, want the created report's location", sub, err)
+	}
+	if want := map[string]string{"site": "exampel.com", "mail": "brand@example.com"}; !reflect.DeepEqual(form, want) {
+		t.Errorf("form = %v, want %v", form, want)
+	}
+
+	o.Takedown = &processor.Takedown{Target: TargetHosting, Provider: "Broken Host"}
+	if _, err := s.Submit(context.Background(), o, report); err == nil {
+		t.Errorf("Submit(400) succeeded")
+	}
+	o.Takedown = &processor.Takedown{Target: TargetRegistrar, Provider: "Email Registrar"}
+	if _, err := s.Submit(context.Background(), o, report); !errors.Is(err, ErrNotSubmittable) {
+		t.Errorf("Submit(email only) error = %v, want ErrNotSubmittable", err)
+	}
+}
+
+func TestNewReport(t *testing.T) {
+	o := processor.Output{Domain: "exampel.com", Class: "for_sale", Score: 35, ScoreTags: []string{"typo_distance"}}
+	want := "exampel.com imitates example.com and serves a for sale page at https://exampel.com/. Findings: typo_distance."
+	if got := NewReport(o, "example.com", "", "brand@example.com").Description; got != want {
+		t.Errorf("Description = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,215 @@
+package takedown
+
+/*
+  This library routes flagged candidates to the abuse channel most likely
+  to take them down: the registrar, to suspend the domain, or the hosting
+  provider, to remove the content. Providers and their channels (email,
+  web form, API) ship as a versioned routing table (routes.json, compiled
+  in as Default) that a local file can replace; see Load.
+*/
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"squatrr/lib/classify"
+	"squatrr/lib/processor"
+	"squatrr/lib/sink"
+	"strings"
+)
+
+// Targets of a takedown.
+const (
+	TargetRegistrar = "registrar"
+	TargetHosting   = "hosting"
+)
+
+// Provider is a registrar or hosting provider and its abuse channels.
+// Registrars match on the RDAP registrar name (case-insensitive
+// substring of Names) or IANA ID; hosting providers on the origin ASN of
+// a resolved address, or a suffix of the candidate's CNAME, nameservers
+// or final URL host (Hosts).
+type Provider struct {
+	Name    string   `json:"name"`
+	Names   []string `json:"names,omitempty"`
+	IANAIDs []string `json:"iana_ids,omitempty"`
+	ASNs    []string `json:"asns,omitempty"`
+	Hosts   []string `json:"hosts,omitempty"`
+	Email   string   `json:"email,omitempty"`
+	Form    string   `json:"form,omitempty"`
+	API     string   `json:"api,omitempty"`
+
+	// APITokenEnv names the environment variable holding the bearer
+	// token the API wants, if any. FormFields maps Report fields to the
+	// names of a standardized form's fields; only forms with a mapping
+	// can be submitted (see Submitter).
+	APITokenEnv string            `json:"api_token_env,omitempty"`
+	FormFields  map[string]string `json:"form_fields,omitempty"`
+}
+
+// Table is a versioned routing table.
+type Table struct {
+	Version    string     `json:"version"`
+	Registrars []Provider `json:"registrars"`
+	Hosting    []Provider `json:"hosting"`
+}
+
+//go:embed routes.json
+var builtin []byte
+
+// Default is the routing table compiled into the binary.
+var Default = mustParse(builtin)
+
+// Parse decodes and validates a routing table.
+func Parse(data []byte) (*Table, error) {
+	var t Table
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	if t.Version == "" {
+		return nil, errors.New("routing table has no version")
+	}
+	for _, p := range append(append([]Provider{}, t.Registrars...), t.Hosting...) {
+		if p.Name == "" {
+			return nil, errors.New("routing table: provider without a name")
+		}
+		if p.Email == "" && p.Form == "" && p.API == "" {
+			return nil, fmt.Errorf("provider %q: no email, form or api", p.Name)
+		}
+		for field := range p.FormFields {
+			if !slices.Contains(reportFields, field) {
+				return nil, fmt.Errorf("provider %q: unknown form field %q", p.Name, field)
+			}
+		}
+	}
+	return &t, nil
+}
+
+func mustParse(data []byte) *Table {
+	t, err := Parse(data)
+	if err != nil {
+		panic("takedown: built-in routes: " + err.Error())
+	}
+	return t
+}
+
+// Load reads the routing table at path; an empty path is Default.
+func Load(path string) (*Table, error) {
+	if path == "" {
+		return Default, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// Route recommends a takedown channel for o, or nil when neither its
+// registrar nor its hosting is known. Live credential phishing and reverse
+// proxies go to the hosting provider first, as removing the content is quickest; otherwise
+// the registrar, unless it is known to be slow to act. A registrar
+// without a table entry is reached through its RDAP abuse contact.
+func (t *Table) Route(o processor.Output) *processor.Takedown {
+	reg, host := t.registrar(o), t.hosting(o)
+	first, second := reg, host
+	slow := o.RDAP != nil && o.RDAP.RegistrarClass == classify.RegistrarAbuseFriendly
+	if host != nil && (reg == nil || slow || o.Class == classify.LabelPhishing || o.Class == classify.LabelReverseProxy) {
+		first, second = host, reg
+	}
+	if first == nil {
+		return nil
+	}
+	if second != nil {
+		first.Also = []processor.Takedown{*second}
+	}
+	return first
+}
+
+func (t *Table) registrar(o processor.Output) *processor.Takedown {
+	r := o.RDAP
+	if r == nil || (r.Registrar == "" && r.RegistrarID == "") {
+		return nil
+	}
+	name := strings.ToLower(r.Registrar)
+	for _, p := range t.Registrars {
+		for _, id := range p.IANAIDs {
+			if r.RegistrarID != "" && id == r.RegistrarID {
+				return route(TargetRegistrar, p)
+			}
+		}
+		for _, n := range p.Names {
+			if name != "" && strings.Contains(name, strings.ToLower(n)) {
+				return route(TargetRegistrar, p)
+			}
+		}
+	}
+	if r.AbuseEmail != "" {
+		return &processor.Takedown{Target: TargetRegistrar, Provider: r.Registrar, Email: r.AbuseEmail}
+	}
+	return nil
+}
+
+func (t *Table) hosting(o processor.Output) *processor.Takedown {
+	hosts := append([]string{o.DNS.CNAME}, o.DNS.NS...)
+	if o.HTTP != nil {
+		if u, err := url.Parse(o.HTTP.FinalURL); err == nil {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	for _, p := range t.Hosting {
+		for _, a := range o.DNS.ASN {
+			for _, asn := range p.ASNs {
+				if strings.TrimPrefix(strings.ToUpper(a.ASN), "AS") == asn {
+					return route(TargetHosting, p)
+				}
+			}
+		}
+		for _, h := range hosts {
+			h = strings.ToLower(strings.TrimSuffix(h, "."))
+			for _, suffix := range p.Hosts {
+				if h == suffix || strings.HasSuffix(h, "."+suffix) {
+					return route(TargetHosting, p)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func route(target string, p Provider) *processor.Takedown {
+	return &processor.Takedown{Target: target, Provider: p.Name, Email: p.Email, Form: p.Form, API: p.API}
+}
+
+// Router is a sink annotating flagged results with their takedown route
+// before passing them on to next. A result is flagged when it is labelled
+// phishing or reverse_proxy, or scores at least highScore.
+type Router struct {
+	table     *Table
+	highScore int
+	next      sink.Sink
+}
+
+// NewRouter routes flagged results through table.
+func NewRouter(table *Table, highScore int, next sink.Sink) *Router {
+	return &Router{table: table, highScore: highScore, next: next}
+}
+
+func (r *Router) Write(o processor.Output) error {
+	if o.Live() && (o.Class == classify.LabelPhishing || o.Class == classify.LabelReverseProxy || o.Score >= r.highScore) {
+		o.Takedown = r.table.Route(o)
+	}
+	return r.next.Write(o)
+}
+
+func (r *Router) Close() error {
+	return r.next.Close()
+}
@@ -0,0 +1,92 @@
+package takedown
+
+import (
+	"reflect"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"testing"
+)
+
+func TestRoute(t *testing.T) {
+	godaddy := &verify.RDAPResult{Registrar: "GoDaddy.com, LLC", RegistrarID: "146"}
+	slow := &verify.RDAPResult{Registrar: "Gname.com Pte. Ltd.", RegistrarClass: "abuse_friendly", AbuseEmail: "complaint@gname.test"}
+	onAWS := verify.DNSResult{A: []string{"192.0.2.1"}, ASN: []verify.ASNInfo{{IP: "192.0.2.1", ASN: "16509"}}}
+	tests := []struct {
+		name string
+		o    processor.Output
+		want *processor.Takedown
+	}{
+		{
+			name: "registrar first for a parked squat",
+			o:    processor.Output{RDAP: godaddy, DNS: onAWS, Class: "parked"},
+			want: &processor.Takedown{Target: "registrar", Provider: "GoDaddy", Email: "abuse@godaddy.com", Form: "https://supportcenter.godaddy.com/AbuseReport",
+				Also: []processor.Takedown{{Target: "hosting", Provider: "Amazon Web Services", Email: "abuse@amazonaws.com", Form: "https://support.aws.amazon.com/#/contacts/report-abuse"}}},
+		},
+		{
+			name: "hosting first for live phishing",
+			o:    processor.Output{RDAP: godaddy, DNS: verify.DNSResult{CNAME: "phish.github.io"}, Class: "phishing"},
+			want: &processor.Takedown{Target: "hosting", Provider: "GitHub Pages", Form: "https://support.github.com/contact/report-abuse",
+				Also: []processor.Takedown{{Target: "registrar", Provider: "GoDaddy", Email: "abuse@godaddy.com", Form: "https://supportcenter.godaddy.com/AbuseReport"}}},
+		},
+		{
+			name: "slow registrar behind Cloudflare",
+			o:    processor.Output{RDAP: slow, DNS: verify.DNSResult{NS: []string{"kate.ns.cloudflare.com"}}},
+			want: &processor.Takedown{Target: "hosting", Provider: "Cloudflare", Form: "https://abuse.cloudflare.com/",
+				Also: []processor.Takedown{{Target: "registrar", Provider: "Gname.com Pte. Ltd.", Email: "complaint@gname.test"}}},
+		},
+		{
+			name: "unknown registrar's RDAP abuse contact",
+			o:    processor.Output{RDAP: &verify.RDAPResult{Registrar: "Example Registrar", AbuseEmail: "abuse@registrar.test"}},
+			want: &processor.Takedown{Target: "registrar", Provider: "Example Registrar", Email: "abuse@registrar.test"},
+		},
+		{name: "nothing known", o: processor.Output{DNS: verify.DNSResult{A: []string{"192.0.2.1"}}}},
+	}
+	for _, tt := range tests {
+		if got := Default.Route(tt.o); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Route() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		data    string
+		wantErr bool
+	}{
+		{`{"version":"1","registrars":[{"name":"x","names":["x"],"email":"abuse@x.test"}]}`, false},
+		{`{"registrars":[]}`, true},
+		{`{"version":"1","hosting":[{"name":"x","asns":["1"]}]}`, true},
+		{`{"version":"1","hosting":[{"asns":["1"],"form":"https://x.test/"}]}`, true},
+		{`{"version":"1","hosting":[{"name":"x","form":"https://x.test/","form_fields":{"domain":"d"}}]}`, false},
+		{`{"version":"1","hosting":[{"name":"x","form":"https://x.test/","form_fields":{"phone":"p"}}]}`, true},
+	}
+	for _, tt := range tests {
+		if _, err := Parse([]byte(tt.data)); (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+		}
+	}
+}
+
+// collect is a sink keeping what it is written.
+type collect struct{ outputs []processor.Output }
+
+func (c *collect) Write(o processor.Output) error { c.outputs = append(c.outputs, o); return nil }
+func (c *collect) Close() error                   { return nil }
+
+func TestRouter(t *testing.T) {
+	got := &collect{}
+	r := NewRouter(Default, 40, got)
+	rdap := &verify.RDAPResult{Registrar: "NameSilo, LLC"}
+	for _, o := range []processor.Output{
+		{Domain: "exampel.com", Resolvable: true, Score: 52, RDAP: rdap},
+		{Domain: "examp1e.com", Resolvable: true, Class: "phishing", RDAP: rdap},
+		{Domain: "exampie.com", Resolvable: true, Score: 10, RDAP: rdap},
+	} {
+		if err := r.Write(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got.outputs[0].Takedown == nil || got.outputs[1].Takedown == nil || got.outputs[2].Takedown != nil {
+		t.Errorf("routed = %v %v %v, want only the flagged two", got.outputs[0].Takedown, got.outputs[1].Takedown, got.outputs[2].Takedown)
+	}
+}
@@ -0,0 +1,133 @@
+package typo
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultIDNScripts are the scripts registries are known to accept in
+// internationalized labels, used by Registrable. A label on one of these
+// TLDs must be written in a single listed script (Japanese, Chinese and
+// Korean may combine theirs); on other TLDs in the IDN list any single or
+// mixed script passes, as their policies are not known here.
+var DefaultIDNScripts = map[string][]string{
+	"de": {"Latin"}, "at": {"Latin"}, "ch": {"Latin"}, "li": {"Latin"},
+	"fr": {"Latin"}, "it": {"Latin"}, "es": {"Latin"}, "pt": {"Latin"},
+	"se": {"Latin"}, "dk": {"Latin"}, "no": {"Latin"}, "fi": {"Latin"},
+	"is": {"Latin"}, "hu": {"Latin"}, "lt": {"Latin"}, "lv": {"Latin"},
+	"ee": {"Latin"}, "ca": {"Latin"}, "br": {"Latin"}, "ar": {"Latin"},
+	"cl": {"Latin"}, "pe": {"Latin"}, "mx": {"Latin"}, "vn": {"Latin"},
+	"eu": {"Latin", "Greek", "Cyrillic"},
+	"gr": {"Greek"},
+	"bg": {"Cyrillic"}, "ua": {"Cyrillic"},
+	"cn": {"Han"}, "tw": {"Han"}, "hk": {"Han"},
+	"jp": {"Han", "Hiragana", "Katakana"},
+	"kr": {"Hangul", "Han"},
+	"th": {"Thai"},
+	"il": {"Hebrew"},
+	"ir": {"Arabic"},
+}
+
+// cjkScripts are the script combinations a single-script rule allows.
+var cjkScripts = [][]string{{"Han", "Hiragana", "Katakana"}, {"Han", "Hangul"}, {"Han", "Bopomofo"}}
+
+// Registrable reports why label could not be registered under tld, or nil
+// if it could: it must survive an IDNA2008 round trip (ToASCII then
+// ToUnicode under the registration profile, which also applies the
+// hyphen, bidi and joiner rules), a non-ASCII label needs a TLD in the IDN
+// list, and on a TLD with a known script policy it must be written in one
+// of that TLD's scripts. A nil policy uses the built-in lists.
+func (p *TLDPolicy) Registrable(label, tld string) error {
+	if isLDH(label) {
+		// The common case, without the IDNA machinery: only the hyphen
+		// rules apply. Labels with "--" in the third and fourth positions
+		// are reserved for encodings like punycode's xn--.
+		if len(label) >= 4 && label[2:4] == "--" {
+			return fmt.Errorf("not valid IDNA2008: %q is reserved", label[:4])
+		}
+		return nil
+	}
+	ascii, err := idna.Registration.ToASCII(label)
+	if err != nil {
+		return fmt.Errorf("not valid IDNA2008: %w", err)
+	}
+	if back, err := idna.Registration.ToUnicode(ascii); err != nil || back != label {
+		return fmt.Errorf("does not round-trip through punycode (%s)", ascii)
+	}
+	if ascii == label {
+		return nil
+	}
+	tld = normalizeTLD(tld)
+	last := tld[strings.LastIndexByte(tld, '.')+1:]
+	if idn := p.idnTLDs(); !slices.Contains(idn, tld) && !slices.Contains(idn, last) {
+		return fmt.Errorf(".%s does not register internationalized labels", tld)
+	}
+	table := DefaultIDNScripts
+	if p != nil && p.IDNScripts != nil {
+		table = p.IDNScripts
+	}
+	allowed, known := table[tld]
+	if !known {
+		allowed, known = table[last]
+	}
+	if !known {
+		return nil
+	}
+	scripts := labelScripts(label)
+	for _, s := range scripts {
+		if !slices.Contains(allowed, s) {
+			return fmt.Errorf(".%s does not register %s labels", tld, s)
+		}
+	}
+	if len(scripts) > 1 && !slices.ContainsFunc(cjkScripts, func(combo []string) bool {
+		return !slices.ContainsFunc(scripts, func(s string) bool { return !slices.Contains(combo, s) })
+	}) {
+		return fmt.Errorf("mixes %s scripts", strings.Join(scripts, " and "))
+	}
+	return nil
+}
+
+// isLDH reports whether label is lowercase letters, digits and hyphens,
+// not starting or ending with one.
+func isLDH(label string) bool {
+	if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		if c := label[i]; !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// labelScripts lists the scripts of label's letters, sorted; digits,
+// hyphens and combining marks (Common and Inherited) belong to none.
+func labelScripts(label string) []string {
+	seen := map[string]bool{}
+	for _, r := range label {
+		if r < unicode.MaxASCII {
+			if unicode.IsLetter(r) {
+				seen["Latin"] = true
+			}
+			continue
+		}
+		for name, table := range unicode.Scripts {
+			if name != "Common" && name != "Inherited" && unicode.Is(table, r) {
+				seen[name] = true
+				break
+			}
+		}
+	}
+	scripts := make([]string, 0, len(seen))
+	for s := range seen {
+		scripts = append(scripts, s)
+	}
+	sort.Strings(scripts)
+	return scripts
+}
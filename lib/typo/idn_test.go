@@ -0,0 +1,44 @@
+package typo
+
+import "testing"
+
+func TestRegistrable(t *testing.T) {
+	tests := []struct {
+		label, tld string
+		ok         bool
+	}{
+		{"example", "com", true},
+		{"exa--mple", "com", true},
+		{"ex--ample", "com", false}, // reserved, like xn--
+		{"exаmple", "com", true},    // mixed Latin and Cyrillic: .com's policy is not known here
+		{"exаmple", "uk", false},    // .uk registers no IDNs
+		{"exаmple", "de", false},    // Cyrillic in .de
+		{"exаmple", "eu", false},    // .eu allows Cyrillic, but not mixed with Latin
+		{"ехамрӏе", "eu", true},
+		{"exämple", "de", true},
+		{"exämple", "co.at", true}, // policy of the final label
+		{"例え", "jp", true},         // Han and Hiragana
+		{"exampIe", "com", false},  // uppercase is mapped, not registered
+		{"ex‍ample", "com", false},
+		{"exam_ple", "com", false},
+	}
+	for _, tt := range tests {
+		if err := (*TLDPolicy)(nil).Registrable(tt.label, tt.tld); (err == nil) != tt.ok {
+			t.Errorf("Registrable(%q, %s) = %v, want ok %v", tt.label, tt.tld, err, tt.ok)
+		}
+	}
+
+	p := &TLDPolicy{IDNTLDs: []string{"uk"}, IDNScripts: map[string][]string{"UK": {"cyrillic"}}}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	if err := p.Registrable("ехамрӏе", "uk"); err != nil {
+		t.Errorf("Registrable() with idn_scripts = %v", err)
+	}
+	if err := p.Registrable("exämple", "uk"); err == nil {
+		t.Errorf("Registrable() accepted Latin where idn_scripts allows only Cyrillic")
+	}
+	if err := (&TLDPolicy{IDNScripts: map[string][]string{"uk": {"Klingon"}}}).Validate(); err == nil {
+		t.Errorf("Validate() accepted an unknown script")
+	}
+}
@@ -0,0 +1,193 @@
+package typo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Imported is a lookalike domain produced by an external permutation tool.
+// Unlike generated permutations, Domain already carries its TLD.
+type Imported struct {
+	Domain   string
+	Strategy string // e.g. "dnstwist:addition", "urlcrazy:character omission"
+}
+
+// ErrUnknownImportFormat is returned when a results file cannot be recognized.
+var ErrUnknownImportFormat = errorString("unrecognized import format; expected dnstwist or urlcrazy CSV/JSON, or one domain per line")
+
+// ParseImport reads a results file from dnstwist (CSV or JSON, any version)
+// or urlcrazy (CSV or JSON), or a plain list with one domain per line.
+// The format is detected from the content; the original domain rows that both
+// tools emit are dropped.
+func ParseImport(r io.Reader) ([]Imported, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var out []Imported
+	switch data[0] {
+	case '[', '{':
+		out, err = parseImportJSON(data)
+	default:
+		first, _, _ := bytes.Cut(data, []byte("\n"))
+		if bytes.ContainsRune(first, ',') {
+			out, err = parseImportCSV(data)
+		} else {
+			out, err = parseImportList(data)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var uniq []Imported
+	for _, imp := range out {
+		imp.Domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(imp.Domain), "."))
+		if imp.Domain == "" || seen[imp.Domain] {
+			continue
+		}
+		seen[imp.Domain] = true
+		uniq = append(uniq, imp)
+	}
+	return uniq, nil
+}
+
+// isOriginal reports whether a tool-specific strategy name marks the input domain.
+func isOriginal(strategy string) bool {
+	s := strings.ToLower(strings.TrimSpace(strategy))
+	return s == "*original" || s == "original"
+}
+
+func parseImportCSV(data []byte) ([]Imported, error) {
+	rd := csv.NewReader(bytes.NewReader(data))
+	rd.FieldsPerRecord = -1
+	rows, err := rd.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	domainCol, strategyCol, source := -1, -1, ""
+	for i, h := range rows[0] {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "domain", "domain-name", "domain_name":
+			domainCol, source = i, "dnstwist"
+		case "fuzzer":
+			strategyCol = i
+		case "typo":
+			domainCol, source = i, "urlcrazy"
+		case "typo type":
+			strategyCol = i
+		}
+	}
+	if domainCol < 0 {
+		return nil, ErrUnknownImportFormat
+	}
+
+	var out []Imported
+	for _, row := range rows[1:] {
+		if domainCol >= len(row) {
+			continue
+		}
+		strategy := ""
+		if strategyCol >= 0 && strategyCol < len(row) {
+			strategy = row[strategyCol]
+			if isOriginal(strategy) {
+				continue
+			}
+		}
+		out = append(out, Imported{Domain: row[domainCol], Strategy: importStrategy(source, strategy)})
+	}
+	return out, nil
+}
+
+// importRecord covers the JSON field names used across dnstwist and urlcrazy releases.
+type importRecord struct {
+	Domain     string `json:"domain"`
+	DomainName string `json:"domain-name"`
+	Name       string `json:"name"`
+	Typo       string `json:"typo"`
+	Fuzzer     string `json:"fuzzer"`
+	Type       string `json:"type"`
+	TypoType   string `json:"typo_type"`
+}
+
+func parseImportJSON(data []byte) ([]Imported, error) {
+	var records []importRecord
+	source := "dnstwist"
+	if data[0] == '{' {
+		var wrapped struct {
+			Typos   []importRecord `json:"typos"`
+			Domains []importRecord `json:"domains"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, err
+		}
+		records = append(wrapped.Typos, wrapped.Domains...)
+		if len(wrapped.Typos) > 0 {
+			source = "urlcrazy"
+		}
+	} else if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+
+	var out []Imported
+	for _, rec := range records {
+		domain := firstNonEmpty(rec.Domain, rec.DomainName, rec.Name, rec.Typo)
+		strategy := firstNonEmpty(rec.Fuzzer, rec.Type, rec.TypoType)
+		if domain == "" || isOriginal(strategy) {
+			continue
+		}
+		out = append(out, Imported{Domain: domain, Strategy: importStrategy(source, strategy)})
+	}
+	if len(records) > 0 && len(out) == 0 {
+		return nil, ErrUnknownImportFormat
+	}
+	return out, nil
+}
+
+func parseImportList(data []byte) ([]Imported, error) {
+	var out []Imported
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.ContainsAny(line, " \t") || !strings.Contains(line, ".") {
+			return nil, errors.Join(ErrUnknownImportFormat, errors.New("unexpected line: "+line))
+		}
+		out = append(out, Imported{Domain: line, Strategy: "import"})
+	}
+	return out, sc.Err()
+}
+
+func importStrategy(source, strategy string) string {
+	strategy = strings.ToLower(strings.TrimSpace(strategy))
+	if strategy == "" {
+		return source
+	}
+	return source + ":" + strategy
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package typo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImport(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Imported
+	}{
+		{
+			name: "dnstwist CSV",
+			input: "fuzzer,domain,dns_a,dns_mx\n" +
+				"*original,example.com,93.184.216.34,\n" +
+				"addition,examplea.com,203.0.113.1,mx.examplea.com\n" +
+				"tld-swap,Example.NET.,,\n",
+			want: []Imported{
+				{Domain: "examplea.com", Strategy: "dnstwist:addition"},
+				{Domain: "example.net", Strategy: "dnstwist:tld-swap"},
+			},
+		},
+		{
+			name:  "dnstwist JSON (legacy domain-name)",
+			input: `[{"fuzzer":"*original","domain-name":"example.com"},{"fuzzer":"bitsquatting","domain-name":"dxample.com","dns-a":["203.0.113.2"]}]`,
+			want:  []Imported{{Domain: "dxample.com", Strategy: "dnstwist:bitsquatting"}},
+		},
+		{
+			name: "urlcrazy CSV",
+			input: "\"Typo Type\",\"Typo\",\"DNS-A\",\"CC-A\",\"DNS-MX\",\"Extn\"\n" +
+				"\"Original\",\"example.com\",\"93.184.216.34\",\"US\",\"\",\"com\"\n" +
+				"\"Character Omission\",\"exmple.com\",\"\",\"\",\"\",\"com\"\n",
+			want: []Imported{{Domain: "exmple.com", Strategy: "urlcrazy:character omission"}},
+		},
+		{
+			name:  "urlcrazy JSON",
+			input: `{"typos":[{"type":"Character Repeat","name":"exaample.com"}]}`,
+			want:  []Imported{{Domain: "exaample.com", Strategy: "urlcrazy:character repeat"}},
+		},
+		{
+			name:  "Plain list with duplicates and comments",
+			input: "# shortlist\nexampel.com\n\nexampel.com\nexamp1e.org\n",
+			want: []Imported{
+				{Domain: "exampel.com", Strategy: "import"},
+				{Domain: "examp1e.org", Strategy: "import"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseImport(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseImport() error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseImport() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseImportUnknown(t *testing.T) {
+	_, err := ParseImport(strings.NewReader("some,random,columns\n1,2,3\n"))
+	if !errors.Is(err, ErrUnknownImportFormat) {
+		t.Errorf("ParseImport() error = %v, want ErrUnknownImportFormat", err)
+	}
+}
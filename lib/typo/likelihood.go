@@ -0,0 +1,156 @@
+package typo
+
+import "strings"
+
+// StrategyWeights is the prior probability that a permutation strategy matches
+// what attackers actually register, from common typo classes (fat-finger
+// substitutions, omissions, transpositions) down to exotic ones. Imported
+// strategies are looked up by the name after the tool prefix ("dnstwist:omission").
+var StrategyWeights = map[string]float64{
+	"omission":      1.0,
+	"transposition": 0.95,
+	"digraphswap":   0.85,
+	"replace":       0.9,
+	"repetition":    0.9,
+	"doubling":      0.9,
+	"addition":      0.8,
+	"numbersuffix":  0.6,
+	"doublehit":     0.8,
+	"homoglyph":     0.8,
+	"wholescript":   0.8,
+	"idnhomoglyph":  0.8,
+	"vowelswap":     0.75,
+	"misspelling":   0.75,
+	"hyphenation":   0.7,
+	"similar":       0.7,
+	"tldreplace":    0.7,
+	"prefix":        0.6,
+	"wordboundary":  0.6,
+	"rowslip":       0.5,
+	"subdomain":     0.5,
+	"abbreviation":  0.5,
+	"tldrepeat":     0.5,
+	"bitsquatting":  0.4,
+}
+
+// defaultStrategyWeight applies to strategies missing from StrategyWeights.
+const defaultStrategyWeight = 0.5
+
+// qwertyRows lays out the keyboard used for adjacency checks.
+var qwertyRows = []string{"1234567890-", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+var keyPos = func() map[byte][2]int {
+	pos := map[byte][2]int{}
+	for r, row := range qwertyRows {
+		for c := 0; c < len(row); c++ {
+			pos[row[c]] = [2]int{r, c}
+		}
+	}
+	return pos
+}()
+
+// adjacentKeys reports whether a and b neighbor each other on a QWERTY keyboard.
+func adjacentKeys(a, b byte) bool {
+	pa, okA := keyPos[a]
+	pb, okB := keyPos[b]
+	if !okA || !okB || a == b {
+		return false
+	}
+	dr, dc := pa[0]-pb[0], pa[1]-pb[1]
+	return dr >= -1 && dr <= 1 && dc >= -1 && dc <= 1
+}
+
+// Likelihood estimates how probable candidate is as a squat of base, in (0, 1].
+// It combines the generating strategy's weight, the edit distance between the
+// registrable labels, whether the edit is a plausible keyboard slip, and
+// whether the candidate stays on the base domain's TLD.
+func Likelihood(base, candidate, strategy string) float64 {
+	baseLabel, baseTLD := splitLast(strings.ToLower(base))
+	candLabel, candTLD := splitLast(strings.ToLower(candidate))
+
+	weight := defaultStrategyWeight
+	name := strings.ToLower(strategy)
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	if w, ok := StrategyWeights[strings.NewReplacer("-", "", " ", "", "_", "").Replace(name)]; ok {
+		weight = w
+	}
+
+	l := weight / float64(1+EditDistance(baseLabel, candLabel))
+	l *= keyboardFactor(baseLabel, candLabel)
+	if candTLD != baseTLD {
+		l *= 0.7
+	}
+	return l
+}
+
+// keyboardFactor boosts single-character edits that a finger could plausibly
+// make: substituting or inserting a key adjacent to its neighbor.
+func keyboardFactor(base, cand string) float64 {
+	switch {
+	case len(base) == len(cand):
+		var diffs []int
+		for i := 0; i < len(base); i++ {
+			if base[i] != cand[i] {
+				diffs = append(diffs, i)
+			}
+		}
+		if len(diffs) == 1 {
+			if adjacentKeys(base[diffs[0]], cand[diffs[0]]) {
+				return 1.0
+			}
+			return 0.5
+		}
+	case len(cand) == len(base)+1:
+		for i := 0; i < len(cand); i++ {
+			if cand[:i]+cand[i+1:] != base {
+				continue
+			}
+			c := cand[i]
+			if (i > 0 && (cand[i-1] == c || adjacentKeys(cand[i-1], c))) ||
+				(i+1 < len(cand) && (cand[i+1] == c || adjacentKeys(cand[i+1], c))) {
+				return 1.0
+			}
+			return 0.5
+		}
+	}
+	return 0.75
+}
+
+// EditDistance is the optimal string alignment (Damerau-Levenshtein with
+// adjacent transpositions) distance between a and b.
+func EditDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	n, m := len(ra), len(rb)
+	dp := make([][]int, n+1)
+	for i := range dp {
+		dp[i] = make([]int, m+1)
+		dp[i][0] = i
+	}
+	for j := 0; j <= m; j++ {
+		dp[0][j] = j
+	}
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			dp[i][j] = min(dp[i-1][j]+1, dp[i][j-1]+1, dp[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				dp[i][j] = min(dp[i][j], dp[i-2][j-2]+1)
+			}
+		}
+	}
+	return dp[n][m]
+}
+
+// splitLast splits a domain at its final dot into label part and TLD.
+func splitLast(domain string) (string, string) {
+	domain = strings.TrimSuffix(domain, ".")
+	if i := strings.LastIndex(domain, "."); i >= 0 {
+		return domain[:i], domain[i+1:]
+	}
+	return domain, ""
+}
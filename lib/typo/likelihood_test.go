@@ -0,0 +1,52 @@
+package typo
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"example", "example", 0},
+		{"example", "exmple", 1},
+		{"example", "exapmle", 1},
+		{"example", "examlpe", 1},
+		{"example", "exampel", 1},
+		{"example", "wxample", 1},
+		{"example", "eaxmpel", 2},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestLikelihoodOrdering(t *testing.T) {
+	// Each pair lists a more likely candidate before a less likely one.
+	tests := []struct {
+		name         string
+		more, less   string
+		moreStrategy string
+		lessStrategy string
+	}{
+		{"Adjacent key beats distant key", "wxample.com", "pxample.com", "Replace", "Replace"},
+		{"Same TLD beats TLD swap", "exmple.com", "exmple.net", "Omission", "Omission"},
+		{"Single edit beats double edit", "exmple.com", "exmpl.com", "Omission", "Omission"},
+		{"Omission beats bitsquatting", "exmple.com", "dxample.com", "Omission", "BitSquatting"},
+		{"Imported strategy names are weighted", "exmple.com", "exmple.com", "dnstwist:omission", "urlcrazy:unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			more := Likelihood("example.com", tt.more, tt.moreStrategy)
+			less := Likelihood("example.com", tt.less, tt.lessStrategy)
+			if more <= less {
+				t.Errorf("Likelihood(%s/%s) = %f, not greater than Likelihood(%s/%s) = %f",
+					tt.more, tt.moreStrategy, more, tt.less, tt.lessStrategy, less)
+			}
+		})
+	}
+}
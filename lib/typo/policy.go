@@ -0,0 +1,171 @@
+package typo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DefaultIDNTLDs are registries that accept internationalized labels, used
+// for the "idn" TLD group. Scripts accepted vary by registry; the list only
+// separates TLDs where a homoglyph could be registered at all.
+var DefaultIDNTLDs = []string{
+	"com", "net", "org", "info", "biz", "name", "mobi", "asia", "tel", "pro",
+	"app", "dev", "online", "site", "shop", "store", "xyz", "top",
+	"eu", "de", "at", "ch", "li", "fr", "it", "es", "pt", "be", "se", "no",
+	"dk", "fi", "is", "pl", "cz", "hu", "lt", "lv", "ee", "gr", "bg", "ru",
+	"su", "ua", "by", "kz", "ca", "br", "ar", "cl", "pe", "mx", "jp", "kr",
+	"cn", "tw", "hk", "in", "il", "ir", "vn", "th",
+}
+
+// TLD groups a policy rule can name besides individual TLDs.
+const (
+	GroupAll   = "*"
+	GroupIDN   = "idn"   // TLDs in the IDN list
+	GroupGTLD  = "gtld"  // generic TLDs: final label longer than two letters
+	GroupCCTLD = "cctld" // country-code TLDs: final label of two letters
+)
+
+// PolicyRule limits the named strategies ("*" for every strategy) to the
+// listed TLDs and TLD groups.
+type PolicyRule struct {
+	Strategies []string `json:"strategies"`
+	TLDs       []string `json:"tlds"`
+}
+
+// TLDPolicy is the "permutations" section of the config file: which
+// strategies are verified against which TLDs, so the candidate space
+// respects what each registry will actually register.
+//
+//	"permutations": {
+//	  "tld_policies": [
+//	    {"strategies": ["Homoglyph", "IDNHomoglyph", "WholeScript"], "tlds": ["idn"]},
+//	    {"strategies": ["*"], "tlds": ["gtld", "de", "co.uk"]}
+//	  ],
+//	  "idn_tlds": ["com", "net", "de"],
+//	  "idn_scripts": {"de": ["Latin"], "eu": ["Latin", "Greek", "Cyrillic"]}
+//	}
+//
+// A strategy named by a rule is allowed on the TLDs of the rules naming
+// it; otherwise on those of the "*" rules; with neither, everywhere.
+type TLDPolicy struct {
+	Rules      []PolicyRule        `json:"tld_policies,omitempty"`
+	IDNTLDs    []string            `json:"idn_tlds,omitempty"`    // replaces DefaultIDNTLDs
+	IDNScripts map[string][]string `json:"idn_scripts,omitempty"` // replaces DefaultIDNScripts
+}
+
+// Validate checks the policy against the default strategy names and
+// normalises its names and TLDs for Allows.
+func (p *TLDPolicy) Validate() error {
+	known := map[string]bool{}
+	for _, s := range Strategies(DefaultNumberRange) {
+		known[strings.ToLower(s.GetName())] = true
+	}
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if len(r.Strategies) == 0 || len(r.TLDs) == 0 {
+			return fmt.Errorf("tld_policies[%d]: strategies and tlds are required", i)
+		}
+		for j, s := range r.Strategies {
+			s = strings.ToLower(strings.TrimSpace(s))
+			if s != GroupAll && !known[s] {
+				return fmt.Errorf("tld_policies[%d]: unknown strategy %q", i, r.Strategies[j])
+			}
+			r.Strategies[j] = s
+		}
+		for j, tld := range r.TLDs {
+			if r.TLDs[j] = normalizeTLD(tld); r.TLDs[j] == "" {
+				return fmt.Errorf("tld_policies[%d]: empty TLD", i)
+			}
+		}
+	}
+	for i, tld := range p.IDNTLDs {
+		p.IDNTLDs[i] = normalizeTLD(tld)
+	}
+	if p.IDNScripts != nil {
+		scripts := make(map[string][]string, len(p.IDNScripts))
+		for tld, names := range p.IDNScripts {
+			for _, name := range names {
+				script := scriptName(name)
+				if script == "" {
+					return fmt.Errorf("idn_scripts[%s]: unknown script %q", tld, name)
+				}
+				scripts[normalizeTLD(tld)] = append(scripts[normalizeTLD(tld)], script)
+			}
+		}
+		p.IDNScripts = scripts
+	}
+	return nil
+}
+
+// scriptName returns the Unicode script named name, case-insensitively,
+// or "".
+func scriptName(name string) string {
+	for script := range unicode.Scripts {
+		if strings.EqualFold(script, strings.TrimSpace(name)) {
+			return script
+		}
+	}
+	return ""
+}
+
+// Allows reports whether candidates from strategy may be verified on tld.
+// A nil policy allows everything.
+func (p *TLDPolicy) Allows(strategy, tld string) bool {
+	if p == nil || len(p.Rules) == 0 {
+		return true
+	}
+	strategy, tld = strings.ToLower(strategy), normalizeTLD(tld)
+	var named, wildcard []PolicyRule
+	for _, r := range p.Rules {
+		if slices.Contains(r.Strategies, strategy) {
+			named = append(named, r)
+		} else if slices.Contains(r.Strategies, GroupAll) {
+			wildcard = append(wildcard, r)
+		}
+	}
+	rules := named
+	if len(rules) == 0 {
+		rules = wildcard
+	}
+	if len(rules) == 0 {
+		return true
+	}
+	for _, r := range rules {
+		for _, sel := range r.TLDs {
+			if p.matches(sel, tld) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *TLDPolicy) matches(sel, tld string) bool {
+	last := tld[strings.LastIndexByte(tld, '.')+1:]
+	switch sel {
+	case GroupAll:
+		return true
+	case GroupIDN:
+		idn := p.idnTLDs()
+		return slices.Contains(idn, tld) || slices.Contains(idn, last)
+	case GroupGTLD:
+		return len(last) > 2
+	case GroupCCTLD:
+		return len(last) == 2
+	}
+	return sel == tld
+}
+
+// idnTLDs is the IDN list in effect; p may be nil.
+func (p *TLDPolicy) idnTLDs() []string {
+	if p == nil || p.IDNTLDs == nil {
+		return DefaultIDNTLDs
+	}
+	return p.IDNTLDs
+}
+
+func normalizeTLD(tld string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(tld), "."))
+}
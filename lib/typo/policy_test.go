@@ -0,0 +1,38 @@
+package typo
+
+import "testing"
+
+func TestTLDPolicy(t *testing.T) {
+	p := &TLDPolicy{Rules: []PolicyRule{
+		{Strategies: []string{"Homoglyph", "WholeScript"}, TLDs: []string{"idn"}},
+		{Strategies: []string{"*"}, TLDs: []string{"gtld", ".DE", "co.uk"}},
+	}}
+	if err := p.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+	tests := []struct {
+		strategy, tld string
+		want          bool
+	}{
+		{"Homoglyph", "com", true},
+		{"Homoglyph", "uk", false}, // not in the IDN list
+		{"homoglyph", "de", true},  // names are case-insensitive
+		{"Omission", "net", true},  // gTLD
+		{"Omission", "de", true},
+		{"Omission", "co.uk", true},
+		{"Omission", "fr", false}, // ccTLD not listed
+	}
+	for _, tt := range tests {
+		if got := p.Allows(tt.strategy, tt.tld); got != tt.want {
+			t.Errorf("Allows(%s, %s) = %v, want %v", tt.strategy, tt.tld, got, tt.want)
+		}
+	}
+
+	var none *TLDPolicy
+	if !none.Allows("Homoglyph", "uk") {
+		t.Errorf("nil policy Allows() = false, want true")
+	}
+	if err := (&TLDPolicy{Rules: []PolicyRule{{Strategies: []string{"Combosquat"}, TLDs: []string{"*"}}}}).Validate(); err == nil {
+		t.Errorf("Validate() accepted an unknown strategy")
+	}
+}
@@ -0,0 +1,95 @@
+package typo
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:embed confusables.txt
+var builtinConfusables []byte
+
+// confusables maps a character to its prototype, from confusables.txt.
+var confusables = parseConfusables(builtinConfusables)
+
+// parseConfusables reads "source ; prototype ; type" lines of hex code
+// points.
+func parseConfusables(data []byte) map[rune]string {
+	table := map[rune]string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Split(line, ";")
+		if len(fields) < 2 {
+			continue
+		}
+		src, err := strconv.ParseUint(strings.TrimSpace(fields[0]), 16, 32)
+		if err != nil {
+			continue
+		}
+		var proto strings.Builder
+		for _, cp := range strings.Fields(fields[1]) {
+			r, err := strconv.ParseUint(cp, 16, 32)
+			if err != nil {
+				proto.Reset()
+				break
+			}
+			proto.WriteRune(rune(r))
+		}
+		if proto.Len() > 0 {
+			table[rune(src)] = proto.String()
+		}
+	}
+	return table
+}
+
+// Skeleton is the Unicode TR39 skeleton of s: NFD, every character
+// replaced by its confusable prototype, NFD again. Two strings with the
+// same skeleton look alike, whichever characters spell them. Punycode
+// labels are decoded first.
+func Skeleton(s string) string {
+	if u, err := idna.ToUnicode(s); err == nil {
+		s = u
+	}
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if proto, ok := confusables[r]; ok {
+			b.WriteString(proto)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(b.String())
+}
+
+// Confusable reports whether candidate's registrable label is a different
+// string with the same skeleton as base's, on any TLD: a lookalike of the
+// brand name itself, however it was generated. Comparison is
+// case-insensitive, as DNS is.
+func Confusable(base, candidate string) bool {
+	baseLabel, _ := splitLast(strings.ToLower(base))
+	candLabel, _ := splitLast(strings.ToLower(candidate))
+	baseSkel, candSkel := Skeleton(baseLabel), Skeleton(candLabel)
+	return baseSkel == candSkel && unicodeLabel(baseLabel) != unicodeLabel(candLabel)
+}
+
+// Unicode returns the Unicode form of an internationalized domain (one
+// with punycode labels), or "" when it is plain ASCII.
+func Unicode(domain string) string {
+	if u := unicodeLabel(domain); u != domain {
+		return u
+	}
+	return ""
+}
+
+func unicodeLabel(label string) string {
+	if u, err := idna.ToUnicode(label); err == nil {
+		return u
+	}
+	return label
+}
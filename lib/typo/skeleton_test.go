@@ -0,0 +1,58 @@
+package typo
+
+import "testing"
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"example", "ехаmple", true},        // Cyrillic е, х, а
+		{"apple", "аррӏе", true},            // whole-script Cyrillic
+		{"paypal", "paypa1", true},          // 1 → l
+		{"modern", "rnodern", true},         // m → rn
+		{"google", "gοοgle", true},          // Greek omicrons
+		{"example", "xn--exmple-4nf", true}, // punycode of exаmple
+		{"google", "g00gle", false},         // 0 is confusable with O, not o
+		{"example", "exampel", false},
+		{"café", "cafe", false}, // marks are kept
+	}
+	for _, tt := range tests {
+		if got := Skeleton(tt.a) == Skeleton(tt.b); got != tt.same {
+			t.Errorf("Skeleton(%q) == Skeleton(%q) is %v, want %v (%q, %q)", tt.a, tt.b, got, tt.same, Skeleton(tt.a), Skeleton(tt.b))
+		}
+	}
+}
+
+func TestConfusable(t *testing.T) {
+	tests := []struct {
+		candidate string
+		want      bool
+	}{
+		{"exаmple.com", true}, // Cyrillic а
+		{"xn--exmple-4nf.net", true},
+		{"examp1e.org", true},   // 1 → l
+		{"exannple.com", false}, // nn is not rn
+		{"exarnple.com", true},
+		{"example.net", false}, // the same label
+		{"exampel.com", false},
+	}
+	for _, tt := range tests {
+		if got := Confusable("example.com", tt.candidate); got != tt.want {
+			t.Errorf("Confusable(example.com, %q) = %v, want %v", tt.candidate, got, tt.want)
+		}
+	}
+}
+
+func TestUnicode(t *testing.T) {
+	tests := []struct{ domain, want string }{
+		{"xn--exmple-4nf.com", "exаmple.com"},
+		{"example.com", ""},
+		{"exаmple.com", ""}, // already Unicode
+	}
+	for _, tt := range tests {
+		if got := Unicode(tt.domain); got != tt.want {
+			t.Errorf("Unicode(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package typo
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"zntr.io/typogenerator/strategy"
+)
+
+// labelStrategy adapts a permutation function over the registrable label
+// into a typogenerator strategy, for the classes typogenerator lacks.
+type labelStrategy struct {
+	name string
+	fn   func(label string) []string
+}
+
+func (s labelStrategy) Generate(domain, _ string) ([]string, error) {
+	return validPermutations(domain, s.fn(domain)), nil
+}
+
+func (s labelStrategy) GetName() string { return s.name }
+
+// validPermutations drops duplicates, the label itself and anything that is
+// not a valid DNS label.
+func validPermutations(label string, perms []string) []string {
+	seen := map[string]bool{label: true}
+	var out []string
+	for _, p := range perms {
+		if seen[p] || p == "" || len(p) > 63 || strings.HasPrefix(p, "-") || strings.HasSuffix(p, "-") {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out
+}
+
+// Doubling doubles each character and collapses existing doubles: the
+// sticky-key and missed-repeat slips (gooogle, gogle for google). It is
+// kept apart from Repetition and Omission so its yield can be measured on
+// its own in the per-strategy statistics.
+var Doubling strategy.Strategy = labelStrategy{"Doubling", doubling}
+
+func doubling(label string) []string {
+	var out []string
+	for i := 0; i < len(label); i++ {
+		if label[i] == '-' {
+			continue
+		}
+		out = append(out, label[:i+1]+label[i:])
+		if i > 0 && label[i] == label[i-1] {
+			out = append(out, label[:i]+label[i+1:])
+		}
+	}
+	return out
+}
+
+// digraphConfusions are letter pairs commonly written the wrong way round
+// or for one another; each is tried in both directions.
+var digraphConfusions = [][2]string{
+	{"ie", "ei"}, {"ou", "uo"}, {"ea", "ae"}, {"ai", "ia"}, {"au", "ua"},
+	{"oi", "io"}, {"ui", "iu"}, {"oa", "ao"}, {"eu", "ue"},
+	{"ph", "f"}, {"ck", "k"}, {"ks", "x"}, {"qu", "kw"},
+}
+
+// DigraphSwap extends Transposition to the spelling confusions a single
+// adjacent swap misses: digraphs (ie/ei, ou/uo, ph/f) replaced one
+// occurrence at a time.
+var DigraphSwap strategy.Strategy = labelStrategy{"DigraphSwap", digraphSwap}
+
+func digraphSwap(label string) []string {
+	var out []string
+	for _, pair := range digraphConfusions {
+		for _, d := range [][2]string{pair, {pair[1], pair[0]}} {
+			for i := 0; ; {
+				j := strings.Index(label[i:], d[0])
+				if j < 0 {
+					break
+				}
+				at := i + j
+				out = append(out, label[:at]+d[1]+label[at+len(d[0]):])
+				i = at + 1
+			}
+		}
+	}
+	return out
+}
+
+// RowSlip shifts two consecutive characters one key left or right along
+// their keyboard rows, as when a hand lands off its home position for a
+// stroke: example -> wxample (one key) is Replace, rcample (two) is RowSlip.
+var RowSlip strategy.Strategy = labelStrategy{"RowSlip", rowSlip}
+
+func rowSlip(label string) []string {
+	var out []string
+	for i := 0; i+1 < len(label); i++ {
+		for _, dir := range []int{-1, 1} {
+			a, okA := shiftKey(label[i], dir)
+			b, okB := shiftKey(label[i+1], dir)
+			if okA && okB {
+				out = append(out, label[:i]+string([]byte{a, b})+label[i+2:])
+			}
+		}
+	}
+	return out
+}
+
+// shiftKey returns the key dir positions along c's keyboard row.
+func shiftKey(c byte, dir int) (byte, bool) {
+	pos, ok := keyPos[c]
+	if !ok {
+		return 0, false
+	}
+	row := qwertyRows[pos[0]]
+	if col := pos[1] + dir; col >= 0 && col < len(row) {
+		return row[col], true
+	}
+	return 0, false
+}
+
+//go:embed misspellings.txt
+var builtinMisspellings []byte
+
+// misspellings maps correctly spelt words to how people commonly misspell
+// them, from misspellings.txt.
+var misspellings = parseMisspellings(builtinMisspellings)
+
+// parseMisspellings reads misspelling->correct[,correct...] lines. Entries that could not appear in a DNS label, or whose word is too short
+// to be worth finding inside a brand, are dropped.
+func parseMisspellings(data []byte) map[string][]string {
+	words := map[string][]string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		wrong, right, ok := strings.Cut(strings.ToLower(line), "->")
+		if !ok || !isLetters(wrong) {
+			continue
+		}
+		for _, w := range strings.Split(right, ",") {
+			if w = strings.TrimSpace(w); len(w) >= 4 && isLetters(w) {
+				words[w] = append(words[w], wrong)
+			}
+		}
+	}
+	return words
+}
+
+func isLetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 'a' || s[i] > 'z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// Misspelling applies a dictionary of common misspellings to the words
+// found in the label (secureaccount -> secureaccout, paymentsupport ->
+// paymentsuport): realistic spelling errors that mechanical edits miss.
+var Misspelling strategy.Strategy = labelStrategy{"Misspelling", misspelling}
+
+func misspelling(label string) []string {
+	var out []string
+	for word, wrongs := range misspellings {
+		for i := 0; ; {
+			j := strings.Index(label[i:], word)
+			if j < 0 {
+				break
+			}
+			at := i + j
+			for _, w := range wrongs {
+				out = append(out, label[:at]+w+label[at+len(word):])
+			}
+			i = at + 1
+		}
+	}
+	slices.Sort(out)
+	return out
+}
+
+// abbreviationStopwords are left out of initials and never end a truncation.
+var abbreviationStopwords = map[string]bool{"of": true, "the": true, "and": true, "for": true}
+
+// Abbreviation generates the short forms of multi-word brands that
+// SMS-friendly phishing domains use: initials (bankofamerica -> boa, ba),
+// the leading words with trailing ones dropped (acme-secure-login ->
+// acme-secure, acme; never ending on a stopword like "of"), and an
+// unhyphenated label minus its last syllable (example -> exam).
+var Abbreviation strategy.Strategy = labelStrategy{"Abbreviation", abbreviation}
+
+func abbreviation(label string) []string {
+	var out []string
+	words := splitWords(label)
+	if len(words) > 1 {
+		var all, content strings.Builder
+		for _, w := range words {
+			all.WriteByte(w[0])
+			if !abbreviationStopwords[w] {
+				content.WriteByte(w[0])
+			}
+		}
+		for _, initials := range []string{all.String(), content.String()} {
+			if len(initials) >= 2 {
+				out = append(out, initials)
+			}
+		}
+		sep := ""
+		if strings.Contains(label, "-") {
+			sep = "-"
+		}
+		for k := 1; k < len(words); k++ {
+			if !abbreviationStopwords[words[k-1]] {
+				out = append(out, strings.Join(words[:k], sep))
+			}
+		}
+	}
+	if short := dropLastSyllable(label); len(short) >= 3 {
+		out = append(out, short)
+	}
+	return out
+}
+
+// dropLastSyllable approximates the label without its final syllable:
+// the last vowel group with the consonant before it, a trailing silent e,
+// or a consonant + "le" ending (exam-ple, goo-gle, micro-soft, ama-zon).
+func dropLastSyllable(label string) string {
+	if !isLetters(label) {
+		return ""
+	}
+	isVowel := func(c byte) bool { return strings.IndexByte("aeiouy", c) >= 0 }
+	var groups []int // start of each vowel group
+	for i := 0; i < len(label); i++ {
+		if isVowel(label[i]) && (i == 0 || !isVowel(label[i-1])) {
+			groups = append(groups, i)
+		}
+	}
+	n := len(label)
+	if n >= 3 && strings.HasSuffix(label, "le") && !isVowel(label[n-3]) {
+		return label[:n-3]
+	}
+	if len(groups) > 1 && label[n-1] == 'e' && groups[len(groups)-1] == n-1 {
+		groups = groups[:len(groups)-1] // silent e
+	}
+	if len(groups) < 2 {
+		return ""
+	}
+	start := groups[len(groups)-1]
+	if start > 0 && !isVowel(label[start-1]) && start-1 > groups[len(groups)-2] {
+		start--
+	}
+	return label[:start]
+}
+
+// WordBoundary permutes multi-word brands across their word boundaries:
+// word-order swaps (my-brand -> brand-my), hyphens added or dropped at a
+// boundary (mybrandapp -> mybrand-app, my-brand -> mybrand) and hyphens
+// shifted one letter (my-brand -> myb-rand).
+var WordBoundary strategy.Strategy = labelStrategy{"WordBoundary", wordBoundary}
+
+func wordBoundary(label string) []string {
+	words := splitWords(label)
+	if len(words) < 2 {
+		return nil
+	}
+	var out []string
+	for i := 0; i+1 < len(words); i++ {
+		swapped := append([]string{}, words...)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		out = append(out, strings.Join(swapped, ""), strings.Join(swapped, "-"))
+	}
+	for i := 1; i < len(words); i++ {
+		out = append(out, strings.Join(words[:i], "")+"-"+strings.Join(words[i:], ""))
+	}
+	out = append(out, strings.Join(words, ""), strings.Join(words, "-"))
+	for i := 0; i < len(label); i++ {
+		if label[i] != '-' {
+			continue
+		}
+		out = append(out, label[:i]+label[i+1:])
+		if i > 1 {
+			out = append(out, label[:i-1]+"-"+label[i-1:i]+label[i+1:])
+		}
+		if i+2 < len(label) {
+			out = append(out, label[:i]+label[i+1:i+2]+"-"+label[i+2:])
+		}
+	}
+	return out
+}
+
+// wholeScriptConfusables map Latin letters to lookalikes within one other
+// script. Digits and hyphens are common to every script.
+var wholeScriptConfusables = map[string]map[rune]rune{
+	"cyrillic": {
+		'a': 'а', 'b': 'Ь', 'c': 'с', 'd': 'ԁ', 'e': 'е', 'h': 'һ', 'i': 'і',
+		'j': 'ј', 'k': 'к', 'l': 'ӏ', 'm': 'м', 'n': 'п', 'o': 'о', 'p': 'р',
+		'q': 'ԛ', 's': 'ѕ', 't': 'т', 'w': 'ԝ', 'x': 'х', 'y': 'у',
+	},
+	"greek": {
+		'a': 'α', 'i': 'ι', 'k': 'κ', 'n': 'η', 'o': 'ο', 'p': 'ρ', 't': 'τ',
+		'u': 'υ', 'v': 'ν', 'w': 'ω', 'x': 'χ', 'y': 'γ',
+	},
+}
+
+// WholeScript spells the entire label in one non-Latin script's
+// lookalikes (аррӏе for apple, all Cyrillic), for labels whose every letter
+// has one. Registries that forbid mixed-script labels, which stops
+// single-character Homoglyph candidates, often still accept these.
+var WholeScript strategy.Strategy = labelStrategy{"WholeScript", wholeScript}
+
+func wholeScript(label string) []string {
+	var out []string
+	for _, script := range []string{"cyrillic", "greek"} {
+		table := wholeScriptConfusables[script]
+		var b strings.Builder
+		ok := true
+		for _, r := range label {
+			switch lookalike, found := table[r]; {
+			case found:
+				b.WriteRune(lookalike)
+			case r == '-' || r >= '0' && r <= '9':
+				b.WriteRune(r)
+			default:
+				ok = false
+			}
+		}
+		if ok {
+			out = append(out, b.String())
+		}
+	}
+	return out
+}
+
+// idnHomoglyphs maps each ASCII letter to its single-character Latin,
+// Greek and Cyrillic lookalikes in the confusables table, in code point
+// order.
+var idnHomoglyphs = func() map[rune][]rune {
+	table := map[rune][]rune{}
+	for r, proto := range confusables {
+		if r < unicode.MaxASCII || len(proto) != 1 || proto[0] < 'a' || proto[0] > 'z' {
+			continue
+		}
+		if unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic) {
+			table[rune(proto[0])] = append(table[rune(proto[0])], r)
+		}
+	}
+	for _, lookalikes := range table {
+		slices.Sort(lookalikes)
+	}
+	return table
+}()
+
+// IDNHomoglyph swaps one letter at a time for a Cyrillic, Greek or
+// Latin-extended lookalike (exаmple with a Cyrillic а, gοogle with a Greek
+// ο, ɡoogle with a script g), drawn from the table Skeleton uses, so every
+// candidate is Confusable with the base. Candidates are Unicode; verify
+// resolves them in their punycode form.
+var IDNHomoglyph strategy.Strategy = labelStrategy{"IDNHomoglyph", idnHomoglyph}
+
+func idnHomoglyph(label string) []string {
+	var out []string
+	for i, r := range label {
+		for _, lookalike := range idnHomoglyphs[r] {
+			out = append(out, label[:i]+string(lookalike)+label[i+utf8.RuneLen(r):])
+		}
+	}
+	return out
+}
+
+// NumberRange bounds the small numbers NumberSuffix appends.
+type NumberRange struct{ Min, Max int }
+
+// DefaultNumberRange appends 0 through 9.
+var DefaultNumberRange = NumberRange{0, 9}
+
+// maxNumberRange caps how many numbers a range may append per label.
+const maxNumberRange = 1000
+
+// ParseNumberRange parses "min-max" (e.g. 0-99) or a single number.
+func ParseNumberRange(s string) (NumberRange, error) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		hi = lo
+	}
+	var r NumberRange
+	var err error
+	if r.Min, err = strconv.Atoi(lo); err != nil {
+		return NumberRange{}, fmt.Errorf("number range %q is not min-max", s)
+	}
+	if r.Max, err = strconv.Atoi(hi); err != nil {
+		return NumberRange{}, fmt.Errorf("number range %q is not min-max", s)
+	}
+	if r.Min < 0 || r.Max < r.Min || r.Max-r.Min >= maxNumberRange {
+		return NumberRange{}, fmt.Errorf("number range %d-%d must be ascending, non-negative and span at most %d numbers", r.Min, r.Max, maxNumberRange)
+	}
+	return r, nil
+}
+
+// NumberSuffix appends the years around year (last, current and next,
+// bare and hyphenated) and the numbers in r: example2024, example-2025,
+// example1. Short-lived phishing campaigns favour these.
+func NumberSuffix(r NumberRange, year int) strategy.Strategy {
+	return labelStrategy{"NumberSuffix", func(label string) []string {
+		var out []string
+		for y := year - 1; y <= year+1; y++ {
+			out = append(out, label+strconv.Itoa(y), label+"-"+strconv.Itoa(y))
+		}
+		for n := r.Min; n <= r.Max; n++ {
+			out = append(out, label+strconv.Itoa(n))
+		}
+		return out
+	}}
+}
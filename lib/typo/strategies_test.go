@@ -0,0 +1,211 @@
+package typo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDoubling(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"google", []string{"ggoogle", "gooogle", "gogle", "googgle", "googlle", "googlee"}},
+		{"a-b", []string{"aa-b", "a-bb"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := Doubling.Generate(tt.label, "")
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Doubling.Generate() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDigraphSwap(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"receipt", []string{"reciept"}},
+		{"youtube", []string{"yuotube"}},
+		{"phone", []string{"fone"}},
+		{"abc", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got, _ := DigraphSwap.Generate(tt.label, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DigraphSwap.Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowSlip(t *testing.T) {
+	got, _ := RowSlip.Generate("ex", "")
+	if want := []string{"wz", "rc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RowSlip.Generate(ex) = %v, want %v", got, want)
+	}
+	// q has no key to its left; p none to its right.
+	if got, _ := RowSlip.Generate("qp", ""); !reflect.DeepEqual(got, []string(nil)) {
+		t.Errorf("RowSlip.Generate(qp) = %v, want none", got)
+	}
+}
+
+func TestMisspelling(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"paymentsupport", []string{"paymantsupport", "paymentsuport", "paymentsupprot", "paymnetsupport"}},
+		{"acme", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got, _ := Misspelling.Generate(tt.label, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Misspelling.Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := misspellings["whether"]; !reflect.DeepEqual(got, []string{"wether"}) {
+		t.Errorf("misspellings[whether] = %v, want [wether]", got)
+	}
+	if _, ok := misspellings["a lot"]; ok {
+		t.Errorf("misspellings kept an entry that cannot appear in a label")
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"bankofamerica", []string{"bank", "of", "america"}},
+		{"myacmeapp", []string{"my", "acme", "app"}},
+		{"acme-secure-login", []string{"acme", "secure", "login"}},
+		{"microsoft", []string{"micro", "soft"}},
+		{"google", []string{"google"}},
+		{"amazon", []string{"amazon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got := splitWords(tt.label); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitWords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbbreviation(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"bankofamerica", []string{"boa", "ba", "bank", "bankofameri"}},
+		{"acme-secure-login", []string{"asl", "acme", "acme-secure"}},
+		{"example", []string{"exam"}},
+		{"google", []string{"goo"}},
+		{"amazon", []string{"ama"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got, _ := Abbreviation.Generate(tt.label, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Abbreviation.Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordBoundary(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"my-brand", []string{"brandmy", "brand-my", "mybrand", "m-ybrand", "myb-rand"}},
+		{"mybrandapp", []string{"brandmyapp", "brand-my-app", "myappbrand", "my-app-brand", "my-brandapp", "mybrand-app", "my-brand-app"}},
+		{"google", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got, _ := WordBoundary.Generate(tt.label, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WordBoundary.Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWholeScript(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"apple", []string{"аррӏе"}},
+		{"top-10", []string{"тор-10", "τορ-10"}},
+		{"google", nil}, // no Cyrillic or Greek g
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if got, _ := WholeScript.Generate(tt.label, ""); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WholeScript.Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIDNHomoglyph(t *testing.T) {
+	tests := []struct {
+		label string
+		want  []string
+	}{
+		{"ace", []string{"ɑce", "αce", "аce", "aсe", "acе"}}, // Latin alpha, Greek alpha, Cyrillic а, с, е
+		{"zz-9", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, _ := IDNHomoglyph.Generate(tt.label, "")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IDNHomoglyph.Generate() = %v, want %v", got, tt.want)
+			}
+			for _, p := range got {
+				if !Confusable(tt.label+".com", p+".com") {
+					t.Errorf("%s is not confusable with %s", p, tt.label)
+				}
+			}
+		})
+	}
+	// Armenian lookalikes are in the table, but not among the scripts
+	// drawn from.
+	if got, _ := IDNHomoglyph.Generate("n", ""); len(got) != 0 {
+		t.Errorf("IDNHomoglyph.Generate(n) = %v, want none", got)
+	}
+}
+
+func TestNumberSuffix(t *testing.T) {
+	got, _ := NumberSuffix(NumberRange{1, 2}, 2025).Generate("example", "")
+	want := []string{"example2024", "example-2024", "example2025", "example-2025", "example2026", "example-2026", "example1", "example2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NumberSuffix.Generate() = %v, want %v", got, want)
+	}
+}
+
+func TestParseNumberRange(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    NumberRange
+		wantErr bool
+	}{
+		{"0-9", NumberRange{0, 9}, false},
+		{"7", NumberRange{7, 7}, false},
+		{"10-99", NumberRange{10, 99}, false},
+		{"9-0", NumberRange{}, true},
+		{"0-5000", NumberRange{}, true},
+		{"a-b", NumberRange{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumberRange(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseNumberRange(%q) = %v, %v, want %v, wantErr %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
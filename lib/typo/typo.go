@@ -3,6 +3,7 @@ package typo
 import (
 	"log/slog"
 	"strings"
+	"time"
 	"zntr.io/typogenerator"
 	"zntr.io/typogenerator/mapping"
 	"zntr.io/typogenerator/strategy"
@@ -20,28 +21,12 @@ func Generate(domain string, cfg []strategy.Strategy, logger slog.Logger) ([]typ
 	}
 
 	// default cfg to use
-	if cfg == nil || len(cfg) == 0 {
-		cfg = []strategy.Strategy{
-			strategy.Addition,
-			strategy.BitSquatting,
-			strategy.DoubleHit(mapping.English),
-			strategy.Homoglyph,
-			strategy.Hyphenation,
-			strategy.Omission,
-			strategy.Prefix,
-			strategy.Repetition,
-			strategy.Replace(mapping.English),
-			strategy.Similar(mapping.English),
-			strategy.SubDomain,
-			strategy.TLDRepeat,
-			strategy.TLDReplace,
-			strategy.Transposition,
-			strategy.VowelSwap,
-		}
+	if len(cfg) == 0 {
+		cfg = Strategies(DefaultNumberRange)
 	}
 
-	// TODO: Issue #15 here the strategy name is preserved.
-	// On the return pass that through to the HTTPResult object for results storing
+	// Strategy names are preserved on each result and carried through the
+	// verification queue into the output (see processor.Candidate).
 	results, err := typogenerator.Fuzz(sld, cfg...)
 	if err != nil {
 		return results, err
@@ -56,6 +41,37 @@ func Generate(domain string, cfg []strategy.Strategy, logger slog.Logger) ([]typ
 	return results, nil
 }
 
+// Strategies returns the default strategy set, with NumberSuffix appending
+// numbers from numbers around the current year.
+func Strategies(numbers NumberRange) []strategy.Strategy {
+	return []strategy.Strategy{
+		strategy.Addition,
+		strategy.BitSquatting,
+		strategy.DoubleHit(mapping.English),
+		strategy.Homoglyph,
+		strategy.Hyphenation,
+		strategy.Omission,
+		strategy.Prefix,
+		strategy.Repetition,
+		strategy.Replace(mapping.English),
+		strategy.Similar(mapping.English),
+		strategy.SubDomain,
+		strategy.TLDRepeat,
+		strategy.TLDReplace,
+		strategy.Transposition,
+		strategy.VowelSwap,
+		Doubling,
+		DigraphSwap,
+		RowSlip,
+		Misspelling,
+		Abbreviation,
+		WordBoundary,
+		WholeScript,
+		IDNHomoglyph,
+		NumberSuffix(numbers, time.Now().Year()),
+	}
+}
+
 var ErrInvalidDomain = errorString("invalid domain; expected form: <label>.<tld>")
 
 type errorString string
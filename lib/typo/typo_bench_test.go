@@ -0,0 +1,22 @@
+package typo
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+)
+
+// Run with: go test -bench=Generate -benchmem ./lib/typo/
+func BenchmarkGenerate(b *testing.B) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	for _, domain := range []string{"example.com", "internationalbusinessmachines.com"} {
+		b.Run(domain, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := Generate(domain, nil, *logger); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package typo
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"strings"
+)
+
+//go:embed words.txt
+var builtinWords []byte
+
+// dictionary is the word list splitWords recognises, from words.txt.
+var dictionary = func() map[string]bool {
+	words := map[string]bool{}
+	sc := bufio.NewScanner(bytes.NewReader(builtinWords))
+	for sc.Scan() {
+		if w := strings.TrimSpace(sc.Text()); w != "" && !strings.HasPrefix(w, "#") {
+			words[w] = true
+		}
+	}
+	return words
+}()
+
+// splitWords splits a label into the words it is made of: at hyphens, then
+// each part into dictionary words (bankofamerica -> bank of america). Runs
+// of unknown letters, usually the brand itself, become words of their own
+// (myacmeapp -> my acme app) when at least three letters long. A part that
+// cannot be split plausibly is kept whole, so "google" is not go + ogle.
+func splitWords(label string) []string {
+	var words []string
+	for _, part := range strings.Split(strings.ToLower(label), "-") {
+		if part != "" {
+			words = append(words, segment(part)...)
+		}
+	}
+	return words
+}
+
+// Segment kinds, for the adjacency rules in segment.
+const (
+	segWord  = iota // dictionary word of three letters or more
+	segShort        // one- or two-letter dictionary word
+	segBrand        // run of unknown letters
+)
+
+// shortNextToBrand are the short words allowed next to unknown letters:
+// any other ("go", "on") would split ordinary names at random.
+var shortNextToBrand = map[string]bool{"my": true}
+
+// segment splits a hyphen-free part into the fewest words, first
+// minimising unknown letters. A short word may sit next to unknown letters
+// only if it is in shortNextToBrand.
+func segment(part string) []string {
+	const unknownCost = 10 // per unknown letter; each segment costs 1
+	type state struct {
+		cost  int
+		from  int // start of the last segment
+		prev  int // kind of the segment before it
+		valid bool
+	}
+	n := len(part)
+	// best[i][k] is the cheapest split of part[:i] ending in a segment of kind k.
+	best := make([][3]state, n+1)
+	best[0][segWord] = state{valid: true, prev: -1}
+	for i := 1; i <= n; i++ {
+		for j := 0; j < i; j++ {
+			seg := part[j:i]
+			kind, cost := segBrand, unknownCost*len(seg)+1
+			switch {
+			case dictionary[seg] && len(seg) >= 3:
+				kind, cost = segWord, 1
+			case dictionary[seg]:
+				kind, cost = segShort, 1
+			case len(seg) < 3:
+				continue
+			}
+			for pk := range 3 {
+				p := best[j][pk]
+				if !p.valid {
+					continue
+				}
+				if j > 0 && !adjacentOK(pk, part[p.from:j], kind, seg) {
+					continue
+				}
+				if c := p.cost + cost; !best[i][kind].valid || c < best[i][kind].cost {
+					best[i][kind] = state{cost: c, from: j, prev: pk, valid: true}
+				}
+			}
+		}
+	}
+
+	end := -1
+	for k := range 3 {
+		if s := best[n][k]; s.valid && (end < 0 || s.cost < best[n][end].cost) {
+			end = k
+		}
+	}
+	if end < 0 {
+		return []string{part}
+	}
+	var words []string
+	for i, k := n, end; i > 0; {
+		s := best[i][k]
+		words = append([]string{part[s.from:i]}, words...)
+		i, k = s.from, s.prev
+	}
+	if len(words) > 1 && !containsWord(words) {
+		return []string{part}
+	}
+	return words
+}
+
+// adjacentOK applies segment's rules to consecutive segments a and b.
+func adjacentOK(kindA int, a string, kindB int, b string) bool {
+	switch {
+	case kindA == segBrand && kindB == segBrand:
+		return false // one run, not two
+	case kindA == segShort && kindB == segBrand:
+		return shortNextToBrand[a]
+	case kindA == segBrand && kindB == segShort:
+		return shortNextToBrand[b]
+	}
+	return true
+}
+
+// containsWord reports whether any of words is a dictionary word of three
+// letters or more: a split on short words alone is not evidence.
+func containsWord(words []string) bool {
+	for _, w := range words {
+		if dictionary[w] && len(w) >= 3 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+)
+
+// azureStore uploads block blobs with the Azure SDK. The account comes from
+// AZURE_STORAGE_ACCOUNT and is authorized by the Azure default credential
+// chain (environment, workload and managed identity, az CLI login), or by
+// AZURE_STORAGE_KEY (shared key) or AZURE_STORAGE_SAS_TOKEN when one is
+// set, the variables the az CLI reads. AZURE_STORAGE_BLOB_ENDPOINT
+// overrides https://<account>.blob.core.windows.net (e.g. for Azurite).
+type azureStore struct {
+	client    *azblob.Client
+	endpoint  string
+	container string
+}
+
+func newAzure(container string, opts *azblob.ClientOptions) (*azureStore, error) {
+	account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+	if account == "" {
+		return nil, errors.New("azblob: AZURE_STORAGE_ACCOUNT is not set")
+	}
+	s := &azureStore{endpoint: os.Getenv("AZURE_STORAGE_BLOB_ENDPOINT"), container: container}
+	if s.endpoint == "" {
+		s.endpoint = "https://" + account + ".blob.core.windows.net"
+	}
+	s.endpoint = strings.TrimSuffix(s.endpoint, "/")
+
+	var err error
+	switch key, sas := os.Getenv("AZURE_STORAGE_KEY"), os.Getenv("AZURE_STORAGE_SAS_TOKEN"); {
+	case key != "":
+		cred, kerr := azblob.NewSharedKeyCredential(account, key)
+		if kerr != nil {
+			return nil, fmt.Errorf("azblob: AZURE_STORAGE_KEY: %w", kerr)
+		}
+		s.client, err = azblob.NewClientWithSharedKeyCredential(s.endpoint+"/", cred, opts)
+	case sas != "":
+		s.client, err = azblob.NewClientWithNoCredential(s.endpoint+"/?"+strings.TrimPrefix(sas, "?"), opts)
+	default:
+		cred, cerr := azidentity.NewDefaultAzureCredential(nil)
+		if cerr != nil {
+			return nil, fmt.Errorf("azblob: %w", cerr)
+		}
+		s.client, err = azblob.NewClient(s.endpoint+"/", cred, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("azblob: %w", err)
+	}
+	return s, nil
+}
+
+func (s *azureStore) put(ctx context.Context, key string, f *os.File, _ int64, contentType string) error {
+	_, err := s.client.UploadFile(ctx, s.container, key, f, &azblob.UploadFileOptions{
+		HTTPHeaders: &blob.HTTPHeaders{BlobContentType: &contentType},
+	})
+	return err
+}
+
+func (s *azureStore) url(key string) string {
+	return s.endpoint + "/" + s.container + "/" + key
+}
@@ -0,0 +1,41 @@
+package upload
+
+import (
+	"context"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3Store uploads with the AWS default credential chain (environment,
+// shared config/profile, SSO, container and instance roles). AWS_REGION
+// and AWS_ENDPOINT_URL_S3 select the region and S3-compatible endpoints.
+type s3Store struct {
+	client *s3.Client
+	bucket string
+}
+
+func newS3(ctx context.Context, bucket string) (*s3Store, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{client: s3.NewFromConfig(cfg), bucket: bucket}, nil
+}
+
+func (s *s3Store) put(ctx context.Context, key string, f *os.File, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        aws.String(s.bucket),
+		Key:           aws.String(key),
+		Body:          f,
+		ContentLength: aws.Int64(size),
+		ContentType:   aws.String(contentType),
+	})
+	return err
+}
+
+func (s *s3Store) url(key string) string {
+	return "s3://" + s.bucket + "/" + key
+}
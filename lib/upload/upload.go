@@ -0,0 +1,87 @@
+package upload
+
+/*
+  This library copies finished result files to cloud object storage so
+  scheduled scans running in containers can persist their output without a
+  local volume. Destinations are URLs (s3://bucket/prefix,
+  gs://bucket/prefix, azblob://container/prefix); credentials come from each
+  provider's standard environment chain.
+*/
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// store puts one object into a bucket.
+type store interface {
+	put(ctx context.Context, key string, f *os.File, size int64, contentType string) error
+	url(key string) string
+}
+
+// Target is a bucket and key prefix files are uploaded under.
+type Target struct {
+	store  store
+	prefix string
+}
+
+// Open resolves dest to a bucket on S3 (s3://), Google Cloud Storage (gs://)
+// or Azure Blob Storage (azblob://) and loads the provider's credentials.
+func Open(ctx context.Context, dest string) (*Target, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("upload destination %q has no bucket", dest)
+	}
+	t := &Target{prefix: strings.Trim(u.Path, "/")}
+	switch u.Scheme {
+	case "s3":
+		t.store, err = newS3(ctx, u.Host)
+	case "gs":
+		t.store, err = newGCS(ctx, u.Host)
+	case "azblob":
+		t.store, err = newAzure(u.Host, nil)
+	default:
+		return nil, fmt.Errorf("unknown upload scheme %q; expected s3, gs or azblob", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Key joins the target's prefix and name into an object key.
+func (t *Target) Key(name string) string {
+	return path.Join(t.prefix, name)
+}
+
+// File uploads the local file at localPath as <prefix>/<dir>/<base name>
+// and returns the object's URL.
+func (t *Target) File(ctx context.Context, localPath, dir string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	key := t.Key(path.Join(dir, filepath.Base(localPath)))
+	contentType := mime.TypeByExtension(filepath.Ext(localPath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if err := t.store.put(ctx, key, f, info.Size(), contentType); err != nil {
+		return "", fmt.Errorf("uploading %s: %w", localPath, err)
+	}
+	return t.store.url(key), nil
+}
@@ -0,0 +1,138 @@
+package upload
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+func TestOpenErrors(t *testing.T) {
+	tests := []struct {
+		dest string
+	}{
+		{"ftp://bucket/prefix"},
+		{"s3:///prefix"},
+		{"results"},
+	}
+	for _, tt := range tests {
+		if _, err := Open(context.Background(), tt.dest); err == nil {
+			t.Errorf("Open(%q) error = nil, want error", tt.dest)
+		}
+	}
+}
+
+// recorded is what a fake object store saw for one upload.
+type recorded struct {
+	method, path, query, contentType, auth, body string
+	header                                       http.Header
+}
+
+func fakeStore(t *testing.T, status int) (*httptest.Server, *recorded) {
+	var got recorded
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = recorded{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), string(body), r.Header}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &got
+}
+
+func writeFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "results.json")
+	if err := os.WriteFile(path, []byte(`[{"domain":"exampel.com"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGCSFile(t *testing.T) {
+	srv, got := fakeStore(t, http.StatusOK)
+	target := &Target{store: &gcsStore{client: srv.Client(), endpoint: srv.URL, bucket: "scans"}, prefix: "squatrr"}
+
+	u, err := target.File(context.Background(), writeFile(t), "example.com/20240501T120000Z")
+	if err != nil {
+		t.Fatalf("File() error: %v", err)
+	}
+	if u != "gs://scans/squatrr/example.com/20240501T120000Z/results.json" {
+		t.Errorf("File() = %q", u)
+	}
+	if got.method != http.MethodPost || got.path != "/upload/storage/v1/b/scans/o" ||
+		got.query != "uploadType=media&name=squatrr%2Fexample.com%2F20240501T120000Z%2Fresults.json" ||
+		got.contentType != "application/json" || got.body != `[{"domain":"exampel.com"}]` {
+		t.Errorf("request = %+v", *got)
+	}
+}
+
+func TestAzureFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		key, sas  string
+		wantQuery string
+		wantAuth  string
+	}{
+		{name: "Shared key", key: "c2VjcmV0", wantAuth: "SharedKey acct:"},
+		{name: "SAS token", sas: "?sv=2021-08-06&sig=abc", wantQuery: "sig=abc&sv=2021-08-06"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, got := fakeStore(t, http.StatusCreated)
+			t.Setenv("AZURE_STORAGE_ACCOUNT", "acct")
+			t.Setenv("AZURE_STORAGE_KEY", tt.key)
+			t.Setenv("AZURE_STORAGE_SAS_TOKEN", tt.sas)
+			t.Setenv("AZURE_STORAGE_BLOB_ENDPOINT", srv.URL+"/")
+			s, err := newAzure("scans", &azblob.ClientOptions{ClientOptions: azcore.ClientOptions{Transport: srv.Client()}})
+			if err != nil {
+				t.Fatalf("newAzure() error: %v", err)
+			}
+			target := &Target{store: s}
+
+			u, err := target.File(context.Background(), writeFile(t), "run")
+			if err != nil {
+				t.Fatalf("File() error: %v", err)
+			}
+			if u != srv.URL+"/scans/run/results.json" {
+				t.Errorf("File() = %q", u)
+			}
+			if got.method != http.MethodPut || got.path != "/scans/run/results.json" || got.query != tt.wantQuery ||
+				got.header.Get("x-ms-blob-content-type") != "application/json" || got.body != `[{"domain":"exampel.com"}]` {
+				t.Errorf("request = %+v", *got)
+			}
+			if !strings.HasPrefix(got.auth, tt.wantAuth) || (tt.wantAuth == "") != (got.auth == "") {
+				t.Errorf("Authorization = %q, want %q...", got.auth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestNewAzure(t *testing.T) {
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "")
+	if _, err := newAzure("scans", nil); err == nil {
+		t.Error("newAzure() without an account succeeded")
+	}
+	// Without a key or SAS token the default credential chain is used,
+	// resolved on the first upload.
+	t.Setenv("AZURE_STORAGE_ACCOUNT", "acct")
+	t.Setenv("AZURE_STORAGE_KEY", "")
+	t.Setenv("AZURE_STORAGE_SAS_TOKEN", "")
+	t.Setenv("AZURE_STORAGE_BLOB_ENDPOINT", "")
+	s, err := newAzure("scans", nil)
+	if err != nil {
+		t.Fatalf("newAzure() error: %v", err)
+	}
+	if got := s.url("a.json"); got != "https://acct.blob.core.windows.net/scans/a.json" {
+		t.Errorf("url() = %q", got)
+	}
+	t.Setenv("AZURE_STORAGE_KEY", "not base64")
+	if _, err := newAzure("scans", nil); err == nil {
+		t.Error("newAzure() with an invalid key succeeded")
+	}
+}
@@ -0,0 +1,89 @@
+package verify
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// Archiver stores the raw HTTP exchanges of a candidate's probe as evidence
+// (lib/archive writes HAR or WARC). It returns where they were written, or
+// "" when archiving failed; implementations log their own errors.
+type Archiver interface {
+	Archive(domain string, exchanges []Exchange) string
+}
+
+// Exchange is one request/response pair exactly as the probe saw it,
+// including redirect hops and the favicon fetch.
+type Exchange struct {
+	Started  time.Time
+	Duration time.Duration
+	Method   string
+	URL      string
+	Proto    string
+	Header   http.Header // request headers set by the client
+
+	Status         string
+	StatusCode     int
+	ResponseProto  string
+	ResponseHeader http.Header
+	Body           []byte // as served (still content-encoded), up to the cap
+	BodyTruncated  bool
+	RemoteAddr     string
+	Err            string // transport error, when there was no response
+}
+
+// recorder is an http.RoundTripper that keeps a copy of every exchange. It
+// buffers each response body (up to limit) and hands the caller a replay of
+// it, so downstream body sampling is unaffected.
+type recorder struct {
+	next      http.RoundTripper
+	limit     int64
+	exchanges []Exchange
+}
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	ex := Exchange{Started: time.Now(), Method: req.Method, URL: req.URL.String(), Proto: "HTTP/1.1", Header: req.Header.Clone()}
+	trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) {
+		if host, _, err := net.SplitHostPort(info.Conn.RemoteAddr().String()); err == nil {
+			ex.RemoteAddr = host
+		}
+	}}
+	resp, err := r.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	ex.Duration = time.Since(ex.Started)
+	if err != nil {
+		ex.Err = err.Error()
+		r.exchanges = append(r.exchanges, ex)
+		return resp, err
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, r.limit+1))
+	resp.Body.Close()
+	if int64(len(body)) > r.limit {
+		ex.Body, ex.BodyTruncated = body[:r.limit], true
+	} else {
+		ex.Body = body
+	}
+	ex.Status, ex.StatusCode, ex.ResponseProto = resp.Status, resp.StatusCode, resp.Proto
+	ex.ResponseHeader = resp.Header.Clone()
+	if readErr != nil {
+		ex.Err = readErr.Error()
+	}
+	r.exchanges = append(r.exchanges, ex)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// archiveLimit is how much of each response body is kept as evidence: a
+// byte over the sampling cap, so sampling can still detect truncation.
+func (cfg Config) archiveLimit() int64 {
+	limit := cfg.MaxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	return limit + 1
+}
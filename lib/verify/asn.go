@@ -0,0 +1,101 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ASNInfo is the origin AS announcement covering a resolved IP.
+type ASNInfo struct {
+	IP       string
+	ASN      string
+	Prefix   string
+	Country  string
+	Registry string
+}
+
+// lookupASN resolves the origin ASN of each IP through Team Cymru's DNS
+// mapping service, which needs no API key and rides the normal resolver path.
+func lookupASN(ctx context.Context, resolver Resolver, ips []string) []ASNInfo {
+	var out []ASNInfo
+
+	for _, ip := range ips {
+		name, ok := cymruOriginName(ip)
+		if !ok {
+			continue
+		}
+		txts, err := resolver.LookupTXT(ctx, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return out
+			}
+			continue
+		}
+		if len(txts) == 0 {
+			continue
+		}
+		if info, ok := parseCymruOrigin(txts[0]); ok {
+			info.IP = ip
+			out = append(out, info)
+		}
+	}
+	return out
+}
+
+// cymruOriginName builds the reversed origin query name for an IPv4 or IPv6 address.
+func cymruOriginName(s string) (string, bool) {
+	rev, v4, ok := reverseIP(s)
+	if !ok {
+		return "", false
+	}
+	if v4 {
+		return rev + ".origin.asn.cymru.com", true
+	}
+	return rev + ".origin6.asn.cymru.com", true
+}
+
+// reverseIP returns the reversed label form of an address used by DNS-based
+// lookup services: octets for IPv4, nibbles for IPv6.
+func reverseIP(s string) (rev string, v4 bool, ok bool) {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return "", false, false
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d", v4[3], v4[2], v4[1], v4[0]), true, true
+	}
+
+	const hexDigits = "0123456789abcdef"
+	var b strings.Builder
+	v6 := ip.To16()
+	for i := len(v6) - 1; i >= 0; i-- {
+		if b.Len() > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteByte(hexDigits[v6[i]&0x0f])
+		b.WriteByte('.')
+		b.WriteByte(hexDigits[v6[i]>>4])
+	}
+	return b.String(), false, true
+}
+
+// parseCymruOrigin parses "13335 | 104.16.0.0/13 | US | arin | 2014-03-28".
+// Multi-origin prefixes list several ASNs in the first field; the first wins.
+func parseCymruOrigin(txt string) (ASNInfo, bool) {
+	fields := strings.Split(txt, "|")
+	if len(fields) < 4 {
+		return ASNInfo{}, false
+	}
+	asns := strings.Fields(fields[0])
+	if len(asns) == 0 {
+		return ASNInfo{}, false
+	}
+	return ASNInfo{
+		ASN:      "AS" + asns[0],
+		Prefix:   strings.TrimSpace(fields[1]),
+		Country:  strings.ToUpper(strings.TrimSpace(fields[2])),
+		Registry: strings.TrimSpace(fields[3]),
+	}, true
+}
@@ -0,0 +1,40 @@
+package verify
+
+import "testing"
+
+func TestCymruOriginName(t *testing.T) {
+	tests := []struct {
+		name   string
+		ip     string
+		want   string
+		wantOk bool
+	}{
+		{name: "IPv4", ip: "104.16.132.229", want: "229.132.16.104.origin.asn.cymru.com", wantOk: true},
+		{name: "IPv6", ip: "2606:4700::1", want: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.7.4.6.0.6.2.origin6.asn.cymru.com", wantOk: true},
+		{name: "Invalid", ip: "not-an-ip", want: "", wantOk: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := cymruOriginName(tt.ip)
+			if got != tt.want || ok != tt.wantOk {
+				t.Errorf("cymruOriginName(%q) = %q, %v, want %q, %v", tt.ip, got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestParseCymruOrigin(t *testing.T) {
+	got, ok := parseCymruOrigin("13335 14789 | 104.16.0.0/13 | us | arin | 2014-03-28")
+	if !ok {
+		t.Fatalf("parseCymruOrigin() ok = false, want true")
+	}
+	want := ASNInfo{ASN: "AS13335", Prefix: "104.16.0.0/13", Country: "US", Registry: "arin"}
+	if got != want {
+		t.Errorf("parseCymruOrigin() = %+v, want %+v", got, want)
+	}
+
+	if _, ok := parseCymruOrigin("garbage"); ok {
+		t.Errorf("parseCymruOrigin(garbage) ok = true, want false")
+	}
+}
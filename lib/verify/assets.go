@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+)
+
+// AssetMatcher recognizes the brand's own assets in fetched content: its
+// copyright lines and support numbers in pages, its logos in images.
+// lib/brand implements it.
+type AssetMatcher interface {
+	MatchPage(body []byte) []string
+	MatchImage(data []byte) []string
+	HasLogos() bool // whether the page's images are worth fetching
+	Digest() string // changes with the assets, keying cached results
+}
+
+const (
+	// maxLogoImages caps the logo images fetched per page, besides the
+	// favicon.
+	maxLogoImages = 4
+	// maxImageBytes caps each image download fed to Config.BrandAssets.
+	maxImageBytes = 512 << 10
+)
+
+var imgTagRe = regexp.MustCompile(`(?is)<img\s[^>]*>`)
+var srcRe = regexp.MustCompile(`(?is)\ssrc\s*=\s*["']?([^"'\s>]+)`)
+
+// logoURLs resolves the page's images that present themselves as logos (an
+// <img> mentioning "logo" in its source, alt text, class or id), at most
+// maxLogoImages of them.
+func logoURLs(base *url.URL, body []byte) []string {
+	var out []string
+	for _, tag := range imgTagRe.FindAll(body, -1) {
+		if !bytes.Contains(bytes.ToLower(tag), []byte("logo")) {
+			continue
+		}
+		m := srcRe.FindSubmatch(tag)
+		if m == nil {
+			continue
+		}
+		u, err := base.Parse(string(m[1]))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || slices.Contains(out, u.String()) {
+			continue
+		}
+		if out = append(out, u.String()); len(out) == maxLogoImages {
+			break
+		}
+	}
+	return out
+}
+
+// matchLogos downloads the favicon and the page's logo images with the
+// probe client and matches them against Config.BrandAssets.
+func matchLogos(ctx context.Context, client *http.Client, res *HTTPResult, cfg Config) {
+	if cfg.BrandAssets == nil || !cfg.BrandAssets.HasLogos() {
+		return
+	}
+	urls := res.LogoURLs
+	if res.FaviconURL != "" {
+		urls = append([]string{res.FaviconURL}, urls...)
+	}
+	for _, u := range urls {
+		if data := fetchAsset(ctx, client, u, cfg, maxImageBytes); data != nil {
+			res.BrandAssets = addAssets(res.BrandAssets, cfg.BrandAssets.MatchImage(data))
+		}
+	}
+}
+
+// addAssets appends the matches not already in assets.
+func addAssets(assets, matches []string) []string {
+	for _, m := range matches {
+		if !slices.Contains(assets, m) {
+			assets = append(assets, m)
+		}
+	}
+	return assets
+}
+
+// fetchAsset GETs a page resource with the probe client, returning at most
+// limit bytes of a 200 response, or nil.
+func fetchAsset(ctx context.Context, client *http.Client, rawURL string, cfg Config, limit int64) []byte {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(io.LimitReader(resp.Body, limit)); err != nil || buf.Len() == 0 {
+		return nil
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,82 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogoURLs(t *testing.T) {
+	base, _ := url.Parse("https://examp1e.com/login/")
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"by source", `<img src="/static/acme-logo.svg"><img src="hero.jpg">`, []string{"https://examp1e.com/static/acme-logo.svg"}},
+		{"by alt and class", `<IMG class="site-Logo" SRC='img/a.png'><img alt="Acme logo" src=b.png>`,
+			[]string{"https://examp1e.com/login/img/a.png", "https://examp1e.com/login/b.png"}},
+		{"once each", `<img src="logo.png"><img alt="logo" src="logo.png">`, []string{"https://examp1e.com/login/logo.png"}},
+		{"capped", `<img src="logo.png?1"><img src="logo2.png"><img src="logo3.png"><img src="logo4.png"><img src="logo5.png">`,
+			[]string{"https://examp1e.com/login/logo.png?1", "https://examp1e.com/login/logo2.png", "https://examp1e.com/login/logo3.png", "https://examp1e.com/login/logo4.png"}},
+		{"data uri", `<img class="logo" src="data:image/png;base64,AAAA">`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logoURLs(base, []byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("logoURLs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeAssets matches pages mentioning "Acme Corp" and images reading "LOGO".
+type fakeAssets struct{}
+
+func (fakeAssets) MatchPage(body []byte) []string {
+	if strings.Contains(string(body), "Acme Corp") {
+		return []string{"copyright:Acme Corp"}
+	}
+	return nil
+}
+
+func (fakeAssets) MatchImage(data []byte) []string {
+	if string(data) == "LOGO" {
+		return []string{"logo:acme"}
+	}
+	return nil
+}
+
+func (fakeAssets) HasLogos() bool { return true }
+
+func (fakeAssets) Digest() string { return "fake" }
+
+func TestFetchHTTPBrandAssets(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<img class="logo" src="/a.png"><img src="/logo-b.png"> &copy; Acme Corp`)
+		case "/a.png", "/favicon.ico":
+			fmt.Fprint(w, "LOGO")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := Config{FetchBody: true, HTTPTimeout: 2 * time.Second, BrandAssets: fakeAssets{}}
+	res := fetchHTTP(context.Background(), false, strings.TrimPrefix(srv.URL, "http://"), cfg)
+	if want := []string{"copyright:Acme Corp", "logo:acme"}; !reflect.DeepEqual(res.BrandAssets, want) {
+		t.Errorf("BrandAssets = %q, want %q", res.BrandAssets, want)
+	}
+	if len(res.LogoURLs) != 2 {
+		t.Errorf("LogoURLs = %q", res.LogoURLs)
+	}
+}
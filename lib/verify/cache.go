@@ -0,0 +1,92 @@
+package verify
+
+import (
+	"slices"
+	"strings"
+)
+
+// Verification stages that can be served from a Cache.
+const (
+	StageDNS   = "dns"
+	StageTLS   = "tls"
+	StageHTTP  = "http"
+	StageRDAP  = "rdap"
+	StageCT    = "ct"
+	StagePDNS  = "pdns"
+	StageDNSBL = "dnsbl"
+
+	// StageNegative remembers names that did not exist until their
+	// negative TTL expires, so monitoring scans skip them.
+	StageNegative = "negative"
+)
+
+// Cache persists per-stage verification results across runs, keyed by the
+// ASCII domain. Implementations own their TTL policy.
+type Cache interface {
+	// Get decodes the entry for stage/domain into v and reports whether an
+	// entry existed and whether it is still within its TTL.
+	Get(stage, domain string, v any) (found, fresh bool)
+	Put(stage, domain string, v any)
+}
+
+func (cfg Config) cacheGet(stage, domain string, v any) (found, fresh bool) {
+	if cfg.Cache == nil {
+		return false, false
+	}
+	return cfg.Cache.Get(stage, domain, v)
+}
+
+func (cfg Config) cachePut(stage, domain string, v any) {
+	if cfg.Cache != nil {
+		cfg.Cache.Put(stage, domain, v)
+	}
+}
+
+// httpCacheStage keys HTTP results by the options that change their shape, so
+// a HEAD-only result is never served to a run that wants body fingerprints.
+func (cfg Config) httpCacheStage() string {
+	stage := StageHTTP
+	if cfg.FetchBody {
+		stage += "+body"
+	}
+	if cfg.HTTPFollowRedirects {
+		stage += "+follow"
+	}
+	if cfg.CrossProtocol {
+		stage += "+cross"
+	}
+	if len(cfg.BrandCookies) > 0 {
+		stage += "+cookies"
+	}
+	if cfg.Rules != nil {
+		stage += "+rules"
+	}
+	if cfg.Scanner != nil && cfg.FetchBody {
+		stage += "+scan"
+	}
+	if cfg.BrandAssets != nil && cfg.FetchBody {
+		// Results matched against other assets are stale.
+		stage += "+assets-" + cfg.BrandAssets.Digest()
+	}
+	if cfg.Base != nil && cfg.FetchBody {
+		stage += "+base"
+	}
+	return stage
+}
+
+// sameInfrastructure reports whether two DNS observations point at the same
+// hosting, ignoring answer ordering from round-robin resolvers.
+func sameInfrastructure(a, b DNSResult) bool {
+	return sameSet(a.A, b.A) && sameSet(a.AAAA, b.AAAA) && strings.EqualFold(a.CNAME, b.CNAME) &&
+		sameSet(a.MX, b.MX) && sameSet(a.NS, b.NS)
+}
+
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
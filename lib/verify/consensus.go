@@ -0,0 +1,71 @@
+package verify
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// DNSConsensus is a candidate's answer from Config.ConsensusResolver, an
+// independent second resolver, compared with the primary one. Corporate
+// DNS filtering can hide a live squat from a scan run behind it; the
+// second opinion shows it.
+type DNSConsensus struct {
+	Source string   `json:",omitempty"`
+	A      []string `json:",omitempty"`
+	AAAA   []string `json:",omitempty"`
+	CNAME  string   `json:",omitempty"`
+	// Differences names where the two answers disagree: "exists" (one
+	// resolves the name, the other doesn't), "a", "aaaa" and "cname".
+	// Addresses can legitimately differ behind GeoDNS and CDNs.
+	Differences []string `json:",omitempty"`
+	// Filtered is set when the primary resolver returned nothing, or only
+	// sinkhole addresses (unspecified, loopback, private), for a name the
+	// consensus resolver resolves to public addresses.
+	Filtered bool        `json:",omitempty"`
+	Error    *StageError `json:",omitempty"` // the consensus lookups failed; nothing was compared
+}
+
+// checkConsensus resolves domain through cfg.ConsensusResolver and compares
+// the answer with the primary one.
+func checkConsensus(ctx context.Context, domain string, primary DNSResult, cfg Config) *DNSConsensus {
+	ctx, cancel := context.WithTimeout(ctx, cfg.DNSTimeout)
+	defer cancel()
+	ctx, src := withAnswerSource(ctx)
+
+	r, err := lookupDNS(ctx, cfg.ConsensusResolver, domain)
+	c := &DNSConsensus{Source: sourceOf(cfg.ConsensusResolver, src), A: r.A, AAAA: r.AAAA, CNAME: r.CNAME}
+	if err != nil && !isNegative(r, err) {
+		c.Error = NewStageError(StageDNS, err)
+		return c
+	}
+	resolves := func(d DNSResult) bool { return d.HasA || d.HasAAAA || d.HasCNAME }
+	diff := func(field string, differ bool) {
+		if differ {
+			c.Differences = append(c.Differences, field)
+		}
+	}
+	diff("exists", resolves(primary) != resolves(r))
+	diff("a", !sameSet(primary.A, r.A))
+	diff("aaaa", !sameSet(primary.AAAA, r.AAAA))
+	diff("cname", !strings.EqualFold(primary.CNAME, r.CNAME))
+	c.Filtered = sinkholed(primary) && !sinkholed(r)
+	return c
+}
+
+// hides reports whether the primary resolver hid the candidate, per c.
+func (c *DNSConsensus) hides() bool {
+	return c != nil && c.Filtered
+}
+
+// sinkholed reports whether d has no usable address: none at all, or only
+// addresses a filtering resolver answers with instead of the real ones.
+func sinkholed(d DNSResult) bool {
+	for _, s := range append(append([]string{}, d.A...), d.AAAA...) {
+		ip := net.ParseIP(s)
+		if ip != nil && !ip.IsUnspecified() && !ip.IsLoopback() && !ip.IsPrivate() {
+			return false
+		}
+	}
+	return true
+}
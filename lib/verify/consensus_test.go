@@ -0,0 +1,53 @@
+package verify
+
+import (
+	"context"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"testing"
+	"time"
+)
+
+func TestVerifyDomainConsensus(t *testing.T) {
+	public := verifytest.Zone{
+		"com":          {SOA: time.Hour},
+		"exampel.com":  {A: []string{"192.0.2.10"}},
+		"examp1e.com":  {A: []string{"198.51.100.7"}},
+		"cdn-typo.com": {A: []string{"203.0.113.1"}},
+	}
+	// The corporate resolver blocks one candidate outright, sinkholes
+	// another and is steered to a different CDN edge for the third.
+	corporate := verifytest.Zone{
+		"com":          {SOA: time.Hour},
+		"examp1e.com":  {A: []string{"10.0.0.1"}},
+		"cdn-typo.com": {A: []string{"203.0.113.2"}},
+	}
+	c := mapCache{}
+	cfg := Config{Resolver: verifytest.NewResolver(corporate), ConsensusResolver: verifytest.NewResolver(public), Cache: c}
+
+	tests := []struct {
+		domain     string
+		resolvable bool
+		want       DNSConsensus
+	}{
+		{"exampel.com", true, DNSConsensus{A: []string{"192.0.2.10"}, Differences: []string{"exists", "a"}, Filtered: true}},
+		{"examp1e.com", true, DNSConsensus{A: []string{"198.51.100.7"}, Differences: []string{"a"}, Filtered: true}},
+		{"cdn-typo.com", true, DNSConsensus{A: []string{"203.0.113.1"}, Differences: []string{"a"}}},
+		{"unregistered.com", false, DNSConsensus{}},
+	}
+	for _, tt := range tests {
+		v, err := VerifyDomain(context.Background(), tt.domain, cfg)
+		if err != nil || v.Resolvable != tt.resolvable || v.DNS.Consensus == nil || !reflect.DeepEqual(*v.DNS.Consensus, tt.want) {
+			t.Errorf("VerifyDomain(%s) = %v %+v, %v, want resolvable %v and %+v", tt.domain, v.Resolvable, v.DNS.Consensus, err, tt.resolvable, tt.want)
+		}
+	}
+
+	// Only the name both resolvers agree is missing is cached as negative.
+	var neg negativeEntry
+	if found, _ := c.Get(StageNegative, "exampel.com", &neg); found {
+		t.Errorf("name hidden by the primary resolver cached as negative")
+	}
+	if found, _ := c.Get(StageNegative, "unregistered.com", &neg); !found {
+		t.Errorf("name missing from both resolvers not cached as negative")
+	}
+}
@@ -0,0 +1,48 @@
+package verify
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+)
+
+// BodyInspector derives labelled signals (e.g. "parked:bodis") from a sampled
+// response body. It lets callers plug in content signatures without verify
+// knowing about them.
+type BodyInspector interface {
+	Inspect(body []byte) []string
+}
+
+// ResponseRules evaluates user-defined detections against a probed response.
+// body is nil for HEAD requests or skipped bodies. lib/rules implements it.
+type ResponseRules interface {
+	Match(status int, header http.Header, body []byte) []RuleMatch
+}
+
+// RuleMatch is a user content rule that matched a response.
+type RuleMatch struct {
+	Rule     string
+	Severity string
+}
+
+var (
+	passwordFieldRe = regexp.MustCompile(`(?i)<input[^>]+type\s*=\s*["']?password`)
+	formActionRe    = regexp.MustCompile(`(?i)<form[^>]+action\s*=\s*["']?(https?:)?//`)
+	loginWordRe     = regexp.MustCompile(`(?i)\b(sign[ -]?in|log[ -]?in|verify your account|password)\b`)
+)
+
+// contentSignals returns the built-in page signals used for landing-page
+// classification: credential fields, forms posting off-site, and login copy.
+func contentSignals(body []byte) []string {
+	var out []string
+	if passwordFieldRe.Match(body) {
+		out = append(out, "password_field")
+	}
+	if formActionRe.Match(body) {
+		out = append(out, "external_form")
+	}
+	if bytes.Contains(bytes.ToLower(body), []byte("<form")) && loginWordRe.Match(body) {
+		out = append(out, "login_form")
+	}
+	return out
+}
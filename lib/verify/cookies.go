@@ -0,0 +1,66 @@
+package verify
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Cookie is a cookie a candidate set, without its value: names and
+// attributes identify the application or phishing kit behind a page.
+type Cookie struct {
+	Name     string
+	Domain   string `json:",omitempty"`
+	Path     string `json:",omitempty"`
+	Secure   bool
+	HttpOnly bool
+	SameSite string `json:",omitempty"` // "lax", "strict" or "none"
+	Session  bool   // no Expires or Max-Age: dropped when the browser closes
+}
+
+// newCookie records c's name and attributes.
+func newCookie(c *http.Cookie) Cookie {
+	out := Cookie{Name: c.Name, Domain: c.Domain, Path: c.Path, Secure: c.Secure, HttpOnly: c.HttpOnly,
+		Session: c.Expires.IsZero() && c.MaxAge == 0}
+	switch c.SameSite {
+	case http.SameSiteLaxMode:
+		out.SameSite = "lax"
+	case http.SameSiteStrictMode:
+		out.SameSite = "strict"
+	case http.SameSiteNoneMode:
+		out.SameSite = "none"
+	}
+	return out
+}
+
+// addCookies appends the cookies resp sets to cookies, once per name,
+// domain and path.
+func addCookies(cookies []Cookie, resp *http.Response) []Cookie {
+next:
+	for _, c := range resp.Cookies() {
+		nc := newCookie(c)
+		for _, have := range cookies {
+			if have.Name == nc.Name && have.Domain == nc.Domain && have.Path == nc.Path {
+				continue next
+			}
+		}
+		cookies = append(cookies, nc)
+	}
+	return cookies
+}
+
+// brandCookies returns the names in cookies matching the brand's own cookie
+// names, case-insensitively; a pattern ending in "*" matches by prefix.
+func brandCookies(cookies []Cookie, patterns []string) []string {
+	var out []string
+	for _, c := range cookies {
+		name := strings.ToLower(c.Name)
+		for _, p := range patterns {
+			p = strings.ToLower(p)
+			if prefix, ok := strings.CutSuffix(p, "*"); (ok && strings.HasPrefix(name, prefix)) || name == p {
+				out = append(out, c.Name)
+				break
+			}
+		}
+	}
+	return out
+}
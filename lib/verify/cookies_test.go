@@ -0,0 +1,38 @@
+package verify
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFetchHTTPCookies(t *testing.T) {
+	// A kit redirecting to its login page, setting cookies on both hops.
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}
+		if r.URL.Path == "/" {
+			resp.StatusCode = http.StatusFound
+			resp.Header.Set("Location", "/signin")
+			resp.Header.Add("Set-Cookie", "visitor=abc; Path=/; Max-Age=86400")
+			return resp, nil
+		}
+		resp.Header.Add("Set-Cookie", "ACMESESSID_2=secret; Path=/; Secure; HttpOnly; SameSite=Lax")
+		resp.Header.Add("Set-Cookie", "visitor=abc; Path=/; Max-Age=86400")
+		return resp, nil
+	})
+	cfg := Config{HTTPTimeout: time.Second, HTTPFollowRedirects: true, Transport: transport, BrandCookies: []string{"acmesessid*", "JSESSIONID"}}
+	res := fetchHTTP(context.Background(), true, "exampel.com", cfg)
+
+	want := []Cookie{
+		{Name: "visitor", Path: "/"},
+		{Name: "ACMESESSID_2", Path: "/", Secure: true, HttpOnly: true, SameSite: "lax", Session: true},
+	}
+	if !reflect.DeepEqual(res.Cookies, want) {
+		t.Errorf("Cookies = %+v, want %+v", res.Cookies, want)
+	}
+	if !reflect.DeepEqual(res.BrandCookies, []string{"ACMESESSID_2"}) {
+		t.Errorf("BrandCookies = %v, want [ACMESESSID_2]", res.BrandCookies)
+	}
+}
@@ -0,0 +1,83 @@
+package verify
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// CrossProtocol compares a candidate's plain-HTTP response with its HTTPS
+// one. Squatters often serve phishing only over plain HTTP, where no
+// certificate lands in Certificate Transparency.
+type CrossProtocol struct {
+	Status     int
+	Location   string `json:",omitempty"`
+	FinalURL   string `json:",omitempty"`
+	Title      string `json:",omitempty"`
+	BodySHA256 string `json:",omitempty"`
+	// Upgrades is set when plain HTTP just redirects to HTTPS on the same
+	// host, the consistent setup.
+	Upgrades bool
+	// Differences names what plain HTTP serves differently: "error",
+	// "status", "location", "final_url", "title" and "body".
+	Differences []string `json:",omitempty"`
+}
+
+// crossProtocol probes plain HTTP for a candidate whose HTTPS probe res
+// answered, and compares the two.
+func crossProtocol(ctx context.Context, domain string, res HTTPResult, cfg Config) *CrossProtocol {
+	cfg.CrossProtocol, cfg.Archive, cfg.prevHTTP = false, nil, nil
+	plain := fetchHTTP(ctx, false, domain, cfg)
+	cp := &CrossProtocol{
+		Status:     plain.StatusCode,
+		Location:   plain.Location,
+		FinalURL:   plain.FinalURL,
+		Title:      plain.Title,
+		BodySHA256: plain.BodySHA256,
+	}
+	if plain.Error != nil {
+		cp.Differences = []string{"error"}
+		return cp
+	}
+	if plain.StatusCode >= 300 && plain.StatusCode < 400 && upgradesTo(plain.Location, domain) {
+		cp.Upgrades = true
+		if !cfg.HTTPFollowRedirects {
+			return cp
+		}
+	}
+	diff := func(field, a, b string) {
+		if a != b {
+			cp.Differences = append(cp.Differences, field)
+		}
+	}
+	if !cp.Upgrades {
+		diff("status", strconv.Itoa(plain.StatusCode), strconv.Itoa(res.StatusCode))
+		diff("location", plain.Location, res.Location)
+	}
+	diff("final_url", schemeless(plain.FinalURL), schemeless(res.FinalURL))
+	diff("title", plain.Title, res.Title)
+	diff("body", plain.BodySHA256, res.BodySHA256)
+	return cp
+}
+
+// upgradesTo reports whether location is an HTTPS URL on domain or its www
+// host.
+func upgradesTo(location, domain string) bool {
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == domain || host == "www."+domain
+}
+
+// schemeless drops a URL's scheme, so the same landing page reached over
+// either protocol compares equal.
+func schemeless(u string) string {
+	_, rest, ok := strings.Cut(u, "://")
+	if !ok {
+		return u
+	}
+	return rest
+}
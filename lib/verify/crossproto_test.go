@@ -0,0 +1,85 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// page answers a probe with status, an optional Location and an HTML title.
+func page(r *http.Request, status int, location, title string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{"Content-Type": {"text/html"}}, Request: r,
+		Body: io.NopCloser(strings.NewReader("<html><title>" + title + "</title></html>"))}
+	if location != "" {
+		resp.Header.Set("Location", location)
+	}
+	return resp
+}
+
+func TestCrossProtocol(t *testing.T) {
+	tests := []struct {
+		name        string
+		plain       func(r *http.Request) (*http.Response, error)
+		wantUpgrade bool
+		wantDiff    []string
+	}{
+		{
+			name: "upgrade to https",
+			plain: func(r *http.Request) (*http.Response, error) {
+				return page(r, http.StatusMovedPermanently, "https://exampel.com/", ""), nil
+			},
+			wantUpgrade: true,
+		},
+		{
+			name: "same site both ways",
+			plain: func(r *http.Request) (*http.Response, error) {
+				return page(r, http.StatusOK, "", "Parked"), nil
+			},
+		},
+		{
+			name: "phishing only on plain http",
+			plain: func(r *http.Request) (*http.Response, error) {
+				return page(r, http.StatusOK, "", "Sign in to your account"), nil
+			},
+			wantDiff: []string{"title", "body"},
+		},
+		{
+			name: "plain http redirects elsewhere",
+			plain: func(r *http.Request) (*http.Response, error) {
+				return page(r, http.StatusFound, "http://login-exampel.test/", ""), nil
+			},
+			wantDiff: []string{"status", "location", "title", "body"},
+		},
+		{
+			name: "plain http refused",
+			plain: func(r *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			},
+			wantDiff: []string{"error"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				if r.URL.Scheme == "https" {
+					return page(r, http.StatusOK, "", "Parked"), nil
+				}
+				return tt.plain(r)
+			})
+			cfg := Config{HTTPTimeout: time.Second, FetchBody: true, CrossProtocol: true, Transport: transport}
+			res := fetchHTTP(context.Background(), true, "exampel.com", cfg)
+			cp := res.CrossProtocol
+			if cp == nil {
+				t.Fatalf("CrossProtocol = nil, want a comparison")
+			}
+			if cp.Upgrades != tt.wantUpgrade || !reflect.DeepEqual(cp.Differences, tt.wantDiff) {
+				t.Errorf("CrossProtocol = %+v, want upgrade %v and differences %v", cp, tt.wantUpgrade, tt.wantDiff)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultCTBase is the crt.sh Certificate Transparency search used when
+// Config.CTBase is empty.
+const DefaultCTBase = "https://crt.sh"
+
+// maxCTNames caps how many distinct certificate names are kept per candidate.
+const maxCTNames = 50
+
+// CTResult summarises the certificates logged for a candidate and its
+// subdomains. It is gathered from a CT search service, never the candidate.
+type CTResult struct {
+	Attempted    bool
+	Certificates int
+	FirstSeen    time.Time // earliest not_before
+	LastSeen     time.Time // latest not_before
+	Issuers      []string
+	Names        []string    // distinct names on the certificates (capped)
+	Error        *StageError `json:",omitempty"` // why crt.sh gave no answer; not cached
+}
+
+type crtshEntry struct {
+	IssuerName string `json:"issuer_name"`
+	NameValue  string `json:"name_value"`
+	NotBefore  string `json:"not_before"`
+}
+
+// lookupCT searches crt.sh for certificates covering domain or its subdomains.
+func lookupCT(ctx context.Context, domain string, cfg Config) CTResult {
+	res := CTResult{Attempted: true}
+	base := cfg.CTBase
+	if base == "" {
+		base = DefaultCTBase
+	}
+	q := url.Values{"q": {"%." + domain}, "output": {"json"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(base, "/")+"/?"+q.Encode(), nil)
+	if err != nil {
+		res.Error = NewStageError(StageCT, err)
+		return res
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		res.Error = NewStageError(StageCT, err)
+		return res
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		res.Error = NewStageError(StageCT, fmt.Errorf("crt.sh: %s", resp.Status))
+		return res
+	}
+	var entries []crtshEntry
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 8<<20)).Decode(&entries); err != nil {
+		res.Error = NewStageError(StageCT, err)
+		return res
+	}
+	res.apply(entries)
+	return res
+}
+
+func (res *CTResult) apply(entries []crtshEntry) {
+	res.Certificates = len(entries)
+	for _, e := range entries {
+		if t, err := time.Parse("2006-01-02T15:04:05", e.NotBefore); err == nil {
+			if res.FirstSeen.IsZero() || t.Before(res.FirstSeen) {
+				res.FirstSeen = t
+			}
+			if t.After(res.LastSeen) {
+				res.LastSeen = t
+			}
+		}
+		if e.IssuerName != "" && !slices.Contains(res.Issuers, e.IssuerName) {
+			res.Issuers = append(res.Issuers, e.IssuerName)
+		}
+		for _, n := range strings.Split(e.NameValue, "\n") {
+			n = strings.ToLower(strings.TrimSpace(n))
+			if n != "" && len(res.Names) < maxCTNames && !slices.Contains(res.Names, n) {
+				res.Names = append(res.Names, n)
+			}
+		}
+	}
+	slices.Sort(res.Names)
+}
+
+// PDNSRecord is one passive DNS observation in Passive DNS Common Output
+// Format (as served by CIRCL and compatible providers).
+type PDNSRecord struct {
+	RRName    string `json:"rrname"`
+	RRType    string `json:"rrtype"`
+	RData     string `json:"rdata"`
+	TimeFirst int64  `json:"time_first"`
+	TimeLast  int64  `json:"time_last"`
+	Count     int    `json:"count,omitempty"`
+}
+
+// PDNSResult is the passive DNS history for a candidate.
+type PDNSResult struct {
+	Attempted bool
+	Records   []PDNSRecord
+	Error     *StageError `json:",omitempty"` // why the service gave no answer; not cached
+}
+
+// maxPDNSRecords caps how much history is kept per candidate.
+const maxPDNSRecords = 100
+
+// lookupPDNS queries a Common Output Format passive DNS service at
+// <PDNSURL>/<domain>, which answers with one JSON record per line.
+func lookupPDNS(ctx context.Context, domain string, cfg Config) PDNSResult {
+	res := PDNSResult{Attempted: true}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cfg.PDNSURL, "/")+"/"+domain, nil)
+	if err != nil {
+		res.Error = NewStageError(StagePDNS, err)
+		return res
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	req.Header.Set("Accept", "application/x-ndjson")
+	if cfg.PDNSUser != "" || cfg.PDNSKey != "" {
+		req.SetBasicAuth(cfg.PDNSUser, cfg.PDNSKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		res.Error = NewStageError(StagePDNS, err)
+		return res
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		res.Error = NewStageError(StagePDNS, fmt.Errorf("passive DNS: %s", resp.Status))
+		return res
+	}
+	dec := json.NewDecoder(io.LimitReader(resp.Body, 8<<20))
+	for len(res.Records) < maxPDNSRecords {
+		var r PDNSRecord
+		if err := dec.Decode(&r); err != nil {
+			if err != io.EOF {
+				res.Error = NewStageError(StagePDNS, err)
+			}
+			break
+		}
+		res.Records = append(res.Records, r)
+	}
+	return res
+}
@@ -0,0 +1,90 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"testing"
+	"time"
+)
+
+func TestLookupCT(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("q") != "%.exampel.com" || r.URL.Query().Get("output") != "json" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `[
+			{"issuer_name": "C=US, O=Let's Encrypt, CN=R3", "name_value": "exampel.com\nwww.exampel.com", "not_before": "2024-05-01T00:00:00"},
+			{"issuer_name": "C=US, O=Let's Encrypt, CN=R3", "name_value": "login.exampel.com", "not_before": "2024-06-01T12:00:00"}
+		]`)
+	}))
+	defer srv.Close()
+
+	got := lookupCT(context.Background(), "exampel.com", Config{CTBase: srv.URL})
+	want := CTResult{
+		Attempted:    true,
+		Certificates: 2,
+		FirstSeen:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		LastSeen:     time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC),
+		Issuers:      []string{"C=US, O=Let's Encrypt, CN=R3"},
+		Names:        []string{"exampel.com", "login.exampel.com", "www.exampel.com"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lookupCT() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLookupPDNS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); !ok || u != "user" || p != "key" || r.URL.Path != "/pdns/query/exampel.com" {
+			http.Error(w, "denied", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintln(w, `{"rrname":"exampel.com","rrtype":"A","rdata":"192.0.2.1","time_first":1700000000,"time_last":1710000000,"count":12}`)
+		fmt.Fprintln(w, `{"rrname":"exampel.com","rrtype":"NS","rdata":"ns1.parkingcrew.net","time_first":1690000000,"time_last":1710000000}`)
+	}))
+	defer srv.Close()
+
+	got := lookupPDNS(context.Background(), "exampel.com", Config{PDNSURL: srv.URL + "/pdns/query", PDNSUser: "user", PDNSKey: "key"})
+	if len(got.Records) != 2 || got.Records[0].RData != "192.0.2.1" || got.Records[0].Count != 12 || got.Records[1].RRType != "NS" {
+		t.Errorf("lookupPDNS() = %+v", got)
+	}
+}
+
+func TestVerifyDomainCTFailureNotCached(t *testing.T) {
+	var ctCalls, pdnsCalls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/pdns/exampel.com" {
+			if pdnsCalls++; pdnsCalls == 1 {
+				http.Error(w, "quota", http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprintln(w, `{"rrname":"exampel.com","rrtype":"A","rdata":"192.0.2.1","time_first":1700000000,"time_last":1710000000}`)
+			return
+		}
+		if ctCalls++; ctCalls == 1 {
+			http.Error(w, "rate limited", http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `[{"issuer_name": "R3", "name_value": "exampel.com", "not_before": "2024-05-01T00:00:00"}]`)
+	}))
+	defer srv.Close()
+
+	c := mapCache{}
+	cfg := Config{Resolver: verifytest.NewResolver(testZone), DoCT: true, CTBase: srv.URL, PDNSURL: srv.URL + "/pdns", Cache: c}
+	v, err := VerifyDomain(context.Background(), "exampel.com", cfg)
+	if err != nil || v.CT == nil || v.CT.Error == nil || v.PDNS == nil || v.PDNS.Error == nil {
+		t.Fatalf("VerifyDomain() = %+v, %v, want CT and passive DNS errors", v, err)
+	}
+	if v, _ = VerifyDomain(context.Background(), "exampel.com", cfg); v.CT.Certificates != 1 || len(v.PDNS.Records) != 1 {
+		t.Errorf("second scan CT = %+v, PDNS = %+v, want the failures retried", v.CT, v.PDNS)
+	}
+	_, _ = VerifyDomain(context.Background(), "exampel.com", cfg)
+	if ctCalls != 2 || pdnsCalls != 2 {
+		t.Errorf("CT and passive DNS queried %d and %d times, want the successes cached", ctCalls, pdnsCalls)
+	}
+}
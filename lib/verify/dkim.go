@@ -0,0 +1,58 @@
+package verify
+
+import (
+	"context"
+	"strings"
+)
+
+// DefaultDKIMSelectors are the selectors most commonly provisioned by hosted
+// mail providers (Google Workspace, Microsoft 365, Mailchimp, generic MTAs).
+var DefaultDKIMSelectors = []string{"default", "google", "selector1", "selector2", "k1"}
+
+// lookupDKIM probes <selector>._domainkey.<domain> TXT records for each selector
+// and returns the selectors that published a DKIM key. A domain that signs its
+// outbound mail is a much stronger BEC indicator than an MX record alone.
+func lookupDKIM(ctx context.Context, resolver Resolver, domain string, selectors []string) []string {
+	var found []string
+
+	for _, sel := range selectors {
+		sel = strings.TrimSpace(sel)
+		if sel == "" {
+			continue
+		}
+		txts, err := resolver.LookupTXT(ctx, sel+"._domainkey."+domain)
+		if err != nil {
+			if ctx.Err() != nil {
+				return found
+			}
+			continue
+		}
+		for _, txt := range txts {
+			if isDKIMRecord(txt) {
+				found = append(found, sel)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// isDKIMRecord reports whether a TXT record looks like a DKIM key record.
+// The version tag is optional per RFC 6376 so a public key tag is also accepted.
+func isDKIMRecord(txt string) bool {
+	for _, tag := range strings.Split(txt, ";") {
+		k, v, ok := strings.Cut(strings.TrimSpace(tag), "=")
+		if !ok {
+			continue
+		}
+		k = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+		if k == "v" && strings.EqualFold(v, "DKIM1") {
+			return true
+		}
+		if k == "p" && v != "" { // an empty p= means the key has been revoked
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package verify
+
+import "testing"
+
+func TestIsDKIMRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		want bool
+	}{
+		{name: "Versioned key record", txt: "v=DKIM1; k=rsa; p=MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQC", want: true},
+		{name: "Key record without version", txt: "k=rsa; p=MIGfMA0GCSqGSIb3DQEB", want: true},
+		{name: "Revoked key", txt: "k=rsa; p=", want: false},
+		{name: "SPF record", txt: "v=spf1 include:_spf.google.com ~all", want: false},
+		{name: "Empty record", txt: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isDKIMRecord(tt.txt); got != tt.want {
+				t.Errorf("isDKIMRecord(%q) = %v, want %v", tt.txt, got, tt.want)
+			}
+		})
+	}
+}
@@ -6,27 +6,60 @@ import (
 	"strings"
 )
 
+// Resolver is the subset of *net.Resolver the DNS stages use, so tests can
+// substitute an in-memory fake (see verifytest).
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupCNAME(ctx context.Context, host string) (string, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+var _ Resolver = (*net.Resolver)(nil)
+
+// resolver returns Config.Resolver, or the system resolver.
+func (c Config) resolver() Resolver {
+	if c.Resolver != nil {
+		return c.Resolver
+	}
+	return net.DefaultResolver
+}
+
 type DNSResult struct {
 	HasA     bool
 	HasAAAA  bool
 	HasCNAME bool
 	HasMX    bool
 	HasNS    bool
+	HasDKIM  bool
 
 	A     []string
 	AAAA  []string
 	CNAME string
 	MX    []string
 	NS    []string
+	DKIM  []string  // selectors that published a DKIM key
+	ASN   []ASNInfo // origin AS per A/AAAA address, when Config.DoASN is set
+	// Error is set when the lookups failed other than by the name not
+	// existing (SERVFAIL, timeout); such results aren't cached.
+	Error *StageError `json:",omitempty"`
+
+	// Source is the upstream that answered: a -resolvers server address,
+	// or "system". Cached marks an answer served from Config.Cache, where
+	// Source is the upstream of the original lookup.
+	Source string `json:",omitempty"`
+	Cached bool   `json:",omitempty"`
+	// Consensus is the second opinion of Config.ConsensusResolver.
+	Consensus *DNSConsensus `json:",omitempty"`
 }
 
 // lookupDNS performs DNS lookups for A, AAAA, CNAME, MX, and NS records for a given domain
 // Returns DNSResult struct and an error, prefer most informative error if multiple lookups fail
-func lookupDNS(ctx context.Context, domain string) (DNSResult, error) {
+func lookupDNS(ctx context.Context, resolver Resolver, domain string) (DNSResult, error) {
 	var r DNSResult
 
-	resolver := net.DefaultResolver
-
 	// A / AAAA
 	ips, err := resolver.LookupIPAddr(ctx, domain)
 	if err == nil {
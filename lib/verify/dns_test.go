@@ -0,0 +1,151 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"testing"
+	"time"
+)
+
+var _ Resolver = (*verifytest.Resolver)(nil)
+
+var testZone = verifytest.Zone{
+	"exampel.com":                   {A: []string{"192.0.2.10"}, MX: []string{"mx.exampel.com"}, NS: []string{"ns1.sedoparking.com"}},
+	"google._domainkey.exampel.com": {TXT: []string{"v=DKIM1; k=rsa; p=MIGfMA0"}},
+	"www.examp1e.com":               {CNAME: "lb.hosting.test"},
+	"lb.hosting.test":               {AAAA: []string{"2001:db8::1"}},
+	"mailonly.test":                 {MX: []string{"mx.mailonly.test"}},
+}
+
+func TestLookupDNS(t *testing.T) {
+	tests := []struct {
+		domain  string
+		want    DNSResult
+		wantErr bool
+	}{
+		{"exampel.com", DNSResult{HasA: true, HasMX: true, HasNS: true, A: []string{"192.0.2.10"}, MX: []string{"mx.exampel.com"}, NS: []string{"ns1.sedoparking.com"}}, false},
+		{"www.examp1e.com", DNSResult{HasAAAA: true, HasCNAME: true, AAAA: []string{"2001:db8::1"}, CNAME: "lb.hosting.test"}, false},
+		{"mailonly.test", DNSResult{HasMX: true, MX: []string{"mx.mailonly.test"}}, false},
+		{"unregistered.com", DNSResult{}, true},
+	}
+	r := verifytest.NewResolver(testZone)
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			got, err := lookupDNS(context.Background(), r, tt.domain)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupDNS() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("lookupDNS() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyDomainResolver(t *testing.T) {
+	cfg := Config{Resolver: verifytest.NewResolver(testZone), DKIMSelectors: DefaultDKIMSelectors}
+	v, err := VerifyDomain(context.Background(), "exampel.com", cfg)
+	if err != nil {
+		t.Fatalf("VerifyDomain() error: %v", err)
+	}
+	if !v.Resolvable || !v.HasMail || !reflect.DeepEqual(v.DNS.DKIM, []string{"google"}) {
+		t.Errorf("VerifyDomain() = %+v, want resolvable with mail and DKIM selector google", v)
+	}
+
+	v, err = VerifyDomain(context.Background(), "unregistered.com", cfg)
+	if err != nil || v.Resolvable || v.HasMail {
+		t.Errorf("VerifyDomain(unregistered) = %+v, %v, want a dead candidate", v, err)
+	}
+
+	// The same records served over UDP through a real resolver.
+	srv, err := verifytest.NewDNSServer(testZone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	cfg.Resolver = srv.Resolver()
+	v, err = VerifyDomain(context.Background(), "www.examp1e.com", cfg)
+	if err != nil || !v.Resolvable || v.DNS.CNAME != "lb.hosting.test" {
+		t.Errorf("VerifyDomain() over DNSServer = %+v, %v, want resolvable through its CNAME", v.DNS, err)
+	}
+}
+
+// mapCache is an in-memory Cache where every entry stays fresh.
+type mapCache map[string][]byte
+
+func (m mapCache) Get(stage, domain string, v any) (found, fresh bool) {
+	raw, ok := m[stage+"/"+domain]
+	if !ok || json.Unmarshal(raw, v) != nil {
+		return false, false
+	}
+	return true, true
+}
+
+func (m mapCache) Put(stage, domain string, v any) {
+	m[stage+"/"+domain], _ = json.Marshal(v)
+}
+
+func TestVerifyDomainNegativeCache(t *testing.T) {
+	zone := verifytest.Zone{
+		"com":         {SOA: 15 * time.Minute},
+		"exampel.com": {A: []string{"192.0.2.10"}},
+	}
+	r := verifytest.NewResolver(zone)
+	c := mapCache{}
+	cfg := Config{Resolver: r, Cache: c}
+
+	before := time.Now()
+	if v, err := VerifyDomain(context.Background(), "unregistered.com", cfg); err != nil || v.Resolvable {
+		t.Fatalf("VerifyDomain() = %+v, %v, want a dead candidate", v, err)
+	}
+	var neg negativeEntry
+	if found, _ := c.Get(StageNegative, "unregistered.com", &neg); !found || neg.Until.Before(before.Add(15*time.Minute)) || neg.Until.After(time.Now().Add(15*time.Minute)) {
+		t.Fatalf("negative entry = %+v (found %v), want 15m from the SOA", neg, found)
+	}
+
+	queried := len(r.Queries())
+	if _, err := VerifyDomain(context.Background(), "unregistered.com", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(r.Queries()); n != queried {
+		t.Errorf("rescan sent %d queries, want none until the negative TTL expires", n-queried)
+	}
+
+	// Once expired, the name is resolved again.
+	c.Put(StageNegative, "unregistered.com", negativeEntry{Until: time.Now().Add(-time.Second)})
+	delete(c, StageDNS+"/unregistered.com")
+	if _, err := VerifyDomain(context.Background(), "unregistered.com", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(r.Queries()); n == queried {
+		t.Errorf("expired negative entry still skipped the lookup")
+	}
+
+	if _, err := VerifyDomain(context.Background(), "exampel.com", cfg); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := c.Get(StageNegative, "exampel.com", &neg); found {
+		t.Errorf("resolvable name cached as negative")
+	}
+}
+
+func TestQuerySOA(t *testing.T) {
+	srv, err := verifytest.NewDNSServer(verifytest.Zone{
+		"com":         {SOA: 10 * time.Minute},
+		"exampel.com": {A: []string{"192.0.2.10"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	for _, name := range []string{"unregistered.com", "exampel.com", "com"} {
+		if ttl, err := querySOA(context.Background(), srv.Addr, name); err != nil || ttl != 10*time.Minute {
+			t.Errorf("querySOA(%s) = %v, %v, want 10m", name, ttl, err)
+		}
+	}
+	if _, err := querySOA(context.Background(), srv.Addr, "unregistered.test"); err == nil {
+		t.Errorf("querySOA() outside any zone succeeded")
+	}
+}
@@ -0,0 +1,101 @@
+package verify
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+)
+
+// Default blocklist zones. Domain lists (SURBL, URIBL, Spamhaus DBL) are
+// queried with the candidate itself; IP lists with the candidate's MX hosts'
+// addresses. Spamhaus and URIBL refuse queries from large public resolvers,
+// answering with the error codes listedCodes drops.
+var (
+	DefaultDomainBLZones = []string{"dbl.spamhaus.org", "multi.surbl.org", "multi.uribl.com"}
+	DefaultIPBLZones     = []string{"zen.spamhaus.org", "bl.spamcop.net", "b.barracudacentral.org"}
+)
+
+// DNSBLListing is a candidate domain or MX address found on a blocklist.
+type DNSBLListing struct {
+	Zone  string
+	Query string   // the candidate domain or MX IP that was looked up
+	Codes []string // 127.0.0.x return codes (their meaning is per list)
+}
+
+// DNSBLResult holds every listing for a candidate.
+type DNSBLResult struct {
+	Attempted bool
+	MXIPs     []string
+	Listings  []DNSBLListing
+}
+
+// lookupDNSBL checks the candidate against the domain lists and the
+// addresses of its MX hosts against the IP lists.
+func lookupDNSBL(ctx context.Context, ascii string, dns DNSResult, cfg Config) DNSBLResult {
+	res := DNSBLResult{Attempted: true}
+	resolver := cfg.resolver()
+
+	for _, zone := range cfg.DomainBLZones {
+		if l, ok := queryBL(ctx, resolver, ascii, ascii+"."+zone, zone); ok {
+			res.Listings = append(res.Listings, l)
+		}
+	}
+
+	if len(cfg.IPBLZones) == 0 {
+		return res
+	}
+	for _, mx := range dns.MX {
+		addrs, err := resolver.LookupHost(ctx, strings.TrimSuffix(mx, "."))
+		if err != nil {
+			if ctx.Err() != nil {
+				return res
+			}
+			continue
+		}
+		for _, a := range addrs {
+			if !slices.Contains(res.MXIPs, a) {
+				res.MXIPs = append(res.MXIPs, a)
+			}
+		}
+	}
+	for _, ip := range res.MXIPs {
+		rev, _, ok := reverseIP(ip)
+		if !ok {
+			continue
+		}
+		for _, zone := range cfg.IPBLZones {
+			if l, ok := queryBL(ctx, resolver, ip, rev+"."+zone, zone); ok {
+				res.Listings = append(res.Listings, l)
+			}
+		}
+	}
+	return res
+}
+
+func queryBL(ctx context.Context, resolver Resolver, query, name, zone string) (DNSBLListing, bool) {
+	addrs, err := resolver.LookupHost(ctx, name)
+	if err != nil {
+		return DNSBLListing{}, false // NXDOMAIN: not listed
+	}
+	codes := listedCodes(addrs)
+	if len(codes) == 0 {
+		return DNSBLListing{}, false
+	}
+	return DNSBLListing{Zone: zone, Query: query, Codes: codes}, true
+}
+
+// listedCodes keeps genuine listing codes: 127.0.0.2 and up. Anything outside
+// 127/8 is a resolver rewriting NXDOMAIN, 127.255.255.x is Spamhaus refusing
+// the query, and 127.0.0.1 is SURBL/URIBL blocking the resolver.
+func listedCodes(addrs []string) []string {
+	var out []string
+	for _, a := range addrs {
+		ip := net.ParseIP(a).To4()
+		if ip == nil || ip[0] != 127 || (ip[1] == 255 && ip[2] == 255) || a == "127.0.0.1" {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+package verify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReverseIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		want   string
+		wantV4 bool
+	}{
+		{"192.0.2.99", "99.2.0.192", true},
+		{"2001:db8::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2", false},
+	}
+	for _, tt := range tests {
+		got, v4, ok := reverseIP(tt.ip)
+		if got != tt.want || v4 != tt.wantV4 || !ok {
+			t.Errorf("reverseIP(%q) = %q, %v, %v, want %q, %v, true", tt.ip, got, v4, ok, tt.want, tt.wantV4)
+		}
+	}
+}
+
+func TestListedCodes(t *testing.T) {
+	tests := []struct {
+		name  string
+		addrs []string
+		want  []string
+	}{
+		{"listed", []string{"127.0.0.2", "127.0.1.4"}, []string{"127.0.0.2", "127.0.1.4"}},
+		{"spamhaus refused", []string{"127.255.255.254"}, nil},
+		{"surbl blocked resolver", []string{"127.0.0.1"}, nil},
+		{"nxdomain rewritten", []string{"198.51.100.53"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listedCodes(tt.addrs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listedCodes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
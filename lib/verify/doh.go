@@ -0,0 +1,130 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// dohClient carries DNS-over-HTTPS exchanges; each is bounded by the
+// lookup's context.
+var dohClient = &http.Client{}
+
+// maxDNSMessage bounds a DoH answer.
+const maxDNSMessage = 65535
+
+// isDoH reports whether an upstream is a DNS-over-HTTPS endpoint URL rather
+// than a host:port.
+func isDoH(server string) bool {
+	return strings.HasPrefix(server, "https://")
+}
+
+// newUpstreamResolver returns a resolver sending every query to server: a
+// nameserver address (host:port) or a DoH endpoint (https://...).
+func newUpstreamResolver(server string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			if isDoH(server) {
+				return &dohConn{ctx: ctx, url: server}, nil
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// normalizeUpstream fills in port 53 on a nameserver address; DoH URLs are
+// kept as they are.
+func normalizeUpstream(server string) string {
+	server = strings.TrimSpace(server)
+	if isDoH(server) {
+		return server
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return server
+}
+
+// exchangeDoH POSTs one DNS message to a DoH endpoint per RFC 8484.
+func exchangeDoH(ctx context.Context, url string, msg []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(msg))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/dns-message")
+	req.Header.Set("Accept", "application/dns-message")
+	resp, err := dohClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("doh: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxDNSMessage))
+}
+
+// dohConn lets the pure-Go resolver speak DoH: it isn't a PacketConn, so
+// the resolver frames each query with a length prefix as over TCP, and
+// every complete query written is answered by one DoH exchange.
+type dohConn struct {
+	ctx      context.Context
+	url      string
+	deadline time.Time
+	out, in  bytes.Buffer
+}
+
+func (c *dohConn) Write(b []byte) (int, error) {
+	c.out.Write(b)
+	for c.out.Len() >= 2 {
+		n := int(binary.BigEndian.Uint16(c.out.Bytes()))
+		if c.out.Len() < 2+n {
+			break
+		}
+		msg := c.out.Next(2 + n)[2:]
+		ctx := c.ctx
+		if !c.deadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, c.deadline)
+			defer cancel()
+		}
+		answer, err := exchangeDoH(ctx, c.url, msg)
+		if err != nil {
+			return 0, err
+		}
+		if len(answer) < 2 {
+			return 0, errors.New("doh: short answer")
+		}
+		c.in.Write(binary.BigEndian.AppendUint16(nil, uint16(len(answer))))
+		c.in.Write(answer)
+	}
+	return len(b), nil
+}
+
+func (c *dohConn) Read(b []byte) (int, error) {
+	if c.in.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.in.Read(b)
+}
+
+func (c *dohConn) Close() error                       { return nil }
+func (c *dohConn) LocalAddr() net.Addr                { return dohAddr(c.url) }
+func (c *dohConn) RemoteAddr() net.Addr               { return dohAddr(c.url) }
+func (c *dohConn) SetDeadline(t time.Time) error      { c.deadline = t; return nil }
+func (c *dohConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *dohConn) SetWriteDeadline(t time.Time) error { c.deadline = t; return nil }
+
+type dohAddr string
+
+func (a dohAddr) Network() string { return "https" }
+func (a dohAddr) String() string  { return string(a) }
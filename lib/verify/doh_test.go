@@ -0,0 +1,70 @@
+package verify
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"testing"
+	"time"
+)
+
+// dohServer serves DoH by relaying each query to a verifytest.DNSServer.
+func dohServer(t *testing.T, zone verifytest.Zone) *httptest.Server {
+	t.Helper()
+	dns, err := verifytest.NewDNSServer(zone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dns.Close() })
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/dns-message" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		query, _ := io.ReadAll(r.Body)
+		conn, err := net.Dial("udp", dns.Addr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		defer conn.Close()
+		conn.Write(query)
+		buf := make([]byte, 1232)
+		n, err := conn.Read(buf)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(buf[:n])
+	}))
+	t.Cleanup(srv.Close)
+	prev := dohClient
+	dohClient = srv.Client()
+	t.Cleanup(func() { dohClient = prev })
+	return srv
+}
+
+func TestResolverPoolDoH(t *testing.T) {
+	srv := dohServer(t, verifytest.Zone{
+		"com":         {SOA: 20 * time.Minute},
+		"exampel.com": {A: []string{"192.0.2.10"}, MX: []string{"mx.exampel.com"}},
+	})
+	url := srv.URL + "/dns-query"
+	pool, err := NewResolverPool([]string{url})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := VerifyDomain(context.Background(), "exampel.com", Config{Resolver: pool, DNSTimeout: 2 * time.Second})
+	if err != nil || !reflect.DeepEqual(v.DNS.A, []string{"192.0.2.10"}) || !v.DNS.HasMX || v.DNS.Source != url {
+		t.Errorf("VerifyDomain() over DoH = %+v, %v, want resolved by %s", v.DNS, err, url)
+	}
+	if ttl, err := pool.NegativeTTL(context.Background(), "unregistered.com"); err != nil || ttl != 20*time.Minute {
+		t.Errorf("NegativeTTL() over DoH = %v, %v, want 20m", ttl, err)
+	}
+}
@@ -0,0 +1,77 @@
+package verify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// StageError records a verification stage that could not complete, so a
+// candidate that couldn't be checked isn't mistaken for a safe one.
+type StageError struct {
+	Stage   string `json:"stage"` // StageDNS, StageTLS, StageHTTP, or "verify" for the whole candidate
+	Kind    string `json:"kind"`  // see ErrorKind
+	Message string `json:"message"`
+}
+
+// NewStageError describes err as a failure of stage.
+func NewStageError(stage string, err error) *StageError {
+	return &StageError{Stage: stage, Kind: ErrorKind(err), Message: err.Error()}
+}
+
+// ErrorKind buckets a probe or lookup error: "timeout", "refused",
+// "reset", "unreachable", "tls_alert", "tls_protocol", "dns", "canceled" or
+// "other".
+func ErrorKind(err error) string {
+	var alert tls.AlertError
+	var record tls.RecordHeaderError
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return "reset"
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.Is(err, syscall.ENETUNREACH):
+		return "unreachable"
+	case errors.As(err, &alert):
+		return "tls_alert"
+	case errors.As(err, &record):
+		return "tls_protocol"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	}
+	return "other"
+}
+
+// stageErrors collects the errors of v's DNS, TLS and HTTP stages.
+func (v Verification) stageErrors() []StageError {
+	var errs []StageError
+	for _, e := range []*StageError{v.DNS.Error, tlsError(v.TLS), httpError(v.HTTP)} {
+		if e != nil {
+			errs = append(errs, *e)
+		}
+	}
+	return errs
+}
+
+func tlsError(r *TLSResult) *StageError {
+	if r == nil {
+		return nil
+	}
+	return r.Error
+}
+
+func httpError(r *HTTPResult) *StageError {
+	if r == nil {
+		return nil
+	}
+	return r.Error
+}
@@ -0,0 +1,89 @@
+package verify
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{context.DeadlineExceeded, "timeout"},
+		{&net.OpError{Op: "dial", Err: os.ErrDeadlineExceeded}, "timeout"},
+		{&net.OpError{Op: "dial", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}, "refused"},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), "reset"},
+		{&net.OpError{Op: "dial", Err: syscall.ENETUNREACH}, "unreachable"},
+		{&net.OpError{Op: "remote error", Err: tls.AlertError(40)}, "tls_alert"},
+		{tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}, "tls_protocol"},
+		{&net.DNSError{Err: "server misbehaving", Name: "exampel.com"}, "dns"},
+		{context.Canceled, "canceled"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorKind(tt.err); got != tt.want {
+			t.Errorf("ErrorKind(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyDomainErrors(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close() // nothing listens: connections are refused
+
+	cfg := Config{
+		Resolver:   failingResolver{},
+		DoTLS:      true,
+		DNSTimeout: time.Second,
+	}
+	v, err := VerifyDomain(context.Background(), "exampel.com", cfg)
+	if err != nil {
+		t.Fatalf("VerifyDomain() error: %v", err)
+	}
+	if len(v.Errors) != 1 || v.Errors[0].Stage != StageDNS || v.Errors[0].Kind != "dns" {
+		t.Errorf("Errors = %+v, want the DNS stage failure", v.Errors)
+	}
+
+	tr := fetchTLS(context.Background(), "exampel.com", Config{Dialer: &pinnedDialer{addr: addr}})
+	if tr.Connected || tr.Error == nil || tr.Error.Stage != StageTLS || tr.Error.Kind != "refused" {
+		t.Errorf("fetchTLS() = %+v, want a refused TLS stage error", tr.Error)
+	}
+}
+
+// failingResolver fails every lookup the way a SERVFAIL does.
+type failingResolver struct{}
+
+func (failingResolver) fail(name string) error {
+	return &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+}
+
+func (r failingResolver) LookupIPAddr(_ context.Context, host string) ([]net.IPAddr, error) {
+	return nil, r.fail(host)
+}
+func (r failingResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	return nil, r.fail(host)
+}
+func (r failingResolver) LookupCNAME(_ context.Context, host string) (string, error) {
+	return "", r.fail(host)
+}
+func (r failingResolver) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	return nil, r.fail(name)
+}
+func (r failingResolver) LookupNS(_ context.Context, name string) ([]*net.NS, error) {
+	return nil, r.fail(name)
+}
+func (r failingResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	return nil, r.fail(name)
+}
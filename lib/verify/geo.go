@@ -0,0 +1,51 @@
+package verify
+
+import (
+	"slices"
+	"strings"
+)
+
+// GeoLocator maps an IP to an ISO 3166-1 alpha-2 country code ("" when
+// unknown). lib/geo implements it over a local mmdb file.
+type GeoLocator interface {
+	Country(ip string) string
+}
+
+// GeoResult is where a candidate is hosted.
+type GeoResult struct {
+	Countries []string // distinct hosting countries, in resolution order
+	HighRisk  []string // the subset on Config.HighRiskCountries
+}
+
+// locate collects hosting countries from cfg.Geo, falling back to the
+// registry country of the origin ASN (-asn) for IPs it doesn't know. It
+// returns nil when neither source knows anything.
+func locate(dns DNSResult, cfg Config) *GeoResult {
+	asnCountry := map[string]string{}
+	for _, a := range dns.ASN {
+		asnCountry[a.IP] = a.Country
+	}
+
+	var g GeoResult
+	for _, ip := range append(append([]string{}, dns.A...), dns.AAAA...) {
+		var cc string
+		if cfg.Geo != nil {
+			cc = cfg.Geo.Country(ip)
+		}
+		if cc == "" {
+			cc = asnCountry[ip]
+		}
+		cc = strings.ToUpper(cc)
+		if cc == "" || slices.Contains(g.Countries, cc) {
+			continue
+		}
+		g.Countries = append(g.Countries, cc)
+		if slices.ContainsFunc(cfg.HighRiskCountries, func(h string) bool { return strings.EqualFold(h, cc) }) {
+			g.HighRisk = append(g.HighRisk, cc)
+		}
+	}
+	if len(g.Countries) == 0 {
+		return nil
+	}
+	return &g
+}
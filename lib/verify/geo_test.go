@@ -0,0 +1,40 @@
+package verify
+
+import (
+	"reflect"
+	"testing"
+)
+
+type mapGeo map[string]string
+
+func (m mapGeo) Country(ip string) string { return m[ip] }
+
+func TestLocate(t *testing.T) {
+	dns := DNSResult{
+		A:    []string{"192.0.2.1", "192.0.2.2", "192.0.2.3"},
+		AAAA: []string{"2001:db8::1"},
+		ASN:  []ASNInfo{{IP: "192.0.2.3", Country: "ru"}, {IP: "2001:db8::1", Country: "US"}},
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+		want *GeoResult
+	}{
+		{"asn fallback only", Config{HighRiskCountries: []string{"ru"}},
+			&GeoResult{Countries: []string{"RU", "US"}, HighRisk: []string{"RU"}}},
+		{"geoip first", Config{Geo: mapGeo{"192.0.2.1": "nl", "192.0.2.2": "NL", "2001:db8::1": "IR"}, HighRiskCountries: []string{"IR"}},
+			&GeoResult{Countries: []string{"NL", "RU", "IR"}, HighRisk: []string{"IR"}}},
+		{"unknown", Config{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := dns
+			if tt.want == nil {
+				d.ASN = nil
+			}
+			if got := locate(d, tt.cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("locate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
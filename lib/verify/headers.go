@@ -0,0 +1,91 @@
+package verify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// volatileHeaders change between responses of the same site, or are the
+// ones a phishing reverse proxy rewrites or strips: redirects and cookies
+// pointing at the real domain, the security policies that would stop it
+// framing or rewriting pages, and encodings undone to rewrite bodies.
+// HeaderFingerprint ignores them.
+var volatileHeaders = map[string]bool{
+	"date": true, "age": true, "expires": true, "last-modified": true, "etag": true,
+	"content-length": true, "content-encoding": true, "transfer-encoding": true,
+	"connection": true, "keep-alive": true, "alt-svc": true, "via": true, "location": true,
+	"content-security-policy": true, "content-security-policy-report-only": true,
+	"strict-transport-security": true, "x-frame-options": true, "x-xss-protection": true,
+	"x-content-type-options": true, "report-to": true, "nel": true,
+}
+
+// valuedHeaders name the software behind a response by their values. Of
+// the other headers only the names are kept: their values (request IDs,
+// cache states, timestamps) differ per response.
+var valuedHeaders = map[string]bool{
+	"server": true, "x-powered-by": true, "x-aspnet-version": true, "x-generator": true,
+	"content-type": true, "cache-control": true, "vary": true,
+}
+
+// minFingerprintHeaders is the fewest headers HeaderFingerprint hashes;
+// smaller sets are common to too many sites to tell them apart.
+const minFingerprintHeaders = 6
+
+// HeaderFingerprint is the SHA-256 of a response's header set: the names
+// of its stable headers, the values of those naming its software, and the
+// names of the cookies it sets. A reverse proxy in front of a site (e.g.
+// evilginx) passes the site's headers through, so it answers with the
+// site's fingerprint. It is "" for a set too small to identify a site.
+func HeaderFingerprint(h http.Header) string {
+	var lines []string
+	for name, values := range h {
+		name = strings.ToLower(name)
+		switch {
+		case volatileHeaders[name]:
+		case name == "set-cookie":
+			var cookies []string
+			for _, c := range (&http.Response{Header: http.Header{"Set-Cookie": values}}).Cookies() {
+				cookies = append(cookies, c.Name)
+			}
+			slices.Sort(cookies)
+			lines = append(lines, name+": "+strings.Join(slices.Compact(cookies), " "))
+		case valuedHeaders[name]:
+			lines = append(lines, name+": "+strings.Join(values, ", "))
+		default:
+			lines = append(lines, name)
+		}
+	}
+	if len(lines) < minFingerprintHeaders {
+		return ""
+	}
+	slices.Sort(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// mirrorsBase reports whether the candidate domain served res under its
+// own name with the base site's header fingerprint. A candidate
+// redirecting to the real site lands on the real site's headers, which is
+// not a mirror.
+func mirrorsBase(res *HTTPResult, domain string, base *BaseSite) bool {
+	return base != nil && base.Fingerprint != "" && res.HeaderFingerprint == base.Fingerprint && landedOn(res, domain)
+}
+
+// landedOn reports whether res's probe ended on domain or a subdomain.
+func landedOn(res *HTTPResult, domain string) bool {
+	u, err := url.Parse(res.FinalURL)
+	if err != nil {
+		return false
+	}
+	return onHost(u.Hostname(), domain)
+}
+
+// onHost reports whether host is domain or one of its subdomains.
+func onHost(host, domain string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
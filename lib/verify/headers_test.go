@@ -0,0 +1,78 @@
+package verify
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestHeaderFingerprint(t *testing.T) {
+	site := func() http.Header {
+		return http.Header{
+			"Server":                    {"AcmeEdge/2.1"},
+			"Content-Type":              {"text/html; charset=utf-8"},
+			"Cache-Control":             {"private, no-store"},
+			"X-Acme-Request-Id":         {"7f3a9c"},
+			"X-Acme-Region":             {"eu-west-1"},
+			"Set-Cookie":                {"ACMESESSID=abc; Domain=acme.com; Secure", "acme_lb=1; Path=/"},
+			"Date":                      {"Mon, 05 Oct 2026 10:00:00 GMT"},
+			"Strict-Transport-Security": {"max-age=31536000"},
+		}
+	}
+	base := HeaderFingerprint(site())
+	if base == "" {
+		t.Fatal("HeaderFingerprint() of a full header set is empty")
+	}
+	tests := []struct {
+		name  string
+		edit  func(http.Header)
+		equal bool
+	}{
+		{"per-response values", func(h http.Header) {
+			h.Set("X-Acme-Request-Id", "00b1e2")
+			h.Set("Date", "Tue, 06 Oct 2026 11:00:00 GMT")
+			h["Set-Cookie"] = []string{"acme_lb=2", "ACMESESSID=def; Domain=examp1e.com"}
+		}, true},
+		{"proxy strips policies and rewrites redirects", func(h http.Header) {
+			h.Del("Strict-Transport-Security")
+			h.Set("Location", "https://examp1e.com/login")
+		}, true},
+		{"other server", func(h http.Header) { h.Set("Server", "nginx") }, false},
+		{"other cookie", func(h http.Header) { h.Add("Set-Cookie", "PHPSESSID=1") }, false},
+		{"extra header", func(h http.Header) { h.Set("X-Kit", "1") }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := site()
+			tt.edit(h)
+			if got := HeaderFingerprint(h) == base; got != tt.equal {
+				t.Errorf("same fingerprint = %v, want %v", got, tt.equal)
+			}
+		})
+	}
+	if got := HeaderFingerprint(http.Header{"Server": {"nginx"}, "Content-Type": {"text/html"}, "Date": {"x"}}); got != "" {
+		t.Errorf("HeaderFingerprint() of a generic set = %q, want empty", got)
+	}
+}
+
+func TestMirrorsBase(t *testing.T) {
+	tests := []struct {
+		name        string
+		fingerprint string
+		finalURL    string
+		want        bool
+	}{
+		{"proxied under own name", "abc", "https://examp1e.com/login", true},
+		{"proxied on a subdomain", "abc", "https://login.examp1e.com/", true},
+		{"redirected to the real site", "abc", "https://example.com/", false},
+		{"different headers", "def", "https://examp1e.com/", false},
+		{"no fingerprint", "", "https://examp1e.com/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &HTTPResult{HeaderFingerprint: tt.fingerprint, FinalURL: tt.finalURL}
+			if got := mirrorsBase(res, "examp1e.com", &BaseSite{Fingerprint: "abc"}); got != tt.want {
+				t.Errorf("mirrorsBase() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,39 +1,154 @@
 package verify
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"html"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultMaxBodyBytes caps how much of a response body is read when
+// FetchBody is set and Config.MaxBodyBytes is zero. The cap applies to the
+// decompressed body, so compressed responses can't inflate past it.
+const DefaultMaxBodyBytes = 1 << 20
+
+// maxCompressionRatio is the decompressed/compressed ratio above which a
+// truncated gzip body is flagged as a decompression bomb.
+const maxCompressionRatio = 100
+
+// DefaultBodyContentTypes are the media types whose bodies are sampled when
+// Config.BodyContentTypes is empty. Anything else (binaries, archives,
+// media) is never read.
+var DefaultBodyContentTypes = []string{
+	"text/html", "text/plain", "application/xhtml+xml", "application/json",
+	"application/javascript", "text/javascript", "text/xml", "application/xml",
+}
+
+// bodyPool recycles body sample buffers across requests so large scans with
+// body fetching don't allocate a fresh buffer per candidate.
+var bodyPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
 type HTTPResult struct {
-	Attempted     bool
-	URL           string
-	Status        string
-	StatusCode    int
-	Location      string
-	Server        string
-	RedirectChain []string
-	HasRedirect   bool
+	Attempted  bool
+	URL        string // the candidate URL that answered: https, or the http fallback
+	FinalURL   string // where the probe landed, after any followed redirects
+	Status     string
+	StatusCode int
+	Location   string
+	Server     string
+	// HeaderFingerprint identifies the landing response's header set (see
+	// HeaderFingerprint); MirrorsBase is set when it is the base site's
+	// (Config.Base), served under the candidate's own name.
+	// ProxyIndicators lists all it shares with the base site that a
+	// transparent reverse proxy would (see ReverseProxy).
+	HeaderFingerprint string   `json:",omitempty"`
+	MirrorsBase       bool     `json:",omitempty"`
+	ProxyIndicators   []string `json:",omitempty"`
+	// Validators of the landing page, sent back on the next probe of a
+	// cached candidate. NotModified is set when it answered 304 and the
+	// previous result was kept; ContentChangedAt is when a probe last found
+	// the content different from the one before.
+	ETag             string    `json:",omitempty"`
+	LastModified     string    `json:",omitempty"`
+	NotModified      bool      `json:",omitempty"`
+	ContentChangedAt time.Time `json:",omitzero"`
+	// Cookies are those set by any response of the probe, values omitted;
+	// BrandCookies are their names matching Config.BrandCookies.
+	Cookies      []Cookie `json:",omitempty"`
+	BrandCookies []string `json:",omitempty"`
+	// RedirectChain is every request of the probe in order: a failed HTTPS
+	// attempt, the HTTP fallback and each Location hop followed.
+	RedirectChain []Hop
+	HasRedirect   bool // some hop answered with a redirect
+
+	// Populated only when Config.FetchBody is set.
+	Title         string
+	ContentType   string
+	ContentLength int64
+	BodySHA256    string
+	TrackingIDs   []string
+	OwnLinks      int      // absolute links to the probed domain or its subdomains
+	BaseLinks     int      // absolute links to the base domain (Config.Base only)
+	Signals       []string // content signals, see contentSignals and Config.BodyInspector
+	BodyTruncated bool     // the body exceeded the size cap
+	BodySkipped   string   // why the body wasn't read: "content_type" or "encoding"
+
+	RuleMatches []RuleMatch // user content rules that matched, see Config.Rules
+	FaviconURL  string      // declared (or default) icon, resolved; FetchBody only
+	ScanMatches []ScanMatch // Config.Scanner (YARA) matches on the body and favicon
+	// LogoURLs are the page's logo images, resolved, and BrandAssets the
+	// brand's own assets found on the page, its favicon or its logos
+	// (e.g. "logo:acme", "phone:+1 800 555 0199"); Config.BrandAssets only.
+	LogoURLs    []string    `json:",omitempty"`
+	BrandAssets []string    `json:",omitempty"`
+	ArchivePath string      // where Config.Archive stored the raw exchanges
+	Error       *StageError `json:",omitempty"` // why no response was received
+
+	// CrossProtocol is plain HTTP compared with HTTPS, when
+	// Config.CrossProtocol is set and HTTPS answered.
+	CrossProtocol *CrossProtocol `json:",omitempty"`
 	// TODO: For fast lookup downstream
 	// TODO: Remediated 	bool // validate last redirect == Verification.Domain
 }
 
+// Hop is one request of an HTTP probe.
+type Hop struct {
+	URL      string
+	Scheme   string // "https" or "http"
+	Status   int    `json:",omitempty"`
+	Location string `json:",omitempty"`
+	Error    string `json:",omitempty"` // no response, e.g. HTTPS refused before the HTTP fallback
+}
+
+// hopRecorder is a RoundTripper noting each request it carries as a Hop,
+// and the cookies set along the way.
+type hopRecorder struct {
+	next    http.RoundTripper
+	hops    []Hop
+	cookies []Cookie
+}
+
+func (h *hopRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := h.next.RoundTrip(req)
+	hop := Hop{URL: req.URL.String(), Scheme: req.URL.Scheme}
+	if err != nil {
+		hop.Error = err.Error()
+	} else {
+		hop.Status, hop.Location = resp.StatusCode, resp.Header.Get("Location")
+		h.cookies = addCookies(h.cookies, resp)
+	}
+	h.hops = append(h.hops, hop)
+	return resp, err
+}
+
 // generateHTTPResult initializes an HTTPResult struct with attempted
 // flag set to true and an empty RedirectChain. The URL field is set
 // to the target domain after extracting it from the provided domain
 // string. TODO: Should probably be an init method on the HTTPResult type
 func generateHTTPResult(https bool, domain string) HTTPResult {
-	res := HTTPResult{Attempted: true, RedirectChain: []string{}, HasRedirect: false}
+	res := HTTPResult{Attempted: true, RedirectChain: []Hop{}, HasRedirect: false}
 	target := getTargetDomain(https, domain)
 	res.URL = target
 
 	return res
 }
 
-func configureHTTPClient(cfg Config, result HTTPResult) http.Client {
+func configureHTTPClient(cfg Config) http.Client {
 	client := &http.Client{
-		Timeout: cfg.HTTPTimeout,
+		Timeout:   cfg.HTTPTimeout,
+		Transport: cfg.probeTransport(),
 	}
 
 	if !cfg.HTTPFollowRedirects { // don't follow the redirects and short circuit
@@ -45,56 +160,238 @@ func configureHTTPClient(cfg Config, result HTTPResult) http.Client {
 			if len(via) >= 10 {
 				return fmt.Errorf("stopped after 10 redirects")
 			}
-			result.RedirectChain = append(result.RedirectChain, req.URL.String())
 			return nil
 		}
 	}
 	return *client
 }
 
-// fetchHTTP executes the provided domain and returns the HTTPResult
-// The last item in the HTTPResult.RedirectChain array is the final landing spot.
-func fetchHTTP(ctx context.Context, https bool, domain string, cfg Config) HTTPResult {
-	res := generateHTTPResult(https, domain)
-	client := configureHTTPClient(cfg, res)
+// fetchHTTP executes the provided domain and returns the HTTPResult.
+// Every request made, HTTPS attempt and HTTP fallback included, is recorded
+// in RedirectChain; FinalURL is the final landing spot.
+func fetchHTTP(ctx context.Context, https bool, domain string, cfg Config) (res HTTPResult) {
+	res = generateHTTPResult(https, domain)
+	client := configureHTTPClient(cfg)
+	hops := &hopRecorder{next: client.Transport}
+	client.Transport = hops
+	if cfg.Archive != nil {
+		rec := &recorder{next: client.Transport, limit: cfg.archiveLimit()}
+		client.Transport = rec
+		defer func() { res.ArchivePath = cfg.Archive.Archive(domain, rec.exchanges) }()
+	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodHead, res.URL, nil)
+	resp, err := probeHTTP(ctx, &client, res.URL, cfg)
+	if err != nil && https { // If HTTPS fails, try HTTP as a fallback.
+		res.URL = getTargetDomain(false, domain)
+		resp, err = probeHTTP(ctx, &client, res.URL, cfg)
+	}
+	res.RedirectChain = append(res.RedirectChain, hops.hops...)
+	res.Cookies = hops.cookies
+	res.BrandCookies = brandCookies(res.Cookies, cfg.BrandCookies)
+	for _, hop := range res.RedirectChain {
+		if hop.Status >= 300 && hop.Status < 400 && hop.Location != "" {
+			res.HasRedirect = true
+		}
+	}
 	if err != nil {
+		res.Error = NewStageError(StageHTTP, err)
 		return res
 	}
-	req.Header.Set("User-Agent", cfg.UserAgent)
+	defer resp.Body.Close()
+	if prev := cfg.prevHTTP; prev != nil && resp.StatusCode == http.StatusNotModified {
+		chain := res.RedirectChain
+		res = *prev
+		res.RedirectChain, res.NotModified = chain, true
+		return res
+	}
+	res.FinalURL = resp.Request.URL.String()
+	processHTTPResponse(&res, resp, cfg)
+	scanFavicon(ctx, &client, &res, cfg)
+	matchLogos(ctx, &client, &res, cfg)
+	if prev := cfg.prevHTTP; prev != nil {
+		res.ContentChangedAt = prev.ContentChangedAt
+		if contentChanged(*prev, res) {
+			res.ContentChangedAt = time.Now().UTC()
+		}
+	}
+	if cfg.CrossProtocol && strings.HasPrefix(res.URL, "https://") {
+		res.CrossProtocol = crossProtocol(ctx, domain, res, cfg)
+	}
+	return res
+}
 
-	// TODO: Factor this out to a processHTTP method to compliment this fetchHTTP for unit testing
-	resp, err := client.Do(req)
-	if err != nil && https { // If HTTPS fails, try HTTP as a fallback.
-		// TODO: recall fetchHTTP without HTTPS to reduce code
-		// TODO: attempted above but getting weird nil ptr issues and couldn't figure out so bailed
-		res.URL = getTargetDomain(false, domain)
-		req2, err2 := http.NewRequestWithContext(ctx, http.MethodHead, res.URL, nil)
-		if err2 != nil {
-			return res
+// probeHTTP requests url as a probe: HEAD, or GET when bodies are sampled.
+func probeHTTP(ctx context.Context, client *http.Client, url string, cfg Config) (*http.Response, error) {
+	method := http.MethodHead
+	if cfg.FetchBody {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	if cfg.FetchBody {
+		// Decompress ourselves so the size cap and bomb check see both sides.
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	if prev := cfg.prevHTTP; prev != nil && prev.URL == url && prev.Error == nil {
+		if prev.ETag != "" {
+			req.Header.Set("If-None-Match", prev.ETag)
 		}
-		req2.Header.Set("User-Agent", cfg.UserAgent)
-		resp2, err2 := client.Do(req2)
-		if err2 != nil {
-			return res
+		if prev.LastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.LastModified)
 		}
-		defer resp2.Body.Close()
-		res.Status = resp2.Status
-		res.StatusCode = resp2.StatusCode
-		res.Location = resp2.Header.Get("Location")
-		res.Server = resp2.Header.Get("Server")
-		return res
 	}
+	return client.Do(req)
+}
 
+// contentChanged compares two probes of a page by the strongest evidence
+// both have: ETag, then Last-Modified, then the body hash.
+func contentChanged(prev, cur HTTPResult) bool {
+	switch {
+	case prev.ETag != "" && cur.ETag != "":
+		return prev.ETag != cur.ETag
+	case prev.LastModified != "" && cur.LastModified != "":
+		return prev.LastModified != cur.LastModified
+	case prev.BodySHA256 != "" && cur.BodySHA256 != "":
+		return prev.BodySHA256 != cur.BodySHA256
+	}
+	return false
+}
+
+// processHTTPResponse copies response metadata into res and, when body fetching
+// is enabled, reads a bounded sample of the body for title, hash and tracking IDs.
+func processHTTPResponse(res *HTTPResult, resp *http.Response, cfg Config) {
 	res.Status = resp.Status
 	res.StatusCode = resp.StatusCode
 	res.Location = resp.Header.Get("Location")
 	res.Server = resp.Header.Get("Server")
+	res.ETag = resp.Header.Get("ETag")
+	res.LastModified = resp.Header.Get("Last-Modified")
+	res.HeaderFingerprint = HeaderFingerprint(resp.Header)
+
+	var body []byte
+	if cfg.FetchBody {
+		buf := bodyPool.Get().(*bytes.Buffer)
+		buf.Reset()
+		defer bodyPool.Put(buf)
+		body = sampleBody(res, resp, cfg, buf)
+	}
+	if cfg.Rules != nil {
+		res.RuleMatches = cfg.Rules.Match(resp.StatusCode, resp.Header, body)
+	}
+}
 
-	if len(res.RedirectChain) > 0 {
-		res.HasRedirect = true
+// sampleBody reads up to the size cap of an allowed body into buf and
+// fingerprints it. It returns nil when the body was skipped or unreadable.
+func sampleBody(res *HTTPResult, resp *http.Response, cfg Config, buf *bytes.Buffer) []byte {
+	res.ContentType = resp.Header.Get("Content-Type")
+	if !cfg.bodyAllowed(res.ContentType) {
+		res.BodySkipped = "content_type"
+		return nil
 	}
 
-	return res
+	limit := cfg.MaxBodyBytes
+	if limit <= 0 {
+		limit = DefaultMaxBodyBytes
+	}
+	raw := &countingReader{r: io.LimitReader(resp.Body, limit+1)}
+	var src io.Reader = raw
+	compressed := false
+	switch enc := strings.ToLower(resp.Header.Get("Content-Encoding")); enc {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(raw)
+		if err != nil {
+			return nil
+		}
+		defer gz.Close()
+		src, compressed = gz, true
+	default: // never requested; don't try to interpret it
+		res.BodySkipped = "encoding"
+		return nil
+	}
+
+	// Read one byte past the cap to tell a full body from a truncated one.
+	_, err := buf.ReadFrom(io.LimitReader(src, limit+1))
+	if int64(buf.Len()) > limit {
+		buf.Truncate(int(limit))
+		res.BodyTruncated = true
+	}
+	body := buf.Bytes()
+	if err != nil && len(body) == 0 {
+		return nil
+	}
+	if res.BodyTruncated && compressed && int64(len(body)) > maxCompressionRatio*raw.n {
+		res.Signals = append(res.Signals, "decompression_bomb")
+	}
+	sum := sha256.Sum256(body)
+	res.BodySHA256 = hex.EncodeToString(sum[:])
+	res.ContentLength = int64(len(body))
+	res.Title = extractTitle(body)
+	res.TrackingIDs = ExtractTrackingIDs(body)
+	if u, err := url.Parse(res.URL); err == nil {
+		res.OwnLinks = countLinks(body, u.Hostname())
+	}
+	if cfg.Base != nil {
+		res.BaseLinks = countLinks(body, cfg.Base.Domain)
+	}
+	res.Signals = append(res.Signals, contentSignals(body)...)
+	if cfg.BodyInspector != nil {
+		res.Signals = append(res.Signals, cfg.BodyInspector.Inspect(body)...)
+	}
+	if resp.Request != nil {
+		res.FaviconURL = faviconURL(resp.Request.URL, body)
+	}
+	if cfg.Scanner != nil {
+		res.ScanMatches = scanMatches("body", cfg.Scanner.Scan("body", body))
+	}
+	if cfg.BrandAssets != nil {
+		res.BrandAssets = cfg.BrandAssets.MatchPage(body)
+		if cfg.BrandAssets.HasLogos() && resp.Request != nil {
+			res.LogoURLs = logoURLs(resp.Request.URL, body)
+		}
+	}
+	return body
+}
+
+// bodyAllowed reports whether a Content-Type is on the body allowlist. A
+// missing Content-Type is sampled, as many parking pages omit it.
+func (cfg Config) bodyAllowed(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	allow := cfg.BodyContentTypes
+	if len(allow) == 0 {
+		allow = DefaultBodyContentTypes
+	}
+	return slices.Contains(allow, mediaType)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+var titleRe = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// extractTitle returns the whitespace-normalized contents of the first <title> element.
+func extractTitle(body []byte) string {
+	m := titleRe.FindSubmatch(body)
+	if m == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(string(m[1]))), " ")
 }
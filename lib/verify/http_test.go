@@ -1,54 +1,55 @@
 package verify
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"squatrr/lib/verify/verifytest"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestCreateHTTPClient(t *testing.T) {
-	type args struct {
-		cfg    Config
-		result HTTPResult
-	}
-
 	tests := []struct {
 		name string
-		args args
-		want http.Client
+		cfg  Config
 	}{
 		{
 			name: "Test case 1: Check creation of client when HTTPFollowRedirects is true",
-			args: args{
-				cfg: Config{
-					HTTPTimeout:         time.Second * 5,
-					HTTPFollowRedirects: true,
-				},
-				result: HTTPResult{RedirectChain: []string{"redirect1.test", "redirect2.test"}},
+			cfg: Config{
+				HTTPTimeout:         time.Second * 5,
+				HTTPFollowRedirects: true,
 			},
 		},
 		{
 			name: "Test case 2: Check creation of client when HTTPFollowRedirects is false",
-			args: args{
-				cfg: Config{
-					HTTPTimeout:         time.Second * 10,
-					HTTPFollowRedirects: false,
-				},
-				result: HTTPResult{RedirectChain: []string{}},
+			cfg: Config{
+				HTTPTimeout:         time.Second * 10,
+				HTTPFollowRedirects: false,
 			},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := configureHTTPClient(tt.args.cfg, tt.args.result)
-			if got.Timeout != tt.args.cfg.HTTPTimeout {
-				t.Errorf("createHTTPClient() = %v, want %v", got.Timeout, tt.args.cfg.HTTPTimeout)
+			got := configureHTTPClient(tt.cfg)
+			if got.Timeout != tt.cfg.HTTPTimeout {
+				t.Errorf("createHTTPClient() = %v, want %v", got.Timeout, tt.cfg.HTTPTimeout)
 			}
 			// note really sure how to test the redirect chain code or if necessary
 			req, _ := http.NewRequest("GET", "http://example.com", nil)
 
-			if !tt.args.cfg.HTTPFollowRedirects {
+			if !tt.cfg.HTTPFollowRedirects {
 				if err := got.CheckRedirect(req, []*http.Request{}); err != http.ErrUseLastResponse {
 					t.Errorf("createHTTPClient() CheckRedirect expected ErrUseLastResponse")
 				}
@@ -61,3 +62,364 @@ func TestCreateHTTPClient(t *testing.T) {
 		})
 	}
 }
+
+func TestFetchHTTPRedirectChain(t *testing.T) {
+	// HTTPS is refused; plain HTTP redirects to a login page.
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		resp := &http.Response{Header: http.Header{}, Body: http.NoBody, Request: r}
+		switch {
+		case r.URL.Scheme == "https":
+			return nil, errors.New("connection refused")
+		case r.URL.Path == "/":
+			resp.StatusCode = http.StatusFound
+			resp.Header.Set("Location", "/login")
+		default:
+			resp.StatusCode = http.StatusOK
+		}
+		return resp, nil
+	})
+	wantChain := []Hop{
+		{URL: "https://exampel.com/", Scheme: "https", Error: "connection refused"},
+		{URL: "http://exampel.com/", Scheme: "http", Status: http.StatusFound, Location: "/login"},
+		{URL: "http://exampel.com/login", Scheme: "http", Status: http.StatusOK},
+	}
+	tests := []struct {
+		follow    bool
+		wantChain []Hop
+		wantFinal string
+	}{
+		{true, wantChain, "http://exampel.com/login"},
+		{false, wantChain[:2], "http://exampel.com/"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("follow=", tt.follow), func(t *testing.T) {
+			cfg := Config{HTTPTimeout: time.Second, HTTPFollowRedirects: tt.follow, Transport: transport}
+			res := fetchHTTP(context.Background(), true, "exampel.com", cfg)
+			if !reflect.DeepEqual(res.RedirectChain, tt.wantChain) {
+				t.Errorf("RedirectChain = %+v, want %+v", res.RedirectChain, tt.wantChain)
+			}
+			if res.URL != "http://exampel.com/" || res.FinalURL != tt.wantFinal || !res.HasRedirect {
+				t.Errorf("URL, FinalURL, HasRedirect = %q, %q, %v, want the fallback landing on %q", res.URL, res.FinalURL, res.HasRedirect, tt.wantFinal)
+			}
+		})
+	}
+}
+
+func TestExtractTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "Simple title", body: "<html><head><title>Sign in</title></head></html>", want: "Sign in"},
+		{name: "Attributes, entities and whitespace", body: "<TITLE lang=\"en\">\n  Example &amp; Co\n  Login </TITLE>", want: "Example & Co Login"},
+		{name: "No title", body: "<html><body>parked</body></html>", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractTitle([]byte(tt.body)); got != tt.want {
+				t.Errorf("extractTitle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentSignals(t *testing.T) {
+	tests := []struct {
+		body string
+		want []string
+	}{
+		{`<form action="https://collect.example/p"><input type="password" name="p">Sign in</form>`, []string{"password_field", "external_form", "login_form"}},
+		{`<form action="/search"><input type=text></form>`, nil},
+		{`<p>Welcome</p>`, nil},
+	}
+	for _, tt := range tests {
+		if got := contentSignals([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("contentSignals(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestDialProbeRandomSourcePort(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	cfg := Config{RandomSourcePort: true}
+	conn, err := cfg.dialProbe(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dialProbe() error: %v", err)
+	}
+	defer conn.Close()
+	if port := conn.LocalAddr().(*net.TCPAddr).Port; port < ephemeralLow || port >= ephemeralHigh {
+		t.Errorf("dialProbe() local port = %d, want [%d, %d)", port, ephemeralLow, ephemeralHigh)
+	}
+	if _, ok := cfg.probeTransport().(*http.Transport); !ok {
+		t.Errorf("probeTransport() is not an *http.Transport")
+	}
+}
+
+func TestFetchHTTPBodyLimits(t *testing.T) {
+	page := "<html><title>Parked</title>" + strings.Repeat("x", 4096) + "</html>"
+	var bomb bytes.Buffer
+	gz := gzip.NewWriter(&bomb)
+	gz.Write(bytes.Repeat([]byte{'a'}, 8<<20))
+	gz.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, page)
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(make([]byte, 1024))
+		case "/bomb":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(bomb.Bytes())
+		case "/brotli":
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Content-Encoding", "br")
+			fmt.Fprint(w, "garbage")
+		}
+	}))
+	defer srv.Close()
+
+	get := func(path string, cfg Config) HTTPResult {
+		cfg.FetchBody, cfg.HTTPTimeout = true, 2*time.Second
+		resp, err := http.Get(srv.URL + path)
+		if path == "/bomb" { // ask for gzip as fetchHTTP does, so it isn't decoded for us
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			resp, err = http.DefaultClient.Do(req)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var res HTTPResult
+		processHTTPResponse(&res, resp, cfg)
+		return res
+	}
+
+	if res := get("/", Config{}); res.Title != "Parked" || res.BodyTruncated || res.ContentLength != int64(len(page)) {
+		t.Errorf("html: %+v", res)
+	}
+	if res := get("/", Config{MaxBodyBytes: 1024}); !res.BodyTruncated || res.ContentLength != 1024 || res.Title != "Parked" {
+		t.Errorf("capped html: truncated=%v length=%d title=%q", res.BodyTruncated, res.ContentLength, res.Title)
+	}
+	if res := get("/image", Config{}); res.BodySkipped != "content_type" || res.BodySHA256 != "" {
+		t.Errorf("image: %+v", res)
+	}
+	if res := get("/image", Config{BodyContentTypes: []string{"image/png"}}); res.BodySkipped != "" || res.ContentLength != 1024 {
+		t.Errorf("allowed image: %+v", res)
+	}
+	if res := get("/bomb", Config{}); !res.BodyTruncated || res.ContentLength != DefaultMaxBodyBytes || !slices.Contains(res.Signals, "decompression_bomb") {
+		t.Errorf("bomb: truncated=%v length=%d signals=%v", res.BodyTruncated, res.ContentLength, res.Signals)
+	}
+	if res := get("/brotli", Config{}); res.BodySkipped != "encoding" {
+		t.Errorf("brotli: %+v", res)
+	}
+}
+
+func TestFaviconURL(t *testing.T) {
+	base, _ := url.Parse("https://examp1e.com/login/index.html")
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"default", `<html><head></head></html>`, "https://examp1e.com/favicon.ico"},
+		{"relative", `<link rel="icon" href="img/fav.png">`, "https://examp1e.com/login/img/fav.png"},
+		{"shortcut absolute", `<LINK REL='shortcut icon' HREF='//cdn.examp1e.net/f.ico'>`, "https://cdn.examp1e.net/f.ico"},
+		{"data uri", `<link rel=icon href=data:image/png;base64,AAAA>`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := faviconURL(base, []byte(tt.body)); got != tt.want {
+				t.Errorf("faviconURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+type memArchive struct{ exchanges []Exchange }
+
+func (m *memArchive) Archive(_ string, exchanges []Exchange) string {
+	m.exchanges = exchanges
+	return "mem"
+}
+
+func TestFetchHTTPArchive(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/home", http.StatusFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<title>Home</title>")
+	}))
+	defer srv.Close()
+
+	archive := &memArchive{}
+	cfg := Config{FetchBody: true, HTTPFollowRedirects: true, HTTPTimeout: 2 * time.Second, Archive: archive}
+	res := fetchHTTP(context.Background(), false, strings.TrimPrefix(srv.URL, "http://"), cfg)
+	if res.ArchivePath != "mem" || res.Title != "Home" {
+		t.Errorf("fetchHTTP() = %+v", res)
+	}
+	if len(archive.exchanges) < 2 || archive.exchanges[0].StatusCode != http.StatusFound || string(archive.exchanges[1].Body) != "<title>Home</title>" || archive.exchanges[1].RemoteAddr != "127.0.0.1" {
+		t.Errorf("archived exchanges = %+v", archive.exchanges)
+	}
+}
+
+// roundTripFunc adapts a function into an http.RoundTripper.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestFetchHTTPEgress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<title>%s</title>", r.Host)
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+
+	// A custom transport sees every request and may send it anywhere.
+	var hosts []string
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		hosts = append(hosts, r.URL.Host)
+		r.URL.Host = target.Host
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	cfg := Config{FetchBody: true, HTTPTimeout: 2 * time.Second, Transport: transport}
+	res := fetchHTTP(context.Background(), false, "exampel.com", cfg)
+	if res.StatusCode != http.StatusOK || res.Title != "exampel.com" || !reflect.DeepEqual(hosts, []string{"exampel.com"}) {
+		t.Errorf("fetchHTTP() with Transport = %+v, requests to %v", res, hosts)
+	}
+
+	// A custom dialer is used under the default transport.
+	d := &pinnedDialer{addr: target.Host}
+	cfg = Config{FetchBody: true, HTTPTimeout: 2 * time.Second, Dialer: d, RandomSourcePort: true}
+	res = fetchHTTP(context.Background(), false, "exampel.com", cfg)
+	if res.StatusCode != http.StatusOK || res.Title != "exampel.com" || !reflect.DeepEqual(d.dials, []string{"exampel.com:80"}) {
+		t.Errorf("fetchHTTP() with Dialer = %+v, dialed %v", res, d.dials)
+	}
+}
+
+// blackholeDialer never gets an answer, as for a host dropping SYNs.
+type blackholeDialer struct{}
+
+func (blackholeDialer) DialContext(ctx context.Context, _, _ string) (net.Conn, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestVerifyDomainTiming(t *testing.T) {
+	// Accepts, never answers.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		var held []net.Conn
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				break
+			}
+			held = append(held, c)
+		}
+		for _, c := range held {
+			c.Close()
+		}
+	}()
+	fast := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+	})
+	tests := []struct {
+		name      string
+		dialer    Dialer
+		transport http.RoundTripper
+		wantSlow  bool
+	}{
+		{"tarpit", fixedDialer(ln.Addr().String()), nil, true},
+		{"blackhole", blackholeDialer{}, nil, false},
+		{"fast", nil, fast, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{
+				Resolver:    verifytest.NewResolver(testZone),
+				DoHTTP:      true,
+				HTTPTimeout: 200 * time.Millisecond,
+				Dialer:      tt.dialer,
+				Transport:   tt.transport,
+			}
+			v, err := VerifyDomain(context.Background(), "exampel.com", cfg)
+			if err != nil {
+				t.Fatalf("VerifyDomain() error: %v", err)
+			}
+			if v.Timing.Slow != tt.wantSlow || v.Timing.TotalMillis < v.Timing.HTTPMillis {
+				t.Errorf("Timing = %+v, want slow %v and total covering the HTTP stage", v.Timing, tt.wantSlow)
+			}
+			if tt.dialer != nil && v.Timing.HTTPMillis < 150 {
+				t.Errorf("HTTPMillis = %d, want about the 200ms timeout", v.Timing.HTTPMillis)
+			}
+		})
+	}
+}
+
+func TestFetchHTTPConditional(t *testing.T) {
+	etag, requests := `"v1"`, 0
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag {
+			return &http.Response{StatusCode: http.StatusNotModified, Header: http.Header{}, Body: http.NoBody, Request: r}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Etag": {etag}, "Content-Type": {"text/html"}}, Request: r,
+			Body: io.NopCloser(strings.NewReader("<title>" + etag + "</title>"))}, nil
+	})
+	cfg := Config{HTTPTimeout: time.Second, FetchBody: true, Transport: transport}
+
+	first := fetchHTTP(context.Background(), true, "exampel.com", cfg)
+	if first.ETag != `"v1"` || !first.ContentChangedAt.IsZero() {
+		t.Fatalf("first probe = %+v, want ETag v1 and no change yet", first)
+	}
+
+	cfg.prevHTTP = &first
+	same := fetchHTTP(context.Background(), true, "exampel.com", cfg)
+	if !same.NotModified || same.Title != first.Title || same.StatusCode != http.StatusOK {
+		t.Errorf("revalidated probe = %+v, want the previous result kept as not modified", same)
+	}
+
+	etag = `"v2"`
+	before := time.Now()
+	changed := fetchHTTP(context.Background(), true, "exampel.com", cfg)
+	if changed.NotModified || changed.Title != `"v2"` || changed.ContentChangedAt.Before(before) {
+		t.Errorf("changed probe = %+v, want new content stamped changed", changed)
+	}
+
+	cfg.prevHTTP = &changed
+	again := fetchHTTP(context.Background(), true, "exampel.com", cfg)
+	if !again.ContentChangedAt.Equal(changed.ContentChangedAt) {
+		t.Errorf("ContentChangedAt = %v, want carried over as %v", again.ContentChangedAt, changed.ContentChangedAt)
+	}
+	if requests != 4 {
+		t.Errorf("requests = %d, want 4", requests)
+	}
+}
@@ -0,0 +1,183 @@
+package verify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// MaxNegativeTTL caps how long a negative answer is cached, whatever the
+// zone's SOA says, so a name registered mid-campaign is seen within hours.
+const MaxNegativeTTL = 3 * time.Hour
+
+// NegativeTTLResolver is implemented by resolvers that can report how long
+// a negative answer for a name may be cached. Without it, the system
+// resolver's nameserver is asked for the SOA directly.
+type NegativeTTLResolver interface {
+	NegativeTTL(ctx context.Context, name string) (time.Duration, error)
+}
+
+// negativeEntry is the StageNegative cache value: the name had no records
+// when last resolved, and may be skipped until Until.
+type negativeEntry struct {
+	Until time.Time `json:"until"`
+}
+
+// isNegative reports whether a DNS stage found nothing because the name
+// does not exist, as opposed to a lookup failure worth retrying.
+func isNegative(r DNSResult, err error) bool {
+	var dnsErr *net.DNSError
+	return !r.HasA && !r.HasAAAA && !r.HasCNAME && !r.HasMX && !r.HasNS &&
+		errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// negativeTTL returns the negative caching TTL of name per RFC 2308: the
+// lesser of the SOA record's TTL and its MINIMUM field, capped at
+// MaxNegativeTTL. Zero means it could not be determined.
+func (c Config) negativeTTL(ctx context.Context, name string) time.Duration {
+	ctx, cancel := context.WithTimeout(ctx, c.DNSTimeout)
+	defer cancel()
+
+	var ttl time.Duration
+	var err error
+	if r, ok := c.resolver().(NegativeTTLResolver); ok {
+		ttl, err = r.NegativeTTL(ctx, name)
+	} else if c.Resolver == nil {
+		var server string
+		if server, err = systemNameserver(); err == nil {
+			ttl, err = querySOA(ctx, server, name)
+		}
+	} else {
+		return 0
+	}
+	if err != nil || ttl <= 0 {
+		return 0
+	}
+	return min(ttl, MaxNegativeTTL)
+}
+
+// querySOA asks server (host:port, or a DoH URL) for name's SOA and reads the negative
+// TTL off the SOA in the answer or, for a missing name, the authority
+// section.
+func querySOA(ctx context.Context, server, name string) (time.Duration, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return 0, err
+	}
+	id := uint16(rand.UintN(1 << 16))
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true})
+	if err := b.StartQuestions(); err != nil {
+		return 0, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET}); err != nil {
+		return 0, err
+	}
+	query, err := b.Finish()
+	if err != nil {
+		return 0, err
+	}
+
+	var resp []byte
+	if isDoH(server) {
+		resp, err = exchangeDoH(ctx, server, query)
+	} else {
+		resp, err = exchangeUDP(ctx, server, query)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return parseSOA(resp, id)
+}
+
+// exchangeUDP sends one DNS message to server (host:port) and reads the
+// answer.
+func exchangeUDP(ctx context.Context, server string, query []byte) ([]byte, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 1232)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// parseSOA returns the negative TTL from the first SOA of a response.
+func parseSOA(msg []byte, id uint16) (time.Duration, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(msg)
+	if err != nil {
+		return 0, err
+	}
+	if h.ID != id {
+		return 0, errors.New("dns: mismatched response id")
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return 0, err
+	}
+	next := p.AnswerHeader
+	for section := 0; section < 2; section++ {
+		for {
+			rh, err := next()
+			if errors.Is(err, dnsmessage.ErrSectionDone) {
+				break
+			}
+			if err != nil {
+				return 0, err
+			}
+			if rh.Type != dnsmessage.TypeSOA {
+				if err := skip(&p, section); err != nil {
+					return 0, err
+				}
+				continue
+			}
+			soa, err := p.SOAResource()
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(min(rh.TTL, soa.MinTTL)) * time.Second, nil
+		}
+		next = p.AuthorityHeader
+	}
+	return 0, errors.New("dns: no SOA in response")
+}
+
+func skip(p *dnsmessage.Parser, section int) error {
+	if section == 0 {
+		return p.SkipAnswer()
+	}
+	return p.SkipAuthority()
+}
+
+// systemNameserver returns the first nameserver of /etc/resolv.conf.
+func systemNameserver() (string, error) {
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if fields := strings.Fields(sc.Text()); len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(strings.Split(fields[1], "%")[0], "53"), nil
+		}
+	}
+	return "", errors.New("dns: no nameserver in /etc/resolv.conf")
+}
@@ -0,0 +1,123 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ResolverPool spreads DNS lookups over upstream nameservers. All the
+// lookups of one candidate go to the same server, picked round-robin, and
+// the server is recorded as DNSResult.Source, so answers that differ
+// between upstreams (filtering, poisoning, split-horizon) can be traced.
+type ResolverPool struct {
+	servers   []string
+	resolvers []*net.Resolver
+	next      atomic.Uint64
+}
+
+var (
+	_ Resolver            = (*ResolverPool)(nil)
+	_ NegativeTTLResolver = (*ResolverPool)(nil)
+	_ SRVResolver         = (*ResolverPool)(nil)
+)
+
+// NewResolverPool returns a pool over servers, each host or host:port
+// (port 53 when omitted), or a DNS-over-HTTPS endpoint URL (https://...).
+func NewResolverPool(servers []string) (*ResolverPool, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("resolver pool: no servers")
+	}
+	p := &ResolverPool{}
+	for _, s := range servers {
+		addr := normalizeUpstream(s)
+		p.servers = append(p.servers, addr)
+		p.resolvers = append(p.resolvers, newUpstreamResolver(addr))
+	}
+	return p, nil
+}
+
+// answerSource carries the upstream picked for one candidate through its
+// lookups: the server's index, or -1 before the first lookup.
+type answerSource struct{ server atomic.Int64 }
+
+type answerSourceKey struct{}
+
+// withAnswerSource returns a context pinning the pool lookups made with it
+// to one upstream, and the record of which.
+func withAnswerSource(ctx context.Context) (context.Context, *answerSource) {
+	src := &answerSource{}
+	src.server.Store(-1)
+	return context.WithValue(ctx, answerSourceKey{}, src), src
+}
+
+// pick returns the upstream for ctx's candidate, choosing one on first use.
+func (p *ResolverPool) pick(ctx context.Context) int {
+	src, ok := ctx.Value(answerSourceKey{}).(*answerSource)
+	if ok {
+		if i := src.server.Load(); i >= 0 {
+			return int(i)
+		}
+	}
+	i := int64((p.next.Add(1) - 1) % uint64(len(p.servers)))
+	if ok && !src.server.CompareAndSwap(-1, i) {
+		i = src.server.Load()
+	}
+	return int(i)
+}
+
+// dnsSource names the upstream that answered the lookups made under src
+// with Config.Resolver.
+func (c Config) dnsSource(src *answerSource) string {
+	if c.Resolver == nil {
+		return "system"
+	}
+	return sourceOf(c.Resolver, src)
+}
+
+// sourceOf names the upstream of r that answered the lookups made under
+// src: a pool server's address, or empty for resolvers that aren't pools.
+func sourceOf(r Resolver, src *answerSource) string {
+	if p, ok := r.(*ResolverPool); ok {
+		if i := src.server.Load(); i >= 0 {
+			return p.servers[i]
+		}
+	}
+	return ""
+}
+
+func (p *ResolverPool) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return p.resolvers[p.pick(ctx)].LookupIPAddr(ctx, host)
+}
+
+func (p *ResolverPool) LookupCNAME(ctx context.Context, host string) (string, error) {
+	return p.resolvers[p.pick(ctx)].LookupCNAME(ctx, host)
+}
+
+func (p *ResolverPool) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return p.resolvers[p.pick(ctx)].LookupMX(ctx, name)
+}
+
+func (p *ResolverPool) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	return p.resolvers[p.pick(ctx)].LookupNS(ctx, name)
+}
+
+func (p *ResolverPool) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	return p.resolvers[p.pick(ctx)].LookupTXT(ctx, name)
+}
+
+func (p *ResolverPool) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return p.resolvers[p.pick(ctx)].LookupHost(ctx, host)
+}
+
+func (p *ResolverPool) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	return p.resolvers[p.pick(ctx)].LookupSRV(ctx, service, proto, name)
+}
+
+// NegativeTTL implements NegativeTTLResolver, asking the candidate's
+// upstream for the SOA.
+func (p *ResolverPool) NegativeTTL(ctx context.Context, name string) (time.Duration, error) {
+	return querySOA(ctx, p.servers[p.pick(ctx)], name)
+}
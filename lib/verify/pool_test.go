@@ -0,0 +1,56 @@
+package verify
+
+import (
+	"context"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"testing"
+)
+
+func TestNewResolverPool(t *testing.T) {
+	p, err := NewResolverPool([]string{"9.9.9.9", " 192.0.2.53:5353", "2001:db8::53", "[2001:db8::54]:53"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"9.9.9.9:53", "192.0.2.53:5353", "[2001:db8::53]:53", "[2001:db8::54]:53"}
+	if !reflect.DeepEqual(p.servers, want) {
+		t.Errorf("servers = %v, want %v", p.servers, want)
+	}
+	if _, err := NewResolverPool(nil); err == nil {
+		t.Errorf("NewResolverPool(nil) succeeded")
+	}
+}
+
+func TestVerifyDomainAnswerSource(t *testing.T) {
+	// The second upstream filters the candidate, as a corporate resolver
+	// might.
+	open, err := verifytest.NewDNSServer(verifytest.Zone{"exampel.com": {A: []string{"192.0.2.10"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer open.Close()
+	filtering, err := verifytest.NewDNSServer(verifytest.Zone{"exampel.com": {A: []string{"0.0.0.0"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer filtering.Close()
+	pool, err := NewResolverPool([]string{open.Addr, filtering.Addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Config{Resolver: pool}
+	for _, want := range []struct{ source, a string }{{open.Addr, "192.0.2.10"}, {filtering.Addr, "0.0.0.0"}} {
+		v, err := VerifyDomain(context.Background(), "exampel.com", cfg)
+		if err != nil || v.DNS.Source != want.source || !reflect.DeepEqual(v.DNS.A, []string{want.a}) || v.DNS.Cached {
+			t.Errorf("VerifyDomain() = %+v, %v, want %s answered by %s", v.DNS, err, want.a, want.source)
+		}
+	}
+
+	cfg.Cache = mapCache{}
+	first, _ := VerifyDomain(context.Background(), "exampel.com", cfg)
+	again, err := VerifyDomain(context.Background(), "exampel.com", cfg)
+	if err != nil || !again.DNS.Cached || again.DNS.Source != first.DNS.Source || first.DNS.Cached {
+		t.Errorf("cached answer = %+v, %v, want Cached from %s", again.DNS, err, first.DNS.Source)
+	}
+}
@@ -0,0 +1,169 @@
+package verify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// PreflightHost is the name Preflight resolves and connects to: one that
+// always exists and serves HTTP and HTTPS.
+const PreflightHost = "example.com"
+
+// Preflight checks, before a scan, that this host can verify candidates
+// the way cfg asks, so a broken environment fails in seconds instead of
+// as a scan of timeouts:
+//
+//   - every resolver (each upstream of a pool, the consensus resolver, or
+//     the system one) answers PreflightHost, and answers a name that
+//     doesn't exist with NXDOMAIN rather than an address, which would make
+//     every candidate look registered;
+//   - outbound TCP 443 (TLS, HTTP, CT, RDAP and passive DNS) and 80 (HTTP)
+//     connect, for the stages that need them;
+//   - the passive DNS endpoint accepts its credentials.
+//
+// It returns one error per failed check, each saying what to change.
+func Preflight(ctx context.Context, cfg Config) error {
+	if cfg.DNSTimeout <= 0 {
+		cfg.DNSTimeout = 2 * time.Second
+	}
+	if cfg.HTTPTimeout <= 0 {
+		cfg.HTTPTimeout = 4 * time.Second
+	}
+	if cfg.TLSTimeout <= 0 {
+		cfg.TLSTimeout = 3 * time.Second
+	}
+	var errs []error
+	var addrs []string
+	for _, u := range preflightResolvers(cfg) {
+		found, err := checkResolver(ctx, u.name, u.r, cfg.DNSTimeout)
+		if err != nil {
+			errs = append(errs, err)
+		} else if addrs == nil {
+			addrs = found
+		}
+	}
+
+	var ports []string
+	probes := (cfg.DoTLS || cfg.DoHTTP) && !cfg.Passive
+	if probes || cfg.DoCT || cfg.DoRDAP || strings.HasPrefix(cfg.PDNSURL, "https:") {
+		ports = append(ports, "443")
+	}
+	if probes && cfg.DoHTTP {
+		ports = append(ports, "80")
+	}
+	if addrs != nil {
+		for _, port := range ports {
+			if err := checkPort(ctx, cfg, addrs, port); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	if cfg.PDNSURL != "" {
+		if err := checkPDNS(ctx, cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type namedResolver struct {
+	name string
+	r    Resolver
+}
+
+// preflightResolvers lists the resolvers cfg looks candidates up through,
+// a pool's upstreams one by one.
+func preflightResolvers(cfg Config) []namedResolver {
+	var out []namedResolver
+	add := func(prefix string, r Resolver) {
+		if p, ok := r.(*ResolverPool); ok {
+			for i, server := range p.servers {
+				out = append(out, namedResolver{prefix + server, p.resolvers[i]})
+			}
+			return
+		}
+		out = append(out, namedResolver{prefix + "(configured)", r})
+	}
+	if cfg.Resolver == nil {
+		out = append(out, namedResolver{"system resolver", net.DefaultResolver})
+	} else {
+		add("resolver ", cfg.Resolver)
+	}
+	if cfg.ConsensusResolver != nil {
+		add("consensus resolver ", cfg.ConsensusResolver)
+	}
+	return out
+}
+
+// checkResolver resolves PreflightHost through r, returning its addresses,
+// then makes sure a random name under it does not resolve.
+func checkResolver(ctx context.Context, name string, r Resolver, timeout time.Duration) ([]string, error) {
+	lookup := func(host string) ([]string, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return r.LookupHost(ctx, host)
+	}
+	addrs, err := lookup(PreflightHost)
+	if err != nil || len(addrs) == 0 {
+		return nil, fmt.Errorf("%s cannot resolve %s (%v): check its address and that outbound DNS (port 53, or 443 for DoH) is allowed", name, PreflightHost, err)
+	}
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	missing := "sasquat-preflight-" + hex.EncodeToString(nonce) + "." + PreflightHost
+	if bogus, err := lookup(missing); err == nil && len(bogus) > 0 {
+		return nil, fmt.Errorf("%s answers names that don't exist (%s resolved to %s), so every candidate would look registered: use a resolver that returns NXDOMAIN", name, missing, strings.Join(bogus, ", "))
+	}
+	return addrs, nil
+}
+
+// checkPort connects to port on any of addrs within the TLS timeout, the
+// way probes dial.
+func checkPort(ctx context.Context, cfg Config, addrs []string, port string) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.TLSTimeout)
+	defer cancel()
+	var err error
+	for _, a := range addrs {
+		var conn net.Conn
+		if conn, err = cfg.dialProbe(ctx, "tcp", net.JoinHostPort(a, port)); err == nil {
+			conn.Close()
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot connect out to TCP %s (%s: %v), so every stage using it would time out: allow outbound %s, or turn those stages off", port, PreflightHost, err, port)
+}
+
+// checkPDNS queries the passive DNS endpoint for PreflightHost.
+func checkPDNS(ctx context.Context, cfg Config) error {
+	ctx, cancel := context.WithTimeout(ctx, cfg.HTTPTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(cfg.PDNSURL, "/")+"/"+PreflightHost, nil)
+	if err != nil {
+		return fmt.Errorf("passive DNS URL %q: %w", cfg.PDNSURL, err)
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	if cfg.PDNSUser != "" || cfg.PDNSKey != "" {
+		req.SetBasicAuth(cfg.PDNSUser, cfg.PDNSKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("passive DNS at %s is unreachable: %w", req.URL.Host, err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("passive DNS at %s rejected the credentials (%s): check the user and key", req.URL.Host, resp.Status)
+	case resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound:
+		// Common Output Format servers answer 404 for a name without
+		// history.
+		return fmt.Errorf("passive DNS at %s answered %s: check the URL", req.URL.Host, resp.Status)
+	}
+	return nil
+}
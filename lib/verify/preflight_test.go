@@ -0,0 +1,97 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"squatrr/lib/verify/verifytest"
+	"strings"
+	"testing"
+)
+
+// hijacking answers every name, as resolvers rewriting NXDOMAIN do.
+type hijacking struct{ *verifytest.Resolver }
+
+func (hijacking) LookupHost(context.Context, string) ([]string, error) {
+	return []string{"192.0.2.99"}, nil
+}
+
+// portDialer connects only to the open ports, without touching the network.
+type portDialer struct{ open []string }
+
+func (d portDialer) DialContext(_ context.Context, _, addr string) (net.Conn, error) {
+	_, port, _ := net.SplitHostPort(addr)
+	for _, p := range d.open {
+		if p == port {
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+	}
+	return nil, errors.New("connection refused")
+}
+
+func TestPreflight(t *testing.T) {
+	pdns := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, key, _ := r.BasicAuth(); user != "me" || key != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound) // no history for the name
+	}))
+	defer pdns.Close()
+	good := verifytest.NewResolver(verifytest.Zone{PreflightHost: {A: []string{"192.0.2.1"}}})
+
+	for _, tc := range []struct {
+		name string
+		cfg  Config
+		want []string // substrings of the expected errors, one per failed check
+	}{
+		{"healthy", Config{Resolver: good, DoTLS: true, DoHTTP: true, Dialer: portDialer{[]string{"443", "80"}}, PDNSURL: pdns.URL, PDNSUser: "me", PDNSKey: "secret"}, nil},
+		{"no DNS", Config{Resolver: verifytest.NewResolver(nil), DoTLS: true, Dialer: portDialer{}}, []string{"cannot resolve example.com"}},
+		{"NXDOMAIN rewritten", Config{Resolver: hijacking{good}}, []string{"answers names that don't exist"}},
+		{"consensus broken", Config{Resolver: good, ConsensusResolver: verifytest.NewResolver(nil)}, []string{"consensus resolver (configured) cannot resolve"}},
+		{"port 80 blocked", Config{Resolver: good, DoHTTP: true, Dialer: portDialer{[]string{"443"}}}, []string{"TCP 80"}},
+		{"passive needs no probe ports", Config{Resolver: good, DoTLS: true, DoHTTP: true, Passive: true, Dialer: portDialer{}}, nil},
+		{"CT needs 443", Config{Resolver: good, Passive: true, DoCT: true, Dialer: portDialer{}}, []string{"TCP 443"}},
+		{"bad key", Config{Resolver: good, PDNSURL: pdns.URL + "/", PDNSUser: "me", PDNSKey: "wrong"}, []string{"rejected the credentials (401 Unauthorized)"}},
+	} {
+		err := Preflight(context.Background(), tc.cfg)
+		var got []error
+		if err != nil {
+			got = err.(interface{ Unwrap() []error }).Unwrap()
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: Preflight() = %v, want %d errors", tc.name, err, len(tc.want))
+			continue
+		}
+		for i, want := range tc.want {
+			if !strings.Contains(got[i].Error(), want) {
+				t.Errorf("%s: error %q, want it to mention %q", tc.name, got[i], want)
+			}
+		}
+	}
+}
+
+func TestPreflightResolverPool(t *testing.T) {
+	up, err := verifytest.NewDNSServer(verifytest.Zone{PreflightHost: {A: []string{"192.0.2.1"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer up.Close()
+	empty, err := verifytest.NewDNSServer(verifytest.Zone{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	pool, err := NewResolverPool([]string{up.Addr, empty.Addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Preflight(context.Background(), Config{Resolver: pool})
+	if err == nil || !strings.Contains(err.Error(), "resolver "+empty.Addr+" cannot resolve") || strings.Contains(err.Error(), up.Addr) {
+		t.Errorf("Preflight() = %v, want only upstream %s failing", err, empty.Addr)
+	}
+}
@@ -0,0 +1,105 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BaseSite is the base domain's own landing page as ProbeBase saw it:
+// what a candidate transparently reverse-proxying it (evilginx and the
+// like) serves back.
+type BaseSite struct {
+	Domain      string
+	Fingerprint string // HeaderFingerprint; "" when too generic to compare
+	Title       string
+	Cookies     []string // names of the cookies it sets
+	SelfLinks   bool     // its page links to its own hosts by absolute URL
+}
+
+// ProbeBase probes base the way cfg probes candidates, for Config.Base.
+func ProbeBase(ctx context.Context, base string, cfg Config) (*BaseSite, error) {
+	cfg.Cache, cfg.Archive, cfg.BrandAssets, cfg.Scanner, cfg.Base, cfg.prevHTTP = nil, nil, nil, nil, nil, nil
+	ctx, cancel := context.WithTimeout(ctx, cfg.HTTPTimeout)
+	defer cancel()
+	site := &BaseSite{Domain: strings.ToLower(strings.TrimSuffix(base, "."))}
+	res := fetchHTTP(ctx, true, site.Domain, cfg)
+	if res.Error != nil {
+		return nil, errors.New(res.Error.Message)
+	}
+	site.Fingerprint, site.Title, site.SelfLinks = res.HeaderFingerprint, res.Title, res.OwnLinks > 0
+	for _, c := range res.Cookies {
+		if !slices.Contains(site.Cookies, c.Name) {
+			site.Cookies = append(site.Cookies, c.Name)
+		}
+	}
+	return site, nil
+}
+
+// Reverse-proxy indicators (HTTPResult.ProxyIndicators).
+const (
+	ProxyHeaders      = "headers"       // the base site's header fingerprint (MirrorsBase)
+	ProxyTitle        = "title"         // the base site's title
+	ProxyLinks        = "links"         // absolute links rewritten from the base site's hosts to the candidate's
+	ProxyCookieDomain = "cookie_domain" // a base site cookie, scoped to the candidate's domain
+	ProxyNewCert      = "new_cert"      // a certificate issued within NewCertDays, alongside another indicator
+)
+
+// NewCertDays is how recent a certificate counts as ProxyNewCert: a proxy
+// is stood up, certified and used within days.
+const NewCertDays = 7
+
+// linkHostRe captures the host of absolute links in attributes.
+var linkHostRe = regexp.MustCompile(`(?i)(?:href|src|action)\s*=\s*["']?(?:https?:)?//([a-z0-9.-]+)`)
+
+// countLinks counts the absolute links in body to domain or its
+// subdomains.
+func countLinks(body []byte, domain string) int {
+	n := 0
+	for _, m := range linkHostRe.FindAllSubmatch(body, -1) {
+		if onHost(string(m[1]), domain) {
+			n++
+		}
+	}
+	return n
+}
+
+// proxyIndicators lists what res (and the candidate's certificate) shares
+// with the base site that a transparent reverse proxy of it would.
+func proxyIndicators(res *HTTPResult, tls *TLSResult, domain string, base *BaseSite, now time.Time) []string {
+	if base == nil || !landedOn(res, domain) {
+		return nil
+	}
+	var out []string
+	if mirrorsBase(res, domain, base) {
+		out = append(out, ProxyHeaders)
+	}
+	if base.Title != "" && strings.EqualFold(strings.TrimSpace(res.Title), strings.TrimSpace(base.Title)) {
+		out = append(out, ProxyTitle)
+	}
+	if base.SelfLinks && res.OwnLinks > 0 && res.BaseLinks == 0 {
+		out = append(out, ProxyLinks)
+	}
+	for _, c := range res.Cookies {
+		if slices.Contains(base.Cookies, c.Name) && c.Domain != "" && onHost(strings.TrimPrefix(c.Domain, "."), domain) {
+			out = append(out, ProxyCookieDomain)
+			break
+		}
+	}
+	if len(out) > 0 && tls != nil && tls.Connected && !tls.NotBefore.IsZero() && now.Sub(tls.NotBefore) < NewCertDays*24*time.Hour {
+		out = append(out, ProxyNewCert)
+	}
+	return out
+}
+
+// ReverseProxy reports whether indicators show a transparent reverse
+// proxy of the base site: three of them, or two when one is the mirrored
+// header set or a rewritten cookie domain, which an independent page does
+// not produce.
+func ReverseProxy(indicators []string) bool {
+	strong := slices.Contains(indicators, ProxyHeaders) || slices.Contains(indicators, ProxyCookieDomain)
+	return len(indicators) >= 3 || (len(indicators) >= 2 && strong)
+}
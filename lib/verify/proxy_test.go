@@ -0,0 +1,74 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProbeBase(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, h := range []string{"Server", "X-Acme-Region", "X-Acme-Request-Id", "Cache-Control", "Vary"} {
+			w.Header().Set(h, "1")
+		}
+		w.Header().Set("Content-Type", "text/html")
+		http.SetCookie(w, &http.Cookie{Name: "ACMESESSID", Value: "1"})
+		fmt.Fprintf(w, `<title>Acme sign in</title><a href="http://%s/help">Help</a>`, r.Host)
+	}))
+	defer srv.Close()
+	cfg := Config{HTTPTimeout: 2 * time.Second, FetchBody: true}
+	site, err := ProbeBase(context.Background(), strings.TrimPrefix(srv.URL, "http://"), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(site.Fingerprint) != 64 || site.Title != "Acme sign in" || !reflect.DeepEqual(site.Cookies, []string{"ACMESESSID"}) || !site.SelfLinks {
+		t.Errorf("ProbeBase() = %+v", site)
+	}
+}
+
+func TestProxyIndicators(t *testing.T) {
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	base := &BaseSite{Domain: "example.com", Fingerprint: "abc", Title: "Example - Sign in", Cookies: []string{"EXSESSID"}, SelfLinks: true}
+	proxied := func() HTTPResult {
+		return HTTPResult{
+			FinalURL: "https://login.examp1e.com/signin", HeaderFingerprint: "abc", Title: "Example - Sign in",
+			OwnLinks: 12, Cookies: []Cookie{{Name: "EXSESSID", Domain: ".examp1e.com"}},
+		}
+	}
+	fresh := &TLSResult{Connected: true, NotBefore: now.Add(-48 * time.Hour)}
+	tests := []struct {
+		name  string
+		edit  func(*HTTPResult)
+		tls   *TLSResult
+		want  []string
+		proxy bool
+	}{
+		{"evilginx", func(*HTTPResult) {}, fresh,
+			[]string{ProxyHeaders, ProxyTitle, ProxyLinks, ProxyCookieDomain, ProxyNewCert}, true},
+		{"links left pointing at the base", func(h *HTTPResult) { h.BaseLinks = 3; h.HeaderFingerprint = "" }, nil,
+			[]string{ProxyTitle, ProxyCookieDomain}, true},
+		{"cloned title on an old certificate", func(h *HTTPResult) { h.HeaderFingerprint, h.OwnLinks, h.Cookies = "def", 0, nil },
+			&TLSResult{Connected: true, NotBefore: now.AddDate(0, -6, 0)}, []string{ProxyTitle}, false},
+		{"redirects to the real site", func(h *HTTPResult) { h.FinalURL = "https://www.example.com/" }, fresh, nil, false},
+		{"unrelated page", func(h *HTTPResult) { *h = HTTPResult{FinalURL: "https://examp1e.com/", Title: "Blog", OwnLinks: 4} }, fresh,
+			[]string{ProxyLinks, ProxyNewCert}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := proxied()
+			tt.edit(&res)
+			got := proxyIndicators(&res, tt.tls, "examp1e.com", base, now)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("proxyIndicators() = %v, want %v", got, tt.want)
+			}
+			if ReverseProxy(got) != tt.proxy {
+				t.Errorf("ReverseProxy(%v) = %v, want %v", got, !tt.proxy, tt.proxy)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package verify
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// FallbackDelay is how long a probe's preferred address family (IPv6) gets
+// before the other is dialled in parallel, per RFC 8305.
+const FallbackDelay = 250 * time.Millisecond
+
+// probeAddrs are a candidate's resolved addresses, which its TLS and HTTP
+// probes dial directly instead of resolving the name again.
+type probeAddrs struct {
+	host     string
+	v6, v4   []string
+	fallback time.Duration
+}
+
+// withAddrs returns cfg with probes to host racing the addresses in dns.
+func (cfg Config) withAddrs(host string, dns DNSResult) Config {
+	if len(dns.A)+len(dns.AAAA) > 0 {
+		cfg.addrs = &probeAddrs{host: host, v6: dns.AAAA, v4: dns.A, fallback: FallbackDelay}
+	}
+	return cfg
+}
+
+// dialRace connects to one of addrs on port happy-eyeballs style: the IPv6
+// addresses are tried in turn, and the IPv4 ones alongside them once
+// fallback has passed or IPv6 has failed outright. The first connection
+// wins and the other attempt is cancelled, so a broken address family costs
+// at most the fallback delay rather than a full timeout.
+func dialRace(ctx context.Context, network, port string, addrs *probeAddrs, dial func(ctx context.Context, network, addr string) (net.Conn, error)) (net.Conn, error) {
+	primary, secondary := addrs.v6, addrs.v4
+	if len(primary) == 0 {
+		primary, secondary = secondary, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, 2)
+	family := func(ips []string) {
+		var err error
+		for _, ip := range ips {
+			var conn net.Conn
+			if conn, err = dial(ctx, network, net.JoinHostPort(ip, port)); err == nil {
+				results <- result{conn: conn}
+				return
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		results <- result{err: err}
+	}
+
+	go family(primary)
+	pending := 1
+	var timer <-chan time.Time
+	if len(secondary) > 0 {
+		t := time.NewTimer(addrs.fallback)
+		defer t.Stop()
+		timer = t.C
+	}
+	startSecondary := func() {
+		if len(secondary) > 0 {
+			go family(secondary)
+			secondary, timer = nil, nil
+			pending++
+		}
+	}
+
+	var firstErr error
+	for pending > 0 {
+		select {
+		case <-timer:
+			startSecondary()
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go func(n int) { // close a connection completing after the winner
+					for range n {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			startSecondary()
+		}
+	}
+	return nil, firstErr
+}
+
+// racing reports whether addr is the candidate's host, whose addresses
+// dialProbe races.
+func (a *probeAddrs) racing(addr string) (port string, ok bool) {
+	if a == nil {
+		return "", false
+	}
+	host, port, err := net.SplitHostPort(addr)
+	return port, err == nil && strings.EqualFold(host, a.host)
+}
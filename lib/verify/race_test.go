@@ -0,0 +1,125 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeDial simulates address families: each address either hangs until
+// cancelled, is refused, or connects.
+type fakeDial struct {
+	mu        sync.Mutex
+	hang      map[string]bool
+	refuse    map[string]bool
+	cancelled []string
+}
+
+func (f *fakeDial) dial(ctx context.Context, _, addr string) (net.Conn, error) {
+	host, _, _ := net.SplitHostPort(addr)
+	switch {
+	case f.hang[host]:
+		<-ctx.Done()
+		f.mu.Lock()
+		f.cancelled = append(f.cancelled, host)
+		f.mu.Unlock()
+		return nil, ctx.Err()
+	case f.refuse[host]:
+		return nil, syscall.ECONNREFUSED
+	}
+	c, _ := net.Pipe()
+	return c, nil
+}
+
+func TestDialRace(t *testing.T) {
+	tests := []struct {
+		name    string
+		v6, v4  []string
+		hang    []string
+		refuse  []string
+		wantErr bool
+		maxTime time.Duration
+	}{
+		{name: "IPv6 blackholed", v6: []string{"2001:db8::1"}, v4: []string{"192.0.2.1"}, hang: []string{"2001:db8::1"}, maxTime: time.Second},
+		{name: "IPv6 refused", v6: []string{"2001:db8::1"}, v4: []string{"192.0.2.1"}, refuse: []string{"2001:db8::1"}, maxTime: 100 * time.Millisecond},
+		{name: "second IPv4 address", v4: []string{"192.0.2.1", "192.0.2.2"}, refuse: []string{"192.0.2.1"}, maxTime: 100 * time.Millisecond},
+		{name: "all refused", v6: []string{"2001:db8::1"}, v4: []string{"192.0.2.1"}, refuse: []string{"2001:db8::1", "192.0.2.1"}, wantErr: true, maxTime: 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeDial{hang: map[string]bool{}, refuse: map[string]bool{}}
+			for _, h := range tt.hang {
+				f.hang[h] = true
+			}
+			for _, h := range tt.refuse {
+				f.refuse[h] = true
+			}
+			addrs := &probeAddrs{host: "exampel.com", v6: tt.v6, v4: tt.v4, fallback: 50 * time.Millisecond}
+
+			start := time.Now()
+			conn, err := dialRace(context.Background(), "tcp", "443", addrs, f.dial)
+			if elapsed := time.Since(start); elapsed > tt.maxTime {
+				t.Errorf("dialRace() took %v, want under %v", elapsed, tt.maxTime)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dialRace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if conn != nil {
+				conn.Close()
+			}
+			if errors.Is(err, context.Canceled) {
+				t.Errorf("dialRace() error = %v, want the dial error", err)
+			}
+		})
+	}
+}
+
+func TestDialRaceCancelsLoser(t *testing.T) {
+	f := &fakeDial{hang: map[string]bool{"2001:db8::1": true}}
+	addrs := &probeAddrs{host: "exampel.com", v6: []string{"2001:db8::1"}, v4: []string{"192.0.2.1"}, fallback: 10 * time.Millisecond}
+	conn, err := dialRace(context.Background(), "tcp", "443", addrs, f.dial)
+	if err != nil {
+		t.Fatalf("dialRace() error: %v", err)
+	}
+	conn.Close()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		f.mu.Lock()
+		n := len(f.cancelled)
+		f.mu.Unlock()
+		if n == 1 {
+			return
+		}
+	}
+	t.Errorf("the hanging IPv6 attempt was not cancelled")
+}
+
+func TestDialProbeRacesCandidate(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	// The candidate never resolves through the system resolver: only its
+	// DNS stage addresses lead to the listener.
+	cfg := Config{}.withAddrs("exampel.invalid", DNSResult{A: []string{"127.0.0.1"}})
+	conn, err := cfg.dialProbe(context.Background(), "tcp", net.JoinHostPort("exampel.invalid", port))
+	if err != nil {
+		t.Fatalf("dialProbe() error: %v", err)
+	}
+	conn.Close()
+}
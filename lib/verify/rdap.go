@@ -0,0 +1,242 @@
+package verify
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultRDAPBase is the RDAP bootstrap redirector used when Config.RDAPBase
+// is empty; it forwards /domain/<name> to the authoritative registry.
+const DefaultRDAPBase = "https://rdap.org"
+
+// RDAPResult is the registration data for a candidate. Registry answers are
+// supplemented with the registrar's own RDAP server (thin registries such as
+// .com only publish registrant details there).
+type RDAPResult struct {
+	Attempted      bool
+	Registrar      string
+	RegistrarID    string // IANA registrar ID
+	RegistrarClass string // see Config.Registrars
+	AbuseEmail     string
+	Privacy        bool   // registrant is redacted or behind a privacy/proxy service
+	PrivacyService string // the proxy organisation, when named
+	Registrant     string // registrant organisation or name when published
+	Created        time.Time
+	Expires        time.Time
+	Status         []string
+
+	// Raw holds the registry (and registrar) RDAP responses verbatim when
+	// Config.KeepRaw is set, for evidence bundles.
+	Raw []json.RawMessage `json:",omitempty"`
+}
+
+// RegistrarClassifier buckets registrars (e.g. brand protection, bulk,
+// abuse-friendly) from their RDAP name and IANA ID.
+type RegistrarClassifier interface {
+	RegistrarClass(name, ianaID string) string
+}
+
+// privacyMarkers identify redacted or proxied registrant contacts.
+var privacyMarkers = []string{
+	"privacy", "redacted", "proxy", "whoisguard", "withheld", "not disclosed",
+	"data protected", "gdpr", "masked", "private", "identity protect",
+	"domain protection", "domains by proxy", "contact protection",
+}
+
+type rdapEntity struct {
+	Roles      []string          `json:"roles"`
+	VCardArray []json.RawMessage `json:"vcardArray"`
+	PublicIDs  []struct {
+		Type       string `json:"type"`
+		Identifier string `json:"identifier"`
+	} `json:"publicIds"`
+	Remarks  []rdapRemark `json:"remarks"`
+	Entities []rdapEntity `json:"entities"`
+}
+
+type rdapRemark struct {
+	Title       string   `json:"title"`
+	Description []string `json:"description"`
+}
+
+type rdapDomain struct {
+	Status   []string     `json:"status"`
+	Entities []rdapEntity `json:"entities"`
+	Events   []struct {
+		Action string    `json:"eventAction"`
+		Date   time.Time `json:"eventDate"`
+	} `json:"events"`
+	Links []struct {
+		Rel  string `json:"rel"`
+		Href string `json:"href"`
+		Type string `json:"type"`
+	} `json:"links"`
+	Remarks []rdapRemark `json:"remarks"`
+}
+
+// lookupRDAP queries the registry for domain and, when it links to one, the
+// registrar's RDAP server for the registrant.
+func lookupRDAP(ctx context.Context, domain string, cfg Config) RDAPResult {
+	res := RDAPResult{Attempted: true}
+	base := cfg.RDAPBase
+	if base == "" {
+		base = DefaultRDAPBase
+	}
+
+	registry, raw, err := fetchRDAP(ctx, strings.TrimSuffix(base, "/")+"/domain/"+domain, cfg.UserAgent)
+	if err != nil {
+		return res
+	}
+	res.applyDomain(registry)
+	if cfg.KeepRaw {
+		res.Raw = append(res.Raw, raw)
+	}
+
+	for _, l := range registry.Links {
+		if l.Rel == "related" && strings.Contains(l.Type, "rdap") && l.Href != "" {
+			if registrar, raw, err := fetchRDAP(ctx, l.Href, cfg.UserAgent); err == nil {
+				res.applyDomain(registrar)
+				if cfg.KeepRaw {
+					res.Raw = append(res.Raw, raw)
+				}
+			}
+			break
+		}
+	}
+	if cfg.Registrars != nil {
+		res.RegistrarClass = cfg.Registrars.RegistrarClass(res.Registrar, res.RegistrarID)
+	}
+	return res
+}
+
+func fetchRDAP(ctx context.Context, url, userAgent string) (rdapDomain, json.RawMessage, error) {
+	var d rdapDomain
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return d, nil, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return d, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return d, nil, fmt.Errorf("rdap %s: %s", url, resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return d, nil, err
+	}
+	err = json.Unmarshal(raw, &d)
+	return d, raw, err
+}
+
+// applyDomain merges an RDAP domain object into res. Later (registrar)
+// answers fill in what earlier (registry) answers left empty.
+func (res *RDAPResult) applyDomain(d rdapDomain) {
+	if len(res.Status) == 0 {
+		res.Status = d.Status
+	}
+	for _, e := range d.Events {
+		switch e.Action {
+		case "registration":
+			if res.Created.IsZero() {
+				res.Created = e.Date
+			}
+		case "expiration":
+			if res.Expires.IsZero() {
+				res.Expires = e.Date
+			}
+		}
+	}
+	for _, r := range d.Remarks {
+		if hasPrivacyMarker(r.Title + " " + strings.Join(r.Description, " ")) {
+			res.Privacy = true
+		}
+	}
+
+	for _, e := range d.Entities {
+		switch {
+		case hasRole(e, "registrar"):
+			if res.Registrar == "" {
+				res.Registrar = vcardText(e.VCardArray, "fn")
+			}
+			for _, id := range e.PublicIDs {
+				if id.Type == "IANA Registrar ID" && res.RegistrarID == "" {
+					res.RegistrarID = id.Identifier
+				}
+			}
+			for _, sub := range e.Entities {
+				if hasRole(sub, "abuse") && res.AbuseEmail == "" {
+					res.AbuseEmail = vcardText(sub.VCardArray, "email")
+				}
+			}
+		case hasRole(e, "registrant"):
+			org, fn := vcardText(e.VCardArray, "org"), vcardText(e.VCardArray, "fn")
+			who := strings.TrimSpace(org + " " + fn)
+			for _, r := range e.Remarks {
+				who += " " + r.Title + " " + strings.Join(r.Description, " ")
+			}
+			if hasPrivacyMarker(who) {
+				res.Privacy = true
+				if org != "" && hasPrivacyMarker(org) && !strings.Contains(strings.ToLower(org), "redacted") {
+					res.PrivacyService = org
+				}
+			} else if res.Registrant == "" {
+				res.Registrant = cmp.Or(org, fn)
+			}
+		}
+	}
+}
+
+func hasRole(e rdapEntity, role string) bool {
+	for _, r := range e.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrivacyMarker(s string) bool {
+	s = strings.ToLower(s)
+	for _, m := range privacyMarkers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// vcardText returns the first text value of a jCard property:
+// ["vcard", [[name, params, type, value], ...]].
+func vcardText(card []json.RawMessage, name string) string {
+	if len(card) < 2 {
+		return ""
+	}
+	var props [][]json.RawMessage
+	if err := json.Unmarshal(card[1], &props); err != nil {
+		return ""
+	}
+	for _, p := range props {
+		if len(p) < 4 {
+			continue
+		}
+		var n, v string
+		if json.Unmarshal(p[0], &n) != nil || n != name {
+			continue
+		}
+		if json.Unmarshal(p[3], &v) == nil {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,58 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLookupRDAP(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/domain/exampel.com": // thin registry answer
+			fmt.Fprintf(w, `{
+				"status": ["client transfer prohibited"],
+				"events": [
+					{"eventAction": "registration", "eventDate": "2024-03-01T10:00:00Z"},
+					{"eventAction": "expiration", "eventDate": "2025-03-01T10:00:00Z"}
+				],
+				"entities": [{
+					"roles": ["registrar"],
+					"vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "NameCheap, Inc."]]],
+					"publicIds": [{"type": "IANA Registrar ID", "identifier": "1068"}],
+					"entities": [{"roles": ["abuse"], "vcardArray": ["vcard", [["email", {}, "text", "abuse@namecheap.com"]]]}]
+				}],
+				"links": [{"rel": "related", "type": "application/rdap+json", "href": "%s/registrar/domain/exampel.com"}]
+			}`, srv.URL)
+		case "/registrar/domain/exampel.com":
+			fmt.Fprint(w, `{
+				"entities": [{
+					"roles": ["registrant"],
+					"vcardArray": ["vcard", [["fn", {}, "text", "Redacted for Privacy"], ["org", {}, "text", "Privacy service provided by Withheld for Privacy ehf"]]]
+				}]
+			}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	got := lookupRDAP(context.Background(), "exampel.com", Config{RDAPBase: srv.URL})
+	if got.Registrar != "NameCheap, Inc." || got.RegistrarID != "1068" || got.AbuseEmail != "abuse@namecheap.com" {
+		t.Errorf("lookupRDAP() registrar = %q/%q/%q", got.Registrar, got.RegistrarID, got.AbuseEmail)
+	}
+	if !got.Privacy || got.PrivacyService != "Privacy service provided by Withheld for Privacy ehf" || got.Registrant != "" {
+		t.Errorf("lookupRDAP() privacy = %v/%q, registrant %q", got.Privacy, got.PrivacyService, got.Registrant)
+	}
+	if want := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC); !got.Expires.Equal(want) {
+		t.Errorf("lookupRDAP() expires = %v, want %v", got.Expires, want)
+	}
+
+	if missing := lookupRDAP(context.Background(), "nope.com", Config{RDAPBase: srv.URL}); missing.Registrar != "" || !missing.Attempted {
+		t.Errorf("lookupRDAP(missing) = %+v", missing)
+	}
+}
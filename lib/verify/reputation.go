@@ -0,0 +1,27 @@
+package verify
+
+// IPReputation reports which reputation feeds list an IP. lib/reputation
+// implements it over Spamhaus DROP, abuse.ch and similar lists.
+type IPReputation interface {
+	Lookup(ip string) []string
+}
+
+// ReputationMatch is a resolved IP found on a reputation feed.
+type ReputationMatch struct {
+	IP   string
+	Feed string
+}
+
+// checkReputation looks every resolved address up in cfg.Reputation.
+func checkReputation(dns DNSResult, cfg Config) []ReputationMatch {
+	if cfg.Reputation == nil {
+		return nil
+	}
+	var out []ReputationMatch
+	for _, ip := range append(append([]string{}, dns.A...), dns.AAAA...) {
+		for _, feed := range cfg.Reputation.Lookup(ip) {
+			out = append(out, ReputationMatch{IP: ip, Feed: feed})
+		}
+	}
+	return out
+}
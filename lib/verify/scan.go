@@ -0,0 +1,61 @@
+package verify
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+)
+
+// ContentScanner matches fetched content against external rules, e.g. YARA
+// phishing-kit rules (lib/yara). target says what is being scanned: "body"
+// or "favicon".
+type ContentScanner interface {
+	Scan(target string, data []byte) []string
+}
+
+// ScanMatch is a ContentScanner rule that matched fetched content.
+type ScanMatch struct {
+	Target string // "body" or "favicon"
+	Rule   string
+}
+
+// maxFaviconBytes caps the favicon download fed to Config.Scanner.
+const maxFaviconBytes = 256 << 10
+
+var iconLinkRe = regexp.MustCompile(`(?is)<link[^>]+rel\s*=\s*["']?(?:shortcut )?icon["']?[^>]*>`)
+var hrefRe = regexp.MustCompile(`(?is)href\s*=\s*["']?([^"'\s>]+)`)
+
+// faviconURL resolves the page's declared icon against base, falling back
+// to /favicon.ico.
+func faviconURL(base *url.URL, body []byte) string {
+	ref := "/favicon.ico"
+	if link := iconLinkRe.Find(body); link != nil {
+		if m := hrefRe.FindSubmatch(link); m != nil {
+			ref = string(m[1])
+		}
+	}
+	u, err := base.Parse(ref)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
+
+func scanMatches(target string, rules []string) []ScanMatch {
+	var out []ScanMatch
+	for _, r := range rules {
+		out = append(out, ScanMatch{Target: target, Rule: r})
+	}
+	return out
+}
+
+// scanFavicon downloads res.FaviconURL with the probe client and scans it.
+func scanFavicon(ctx context.Context, client *http.Client, res *HTTPResult, cfg Config) {
+	if cfg.Scanner == nil || res.FaviconURL == "" {
+		return
+	}
+	if data := fetchAsset(ctx, client, res.FaviconURL, cfg, maxFaviconBytes); data != nil {
+		res.ScanMatches = append(res.ScanMatches, scanMatches("favicon", cfg.Scanner.Scan("favicon", data))...)
+	}
+}
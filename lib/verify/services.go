@@ -0,0 +1,202 @@
+package verify
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// StageServices caches ServicesResult.
+const StageServices = "services"
+
+// DefaultSRVServices are the SRV names looked up under a candidate: mail
+// client autodiscovery, SIP/Teams federation and XMPP chat. Autodiscover on
+// a typo domain collects credentials from misconfigured mail clients.
+var DefaultSRVServices = []string{
+	"_autodiscover._tcp", "_sip._tcp", "_sips._tcp", "_sipfederationtls._tcp",
+	"_xmpp-client._tcp", "_xmpp-server._tcp",
+}
+
+// DefaultWellKnownPaths are the RFC 8615 documents fetched from a live
+// candidate: a copied security.txt, app association files claiming the
+// brand's mobile apps, and an OpenID provider posing as the brand's SSO.
+var DefaultWellKnownPaths = []string{
+	"/.well-known/security.txt",
+	"/.well-known/apple-app-site-association",
+	"/.well-known/assetlinks.json",
+	"/.well-known/openid-configuration",
+}
+
+// maxWellKnownBytes caps each well-known document read.
+const maxWellKnownBytes = 64 << 10
+
+// SRVResolver is implemented by resolvers that can look up SRV records, as
+// *net.Resolver does. Without it the SRV lookups are skipped.
+type SRVResolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+var _ SRVResolver = (*net.Resolver)(nil)
+
+// ServicesResult is what a candidate publishes beyond its website: SRV
+// records and well-known documents impersonating the brand's service
+// endpoints.
+type ServicesResult struct {
+	SRV       []ServiceRecord `json:",omitempty"`
+	WellKnown []WellKnownDoc  `json:",omitempty"`
+}
+
+// ServiceRecord is one SRV target, e.g. _autodiscover._tcp -> mail.host:443.
+type ServiceRecord struct {
+	Service string
+	Target  string
+	Port    uint16
+}
+
+// WellKnownDoc is a well-known document the candidate serves. Refs are the
+// identifiers it points at: security.txt contacts and policy URLs, app IDs
+// and package names, the OpenID issuer and endpoints.
+type WellKnownDoc struct {
+	Path        string
+	ContentType string   `json:",omitempty"`
+	SHA256      string   `json:",omitempty"`
+	Refs        []string `json:",omitempty"`
+}
+
+// lookupServices looks up domain's SRV records and, when probe is set,
+// fetches its well-known documents over HTTPS.
+func lookupServices(ctx context.Context, domain string, probe bool, cfg Config) ServicesResult {
+	var res ServicesResult
+	if r, ok := cfg.resolver().(SRVResolver); ok {
+		dnsCtx, cancel := context.WithTimeout(ctx, cfg.DNSTimeout)
+		defer cancel()
+		for _, svc := range DefaultSRVServices {
+			_, srvs, err := r.LookupSRV(dnsCtx, "", "", svc+"."+domain)
+			if err != nil {
+				continue
+			}
+			for _, s := range srvs {
+				if target := strings.TrimSuffix(s.Target, "."); target != "" {
+					res.SRV = append(res.SRV, ServiceRecord{Service: svc, Target: target, Port: s.Port})
+				}
+			}
+		}
+	}
+	if !probe {
+		return res
+	}
+
+	httpCtx, cancel := context.WithTimeout(ctx, cfg.HTTPTimeout)
+	defer cancel()
+	cfg.HTTPFollowRedirects = false // a document served elsewhere isn't the candidate's
+	client := configureHTTPClient(cfg)
+	for _, path := range DefaultWellKnownPaths {
+		if doc, ok := fetchWellKnown(httpCtx, &client, "https://"+domain+path, cfg); ok {
+			doc.Path = path
+			res.WellKnown = append(res.WellKnown, doc)
+		}
+	}
+	return res
+}
+
+// fetchWellKnown GETs one well-known document. Catch-all sites answer any
+// path with their HTML home page, so only a 200 that parses as the
+// document counts.
+func fetchWellKnown(ctx context.Context, client *http.Client, url string, cfg Config) (WellKnownDoc, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return WellKnownDoc{}, false
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	resp, err := client.Do(req)
+	if err != nil {
+		return WellKnownDoc{}, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return WellKnownDoc{}, false
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWellKnownBytes))
+	if err != nil {
+		return WellKnownDoc{}, false
+	}
+	ct := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(ct); err == nil && mt == "text/html" {
+		return WellKnownDoc{}, false
+	}
+	var refs []string
+	var ok bool
+	if strings.HasSuffix(url, "/security.txt") {
+		refs, ok = securityTxtRefs(body)
+	} else {
+		refs, ok = jsonRefs(body)
+	}
+	if !ok {
+		return WellKnownDoc{}, false
+	}
+	sum := sha256.Sum256(body)
+	return WellKnownDoc{ContentType: ct, SHA256: hex.EncodeToString(sum[:]), Refs: refs}, true
+}
+
+// securityTxtRefs returns the values of an RFC 9116 file's Contact,
+// Policy, Canonical and Hiring fields; a file without Contact isn't one.
+func securityTxtRefs(body []byte) ([]string, bool) {
+	var refs []string
+	contact := false
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	for sc.Scan() {
+		field, value, ok := strings.Cut(sc.Text(), ":")
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "contact":
+			contact = true
+			fallthrough
+		case "policy", "canonical", "hiring":
+			refs = append(refs, strings.TrimSpace(value))
+		}
+	}
+	return refs, contact
+}
+
+// jsonRefs returns the identifiers in an app association or OpenID
+// configuration document: every string value under the keys naming apps,
+// packages, sites, the issuer and endpoints.
+func jsonRefs(body []byte) ([]string, bool) {
+	var doc any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, false
+	}
+	var refs []string
+	var walk func(key string, v any)
+	walk = func(key string, v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for k, child := range v {
+				walk(k, child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(key, child)
+			}
+		case string:
+			switch key {
+			case "appID", "appIDs", "apps", "package_name", "site", "issuer", "authorization_endpoint", "token_endpoint":
+				refs = append(refs, v)
+			}
+		}
+	}
+	walk("", doc)
+	slices.Sort(refs)
+	return slices.Compact(refs), true
+}
@@ -0,0 +1,64 @@
+package verify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyDomainServices(t *testing.T) {
+	zone := verifytest.Zone{
+		"exampel.com":                    {A: []string{"192.0.2.10"}},
+		"_autodiscover._tcp.exampel.com": {SRV: []string{"mail.exampel.com:443"}},
+		"_sip._tcp.exampel.com":          {SRV: []string{"sip.exampel.com:5060"}},
+	}
+	docs := map[string]struct{ contentType, body string }{
+		"/.well-known/security.txt":               {"text/plain", "Contact: mailto:security@example.com\nPolicy: https://example.com/security\n# copied\n"},
+		"/.well-known/apple-app-site-association": {"application/json", `{"applinks":{"details":[{"appIDs":["ABCDE12345.com.example.app"],"components":[{"/":"/*"}]}]},"webcredentials":{"apps":["ABCDE12345.com.example.app"]}}`},
+		// A catch-all site answering every path with its home page.
+		"/.well-known/assetlinks.json":      {"text/html", "<html><title>Welcome</title></html>"},
+		"/.well-known/openid-configuration": {"application/json", "not json"},
+	}
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		doc, ok := docs[r.URL.Path]
+		if !ok {
+			return page(r, http.StatusNotFound, "", "Not found"), nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{"Content-Type": {doc.contentType}}, Request: r,
+			Body: io.NopCloser(strings.NewReader(doc.body))}, nil
+	})
+	cfg := Config{Resolver: verifytest.NewResolver(zone), DoHTTP: true, DoServices: true, Transport: transport, HTTPTimeout: time.Second}
+
+	v, err := VerifyDomain(context.Background(), "exampel.com", cfg)
+	if err != nil || v.Services == nil {
+		t.Fatalf("VerifyDomain() = %+v, %v, want services", v, err)
+	}
+	wantSRV := []ServiceRecord{{"_autodiscover._tcp", "mail.exampel.com", 443}, {"_sip._tcp", "sip.exampel.com", 5060}}
+	if !reflect.DeepEqual(v.Services.SRV, wantSRV) {
+		t.Errorf("SRV = %+v, want %+v", v.Services.SRV, wantSRV)
+	}
+	var paths []string
+	for _, doc := range v.Services.WellKnown {
+		paths = append(paths, doc.Path)
+	}
+	if want := []string{"/.well-known/security.txt", "/.well-known/apple-app-site-association"}; !reflect.DeepEqual(paths, want) {
+		t.Fatalf("well-known = %v, want %v", paths, want)
+	}
+	if want := []string{"mailto:security@example.com", "https://example.com/security"}; !reflect.DeepEqual(v.Services.WellKnown[0].Refs, want) {
+		t.Errorf("security.txt refs = %v, want %v", v.Services.WellKnown[0].Refs, want)
+	}
+	if want := []string{"ABCDE12345.com.example.app"}; !reflect.DeepEqual(v.Services.WellKnown[1].Refs, want) {
+		t.Errorf("app association refs = %v, want %v", v.Services.WellKnown[1].Refs, want)
+	}
+
+	// Without -http only DNS is consulted.
+	cfg.DoHTTP = false
+	if v, _ := VerifyDomain(context.Background(), "exampel.com", cfg); v.Services == nil || len(v.Services.WellKnown) != 0 || len(v.Services.SRV) != 2 {
+		t.Errorf("DNS-only services = %+v, want SRV records only", v.Services)
+	}
+}
@@ -0,0 +1,250 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StageSMTP caches SMTPResult.
+const StageSMTP = "smtp"
+
+// maxSMTPHosts caps the MX hosts probed per candidate, most preferred
+// first.
+const maxSMTPHosts = 2
+
+// A deferred probe is retried by the first run (with a Cache) after
+// smtpRetryDelay, doubling each time, at most maxSMTPRetries times before
+// the result is kept for the stage TTL like any other. Greylisting MTAs
+// accept a client retrying after a few minutes.
+const (
+	smtpRetryDelay = 10 * time.Minute
+	maxSMTPRetries = 3
+)
+
+// SMTPResult is what a candidate's mail servers call themselves: a server
+// set up to receive (or relay) mail for business email compromise often
+// names itself after the brand's real mail hosts, so replies and logs look
+// like the brand's own.
+type SMTPResult struct {
+	Hosts []SMTPHost `json:",omitempty"`
+	// Impersonates lists the greeting and EHLO identities claiming the base
+	// domain's mail hostnames (Config.BaseMail).
+	Impersonates []string `json:",omitempty"`
+	// RetryAt is when a result with Deferred hosts is due to be probed
+	// again, and Retries how many times it already was.
+	RetryAt time.Time `json:",omitzero"`
+	Retries int       `json:",omitempty"`
+}
+
+// SMTPHost is one MX host's greeting and EHLO reply on port 25.
+type SMTPHost struct {
+	MX       string
+	Banner   string `json:",omitempty"` // the 220 greeting's first line
+	Identity string `json:",omitempty"` // the hostname the EHLO reply announces
+	StartTLS bool
+	Deferred string `json:",omitempty"` // the 4xx reply (greylisting, rate limiting) that put the greeting off
+	Error    string `json:",omitempty"` // why no EHLO reply was read otherwise
+}
+
+// deferred reports whether any host put the probe off with a 4xx reply.
+// Such a host does receive mail, just not from a client it hasn't seen
+// before.
+func (r *SMTPResult) deferred() bool {
+	return slices.ContainsFunc(r.Hosts, func(h SMTPHost) bool { return h.Deferred != "" })
+}
+
+// retryDue reports whether r was deferred and its re-probe is due at now.
+func (r *SMTPResult) retryDue(now time.Time) bool {
+	return !r.RetryAt.IsZero() && !now.Before(r.RetryAt)
+}
+
+// scheduleRetry sets when a deferred r is probed again, counting on from
+// prev, the result it replaces (if found).
+func (r *SMTPResult) scheduleRetry(prev *SMTPResult, found bool, now time.Time) {
+	if !r.deferred() {
+		return
+	}
+	retries := 0
+	if found && !prev.RetryAt.IsZero() {
+		retries = prev.Retries + 1
+	}
+	if retries >= maxSMTPRetries {
+		return
+	}
+	r.Retries = retries
+	r.RetryAt = now.Add(smtpRetryDelay << retries)
+}
+
+// probeSMTP greets up to maxSMTPHosts of mx, domain's mail hosts, and
+// records how each identifies itself. Nothing is sent: the envelope opened
+// to tell a greylisting host is reset before QUIT.
+func probeSMTP(ctx context.Context, domain string, mx []string, cfg Config) SMTPResult {
+	var res SMTPResult
+	for _, host := range mx[:min(maxSMTPHosts, len(mx))] {
+		h := SMTPHost{MX: host}
+		var tempfail *textproto.Error
+		if err := greetSMTP(ctx, &h, domain, cfg); errors.As(err, &tempfail) && tempfail.Code/100 == 4 {
+			msg, _, _ := strings.Cut(tempfail.Msg, "\n")
+			h.Deferred = strconv.Itoa(tempfail.Code) + " " + msg
+		} else if err != nil {
+			h.Error = err.Error()
+		}
+		res.Hosts = append(res.Hosts, h)
+	}
+	return res
+}
+
+// greetSMTP reads h.MX's greeting, sends EHLO and records the reply, then
+// checks whether the host defers mail for domain.
+func greetSMTP(ctx context.Context, h *SMTPHost, domain string, cfg Config) error {
+	conn, err := cfg.dialProbe(ctx, "tcp", net.JoinHostPort(h.MX, "25"))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	tp := textproto.NewConn(conn)
+	_, greeting, err := tp.ReadResponse(220)
+	if err != nil {
+		return err
+	}
+	h.Banner, _, _ = strings.Cut(greeting, "\n")
+	id, err := tp.Cmd("EHLO %s", smtpHelo())
+	if err != nil {
+		return err
+	}
+	tp.StartResponse(id)
+	_, reply, err := tp.ReadResponse(250)
+	tp.EndResponse(id)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(reply, "\n")
+	if f := strings.Fields(lines[0]); len(f) > 0 {
+		h.Identity = strings.TrimSuffix(f[0], ".")
+	}
+	for _, ext := range lines[1:] {
+		if strings.EqualFold(strings.TrimSpace(ext), "STARTTLS") {
+			h.StartTLS = true
+		}
+	}
+	err = smtpEnvelope(tp, domain)
+	_, _ = tp.Cmd("QUIT")
+	return err
+}
+
+// smtpEnvelope opens an envelope to postmaster@domain, a mailbox every
+// mail domain must accept, and resets it. Greylisting MTAs defer the first
+// recipient from a client they haven't seen, so a 4xx reply here is
+// returned as a *textproto.Error; refusals (5xx) say nothing about
+// greylisting and are ignored.
+func smtpEnvelope(tp *textproto.Conn, domain string) error {
+	err := smtpCmd(tp, 250, "MAIL FROM:<>")
+	if err == nil {
+		err = smtpCmd(tp, 25, "RCPT TO:<postmaster@%s>", domain)
+	}
+	var refused *textproto.Error
+	if errors.As(err, &refused) && refused.Code/100 == 5 {
+		err = nil
+	}
+	_ = smtpCmd(tp, 250, "RSET")
+	return err
+}
+
+// smtpCmd sends a command and reads its reply, expecting code.
+func smtpCmd(tp *textproto.Conn, code int, format string, args ...any) error {
+	id, err := tp.Cmd(format, args...)
+	if err != nil {
+		return err
+	}
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+	_, _, err = tp.ReadResponse(code)
+	return err
+}
+
+// smtpHelo is the name this host greets mail servers with.
+func smtpHelo() string {
+	if name, err := os.Hostname(); err == nil && strings.Contains(name, ".") {
+		return name
+	}
+	return "localhost"
+}
+
+// impersonations lists the banner and EHLO hostnames of res claiming one
+// of baseMail (see BaseMailHosts). A candidate whose MX is one of the
+// brand's own mail hosts (a defensive registration, typically) is answered
+// by the real server, which rightly names itself so.
+func impersonations(res *SMTPResult, baseMail []string) []string {
+	if len(baseMail) == 0 {
+		return nil
+	}
+	var out []string
+	for _, h := range res.Hosts {
+		mx := strings.ToLower(strings.TrimSuffix(h.MX, "."))
+		if onHost(mx, baseMail[0]) || slices.Contains(baseMail[1:], mx) {
+			continue
+		}
+		names := []string{h.Identity}
+		if f := strings.Fields(h.Banner); len(f) > 0 {
+			names = append(names, strings.TrimSuffix(f[0], "."))
+		}
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if name == "" || slices.Contains(out, name) {
+				continue
+			}
+			if onHost(name, baseMail[0]) || slices.Contains(baseMail[1:], name) {
+				out = append(out, name)
+			}
+		}
+	}
+	return out
+}
+
+// BaseMailHosts returns base followed by those of its MX hosts that name
+// the brand outside it (e.g. example-com.mail.protection.outlook.com),
+// for Config.BaseMail. A mail server calling itself by a name under base,
+// or by one of those, impersonates the brand's mail; shared provider hosts
+// such as aspmx.l.google.com name every customer and are left out. A host
+// names the brand when one of its labels is the brand, or starts with it
+// followed by a hyphen, so a short brand like go.com doesn't claim
+// google.com's hosts.
+func BaseMailHosts(ctx context.Context, base string, cfg Config) []string {
+	base = strings.ToLower(strings.TrimSuffix(base, "."))
+	out := []string{base}
+	brand, _, _ := strings.Cut(base, ".")
+	ctx, cancel := context.WithTimeout(ctx, cfg.DNSTimeout)
+	defer cancel()
+	mxs, err := cfg.resolver().LookupMX(ctx, base)
+	if err != nil {
+		return out
+	}
+	for _, mx := range mxs {
+		host := strings.ToLower(strings.TrimSuffix(mx.Host, "."))
+		if !onHost(host, base) && namesBrand(host, brand) && !slices.Contains(out, host) {
+			out = append(out, host)
+		}
+	}
+	return out
+}
+
+// namesBrand reports whether a label of host is brand, or brand followed
+// by a hyphen and more.
+func namesBrand(host, brand string) bool {
+	for _, label := range strings.Split(host, ".") {
+		if label == brand || strings.HasPrefix(label, brand+"-") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package verify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpServer answers every connection with greeting, then the EHLO reply
+// lines, and rcpt to RCPT TO, until QUIT.
+func smtpServer(t *testing.T, greeting, rcpt string, ehlo ...string) net.Listener {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprintf(conn, "%s\r\n", greeting)
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+					case strings.HasPrefix(cmd, "EHLO "):
+						for i, l := range ehlo {
+							sep := "-"
+							if i == len(ehlo)-1 {
+								sep = " "
+							}
+							fmt.Fprintf(conn, "250%s%s\r\n", sep, l)
+						}
+					case strings.HasPrefix(cmd, "MAIL FROM:"), cmd == "RSET":
+						fmt.Fprint(conn, "250 ok\r\n")
+					case strings.HasPrefix(cmd, "RCPT TO:"):
+						fmt.Fprintf(conn, "%s\r\n", rcpt)
+					case cmd == "QUIT":
+						fmt.Fprint(conn, "221 bye\r\n")
+						return
+					}
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+// fixedDialer connects every probe to addr.
+type fixedDialer string
+
+func (d fixedDialer) DialContext(ctx context.Context, network, _ string) (net.Conn, error) {
+	var nd net.Dialer
+	return nd.DialContext(ctx, network, string(d))
+}
+
+func TestProbeSMTP(t *testing.T) {
+	ln := smtpServer(t, "220 mail.example.com ESMTP Postfix", "250 ok", "mail.example.com Hello", "PIPELINING", "STARTTLS", "8BITMIME")
+	cfg := Config{Dialer: fixedDialer(ln.Addr().String())}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	res := probeSMTP(ctx, "examp1e.com", []string{"mx1.examp1e.com", "mx2.examp1e.com", "mx3.examp1e.com"}, cfg)
+	want := SMTPHost{MX: "mx1.examp1e.com", Banner: "mail.example.com ESMTP Postfix", Identity: "mail.example.com", StartTLS: true}
+	if len(res.Hosts) != maxSMTPHosts || res.Hosts[0] != want {
+		t.Fatalf("probeSMTP() = %+v, want %d hosts like %+v", res, maxSMTPHosts, want)
+	}
+	if got := impersonations(&res, []string{"example.com"}); !reflect.DeepEqual(got, []string{"mail.example.com"}) {
+		t.Errorf("impersonations() = %v", got)
+	}
+
+	refused := probeSMTP(ctx, "examp1e.com", []string{"mx.examp1e.com"}, Config{Dialer: fixedDialer("127.0.0.1:1")})
+	if len(refused.Hosts) != 1 || refused.Hosts[0].Error == "" {
+		t.Errorf("probeSMTP() of a closed port = %+v", refused)
+	}
+
+	busy := smtpServer(t, "421 4.7.0 mx.examp1e.com greylisted, try again later", "")
+	deferred := probeSMTP(ctx, "examp1e.com", []string{"mx.examp1e.com"}, Config{Dialer: fixedDialer(busy.Addr().String())})
+	if h := deferred.Hosts[0]; h.Deferred != "421 4.7.0 mx.examp1e.com greylisted, try again later" || h.Error != "" || !deferred.deferred() {
+		t.Errorf("probeSMTP() of a host deferring the greeting = %+v", deferred)
+	}
+
+	// Greylisting defers the recipient; the greeting went through.
+	greylist := smtpServer(t, "220 mx.examp1e.com ESMTP", "450 4.2.0 <postmaster@examp1e.com>: Recipient address rejected: Greylisted", "mx.examp1e.com")
+	deferred = probeSMTP(ctx, "examp1e.com", []string{"mx.examp1e.com"}, Config{Dialer: fixedDialer(greylist.Addr().String())})
+	if h := deferred.Hosts[0]; h.Identity != "mx.examp1e.com" || h.Deferred != "450 4.2.0 <postmaster@examp1e.com>: Recipient address rejected: Greylisted" || h.Error != "" {
+		t.Errorf("probeSMTP() of a greylisting host = %+v", deferred)
+	}
+
+	// A refused recipient isn't a deferral, nor an error.
+	relay := smtpServer(t, "220 mx.examp1e.com ESMTP", "550 5.7.1 Relaying denied", "mx.examp1e.com")
+	res = probeSMTP(ctx, "examp1e.com", []string{"mx.examp1e.com"}, Config{Dialer: fixedDialer(relay.Addr().String())})
+	if h := res.Hosts[0]; h.Identity != "mx.examp1e.com" || h.Deferred != "" || h.Error != "" {
+		t.Errorf("probeSMTP() of a host refusing the recipient = %+v", res)
+	}
+}
+
+func TestSMTPScheduleRetry(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	greylisted := []SMTPHost{{MX: "mx1", Deferred: "450 greylisted"}, {MX: "mx2", Error: "i/o timeout"}}
+	tests := []struct {
+		name        string
+		hosts       []SMTPHost
+		prev        *SMTPResult
+		found       bool
+		wantAt      time.Time
+		wantRetries int
+	}{
+		{"answered", []SMTPHost{{MX: "mx1", Identity: "mx1"}}, &SMTPResult{}, false, time.Time{}, 0},
+		{"first deferral", greylisted, &SMTPResult{}, false, now.Add(smtpRetryDelay), 0},
+		{"deferred again", greylisted, &SMTPResult{RetryAt: now, Retries: 0}, true, now.Add(2 * smtpRetryDelay), 1},
+		{"expired answer deferred", greylisted, &SMTPResult{}, true, now.Add(smtpRetryDelay), 0},
+		{"retries exhausted", greylisted, &SMTPResult{RetryAt: now, Retries: maxSMTPRetries - 1}, true, time.Time{}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := SMTPResult{Hosts: tt.hosts}
+			r.scheduleRetry(tt.prev, tt.found, now)
+			if !r.RetryAt.Equal(tt.wantAt) || r.Retries != tt.wantRetries {
+				t.Errorf("scheduleRetry() = %v, %d retries; want %v, %d", r.RetryAt, r.Retries, tt.wantAt, tt.wantRetries)
+			}
+			if due := r.retryDue(now.Add(smtpRetryDelay << tt.wantRetries)); due != !tt.wantAt.IsZero() {
+				t.Errorf("retryDue() at the retry time = %v", due)
+			}
+			if !tt.wantAt.IsZero() && r.retryDue(now) {
+				t.Errorf("retryDue() before the retry time = true")
+			}
+		})
+	}
+}
+
+func TestImpersonations(t *testing.T) {
+	baseMail := []string{"example.com", "example-com.mail.protection.outlook.com"}
+	tests := []struct {
+		name string
+		host SMTPHost
+		want []string
+	}{
+		{"own name", SMTPHost{Banner: "mx.examp1e.com ESMTP", Identity: "mx.examp1e.com"}, nil},
+		{"brand subdomain in EHLO", SMTPHost{Banner: "mx.examp1e.com ESMTP", Identity: "smtp.example.com"}, []string{"smtp.example.com"}},
+		{"brand tenant host in banner", SMTPHost{Banner: "EXAMPLE-COM.mail.protection.outlook.com Microsoft ESMTP", Identity: "mx.examp1e.com"},
+			[]string{"example-com.mail.protection.outlook.com"}},
+		{"lookalike base", SMTPHost{Identity: "mail.example.com.examp1e.com"}, nil},
+		{"no reply", SMTPHost{Error: "connection refused"}, nil},
+		{"brand's own MX", SMTPHost{MX: "mx.example.com.", Identity: "mx.example.com"}, nil},
+		{"brand's tenant MX", SMTPHost{MX: "example-com.mail.protection.outlook.com", Banner: "example-com.mail.protection.outlook.com ESMTP"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := impersonations(&SMTPResult{Hosts: []SMTPHost{tt.host}}, baseMail); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("impersonations() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseMailHosts(t *testing.T) {
+	r := verifytest.NewResolver(verifytest.Zone{
+		"example.com": {MX: []string{"example-com.mail.protection.outlook.com", "mx.example.com", "aspmx.l.google.com"}},
+	})
+	cfg := Config{Resolver: r, DNSTimeout: time.Second}
+	want := []string{"example.com", "example-com.mail.protection.outlook.com"}
+	if got := BaseMailHosts(context.Background(), "Example.com.", cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("BaseMailHosts() = %v, want %v", got, want)
+	}
+
+	// A short brand is matched on label boundaries only.
+	r = verifytest.NewResolver(verifytest.Zone{
+		"go.com": {MX: []string{"aspmx.l.google.com", "go-com.mail.protection.outlook.com", "mx.cargo.net"}},
+	})
+	cfg.Resolver = r
+	want = []string{"go.com", "go-com.mail.protection.outlook.com"}
+	if got := BaseMailHosts(context.Background(), "go.com", cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("BaseMailHosts() = %v, want %v", got, want)
+	}
+}
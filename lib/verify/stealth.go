@@ -0,0 +1,106 @@
+package verify
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ephemeralLow/High bound the source ports picked by RandomSourcePort.
+const (
+	ephemeralLow  = 10000
+	ephemeralHigh = 65000
+)
+
+// Dialer opens probe connections; *net.Dialer and the SOCKS dialers of
+// golang.org/x/net/proxy satisfy it.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+var _ Dialer = (*net.Dialer)(nil)
+
+type acceptedKey struct{}
+
+// withAccepted returns a context recording whether a probe dialled with it
+// had a connection accepted, and the record.
+func withAccepted(ctx context.Context) (context.Context, *atomic.Bool) {
+	accepted := &atomic.Bool{}
+	return context.WithValue(ctx, acceptedKey{}, accepted), accepted
+}
+
+// dialProbe opens the TCP connection for a TLS or HTTP probe, through
+// Config.Dialer when set. Otherwise connections to the candidate race its
+// resolved addresses (dialRace), and with RandomSourcePort each connection
+// binds a random local port, retrying a few times if the port is taken.
+func (cfg Config) dialProbe(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := cfg.dialConn(ctx, network, addr)
+	if accepted, ok := ctx.Value(acceptedKey{}).(*atomic.Bool); ok && err == nil {
+		accepted.Store(true)
+	}
+	return conn, err
+}
+
+func (cfg Config) dialConn(ctx context.Context, network, addr string) (net.Conn, error) {
+	if cfg.Dialer != nil {
+		return cfg.Dialer.DialContext(ctx, network, addr)
+	}
+	if port, ok := cfg.addrs.racing(addr); ok {
+		return dialRace(ctx, network, port, cfg.addrs, cfg.dialDirect)
+	}
+	return cfg.dialDirect(ctx, network, addr)
+}
+
+// dialDirect dials addr from a random source port with RandomSourcePort.
+func (cfg Config) dialDirect(ctx context.Context, network, addr string) (net.Conn, error) {
+	if !cfg.RandomSourcePort {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+	var err error
+	for range 5 {
+		d := net.Dialer{LocalAddr: &net.TCPAddr{Port: ephemeralLow + rand.IntN(ephemeralHigh-ephemeralLow)}}
+		var conn net.Conn
+		if conn, err = d.DialContext(ctx, network, addr); err == nil || !errors.Is(err, syscall.EADDRINUSE) {
+			return conn, err
+		}
+	}
+	return nil, err
+}
+
+// probeTransport is the HTTP transport for probes: Config.Transport, the
+// default one, or a clone dialing through dialProbe when a Dialer is set,
+// the candidate's addresses are raced or source ports are randomised.
+func (cfg Config) probeTransport() http.RoundTripper {
+	if cfg.Transport != nil {
+		return cfg.Transport
+	}
+	if cfg.Dialer == nil && cfg.addrs == nil && !cfg.RandomSourcePort {
+		return http.DefaultTransport
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = cfg.dialProbe
+	t.DisableKeepAlives = cfg.Dialer == nil // a fresh port per request
+	return t
+}
+
+// jitter sleeps for a random duration up to cfg.StageJitter before a probe,
+// so a candidate's DNS, TLS and HTTP traffic doesn't arrive in a burst.
+func (cfg Config) jitter(ctx context.Context) error {
+	if cfg.StageJitter <= 0 {
+		return nil
+	}
+	t := time.NewTimer(rand.N(cfg.StageJitter))
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
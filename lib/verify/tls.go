@@ -2,8 +2,14 @@ package verify
 
 import (
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"math"
 	"net"
+	"strings"
 	"time"
 )
 
@@ -17,27 +23,70 @@ type TLSResult struct {
 	DNSNames     []string
 	CommonName   string
 	SerialNumber string
+	// FingerprintSHA256 is the hex SHA-256 of the leaf certificate's DER encoding
+	FingerprintSHA256 string
+	// CertValid reports whether the presented chain validates for the
+	// candidate hostname against the system roots; ValidationError says why
+	// not. The metadata above is captured either way.
+	CertValid       bool
+	ValidationError string
+	// Validity is the leaf's state at the trusted time (Config.Clock):
+	// CertCurrent, CertExpired or CertNotYetValid. DaysUntilExpiry counts
+	// whole days to NotAfter, negative once expired. Both are judged again
+	// when the result comes from the cache.
+	Validity        string `json:",omitempty"`
+	DaysUntilExpiry int
+	// DefaultCertSHA256 is the leaf fingerprint served to a handshake without
+	// SNI, i.e. the host's default vhost. DefaultVhost is set when the
+	// candidate gets that same certificate: it has no site of its own there.
+	DefaultCertSHA256  string
+	DefaultCertSubject string
+	DefaultVhost       bool
+	// ChainPEM is the presented chain, leaf first, when Config.KeepRaw is set.
+	ChainPEM string `json:",omitempty"`
+	// Error says why no handshake completed.
+	Error *StageError `json:",omitempty"`
 }
 
-func fetchTLS(ctx context.Context, domain string) TLSResult {
-	res := TLSResult{ServerName: domain}
+// Certificate validity states (TLSResult.Validity).
+const (
+	CertCurrent     = "current"
+	CertExpired     = "expired"
+	CertNotYetValid = "not_yet_valid"
+)
 
-	dialer := &net.Dialer{}
-	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(domain, "443"))
-	if err != nil {
-		return res
+// assess judges the leaf's validity period at now.
+func (r *TLSResult) assess(now time.Time) {
+	if r.NotAfter.IsZero() {
+		return
 	}
-	defer conn.Close()
+	switch {
+	case now.Before(r.NotBefore):
+		r.Validity = CertNotYetValid
+	case now.After(r.NotAfter):
+		r.Validity = CertExpired
+	default:
+		r.Validity = CertCurrent
+	}
+	r.DaysUntilExpiry = int(math.Floor(r.NotAfter.Sub(now).Hours() / 24))
+}
 
-	tlsConn := tls.Client(conn, &tls.Config{
-		ServerName:         domain, // SNI
-		InsecureSkipVerify: true,   // We want metadata even for bad certs; do not use for trust decisions.
-	})
-	_ = tlsConn.SetDeadline(time.Now().Add(3 * time.Second))
-	if err := tlsConn.Handshake(); err != nil {
+// now is the trusted current time.
+func (cfg Config) now() time.Time {
+	if cfg.Clock != nil {
+		return cfg.Clock()
+	}
+	return time.Now()
+}
+
+func fetchTLS(ctx context.Context, domain string, cfg Config) TLSResult {
+	res := TLSResult{ServerName: domain}
+
+	state, err := handshake(ctx, domain, domain, cfg)
+	if err != nil {
+		res.Error = NewStageError(StageTLS, err)
 		return res
 	}
-	state := tlsConn.ConnectionState()
 	res.Connected = true
 
 	if len(state.PeerCertificates) > 0 {
@@ -49,6 +98,68 @@ func fetchTLS(ctx context.Context, domain string) TLSResult {
 		res.DNSNames = append([]string{}, cert.DNSNames...)
 		res.CommonName = cert.Subject.CommonName
 		res.SerialNumber = cert.SerialNumber.String()
+		sum := sha256.Sum256(cert.Raw)
+		res.FingerprintSHA256 = hex.EncodeToString(sum[:])
+
+		if cfg.KeepRaw {
+			var b strings.Builder
+			for _, c := range state.PeerCertificates {
+				_ = pem.Encode(&b, &pem.Block{Type: "CERTIFICATE", Bytes: c.Raw})
+			}
+			res.ChainPEM = b.String()
+		}
+
+		if err := validateChain(state.PeerCertificates, domain, cfg.TLSRoots, cfg.now()); err != nil {
+			res.ValidationError = err.Error()
+		} else {
+			res.CertValid = true
+		}
+
+		// Second handshake without SNI to see the default vhost.
+		if def, err := handshake(ctx, domain, "", cfg); err == nil && len(def.PeerCertificates) > 0 {
+			leaf := def.PeerCertificates[0]
+			sum := sha256.Sum256(leaf.Raw)
+			res.DefaultCertSHA256 = hex.EncodeToString(sum[:])
+			res.DefaultCertSubject = leaf.Subject.String()
+			res.DefaultVhost = res.DefaultCertSHA256 == res.FingerprintSHA256
+		}
 	}
 	return res
 }
+
+// handshake connects to domain:443 and completes a TLS handshake sending sni
+// (none when empty), returning the connection state.
+func handshake(ctx context.Context, domain, sni string, cfg Config) (tls.ConnectionState, error) {
+	conn, err := cfg.dialProbe(ctx, "tcp", net.JoinHostPort(domain, "443"))
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         sni,
+		InsecureSkipVerify: true, // We want metadata even for bad certs; do not use for trust decisions.
+	})
+	_ = tlsConn.SetDeadline(time.Now().Add(3 * time.Second))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, err
+	}
+	return tlsConn.ConnectionState(), nil
+}
+
+// validateChain is the verification pass the handshake skipped: the leaf must
+// chain to roots (the system pool when nil) through the presented
+// intermediates and be valid for host at now.
+func validateChain(certs []*x509.Certificate, host string, roots *x509.CertPool, now time.Time) error {
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	return err
+}
@@ -0,0 +1,98 @@
+package verify
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateChain(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	cert := srv.Certificate() // self-signed, valid for example.com
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	tests := []struct {
+		name    string
+		host    string
+		roots   *x509.CertPool
+		now     time.Time // zero: the local clock
+		wantErr string
+	}{
+		{name: "valid", host: "example.com", roots: roots},
+		{name: "wrong host", host: "exampel.com", roots: roots, wantErr: "not exampel.com"},
+		{name: "untrusted", host: "example.com", roots: x509.NewCertPool(), wantErr: "unknown authority"},
+		{name: "expired at the trusted time", host: "example.com", roots: roots, now: cert.NotAfter.Add(time.Hour), wantErr: "expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChain([]*x509.Certificate{cert}, tt.host, tt.roots, tt.now)
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("validateChain() error = %v, want nil", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validateChain() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssess(t *testing.T) {
+	notBefore := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	notAfter := notBefore.Add(90 * 24 * time.Hour)
+	tests := []struct {
+		now      time.Time
+		validity string
+		days     int
+	}{
+		{notBefore.Add(-time.Minute), CertNotYetValid, 90},
+		{notBefore.Add(time.Hour), CertCurrent, 89},
+		{notAfter.Add(-time.Hour), CertCurrent, 0},
+		{notAfter.Add(time.Hour), CertExpired, -1},
+		{notAfter.Add(30 * 24 * time.Hour), CertExpired, -30},
+	}
+	for _, tt := range tests {
+		r := TLSResult{Connected: true, NotBefore: notBefore, NotAfter: notAfter}
+		r.assess(tt.now)
+		if r.Validity != tt.validity || r.DaysUntilExpiry != tt.days {
+			t.Errorf("assess(%v) = %s, %d days, want %s, %d", tt.now, r.Validity, r.DaysUntilExpiry, tt.validity, tt.days)
+		}
+	}
+	var none TLSResult
+	if none.assess(notBefore); none.Validity != "" {
+		t.Errorf("assess() without a certificate = %q, want none", none.Validity)
+	}
+}
+
+// pinnedDialer sends every connection to addr, whatever host was asked for.
+type pinnedDialer struct {
+	addr  string
+	dials []string
+}
+
+func (d *pinnedDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.dials = append(d.dials, addr)
+	var nd net.Dialer
+	return nd.DialContext(ctx, network, d.addr)
+}
+
+func TestFetchTLSDialer(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	d := &pinnedDialer{addr: srv.Listener.Addr().String()}
+	res := fetchTLS(context.Background(), "example.com", Config{Dialer: d, TLSRoots: roots})
+	if !res.Connected || !res.CertValid || !res.DefaultVhost {
+		t.Errorf("fetchTLS() = %+v, want a valid certificate served as the default vhost", res)
+	}
+	if len(d.dials) != 2 || d.dials[0] != "example.com:443" {
+		t.Errorf("dialed %v, want example.com:443 twice", d.dials)
+	}
+}
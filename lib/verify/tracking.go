@@ -0,0 +1,43 @@
+package verify
+
+import (
+	"regexp"
+	"sort"
+)
+
+// trackingPatterns maps a tracking-ID kind to the expression that extracts it
+// from page content. The first capture group is the ID itself. Kinds prefix the
+// emitted IDs (e.g. "gtm:GTM-ABC123") so clusters stay readable.
+var trackingPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"ua", regexp.MustCompile(`\b(UA-\d{4,10}-\d{1,4})\b`)},
+	{"ga4", regexp.MustCompile(`(?:gtag/js\?id=|['"]config['"]\s*,\s*['"])(G-[A-Z0-9]{6,12})\b`)},
+	{"gtm", regexp.MustCompile(`\b(GTM-[A-Z0-9]{4,9})\b`)},
+	{"gads", regexp.MustCompile(`\b(AW-\d{6,12})\b`)},
+	{"fbpixel", regexp.MustCompile(`fbq\(\s*['"]init['"]\s*,\s*['"]?(\d{10,20})`)},
+	{"fbpixel", regexp.MustCompile(`facebook\.com/tr\?id=(\d{10,20})`)},
+	{"adsense", regexp.MustCompile(`\b(ca-pub-\d{10,20})\b`)},
+	{"adsense", regexp.MustCompile(`google_ad_client\s*[=:]\s*['"](pub-\d{10,20})['"]`)},
+	{"yandex", regexp.MustCompile(`\bym\(\s*(\d{6,12})\s*,\s*['"]init['"]`)},
+}
+
+// ExtractTrackingIDs returns the sorted, de-duplicated analytics and ad-network
+// identifiers found in a page body. Operators reuse these across every site
+// they run, which makes them the strongest signal for tying squats together.
+func ExtractTrackingIDs(body []byte) []string {
+	seen := map[string]bool{}
+	for _, p := range trackingPatterns {
+		for _, m := range p.re.FindAllSubmatch(body, -1) {
+			seen[p.kind+":"+string(m[1])] = true
+		}
+	}
+
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,41 @@
+package verify
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTrackingIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "Google tags",
+			body: `<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC123XYZ9"></script>
+				<script>gtag('config', 'G-ABC123XYZ9'); gtag('config', 'AW-123456789');</script>
+				<!-- GTM-5XK2PQ --> ga('create', 'UA-1234567-2', 'auto');`,
+			want: []string{"ga4:G-ABC123XYZ9", "gads:AW-123456789", "gtm:GTM-5XK2PQ", "ua:UA-1234567-2"},
+		},
+		{
+			name: "Facebook pixel and AdSense",
+			body: `fbq('init', '123456789012345'); <img src="https://www.facebook.com/tr?id=123456789012345&ev=PageView">
+				data-ad-client="ca-pub-1234567890123456"`,
+			want: []string{"adsense:ca-pub-1234567890123456", "fbpixel:123456789012345"},
+		},
+		{
+			name: "No identifiers",
+			body: `<html><title>G-major scale</title></html>`,
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractTrackingIDs([]byte(tt.body)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractTrackingIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -9,6 +9,8 @@ import (
 	"context"
 	"crypto/x509"
 	"errors"
+	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -16,13 +18,87 @@ import (
 )
 
 type Config struct {
-	DNSTimeout          time.Duration
-	HTTPTimeout         time.Duration
-	TLSTimeout          time.Duration
-	DoTLS               bool
-	DoHTTP              bool
+	DNSTimeout  time.Duration
+	Resolver    Resolver // answers every DNS lookup; nil uses the system resolver
+	HTTPTimeout time.Duration
+	TLSTimeout  time.Duration
+	DoTLS       bool
+	DoHTTP      bool
+	DoASN       bool   // map resolved IPs to origin ASNs
+	DoRDAP      bool   // look up registration data for live candidates
+	RDAPBase    string // RDAP bootstrap URL; empty uses DefaultRDAPBase
+	DoCT        bool   // search Certificate Transparency for live candidates
+	CTBase      string // crt.sh-compatible search URL; empty uses DefaultCTBase
+	PDNSURL     string // passive DNS (Common Output Format) endpoint; empty disables
+	PDNSUser    string
+	PDNSKey     string
+
+	// ConsensusResolver, when set, resolves every candidate a second time
+	// for DNSResult.Consensus.
+	ConsensusResolver Resolver
+
+	// Geo maps resolved IPs to hosting countries (the -asn registry country
+	// is the fallback); candidates hosted in HighRiskCountries are flagged.
+	Geo               GeoLocator
+	HighRiskCountries []string
+
+	// DNS blocklists: the candidate is checked against DomainBLZones and its
+	// MX hosts' addresses against IPBLZones; both empty disables the stage.
+	DomainBLZones []string
+	IPBLZones     []string
+
+	// Reputation cross-references resolved IPs against reputation feeds.
+	Reputation IPReputation
+
+	// TLSRoots validates presented chains (TLSResult.CertValid); nil uses
+	// the system roots.
+	TLSRoots *x509.CertPool
+
+	// Clock is the trusted time certificates are judged against (see
+	// lib/clock); nil uses the local clock.
+	Clock func() time.Time
+
+	// Stealth: a random pause up to StageJitter before each TLS/HTTP probe,
+	// and a random local port per probe connection.
+	StageJitter      time.Duration
+	RandomSourcePort bool
+
+	// Egress for the TLS and HTTP probes. Dialer opens every probe
+	// connection (RandomSourcePort then no longer applies) and Transport
+	// carries every HTTP probe request, so tests can point probes at local
+	// servers and deployments can route them through a proxy or capture.
+	// nil uses the defaults.
+	Dialer    Dialer
+	Transport http.RoundTripper
+
+	addrs    *probeAddrs // set by VerifyDomain for dialProbe to race
+	prevHTTP *HTTPResult // the cached HTTP result fetchHTTP revalidates
+
+	// Passive never contacts candidate infrastructure: TLS and HTTP probes
+	// are skipped whatever DoTLS/DoHTTP say, leaving recursive DNS and
+	// third-party sources (CT, passive DNS, RDAP).
+	Passive             bool
+	FetchBody           bool     // GET instead of HEAD and sample the body for content fingerprints
+	MaxBodyBytes        int64    // cap on the (decompressed) body sample; 0 uses DefaultMaxBodyBytes
+	BodyContentTypes    []string // media types whose bodies are read; empty uses DefaultBodyContentTypes
 	HTTPFollowRedirects bool
+	CrossProtocol       bool     // also probe plain HTTP when HTTPS answers, see HTTPResult.CrossProtocol
+	CheckWWW            bool     // also resolve and probe www.<candidate>, see Verification.WWW
+	DoServices          bool     // look up service SRV records and fetch well-known documents
+	DoSMTP              bool     // greet MX hosts on port 25 and record their EHLO identity
+	BaseMail            []string // the base domain and its brand-named MX hosts (BaseMailHosts), see SMTPResult.Impersonates
+	BrandCookies        []string // the brand's own cookie names ("*" suffix: prefix), see HTTPResult.BrandCookies
 	UserAgent           string
+	DKIMSelectors       []string            // probed only for candidates with MX; empty disables
+	Cache               Cache               // optional cross-run cache of stage results
+	BodyInspector       BodyInspector       // optional extra signals from sampled bodies (FetchBody only)
+	Rules               ResponseRules       // optional user content rules run on every HTTP response
+	Scanner             ContentScanner      // optional YARA-style scanning of sampled bodies and favicons (FetchBody only)
+	BrandAssets         AssetMatcher        // optional brand logos, copyright lines and phone numbers to find (FetchBody only)
+	Base                *BaseSite           // optional base domain's site (ProbeBase), see HTTPResult.MirrorsBase and ProxyIndicators
+	Archive             Archiver            // optional evidence archive of raw HTTP exchanges; bypasses the HTTP cache
+	KeepRaw             bool                // keep the certificate chain PEM and raw RDAP responses (evidence bundles)
+	Registrars          RegistrarClassifier // optional registrar classes for RDAP results
 }
 
 type Verification struct {
@@ -31,8 +107,45 @@ type Verification struct {
 	DNS        DNSResult
 	TLS        *TLSResult
 	HTTP       *HTTPResult
+	RDAP       *RDAPResult
+	CT         *CTResult
+	PDNS       *PDNSResult
+	Geo        *GeoResult
+	Reputation []ReputationMatch
+	DNSBL      *DNSBLResult
+	WWW        *WWW            // www.<candidate> compared with it, when Config.CheckWWW is set
+	Services   *ServicesResult // SRV records and well-known documents, when Config.DoServices is set
+	SMTP       *SMTPResult     // MX hosts' greetings, when Config.DoSMTP is set
 	Resolvable bool
 	HasMail    bool
+	Timing     Timing
+	Errors     []StageError // stages that could not complete
+}
+
+// SlowFraction of a probe's timeout marks the candidate Timing.Slow.
+const SlowFraction = 0.75
+
+// Timing is how long a verification took, in milliseconds. A stage served
+// from the cache, or skipped, took 0. Slow is set when a TLS or HTTP probe
+// had its connection accepted, then used at least SlowFraction of its
+// timeout: a tarpit holding connections open, or infrastructure worth a
+// second look. A host dropping connections unanswered is not slow, only
+// down.
+type Timing struct {
+	DNSMillis   int64 `json:"dns_ms"`
+	TLSMillis   int64 `json:"tls_ms,omitempty"`
+	HTTPMillis  int64 `json:"http_ms,omitempty"`
+	TotalMillis int64 `json:"total_ms"`
+	Slow        bool  `json:"slow,omitempty"`
+}
+
+// probe records the duration of one TLS or HTTP probe given its timeout,
+// and whether the host accepted its connection.
+func (t *Timing) probe(field *int64, took, timeout time.Duration, accepted bool) {
+	*field = took.Milliseconds()
+	if accepted && took >= time.Duration(SlowFraction*float64(timeout)) {
+		t.Slow = true
+	}
 }
 
 func VerifyDomain(ctx context.Context, domain string, cfg Config) (Verification, error) {
@@ -55,40 +168,240 @@ func VerifyDomain(ctx context.Context, domain string, cfg Config) (Verification,
 	}
 
 	v := Verification{Domain: domain, ASCII: ascii}
+	start := time.Now()
+
+	// A name known not to exist is skipped until its negative TTL expires.
+	var neg negativeEntry
+	if found, _ := cfg.cacheGet(StageNegative, ascii, &neg); found && time.Now().Before(neg.Until) {
+		return v, nil
+	}
+
+	var prevDNS DNSResult
+	hadDNS, freshDNS := cfg.cacheGet(StageDNS, ascii, &prevDNS)
+	if cfg.ConsensusResolver != nil && prevDNS.Consensus == nil {
+		freshDNS = false // cached before -consensus-resolver was in use
+	}
+	if freshDNS {
+		v.DNS = prevDNS
+		v.DNS.Cached = true
+	} else {
+		dnsCtx, src := withAnswerSource(ctx)
+		dnsRes, negative, err := resolveDomain(dnsCtx, ascii, cfg)
+		if err != nil {
+			return Verification{}, err
+		}
+		v.Timing.DNSMillis = time.Since(start).Milliseconds()
+		v.DNS = dnsRes
+		v.DNS.Source = cfg.dnsSource(src)
+		if cfg.ConsensusResolver != nil {
+			c := checkConsensus(ctx, ascii, v.DNS, cfg)
+			v.DNS.Consensus = c
+			// Not known to be missing unless both resolvers agree.
+			negative = negative && c.Error == nil && !slices.Contains(c.Differences, "exists")
+		}
+		if negative && cfg.Cache != nil {
+			if ttl := cfg.negativeTTL(dnsCtx, ascii); ttl > 0 {
+				cfg.cachePut(StageNegative, ascii, negativeEntry{Until: time.Now().Add(ttl)})
+			}
+		}
+		if v.DNS.Error == nil && (v.DNS.Consensus == nil || v.DNS.Consensus.Error == nil) {
+			cfg.cachePut(StageDNS, ascii, v.DNS)
+		}
+	}
+	apexLive := v.DNS.HasA || v.DNS.HasAAAA || v.DNS.HasCNAME || v.DNS.Consensus.hides()
+	v.Resolvable = apexLive
+	v.HasMail = v.DNS.HasMX
+	v.Geo = locate(v.DNS, cfg)
+	v.Reputation = checkReputation(v.DNS, cfg)
+
+	// Cached TLS/HTTP results are only trusted while the hosting is unchanged.
+	reuse := freshDNS || (hadDNS && sameInfrastructure(prevDNS, v.DNS))
+
+	if cfg.Passive {
+		cfg.DoTLS, cfg.DoHTTP = false, false
+	}
+	if v.DNS.Consensus.hides() {
+		// Probe the addresses the filtering resolver withheld.
+		cfg = cfg.withAddrs(ascii, DNSResult{A: v.DNS.Consensus.A, AAAA: v.DNS.Consensus.AAAA})
+	} else {
+		cfg = cfg.withAddrs(ascii, v.DNS)
+	}
+
+	if cfg.DoTLS && apexLive { // Only attempt TLS if it resolves
+		var tr TLSResult
+		if _, fresh := cfg.cacheGet(StageTLS, ascii, &tr); !fresh || !reuse {
+			if err := cfg.jitter(ctx); err != nil {
+				return Verification{}, err
+			}
+			tlsCtx, cancelTLS := context.WithTimeout(ctx, cfg.TLSTimeout)
+			defer cancelTLS()
+			tlsCtx, accepted := withAccepted(tlsCtx)
+			began := time.Now()
+			tr = fetchTLS(tlsCtx, ascii, cfg)
+			v.Timing.probe(&v.Timing.TLSMillis, time.Since(began), cfg.TLSTimeout, accepted.Load() || tr.Connected)
+			cfg.cachePut(StageTLS, ascii, tr)
+		}
+		tr.assess(cfg.now())
+		v.TLS = &tr
+	}
+
+	if cfg.DoHTTP && apexLive {
+		var hr HTTPResult
+		stage := cfg.httpCacheStage()
+		if found, fresh := cfg.cacheGet(stage, ascii, &hr); !fresh || !reuse || cfg.Archive != nil {
+			if err := cfg.jitter(ctx); err != nil {
+				return Verification{}, err
+			}
+			httpCtx, cancelHTTP := context.WithTimeout(ctx, cfg.HTTPTimeout)
+			defer cancelHTTP()
+			// A stale result is revalidated: conditional requests, and
+			// ContentChangedAt carried over until the content moves.
+			probeCfg := cfg
+			if found && cfg.Archive == nil {
+				prev := hr
+				probeCfg.prevHTTP = &prev
+			}
+			httpCtx, accepted := withAccepted(httpCtx)
+			began := time.Now()
+			hr = fetchHTTP(httpCtx, true, ascii, probeCfg)
+			// A custom Transport doesn't dial through dialProbe; any
+			// response shows the connection was accepted.
+			v.Timing.probe(&v.Timing.HTTPMillis, time.Since(began), cfg.HTTPTimeout, accepted.Load() || hr.StatusCode != 0)
+			cfg.cachePut(stage, ascii, hr)
+		}
+		hr.MirrorsBase = mirrorsBase(&hr, ascii, cfg.Base)
+		hr.ProxyIndicators = proxyIndicators(&hr, v.TLS, ascii, cfg.Base, cfg.now())
+		v.HTTP = &hr
+	}
+
+	// The www host after the apex, so its probe can be compared.
+	if cfg.CheckWWW && (v.Resolvable || v.HasMail || v.DNS.HasNS) {
+		v.WWW = checkWWW(ctx, ascii, v, reuse, cfg)
+		v.Resolvable = v.Resolvable || v.WWW.Resolvable
+	}
+
+	if cfg.DoServices && (v.Resolvable || v.HasMail || v.DNS.HasNS) {
+		probe := cfg.DoHTTP && apexLive
+		stage := StageServices
+		if probe {
+			stage += "+http"
+		}
+		var sr ServicesResult
+		if _, fresh := cfg.cacheGet(stage, ascii, &sr); !fresh || !reuse {
+			sr = lookupServices(ctx, ascii, probe, cfg)
+			cfg.cachePut(stage, ascii, sr)
+		}
+		v.Services = &sr
+	}
+
+	if cfg.DoSMTP && v.HasMail && !cfg.Passive {
+		// A greylisted result is probed again once its retry is due, well
+		// before the stage TTL.
+		var sr SMTPResult
+		if found, fresh := cfg.cacheGet(StageSMTP, ascii, &sr); !fresh || !reuse || sr.retryDue(cfg.now()) {
+			smtpCtx, cancelSMTP := context.WithTimeout(ctx, cfg.HTTPTimeout)
+			defer cancelSMTP()
+			prev := sr
+			sr = probeSMTP(smtpCtx, ascii, v.DNS.MX, cfg)
+			sr.scheduleRetry(&prev, found, cfg.now())
+			cfg.cachePut(StageSMTP, ascii, sr)
+		}
+		sr.Impersonates = impersonations(&sr, cfg.BaseMail)
+		v.SMTP = &sr
+	}
+
+	// Registration data doesn't depend on hosting, so a fresh entry is
+	// always reused.
+	if cfg.DoRDAP && (v.Resolvable || v.HasMail) {
+		var rr RDAPResult
+		if _, fresh := cfg.cacheGet(StageRDAP, ascii, &rr); !fresh {
+			rdapCtx, cancelRDAP := context.WithTimeout(ctx, cfg.HTTPTimeout)
+			defer cancelRDAP()
+			rr = lookupRDAP(rdapCtx, ascii, cfg)
+			if rr.Registrar != "" || !rr.Created.IsZero() { // don't pin rate-limit failures
+				cfg.cachePut(StageRDAP, ascii, rr)
+			}
+		}
+		v.RDAP = &rr
+	}
+
+	if cfg.DoCT && (v.Resolvable || v.HasMail) {
+		var ct CTResult
+		if _, fresh := cfg.cacheGet(StageCT, ascii, &ct); !fresh {
+			ctCtx, cancelCT := context.WithTimeout(ctx, 2*cfg.HTTPTimeout) // crt.sh is slow
+			defer cancelCT()
+			ct = lookupCT(ctCtx, ascii, cfg)
+			if ct.Error == nil { // don't pin rate-limit failures
+				cfg.cachePut(StageCT, ascii, ct)
+			}
+		}
+		v.CT = &ct
+	}
+
+	if cfg.PDNSURL != "" && (v.Resolvable || v.HasMail) {
+		var pd PDNSResult
+		if _, fresh := cfg.cacheGet(StagePDNS, ascii, &pd); !fresh {
+			pdnsCtx, cancelPDNS := context.WithTimeout(ctx, cfg.HTTPTimeout)
+			defer cancelPDNS()
+			pd = lookupPDNS(pdnsCtx, ascii, cfg)
+			if pd.Error == nil {
+				cfg.cachePut(StagePDNS, ascii, pd)
+			}
+		}
+		v.PDNS = &pd
+	}
 
+	if len(cfg.DomainBLZones)+len(cfg.IPBLZones) > 0 && (v.Resolvable || v.HasMail) {
+		var bl DNSBLResult
+		if _, fresh := cfg.cacheGet(StageDNSBL, ascii, &bl); !fresh {
+			blCtx, cancelBL := context.WithTimeout(ctx, 2*cfg.DNSTimeout)
+			defer cancelBL()
+			bl = lookupDNSBL(blCtx, ascii, v.DNS, cfg)
+			cfg.cachePut(StageDNSBL, ascii, bl)
+		}
+		v.DNSBL = &bl
+	}
+
+	v.Errors = v.stageErrors()
+	v.Timing.TotalMillis = time.Since(start).Milliseconds()
+	return v, nil
+}
+
+// resolveDomain runs the DNS stage: record lookups plus the optional DKIM and
+// ASN enrichment that rides on them. negative reports that the name does
+// not exist.
+func resolveDomain(ctx context.Context, ascii string, cfg Config) (_ DNSResult, negative bool, _ error) {
 	dnsCtx, cancel := context.WithTimeout(ctx, cfg.DNSTimeout)
 	defer cancel()
 
-	dnsRes, err := lookupDNS(dnsCtx, ascii)
+	dnsRes, err := lookupDNS(dnsCtx, cfg.resolver(), ascii)
 	if err != nil {
 		// DNS errors are common; treat as non-fatal unless it’s a hard context error.
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
-			return Verification{}, err
+			return DNSResult{}, false, err
+		}
+		if isNegative(dnsRes, err) {
+			return dnsRes, true, nil
+		}
+		if !dnsRes.HasA && !dnsRes.HasAAAA && !dnsRes.HasCNAME && !dnsRes.HasMX && !dnsRes.HasNS {
+			dnsRes.Error = NewStageError(StageDNS, err)
 		}
 	}
-	v.DNS = dnsRes
-	v.Resolvable = dnsRes.HasA || dnsRes.HasAAAA || dnsRes.HasCNAME
-	v.HasMail = dnsRes.HasMX
 
-	if cfg.DoTLS {
-		tlsCtx, cancelTLS := context.WithTimeout(ctx, cfg.TLSTimeout)
-		defer cancelTLS()
-		if v.Resolvable { // Only attempt TLS if it resolves
-			tr := fetchTLS(tlsCtx, ascii)
-			v.TLS = &tr
-		}
+	if dnsRes.HasMX && len(cfg.DKIMSelectors) > 0 {
+		dkimCtx, cancelDKIM := context.WithTimeout(ctx, cfg.DNSTimeout)
+		defer cancelDKIM()
+		dnsRes.DKIM = lookupDKIM(dkimCtx, cfg.resolver(), ascii, cfg.DKIMSelectors)
+		dnsRes.HasDKIM = len(dnsRes.DKIM) > 0
 	}
 
-	if cfg.DoHTTP {
-		httpCtx, cancelHTTP := context.WithTimeout(ctx, cfg.HTTPTimeout)
-		defer cancelHTTP()
-		if v.Resolvable {
-			hr := fetchHTTP(httpCtx, true, ascii, cfg)
-			v.HTTP = &hr
-		}
+	if cfg.DoASN && (dnsRes.HasA || dnsRes.HasAAAA) {
+		asnCtx, cancelASN := context.WithTimeout(ctx, cfg.DNSTimeout)
+		defer cancelASN()
+		dnsRes.ASN = lookupASN(asnCtx, cfg.resolver(), append(append([]string{}, dnsRes.A...), dnsRes.AAAA...))
 	}
 
-	return v, nil
+	return dnsRes, false, nil
 }
 
 func toASCII(domain string) (string, error) {
@@ -0,0 +1,45 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// BenchmarkFetchHTTP measures the HTTP stage of the verify pipeline, including
+// body sampling and content fingerprinting, against a local server.
+func BenchmarkFetchHTTP(b *testing.B) {
+	page := "<html><head><title>Sign in</title><script>gtag('config', 'G-ABC123XYZ9');</script></head><body>" +
+		strings.Repeat("<p>lorem ipsum</p>", 2000) + "</body></html>"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, page)
+	}))
+	defer srv.Close()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	for _, body := range []bool{false, true} {
+		b.Run(fmt.Sprintf("body=%v", body), func(b *testing.B) {
+			cfg := Config{HTTPTimeout: 2 * time.Second, FetchBody: body, UserAgent: "bench"}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if res := fetchHTTP(context.Background(), false, host, cfg); res.StatusCode != http.StatusOK {
+					b.Fatalf("StatusCode = %d", res.StatusCode)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExtractTrackingIDs(b *testing.B) {
+	body := []byte(strings.Repeat("<div>filler content</div>", 20000) + "fbq('init', '123456789012345'); GTM-5XK2PQ")
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for i := 0; i < b.N; i++ {
+		ExtractTrackingIDs(body)
+	}
+}
@@ -0,0 +1,218 @@
+package verifytest
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNSServer is an authoritative DNS server for a Zone on a loopback UDP
+// port, for exercising code through a real *net.Resolver.
+type DNSServer struct {
+	Addr string // host:port
+
+	conn net.PacketConn
+	zone Zone
+	done chan struct{}
+}
+
+// NewDNSServer starts serving zone. Close it when done.
+func NewDNSServer(zone Zone) (*DNSServer, error) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &DNSServer{Addr: conn.LocalAddr().String(), conn: conn, zone: zone, done: make(chan struct{})}
+	go s.serve()
+	return s, nil
+}
+
+// Resolver returns a pure-Go resolver sending every query to the server.
+func (s *DNSServer) Resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "udp", s.Addr)
+		},
+	}
+}
+
+func (s *DNSServer) Close() error {
+	err := s.conn.Close()
+	<-s.done
+	return err
+}
+
+func (s *DNSServer) serve() {
+	defer close(s.done)
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		if resp, err := s.answer(buf[:n]); err == nil {
+			_, _ = s.conn.WriteTo(resp, addr)
+		}
+	}
+}
+
+// answer builds the response to one query. Like a recursive resolver, it
+// follows CNAMEs for address queries, returning the chain and the target's
+// addresses.
+func (s *DNSServer) answer(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := dnsmessage.Header{ID: h.ID, Response: true, Authoritative: true, RecursionDesired: h.RecursionDesired, RecursionAvailable: true}
+	rec, ok := s.zone.lookup(q.Name.String())
+	if !ok {
+		hdr.RCode = dnsmessage.RCodeNameError
+	}
+	b := dnsmessage.NewBuilder(nil, hdr)
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+
+	name := q.Name
+	rh := func() dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: 60}
+	}
+	hops := 0
+	for ; ok && rec.CNAME != "" && q.Type != dnsmessage.TypeCNAME && len(rec.A)+len(rec.AAAA) == 0 && hops < 8; hops++ {
+		target, err := fqdn(rec.CNAME)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.CNAMEResource(rh(), dnsmessage.CNAMEResource{CNAME: target}); err != nil {
+			return nil, err
+		}
+		name = target
+		rec, ok = s.zone.lookup(target.String())
+	}
+
+	switch q.Type {
+	case dnsmessage.TypeA:
+		for _, a := range rec.A {
+			if ip := net.ParseIP(a).To4(); ip != nil {
+				if err := b.AResource(rh(), dnsmessage.AResource{A: [4]byte(ip)}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case dnsmessage.TypeAAAA:
+		for _, a := range rec.AAAA {
+			if ip := net.ParseIP(a).To16(); ip != nil {
+				if err := b.AAAAResource(rh(), dnsmessage.AAAAResource{AAAA: [16]byte(ip)}); err != nil {
+					return nil, err
+				}
+			}
+		}
+	case dnsmessage.TypeCNAME:
+		if rec.CNAME != "" {
+			target, err := fqdn(rec.CNAME)
+			if err != nil {
+				return nil, err
+			}
+			if err := b.CNAMEResource(rh(), dnsmessage.CNAMEResource{CNAME: target}); err != nil {
+				return nil, err
+			}
+		}
+	case dnsmessage.TypeMX:
+		for i, h := range rec.MX {
+			host, err := fqdn(h)
+			if err != nil {
+				return nil, err
+			}
+			if err := b.MXResource(rh(), dnsmessage.MXResource{Pref: uint16(10 * (i + 1)), MX: host}); err != nil {
+				return nil, err
+			}
+		}
+	case dnsmessage.TypeNS:
+		for _, h := range rec.NS {
+			host, err := fqdn(h)
+			if err != nil {
+				return nil, err
+			}
+			if err := b.NSResource(rh(), dnsmessage.NSResource{NS: host}); err != nil {
+				return nil, err
+			}
+		}
+	case dnsmessage.TypeTXT:
+		for _, txt := range rec.TXT {
+			if err := b.TXTResource(rh(), dnsmessage.TXTResource{TXT: splitTXT(txt)}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Negative answers carry the enclosing zone's SOA for negative caching.
+	if !ok || (hops == 0 && !rec.has(q.Type)) {
+		if apex, ttl, found := s.zone.soa(q.Name.String()); found {
+			if err := b.StartAuthorities(); err != nil {
+				return nil, err
+			}
+			zone, err := fqdn(apex)
+			if err != nil {
+				return nil, err
+			}
+			secs := uint32(ttl / time.Second)
+			soa := dnsmessage.SOAResource{NS: zone, MBox: zone, Serial: 1, Refresh: 3600, Retry: 600, Expire: 86400, MinTTL: secs}
+			if err := b.SOAResource(dnsmessage.ResourceHeader{Name: zone, Class: dnsmessage.ClassINET, TTL: secs}, soa); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.Finish()
+}
+
+// has reports whether r answers queries of type t.
+func (r Records) has(t dnsmessage.Type) bool {
+	switch t {
+	case dnsmessage.TypeA:
+		return len(r.A) > 0
+	case dnsmessage.TypeAAAA:
+		return len(r.AAAA) > 0
+	case dnsmessage.TypeCNAME:
+		return r.CNAME != ""
+	case dnsmessage.TypeMX:
+		return len(r.MX) > 0
+	case dnsmessage.TypeNS:
+		return len(r.NS) > 0
+	case dnsmessage.TypeTXT:
+		return len(r.TXT) > 0
+	}
+	return false
+}
+
+func fqdn(name string) (dnsmessage.Name, error) {
+	return dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+}
+
+// splitTXT splits a record into the 255-byte character strings DNS carries.
+func splitTXT(s string) []string {
+	var out []string
+	for len(s) > 255 {
+		out = append(out, s[:255])
+		s = s[255:]
+	}
+	return append(out, s)
+}
@@ -0,0 +1,238 @@
+package verifytest
+
+/*
+  This library provides DNS fixtures for deterministic tests of lib/verify
+  and its callers, without live network access: Resolver is an in-memory
+  verify.Resolver, and DNSServer serves the same records over UDP for code
+  that must go through a real *net.Resolver.
+*/
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Records are the answers for one name. Names without Records do not
+// exist (NXDOMAIN).
+type Records struct {
+	A     []string
+	AAAA  []string
+	CNAME string
+	MX    []string // hosts, in preference order
+	NS    []string
+	TXT   []string
+	SRV   []string // "target:port", in priority order
+
+	// SOA, set on a zone apex, is the negative caching TTL of names beneath
+	// it: the TTL and MINIMUM of the SOA record sent with NXDOMAIN and
+	// no-data answers.
+	SOA time.Duration
+}
+
+// Zone maps fully qualified names (with or without the trailing dot, any
+// case) to their records.
+type Zone map[string]Records
+
+func (z Zone) lookup(name string) (Records, bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for k, r := range z {
+		if strings.ToLower(strings.TrimSuffix(k, ".")) == name {
+			return r, true
+		}
+	}
+	return Records{}, false
+}
+
+// soa finds the closest enclosing zone apex of name with an SOA.
+func (z Zone) soa(name string) (apex string, ttl time.Duration, ok bool) {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for {
+		if rec, found := z.lookup(name); found && rec.SOA > 0 {
+			return name, rec.SOA, true
+		}
+		_, parent, more := strings.Cut(name, ".")
+		if !more {
+			return "", 0, false
+		}
+		name = parent
+	}
+}
+
+// Resolver answers lookups from a Zone and records every name queried.
+// It implements verify.Resolver.
+type Resolver struct {
+	Zone Zone
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// NewResolver returns a Resolver over zone.
+func NewResolver(zone Zone) *Resolver {
+	return &Resolver{Zone: zone}
+}
+
+// Queries returns every name looked up so far, in order.
+func (r *Resolver) Queries() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.queries...)
+}
+
+func (r *Resolver) find(ctx context.Context, name string) (Records, error) {
+	r.mu.Lock()
+	r.queries = append(r.queries, name)
+	r.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return Records{}, err
+	}
+	rec, ok := r.Zone.lookup(name)
+	if !ok {
+		return Records{}, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+	return rec, nil
+}
+
+// noData is the error net.Resolver returns for an existing name without
+// records of the requested type.
+func noData(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// addresses follows a CNAME to the addresses of its target, as a
+// recursive resolver would.
+func (r *Resolver) addresses(ctx context.Context, host string) (Records, error) {
+	rec, err := r.find(ctx, host)
+	for hops := 0; err == nil && rec.CNAME != "" && len(rec.A)+len(rec.AAAA) == 0 && hops < 8; hops++ {
+		rec, err = r.find(ctx, rec.CNAME)
+	}
+	return rec, err
+}
+
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	rec, err := r.addresses(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	var out []net.IPAddr
+	for _, a := range append(append([]string{}, rec.A...), rec.AAAA...) {
+		if ip := net.ParseIP(a); ip != nil {
+			out = append(out, net.IPAddr{IP: ip})
+		}
+	}
+	if len(out) == 0 {
+		return nil, noData(host)
+	}
+	return out, nil
+}
+
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	ips, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(ips))
+	for i, ip := range ips {
+		out[i] = ip.IP.String()
+	}
+	return out, nil
+}
+
+// LookupCNAME returns the canonical name, which like net.Resolver's is the
+// queried name itself when it has no CNAME.
+func (r *Resolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	rec, err := r.find(ctx, host)
+	if err != nil {
+		return "", err
+	}
+	if rec.CNAME == "" {
+		return strings.TrimSuffix(host, ".") + ".", nil
+	}
+	return strings.TrimSuffix(rec.CNAME, ".") + ".", nil
+}
+
+func (r *Resolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	rec, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(rec.MX) == 0 {
+		return nil, noData(name)
+	}
+	out := make([]*net.MX, len(rec.MX))
+	for i, h := range rec.MX {
+		out[i] = &net.MX{Host: strings.TrimSuffix(h, ".") + ".", Pref: uint16(10 * (i + 1))}
+	}
+	return out, nil
+}
+
+func (r *Resolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	rec, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(rec.NS) == 0 {
+		return nil, noData(name)
+	}
+	out := make([]*net.NS, len(rec.NS))
+	for i, h := range rec.NS {
+		out[i] = &net.NS{Host: strings.TrimSuffix(h, ".") + "."}
+	}
+	return out, nil
+}
+
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	rec, err := r.find(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(rec.TXT) == 0 {
+		return nil, noData(name)
+	}
+	return append([]string(nil), rec.TXT...), nil
+}
+
+// LookupSRV looks up _service._proto.name, or name itself when service
+// and proto are empty, like net.Resolver. It implements verify.SRVResolver.
+func (r *Resolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	if service != "" || proto != "" {
+		name = "_" + service + "._" + proto + "." + name
+	}
+	rec, err := r.find(ctx, name)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(rec.SRV) == 0 {
+		return "", nil, noData(name)
+	}
+	out := make([]*net.SRV, 0, len(rec.SRV))
+	for i, s := range rec.SRV {
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			return "", nil, err
+		}
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return "", nil, err
+		}
+		out = append(out, &net.SRV{Target: strings.TrimSuffix(host, ".") + ".", Port: uint16(p), Priority: uint16(10 * (i + 1))})
+	}
+	return strings.TrimSuffix(name, ".") + ".", out, nil
+}
+
+// NegativeTTL reports how long a negative answer for name may be cached,
+// from the SOA of its enclosing zone. It implements
+// verify.NegativeTTLResolver.
+func (r *Resolver) NegativeTTL(ctx context.Context, name string) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if _, ttl, ok := r.Zone.soa(name); ok {
+		return ttl, nil
+	}
+	return 0, &net.DNSError{Err: "no SOA", Name: name, IsNotFound: true}
+}
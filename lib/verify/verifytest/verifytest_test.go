@@ -0,0 +1,70 @@
+package verifytest
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+var testZone = Zone{
+	"exampel.com":      {A: []string{"192.0.2.10"}, AAAA: []string{"2001:db8::10"}, MX: []string{"mx.exampel.com"}, NS: []string{"ns1.parking.test"}, TXT: []string{"v=spf1 -all"}},
+	"www.exampel.com.": {CNAME: "exampel.com"},
+	"mx.exampel.com":   {A: []string{"192.0.2.25"}},
+}
+
+// lookups runs the same queries against any resolver.
+func lookups(t *testing.T, r interface {
+	LookupHost(context.Context, string) ([]string, error)
+	LookupCNAME(context.Context, string) (string, error)
+	LookupMX(context.Context, string) ([]*net.MX, error)
+	LookupNS(context.Context, string) ([]*net.NS, error)
+	LookupTXT(context.Context, string) ([]string, error)
+}) {
+	t.Helper()
+	ctx := context.Background()
+
+	hosts, err := r.LookupHost(ctx, "www.exampel.com")
+	slices.Sort(hosts)
+	if err != nil || !reflect.DeepEqual(hosts, []string{"192.0.2.10", "2001:db8::10"}) {
+		t.Errorf("LookupHost(www) = %v, %v, want the CNAME target's addresses", hosts, err)
+	}
+	if cname, err := r.LookupCNAME(ctx, "www.exampel.com"); err != nil || cname != "exampel.com." {
+		t.Errorf("LookupCNAME(www) = %q, %v, want exampel.com.", cname, err)
+	}
+	if mx, err := r.LookupMX(ctx, "exampel.com"); err != nil || len(mx) != 1 || mx[0].Host != "mx.exampel.com." {
+		t.Errorf("LookupMX() = %v, %v, want mx.exampel.com.", mx, err)
+	}
+	if ns, err := r.LookupNS(ctx, "exampel.com"); err != nil || len(ns) != 1 || ns[0].Host != "ns1.parking.test." {
+		t.Errorf("LookupNS() = %v, %v, want ns1.parking.test.", ns, err)
+	}
+	if txt, err := r.LookupTXT(ctx, "exampel.com"); err != nil || !reflect.DeepEqual(txt, []string{"v=spf1 -all"}) {
+		t.Errorf("LookupTXT() = %v, %v", txt, err)
+	}
+	var dnsErr *net.DNSError
+	if _, err := r.LookupHost(ctx, "unregistered.com"); !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+		t.Errorf("LookupHost(unregistered) error = %v, want not found", err)
+	}
+	if _, err := r.LookupMX(ctx, "mx.exampel.com"); err == nil {
+		t.Errorf("LookupMX(name without MX) succeeded")
+	}
+}
+
+func TestResolver(t *testing.T) {
+	r := NewResolver(testZone)
+	lookups(t, r)
+	if q := r.Queries(); len(q) == 0 || q[0] != "www.exampel.com" {
+		t.Errorf("Queries() = %v, want the looked-up names in order", q)
+	}
+}
+
+func TestDNSServer(t *testing.T) {
+	s, err := NewDNSServer(testZone)
+	if err != nil {
+		t.Fatalf("NewDNSServer() error: %v", err)
+	}
+	defer s.Close()
+	lookups(t, s.Resolver())
+}
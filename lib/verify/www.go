@@ -0,0 +1,145 @@
+package verify
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// StageWWW caches WWW results, keyed by the apex.
+const StageWWW = "www"
+
+// WWW compares www.<candidate> with the candidate itself. Many squats only
+// configure the www host, which apex-only probing misses.
+type WWW struct {
+	Resolvable bool
+	A          []string `json:",omitempty"`
+	AAAA       []string `json:",omitempty"`
+	CNAME      string   `json:",omitempty"`
+	// HTTP is the www host's HTTP probe, when Config.DoHTTP is set and it
+	// resolves.
+	HTTP *HTTPResult `json:",omitempty"`
+	// Only is set when www resolves but the apex does not.
+	Only bool `json:",omitempty"`
+	// Redirects is set when one host redirects to the other, the usual
+	// setup.
+	Redirects bool `json:",omitempty"`
+	// Differences names how www diverges from the apex: "exists",
+	// "addresses", "error", "status", "final_url", "title" and "body".
+	Differences []string `json:",omitempty"`
+}
+
+// Site returns the HTTP probe of the candidate's site: the apex's, or for
+// a www-only squat the www host's, so it is classified and scored like any
+// other page.
+func (v Verification) Site() *HTTPResult {
+	if v.HTTP == nil && v.WWW != nil && v.WWW.Only {
+		return v.WWW.HTTP
+	}
+	return v.HTTP
+}
+
+// wwwCacheStage keys WWW results like HTTP ones, by the probe options.
+func (cfg Config) wwwCacheStage() string {
+	return StageWWW + strings.TrimPrefix(cfg.httpCacheStage(), StageHTTP)
+}
+
+// checkWWW resolves and, with DoHTTP, probes www.<domain>, comparing it with
+// the apex's verification v so far. A cached result is reused while the www
+// addresses and the apex's hosting are unchanged.
+func checkWWW(ctx context.Context, domain string, v Verification, reuse bool, cfg Config) *WWW {
+	host := "www." + domain
+	dnsCtx, cancel := context.WithTimeout(ctx, cfg.DNSTimeout)
+	defer cancel()
+	w := &WWW{}
+	if ips, err := cfg.resolver().LookupIPAddr(dnsCtx, host); err == nil {
+		for _, ip := range ips {
+			if ip.IP.To4() != nil {
+				w.A = append(w.A, ip.IP.String())
+			} else {
+				w.AAAA = append(w.AAAA, ip.IP.String())
+			}
+		}
+	}
+	if cname, err := cfg.resolver().LookupCNAME(dnsCtx, host); err == nil && !strings.EqualFold(strings.TrimSuffix(cname, "."), host) {
+		w.CNAME = strings.TrimSuffix(cname, ".")
+	}
+	w.Resolvable = len(w.A)+len(w.AAAA) > 0 || w.CNAME != ""
+
+	probe := cfg.DoHTTP && !cfg.Passive && w.Resolvable
+	if probe {
+		var cached WWW
+		stage := cfg.wwwCacheStage()
+		if _, fresh := cfg.cacheGet(stage, domain, &cached); fresh && reuse && sameSet(cached.A, w.A) && sameSet(cached.AAAA, w.AAAA) {
+			w.HTTP = cached.HTTP
+		} else {
+			httpCtx, cancelHTTP := context.WithTimeout(ctx, cfg.HTTPTimeout)
+			defer cancelHTTP()
+			wcfg := cfg.withAddrs(host, DNSResult{A: w.A, AAAA: w.AAAA})
+			wcfg.CrossProtocol, wcfg.Archive, wcfg.prevHTTP = false, nil, nil
+			hr := fetchHTTP(httpCtx, true, host, wcfg)
+			w.HTTP = &hr
+			if hr.Error == nil {
+				cfg.cachePut(stage, domain, WWW{A: w.A, AAAA: w.AAAA, HTTP: w.HTTP})
+			}
+		}
+		if w.HTTP != nil {
+			w.HTTP.MirrorsBase = mirrorsBase(w.HTTP, host, cfg.Base)
+			w.HTTP.ProxyIndicators = proxyIndicators(w.HTTP, nil, host, cfg.Base, cfg.now())
+		}
+	}
+
+	apexLive := v.DNS.HasA || v.DNS.HasAAAA || v.DNS.HasCNAME
+	w.Only = w.Resolvable && !apexLive
+	diff := func(field string, differ bool) {
+		if differ {
+			w.Differences = append(w.Differences, field)
+		}
+	}
+	diff("exists", w.Resolvable != apexLive)
+	if w.Resolvable && apexLive {
+		// www is often a CNAME to the apex: compare where both land.
+		diff("addresses", !sameSet(w.A, v.DNS.A) || !sameSet(w.AAAA, v.DNS.AAAA))
+	}
+	if w.HTTP != nil && v.HTTP != nil {
+		compareWWW(w, v.HTTP, domain, cfg.HTTPFollowRedirects, diff)
+	}
+	return w
+}
+
+// compareWWW records how the www probe differs from the apex one.
+func compareWWW(w *WWW, apex *HTTPResult, domain string, follow bool, diff func(string, bool)) {
+	www := w.HTTP
+	if (apex.Error == nil) != (www.Error == nil) {
+		diff("error", true)
+		return
+	}
+	if apex.Error != nil {
+		return
+	}
+	w.Redirects = redirectsTo(apex, "www."+domain) || redirectsTo(www, domain)
+	if w.Redirects && !follow {
+		return
+	}
+	if !w.Redirects {
+		diff("status", apex.StatusCode != www.StatusCode)
+	}
+	diff("final_url", apexless(apex.FinalURL) != apexless(www.FinalURL))
+	diff("title", apex.Title != www.Title)
+	diff("body", apex.BodySHA256 != www.BodySHA256)
+}
+
+// redirectsTo reports whether r is a redirect to host, over either scheme.
+func redirectsTo(r *HTTPResult, host string) bool {
+	if r.StatusCode < 300 || r.StatusCode >= 400 {
+		return false
+	}
+	u, err := url.Parse(r.Location)
+	return err == nil && strings.EqualFold(u.Hostname(), host)
+}
+
+// apexless drops a URL's scheme and www label, so the same page reached on
+// either host over either protocol compares equal.
+func apexless(u string) string {
+	return strings.TrimPrefix(schemeless(u), "www.")
+}
@@ -0,0 +1,70 @@
+package verify
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"squatrr/lib/verify/verifytest"
+	"testing"
+	"time"
+)
+
+func TestVerifyDomainWWW(t *testing.T) {
+	zone := verifytest.Zone{
+		// Only the www host is configured.
+		"wwwonly.com":     {NS: []string{"ns1.cheaphost.test"}},
+		"www.wwwonly.com": {A: []string{"192.0.2.20"}},
+		// The usual setup: apex redirects to www on the same hosting.
+		"exampel.com":     {A: []string{"192.0.2.10"}},
+		"www.exampel.com": {CNAME: "exampel.com"},
+		// www serves a different page from elsewhere.
+		"examp1e.com":     {A: []string{"192.0.2.30"}},
+		"www.examp1e.com": {A: []string{"198.51.100.7"}},
+		// No www at all.
+		"exampie.com": {A: []string{"192.0.2.40"}},
+	}
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Host {
+		case "exampel.com":
+			return page(r, http.StatusMovedPermanently, "https://www.exampel.com/", ""), nil
+		case "www.examp1e.com":
+			return page(r, http.StatusOK, "", "Sign in"), nil
+		}
+		return page(r, http.StatusOK, "", "Welcome"), nil
+	})
+	cfg := Config{Resolver: verifytest.NewResolver(zone), DoHTTP: true, FetchBody: true, CheckWWW: true, Transport: transport, HTTPTimeout: time.Second}
+
+	tests := []struct {
+		domain     string
+		resolvable bool
+		only       bool
+		redirects  bool
+		diff       []string
+	}{
+		{"wwwonly.com", true, true, false, []string{"exists"}},
+		{"exampel.com", true, false, true, nil},
+		{"examp1e.com", true, false, false, []string{"addresses", "title", "body"}},
+		{"exampie.com", true, false, false, []string{"exists"}},
+	}
+	for _, tt := range tests {
+		v, err := VerifyDomain(context.Background(), tt.domain, cfg)
+		if err != nil || v.WWW == nil {
+			t.Fatalf("VerifyDomain(%s) = %+v, %v, want a www comparison", tt.domain, v, err)
+		}
+		if v.Resolvable != tt.resolvable || v.WWW.Only != tt.only || v.WWW.Redirects != tt.redirects || !reflect.DeepEqual(v.WWW.Differences, tt.diff) {
+			t.Errorf("VerifyDomain(%s) = resolvable %v, www %+v, want resolvable %v, only %v, redirects %v, differences %v",
+				tt.domain, v.Resolvable, v.WWW, tt.resolvable, tt.only, tt.redirects, tt.diff)
+		}
+	}
+
+	v, _ := VerifyDomain(context.Background(), "wwwonly.com", cfg)
+	if v.HTTP != nil || v.WWW.HTTP == nil || v.WWW.HTTP.Title != "Welcome" {
+		t.Errorf("www-only candidate probed the apex (%+v) or not www (%+v)", v.HTTP, v.WWW.HTTP)
+	}
+	if v.Site() != v.WWW.HTTP {
+		t.Errorf("Site() = %+v, want the www probe", v.Site())
+	}
+	if v, _ := VerifyDomain(context.Background(), "unregistered.com", cfg); v.WWW != nil || v.Resolvable {
+		t.Errorf("missing name = %+v, want no www lookup", v)
+	}
+}
@@ -0,0 +1,95 @@
+package workspace
+
+/*
+  This library loads the workspace file of serve mode (-workspaces), which
+  hosts several brand teams or customers in one server instance, each with
+  its own run history, API tokens and scan config:
+
+	[
+	  {"name": "acme", "history": "acme/history.db", "tokens": "acme/tokens.json", "config": "acme/config.json"},
+	  {"name": "globex", "history": "globex/history.db", "tokens": "globex/tokens.json"}
+	]
+
+  Relative paths are relative to the workspace file. A workspace's tokens
+  only open that workspace, so tokens are required: without them any
+  client could read every workspace.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Workspace is one tenant of a shared server.
+type Workspace struct {
+	Name    string `json:"name"`             // served under /w/<name>/
+	History string `json:"history"`          // run history file
+	Tokens  string `json:"tokens"`           // API token file (see lib/auth)
+	Config  string `json:"config,omitempty"` // optional config file for its submitted scans (see lib/config)
+}
+
+// validName keeps workspace names usable as a URL path segment.
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// Parse decodes and validates a workspace file; relative paths are
+// resolved against dir.
+func Parse(data []byte, dir string) ([]Workspace, error) {
+	var spaces []Workspace
+	if err := json.Unmarshal(data, &spaces); err != nil {
+		return nil, err
+	}
+	if len(spaces) == 0 {
+		return nil, errors.New("no workspaces")
+	}
+	names, histories := map[string]bool{}, map[string]bool{}
+	for i := range spaces {
+		w := &spaces[i]
+		if !validName.MatchString(w.Name) {
+			return nil, fmt.Errorf("workspace %q: name must be lowercase letters, digits, - and _", w.Name)
+		}
+		if names[w.Name] {
+			return nil, fmt.Errorf("workspace %q: listed twice", w.Name)
+		}
+		names[w.Name] = true
+		if w.History == "" || w.Tokens == "" {
+			return nil, fmt.Errorf("workspace %q: history and tokens are required", w.Name)
+		}
+		w.History, w.Tokens = resolve(dir, w.History), resolve(dir, w.Tokens)
+		if w.Config != "" {
+			w.Config = resolve(dir, w.Config)
+		}
+		if histories[w.History] {
+			return nil, fmt.Errorf("workspace %q: history %s is another workspace's", w.Name, w.History)
+		}
+		histories[w.History] = true
+	}
+	return spaces, nil
+}
+
+// Load reads the workspace file at path; an empty path is nil (a single,
+// unnamed workspace).
+func Load(path string) ([]Workspace, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	spaces, err := Parse(data, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spaces, nil
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,34 @@
+package workspace
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    []Workspace
+		wantErr bool
+	}{
+		{
+			data: `[{"name":"acme","history":"acme/history.db","tokens":"/etc/sasquat/acme.json","config":"acme.json"}]`,
+			want: []Workspace{{Name: "acme", History: "/srv/acme/history.db", Tokens: "/etc/sasquat/acme.json", Config: "/srv/acme.json"}},
+		},
+		{data: `[]`, wantErr: true},
+		{data: `[{"name":"Acme Corp","history":"h.db","tokens":"t.json"}]`, wantErr: true},
+		{data: `[{"name":"acme","history":"h.db"}]`, wantErr: true},
+		{data: `[{"name":"acme","history":"a.db","tokens":"t.json"},{"name":"acme","history":"b.db","tokens":"t.json"}]`, wantErr: true},
+		{data: `[{"name":"acme","history":"h.db","tokens":"a.json"},{"name":"globex","history":"./h.db","tokens":"b.json"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse([]byte(tt.data), "/srv")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%s) error = %v, wantErr %v", tt.data, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%s) = %+v, want %+v", tt.data, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package yara
+
+/*
+  This library runs user-supplied YARA rules over fetched content by
+  shelling out to the yara command-line scanner, so no cgo build of libyara
+  is needed. It implements verify.ContentScanner.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a single yara invocation.
+const DefaultTimeout = 10 * time.Second
+
+// Scanner scans content with a set of rule files. Compiled rules (yarac
+// output) are loaded with -C and must be the only file given.
+type Scanner struct {
+	Binary   string
+	Rules    []string
+	Compiled bool
+	Timeout  time.Duration
+}
+
+// New locates the yara binary and checks the rules compile by scanning an
+// empty file, so a broken rule fails at startup rather than per candidate.
+func New(binary string, rules []string) (*Scanner, error) {
+	if len(rules) == 0 {
+		return nil, errors.New("no yara rules")
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scanner{Binary: path, Rules: rules, Timeout: DefaultTimeout}
+	s.Compiled = len(rules) == 1 && strings.HasSuffix(rules[0], ".yarc")
+	if _, err := s.scan(nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Scan implements verify.ContentScanner. Scanner errors are dropped: a
+// failed scan records no matches.
+func (s *Scanner) Scan(_ string, data []byte) []string {
+	matches, _ := s.scan(data)
+	return matches
+}
+
+func (s *Scanner) scan(data []byte) ([]string, error) {
+	f, err := os.CreateTemp("", "sasquat-yara-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	args := []string{"--no-warnings"}
+	if s.Compiled {
+		args = append(args, "-C")
+	}
+	args = append(append(args, s.Rules...), f.Name())
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.Binary, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("yara: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseOutput(stdout.String()), nil
+}
+
+// parseOutput reads yara's "<rule> <file>" lines into distinct rule names.
+func parseOutput(out string) []string {
+	var rules []string
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || slices.Contains(rules, fields[0]) {
+			continue
+		}
+		rules = append(rules, fields[0])
+	}
+	return rules
+}
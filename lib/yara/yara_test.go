@@ -0,0 +1,35 @@
+package yara
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseOutput(t *testing.T) {
+	out := "PhishKit_Office365 /tmp/sasquat-yara-1\nPhishKit_Generic /tmp/sasquat-yara-1\nPhishKit_Office365 /tmp/sasquat-yara-1\n\n"
+	want := []string{"PhishKit_Office365", "PhishKit_Generic"}
+	if got := parseOutput(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseOutput() = %v, want %v", got, want)
+	}
+}
+
+func TestScan(t *testing.T) {
+	if _, err := exec.LookPath("yara"); err != nil {
+		t.Skip("yara not installed")
+	}
+	rules := filepath.Join(t.TempDir(), "kit.yar")
+	_ = os.WriteFile(rules, []byte(`rule Kit { strings: $a = "next.php?verify=" condition: $a }`), 0o644)
+	s, err := New("yara", []string{rules})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Scan("body", []byte(`<form action="next.php?verify=1">`)); !reflect.DeepEqual(got, []string{"Kit"}) {
+		t.Errorf("Scan() = %v, want [Kit]", got)
+	}
+	if got := s.Scan("body", []byte(`hello`)); got != nil {
+		t.Errorf("Scan() = %v, want none", got)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"runtime"
+	"squatrr/lib/maltego"
+	"squatrr/lib/processor"
+	"squatrr/lib/verify"
+	"time"
+)
+
+// runMaltego serves squatrr generation + verification as Maltego transforms.
+func runMaltego(args []string) {
+	fs := flag.NewFlagSet("maltego", flag.ExitOnError)
+	var (
+		listen     = fs.String("listen", ":8081", "Address to serve the transform endpoints on")
+		workers    = fs.Int("workers", runtime.NumCPU()*4, "Concurrent verification workers per transform")
+		doTLS      = fs.Bool("tls", true, "Attempt TLS metadata fetch on :443")
+		doHTTP     = fs.Bool("http", false, "Attempt HTTP(S) HEAD request")
+		maxDomains = fs.Int("max", 0, "Optional cap on number of candidates processed per transform (0 = no cap)")
+		logLevel   = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+
+	logger := newLogger(*logLevel)
+	srv := &maltego.Server{
+		Base: processor.Options{
+			Workers: *workers,
+			Max:     *maxDomains,
+			Verify: verify.Config{
+				DNSTimeout:    2 * time.Second,
+				TLSTimeout:    3 * time.Second,
+				HTTPTimeout:   4 * time.Second,
+				DoTLS:         *doTLS,
+				DoHTTP:        *doHTTP,
+				UserAgent:     "saskquat-verifier/1.0",
+				DKIMSelectors: verify.DefaultDKIMSelectors,
+			},
+			Logger: logger,
+		},
+		Scan:   processor.ProcessDomain,
+		Logger: logger,
+	}
+
+	logger.Info("serving maltego transforms", "listen", *listen, "transform", "/run/typosquats")
+	log.Fatal(http.ListenAndServe(*listen, srv.Handler()))
+}
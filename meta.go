@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"net/url"
+	"os"
+	"path/filepath"
+	"squatrr/lib/processor"
+	"squatrr/lib/sink"
+	"strings"
+	"time"
+)
+
+// runMeta is written next to the results so a run's scope and coverage are
+// known without loading the result set.
+type runMeta struct {
+	Domain   string          `json:"domain"`
+	TLDs     []string        `json:"tlds"`
+	Budget   string          `json:"budget,omitempty"`
+	Duration string          `json:"duration"`
+	Coverage float64         `json:"coverage"` // dispatched / queued
+	Complete bool            `json:"complete"` // every queued candidate was verified
+	Stats    processor.Stats `json:"stats"`
+
+	// Estimate extrapolates a -sample run to the full candidate population.
+	Estimate *processor.Estimate `json:"estimate,omitempty"`
+
+	// Summary counts the run's results, so the headline numbers are known
+	// without loading them.
+	Summary sink.RunSummary `json:"summary"`
+}
+
+func newRunMeta(domain string, tlds []string, budget time.Duration, stats processor.Stats, summary sink.RunSummary) runMeta {
+	m := runMeta{
+		Domain:   domain,
+		TLDs:     tlds,
+		Duration: stats.Finished.Sub(stats.Started).Round(time.Millisecond).String(),
+		Coverage: stats.Coverage(),
+		Complete: stats.Dispatched == stats.Queued && !stats.Interrupted,
+		Stats:    stats,
+		Estimate: stats.Estimate(),
+		Summary:  summary,
+	}
+	if budget > 0 {
+		m.Budget = budget.String()
+	}
+	return m
+}
+
+// metaPath returns the explicit path, or <outfile>.meta.json next to the results.
+func metaPath(explicit, outfile string) string {
+	if explicit != "" {
+		return explicit
+	}
+	return strings.TrimSuffix(outfile, filepath.Ext(outfile)) + ".meta.json"
+}
+
+func writeRunMeta(path string, m runMeta) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	enc := json.NewEncoder(file)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// secretFlags hold credentials; a run manifest records them as redacted.
+var secretFlags = map[string]bool{"pdns-key": true, "kafka-password": true, "fleet-token": true, "postgres": true}
+
+// runFlags returns the flags set on the command line for the run manifest,
+// without secrets: secretFlags are redacted, as are credentials in URLs.
+func runFlags() map[string]string {
+	flags := map[string]string{}
+	flag.Visit(func(f *flag.Flag) {
+		if secretFlags[f.Name] {
+			flags[f.Name] = "redacted"
+			return
+		}
+		flags[f.Name] = redactURLs(f.Value.String())
+	})
+	return flags
+}
+
+// redactURLs masks the credentials of the URLs in a comma-separated list:
+// passwords, and users without one, as NATS and others take a bare token
+// there.
+func redactURLs(list string) string {
+	values := strings.Split(list, ",")
+	for i, v := range values {
+		u, err := url.Parse(v)
+		if err != nil || u.User == nil {
+			continue
+		}
+		if _, ok := u.User.Password(); !ok {
+			u.User = url.User("xxxxx")
+		}
+		values[i] = u.Redacted()
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+	"squatrr/lib/history"
+	"squatrr/lib/ownership"
+	"squatrr/lib/verify"
+	"time"
+)
+
+// runOwnership issues the DNS TXT challenges proving the brand controls
+// candidates (its defensive registrations) and, with -check, verifies
+// them, moving proved candidates in the history to the owned state.
+func runOwnership(args []string) {
+	fs := flag.NewFlagSet("ownership", flag.ExitOnError)
+	var (
+		domains    = fs.String("domain", "", "Comma-separated candidates to prove ownership of")
+		secretPath = fs.String("secret", "ownership.key", "File holding the secret challenge tokens derive from; created when missing")
+		check      = fs.Bool("check", false, "Look up the challenge records and move proved candidates to the owned state")
+		histPath   = fs.String("history", "history.db", "Run history file written by scans with -history, updated by -check")
+		base       = fs.String("base", "", "The brand domain whose candidates are updated (default: the only recorded one)")
+		resolvers  = fs.String("resolvers", "", "Comma-separated nameservers (host[:port] or DoH https:// URL) challenge records are looked up through (empty = system resolver)")
+		timeout    = fs.Duration("timeout", 5*time.Second, "Timeout of each lookup")
+		author     = fs.String("author", os.Getenv("USER"), "Author of the note recorded on proved candidates")
+		logLevel   = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	selected := parseList(*domains)
+	if len(selected) == 0 {
+		logger.Error("error: -domain is required")
+		os.Exit(2)
+	}
+	// Only issuing creates a secret; checking against a new one would
+	// prove nothing.
+	secret, err := ownership.LoadSecret(*secretPath, !*check)
+	if err != nil {
+		logger.Error("error: -secret", "path", *secretPath, "error", err)
+		os.Exit(2)
+	}
+	issuer, err := ownership.NewIssuer(secret)
+	if err != nil {
+		logger.Error("error: -secret", "path", *secretPath, "error", err)
+		os.Exit(2)
+	}
+
+	if !*check {
+		for _, d := range selected {
+			c, err := issuer.Challenge(d)
+			if err != nil {
+				logger.Error("issuing challenge", "domain", d, "error", err)
+				os.Exit(2)
+			}
+			fmt.Printf("%s\tTXT\t%q\n", c.Name, c.Value)
+		}
+		logger.Info("publish the records above, then run again with -check", "domains", len(selected))
+		return
+	}
+
+	var r verify.Resolver = net.DefaultResolver
+	if *resolvers != "" {
+		pool, err := verify.NewResolverPool(parseList(*resolvers))
+		if err != nil {
+			logger.Error("error: -resolvers", "error", err)
+			os.Exit(2)
+		}
+		r = pool
+	}
+	var proved []string
+	failed := false
+	for _, d := range selected {
+		c, err := issuer.Challenge(d)
+		if err != nil {
+			logger.Error("checking challenge", "domain", d, "error", err)
+			failed = true
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		ok, err := issuer.Check(ctx, r, c.Domain)
+		cancel()
+		switch {
+		case err != nil:
+			logger.Error("checking challenge", "domain", d, "record", c.Name, "error", err)
+			failed = true
+		case ok:
+			proved = append(proved, c.Domain)
+			fmt.Printf("%s\towned\n", c.Domain)
+		default:
+			fmt.Printf("%s\tunproved\n", c.Domain)
+		}
+	}
+	if len(proved) > 0 {
+		if !recordOwned(*histPath, *base, proved, *author, logger) {
+			failed = true
+		}
+	}
+	logger.Info("processing completed ownership", "checked", len(selected), "owned", len(proved))
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// recordOwned moves the proved candidates recorded in the history to the
+// owned state, noting how they were proved. Candidates no scan has found
+// live yet are skipped.
+func recordOwned(histPath, base string, proved []string, author string, logger *slog.Logger) bool {
+	hist, err := history.Open(histPath, false)
+	if err != nil {
+		logger.Error("opening history", "path", histPath, "error", err)
+		return false
+	}
+	defer hist.Close()
+	if base == "" {
+		if bases, err := hist.Bases(); err == nil && len(bases) == 1 {
+			base = bases[0]
+		}
+	}
+	recorded, err := hist.Domains(base)
+	if err != nil {
+		logger.Error("reading history", "base", base, "error", err)
+		return false
+	}
+	var known []string
+	for _, d := range proved {
+		if slices.ContainsFunc(recorded, func(r history.Domain) bool { return r.Domain == d }) {
+			known = append(known, d)
+		} else {
+			logger.Warn("owned but not in history, so not recorded", "base", base, "domain", d)
+		}
+	}
+	if len(known) == 0 {
+		return true
+	}
+	if err := hist.SetState(base, known, history.StateOwned); err != nil {
+		logger.Error("setting state", "base", base, "error", err)
+		return false
+	}
+	for _, d := range known {
+		n := history.Note{Author: author, Text: "Ownership proved by the DNS TXT challenge at " + ownership.RecordLabel + "." + d}
+		if _, err := hist.AddNote(base, d, n); err != nil {
+			logger.Error("adding note", "domain", d, "error", err)
+			return false
+		}
+	}
+	logger.Info("state set", "base", base, "state", history.StateOwned, "domains", len(known))
+	return true
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+)
+
+// startPprof serves the runtime profiling endpoints on addr in the background.
+// Keep addr on loopback; the endpoints expose process internals.
+func startPprof(addr string, logger *slog.Logger) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	go func() {
+		logger.Info("serving pprof", "listen", addr, "path", "/debug/pprof/")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logger.Error("serving pprof", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"squatrr/lib/report"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// runReport fills report templates with per-domain facts from a results
+// file, drafting UDRP complaints or registrar abuse reports in bulk.
+func runReport(args []string) {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	var (
+		results   = fs.String("results", "site/data/results.json", "Scan results to report on (JSON array or JSON lines)")
+		templates = fs.String("template", "", "Comma-separated text/template files, e.g., templates/udrp-draft.md.tmpl")
+		outDir    = fs.String("out", "reports", "Directory the filled-in reports are written to")
+		base      = fs.String("base", "", "The brand domain being imitated (default: from the results' run metadata)")
+		domains   = fs.String("domain", "", "Comma-separated domains to report on (default: every result passing the filters)")
+		minScore  = fs.Int("min-score", 0, "Only report results scoring at least this")
+		classes   = fs.String("class", "", "Comma-separated landing-page classes to report on, e.g., phishing,parked")
+		evDir     = fs.String("evidence", "", "Directory of evidence bundles; the newest bundle per domain is referenced")
+		logLevel  = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	var tmpls []*template.Template
+	for _, path := range parseList(*templates) {
+		t, err := report.ParseTemplate(path)
+		if err != nil {
+			logger.Error("parsing template", "path", path, "error", err)
+			os.Exit(2)
+		}
+		tmpls = append(tmpls, t)
+	}
+	if len(tmpls) == 0 {
+		logger.Error("error: -template is required")
+		os.Exit(2)
+	}
+
+	f, err := os.Open(*results)
+	if err != nil {
+		logger.Error("opening results", "error", err)
+		os.Exit(2)
+	}
+	outputs, err := report.ReadResults(f)
+	f.Close()
+	if err != nil {
+		logger.Error("reading results", "path", *results, "error", err)
+		os.Exit(2)
+	}
+
+	if *base == "" {
+		var m runMeta
+		if data, err := os.ReadFile(metaPath("", *results)); err == nil && json.Unmarshal(data, &m) == nil {
+			*base = m.Domain
+		}
+	}
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		logger.Error("creating report directory", "error", err)
+		os.Exit(1)
+	}
+
+	only, wantClasses := parseList(*domains), parseList(*classes)
+	now := time.Now()
+	written := 0
+	for _, o := range outputs {
+		if len(only) > 0 && !slices.Contains(only, o.Domain) {
+			continue
+		}
+		if o.Score < *minScore || (len(wantClasses) > 0 && !slices.Contains(wantClasses, o.Class)) {
+			continue
+		}
+		facts := report.FactsFor(o, *base, report.FindEvidence(*evDir, o.Domain), now)
+		for _, t := range tmpls {
+			data, err := report.Render(t, facts)
+			if err != nil {
+				logger.Error("rendering report", "domain", o.Domain, "template", t.Name(), "error", err)
+				os.Exit(1)
+			}
+			path := filepath.Join(*outDir, o.Domain+"-"+strings.TrimSuffix(t.Name(), ".tmpl"))
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				logger.Error("writing report", "path", path, "error", err)
+				os.Exit(1)
+			}
+			written++
+		}
+	}
+	logger.Info("reports written", "dir", *outDir, "count", written)
+}
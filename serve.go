@@ -0,0 +1,682 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"squatrr/lib/auth"
+	"squatrr/lib/client"
+	"squatrr/lib/config"
+	"squatrr/lib/history"
+	"squatrr/lib/jobs"
+	"squatrr/lib/processor"
+	"squatrr/lib/sink"
+	"squatrr/lib/systemd"
+	"squatrr/lib/verify"
+	"squatrr/lib/workspace"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runServe serves the results viewer along with a JSON API over the run
+// history recorded by scans with -history, or over each workspace's with
+// -workspaces.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		listen   = fs.String("listen", "localhost:8080", "Address to serve the viewer and API on")
+		histPath = fs.String("history", "history.db", "Run history file written by scans with -history")
+		siteDir  = fs.String("site", "site", "Directory holding the results viewer")
+		maxAge   = fs.Duration("max-run-age", 0, "Report unhealthy on /healthz when the last recorded run finished longer ago than this, e.g., 26h (0 = never)")
+		tokFile  = fs.String("tokens", "", "Optional JSON file of API tokens with scopes (read, triage, admin); without it the viewer and API are open")
+		wsFile   = fs.String("workspaces", "", "Optional JSON file of workspaces, each with its own history, tokens and config, served under /w/<name>/ (replaces -history and -tokens)")
+		maxScans = fs.Int("max-scans", 0, "Scans submitted through the API run at once; more wait in a queue (0 = no scan submission)")
+		perToken = fs.Int("max-scans-per-tenant", 1, "Scans any one tenant (API token) runs at once (0 = -max-scans)")
+		workers  = fs.Int("scan-workers", runtime.NumCPU()*4, "Concurrent verification workers per submitted scan")
+		logLevel = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	scan := processor.Options{
+		Workers: *workers,
+		Verify: verify.Config{
+			DNSTimeout:    2 * time.Second,
+			TLSTimeout:    3 * time.Second,
+			HTTPTimeout:   4 * time.Second,
+			DoTLS:         true,
+			UserAgent:     "saskquat-verifier/1.0",
+			DKIMSelectors: verify.DefaultDKIMSelectors,
+		},
+		Logger: logger,
+	}
+	var spaces []servedWorkspace
+	if *wsFile == "" {
+		tokens, err := auth.Load(*tokFile)
+		if err != nil {
+			logger.Error("error: -tokens", "error", err)
+			os.Exit(2)
+		}
+		spaces = []servedWorkspace{{histPath: *histPath, tokens: tokens, scan: scan}}
+	} else {
+		defs, err := workspace.Load(*wsFile)
+		if err != nil {
+			logger.Error("error: -workspaces", "error", err)
+			os.Exit(2)
+		}
+		for _, d := range defs {
+			ws, err := loadWorkspace(d, scan)
+			if err != nil {
+				logger.Error("error: -workspaces", "workspace", d.Name, "error", err)
+				os.Exit(2)
+			}
+			spaces = append(spaces, ws)
+		}
+	}
+
+	var queue *jobs.Queue
+	if *maxScans > 0 {
+		queue = jobs.New(*maxScans, *perToken, scanRunner(spaces, logger))
+		defer queue.Close()
+	}
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		logger.Error("serving viewer", "error", err)
+		os.Exit(1)
+	}
+	srv := &http.Server{
+		Handler:           newWorkspacesHandler(spaces, *siteDir, *maxAge, queue, logger),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logger.Info("serving viewer", "listen", ln.Addr(), "history", *histPath, "site", *siteDir, "tokens", *tokFile != "", "workspaces", *wsFile, "max_scans", *maxScans)
+	serveSupervised(srv, ln, nil, logger)
+}
+
+// servedWorkspace is what serve mode keeps of one workspace: the whole
+// server without -workspaces, when its name is empty.
+type servedWorkspace struct {
+	name     string
+	histPath string
+	tokens   *auth.Tokens
+	scan     processor.Options // options of its submitted scans
+}
+
+// loadWorkspace loads d's tokens and config, which applies to its scans
+// on top of scan.
+func loadWorkspace(d workspace.Workspace, scan processor.Options) (servedWorkspace, error) {
+	tokens, err := auth.Load(d.Tokens)
+	if err != nil {
+		return servedWorkspace{}, err
+	}
+	if d.Config != "" {
+		cfg, err := config.Load(d.Config)
+		if err != nil {
+			return servedWorkspace{}, err
+		}
+		scan.Rubric, scan.TLDPolicy = cfg.Rubric, &cfg.Permutations
+		if cfg.Scoring.HighScore > 0 {
+			scan.HighScore = cfg.Scoring.HighScore
+		}
+	}
+	return servedWorkspace{name: d.Name, histPath: d.History, tokens: tokens, scan: scan}, nil
+}
+
+// newWorkspacesHandler serves the only, unnamed workspace at the root, or
+// each named one under /w/<name>/ behind its own tokens, with their
+// health checks together at /healthz. The viewer and
+// client address the API relative to where they are served, so both work
+// unchanged below a prefix.
+func newWorkspacesHandler(spaces []servedWorkspace, siteDir string, maxAge time.Duration, queue *jobs.Queue, logger *slog.Logger) http.Handler {
+	if len(spaces) == 1 && spaces[0].name == "" {
+		return spaces[0].tokens.Links("/", newServeMux(spaces[0], siteDir, maxAge, queue, logger))
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", serveWorkspacesHealthz(spaces, maxAge, time.Now()))
+	for _, ws := range spaces {
+		prefix := "/w/" + ws.name
+		mux.Handle(prefix+"/", ws.tokens.Links(prefix+"/", http.StripPrefix(prefix, newServeMux(ws, siteDir, maxAge, queue, logger.With("workspace", ws.name)))))
+	}
+	return mux
+}
+
+// serveSupervised serves srv on ln until SIGINT/SIGTERM, telling systemd
+// when it is ready and stopping, and feeding its watchdog while healthy
+// (nil = always) reports no error.
+func serveSupervised(srv *http.Server, ln net.Listener, healthy func() error, logger *slog.Logger) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		_ = systemd.Stopping()
+		shutdown, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		_ = srv.Shutdown(shutdown)
+	}()
+	go systemd.Watchdog(ctx, healthy, logger)
+	if err := systemd.Ready(); err != nil {
+		logger.Warn("processing systemd notify", "error", err)
+	}
+
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("serving", "listen", ln.Addr(), "error", err)
+		os.Exit(1)
+	}
+	<-drained
+}
+
+// newServeMux routes the viewer and API of ws, each behind the token scope
+// it needs; the health check stays open to supervisors. A nil queue turns
+// scan submission off.
+func newServeMux(ws servedWorkspace, siteDir string, maxAge time.Duration, queue *jobs.Queue, logger *slog.Logger) *http.ServeMux {
+	histPath, tokens := ws.histPath, ws.tokens
+	api := historyAPI{path: histPath, logger: logger}
+	scans := scanAPI{queue: queue, workspace: ws.name, histPath: histPath, tokens: tokens}
+	read := func(h http.HandlerFunc) http.Handler { return tokens.Require(auth.ScopeRead, h) }
+	triage := func(h http.HandlerFunc) http.Handler { return tokens.Require(auth.ScopeTriage, sameOrigin(h)) }
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", serveHealthz(histPath, maxAge, time.Now()))
+	mux.Handle("GET /api/bases", read(api.handle(func(s *history.Store, _ string) (any, error) { return s.Bases() })))
+	mux.Handle("GET /api/runs", read(api.handle(func(s *history.Store, base string) (any, error) { return s.Runs(base) })))
+	mux.Handle("GET /api/trends", read(api.handle(func(s *history.Store, base string) (any, error) { return s.Trends(base) })))
+	mux.Handle("GET /api/geo", read(api.handle(func(s *history.Store, base string) (any, error) { return geoSummaryOf(s, base, logger) })))
+	mux.Handle("GET /api/leaderboard", read(api.handle(func(s *history.Store, base string) (any, error) {
+		return leaderboardOf(s, base, ws.scan.HighScore, logger)
+	})))
+	mux.Handle("GET /api/states", read(api.handle(caseStatesOf)))
+	mux.Handle("GET /api/whoami", read(whoami(tokens)))
+	mux.Handle("GET /api/openapi.json", read(serveSpec))
+	mux.Handle("POST /api/state", triage(api.update(setCaseState)))
+	mux.Handle("POST /api/assign", triage(api.update(assignCases)))
+	mux.Handle("POST /api/note", triage(api.note(tokens)))
+	mux.Handle("POST /api/export", read(exportHandler(logger)))
+	mux.Handle("GET /api/scans", read(scans.list))
+	mux.Handle("POST /api/scans", triage(scans.submit))
+	mux.Handle("GET /api/scans/{id}", read(scans.get))
+	mux.Handle("DELETE /api/scans/{id}", triage(scans.cancel))
+	mux.Handle("POST /api/webhook", triage(scans.hook))
+	mux.Handle("GET /", tokens.Require(auth.ScopeRead, http.FileServer(http.Dir(siteDir))))
+	return mux
+}
+
+// sameOrigin refuses requests a browser sends from another site's pages.
+// Without -tokens every caller is an admin, so a page the analyst happens
+// to visit could otherwise change cases or queue scans with a cross-site
+// form post. API clients, curl and webhooks send no Origin and pass.
+func sameOrigin(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		crossSite := r.Header.Get("Sec-Fetch-Site") == "cross-site"
+		if origin != "" {
+			u, err := url.Parse(origin)
+			crossSite = err != nil || !strings.EqualFold(u.Host, r.Host)
+		}
+		if crossSite {
+			http.Error(w, "cross-origin request refused", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// whoami tells the viewer the name and scope of the caller's token, so it
+// can hide the controls the token can't use.
+func whoami(tokens *auth.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, _ := tokens.Authenticate(r)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(client.Whoami{Name: t.Name, Scope: t.Scope})
+	}
+}
+
+// serveSpec publishes the API's OpenAPI definition.
+func serveSpec(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(client.Spec)
+}
+
+// serveHealthz reports the most recent run recorded in the history, so a
+// supervisor can alert when scheduled scans stop landing. Only a stale last
+// run is unhealthy: the history being locked by a scan, or not yet
+// written, is expected.
+func serveHealthz(histPath string, maxAge time.Duration, started time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		h := healthOf(histPath, maxAge, started)
+		writeHealth(w, h.Status == "ok", h)
+	}
+}
+
+// workspacesHealth answers the root /healthz of -workspaces mode.
+type workspacesHealth struct {
+	Status     string                   `json:"status"` // ok, or stale when any workspace is
+	Workspaces map[string]client.Health `json:"workspaces"`
+}
+
+// serveWorkspacesHealthz reports the health of every workspace at once, so
+// one supervisor check covers the server; it fails when any workspace's
+// would.
+func serveWorkspacesHealthz(spaces []servedWorkspace, maxAge time.Duration, started time.Time) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		h := workspacesHealth{Status: "ok", Workspaces: map[string]client.Health{}}
+		for _, ws := range spaces {
+			wh := healthOf(ws.histPath, maxAge, started)
+			if wh.Status != "ok" {
+				h.Status = wh.Status
+			}
+			h.Workspaces[ws.name] = wh
+		}
+		writeHealth(w, h.Status == "ok", h)
+	}
+}
+
+func healthOf(histPath string, maxAge time.Duration, started time.Time) client.Health {
+	h := client.Health{Status: "ok", Started: started.UTC()}
+	var last history.Run
+	s, err := history.Open(histPath, true)
+	if err == nil {
+		last, err = s.LastRun()
+		s.Close()
+	}
+	switch {
+	case err != nil:
+		h.History = err.Error()
+	default:
+		h.LastRun = &last
+		if maxAge > 0 && time.Since(last.Finished) > maxAge {
+			h.Status = "stale"
+		}
+	}
+	return h
+}
+
+// writeHealth answers a health check with v as JSON, 200 when ok and 503
+// otherwise.
+func writeHealth(w http.ResponseWriter, ok bool, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// historyAPI opens the history file read-only for each request, so a scan
+// can take the write lock between requests; while one holds it the API
+// answers 503. Updates open it read-write just as briefly.
+type historyAPI struct {
+	path   string
+	logger *slog.Logger
+}
+
+// errBadRequest marks update errors that are the client's, answered 400.
+var errBadRequest = errors.New("bad request")
+
+// maxUpdateBytes caps an update's request body.
+const maxUpdateBytes = 1 << 20
+
+// handle adapts a query over the store for one base domain (the ?base=
+// parameter, or the only recorded base) into a JSON endpoint.
+func (a historyAPI) handle(query func(s *history.Store, base string) (any, error)) http.HandlerFunc {
+	return a.serve(false, func(s *history.Store, base string, _ []byte) (any, error) { return query(s, base) })
+}
+
+// update is handle for requests changing the store, given the request body.
+func (a historyAPI) update(change func(s *history.Store, base string, body []byte) (any, error)) http.HandlerFunc {
+	return a.serve(true, change)
+}
+
+func (a historyAPI) serve(write bool, fn func(s *history.Store, base string, body []byte) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if write {
+			var err error
+			if body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxUpdateBytes)); err != nil {
+				http.Error(w, "reading request: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		s, err := history.Open(a.path, !write)
+		if err != nil {
+			a.logger.Warn("processing api request", "path", r.URL.Path, "error", err)
+			http.Error(w, "history unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		defer s.Close()
+
+		base := r.URL.Query().Get("base")
+		if base == "" {
+			if bases, err := s.Bases(); err == nil && len(bases) == 1 {
+				base = bases[0]
+			}
+		}
+		v, err := fn(s, base, body)
+		switch {
+		case errors.Is(err, history.ErrNoHistory):
+			http.Error(w, "no history for base domain "+base, http.StatusNotFound)
+			return
+		case errors.Is(err, errBadRequest), errors.Is(err, history.ErrInvalidState), errors.Is(err, history.ErrUnknownDomain), errors.Is(err, history.ErrEmptyNote):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case err != nil:
+			a.logger.Error("processing api request", "path", r.URL.Path, "error", err)
+			http.Error(w, "history query failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+// scanAPI queues scans of a workspace for the tenant named by the
+// caller's token. Admin tokens see and cancel every tenant's scans of the
+// workspace, others only their own.
+type scanAPI struct {
+	queue     *jobs.Queue
+	workspace string
+	histPath  string
+	tokens    *auth.Tokens
+}
+
+// tenant returns the caller's tenant, and the tenant filter its queries
+// get ("" for admins).
+func (a scanAPI) tenant(r *http.Request) (name, filter string) {
+	t, _ := a.tokens.Authenticate(r)
+	if t.Scope == auth.ScopeAdmin {
+		return t.Name, ""
+	}
+	return t.Name, t.Name
+}
+
+func (a scanAPI) list(w http.ResponseWriter, r *http.Request) {
+	a.answer(w, r, http.StatusOK, func(_, filter string) (any, error) { return a.queue.List(a.workspace, filter), nil })
+}
+
+func (a scanAPI) submit(w http.ResponseWriter, r *http.Request) {
+	var req jobs.Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateBytes)).Decode(&req); err != nil {
+		http.Error(w, "expected a JSON scan request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.answer(w, r, http.StatusAccepted, func(name, _ string) (any, error) { return a.queue.Submit(a.workspace, name, req) })
+}
+
+// hook queues the scans a webhook's events ask for (see jobs.Event).
+// Events without a base domain are of the ?base= one, or of the only base
+// recorded in the history.
+func (a scanAPI) hook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUpdateBytes))
+	if err != nil {
+		http.Error(w, "reading request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.answer(w, r, http.StatusAccepted, func(name, _ string) (any, error) {
+		events, err := jobs.ParseEvents(body)
+		if err != nil {
+			return nil, err
+		}
+		base := r.URL.Query().Get("base")
+		if base == "" {
+			if s, err := history.Open(a.histPath, true); err == nil {
+				if bases, err := s.Bases(); err == nil && len(bases) == 1 {
+					base = bases[0]
+				}
+				s.Close()
+			}
+		}
+		reqs, err := jobs.Requests(events, base)
+		if err != nil {
+			return nil, err
+		}
+		return a.queue.SubmitAll(a.workspace, name, reqs)
+	})
+}
+
+func (a scanAPI) get(w http.ResponseWriter, r *http.Request) {
+	a.answer(w, r, http.StatusOK, func(_, filter string) (any, error) { return a.queue.Get(a.workspace, filter, r.PathValue("id")) })
+}
+
+func (a scanAPI) cancel(w http.ResponseWriter, r *http.Request) {
+	a.answer(w, r, http.StatusOK, func(_, filter string) (any, error) { return a.queue.Cancel(a.workspace, filter, r.PathValue("id")) })
+}
+
+// answer runs fn for the caller's tenant and writes its result with status
+// ok, or its error with the matching status.
+func (a scanAPI) answer(w http.ResponseWriter, r *http.Request, ok int, fn func(name, filter string) (any, error)) {
+	if a.queue == nil {
+		http.Error(w, "scan submission is off; start serve with -max-scans", http.StatusNotFound)
+		return
+	}
+	v, err := fn(a.tenant(r))
+	switch {
+	case errors.Is(err, jobs.ErrInvalidRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, jobs.ErrQueueFull):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, jobs.ErrUnknownJob):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, jobs.ErrFinished):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(ok)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
+
+// scanRunner runs a queued scan in-process with its workspace's options
+// and records it in the workspace's history once it finishes. Results are
+// held until then, so the history is only locked (and the API unavailable)
+// while they are written, one scan at a time.
+func scanRunner(spaces []servedWorkspace, logger *slog.Logger) jobs.Runner {
+	byName := map[string]servedWorkspace{}
+	for _, ws := range spaces {
+		byName[ws.name] = ws
+	}
+	var recording sync.Mutex
+	return func(ctx context.Context, workspace string, req jobs.Request, progress *processor.Progress) error {
+		ws, ok := byName[workspace]
+		if !ok {
+			return fmt.Errorf("unknown workspace %q", workspace)
+		}
+		var stats processor.Stats
+		opts := ws.scan
+		opts.Domain, opts.TLDs, opts.Max, opts.Targets = req.Domain, parseTLDs(req.Domain, strings.Join(req.TLDs, ",")), req.Max, req.Targets
+		opts.Stats, opts.Progress = &stats, progress
+		out, err := processor.ProcessDomain(ctx, opts)
+		if err != nil {
+			return err
+		}
+		var results []processor.Output
+		for o := range out {
+			results = append(results, o)
+		}
+
+		recording.Lock()
+		defer recording.Unlock()
+		hist, err := history.Open(ws.histPath, false)
+		if err != nil {
+			return fmt.Errorf("opening history: %w", err)
+		}
+		defer hist.Close()
+		dest := hist.ChangeDetector(req.Domain, hist.Recorder(req.Domain, &stats))
+		for _, o := range results {
+			if err := dest.Write(o); err != nil {
+				return err
+			}
+		}
+		if err := dest.Close(); err != nil {
+			return err
+		}
+		logger.Info("processing completed scan", "workspace", workspace, "domain", req.Domain, "found", stats.Found, "verified", stats.Verified, "changed", dest.Changed())
+		return nil
+	}
+}
+
+func caseStatesOf(s *history.Store, base string) (any, error) {
+	domains, err := s.Domains(base)
+	if err != nil {
+		return nil, err
+	}
+	cases := []client.Case{}
+	for _, d := range domains {
+		cases = append(cases, client.Case{Domain: d.Domain, State: d.State, Live: d.Live, Assignee: d.Assignee, Notes: d.Notes})
+	}
+	return cases, nil
+}
+
+func setCaseState(s *history.Store, base string, body []byte) (any, error) {
+	var c client.StateChange
+	if err := json.Unmarshal(body, &c); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	if len(c.Domains) == 0 {
+		return nil, fmt.Errorf("%w: no domains", errBadRequest)
+	}
+	if err := s.SetState(base, c.Domains, c.State); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func assignCases(s *history.Store, base string, body []byte) (any, error) {
+	var a client.Assignment
+	if err := json.Unmarshal(body, &a); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	if len(a.Domains) == 0 {
+		return nil, fmt.Errorf("%w: no domains", errBadRequest)
+	}
+	if err := s.Assign(base, a.Domains, a.Assignee); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// note is update for /api/note, signing notes with the caller's token name
+// so one analyst can't write as another. Without -tokens nobody is
+// identified, and the author in the request is kept.
+func (a historyAPI) note(tokens *auth.Tokens) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var author string
+		if tokens != nil {
+			t, _ := tokens.Authenticate(r)
+			author = t.Name
+		}
+		a.update(func(s *history.Store, base string, body []byte) (any, error) {
+			return addCaseNote(s, base, body, author)
+		})(w, r)
+	}
+}
+
+// addCaseNote adds the note in body, signed by author when set.
+func addCaseNote(s *history.Store, base string, body []byte, author string) (any, error) {
+	var n client.NoteRequest
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadRequest, err)
+	}
+	if author != "" {
+		n.Author = author
+	}
+	return s.AddNote(base, n.Domain, history.Note{Author: n.Author, Text: n.Text})
+}
+
+func geoSummaryOf(s *history.Store, base string, logger *slog.Logger) (client.GeoSummary, error) {
+	domains, err := s.Domains(base)
+	if err != nil {
+		return client.GeoSummary{}, err
+	}
+	g := sink.NewGeo("", logger)
+	for _, d := range domains {
+		if d.Live {
+			_ = g.Write(d.Latest)
+		}
+	}
+	return client.GeoSummary{Base: base, Countries: g.Counts(), Unlocated: g.Unlocated()}, nil
+}
+
+// leaderboardOf ranks the providers behind base's currently live flagged
+// candidates, or behind every base's when it is empty.
+func leaderboardOf(s *history.Store, base string, highScore int, logger *slog.Logger) (sink.Board, error) {
+	bases := []string{base}
+	if base == "" {
+		var err error
+		if bases, err = s.Bases(); err != nil {
+			return sink.Board{}, err
+		}
+	}
+	lb := sink.NewLeaderboard("", base, highScore, 0, logger)
+	for _, b := range bases {
+		domains, err := s.Domains(b)
+		if err != nil {
+			return sink.Board{}, err
+		}
+		for _, d := range domains {
+			if d.Live {
+				lb.Record(b, d.Latest)
+			}
+		}
+	}
+	return lb.Board(), nil
+}
+
+// maxExportBytes caps the result set a browser may post for export.
+const maxExportBytes = 256 << 20
+
+// exportHandler turns the viewer's filtered, sorted rows (a JSON array of
+// results, posted as-is) into a CSV or JSON download, preserving their order.
+func exportHandler(logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" {
+			http.Error(w, "format must be csv or json", http.StatusBadRequest)
+			return
+		}
+		var outputs []processor.Output
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxExportBytes)).Decode(&outputs); err != nil {
+			http.Error(w, "expected a JSON array of results: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var dest sink.Sink
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			dest = sink.NewCSVWriter(nopWriteCloser{w})
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+			dest = sink.NewJSONArrayWriter(nopWriteCloser{w})
+		}
+		name := "sasquat-export-" + time.Now().UTC().Format("20060102T150405Z") + "." + format
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+		for _, o := range outputs {
+			if err := dest.Write(o); err != nil {
+				logger.Warn("processing export", "error", err)
+				return
+			}
+		}
+		if err := dest.Close(); err != nil {
+			logger.Warn("processing export", "error", err)
+		}
+	}
+}
+
+// nopWriteCloser lets a sink stream into a response it must not close.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"squatrr/lib/auth"
+	"squatrr/lib/client"
+	"squatrr/lib/history"
+	"squatrr/lib/processor"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tokens of testTokens, by scope.
+const (
+	readToken   = "read-token-0123456789"
+	triageToken = "triage-token-0123456789"
+)
+
+var testTokens = `[
+	{"name": "viewer", "token": "` + readToken + `", "scope": "read"},
+	{"name": "dana", "token": "` + triageToken + `", "scope": "triage"}
+]`
+
+// recordHistory writes a history with one run of example.com that saw
+// exampel.com, returning its path.
+func recordHistory(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "history.db")
+	s, err := history.Open(path, false)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	defer s.Close()
+	r := s.Recorder("example.com", &processor.Stats{})
+	if err := r.Write(processor.Output{Domain: "exampel.com", Resolvable: true}); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	return path
+}
+
+// newTestServer serves ws, without a scan queue.
+func newTestServer(t *testing.T, ws servedWorkspace) *httptest.Server {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	srv := httptest.NewServer(newWorkspacesHandler([]servedWorkspace{ws}, t.TempDir(), 0, nil, logger))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// call sends method path with body to srv, presenting token when set, and
+// returns the status.
+func call(t *testing.T, srv *httptest.Server, method, path, token, body string, header http.Header) int {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s error: %v", method, path, err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestServeSameOrigin(t *testing.T) {
+	// Without -tokens every caller is an admin: only the origin check
+	// stands between a cross-site form post and the cases.
+	srv := newTestServer(t, servedWorkspace{histPath: recordHistory(t)})
+	body := `{"domains": ["exampel.com"], "state": "triaged"}`
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{name: "API client", want: http.StatusOK},
+		{name: "same origin", header: http.Header{"Origin": {srv.URL}}, want: http.StatusOK},
+		{name: "cross origin", header: http.Header{"Origin": {"https://attacker.example"}}, want: http.StatusForbidden},
+		{name: "cross site", header: http.Header{"Sec-Fetch-Site": {"cross-site"}}, want: http.StatusForbidden},
+		{name: "bad origin", header: http.Header{"Origin": {"%zz"}}, want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := call(t, srv, http.MethodPost, "/api/state", "", body, tt.header); got != tt.want {
+				t.Errorf("POST /api/state = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeScopes(t *testing.T) {
+	tokens, err := auth.Parse([]byte(testTokens))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	srv := newTestServer(t, servedWorkspace{histPath: recordHistory(t), tokens: tokens})
+	tests := []struct {
+		method, path, body string
+	}{
+		{http.MethodPost, "/api/state", `{"domains": ["exampel.com"], "state": "triaged"}`},
+		{http.MethodPost, "/api/assign", `{"domains": ["exampel.com"], "assignee": "dana"}`},
+		{http.MethodPost, "/api/note", `{"domain": "exampel.com", "text": "parked"}`},
+		{http.MethodPost, "/api/scans", `{"domain": "example.com"}`},
+		{http.MethodDelete, "/api/scans/1", ""},
+		{http.MethodPost, "/api/webhook", `{"base": "example.com"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := call(t, srv, tt.method, tt.path, "", tt.body, nil); got != http.StatusUnauthorized {
+				t.Errorf("without a token = %d, want %d", got, http.StatusUnauthorized)
+			}
+			if got := call(t, srv, tt.method, tt.path, readToken, tt.body, nil); got != http.StatusForbidden {
+				t.Errorf("with a read token = %d, want %d", got, http.StatusForbidden)
+			}
+			if got := call(t, srv, tt.method, tt.path, triageToken, tt.body, nil); got == http.StatusUnauthorized || got == http.StatusForbidden {
+				t.Errorf("with a triage token = %d, want it let through", got)
+			}
+		})
+	}
+	if got := call(t, srv, http.MethodGet, "/api/states", readToken, "", nil); got != http.StatusOK {
+		t.Errorf("GET /api/states with a read token = %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestServeNoteAuthor(t *testing.T) {
+	tokens, err := auth.Parse([]byte(testTokens))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	tests := []struct {
+		name   string
+		tokens *auth.Tokens
+		token  string
+		want   string
+	}{
+		// A token signs with its own name, whatever the body claims.
+		{name: "token", tokens: tokens, token: triageToken, want: "dana"},
+		// Without -tokens nobody is identified: the claim is all there is.
+		{name: "no tokens", want: "mallory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, servedWorkspace{histPath: recordHistory(t), tokens: tt.tokens})
+			body := `{"domain": "exampel.com", "author": "mallory", "text": "parked"}`
+			if got := call(t, srv, http.MethodPost, "/api/note", tt.token, body, nil); got != http.StatusOK {
+				t.Fatalf("POST /api/note = %d, want %d", got, http.StatusOK)
+			}
+
+			req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/states", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("GET /api/states error: %v", err)
+			}
+			defer resp.Body.Close()
+			var cases []client.Case
+			if err := json.NewDecoder(resp.Body).Decode(&cases); err != nil {
+				t.Fatalf("decoding /api/states: %v", err)
+			}
+			if len(cases) != 1 || len(cases[0].Notes) != 1 || cases[0].Notes[0].Author != tt.want {
+				t.Errorf("cases = %+v, want one note by %s", cases, tt.want)
+			}
+		})
+	}
+}
+
+func TestServeWorkspacesHealthz(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	spaces := []servedWorkspace{
+		{name: "acme", histPath: recordHistory(t)},
+		{name: "globex", histPath: filepath.Join(t.TempDir(), "none.db")}, // nothing recorded yet
+	}
+	tests := []struct {
+		name       string
+		maxAge     time.Duration
+		wantCode   int
+		wantStatus string
+	}{
+		{name: "fresh", maxAge: time.Hour, wantCode: http.StatusOK, wantStatus: "ok"},
+		{name: "stale", maxAge: time.Nanosecond, wantCode: http.StatusServiceUnavailable, wantStatus: "stale"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(newWorkspacesHandler(spaces, t.TempDir(), tt.maxAge, nil, logger))
+			defer srv.Close()
+			resp, err := srv.Client().Get(srv.URL + "/healthz")
+			if err != nil {
+				t.Fatalf("GET /healthz error: %v", err)
+			}
+			defer resp.Body.Close()
+			var h workspacesHealth
+			if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+				t.Fatalf("decoding /healthz: %v", err)
+			}
+			if resp.StatusCode != tt.wantCode || h.Status != tt.wantStatus {
+				t.Errorf("GET /healthz = %d %q, want %d %q", resp.StatusCode, h.Status, tt.wantCode, tt.wantStatus)
+			}
+			if h.Workspaces["acme"].LastRun == nil || h.Workspaces["globex"].History == "" {
+				t.Errorf("workspaces = %+v, want acme's last run and globex's missing history", h.Workspaces)
+			}
+			if got := call(t, srv, http.MethodGet, "/w/acme/healthz", "", "", nil); got != tt.wantCode {
+				t.Errorf("GET /w/acme/healthz = %d, want %d", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"squatrr/lib/classify"
+	"time"
+)
+
+// defaultSignaturesURL is the published feed, tracking the main branch.
+const defaultSignaturesURL = "https://raw.githubusercontent.com/Splat/sasquat/main/lib/classify/signatures.json"
+
+// runUpdateSignatures downloads the parking/for-sale signature feed and
+// replaces the local copy when it is valid and newer.
+func runUpdateSignatures(args []string) {
+	fs := flag.NewFlagSet("update-signatures", flag.ExitOnError)
+	var (
+		url      = fs.String("url", defaultSignaturesURL, "Signature feed to download")
+		out      = fs.String("out", classify.DefaultPath(), "Where to store the feed (read by -signatures)")
+		force    = fs.Bool("force", false, "Replace the local feed even if it is not older")
+		logLevel = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	data, err := fetchSignatures(ctx, *url)
+	if err != nil {
+		logger.Error("downloading signatures", "url", *url, "error", err)
+		os.Exit(1)
+	}
+	set, err := classify.Parse(data)
+	if err != nil {
+		logger.Error("validating signatures", "url", *url, "error", err)
+		os.Exit(1)
+	}
+
+	current, err := classify.Load(*out)
+	if err != nil {
+		logger.Warn("replacing unreadable signatures", "path", *out, "error", err)
+		current = classify.Default
+	}
+	if set.Version <= current.Version && !*force {
+		logger.Info("signatures up to date", "version", current.Version, "remote", set.Version)
+		return
+	}
+
+	if err := writeFileAtomic(*out, data); err != nil {
+		logger.Error("writing signatures", "path", *out, "error", err)
+		os.Exit(1)
+	}
+	logger.Info("signatures updated", "path", *out, "from", current.Version, "to", set.Version, "signatures", len(set.Signatures))
+}
+
+func fetchSignatures(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+}
+
+// writeFileAtomic writes data next to path and renames it into place so a
+// concurrent scan never reads a partial feed.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".signatures-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
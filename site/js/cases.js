@@ -0,0 +1,154 @@
+/* ---------- case queue (sasquat serve) ---------- */
+// CASES maps each recorded candidate to its case (state, assignee, notes),
+// as served by /api/states; null without the API, when states and
+// assignees come from the results.
+let CASES = null;
+
+const CASE_STATES = ["new","triaged","reported","remediated","accepted-risk","owned"];
+
+// CASE_WRITE is whether the caller's token may change cases (triage scope
+// or above; serve mode without -tokens allows everyone).
+let CASE_WRITE = false;
+
+async function loadCases(){
+    const base = $("baseDomain").value.trim().toLowerCase();
+    const resp = await fetch("api/states"+(base ? "?base="+encodeURIComponent(base) : ""), {cache:"no-store"});
+    if(!resp.ok) throw new Error("case states unavailable ("+resp.status+")");
+    const cases = {};
+    for(const c of await resp.json()) cases[c.domain] = c;
+    const who = await fetch("api/whoami", {cache:"no-store"});
+    CASE_WRITE = who.ok && ["triage","admin"].includes((await who.json()).scope);
+    CASES = cases;
+    applyFilters();
+}
+
+// caseState is a row's current state: the served one when the API is up,
+// else what the scan copied from the history ("" for unrecorded rows).
+function caseState(r){
+    if(CASES) return CASES[r.domain] ? CASES[r.domain].state : "";
+    return r.state;
+}
+
+function caseAssignee(r){
+    if(CASES) return CASES[r.domain] ? safe(CASES[r.domain].assignee) : "";
+    return r.assignee;
+}
+
+function caseStateColor(s){
+    if(s==="new") return "bad";
+    if(s==="triaged" || s==="reported") return "warn";
+    if(s==="remediated" || s==="accepted-risk" || s==="owned") return "good";
+    return "muted";
+}
+
+function renderCases(){
+    const counts = {};
+    for(const r of VIEW){
+        const s = caseState(r);
+        if(s) counts[s] = (counts[s]||0) + 1;
+    }
+    const any = CASES || RAW.some(r=>r.state);
+    $("casesCard").style.display = any ? "" : "none";
+    if(!any) return;
+
+    $("caseCounts").innerHTML = CASE_STATES.map(s=>
+        `<span class="pill caseCount" data-state="${escapeAttr(s)}" style="cursor:pointer;margin:0 4px 4px 0;">${escapeHtml(s)} <strong style="color:var(--${caseStateColor(s)})">${counts[s]||0}</strong></span>`
+    ).join("");
+    for(const el of document.querySelectorAll("#caseCounts .caseCount")){
+        el.onclick = ()=>{ $("stateFilter").value = el.dataset.state; applyFilters(); };
+    }
+
+    const recorded = VIEW.filter(r=>CASES && CASES[r.domain]).length;
+    $("caseApplyBtn").disabled = $("caseAssignBtn").disabled = !recorded || !CASE_WRITE;
+    $("caseApplyBtn").textContent = "Set on "+recorded+" shown";
+    $("caseAssignBtn").textContent = "Assign "+recorded+" shown";
+    $("caseHint").textContent = !CASES
+        ? "States from the results file; serve the viewer with `sasquat serve` to change them."
+        : CASE_WRITE ? "Bulk changes apply to the shown rows recorded in the history."
+        : "Your API token is read-only; changing cases needs the triage scope.";
+}
+
+// postCase sends a case update to `sasquat serve` and returns its answer.
+async function postCase(path, body){
+    const base = $("baseDomain").value.trim().toLowerCase();
+    const resp = await fetch(path+(base ? "?base="+encodeURIComponent(base) : ""), {
+        method:"POST",
+        headers:{"Content-Type":"application/json"},
+        body:JSON.stringify(body),
+    });
+    if(!resp.ok) throw new Error("Updating cases failed ("+resp.status+"): "+(await resp.text()));
+    return resp.json();
+}
+
+function shownCases(){
+    return VIEW.map(r=>r.domain).filter(d=>CASES && CASES[d]);
+}
+
+// setCaseStates moves every shown, recorded row to state in one request.
+async function setCaseStates(state){
+    const domains = shownCases();
+    if(!domains.length) return;
+    if(!confirm("Set "+domains.length+" candidates to "+state+"?")) return;
+    await postCase("api/state", {domains, state});
+    for(const d of domains) CASES[d].state = state;
+    applyFilters();
+}
+
+// assignCases assigns every shown, recorded row to assignee ("" unassigns).
+async function assignCases(assignee){
+    const domains = shownCases();
+    if(!domains.length) return;
+    if(!confirm((assignee ? "Assign "+domains.length+" candidates to "+assignee : "Unassign "+domains.length+" candidates")+"?")) return;
+    await postCase("api/assign", {domains, assignee});
+    for(const d of domains) CASES[d].assignee = assignee;
+    applyFilters();
+}
+
+async function addCaseNote(domain, text){
+    const author = $("caseAnalyst").value.trim();
+    const note = await postCase("api/note", {domain, author, text});
+    CASES[domain].notes = (CASES[domain].notes || []).concat([note]);
+    const r = VIEW.find(x=>x.domain===domain);
+    if(r) renderInspector(r);
+}
+
+// caseHtml is the inspector's case section: state, assignee and notes,
+// with a form to add a note when served by `sasquat serve`.
+function caseHtml(r){
+    const state = caseState(r);
+    if(!state) return "";
+    const c = CASES ? CASES[r.domain] : null;
+    const notes = (c && c.notes) || [];
+    const notesHtml = notes.length
+        ? `<ul style="margin:10px 0 0 18px; padding:0; display:flex; flex-direction:column; gap:6px;">${notes.map(n=>
+            `<li><div>${escapeHtml(n.text)}</div><div class="muted small">${escapeHtml(n.author || "anonymous")} · ${escapeHtml(String(n.at).slice(0,16).replace("T"," "))}</div></li>`).join("")}</ul>`
+        : `<div class="muted small" style="margin-top:8px;">No notes.</div>`;
+    return `
+    <details open>
+      <summary>Case</summary>
+      <div style="margin-top:10px; display:grid; grid-template-columns: 140px 1fr; gap:8px 12px; align-items:start;">
+        <div class="muted small">State</div><div class="mono"><strong style="color:var(--${caseStateColor(state)})">${escapeHtml(state)}</strong></div>
+        <div class="muted small">Assignee</div><div class="mono">${escapeHtml(caseAssignee(r) || "—")}</div>
+      </div>
+      ${c && CASE_WRITE ? notesHtml + `
+      <textarea id="caseNoteText" placeholder="Add a note…" style="margin-top:10px;"></textarea>
+      <button class="btn" id="caseNoteBtn" data-domain="${escapeAttr(r.domain)}">Add note</button>` : c ? notesHtml : ""}
+    </details>`;
+}
+
+// bindCaseNote wires the inspector's note form after it is rendered.
+function bindCaseNote(){
+    const btn = $("caseNoteBtn");
+    if(!btn) return;
+    btn.onclick = ()=>{
+        const text = $("caseNoteText").value.trim();
+        if(text) addCaseNote(btn.dataset.domain, text).catch(err=>alert(err.message));
+    };
+}
+
+$("caseApplyBtn").onclick = ()=>setCaseStates($("caseSet").value).catch(err=>alert(err.message));
+$("caseAssignBtn").onclick = ()=>assignCases($("caseAssignee").value.trim()).catch(err=>alert(err.message));
+$("caseAnalyst").value = localStorage.getItem("sasquatAnalyst") || "";
+$("caseAnalyst").onchange = ()=>localStorage.setItem("sasquatAnalyst", $("caseAnalyst").value.trim());
+$("baseDomain").addEventListener("change", ()=>loadCases().catch(()=>{}));
+loadCases().catch(()=>{ /* no history API; states come from the results */ });
@@ -17,7 +17,27 @@ function reNormalizeAll(){
     applyFilters();
 }
 
+// exportView downloads the filtered, sorted rows through `sasquat serve`,
+// carrying the viewer's scores so the hand-off matches what is on screen.
+async function exportView(format){
+    const rows = VIEW.map(r=>Object.assign({}, r._raw, {score:r.score, score_tags:r.tags}));
+    const resp = await fetch("api/export?format="+format, {
+        method:"POST",
+        headers:{"Content-Type":"application/json"},
+        body:JSON.stringify(rows),
+    });
+    if(!resp.ok) throw new Error("Export needs the viewer served by `sasquat serve` ("+resp.status+")");
+    const name = (/filename="([^"]+)"/.exec(resp.headers.get("Content-Disposition")||"")||[])[1] || "export."+format;
+    const a = document.createElement("a");
+    a.href = URL.createObjectURL(await resp.blob());
+    a.download = name;
+    a.click();
+    URL.revokeObjectURL(a.href);
+}
+
 $("loadBtn").onclick = ()=>load().catch(err=>alert(err.message));
+$("exportCsvBtn").onclick = ()=>exportView("csv").catch(err=>alert(err.message));
+$("exportJsonBtn").onclick = ()=>exportView("json").catch(err=>alert(err.message));
 $("search").oninput = ()=>applyFilters();
 $("variantFilter").onchange = ()=>applyFilters();
 $("tldFilter").onchange = ()=>applyFilters();
@@ -25,6 +45,13 @@ $("minScore").oninput = ()=>applyFilters();
 $("maxScore").oninput = ()=>applyFilters();
 $("resolvableOnly").onchange = ()=>applyFilters();
 $("httpAttempted").onchange = ()=>applyFilters();
+$("classFilter").onchange = ()=>applyFilters();
+$("expiringFilter").onchange = ()=>applyFilters();
+$("countryFilter").onchange = ()=>applyFilters();
+$("dnsblFilter").onchange = ()=>applyFilters();
+$("certFilter").onchange = ()=>applyFilters();
+$("stateFilter").onchange = ()=>applyFilters();
+$("assigneeFilter").oninput = ()=>applyFilters();
 
 $("baseDomain").onchange = ()=>reNormalizeAll();
 $("sinkholeIps").onchange = ()=>reNormalizeAll();
@@ -0,0 +1,84 @@
+/* ---------- hosting map ---------- */
+// Approximate country centroids [lat, lon] for placing bubbles; countries
+// missing here are still listed beside the map.
+const CENTROIDS = {
+    US:[39,-98], CA:[60,-100], MX:[23,-102], BR:[-10,-52], AR:[-34,-64], CL:[-30,-71], CO:[4,-73], PE:[-10,-76], VE:[7,-66], PA:[9,-80], CR:[10,-84],
+    GB:[54,-2], IE:[53,-8], FR:[46,2], DE:[51,10], NL:[52,5.5], BE:[50.5,4.5], LU:[49.8,6.1], CH:[47,8], AT:[47.5,14.5], IT:[42.8,12.8], ES:[40,-4], PT:[39.5,-8],
+    DK:[56,10], NO:[62,10], SE:[62,15], FI:[64,26], IS:[65,-18], PL:[52,20], CZ:[49.8,15.5], SK:[48.7,19.5], HU:[47,20], RO:[46,25], BG:[43,25], GR:[39,22],
+    RS:[44,21], HR:[45.2,15.5], SI:[46.1,14.8], UA:[49,32], BY:[53,28], MD:[47,29], LT:[55.5,24], LV:[57,25], EE:[59,26], RU:[60,100], TR:[39,35], CY:[35,33], MT:[35.9,14.4],
+    IL:[31.5,34.8], JO:[31,36], LB:[33.8,35.8], SA:[24,45], AE:[24,54], QA:[25.5,51.2], KW:[29.5,47.8], BH:[26,50.5], OM:[21,57], IR:[32,53], IQ:[33,44], SY:[35,38], YE:[15.5,47.5],
+    KZ:[48,68], UZ:[41,64], GE:[42,43.5], AM:[40,45], AZ:[40.5,47.5], PK:[30,70], AF:[33,65], IN:[21,78], BD:[24,90], LK:[7,81], NP:[28,84],
+    CN:[35,103], HK:[22.3,114.2], TW:[23.7,121], JP:[36,138], KR:[36.5,128], KP:[40,127], MN:[46,105], MM:[21,96], TH:[15,101], VN:[16,106], KH:[12.5,105], LA:[18,105],
+    MY:[4,102], SG:[1.35,103.8], ID:[-2,118], PH:[13,122], AU:[-25,134], NZ:[-41,174],
+    EG:[27,30], LY:[27,17], TN:[34,9], DZ:[28,3], MA:[32,-6], NG:[9,8], GH:[8,-1], CI:[7.5,-5.5], SN:[14,-14], KE:[0,38], ET:[9,39], TZ:[-6,35], UG:[1,32],
+    ZA:[-29,24], ZW:[-19,29.5], AO:[-12,18], CD:[-3,23], CM:[6,12], SC:[-4.6,55.5], MU:[-20.3,57.6],
+    BZ:[17.2,-88.7], PR:[18.2,-66.5], VG:[18.4,-64.6], KY:[19.3,-81.3], BS:[24,-76], VC:[13.2,-61.2], DO:[19,-70.7], GI:[36.1,-5.35], IM:[54.2,-4.5], JE:[49.2,-2.1],
+};
+
+let GEO_API = null;
+
+// loadGeo fetches history-wide hosting counts from `sasquat serve`; without
+// the API the map counts the filtered table instead.
+async function loadGeo(){
+    const base = $("baseDomain").value.trim().toLowerCase();
+    const resp = await fetch("api/geo"+(base ? "?base="+encodeURIComponent(base) : ""), {cache:"no-store"});
+    if(!resp.ok) throw new Error("geo unavailable ("+resp.status+")");
+    GEO_API = await resp.json();
+    renderMap();
+}
+
+function renderMap(){
+    let counts, unlocated = 0, source;
+    if(GEO_API){
+        counts = GEO_API.countries.map(c=>({cc:c.country, n:c.live, highRisk:!!c.high_risk}));
+        unlocated = GEO_API.unlocated;
+        source = "live across recorded runs";
+    } else {
+        const by = {};
+        for(const r of VIEW){
+            if(!r.countries.length){ unlocated++; continue; }
+            const hr = (r._raw.geo && r._raw.geo.HighRisk) || [];
+            for(const cc of r.countries){
+                by[cc] = by[cc] || {cc, n:0, highRisk:false};
+                by[cc].n++;
+                by[cc].highRisk = by[cc].highRisk || hr.includes(cc);
+            }
+        }
+        counts = Object.values(by).sort((a,b)=>b.n-a.n || a.cc.localeCompare(b.cc));
+        source = "filtered rows";
+    }
+
+    $("mapCard").style.display = counts.length ? "" : "none";
+    if(!counts.length) return;
+
+    // equirectangular projection with a 30° graticule
+    const w = 720, h = 360;
+    const x = lon=>(lon+180)/360*w, y = lat=>(90-lat)/180*h;
+    let grid = "";
+    for(let lon=-150; lon<180; lon+=30) grid += `<line x1="${x(lon)}" y1="0" x2="${x(lon)}" y2="${h}" />`;
+    for(let lat=-60; lat<90; lat+=30) grid += `<line x1="0" y1="${y(lat)}" x2="${w}" y2="${y(lat)}" />`;
+
+    const max = Math.max(...counts.map(c=>c.n));
+    const bubbles = counts.filter(c=>CENTROIDS[c.cc]).map(c=>{
+        const [lat, lon] = CENTROIDS[c.cc];
+        const rad = 4 + 18*Math.sqrt(c.n/max);
+        const color = c.highRisk ? "var(--bad)" : "var(--accent)";
+        return `<g class="mapCountry" data-cc="${escapeAttr(c.cc)}" style="cursor:pointer">`+
+            `<circle cx="${x(lon).toFixed(1)}" cy="${y(lat).toFixed(1)}" r="${rad.toFixed(1)}" fill="${color}" fill-opacity="0.55" stroke="${color}" />`+
+            `<text x="${x(lon).toFixed(1)}" y="${(y(lat)+3).toFixed(1)}" font-size="9" text-anchor="middle" fill="var(--text)">${escapeHtml(c.cc)}</text>`+
+            `<title>${escapeHtml(c.cc)}: ${c.n}</title></g>`;
+    }).join("");
+
+    $("geoMap").innerHTML = `<svg viewBox="0 0 ${w} ${h}" width="100%" style="background:var(--panel2);border:1px solid var(--line);border-radius:10px;">`+
+        `<g stroke="var(--line)" stroke-width="1">${grid}</g>${bubbles}</svg>`;
+    $("geoList").innerHTML =
+        `<div class="small" style="margin-bottom:6px;">Source: ${escapeHtml(source)}${unlocated ? "; "+unlocated+" unlocated" : ""}</div>`+
+        counts.map(c=>`<span class="pill mapCountry" data-cc="${escapeAttr(c.cc)}" style="cursor:pointer;margin:0 4px 4px 0;">${escapeHtml(c.cc)} <strong${c.highRisk ? ' style="color:var(--bad)"' : ""}>${c.n}</strong></span>`).join("");
+
+    for(const el of document.querySelectorAll("#mapCard .mapCountry")){
+        el.onclick = ()=>{ $("countryFilter").value = el.dataset.cc; applyFilters(); };
+    }
+}
+
+$("baseDomain").addEventListener("change", ()=>loadGeo().catch(()=>{}));
+loadGeo().catch(()=>{ /* no history API; renderMap follows the table */ });
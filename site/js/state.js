@@ -68,6 +68,7 @@ function normalizeRecord(r){
         ns: (dns.NS||[]).join(" "),
         mx: (dns.MX||[]).join(" "),
         tlsIssuer: safe(tls.Issuer),
+        certValidity: safe(tls.Validity),
         httpStatus: safe(http.Status) || (http.StatusCode? String(http.StatusCode):""),
         httpStatusCode: Number(http.StatusCode||0),
         location: safe(http.Location),
@@ -79,6 +80,18 @@ function normalizeRecord(r){
         contentLength: Number(http.ContentLength||0),
         bodySHA256: safe(http.BodySHA256) || safe(http.SHA256) || safe(http.BodyHash),
         faviconMMH3: safe(http.FaviconMMH3) || safe(http.FaviconHash) || safe(http.MMH3),
+        trackingIds: http.TrackingIDs || [],
+        defaultCert: (tls.DefaultVhost && tls.DefaultCertSHA256) || "",
+        pageClass: safe(r.class),
+        state: safe(r.state),
+        assignee: safe(r.assignee),
+        classTags: r.class_tags || [],
+        listing: r.listing || null,
+        rdap: r.rdap || null,
+        expiresInDays: expiresInDays(r.rdap),
+        countries: (r.geo && r.geo.Countries) || [],
+        highRisk: (r.geo && r.geo.HighRisk) || [],
+        dnsbl: (r.dnsbl && r.dnsbl.Listings) || [],
         headers: http.Headers || {},
     };
 }
@@ -91,15 +104,29 @@ function applyFilters(){
     const maxS = parseInt($("maxScore").value||"999",10);
     const ro = $("resolvableOnly").value;
     const ha = $("httpAttempted").value;
+    const pc = $("classFilter").value;
+    const ex = parseInt($("expiringFilter").value||"0",10);
+    const cc = $("countryFilter").value;
+    const bl = $("dnsblFilter").value;
+    const cv = $("certFilter").value;
+    const st = $("stateFilter").value;
+    const as = $("assigneeFilter").value.trim().toLowerCase();
 
     VIEW = RAW
         .filter(r=>{
             if(q){
-                const hay = (r.domain+" "+r.ips+" "+r.tlsIssuer+" "+r.location+" "+r.ns+" "+r.mx).toLowerCase();
+                const hay = (r.domain+" "+r.ips+" "+r.tlsIssuer+" "+r.location+" "+r.ns+" "+r.mx+" "+r.trackingIds.join(" ")+" "+r.defaultCert).toLowerCase();
                 if(!hay.includes(q)) return false;
             }
             if(vf && r.variantClass !== vf) return false;
             if(tf && r.tld !== tf) return false;
+            if(pc && r.pageClass !== pc) return false;
+            if(st && caseState(r) !== st) return false;
+            if(as && caseAssignee(r).toLowerCase() !== as) return false;
+            if(ex && !(r.expiresInDays !== null && r.expiresInDays <= ex)) return false;
+            if(bl && (r.dnsbl.length > 0) !== (bl === "true")) return false;
+            if(cv === "live_expired" ? !(r.resolvable && r.certValidity === "expired") : (cv && r.certValidity !== cv)) return false;
+            if(cc === "high_risk" ? !r.highRisk.length : (cc && !r.countries.includes(cc))) return false;
             if(!(r.score >= minS && r.score <= maxS)) return false;
             if(ro){
                 const want = (ro==="true");
@@ -116,6 +143,7 @@ function applyFilters(){
     render();
     renderKpi();
     renderGroups();
+    renderCases();
     renderActiveFilters();
 }
 
@@ -126,6 +154,7 @@ function sortView(){
 
     VIEW.sort((a,b)=>{
         let av = a[k], bv = b[k];
+        if(k==="state"){ av = caseState(a); bv = caseState(b); }
         if(k==="score" || k==="httpStatusCode" || k==="editDistance"){
             av = Number(av||0); bv = Number(bv||0);
             return (av-bv)*mul;
@@ -161,6 +190,16 @@ function render(){
         vc.innerHTML = `<span class="pill"><strong>${r.variantClass}</strong>${r.editDistance!==null?`<span class="mono">d=${r.editDistance}</span>`:""}</span>`;
         tr.appendChild(vc);
 
+        const pcl = document.createElement("td");
+        pcl.innerHTML = r.pageClass ? `<span class="pill" title="${escapeAttr(r.classTags.join(", "))}"><strong style="color:var(--${pageClassColor(r.pageClass)})">${escapeHtml(r.pageClass)}</strong></span>` : "";
+        tr.appendChild(pcl);
+
+        const cs = document.createElement("td");
+        const state = caseState(r);
+        const assignee = caseAssignee(r);
+        cs.innerHTML = state ? `<span class="pill"><strong style="color:var(--${caseStateColor(state)})">${escapeHtml(state)}</strong></span>${assignee ? `<div class="small mono">@${escapeHtml(assignee)}</div>` : ""}` : "";
+        tr.appendChild(cs);
+
         const tld = document.createElement("td");
         tld.innerHTML = `<span class="pill"><strong>${safe(r.tld||"")}</strong></span>`;
         tr.appendChild(tld);
@@ -216,6 +255,15 @@ function render(){
     sel.innerHTML = '<option value="">All</option>' + tlds.map(t=>`<option value="${t}">${t}</option>`).join("");
     sel.value = tlds.includes(current) ? current : "";
 
+    // update country dropdown
+    const countries = Array.from(new Set(RAW.flatMap(r=>r.countries))).sort();
+    const csel = $("countryFilter");
+    const ccur = csel.value;
+    csel.innerHTML = '<option value="">All</option><option value="high_risk">High-risk jurisdictions</option>' + countries.map(c=>`<option value="${c}">${c}</option>`).join("");
+    csel.value = (ccur === "high_risk" || countries.includes(ccur)) ? ccur : "";
+
+    renderMap();
+
     // keep / set selection
     if(VIEW.length){
         const cur = window.__selected;
@@ -261,9 +309,10 @@ function renderInspector(r){
         const status = safe(c.status || c.Status || c.code || c.StatusCode || "");
         const loc = safe(c.location || c.Location || "");
         const title = safe(c.title || c.Title || "");
+        const err = safe(c.error || c.Error || "");
         return `<li>
       <div class="mono"><strong>${i+1}.</strong> ${escapeHtml(url || "(unknown)")}</div>
-      <div class="muted small">${escapeHtml(status)}${loc? " → "+escapeHtml(loc):""}${title? " · "+escapeHtml(title):""}</div>
+      <div class="muted small">${escapeHtml(status)}${loc? " → "+escapeHtml(loc):""}${title? " · "+escapeHtml(title):""}${err? "failed: "+escapeHtml(err):""}</div>
     </li>`;
     }).join("");
 
@@ -297,6 +346,19 @@ function renderInspector(r){
         <div class="muted small">Title</div><div class="mono">${escapeHtml(title || "— (not captured)")}</div>
         <div class="muted small">Content-Type</div><div class="mono">${escapeHtml(safe(r.contentType) || "—")}</div>
         <div class="muted small">Content-Length</div><div class="mono">${r.contentLength ? escapeHtml(String(r.contentLength)) : "—"}</div>
+        <div class="muted small">Class</div><div class="mono">${escapeHtml(r.pageClass || "—")}${r.classTags.length ? ` <span class="small">(${escapeHtml(r.classTags.join(", "))})</span>` : ""}</div>
+        ${r.rdap ? `<div class="muted small">Registrar</div><div class="mono">${escapeHtml(safe(r.rdap.Registrar) || "—")}${r.rdap.RegistrarClass ? ` · ${escapeHtml(r.rdap.RegistrarClass)}` : ""}${r.rdap.Privacy ? ` · privacy${r.rdap.PrivacyService ? ": " + escapeHtml(r.rdap.PrivacyService) : ""}` : ""}</div>
+        <div class="muted small">Expires</div><div class="mono">${r.expiresInDays !== null ? `${escapeHtml(String(r.rdap.Expires).slice(0,10))} (${r.expiresInDays} days)` : "—"}</div>` : ""}
+        ${r.countries.length ? `<div class="muted small">Hosting</div><div class="mono">${escapeHtml(r.countries.join(", "))}${r.highRisk.length ? ` <strong style="color:var(--bad)">high-risk: ${escapeHtml(r.highRisk.join(", "))}</strong>` : ""}</div>` : ""}
+        ${((r._raw.http||{}).RuleMatches||[]).length ? `<div class="muted small">Rules</div><div class="mono">${r._raw.http.RuleMatches.map(m=>escapeHtml(m.Rule + " (" + m.Severity + ")")).join("<br>")}</div>` : ""}
+        ${(r._raw.http||{}).ArchivePath ? `<div class="muted small">Evidence</div><div class="mono">${escapeHtml(r._raw.http.ArchivePath)}</div>` : ""}
+        ${((r._raw.http||{}).ScanMatches||[]).length ? `<div class="muted small">YARA</div><div class="mono" style="color:var(--bad)">${r._raw.http.ScanMatches.map(m=>escapeHtml(m.Rule + " (" + m.Target + ")")).join("<br>")}</div>` : ""}
+        ${(r._raw.reputation||[]).length ? `<div class="muted small">IP reputation</div><div class="mono" style="color:var(--bad)">${r._raw.reputation.map(m=>escapeHtml(m.IP + " on " + m.Feed)).join("<br>")}</div>` : ""}
+        ${r._raw.dnsbl ? `<div class="muted small">Blocklists</div><div class="mono">${r.dnsbl.length ? r.dnsbl.map(l=>escapeHtml(l.Query + " on " + l.Zone + " (" + (l.Codes||[]).join(", ") + ")")).join("<br>") : "not listed"}</div>` : ""}
+        ${r._raw.tls && r._raw.tls.Connected ? `<div class="muted small">Certificate</div><div class="mono">${r._raw.tls.CertValid ? "valid for hostname" : escapeHtml("invalid: " + safe(r._raw.tls.ValidationError))}${certExpiry(r._raw.tls)}</div>` : ""}
+        ${r._raw.ct ? `<div class="muted small">CT</div><div class="mono">${r._raw.ct.Certificates} certificate(s)${r._raw.ct.Certificates ? `, first ${escapeHtml(String(r._raw.ct.FirstSeen).slice(0,10))}` : ""}</div>` : ""}
+        ${r._raw.pdns ? `<div class="muted small">Passive DNS</div><div class="mono">${(r._raw.pdns.Records||[]).length} record(s)</div>` : ""}
+        ${r.listing ? `<div class="muted small">For sale</div><div class="mono">${escapeHtml(r.listing.marketplace || "unknown marketplace")} · ${r.listing.price ? escapeHtml(r.listing.currency + " " + r.listing.price.toLocaleString()) : "no listed price"}</div>` : ""}
       </div>
     </details>`;
 
@@ -304,6 +366,7 @@ function renderInspector(r){
     const fp = [];
     if(r.bodySHA256) fp.push(`<span class="tag">bodySHA256</span> <span class="mono">${escapeHtml(r.bodySHA256)}</span>`);
     if(r.faviconMMH3) fp.push(`<span class="tag">faviconMMH3</span> <span class="mono">${escapeHtml(r.faviconMMH3)}</span>`);
+    for(const id of r.trackingIds) fp.push(`<span class="tag">trackingID</span> <span class="mono">${escapeHtml(id)}</span>`);
 
     const indicators = fingerprintIndicators(r);
     const indHtml = indicators.length
@@ -343,10 +406,12 @@ function renderInspector(r){
       </div>
     </div>
     ${links}
+    ${caseHtml(r)}
     ${chainHtml}
     ${titleHtml}
     ${fpHtml}
   `;
+    bindCaseNote();
 }
 
 // Very lightweight, data-driven indicators (no network fetch).
@@ -394,6 +459,7 @@ function fingerprintIndicators(r){
     if(r.mx) out.push(`<span class="pill"><strong style="color:var(--good)">mx-present</strong></span> MX records present (phishing surface).`);
     if(r.bodySHA256) out.push(`<span class="pill"><strong style="color:var(--good)">body-hash</strong></span> Body hash captured (supports clustering).`);
     if(r.faviconMMH3) out.push(`<span class="pill"><strong style="color:var(--good)">favicon-hash</strong></span> Favicon hash captured (supports clustering).`);
+    if(r.trackingIds.length) out.push(`<span class="pill"><strong style="color:var(--warn)">tracking-ids</strong></span> Analytics/ad IDs present; search an ID to find other squats run by the same operator.`);
 
     return out;
 }
@@ -416,9 +482,11 @@ function shannonEntropy(str){
 function renderGroups(){
     const byVariant = {};
     const byTld = {};
+    const byDefaultCert = {};
     for(const r of RAW){
         byVariant[r.variantClass] = (byVariant[r.variantClass]||0)+1;
         byTld[r.tld||""] = (byTld[r.tld||""]||0)+1;
+        if(r.defaultCert) byDefaultCert[r.defaultCert] = (byDefaultCert[r.defaultCert]||0)+1;
     }
 
     function groupHtml(obj, onClick){
@@ -437,6 +505,7 @@ function renderGroups(){
 
     $("groupVariant").innerHTML = groupHtml(byVariant);
     $("groupTld").innerHTML = groupHtml(byTld);
+    $("groupDefaultCert").innerHTML = groupHtml(byDefaultCert);
 
     // attach click handlers
     for(const el of $("groupVariant").querySelectorAll(".groupItem")){
@@ -445,6 +514,9 @@ function renderGroups(){
     for(const el of $("groupTld").querySelectorAll(".groupItem")){
         el.onclick = ()=>{ $("tldFilter").value = el.dataset.key; applyFilters(); };
     }
+    for(const el of $("groupDefaultCert").querySelectorAll(".groupItem")){
+        el.onclick = ()=>{ $("search").value = el.dataset.key; applyFilters(); };
+    }
 }
 
 function renderActiveFilters(){
@@ -459,6 +531,13 @@ function renderActiveFilters(){
     add("maxScore", $("maxScore").value);
     add("resolvable", $("resolvableOnly").value);
     add("http", $("httpAttempted").value);
+    add("class", $("classFilter").value);
+    add("expiring", $("expiringFilter").value && ("≤"+$("expiringFilter").value+"d"));
+    add("country", $("countryFilter").value);
+    add("blocklisted", $("dnsblFilter").value);
+    add("certificate", $("certFilter").value);
+    add("state", $("stateFilter").value);
+    add("assignee", $("assigneeFilter").value.trim());
 
     $("activeFilters").innerHTML = pills.join("");
 }
\ No newline at end of file
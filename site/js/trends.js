@@ -0,0 +1,44 @@
+/* ---------- trends (sasquat serve) ---------- */
+async function loadTrends(){
+    const base = $("baseDomain").value.trim().toLowerCase();
+    const resp = await fetch("api/trends"+(base ? "?base="+encodeURIComponent(base) : ""), {cache:"no-store"});
+    if(!resp.ok) throw new Error("trends unavailable ("+resp.status+")");
+    renderTrends(await resp.json());
+}
+
+function renderTrends(t){
+    $("trendsCard").style.display = "";
+    $("trendsKpi").innerHTML = [
+        ["Base", t.base],
+        ["Runs", t.runs.length],
+        ["Live now", t.live],
+        ["Ever seen", t.ever_seen],
+        ["Remediated", t.remediated],
+        ["Remediation rate", (100*t.remediation_rate).toFixed(1)+"%"],
+    ].map(([k,v])=>`<span class="pill">${escapeHtml(k)} <strong>${escapeHtml(safe(v))}</strong></span>`).join("");
+    $("trendLive").innerHTML = barChart(t.weeks, "live", "var(--accent)");
+    $("trendNew").innerHTML = barChart(t.weeks, "new", "var(--warn)");
+    $("trendRemediated").innerHTML = barChart(t.weeks, "remediated", "var(--good)");
+}
+
+// barChart draws one bar per week as inline SVG, labelled with the week's
+// start date on hover.
+function barChart(weeks, key, color){
+    if(!weeks.length) return `<div class="small">No runs recorded.</div>`;
+    const w = 300, h = 90, gap = 2;
+    const max = Math.max(1, ...weeks.map(x=>x[key]));
+    const bw = Math.max(1, (w - gap*(weeks.length-1)) / weeks.length);
+    const bars = weeks.map((x,i)=>{
+        const bh = Math.round((h-12) * x[key] / max);
+        const label = x.start.slice(0,10)+": "+x[key];
+        return `<rect x="${(i*(bw+gap)).toFixed(1)}" y="${h-bh}" width="${bw.toFixed(1)}" height="${bh}" fill="${color}"><title>${escapeHtml(label)}</title></rect>`;
+    }).join("");
+    return `<svg viewBox="0 0 ${w} ${h}" width="100%" height="${h}" preserveAspectRatio="none">`+
+        `<text x="0" y="10" font-size="10" fill="var(--muted)">max ${max}</text>${bars}</svg>`;
+}
+
+$("baseDomain").addEventListener("change", ()=>loadTrends().catch(()=>{}));
+
+// The API only exists under `sasquat serve`; stay hidden when opened as a file
+// or served statically.
+loadTrends().catch(()=>{ /* no history API */ });
@@ -100,6 +100,14 @@ function classifyVariant(baseDomain, candidateDomain){
     return {variantClass: dist<=2 ? "other" : "other", editDistance:dist, tld:cand.tld, tldOnly:false};
 }
 
+// TLD risk table, matching the scanner's score.DefaultTLDRisk.
+const tldRisk = {
+    tk:10, ml:10, ga:10, cf:10, gq:10,
+    top:8, xyz:6, icu:8, cyou:8, buzz:6, sbs:8, cfd:8,
+    bond:6, rest:6, quest:6, click:6, link:4, online:4,
+    site:4, shop:4, live:4, support:6, zip:6, mov:6
+};
+
 function scoreRecord(r, cfg){
     const sinkholes = cfg.sinkholes;
     const indicators = cfg.indicators;
@@ -107,7 +115,8 @@ function scoreRecord(r, cfg){
 
     const dns = r.dns || {};
     const tls = r.tls || {};
-    const http = r.http || {};
+    // a www-only squat is scored on its www host's page
+    const http = r.http || (r.www && r.www.Only && r.www.HTTP) || {};
 
     const ips = []
         .concat(dns.A || [])
@@ -183,6 +192,64 @@ function scoreRecord(r, cfg){
         score += 2; tags.push("no_tls");
     }
 
+    // registration data (scanner -rdap)
+    const rdap = r.rdap || {};
+    if(rdap.RegistrarClass === "abuse_friendly"){ score += 6; tags.push("registrar_abuse_friendly"); }
+    else if(rdap.RegistrarClass === "bulk"){ score += 2; tags.push("registrar_bulk"); }
+    else if(rdap.RegistrarClass === "brand_protection"){ score -= 15; tags.push("registrar_brand_protection"); }
+    if(rdap.Privacy){ score += 3; tags.push("whois_privacy"); }
+
+    // user content rules (scanner -rules)
+    const severityWeights = {info:0, low:5, medium:10, high:20, critical:40};
+    for(const m of (http.RuleMatches || [])){ score += severityWeights[m.Severity] || 0; tags.push("rule:"+m.Rule); }
+
+    // brand application cookies (scanner -brand-cookies)
+    const brandCookies = http.BrandCookies || [];
+    if(brandCookies.length){ score += 25; tags.push("brand_cookie:"+brandCookies[0]); }
+
+    // brand logos, copyright lines and phone numbers (scanner -brand-assets)
+    const brandAssets = http.BrandAssets || [];
+    if(brandAssets.length){ score += 20; tags.push("brand_asset:"+brandAssets[0]); }
+
+    // reverse proxy of the base domain (scanner -header-mirror)
+    if(http.MirrorsBase){ score += 30; tags.push("header_mirror"); }
+
+    // service endpoints (scanner -services): SRV records, or well-known
+    // documents naming the brand other than through the candidate's own name
+    const services = r.services || {};
+    const own = (r.domain||"").toLowerCase().replace(/\.$/, "");
+    let service = ((services.SRV || [])[0] || {}).Service || "";
+    for(const doc of (service ? [] : (services.WellKnown || []))){
+        const named = (doc.Refs || []).some(ref => {
+            const rest = own ? ref.toLowerCase().split(own).join("") : ref.toLowerCase();
+            return cfg.brandToken && rest.includes(cfg.brandToken);
+        });
+        if(named){ service = doc.Path.split("/").pop(); break; }
+    }
+    if(service){ score += 15; tags.push("service_endpoints:"+service); }
+
+    // mail server posing as the brand's (scanner -smtp)
+    const impersonates = (r.smtp || {}).Impersonates || [];
+    if(impersonates.length){ score += 20; tags.push("smtp_impersonation:"+impersonates[0]); }
+
+    // IP reputation feeds (scanner -reputation)
+    const rep = r.reputation || [];
+    if(rep.length){ score += 40; tags.push("ip_reputation:"+rep[0].Feed); }
+
+    // hosting jurisdiction (scanner -high-risk-countries)
+    const highRisk = (r.geo && r.geo.HighRisk) || [];
+    if(highRisk.length){ score += 6; tags.push("high_risk_jurisdiction:"+highRisk[0]); }
+
+    // TLD risk (longest listed suffix); the base domain's own TLD is neutral
+    const labels = (r.domain||"").toLowerCase().replace(/\.$/, "").split(".");
+    for(let i=1;i<labels.length;i++){
+        const suffix = labels.slice(i).join(".");
+        if(!(suffix in tldRisk)) continue;
+        const own = cfg.baseDomain === suffix || (cfg.baseDomain||"").endsWith("."+suffix);
+        if(!own){ score += tldRisk[suffix]; tags.push("tld_risk:"+suffix); }
+        break;
+    }
+
     return {score, tags};
 }
 
@@ -192,6 +259,33 @@ function scoreClass(score){
     return "good";
 }
 
+// expiresInDays returns whole days until an RDAP expiry (negative once
+// lapsed), or null when unknown.
+function expiresInDays(rdap){
+    if(!rdap || !rdap.Expires || rdap.Expires.startsWith("0001-")) return null;
+    const t = Date.parse(rdap.Expires);
+    if(isNaN(t)) return null;
+    return Math.floor((t - Date.now()) / 86400000);
+}
+
+// certExpiry describes a certificate's validity when scanned, as judged by
+// the scanner's trusted clock (see -time-server).
+function certExpiry(tls){
+    const d = Number(tls.DaysUntilExpiry);
+    if(tls.Validity==="expired") return `<br><span style="color:var(--bad)">expired ${-d} day(s) ago</span>`;
+    if(tls.Validity==="not_yet_valid") return `<br><span style="color:var(--warn)">not yet valid</span>`;
+    if(tls.Validity==="current") return `<br>expires in ${d} day(s)`;
+    return "";
+}
+
+// pageClassColor maps the scanner's landing-page class to a palette color.
+function pageClassColor(c){
+    if(c==="reverse_proxy" || c==="phishing") return "bad";
+    if(c==="for_sale" || c==="parked") return "warn";
+    if(c==="brand_redirect" || c==="unrelated") return "good";
+    return "muted";
+}
+
 function safe(x){ return (x===null || x===undefined) ? "" : String(x); }
 
 function escapeHtml(s){
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"squatrr/lib/history"
+	"strings"
+)
+
+// runState lists or sets the case state, assignee and notes of candidates
+// in the history, the triage decisions that re-scans preserve.
+func runState(args []string) {
+	fs := flag.NewFlagSet("state", flag.ExitOnError)
+	var (
+		histPath = fs.String("history", "history.db", "Run history file written by scans with -history")
+		base     = fs.String("base", "", "The brand domain whose candidates are listed or updated (default: the only recorded one)")
+		domains  = fs.String("domain", "", "Comma-separated candidates to list or update (default: all listed; required with -set, -assign and -note)")
+		set      = fs.String("set", "", "State to set: "+strings.Join(history.States, "|"))
+		assign   = fs.String("assign", "", "Analyst to assign the candidates to (an empty value unassigns them)")
+		note     = fs.String("note", "", "Note to add to each candidate")
+		author   = fs.String("author", os.Getenv("USER"), "Author recorded with -note")
+		only     = fs.String("state", "", "Only list candidates in this state")
+		logLevel = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	assigning := false
+	fs.Visit(func(f *flag.Flag) { assigning = assigning || f.Name == "assign" })
+	updating := *set != "" || assigning || *note != ""
+	selected := parseList(*domains)
+	if updating && len(selected) == 0 {
+		logger.Error("error: -set, -assign and -note need -domain")
+		os.Exit(2)
+	}
+	hist, err := history.Open(*histPath, !updating)
+	if err != nil {
+		logger.Error("opening history", "path", *histPath, "error", err)
+		os.Exit(2)
+	}
+	defer hist.Close()
+	if *base == "" {
+		if bases, err := hist.Bases(); err == nil && len(bases) == 1 {
+			*base = bases[0]
+		}
+	}
+
+	if updating {
+		if *set != "" {
+			if err := hist.SetState(*base, selected, *set); err != nil {
+				logger.Error("setting state", "base", *base, "error", err)
+				os.Exit(1)
+			}
+			logger.Info("state set", "base", *base, "state", *set, "domains", len(selected))
+		}
+		if assigning {
+			if err := hist.Assign(*base, selected, *assign); err != nil {
+				logger.Error("assigning", "base", *base, "error", err)
+				os.Exit(1)
+			}
+			logger.Info("assigned", "base", *base, "assignee", *assign, "domains", len(selected))
+		}
+		if *note != "" {
+			for _, d := range selected {
+				if _, err := hist.AddNote(*base, d, history.Note{Author: *author, Text: *note}); err != nil {
+					logger.Error("adding note", "domain", d, "error", err)
+					os.Exit(1)
+				}
+			}
+			logger.Info("note added", "base", *base, "domains", len(selected))
+		}
+		return
+	}
+
+	recorded, err := hist.Domains(*base)
+	if err != nil {
+		logger.Error("reading history", "base", *base, "error", err)
+		os.Exit(1)
+	}
+	for _, d := range recorded {
+		if (len(selected) > 0 && !slices.Contains(selected, d.Domain)) || (*only != "" && d.State != *only) {
+			continue
+		}
+		live := "live"
+		if !d.Live {
+			live = "gone"
+		}
+		fmt.Printf("%s\t%s\t%s\t%d\t%s\t%s\t%d\n", d.Domain, d.State, live, d.Latest.Score, d.Latest.Class, d.Assignee, len(d.Notes))
+	}
+}
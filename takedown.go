@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"squatrr/lib/history"
+	"squatrr/lib/processor"
+	"squatrr/lib/takedown"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// runTakedown submits takedown requests for the flagged candidates in the
+// history to the providers accepting them directly (an abuse API or a
+// standardized form), asking before each one. Submissions are recorded in
+// the history so a candidate isn't reported twice.
+func runTakedown(args []string) {
+	fs := flag.NewFlagSet("takedown", flag.ExitOnError)
+	var (
+		histPath   = fs.String("history", "history.db", "Run history file written by scans with -history")
+		base       = fs.String("base", "", "The brand domain whose candidates are reported")
+		domains    = fs.String("domain", "", "Comma-separated candidates to report (default: every live candidate the last scan flagged)")
+		routesFile = fs.String("takedown-routes", "", "Optional JSON routing table replacing the built-in one")
+		name       = fs.String("reporter", "", "Reporter name sent with each request")
+		email      = fs.String("reporter-email", "", "Reporter email sent with each request, where providers answer")
+		resubmit   = fs.Bool("resubmit", false, "Also offer candidates already reported to the same provider")
+		timeout    = fs.Duration("timeout", 30*time.Second, "Timeout of each submission")
+		logLevel   = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	if *base == "" || *email == "" {
+		logger.Error("error: -base and -reporter-email are required")
+		os.Exit(2)
+	}
+	routes, err := takedown.Load(*routesFile)
+	if err != nil {
+		logger.Error("error: -takedown-routes", "error", err)
+		os.Exit(2)
+	}
+	hist, err := history.Open(*histPath, false)
+	if err != nil {
+		logger.Error("opening history", "path", *histPath, "error", err)
+		os.Exit(2)
+	}
+	defer hist.Close()
+	recorded, err := hist.Domains(*base)
+	if err != nil {
+		logger.Error("reading history", "base", *base, "error", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &takedown.Submitter{Table: routes, Client: &http.Client{Timeout: *timeout}}
+	in := bufio.NewReader(os.Stdin)
+	only := parseList(*domains)
+	submitted, failed := 0, false
+	for _, d := range recorded {
+		if ctx.Err() != nil {
+			break
+		}
+		o := d.Latest
+		if !d.Live || (len(only) > 0 && !slices.Contains(only, d.Domain)) || (len(only) == 0 && o.Takedown == nil) {
+			continue
+		}
+		if d.State == history.StateOwned {
+			logger.Info("owned by the brand, not reported", "domain", d.Domain)
+			continue
+		}
+		// Routed again, so an edited table applies to earlier scans.
+		o.Takedown = routes.Route(o)
+		route, channel, err := s.Channel(o)
+		if err != nil {
+			logger.Info("not submittable, report by hand", "domain", d.Domain, "takedown", o.Takedown)
+			continue
+		}
+		if !*resubmit && slices.ContainsFunc(d.Takedowns, func(sub processor.Submission) bool { return sub.Provider == route.Provider }) {
+			logger.Info("already reported", "domain", d.Domain, "provider", route.Provider)
+			continue
+		}
+		if !confirm(in, os.Stderr, fmt.Sprintf("Submit a takedown request for %s (class %s, score %d) to %s (%s) via %s?", d.Domain, o.Class, o.Score, route.Provider, route.Target, channel)) {
+			continue
+		}
+		subCtx, cancel := context.WithTimeout(ctx, *timeout)
+		sub, err := s.Submit(subCtx, o, takedown.NewReport(o, *base, *name, *email))
+		cancel()
+		if err != nil {
+			logger.Error("submitting takedown", "domain", d.Domain, "provider", route.Provider, "error", err)
+			failed = true
+			continue
+		}
+		if err := hist.RecordSubmission(*base, d.Domain, sub); err != nil {
+			logger.Error("recording submission", "domain", d.Domain, "id", sub.ID, "error", err)
+			failed = true
+			continue
+		}
+		submitted++
+		logger.Info("takedown submitted", "domain", d.Domain, "provider", sub.Provider, "channel", sub.Channel, "id", sub.ID)
+	}
+	logger.Info("processing completed takedown", "submitted", submitted)
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// confirm asks question on out and reports whether the answer read from
+// in is yes. Anything else, including end of input, is no.
+func confirm(in *bufio.Reader, out io.Writer, question string) bool {
+	fmt.Fprint(out, question+" [y/N] ")
+	answer, _ := in.ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"squatrr/lib/manifest"
+)
+
+// runVerifyManifest checks a signed run manifest (-sign-key) and the files
+// it lists, so results presented as evidence can be shown to be unaltered.
+func runVerifyManifest(args []string) {
+	fs := flag.NewFlagSet("verify-manifest", flag.ExitOnError)
+	var (
+		path     = fs.String("manifest", "", "Run manifest to check; its signature is read from <manifest>.sig")
+		keyFile  = fs.String("key", "", "Ed25519 public key (PEM) of the -sign-key the run was signed with")
+		logLevel = fs.String("log-level", "info", "debug|info|warn|error")
+	)
+	_ = fs.Parse(args)
+	logger := newLogger(*logLevel)
+
+	if *path == "" || *keyFile == "" {
+		logger.Error("error: -manifest and -key are required")
+		os.Exit(2)
+	}
+	pub, err := manifest.LoadPublicKey(*keyFile)
+	if err != nil {
+		logger.Error("error: -key", "error", err)
+		os.Exit(2)
+	}
+	m, err := manifest.Verify(*path, pub)
+	if m == nil {
+		logger.Error("manifest not verified", "path", *path, "error", err)
+		os.Exit(1)
+	}
+	if err != nil {
+		// The manifest is genuine, so each listed problem is a file that
+		// changed or went missing since the run.
+		for _, e := range unwrapJoined(err) {
+			logger.Error("file not verified", "error", e)
+		}
+		os.Exit(1)
+	}
+	logger.Info("manifest verified", "domain", m.Domain, "version", m.Version, "started", m.Started, "finished", m.Finished,
+		"files", len(m.Files), "signed_by", m.SignedBy)
+}
+
+// unwrapJoined splits an errors.Join error into its errors.
+func unwrapJoined(err error) []error {
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
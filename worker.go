@@ -0,0 +1,30 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// serveWorker serves h to -fleet coordinators until interrupted, letting
+// in-flight verifications finish. Without a token it only serves on
+// loopback, where no one else can drive scans from this host.
+func serveWorker(addr string, h http.Handler, authenticated bool, logger *slog.Logger) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("serving fleet worker", "error", err)
+		os.Exit(1)
+	}
+	if tcp, ok := ln.Addr().(*net.TCPAddr); !authenticated && (!ok || !tcp.IP.IsLoopback()) {
+		ln.Close()
+		logger.Error("error: -worker-listen off loopback needs -fleet-token", "listen", addr)
+		os.Exit(2)
+	}
+	if !authenticated {
+		logger.Warn("serving fleet worker without -fleet-token; local users can drive scans from this host")
+	}
+	logger.Info("serving fleet worker", "listen", ln.Addr())
+	serveSupervised(&http.Server{Handler: h, ReadHeaderTimeout: 10 * time.Second}, ln, nil, logger)
+}